@@ -0,0 +1,244 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/agent-learning/go-agent-api/internal/agent"
+	"gopkg.in/yaml.v3"
+)
+
+func runAgentsList(g *globals, path string, args []string) error {
+	fs := g.leafFlags(path, "")
+	if _, err := parseArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+	c, err := g.client()
+	if err != nil {
+		return err
+	}
+
+	agents, err := c.ListAgents(g.ctx)
+	if err != nil {
+		return err
+	}
+	return g.render(agents, func(t *table) {
+		t.row("ID", "NAME", "TYPE", "MODEL", "VERSION", "STATUS")
+		for _, ag := range agents {
+			t.row(ag.ID, ag.Name, ag.Type, ag.Config.Model, ag.Version, ag.Status)
+		}
+	})
+}
+
+func runAgentsGet(g *globals, path string, args []string) error {
+	fs := g.leafFlags(path, "ID")
+	rest, err := parseArgs(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	c, err := g.client()
+	if err != nil {
+		return err
+	}
+
+	ag, err := c.GetAgent(g.ctx, rest[0])
+	if err != nil {
+		return err
+	}
+	return g.render(ag, func(t *table) {
+		t.row("ID:", ag.ID)
+		t.row("Name:", ag.Name)
+		t.row("Type:", ag.Type)
+		t.row("Status:", ag.Status)
+		t.row("Version:", ag.Version)
+		t.row("Model:", ag.Config.Model)
+		t.row("Temperature:", ag.Config.Temperature)
+		t.row("Max tokens:", ag.Config.MaxTokens)
+		t.row("Tools:", strings.Join(ag.Config.Tools, ", "))
+		t.row("Created:", ag.CreatedAt)
+		t.row("Updated:", ag.UpdatedAt)
+	})
+}
+
+func runAgentsCreate(g *globals, path string, args []string) error {
+	fs := g.leafFlags(path, "")
+	file := fs.String("f", "", "YAML or JSON file with the agent (name, type, config)")
+	name := fs.String("name", "", "Agent name")
+	agentType := fs.String("type", string(agent.AgentTypeGeneral), "Agent type")
+	model := fs.String("model", "", "Model (server default gpt-4)")
+	temperature := fs.Float64("temperature", 0, "Sampling temperature (server default 0.7)")
+	maxTokens := fs.Int("max-tokens", 0, "Maximum tokens per response (server default 2000)")
+	tools := fs.String("tools", "", "Comma-separated tool names")
+	if _, err := parseArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	var req agent.CreateAgentRequest
+	if *file != "" {
+		if err := decodeFile(*file, &req); err != nil {
+			return err
+		}
+	} else {
+		req = agent.CreateAgentRequest{
+			Name: *name,
+			Type: agent.AgentType(*agentType),
+			Config: agent.AgentConfig{
+				Model:       *model,
+				Temperature: float32(*temperature),
+				MaxTokens:   *maxTokens,
+				Tools:       splitList(*tools),
+			},
+		}
+	}
+	if req.Name == "" {
+		return fmt.Errorf("-name or a file with a name is required")
+	}
+
+	c, err := g.client()
+	if err != nil {
+		return err
+	}
+	ag, err := c.CreateAgent(g.ctx, &req)
+	if err != nil {
+		return err
+	}
+	return g.render(ag, func(t *table) {
+		t.row("Agent", ag.ID, "created")
+	})
+}
+
+func runAgentsDelete(g *globals, path string, args []string) error {
+	fs := g.leafFlags(path, "ID...")
+	rest, err := parseArgs(fs, args, 1, -1)
+	if err != nil {
+		return err
+	}
+	c, err := g.client()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range rest {
+		if err := c.DeleteAgent(g.ctx, id); err != nil {
+			return fmt.Errorf("agent %s: %w", id, err)
+		}
+		fmt.Fprintf(g.stdout, "Agent %s deleted\n", id)
+	}
+	return nil
+}
+
+// runAgentsApply creates or updates agents from a bundle. Definitions
+// without an ID update the existing agent with the same name, and the
+// server fills omitted config with its defaults before comparing, so
+// applying the same file twice reports every agent unchanged.
+func runAgentsApply(g *globals, path string, args []string) error {
+	fs := g.leafFlags(path, "")
+	file := fs.String("f", "", "YAML or JSON file of agent definitions, as written by the export endpoint (- for stdin)")
+	if _, err := parseArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if *file == "" {
+		return fmt.Errorf("-f is required")
+	}
+
+	data, err := readInput(*file)
+	if err != nil {
+		return err
+	}
+	defs, err := agent.DecodeAgentBundle(data)
+	if err != nil {
+		return err
+	}
+
+	c, err := g.client()
+	if err != nil {
+		return err
+	}
+	existing, err := c.ListAgents(g.ctx)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string][]string)
+	for _, ag := range existing {
+		byName[ag.Name] = append(byName[ag.Name], ag.ID)
+	}
+	for _, def := range defs {
+		if def.ID != "" {
+			continue
+		}
+		switch ids := byName[def.Name]; len(ids) {
+		case 0:
+		case 1:
+			def.ID = ids[0]
+		default:
+			return fmt.Errorf("agent name %q matches %d agents, set its id in the file", def.Name, len(ids))
+		}
+	}
+
+	result, err := c.ImportAgents(g.ctx, defs)
+	if err != nil {
+		return err
+	}
+	return g.render(result, func(t *table) {
+		t.row("ACTION", "ID")
+		for _, id := range result.Created {
+			t.row("created", id)
+		}
+		for _, id := range result.Updated {
+			t.row("updated", id)
+		}
+		for _, id := range result.Unchanged {
+			t.row("unchanged", id)
+		}
+	})
+}
+
+// decodeFile reads a YAML or JSON file into v. YAML is converted through
+// JSON so field names match the API's JSON names.
+func decodeFile(path string, v interface{}) error {
+	data, err := readInput(path)
+	if err != nil {
+		return err
+	}
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	normalized, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if err := json.Unmarshal(normalized, v); err != nil {
+		return fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return nil
+}
+
+// readInput reads a file, or stdin when path is "-"
+func readInput(path string) ([]byte, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return data, nil
+}
+
+// splitList splits a comma-separated flag value
+func splitList(value string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+func runCompletion(g *globals, path string, args []string) error {
+	fs := g.leafFlags(path, "bash|zsh|fish")
+	rest, err := parseArgs(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	switch rest[0] {
+	case "bash":
+		writeBashCompletion(g.stdout)
+	case "zsh":
+		// zsh runs the bash script through bashcompinit
+		fmt.Fprintln(g.stdout, "autoload -U +X bashcompinit && bashcompinit")
+		writeBashCompletion(g.stdout)
+	case "fish":
+		writeFishCompletion(g.stdout)
+	default:
+		return fmt.Errorf("unsupported shell %q, use bash, zsh or fish", rest[0])
+	}
+	return nil
+}
+
+// commandNames lists the names of a level of the command tree
+func commandNames(cmds []*command) string {
+	names := make([]string, len(cmds))
+	for i, cmd := range cmds {
+		names[i] = cmd.name
+	}
+	return strings.Join(names, " ")
+}
+
+// writeBashCompletion completes commands, subcommands and the values of -o
+//
+//	source <(agentctl completion bash)
+func writeBashCompletion(w io.Writer) {
+	fmt.Fprintln(w, "# agentctl bash completion: source <(agentctl completion bash)")
+	fmt.Fprintln(w, "_agentctl() {")
+	fmt.Fprintln(w, `    local cur prev group
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    prev="${COMP_WORDS[COMP_CWORD-1]}"
+    case "$prev" in
+        -o) COMPREPLY=($(compgen -W "table json" -- "$cur")); return ;;
+        -f|-input-file|-schema|-config) COMPREPLY=($(compgen -f -- "$cur")); return ;;
+    esac
+    if [[ "$cur" == -* ]]; then
+        COMPREPLY=($(compgen -W "-config -profile -server -api-key -o -h" -- "$cur"))
+        return
+    fi
+    local i gi=0
+    group=""
+    for ((i=1; i<COMP_CWORD; i++)); do
+        case "${COMP_WORDS[i]}" in
+            -o|-config|-profile|-server|-api-key) ((i++)) ;;
+            -*) ;;
+            *) group="${COMP_WORDS[i]}"; gi=$i; break ;;
+        esac
+    done
+    case "$group" in`)
+	fmt.Fprintf(w, "        \"\") COMPREPLY=($(compgen -W %q -- \"$cur\")) ;;\n", commandNames(commands))
+	for _, cmd := range commands {
+		switch {
+		case len(cmd.sub) > 0:
+			fmt.Fprintf(w, "        %s) [[ $COMP_CWORD -eq $((gi+1)) ]] && COMPREPLY=($(compgen -W %q -- \"$cur\")) ;;\n", cmd.name, commandNames(cmd.sub))
+		case cmd.name == "completion":
+			fmt.Fprintf(w, "        %s) COMPREPLY=($(compgen -W \"bash zsh fish\" -- \"$cur\")) ;;\n", cmd.name)
+		}
+	}
+	fmt.Fprintln(w, `    esac
+}
+complete -F _agentctl agentctl`)
+}
+
+// writeFishCompletion completes commands, subcommands and global flags
+//
+//	agentctl completion fish > ~/.config/fish/completions/agentctl.fish
+func writeFishCompletion(w io.Writer) {
+	fmt.Fprintln(w, "# agentctl fish completion")
+	fmt.Fprintln(w, "complete -c agentctl -f")
+	fmt.Fprintln(w, "complete -c agentctl -o config -r -d 'Config file'")
+	fmt.Fprintln(w, "complete -c agentctl -o profile -x -d 'Profile to use'")
+	fmt.Fprintln(w, "complete -c agentctl -o server -x -d 'Server URL'")
+	fmt.Fprintln(w, "complete -c agentctl -o api-key -x -d 'API key'")
+	fmt.Fprintln(w, "complete -c agentctl -o o -x -a 'table json' -d 'Output format'")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "complete -c agentctl -n __fish_use_subcommand -a %s -d %q\n", cmd.name, cmd.summary)
+		for _, sub := range cmd.sub {
+			fmt.Fprintf(w, "complete -c agentctl -n '__fish_seen_subcommand_from %s; and not __fish_seen_subcommand_from %s' -a %s -d %q\n",
+				cmd.name, commandNames(cmd.sub), sub.name, sub.summary)
+		}
+	}
+	fmt.Fprintln(w, "complete -c agentctl -n '__fish_seen_subcommand_from completion' -a 'bash zsh fish'")
+}
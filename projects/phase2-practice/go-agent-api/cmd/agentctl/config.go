@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultServer is used when neither a flag, the environment nor a profile
+// names a server
+const defaultServer = "http://localhost:8080"
+
+// Profile holds the connection settings of one server
+type Profile struct {
+	Server string `yaml:"server"`
+	APIKey string `yaml:"api_key,omitempty"`
+	// Output is the default output format of this profile
+	Output string `yaml:"output,omitempty"`
+}
+
+// Config is the agentctl config file
+type Config struct {
+	CurrentProfile string              `yaml:"current_profile,omitempty"`
+	Profiles       map[string]*Profile `yaml:"profiles"`
+}
+
+// path returns the config file location
+func (g *globals) path() (string, error) {
+	if g.configPath != "" {
+		return g.configPath, nil
+	}
+	if path := os.Getenv("AGENTCTL_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot locate config directory, use -config: %w", err)
+	}
+	return filepath.Join(dir, "agentctl", "config.yaml"), nil
+}
+
+// loadConfig reads the config file; a missing file is an empty config
+func (g *globals) loadConfig() (*Config, error) {
+	config := &Config{Profiles: make(map[string]*Profile)}
+
+	path, err := g.path()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	if config.Profiles == nil {
+		config.Profiles = make(map[string]*Profile)
+	}
+	return config, nil
+}
+
+// saveConfig writes the config file. It holds API keys, so only the owner
+// may read it.
+func (g *globals) saveConfig(config *Config) error {
+	path, err := g.path()
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
+
+// resolveProfile merges, in order of precedence, flags, AGENTCTL_*
+// environment variables and the selected profile
+func (g *globals) resolveProfile() (*Profile, error) {
+	config, err := g.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	name := firstNonEmpty(g.profile, os.Getenv("AGENTCTL_PROFILE"), config.CurrentProfile)
+	resolved := Profile{}
+	if name != "" {
+		profile, exists := config.Profiles[name]
+		if !exists {
+			return nil, fmt.Errorf("profile %q not found, see 'agentctl config view'", name)
+		}
+		resolved = *profile
+	}
+
+	resolved.Server = firstNonEmpty(g.server, os.Getenv("AGENTCTL_SERVER"), resolved.Server, defaultServer)
+	resolved.APIKey = firstNonEmpty(g.apiKey, os.Getenv("AGENTCTL_API_KEY"), resolved.APIKey)
+	resolved.Output = firstNonEmpty(g.output, resolved.Output, formatTable)
+	return &resolved, nil
+}
+
+func runConfigView(g *globals, path string, args []string) error {
+	fs := g.leafFlags(path, "")
+	if _, err := parseArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+	config, err := g.loadConfig()
+	if err != nil {
+		return err
+	}
+
+	// Never print API keys in full
+	masked := &Config{CurrentProfile: config.CurrentProfile, Profiles: make(map[string]*Profile)}
+	for name, profile := range config.Profiles {
+		p := *profile
+		p.APIKey = maskKey(p.APIKey)
+		masked.Profiles[name] = &p
+	}
+
+	return g.render(masked, func(t *table) {
+		t.row("CURRENT", "NAME", "SERVER", "API KEY", "OUTPUT")
+		names := make([]string, 0, len(masked.Profiles))
+		for name := range masked.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			p := masked.Profiles[name]
+			current := ""
+			if name == masked.CurrentProfile {
+				current = "*"
+			}
+			t.row(current, name, p.Server, p.APIKey, p.Output)
+		}
+	})
+}
+
+func runConfigSetProfile(g *globals, path string, args []string) error {
+	fs := g.leafFlags(path, "NAME")
+	// The global -server, -api-key and -o flags set the profile fields
+	use := fs.Bool("use", false, "Also make this the current profile")
+	rest, err := parseArgs(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	if g.output != "" && g.output != formatTable && g.output != formatJSON {
+		return fmt.Errorf("invalid output format %q", g.output)
+	}
+
+	config, err := g.loadConfig()
+	if err != nil {
+		return err
+	}
+	name := rest[0]
+	profile, exists := config.Profiles[name]
+	if !exists {
+		profile = &Profile{Server: defaultServer}
+		config.Profiles[name] = profile
+	}
+	if g.server != "" {
+		profile.Server = strings.TrimRight(g.server, "/")
+	}
+	if g.apiKey != "" {
+		profile.APIKey = g.apiKey
+	}
+	if g.output != "" {
+		profile.Output = g.output
+	}
+	if *use || config.CurrentProfile == "" {
+		config.CurrentProfile = name
+	}
+
+	if err := g.saveConfig(config); err != nil {
+		return err
+	}
+	fmt.Fprintf(g.stdout, "Profile %q saved\n", name)
+	return nil
+}
+
+func runConfigUseProfile(g *globals, path string, args []string) error {
+	fs := g.leafFlags(path, "NAME")
+	rest, err := parseArgs(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	config, err := g.loadConfig()
+	if err != nil {
+		return err
+	}
+	if _, exists := config.Profiles[rest[0]]; !exists {
+		return fmt.Errorf("profile %q not found", rest[0])
+	}
+
+	config.CurrentProfile = rest[0]
+	if err := g.saveConfig(config); err != nil {
+		return err
+	}
+	fmt.Fprintf(g.stdout, "Using profile %q\n", rest[0])
+	return nil
+}
+
+func runConfigDeleteProfile(g *globals, path string, args []string) error {
+	fs := g.leafFlags(path, "NAME")
+	rest, err := parseArgs(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	config, err := g.loadConfig()
+	if err != nil {
+		return err
+	}
+	if _, exists := config.Profiles[rest[0]]; !exists {
+		return fmt.Errorf("profile %q not found", rest[0])
+	}
+
+	delete(config.Profiles, rest[0])
+	if config.CurrentProfile == rest[0] {
+		config.CurrentProfile = ""
+	}
+	if err := g.saveConfig(config); err != nil {
+		return err
+	}
+	fmt.Fprintf(g.stdout, "Profile %q deleted\n", rest[0])
+	return nil
+}
+
+// maskKey keeps only the last four characters of an API key
+func maskKey(key string) string {
+	if len(key) <= 4 {
+		return strings.Repeat("*", len(key))
+	}
+	return strings.Repeat("*", len(key)-4) + key[len(key)-4:]
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,211 @@
+// Command agentctl is a command-line client for the go-agent-api server.
+//
+//	agentctl config set-profile prod -server https://agents.example.com -api-key $KEY
+//	agentctl agents apply -f agents.yaml
+//	agentctl tasks submit -agent <id> -type code_review -input-file main.go -watch
+//	agentctl tasks list -status running -o json
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/agent-learning/go-agent-api/internal/client"
+)
+
+// command is a node of the command tree. Groups have subcommands; leaves
+// have a run function that parses its own flags.
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(g *globals, path string, args []string) error
+	sub     []*command
+}
+
+// commands is the command tree. It is built in init because completion
+// walks it.
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{name: "agents", summary: "Manage agents", sub: []*command{
+			{name: "list", summary: "List agents", run: runAgentsList},
+			{name: "get", args: "ID", summary: "Show an agent", run: runAgentsGet},
+			{name: "create", summary: "Create an agent from flags or a file", run: runAgentsCreate},
+			{name: "delete", args: "ID...", summary: "Delete agents", run: runAgentsDelete},
+			{name: "apply", summary: "Create or update agents from a YAML or JSON file", run: runAgentsApply},
+		}},
+		{name: "tasks", summary: "Submit and inspect tasks", sub: []*command{
+			{name: "submit", args: "[INPUT]", summary: "Submit a task", run: runTasksSubmit},
+			{name: "list", summary: "List tasks", run: runTasksList},
+			{name: "get", args: "ID", summary: "Show a task", run: runTasksGet},
+			{name: "watch", args: "ID", summary: "Stream a task's status until it finishes", run: runTasksWatch},
+			{name: "result", args: "ID", summary: "Show the result of a finished task", run: runTasksResult},
+			{name: "cancel", args: "ID...", summary: "Cancel pending or running tasks", run: runTasksCancel},
+		}},
+		{name: "stats", summary: "Show scheduler statistics", run: runStats},
+		{name: "usage", summary: "Show task and token usage", run: runUsage},
+		{name: "config", summary: "Manage server profiles", sub: []*command{
+			{name: "view", summary: "Show profiles", run: runConfigView},
+			{name: "set-profile", args: "NAME", summary: "Create or update a profile", run: runConfigSetProfile},
+			{name: "use-profile", args: "NAME", summary: "Set the default profile", run: runConfigUseProfile},
+			{name: "delete-profile", args: "NAME", summary: "Delete a profile", run: runConfigDeleteProfile},
+		}},
+		{name: "completion", args: "bash|zsh|fish", summary: "Print a shell completion script", run: runCompletion},
+	}
+}
+
+// globals are the options shared by every command
+type globals struct {
+	configPath string
+	profile    string
+	server     string
+	apiKey     string
+	output     string
+
+	ctx    context.Context
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	g := &globals{ctx: ctx, stdout: os.Stdout, stderr: os.Stderr}
+	if err := run(g, os.Args[1:]); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+// run parses global flags and dispatches to a command
+func run(g *globals, args []string) error {
+	fs := g.flagSet("agentctl", func(w io.Writer) {
+		fmt.Fprintln(w, "Usage: agentctl [flags] <command> [subcommand] [flags] [args]")
+		fmt.Fprintln(w, "\nCommands:")
+		for _, cmd := range commands {
+			fmt.Fprintf(w, "  %-12s %s\n", cmd.name, cmd.summary)
+		}
+		fmt.Fprintln(w, "\nRun 'agentctl <command> -h' for details.")
+	})
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	args = fs.Args()
+	if len(args) == 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	cmd, path, rest, err := findCommand(g, args)
+	if err != nil {
+		return err
+	}
+	return cmd.run(g, path, rest)
+}
+
+// findCommand walks the command tree along args and returns the leaf
+// command, its full name and the remaining arguments
+func findCommand(g *globals, args []string) (*command, string, []string, error) {
+	nodes := commands
+	path := []string{"agentctl"}
+	for {
+		var next *command
+		for _, cmd := range nodes {
+			if cmd.name == args[0] {
+				next = cmd
+				break
+			}
+		}
+		if next == nil {
+			return nil, "", nil, fmt.Errorf("unknown command %q, run '%s -h'", args[0], strings.Join(path, " "))
+		}
+		path = append(path, next.name)
+		args = args[1:]
+
+		if next.run != nil {
+			return next, strings.Join(path, " "), args, nil
+		}
+		if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+			printGroupUsage(g.stderr, next, strings.Join(path, " "))
+			return nil, "", nil, flag.ErrHelp
+		}
+		nodes = next.sub
+	}
+}
+
+// printGroupUsage lists the subcommands of a group
+func printGroupUsage(w io.Writer, group *command, path string) {
+	fmt.Fprintf(w, "Usage: %s <subcommand> [flags]\n\nSubcommands:\n", path)
+	for _, cmd := range group.sub {
+		fmt.Fprintf(w, "  %-16s %s\n", strings.TrimSpace(cmd.name+" "+cmd.args), cmd.summary)
+	}
+}
+
+// flagSet creates a flag set that also accepts the global flags, so they
+// may appear before or after the command
+func (g *globals) flagSet(name string, usage func(w io.Writer)) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(g.stderr)
+	fs.StringVar(&g.configPath, "config", g.configPath, "Config file (default $AGENTCTL_CONFIG or ~/.config/agentctl/config.yaml)")
+	fs.StringVar(&g.profile, "profile", g.profile, "Profile to use (default $AGENTCTL_PROFILE or the current profile)")
+	fs.StringVar(&g.server, "server", g.server, "Server URL, overriding the profile")
+	fs.StringVar(&g.apiKey, "api-key", g.apiKey, "API key, overriding the profile")
+	fs.StringVar(&g.output, "o", g.output, "Output format: table or json")
+	fs.Usage = func() {
+		usage(g.stderr)
+		fmt.Fprintln(g.stderr, "\nFlags:")
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// leafFlags creates the flag set of a leaf command
+func (g *globals) leafFlags(path, cmdArgs string) *flag.FlagSet {
+	return g.flagSet(path, func(w io.Writer) {
+		fmt.Fprintln(w, strings.TrimSpace("Usage: "+path+" [flags] "+cmdArgs))
+	})
+}
+
+// client resolves the server and API key and creates an API client
+func (g *globals) client() (*client.Client, error) {
+	profile, err := g.resolveProfile()
+	if err != nil {
+		return nil, err
+	}
+	return client.New(profile.Server, profile.APIKey), nil
+}
+
+// parseArgs parses the flags of a leaf command, which may be mixed with
+// positional arguments, and checks the positional argument count (max < 0
+// means unbounded)
+func parseArgs(fs *flag.FlagSet, args []string, min, max int) ([]string, error) {
+	rest := make([]string, 0)
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		rest = append(rest, args[0])
+		args = args[1:]
+	}
+	if len(rest) < min || (max >= 0 && len(rest) > max) {
+		fs.Usage()
+		return nil, flag.ErrHelp
+	}
+	return rest, nil
+}
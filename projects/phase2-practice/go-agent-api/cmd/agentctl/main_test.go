@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agent-learning/go-agent-api/internal/agent"
+	"github.com/agent-learning/go-agent-api/internal/api"
+	"github.com/agent-learning/go-agent-api/internal/client"
+	"github.com/agent-learning/go-agent-api/internal/scheduler"
+	"github.com/gin-gonic/gin"
+)
+
+// startTestServer serves the API routes over an agent service and a
+// scheduler that is never started, so submitted tasks stay pending
+func startTestServer(t *testing.T) (*httptest.Server, agent.AgentService) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	service := agent.NewAgentServiceWithClient(nil)
+	router := gin.New()
+	api.SetupRoutes(router, api.Dependencies{
+		AgentService: service,
+		Scheduler:    scheduler.NewScheduler(service, 1, time.Minute),
+	})
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server, service
+}
+
+// agentctl runs the command line against a temporary config file and
+// returns its standard output
+func agentctl(t *testing.T, configPath string, args ...string) (string, error) {
+	t.Helper()
+	for _, name := range []string{"AGENTCTL_CONFIG", "AGENTCTL_PROFILE", "AGENTCTL_SERVER", "AGENTCTL_API_KEY"} {
+		t.Setenv(name, "")
+	}
+	var stdout, stderr bytes.Buffer
+	g := &globals{ctx: context.Background(), stdout: &stdout, stderr: &stderr}
+	err := run(g, append([]string{"-config", configPath}, args...))
+	return stdout.String(), err
+}
+
+func decodeOutput(t *testing.T, output string, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal([]byte(output), v); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", output, err)
+	}
+}
+
+func TestAgentsApplyTwiceChangesNothing(t *testing.T) {
+	server, service := startTestServer(t)
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	file := filepath.Join(dir, "agents.yaml")
+	bundle := `agents:
+  - name: triage
+    type: general
+    config:
+      tools: [search]
+  - name: docs
+    type: doc_qa
+`
+	if err := os.WriteFile(file, []byte(bundle), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	apply := func() agent.ImportResult {
+		t.Helper()
+		output, err := agentctl(t, configPath, "-server", server.URL, "-o", "json", "agents", "apply", "-f", file)
+		if err != nil {
+			t.Fatalf("apply failed: %v", err)
+		}
+		var result agent.ImportResult
+		decodeOutput(t, output, &result)
+		return result
+	}
+
+	if first := apply(); len(first.Created) != 2 {
+		t.Fatalf("Expected the first apply to create 2 agents, got %+v", first)
+	}
+	second := apply()
+	if len(second.Created) != 0 || len(second.Updated) != 0 || len(second.Unchanged) != 2 {
+		t.Errorf("Expected the second apply to leave both agents unchanged, got %+v", second)
+	}
+
+	agents, err := service.ListAgents(context.Background())
+	if err != nil {
+		t.Fatalf("ListAgents failed: %v", err)
+	}
+	if len(agents) != 2 {
+		t.Fatalf("Expected 2 agents, got %d", len(agents))
+	}
+	for _, ag := range agents {
+		if ag.Version != 1 {
+			t.Errorf("Expected agent %s to stay at version 1, got %d", ag.Name, ag.Version)
+		}
+	}
+
+	// A changed definition updates the agent of the same name
+	if err := os.WriteFile(file, []byte(strings.Replace(bundle, "[search]", "[search, calculator]", 1)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if third := apply(); len(third.Updated) != 1 || len(third.Unchanged) != 1 {
+		t.Errorf("Expected one updated and one unchanged agent, got %+v", third)
+	}
+}
+
+func TestTasksCommands(t *testing.T) {
+	server, service := startTestServer(t)
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	ag, err := service.CreateAgent(context.Background(), &agent.CreateAgentRequest{Name: "qa", Type: agent.AgentTypeGeneral})
+	if err != nil {
+		t.Fatalf("CreateAgent failed: %v", err)
+	}
+	if _, err := agentctl(t, configPath, "-server", server.URL, "-o", "json", "config", "set-profile", "test"); err != nil {
+		t.Fatalf("set-profile failed: %v", err)
+	}
+
+	// The saved profile supplies the server and output format
+	output, err := agentctl(t, configPath, "tasks", "submit", "-agent", ag.ID, "-metadata", "team=search", "Summarize", "the", "report")
+	if err != nil {
+		t.Fatalf("submit failed: %v", err)
+	}
+	var task agent.Task
+	decodeOutput(t, output, &task)
+	if task.ID == "" || task.Input != "Summarize the report" || task.Status != agent.TaskStatusPending {
+		t.Fatalf("Unexpected submitted task %+v", task)
+	}
+
+	output, err = agentctl(t, configPath, "tasks", "list", "-agent", ag.ID, "-metadata", "team=search")
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	var page client.TaskPage
+	decodeOutput(t, output, &page)
+	if len(page.Tasks) != 1 || page.Tasks[0].ID != task.ID {
+		t.Errorf("Expected the list to hold the submitted task, got %+v", page)
+	}
+
+	output, err = agentctl(t, configPath, "-o", "table", "tasks", "cancel", task.ID)
+	if err != nil {
+		t.Fatalf("cancel failed: %v", err)
+	}
+	if !strings.Contains(output, "Task "+task.ID+" cancelled") {
+		t.Errorf("Unexpected cancel output %q", output)
+	}
+
+	output, err = agentctl(t, configPath, "tasks", "get", task.ID)
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	decodeOutput(t, output, &task)
+	if task.Status != agent.TaskStatusCancelled {
+		t.Errorf("Expected the task to be cancelled, got %s", task.Status)
+	}
+
+	if _, err := agentctl(t, configPath, "tasks", "get", "missing"); err == nil {
+		t.Error("Expected an error for an unknown task")
+	}
+	if _, err := agentctl(t, configPath, "tasks", "submit", "Summarize"); err == nil {
+		t.Error("Expected an error without -agent")
+	}
+}
+
+func TestConfigProfilesSaveAndLoad(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "agentctl", "config.yaml")
+
+	if _, err := agentctl(t, configPath, "-server", "https://prod.example.com/", "-api-key", "prod-secret-1234", "config", "set-profile", "prod"); err != nil {
+		t.Fatalf("set-profile prod failed: %v", err)
+	}
+	if _, err := agentctl(t, configPath, "-server", "http://localhost:9090", "-o", "json", "config", "set-profile", "dev"); err != nil {
+		t.Fatalf("set-profile dev failed: %v", err)
+	}
+
+	info, err := os.Stat(configPath)
+	if err != nil {
+		t.Fatalf("config not written: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("Expected config mode 0600, got %o", perm)
+	}
+
+	g := &globals{configPath: configPath}
+	config, err := g.loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+	// The first profile becomes current; trailing slashes are trimmed
+	if config.CurrentProfile != "prod" || config.Profiles["prod"].Server != "https://prod.example.com" ||
+		config.Profiles["prod"].APIKey != "prod-secret-1234" || config.Profiles["dev"].Output != formatJSON {
+		t.Errorf("Unexpected saved config %+v", config)
+	}
+
+	output, err := agentctl(t, configPath, "config", "view")
+	if err != nil {
+		t.Fatalf("view failed: %v", err)
+	}
+	if strings.Contains(output, "prod-secret-1234") || !strings.Contains(output, "************1234") {
+		t.Errorf("Expected view to mask the API key, got %q", output)
+	}
+
+	if _, err := agentctl(t, configPath, "config", "use-profile", "dev"); err != nil {
+		t.Fatalf("use-profile failed: %v", err)
+	}
+	profile, err := g.resolveProfile()
+	if err != nil {
+		t.Fatalf("resolveProfile failed: %v", err)
+	}
+	if profile.Server != "http://localhost:9090" || profile.Output != formatJSON {
+		t.Errorf("Expected the dev profile, got %+v", profile)
+	}
+
+	// Flags override the profile
+	g.server = "http://override:8080"
+	if profile, _ := g.resolveProfile(); profile.Server != "http://override:8080" {
+		t.Errorf("Expected -server to override the profile, got %s", profile.Server)
+	}
+
+	if _, err := agentctl(t, configPath, "config", "delete-profile", "dev"); err != nil {
+		t.Fatalf("delete-profile failed: %v", err)
+	}
+	if config, _ = g.loadConfig(); config.CurrentProfile != "" || config.Profiles["dev"] != nil {
+		t.Errorf("Expected dev to be deleted and unset as current, got %+v", config)
+	}
+	if _, err := agentctl(t, configPath, "config", "use-profile", "dev"); err == nil {
+		t.Error("Expected an error for a deleted profile")
+	}
+}
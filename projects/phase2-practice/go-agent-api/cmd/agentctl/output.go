@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Output formats
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+// table writes aligned columns
+type table struct {
+	w *tabwriter.Writer
+}
+
+func newTable(w io.Writer) *table {
+	return &table{w: tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)}
+}
+
+// row writes one line; values are formatted with %v
+func (t *table) row(values ...interface{}) {
+	cells := make([]string, len(values))
+	for i, value := range values {
+		cells[i] = cell(value)
+	}
+	fmt.Fprintln(t.w, strings.Join(cells, "\t"))
+}
+
+func (t *table) flush() error {
+	return t.w.Flush()
+}
+
+// format returns the output format selected by flag or profile
+func (g *globals) format() (string, error) {
+	format := g.output
+	if format == "" {
+		if profile, err := g.resolveProfile(); err == nil {
+			format = profile.Output
+		}
+	}
+	switch format {
+	case "", formatTable:
+		return formatTable, nil
+	case formatJSON:
+		return formatJSON, nil
+	default:
+		return "", fmt.Errorf("invalid output format %q, use table or json", format)
+	}
+}
+
+// render prints v as indented JSON, or as a table built by fill
+func (g *globals) render(v interface{}, fill func(t *table)) error {
+	format, err := g.format()
+	if err != nil {
+		return err
+	}
+	if format == formatJSON {
+		encoder := json.NewEncoder(g.stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	}
+
+	t := newTable(g.stdout)
+	fill(t)
+	return t.flush()
+}
+
+// cell formats a table value, keeping cells on one line
+func cell(value interface{}) string {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		return "-"
+	case time.Time:
+		if v.IsZero() {
+			return "-"
+		}
+		return v.Local().Format("2006-01-02 15:04:05")
+	case *time.Time:
+		if v == nil {
+			return "-"
+		}
+		return cell(*v)
+	case string:
+		s = v
+	default:
+		s = fmt.Sprint(v)
+	}
+	if s == "" {
+		return "-"
+	}
+	return truncate(strings.Join(strings.Fields(s), " "), 60)
+}
+
+// truncate shortens s to at most n runes
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-3]) + "..."
+}
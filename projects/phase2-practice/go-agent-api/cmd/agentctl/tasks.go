@@ -0,0 +1,392 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agent-learning/go-agent-api/internal/agent"
+	"github.com/agent-learning/go-agent-api/internal/client"
+	"github.com/agent-learning/go-agent-api/internal/scheduler"
+)
+
+// keyValues collects repeatable key=value flags
+type keyValues map[string]string
+
+func (kv keyValues) String() string {
+	pairs := make([]string, 0, len(kv))
+	for k, v := range kv {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (kv keyValues) Set(value string) error {
+	key, val, found := strings.Cut(value, "=")
+	if !found || key == "" {
+		return fmt.Errorf("must be key=value")
+	}
+	kv[key] = val
+	return nil
+}
+
+func runTasksSubmit(g *globals, path string, args []string) error {
+	fs := g.leafFlags(path, "[INPUT]")
+	agentID := fs.String("agent", "", "Agent ID (required)")
+	taskType := fs.String("type", string(agent.TaskTypeQuery), "Task type")
+	inputFile := fs.String("input-file", "", "Read the input from a file (- for stdin) instead of the arguments")
+	priority := fs.Int("priority", 0, "Priority; higher runs first")
+	tools := fs.String("tools", "", "Comma-separated tool names")
+	schemaFile := fs.String("schema", "", "JSON Schema file the output must satisfy")
+	runAt := fs.String("run-at", "", "Run at an RFC3339 time instead of now")
+	callback := fs.String("callback-url", "", "Webhook called when the task finishes")
+	idempotencyKey := fs.String("idempotency-key", "", "Key that makes retrying this submission safe")
+	watch := fs.Bool("watch", false, "Stream the task's status until it finishes")
+	metadata := keyValues{}
+	fs.Var(metadata, "metadata", "Metadata as key=value (repeatable)")
+	rest, err := parseArgs(fs, args, 0, -1)
+	if err != nil {
+		return err
+	}
+	if *agentID == "" {
+		return fmt.Errorf("-agent is required")
+	}
+
+	req := &agent.CreateTaskRequest{
+		AgentID:     *agentID,
+		Type:        agent.TaskType(*taskType),
+		Input:       strings.Join(rest, " "),
+		Priority:    *priority,
+		Tools:       splitList(*tools),
+		CallbackURL: *callback,
+	}
+	if *inputFile != "" {
+		data, err := readInput(*inputFile)
+		if err != nil {
+			return err
+		}
+		req.Input = string(data)
+	}
+	if req.Input == "" {
+		return fmt.Errorf("input is required, pass it as arguments or with -input-file")
+	}
+	if len(metadata) > 0 {
+		req.Metadata = make(map[string]interface{}, len(metadata))
+		for k, v := range metadata {
+			req.Metadata[k] = v
+		}
+	}
+	if *schemaFile != "" {
+		data, err := readInput(*schemaFile)
+		if err != nil {
+			return err
+		}
+		if !json.Valid(data) {
+			return fmt.Errorf("%s is not valid JSON", *schemaFile)
+		}
+		req.OutputSchema = data
+	}
+	if *runAt != "" {
+		t, err := time.Parse(time.RFC3339, *runAt)
+		if err != nil {
+			return fmt.Errorf("invalid -run-at: must be RFC3339")
+		}
+		req.RunAt = &t
+	}
+
+	c, err := g.client()
+	if err != nil {
+		return err
+	}
+	task, err := c.SubmitTask(g.ctx, req, *idempotencyKey)
+	if err != nil {
+		return err
+	}
+	if *watch {
+		return watchTask(g, c, task.ID)
+	}
+	return g.render(task, func(t *table) {
+		t.row("Task", task.ID, task.Status)
+	})
+}
+
+func runTasksList(g *globals, path string, args []string) error {
+	fs := g.leafFlags(path, "")
+	agentID := fs.String("agent", "", "Only tasks of this agent")
+	status := fs.String("status", "", "Comma-separated statuses")
+	taskType := fs.String("type", "", "Comma-separated task types")
+	search := fs.String("q", "", "Words that must appear in the input or output")
+	limit := fs.Int("limit", 0, "Page size (server default 50)")
+	cursor := fs.String("cursor", "", "Continue from a previous page")
+	all := fs.Bool("all", false, "Fetch every page")
+	metadata := keyValues{}
+	fs.Var(metadata, "metadata", "Metadata filter as key=value (repeatable)")
+	if _, err := parseArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	for name, value := range map[string]string{"agent_id": *agentID, "status": *status, "type": *taskType, "q": *search} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	for k, v := range metadata {
+		query.Add("metadata", k+":"+v)
+	}
+	if *limit > 0 {
+		query.Set("limit", strconv.Itoa(*limit))
+	}
+
+	c, err := g.client()
+	if err != nil {
+		return err
+	}
+	page := &client.TaskPage{NextCursor: *cursor}
+	tasks := make([]*agent.Task, 0)
+	for {
+		if page.NextCursor != "" {
+			query.Set("cursor", page.NextCursor)
+		}
+		if page, err = c.ListTasks(g.ctx, query); err != nil {
+			return err
+		}
+		tasks = append(tasks, page.Tasks...)
+		if !*all || page.NextCursor == "" {
+			break
+		}
+	}
+	page.Tasks, page.Total = tasks, len(tasks)
+
+	return g.render(page, func(t *table) {
+		t.row("ID", "AGENT", "TYPE", "STATUS", "PRIORITY", "CREATED", "INPUT")
+		for _, task := range tasks {
+			t.row(task.ID, task.AgentID, task.Type, task.Status, task.Priority, task.CreatedAt, task.Input)
+		}
+		if page.NextCursor != "" {
+			t.row("")
+			t.row("More tasks: -cursor " + page.NextCursor)
+		}
+	})
+}
+
+func runTasksGet(g *globals, path string, args []string) error {
+	fs := g.leafFlags(path, "ID")
+	rest, err := parseArgs(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	c, err := g.client()
+	if err != nil {
+		return err
+	}
+
+	task, err := c.GetTask(g.ctx, rest[0])
+	if err != nil {
+		return err
+	}
+	return g.render(task, func(t *table) {
+		t.row("ID:", task.ID)
+		t.row("Agent:", task.AgentID)
+		t.row("Type:", task.Type)
+		t.row("Status:", task.Status)
+		t.row("Priority:", task.Priority)
+		t.row("Input:", task.Input)
+		t.row("Output:", task.Output)
+		t.row("Error:", task.Error)
+		t.row("Created:", task.CreatedAt)
+		t.row("Started:", task.StartedAt)
+		t.row("Ended:", task.EndedAt)
+	})
+}
+
+func runTasksWatch(g *globals, path string, args []string) error {
+	fs := g.leafFlags(path, "ID")
+	rest, err := parseArgs(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	c, err := g.client()
+	if err != nil {
+		return err
+	}
+	return watchTask(g, c, rest[0])
+}
+
+// watchTask streams a task until it finishes. JSON output writes one event
+// per line. It fails unless the task completes, so scripts can rely on the
+// exit status.
+func watchTask(g *globals, c *client.Client, id string) error {
+	format, err := g.format()
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(g.stdout)
+
+	var final *agent.TaskResult
+	err = c.WatchTask(g.ctx, id, func(event client.TaskEvent) error {
+		if event.Result != nil {
+			final = event.Result
+		}
+		if format == formatJSON {
+			if event.Task != nil {
+				return encoder.Encode(map[string]interface{}{"event": "status", "task": event.Task})
+			}
+			return encoder.Encode(map[string]interface{}{"event": "result", "result": event.Result})
+		}
+
+		if event.Task != nil {
+			fmt.Fprintf(g.stdout, "%s  %s  %s\n", time.Now().Format("15:04:05"), event.Task.ID, event.Task.Status)
+			return nil
+		}
+		fmt.Fprintf(g.stdout, "\n%s in %dms\n", event.Result.Status, event.Result.Duration)
+		if event.Result.Output != "" {
+			fmt.Fprintf(g.stdout, "\n%s\n", event.Result.Output)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if final != nil && final.Status != agent.TaskStatusCompleted {
+		return fmt.Errorf("task %s %s: %s", id, final.Status, final.Error)
+	}
+	return nil
+}
+
+func runTasksResult(g *globals, path string, args []string) error {
+	fs := g.leafFlags(path, "ID")
+	rest, err := parseArgs(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	c, err := g.client()
+	if err != nil {
+		return err
+	}
+
+	result, err := c.GetTaskResult(g.ctx, rest[0])
+	if err != nil {
+		return err
+	}
+	format, err := g.format()
+	if err != nil {
+		return err
+	}
+	if format == formatJSON {
+		return g.render(result, nil)
+	}
+
+	// The output is printed as-is so it can be piped
+	fmt.Fprintf(g.stderr, "%s in %dms\n", result.Status, result.Duration)
+	if result.Error != "" {
+		fmt.Fprintln(g.stderr, "Error:", result.Error)
+	}
+	if result.StructuredOutput != nil {
+		encoder := json.NewEncoder(g.stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result.StructuredOutput)
+	}
+	fmt.Fprintln(g.stdout, result.Output)
+	return nil
+}
+
+func runTasksCancel(g *globals, path string, args []string) error {
+	fs := g.leafFlags(path, "ID...")
+	rest, err := parseArgs(fs, args, 1, -1)
+	if err != nil {
+		return err
+	}
+	c, err := g.client()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range rest {
+		if err := c.CancelTask(g.ctx, id); err != nil {
+			return fmt.Errorf("task %s: %w", id, err)
+		}
+		fmt.Fprintf(g.stdout, "Task %s cancelled\n", id)
+	}
+	return nil
+}
+
+func runStats(g *globals, path string, args []string) error {
+	fs := g.leafFlags(path, "")
+	if _, err := parseArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+	c, err := g.client()
+	if err != nil {
+		return err
+	}
+
+	stats, err := c.Stats(g.ctx)
+	if err != nil {
+		return err
+	}
+	return g.render(stats, func(t *table) {
+		keys := make([]string, 0, len(stats))
+		for key := range stats {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value := stats[key]
+			// Nested counts such as running_by_agent are printed compactly
+			if nested, ok := value.(map[string]interface{}); ok {
+				data, _ := json.Marshal(nested)
+				value = string(data)
+			}
+			t.row(strings.ReplaceAll(key, "_", " ")+":", value)
+		}
+	})
+}
+
+func runUsage(g *globals, path string, args []string) error {
+	fs := g.leafFlags(path, "")
+	if _, err := parseArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+	c, err := g.client()
+	if err != nil {
+		return err
+	}
+
+	usage, err := c.Usage(g.ctx)
+	if err != nil {
+		return err
+	}
+	return g.render(usage, func(t *table) {
+		t.row("SCOPE", "KEY", "TASKS", "COMPLETED", "FAILED", "CANCELLED", "TOKENS", "AVG MS")
+		row := func(scope, key string, u *scheduler.UsageTotals) {
+			var avg int64
+			if u.Tasks > 0 {
+				avg = u.DurationMs / int64(u.Tasks)
+			}
+			t.row(scope, key, u.Tasks, u.Completed, u.Failed, u.Cancelled, u.TokensUsed, avg)
+		}
+		row("total", "since "+cell(usage.Since), &usage.Total)
+		for _, key := range sortedKeys(usage.ByAgent) {
+			row("agent", key, usage.ByAgent[key])
+		}
+		for _, key := range sortedKeys(usage.BySubmitter) {
+			row("api key", key, usage.BySubmitter[key])
+		}
+	})
+}
+
+func sortedKeys(m map[string]*scheduler.UsageTotals) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
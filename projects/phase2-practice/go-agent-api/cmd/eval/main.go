@@ -0,0 +1,118 @@
+// Command eval runs a golden dataset against one or two agent configs and
+// prints a side-by-side report.
+//
+//	go run ./cmd/eval -dataset examples/eval/dataset.jsonl \
+//	    -baseline examples/eval/baseline.json -candidate examples/eval/candidate.json \
+//	    -script examples/eval/script.json
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/agent-learning/go-agent-api/internal/agent"
+	"github.com/agent-learning/go-agent-api/internal/eval"
+	"github.com/sashabaranov/go-openai"
+)
+
+func main() {
+	datasetPath := flag.String("dataset", "", "JSONL dataset of cases (required)")
+	baselinePath := flag.String("baseline", "", "JSON agent config for the baseline (required)")
+	candidatePath := flag.String("candidate", "", "JSON agent config to compare against the baseline")
+	scriptPath := flag.String("script", "", "JSON script for the offline scripted provider; uses OpenAI when empty")
+	judgeModel := flag.String("judge-model", "gpt-4", "Model used by the llm_judge grader")
+	format := flag.String("format", "text", "Report format: text or json")
+	failOnRegression := flag.Bool("fail-on-regression", false, "Exit with status 1 if the candidate regresses any case")
+	flag.Parse()
+
+	if *datasetPath == "" || *baselinePath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cases, err := eval.LoadDatasetFile(*datasetPath)
+	if err != nil {
+		log.Fatalf("Failed to load dataset: %v", err)
+	}
+
+	client, err := newLLMClient(*scriptPath)
+	if err != nil {
+		log.Fatalf("Failed to create LLM client: %v", err)
+	}
+
+	ctx := context.Background()
+	service := agent.NewAgentServiceWithClient(client)
+
+	baseline, err := createAgent(ctx, service, *baselinePath)
+	if err != nil {
+		log.Fatalf("Failed to create baseline agent: %v", err)
+	}
+
+	var candidate *agent.Agent
+	if *candidatePath != "" {
+		if candidate, err = createAgent(ctx, service, *candidatePath); err != nil {
+			log.Fatalf("Failed to create candidate agent: %v", err)
+		}
+	}
+
+	runner := eval.NewRunner(service, eval.NewJudgeScorer(client, *judgeModel))
+	report, err := runner.Compare(ctx, baseline, candidate, cases)
+	if err != nil {
+		log.Fatalf("Evaluation failed: %v", err)
+	}
+
+	switch *format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	default:
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		log.Fatalf("Failed to write report: %v", err)
+	}
+
+	if *failOnRegression && report.Regressions > 0 {
+		os.Exit(1)
+	}
+}
+
+// newLLMClient returns the scripted provider when a script is given,
+// otherwise an OpenAI client using OPENAI_API_KEY
+func newLLMClient(scriptPath string) (agent.LLMClient, error) {
+	if scriptPath != "" {
+		return agent.LoadScriptedClient(scriptPath)
+	}
+
+	apiKey := os.Getenv("OPENAI_API_KEY")
+	if apiKey == "" {
+		return nil, fmt.Errorf("OPENAI_API_KEY is required without -script")
+	}
+	return openai.NewClient(apiKey), nil
+}
+
+// createAgent creates an agent from a CreateAgentRequest JSON file
+func createAgent(ctx context.Context, service agent.AgentService, path string) (*agent.Agent, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read agent config: %w", err)
+	}
+
+	var req agent.CreateAgentRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, fmt.Errorf("failed to parse agent config: %w", err)
+	}
+	if req.Name == "" {
+		req.Name = path
+	}
+	if req.Type == "" {
+		req.Type = agent.AgentTypeGeneral
+	}
+
+	return service.CreateAgent(ctx, &req)
+}
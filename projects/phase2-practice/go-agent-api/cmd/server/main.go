@@ -1,306 +1,322 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
 	"time"
 
 	"github.com/agent-learning/go-agent-api/internal/agent"
+	"github.com/agent-learning/go-agent-api/internal/api"
+	"github.com/agent-learning/go-agent-api/internal/api/middleware"
+	"github.com/agent-learning/go-agent-api/internal/cache"
+	"github.com/agent-learning/go-agent-api/internal/config"
+	"github.com/agent-learning/go-agent-api/internal/database"
+	"github.com/agent-learning/go-agent-api/internal/eval"
+	"github.com/agent-learning/go-agent-api/internal/guardrails"
 	"github.com/agent-learning/go-agent-api/internal/scheduler"
-	"github.com/google/uuid"
+	"github.com/agent-learning/go-agent-api/internal/state"
+	"github.com/agent-learning/go-agent-api/internal/tools"
+	"github.com/agent-learning/go-agent-api/internal/transcript"
+	"github.com/agent-learning/go-agent-api/internal/webhook"
+	"github.com/gin-gonic/gin"
+	"github.com/sashabaranov/go-openai"
 )
 
 func main() {
-	fmt.Println("🎯 TaskQueue 测试 Demo")
-	fmt.Println("=" + repeat("=", 70))
-	fmt.Println()
+	configFlags := config.RegisterFlags(flag.CommandLine)
+	flag.Parse()
 
-	// 测试 1: 基础队列操作
-	demo1BasicQueueOperations()
-
-	// 测试 2: 优先级队列
-	demo2PriorityQueue()
-
-	// 测试 3: 并发安全测试
-	demo3ConcurrentSafety()
-
-	fmt.Println()
-	fmt.Println("✅ 所有测试完成!")
-}
-
-// 测试1: 基础队列操作
-func demo1BasicQueueOperations() {
-	fmt.Println("📋 测试 1: 基础队列操作")
-	fmt.Println("-" + repeat("-", 70))
+	cfg, err := config.Load(configFlags)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
 
-	// 创建队列
-	queue := scheduler.NewTaskQueue()
-	fmt.Printf("✓ 创建队列成功，当前大小: %d\n", queue.Size())
+	gin.SetMode(cfg.Server.GinMode)
 
-	// 添加任务
-	tasks := []*agent.Task{
-		createTask("task-1", "第一个任务", 1),
-		createTask("task-2", "第二个任务", 1),
-		createTask("task-3", "第三个任务", 1),
+	// Postgres is opened once and shared by the stores that use it
+	var db *database.PostgresDB
+	if cfg.Storage.AgentStore == "postgres" || cfg.Storage.TaskStore == "postgres" {
+		db, err = openPostgres(cfg)
+		if err != nil {
+			log.Fatalf("Failed to connect to Postgres: %v", err)
+		}
+		defer db.Close()
 	}
 
-	fmt.Println("\n📥 添加任务到队列:")
-	for _, task := range tasks {
-		queue.Enqueue(task)
-		fmt.Printf("  ✓ 添加任务: %s (优先级: %d)\n", task.ID, task.Priority)
+	// Agents are loaded from and written through to the configured store
+	registry, err := newAgentRegistry(cfg, db)
+	if err != nil {
+		log.Fatalf("Failed to load agents: %v", err)
 	}
-	fmt.Printf("队列大小: %d\n", queue.Size())
-
-	// 查看堆顶
-	peek := queue.Peek()
-	if peek != nil {
-		fmt.Printf("\n👀 查看堆顶任务: %s\n", peek.ID)
+	log.Printf("Loaded %d agents from %s store", registry.Count(), cfg.Storage.AgentStore)
+
+	// Core services
+	llmClient := openai.NewClient(cfg.OpenAI.APIKey)
+	defaults := agent.NewDefaults(agentDefaults(cfg))
+	agentOpts := []agent.Option{agent.WithRegistry(registry), agent.WithDefaults(defaults)}
+	if cfg.Cache.Enabled {
+		agentOpts = append(agentOpts, agent.WithResponseCache(newResponseCache(cfg, llmClient)))
 	}
-
-	// 出队
-	fmt.Println("\n📤 从队列取出任务:")
-	for queue.Size() > 0 {
-		task := queue.Dequeue()
-		if task != nil {
-			fmt.Printf("  ✓ 取出任务: %s (优先级: %d)\n", task.ID, task.Priority)
+	toolExecutor := tools.NewToolExecutor(newToolRegistry())
+	if cfg.Guardrails.Enabled {
+		pipeline, err := guardrails.New(guardrails.Config{
+			PIIAction:       guardrails.Action(cfg.Guardrails.PIIAction),
+			PIITypes:        cfg.Guardrails.PIITypes,
+			InjectionAction: guardrails.Action(cfg.Guardrails.InjectionAction),
+			OutputBlocklist: cfg.Guardrails.OutputBlocklist,
+			OutputAction:    guardrails.Action(cfg.Guardrails.OutputAction),
+		})
+		if err != nil {
+			log.Fatalf("Invalid guardrails config: %v", err)
 		}
+		agentOpts = append(agentOpts, agent.WithGuardrails(pipeline))
+		toolExecutor.SetGuardrails(pipeline)
 	}
-	fmt.Printf("队列大小: %d\n", queue.Size())
-
-	fmt.Println()
-}
-
-// 测试2: 优先级队列
-func demo2PriorityQueue() {
-	fmt.Println("🎯 测试 2: 优先级调度")
-	fmt.Println("-" + repeat("-", 70))
-
-	queue := scheduler.NewTaskQueue()
-
-	// 创建不同优先级的任务
-	tasks := []*agent.Task{
-		createTask("low-1", "低优先级任务1", 1),
-		createTask("high-1", "高优先级任务1", 10),
-		createTask("medium-1", "中优先级任务1", 5),
-		createTask("low-2", "低优先级任务2", 1),
-		createTask("high-2", "高优先级任务2", 10),
-		createTask("medium-2", "中优先级任务2", 5),
+	agentOpts = append(agentOpts, agent.WithToolExecutor(toolExecutor))
+	var transcripts transcript.Store
+	if cfg.Transcripts.Enabled {
+		transcripts, err = newTranscriptStore(cfg)
+		if err != nil {
+			log.Fatalf("Failed to open transcript store: %v", err)
+		}
+		agentOpts = append(agentOpts, agent.WithTranscripts(transcripts))
 	}
-
-	// 乱序添加
-	fmt.Println("\n📥 按乱序添加任务:")
-	for _, task := range tasks {
-		queue.Enqueue(task)
-		fmt.Printf("  添加: %-15s 优先级: %2d\n", task.ID, task.Priority)
+	agentService := agent.NewAgentServiceWithClient(llmClient, agentOpts...)
+	sched := scheduler.NewScheduler(
+		agentService,
+		cfg.Agent.MaxConcurrent,
+		time.Duration(cfg.Agent.TaskTimeout)*time.Second,
+	)
+	taskStore, err := newTaskStore(cfg, db)
+	if err != nil {
+		log.Fatalf("Failed to open task store: %v", err)
 	}
-
-	fmt.Println("\n📤 按优先级顺序取出:")
-	order := 1
-	for queue.Size() > 0 {
-		task := queue.Dequeue()
-		if task != nil {
-			fmt.Printf("  %d. %-15s 优先级: %2d (%s)\n",
-				order, task.ID, task.Priority, task.Input)
-			order++
+	sched.SetTaskStore(taskStore)
+	sched.SetPriorityAging(time.Duration(cfg.Agent.PriorityAging) * time.Second)
+	sched.SetIdempotencyTTL(time.Duration(cfg.Agent.IdempotencyTTL) * time.Second)
+	for apiKey, weight := range cfg.Agent.APIKeyWeights {
+		w, err := strconv.ParseFloat(weight, 64)
+		if err != nil || w <= 0 {
+			log.Fatalf("Invalid scheduler weight %q for API key", weight)
 		}
+		sched.SetSubmitterWeight(middleware.SubmitterID(apiKey), w)
 	}
 
-	fmt.Println("\n💡 观察: 高优先级任务(10)优先执行，其次是中优先级(5)，最后是低优先级(1)")
-	fmt.Println()
-}
-
-// 测试3: 并发安全
-func demo3ConcurrentSafety() {
-	fmt.Println("🔒 测试 3: 并发安全性")
-	fmt.Println("-" + repeat("-", 70))
-
-	queue := scheduler.NewTaskQueue()
+	// Webhook callbacks on task completion
+	webhooks := webhook.NewDispatcher(webhook.Config{
+		Secret:      cfg.Webhook.Secret,
+		MaxAttempts: cfg.Webhook.MaxAttempts,
+		Timeout:     time.Duration(cfg.Webhook.Timeout) * time.Second,
+	}, agentService)
+	for apiKey, url := range cfg.Webhook.APIKeyDefaults {
+		webhooks.SetSubmitterDefault(middleware.SubmitterID(apiKey), url)
+	}
+	sched.AddNotifier(webhooks)
 
-	// 模拟多个goroutine并发操作
-	numGoroutines := 10
-	tasksPerGoroutine := 10
+	// Batches track their tasks through completion notifications
+	batches := scheduler.NewBatchManager(sched)
+	sched.AddNotifier(batches)
 
-	fmt.Printf("\n🚀 启动 %d 个goroutine，每个添加 %d 个任务\n",
-		numGoroutines, tasksPerGoroutine)
+	// Usage is accumulated from completion notifications
+	usage := scheduler.NewUsageMeter()
+	sched.AddNotifier(usage)
 
-	// 启动多个生产者
-	done := make(chan bool, numGoroutines)
-	for i := 0; i < numGoroutines; i++ {
-		go func(id int) {
-			for j := 0; j < tasksPerGoroutine; j++ {
-				task := createTask(
-					fmt.Sprintf("g%d-task%d", id, j),
-					fmt.Sprintf("Goroutine %d 的任务 %d", id, j),
-					(id+j)%5+1, // 优先级 1-5
-				)
-				queue.Enqueue(task)
-				time.Sleep(time.Millisecond) // 模拟一些延迟
-			}
-			done <- true
-		}(i)
+	// Delayed tasks and recurring schedules survive restarts via the file store
+	scheduleStore, err := scheduler.NewFileScheduleStore(filepath.Join(cfg.Storage.DataDir, "schedules"))
+	if err != nil {
+		log.Fatalf("Failed to open schedule store: %v", err)
 	}
-
-	// 等待所有生产者完成
-	for i := 0; i < numGoroutines; i++ {
-		<-done
+	sched.SetDelayedStore(scheduleStore)
+	if err := sched.RestoreDelayed(); err != nil {
+		log.Fatalf("Failed to restore delayed tasks: %v", err)
 	}
+	schedules := scheduler.NewScheduleManager(sched, scheduleStore)
 
-	expectedTotal := numGoroutines * tasksPerGoroutine
-	actualTotal := queue.Size()
-
-	fmt.Printf("\n📊 统计:")
-	fmt.Printf("\n  预期任务数: %d", expectedTotal)
-	fmt.Printf("\n  实际任务数: %d", actualTotal)
-
-	if expectedTotal == actualTotal {
-		fmt.Println("\n  ✅ 并发安全测试通过！没有数据丢失或竞争")
-	} else {
-		fmt.Println("\n  ❌ 检测到数据不一致")
+	sched.Start()
+	if err := schedules.Start(); err != nil {
+		log.Fatalf("Failed to start schedules: %v", err)
 	}
 
-	// 测试并发读取
-	fmt.Println("\n🔍 测试并发读取:")
-	readers := 5
-	readDone := make(chan bool, readers)
-
-	for i := 0; i < readers; i++ {
-		go func(id int) {
-			// 多次读取队列大小和peek
-			for j := 0; j < 10; j++ {
-				_ = queue.Size()
-				_ = queue.Peek()
-				time.Sleep(time.Millisecond)
-			}
-			readDone <- true
-		}(i)
+	// Safe settings are applied when the config file changes
+	var watcher *config.Watcher
+	if cfg.File != "" {
+		watcher, err = config.NewWatcher(cfg, configFlags, func(next *config.Config) {
+			applyConfig(next, sched, defaults)
+		})
+		if err != nil {
+			log.Fatalf("Failed to watch config file: %v", err)
+		}
+		watcher.Start()
 	}
 
-	// 等待所有读者完成
-	for i := 0; i < readers; i++ {
-		<-readDone
+	router := gin.New()
+	api.SetupRoutes(router, api.Dependencies{
+		AgentService: agentService,
+		Scheduler:    sched,
+		Webhooks:     webhooks,
+		Schedules:    schedules,
+		Batches:      batches,
+		Evals:        eval.NewRunner(agentService, eval.NewJudgeScorer(llmClient, cfg.OpenAI.Model)),
+		Transcripts:  transcripts,
+		Usage:        usage,
+	})
+
+	server := &http.Server{
+		Addr:    ":" + cfg.Server.Port,
+		Handler: router,
 	}
 
-	fmt.Println("  ✅ 并发读取测试通过！")
-
-	// 清空队列
-	fmt.Printf("\n🧹 清空队列 (%d 个任务)...\n", queue.Size())
-	count := 0
-	for queue.Size() > 0 {
-		queue.Dequeue()
-		count++
+	go func() {
+		log.Printf("Server listening on :%s", cfg.Server.Port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server error: %v", err)
+		}
+	}()
+
+	// Wait for interrupt signal
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	log.Println("Shutting down server...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Server forced to shutdown: %v", err)
 	}
-	fmt.Printf("  ✓ 已移除 %d 个任务\n", count)
 
-	fmt.Println()
+	if watcher != nil {
+		watcher.Stop()
+	}
+	schedules.Stop()
+	sched.Stop()
+	webhooks.Stop()
+	log.Println("Server exited")
 }
 
-// 辅助函数：创建测试任务
-func createTask(id, input string, priority int) *agent.Task {
-	return &agent.Task{
-		ID:        id,
-		AgentID:   uuid.New().String(),
-		Type:      agent.TaskTypeQuery,
-		Input:     input,
-		Status:    agent.TaskStatusPending,
-		Priority:  priority,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+// agentDefaults converts the configured defaults for new agents
+func agentDefaults(cfg *config.Config) agent.DefaultsConfig {
+	return agent.DefaultsConfig{
+		Model:        cfg.Agent.Defaults.Model,
+		Temperature:  float32(cfg.Agent.Defaults.Temperature),
+		MaxTokens:    cfg.Agent.Defaults.MaxTokens,
+		AllowedTools: cfg.Agent.AllowedTools,
 	}
 }
 
-// 辅助函数：重复字符串
-func repeat(s string, count int) string {
-	result := ""
-	for i := 0; i < count; i++ {
-		result += s
-	}
-	return result
+// applyConfig applies the settings a config reload may change
+func applyConfig(cfg *config.Config, sched *scheduler.Scheduler, defaults *agent.Defaults) {
+	sched.SetMaxConcurrent(cfg.Agent.MaxConcurrent)
+	sched.SetTaskTimeout(time.Duration(cfg.Agent.TaskTimeout) * time.Second)
+	sched.SetPriorityAging(time.Duration(cfg.Agent.PriorityAging) * time.Second)
+	defaults.Set(agentDefaults(cfg))
 }
 
-// 可视化堆结构（额外功能）
-func visualizeQueue(queue *scheduler.TaskQueue) {
-	tasks := queue.List()
-	if len(tasks) == 0 {
-		fmt.Println("  (空队列)")
-		return
+// openPostgres connects to Postgres and initializes the schema
+func openPostgres(cfg *config.Config) (*database.PostgresDB, error) {
+	db, err := database.NewPostgresDB(cfg.Postgres.GetDSN())
+	if err != nil {
+		return nil, err
 	}
-
-	fmt.Println("\n  堆结构可视化:")
-	fmt.Println("  " + repeat("-", 40))
-
-	// 简单的树形展示（仅显示前几层）
-	levels := [][]int{
-		{0},           // 第0层：根节点
-		{1, 2},        // 第1层：2个节点
-		{3, 4, 5, 6},  // 第2层：4个节点
+	if err := db.InitSchema(); err != nil {
+		db.Close()
+		return nil, err
 	}
+	return db, nil
+}
 
-	for levelNum, level := range levels {
-		indent := repeat("  ", 3-levelNum)
-		fmt.Print(indent)
-
-		for _, idx := range level {
-			if idx < len(tasks) {
-				task := tasks[idx]
-				fmt.Printf("[%s:P%d] ", task.ID[:6], task.Priority)
-			}
+// newAgentRegistry builds the agent registry for the configured store. db is
+// only used by the postgres store.
+func newAgentRegistry(cfg *config.Config, db *database.PostgresDB) (*agent.AgentRegistry, error) {
+	switch cfg.Storage.AgentStore {
+	case "memory":
+		return agent.NewAgentRegistry(), nil
+	case "file":
+		repo, err := agent.NewFileRepository(filepath.Join(cfg.Storage.DataDir, "agents"))
+		if err != nil {
+			return nil, err
 		}
-		fmt.Println()
+		return agent.NewAgentRegistryWithRepository(repo)
+	case "postgres":
+		return agent.NewAgentRegistryWithRepository(agent.NewPostgresRepository(db))
+	default:
+		return nil, fmt.Errorf("unknown AGENT_STORE %q", cfg.Storage.AgentStore)
 	}
+}
 
-	if len(tasks) > 7 {
-		fmt.Printf("  ... 还有 %d 个任务\n", len(tasks)-7)
+// newTaskStore builds the task history store. Postgres task rows reference
+// the agents table, so they require agents to live in Postgres too.
+func newTaskStore(cfg *config.Config, db *database.PostgresDB) (scheduler.TaskStore, error) {
+	switch cfg.Storage.TaskStore {
+	case "memory":
+		return scheduler.NewMemoryTaskStore(), nil
+	case "postgres":
+		if cfg.Storage.AgentStore != "postgres" {
+			return nil, fmt.Errorf("TASK_STORE=postgres requires AGENT_STORE=postgres")
+		}
+		return scheduler.NewPostgresTaskStore(db), nil
+	default:
+		return nil, fmt.Errorf("unknown TASK_STORE %q", cfg.Storage.TaskStore)
 	}
-	fmt.Println()
 }
 
-// 性能测试（可选）
-func demoBenchmark() {
-	fmt.Println("⚡ 性能基准测试")
-	fmt.Println("-" + repeat("-", 70))
-
-	queue := scheduler.NewTaskQueue()
-	numTasks := 10000
-
-	// 测试入队性能
-	fmt.Printf("\n📥 测试入队性能 (%d 个任务)...\n", numTasks)
-	start := time.Now()
-
-	for i := 0; i < numTasks; i++ {
-		task := createTask(
-			fmt.Sprintf("task-%d", i),
-			fmt.Sprintf("测试任务 %d", i),
-			i%10+1,
-		)
-		queue.Enqueue(task)
+// newTranscriptStore builds the store task transcripts are recorded into
+func newTranscriptStore(cfg *config.Config) (transcript.Store, error) {
+	switch cfg.Transcripts.Store {
+	case "memory":
+		return transcript.NewMemoryStore(cfg.Transcripts.MaxEntries), nil
+	case "file":
+		return transcript.NewFileStore(filepath.Join(cfg.Storage.DataDir, "transcripts"))
+	default:
+		return nil, fmt.Errorf("unknown TRANSCRIPT_STORE %q", cfg.Transcripts.Store)
 	}
+}
 
-	enqueueTime := time.Since(start)
-	fmt.Printf("  入队耗时: %v\n", enqueueTime)
-	fmt.Printf("  平均每个: %v\n", enqueueTime/time.Duration(numTasks))
-	fmt.Printf("  吞吐量: %.0f ops/sec\n",
-		float64(numTasks)/enqueueTime.Seconds())
-
-	// 测试出队性能
-	fmt.Printf("\n📤 测试出队性能 (%d 个任务)...\n", numTasks)
-	start = time.Now()
-
-	count := 0
-	for queue.Size() > 0 {
-		queue.Dequeue()
-		count++
+// newToolRegistry registers the tools agents may call. The file and
+// web_fetch tools reach the server's filesystem and network, so they are not
+// offered to model-driven tool calls.
+func newToolRegistry() *tools.ToolRegistry {
+	registry := tools.NewToolRegistry()
+	for _, tool := range []tools.Tool{tools.NewSearchTool(""), tools.NewCodeTool()} {
+		if err := registry.Register(tool); err != nil {
+			log.Fatalf("Failed to register tool %s: %v", tool.Name(), err)
+		}
 	}
+	return registry
+}
 
-	dequeueTime := time.Since(start)
-	fmt.Printf("  出队耗时: %v\n", dequeueTime)
-	fmt.Printf("  平均每个: %v\n", dequeueTime/time.Duration(count))
-	fmt.Printf("  吞吐量: %.0f ops/sec\n",
-		float64(count)/dequeueTime.Seconds())
+// newResponseCache builds the LLM response cache, falling back to memory
+// when Redis is unavailable
+func newResponseCache(cfg *config.Config, client *openai.Client) *cache.ResponseCache {
+	var backend cache.Backend
+	if cfg.Cache.Backend == "redis" {
+		redisState, err := state.NewRedisStateManager(cfg.Redis.GetRedisAddr(), cfg.Redis.Password, cfg.Redis.DB)
+		if err != nil {
+			log.Printf("Redis cache unavailable, using memory cache: %v", err)
+		} else {
+			backend = cache.NewRedisBackend(redisState)
+		}
+	}
+	if backend == nil {
+		backend = cache.NewMemoryBackend(cfg.Cache.MaxEntries)
+	}
 
-	fmt.Println()
-}
+	var embedder cache.Embedder
+	if cfg.Cache.SemanticThreshold > 0 {
+		embedder = cache.NewOpenAIEmbedder(client, cfg.Cache.EmbeddingModel)
+	}
 
-// 添加一个初始化日志函数
-func init() {
-	log.SetFlags(0) // 移除默认的时间戳
+	return cache.NewResponseCache(backend, cache.Config{
+		DefaultTTL:          time.Duration(cfg.Cache.TTL) * time.Second,
+		SimilarityThreshold: cfg.Cache.SemanticThreshold,
+	}, embedder)
 }
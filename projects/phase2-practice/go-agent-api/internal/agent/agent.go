@@ -3,8 +3,13 @@ package agent
 import (
 	"context"
 	"fmt"
+	"log"
 	"time"
 
+	"github.com/agent-learning/go-agent-api/internal/cache"
+	"github.com/agent-learning/go-agent-api/internal/guardrails"
+	"github.com/agent-learning/go-agent-api/internal/tools"
+	"github.com/agent-learning/go-agent-api/internal/transcript"
 	"github.com/google/uuid"
 	"github.com/sashabaranov/go-openai"
 )
@@ -15,27 +20,83 @@ type AgentService interface {
 	GetAgent(ctx context.Context, id string) (*Agent, error)
 	ListAgents(ctx context.Context) ([]*Agent, error)
 	DeleteAgent(ctx context.Context, id string) error
+	UpdateAgent(ctx context.Context, id string, req *UpdateAgentRequest) (*Agent, error)
+	ListAgentVersions(ctx context.Context, id string) ([]*AgentVersion, error)
+	RollbackAgent(ctx context.Context, id string, req *RollbackAgentRequest) (*Agent, error)
+	ExportAgents(ctx context.Context, ids []string) ([]*AgentDefinition, error)
+	ImportAgents(ctx context.Context, defs []*AgentDefinition) (*ImportResult, error)
 	ExecuteTask(ctx context.Context, agent *Agent, task *Task) (*TaskResult, error)
 }
 
 // agentService implements AgentService
 type agentService struct {
-	openaiClient *openai.Client
+	llmClient    LLMClient
 	registry     *AgentRegistry
+	cache        *cache.ResponseCache
+	guardrails   *guardrails.Pipeline
+	toolExecutor *tools.ToolExecutor
+	transcripts  transcript.Store
+	defaults     *Defaults
+}
+
+// Option configures optional agent service features
+type Option func(*agentService)
+
+// WithResponseCache enables caching of LLM responses
+func WithResponseCache(c *cache.ResponseCache) Option {
+	return func(s *agentService) {
+		s.cache = c
+	}
+}
+
+// WithRegistry uses the given registry, e.g. one backed by a repository
+func WithRegistry(r *AgentRegistry) Option {
+	return func(s *agentService) {
+		s.registry = r
+	}
+}
+
+// WithGuardrails screens task input and model output through a guardrails pipeline
+func WithGuardrails(p *guardrails.Pipeline) Option {
+	return func(s *agentService) {
+		s.guardrails = p
+	}
+}
+
+// WithToolExecutor lets tasks call tools through the executor, whose
+// guardrails screen every tool output before the model sees it
+func WithToolExecutor(te *tools.ToolExecutor) Option {
+	return func(s *agentService) {
+		s.toolExecutor = te
+	}
 }
 
 // NewAgentService creates a new agent service
-func NewAgentService(apiKey string) AgentService {
-	return &agentService{
-		openaiClient: openai.NewClient(apiKey),
-		registry:     NewAgentRegistry(),
+func NewAgentService(apiKey string, opts ...Option) AgentService {
+	return NewAgentServiceWithClient(openai.NewClient(apiKey), opts...)
+}
+
+// NewAgentServiceWithClient creates a new agent service backed by the given LLM client
+func NewAgentServiceWithClient(client LLMClient, opts ...Option) AgentService {
+	s := &agentService{
+		llmClient: client,
+		registry:  NewAgentRegistry(),
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
 // CreateAgent creates a new agent
 func (s *agentService) CreateAgent(ctx context.Context, req *CreateAgentRequest) (*Agent, error) {
+	return s.createAgent(uuid.New().String(), req)
+}
+
+// createAgent creates and registers an agent with the given ID
+func (s *agentService) createAgent(id string, req *CreateAgentRequest) (*Agent, error) {
 	agent := &Agent{
-		ID:        uuid.New().String(),
+		ID:        id,
 		Name:      req.Name,
 		Type:      req.Type,
 		Status:    AgentStatusIdle,
@@ -44,17 +105,13 @@ func (s *agentService) CreateAgent(ctx context.Context, req *CreateAgentRequest)
 		UpdatedAt: time.Now(),
 	}
 
-	// Set default config if not provided
-	if agent.Config.Model == "" {
-		agent.Config.Model = "gpt-4"
-	}
-	if agent.Config.Temperature == 0 {
-		agent.Config.Temperature = 0.7
-	}
-	if agent.Config.MaxTokens == 0 {
-		agent.Config.MaxTokens = 2000
+	if err := s.defaults.checkTools(agent.Config.Tools); err != nil {
+		return nil, err
 	}
 
+	// Set default config if not provided
+	s.defaults.apply(&agent.Config)
+
 	// Register agent
 	if err := s.registry.Register(agent); err != nil {
 		return nil, fmt.Errorf("failed to register agent: %w", err)
@@ -82,74 +139,226 @@ func (s *agentService) DeleteAgent(ctx context.Context, id string) error {
 	return s.registry.Unregister(id)
 }
 
-// ExecuteTask executes a task using the agent
+// ExecuteTask executes a task using the agent, recording a transcript when
+// a transcript store is configured
 func (s *agentService) ExecuteTask(ctx context.Context, agent *Agent, task *Task) (*TaskResult, error) {
+	if s.transcripts == nil {
+		return s.execute(ctx, agent, task)
+	}
+
+	ctx, recorder := s.startTranscript(ctx, agent, task)
+	result, err := s.execute(ctx, agent, task)
+	s.finishTranscript(recorder, result, err)
+	return result, err
+}
+
+// execute runs a task: input screening, the LLM call and output screening
+func (s *agentService) execute(ctx context.Context, agent *Agent, task *Task) (*TaskResult, error) {
 	startTime := time.Now()
 
-	// Update agent status
-	agent.Status = AgentStatusBusy
-	agent.UpdatedAt = time.Now()
-	s.registry.Update(agent)
+	// The allow-list may have changed since the agent was created
+	for _, tools := range [][]string{agent.Config.Tools, task.Tools} {
+		if err := s.defaults.checkTools(tools); err != nil {
+			return failedResult(task, startTime, err), err
+		}
+	}
+
+	// Update agent status. The registry copy is updated rather than the
+	// caller's agent, which may be an older version. A replay runs a
+	// detached copy of the recorded agent and leaves the live one alone.
+	if transcript.ReplayerFrom(ctx) == nil {
+		s.registry.SetStatus(agent.ID, AgentStatusBusy)
+		defer s.registry.SetStatus(agent.ID, AgentStatusIdle)
+	}
 
-	defer func() {
-		agent.Status = AgentStatusIdle
-		agent.UpdatedAt = time.Now()
-		s.registry.Update(agent)
-	}()
+	// Screen the input before it reaches the model
+	input := task.Input
+	if s.guardrails != nil {
+		check := s.guardrails.CheckInput(input)
+		recordGuardrails(task, check.Findings)
+		if check.Blocked {
+			err := check.Err()
+			return failedResult(task, startTime, err), err
+		}
+		input = check.Text
+	}
 
 	// Build system prompt based on agent type
 	systemPrompt := s.buildSystemPrompt(agent)
 
-	// Call OpenAI API
-	resp, err := s.openaiClient.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
-		Model: agent.Config.Model,
-		Messages: []openai.ChatCompletionMessage{
-			{
-				Role:    openai.ChatMessageRoleSystem,
-				Content: systemPrompt,
-			},
-			{
-				Role:    openai.ChatMessageRoleUser,
-				Content: task.Input,
-			},
+	messages := []openai.ChatCompletionMessage{
+		{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: systemPrompt,
+		},
+		{
+			Role:    openai.ChatMessageRoleUser,
+			Content: input,
 		},
-		Temperature: agent.Config.Temperature,
-		MaxTokens:   agent.Config.MaxTokens,
-	})
+	}
 
-	if err != nil {
-		return &TaskResult{
-			TaskID:    task.ID,
-			Status:    TaskStatusFailed,
-			Error:     err.Error(),
-			CreatedAt: startTime,
-			EndedAt:   time.Now(),
-			Duration:  time.Since(startTime).Milliseconds(),
-		}, err
+	var result *TaskResult
+	var err error
+	if len(task.OutputSchema) > 0 {
+		// Tasks with an output schema go through the JSON validation loop
+		result, err = s.executeStructured(ctx, agent, task, messages, startTime)
+	} else {
+		result, err = s.executeChat(ctx, agent, task, messages, startTime)
+	}
+	if err != nil || s.guardrails == nil {
+		return result, err
 	}
 
-	output := ""
-	if len(resp.Choices) > 0 {
-		output = resp.Choices[0].Message.Content
+	return s.screenOutput(task, result, startTime)
+}
+
+// executeChat runs a plain chat completion for a task
+func (s *agentService) executeChat(ctx context.Context, agent *Agent, task *Task, messages []openai.ChatCompletionMessage, startTime time.Time) (*TaskResult, error) {
+	if defs := s.taskTools(agent, task); len(defs) > 0 {
+		return s.executeWithTools(ctx, agent, task, messages, defs, startTime)
+	}
+
+	resp, hit, err := s.complete(ctx, agent, task, messages, nil, nil)
+	if err != nil {
+		return failedResult(task, startTime, err), err
 	}
 
 	result := &TaskResult{
 		TaskID:    task.ID,
 		Status:    TaskStatusCompleted,
-		Output:    output,
+		Output:    firstChoiceContent(resp),
 		CreatedAt: startTime,
 		EndedAt:   time.Now(),
 		Duration:  time.Since(startTime).Milliseconds(),
-		Metadata: map[string]interface{}{
-			"model":        resp.Model,
-			"tokens_used":  resp.Usage.TotalTokens,
-			"finish_reason": resp.Choices[0].FinishReason,
-		},
+		Metadata:  responseMetadata(resp),
 	}
+	s.recordCache(result.Metadata, agent, task, hit)
 
 	return result, nil
 }
 
+// complete sends a chat completion request using the agent's model
+// settings, serving it from the response cache when possible. Every call
+// is added to the transcript, and a replay serves recorded responses.
+func (s *agentService) complete(ctx context.Context, agent *Agent, task *Task, messages []openai.ChatCompletionMessage, format *openai.ChatCompletionResponseFormat, toolDefs []openai.Tool) (openai.ChatCompletionResponse, *cache.Hit, error) {
+	req := openai.ChatCompletionRequest{
+		Model:          agent.Config.Model,
+		Messages:       messages,
+		Temperature:    agent.Config.Temperature,
+		MaxTokens:      agent.Config.MaxTokens,
+		ResponseFormat: format,
+		Tools:          toolDefs,
+	}
+
+	client := s.llmClient
+	replayer := transcript.ReplayerFrom(ctx)
+	if replayer != nil {
+		client = replayer
+	}
+	startedAt := time.Now()
+
+	// A replay must not be answered from the cache, and neither may a turn
+	// that can call tools, as the tools would then not run
+	ttl, useCache := s.cacheTTL(agent, task)
+	if !useCache || replayer != nil || len(toolDefs) > 0 {
+		resp, err := client.CreateChatCompletion(ctx, req)
+		s.recordLLM(ctx, startedAt, req, resp, false, err)
+		return resp, nil, err
+	}
+
+	// Cache failures degrade to a normal LLM call
+	lookup, err := s.cache.Lookup(ctx, req)
+	if err != nil {
+		log.Printf("Response cache lookup failed: %v", err)
+	}
+	if lookup != nil && lookup.Response != nil {
+		s.recordLLM(ctx, startedAt, req, *lookup.Response, true, nil)
+		return *lookup.Response, lookup.Hit, nil
+	}
+
+	resp, err := client.CreateChatCompletion(ctx, req)
+	s.recordLLM(ctx, startedAt, req, resp, false, err)
+	if err != nil {
+		return resp, nil, err
+	}
+
+	if lookup != nil {
+		if err := s.cache.Store(ctx, lookup, resp, ttl); err != nil {
+			log.Printf("Response cache store failed: %v", err)
+		}
+	}
+	return resp, nil, nil
+}
+
+// cacheTTL reports whether the cache applies to a task and for how long
+// responses are kept. Agents may override the TTL with config.cache_ttl
+// (seconds, negative disables caching); tasks may bypass the cache with
+// metadata "cache_bypass": true.
+func (s *agentService) cacheTTL(agent *Agent, task *Task) (time.Duration, bool) {
+	if s.cache == nil || agent.Config.CacheTTL < 0 {
+		return 0, false
+	}
+	if bypass, ok := task.Metadata["cache_bypass"]; ok && (bypass == true || bypass == "true") {
+		return 0, false
+	}
+	if agent.Config.CacheTTL > 0 {
+		return time.Duration(agent.Config.CacheTTL) * time.Second, true
+	}
+	return s.cache.DefaultTTL(), true
+}
+
+// recordCache adds cache hit information to result metadata
+func (s *agentService) recordCache(metadata map[string]interface{}, agent *Agent, task *Task, hit *cache.Hit) {
+	if _, useCache := s.cacheTTL(agent, task); !useCache {
+		return
+	}
+	metadata["cache_hit"] = hit != nil
+	if hit == nil {
+		return
+	}
+	metadata["cache_match"] = string(hit.Match)
+	metadata["cache_key"] = hit.Key
+	metadata["cache_age_ms"] = hit.AgeMs
+	if hit.Match == cache.MatchSemantic {
+		metadata["cache_similarity"] = hit.Similarity
+	}
+	// Cached responses cost no tokens
+	metadata["tokens_used"] = 0
+}
+
+// failedResult builds the result returned when execution fails
+func failedResult(task *Task, startTime time.Time, err error) *TaskResult {
+	return &TaskResult{
+		TaskID:     task.ID,
+		Status:     TaskStatusFailed,
+		Error:      err.Error(),
+		CreatedAt:  startTime,
+		EndedAt:    time.Now(),
+		Duration:   time.Since(startTime).Milliseconds(),
+		Guardrails: task.Guardrails,
+	}
+}
+
+// firstChoiceContent returns the content of the first choice, if any
+func firstChoiceContent(resp openai.ChatCompletionResponse) string {
+	if len(resp.Choices) > 0 {
+		return resp.Choices[0].Message.Content
+	}
+	return ""
+}
+
+// responseMetadata extracts result metadata from a completion response
+func responseMetadata(resp openai.ChatCompletionResponse) map[string]interface{} {
+	metadata := map[string]interface{}{
+		"model":       resp.Model,
+		"tokens_used": resp.Usage.TotalTokens,
+	}
+	if len(resp.Choices) > 0 {
+		metadata["finish_reason"] = resp.Choices[0].FinishReason
+	}
+	return metadata
+}
+
 // buildSystemPrompt builds the system prompt based on agent type
 func (s *agentService) buildSystemPrompt(agent *Agent) string {
 	switch agent.Type {
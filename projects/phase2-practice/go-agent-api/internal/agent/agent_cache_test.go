@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/agent-learning/go-agent-api/internal/cache"
+)
+
+func TestExecuteTaskUsesResponseCache(t *testing.T) {
+	client := &fakeLLMClient{replies: []string{"first", "second"}}
+	responseCache := cache.NewResponseCache(cache.NewMemoryBackend(10), cache.Config{}, nil)
+	service := NewAgentServiceWithClient(client, WithResponseCache(responseCache))
+	ctx := context.Background()
+
+	ag, _ := service.CreateAgent(ctx, &CreateAgentRequest{Name: "qa", Type: AgentTypeDocQA})
+
+	miss, err := service.ExecuteTask(ctx, ag, &Task{ID: "t1", Input: "What is Go?"})
+	if err != nil {
+		t.Fatalf("ExecuteTask failed: %v", err)
+	}
+	if miss.Metadata["cache_hit"] != false {
+		t.Errorf("Expected cache miss, got %v", miss.Metadata)
+	}
+
+	hit, _ := service.ExecuteTask(ctx, ag, &Task{ID: "t2", Input: "What is Go?"})
+	if hit.Metadata["cache_hit"] != true || hit.Metadata["cache_match"] != "exact" || hit.Output != "first" {
+		t.Errorf("Expected exact cache hit, got %q %v", hit.Output, hit.Metadata)
+	}
+	if len(client.requests) != 1 {
+		t.Errorf("Expected 1 LLM call, got %d", len(client.requests))
+	}
+
+	bypass, _ := service.ExecuteTask(ctx, ag, &Task{ID: "t3", Input: "What is Go?", Metadata: map[string]interface{}{"cache_bypass": true}})
+	if bypass.Output != "second" || len(client.requests) != 2 {
+		t.Errorf("Expected bypass to call the LLM, got %q", bypass.Output)
+	}
+	if _, ok := bypass.Metadata["cache_hit"]; ok {
+		t.Error("Expected no cache metadata on bypass")
+	}
+}
+
+func TestExecuteTaskAgentDisablesCache(t *testing.T) {
+	client := &fakeLLMClient{replies: []string{"fresh"}}
+	responseCache := cache.NewResponseCache(cache.NewMemoryBackend(10), cache.Config{}, nil)
+	service := NewAgentServiceWithClient(client, WithResponseCache(responseCache))
+	ctx := context.Background()
+
+	ag, _ := service.CreateAgent(ctx, &CreateAgentRequest{Name: "live", Type: AgentTypeGeneral, Config: AgentConfig{CacheTTL: -1}})
+	for i := 0; i < 2; i++ {
+		if _, err := service.ExecuteTask(ctx, ag, &Task{ID: "t", Input: "now?"}); err != nil {
+			t.Fatalf("ExecuteTask failed: %v", err)
+		}
+	}
+	if len(client.requests) != 2 {
+		t.Errorf("Expected caching disabled, got %d LLM calls", len(client.requests))
+	}
+}
@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrToolNotAllowed is returned when an agent or task uses a tool outside the allow-list
+var ErrToolNotAllowed = errors.New("tool not allowed")
+
+// DefaultsConfig holds the config new agents start from and the tools agents
+// and tasks may use
+type DefaultsConfig struct {
+	Model       string
+	Temperature float32
+	MaxTokens   int
+	// AllowedTools restricts tools; empty allows any tool
+	AllowedTools []string
+}
+
+// BuiltinDefaults are used when no defaults are configured
+var BuiltinDefaults = DefaultsConfig{
+	Model:       "gpt-4",
+	Temperature: 0.7,
+	MaxTokens:   2000,
+}
+
+// Defaults holds agent defaults that can be changed while the service runs
+type Defaults struct {
+	config DefaultsConfig
+	mu     sync.RWMutex
+}
+
+// NewDefaults creates defaults from config
+func NewDefaults(config DefaultsConfig) *Defaults {
+	d := &Defaults{}
+	d.Set(config)
+	return d
+}
+
+// WithDefaults uses d for agent defaults and the tool allow-list
+func WithDefaults(d *Defaults) Option {
+	return func(s *agentService) {
+		s.defaults = d
+	}
+}
+
+// Set replaces the defaults. Agents created before keep their config.
+func (d *Defaults) Set(config DefaultsConfig) {
+	config.AllowedTools = append([]string(nil), config.AllowedTools...)
+
+	d.mu.Lock()
+	d.config = config
+	d.mu.Unlock()
+}
+
+// Get returns the current defaults. A nil Defaults returns BuiltinDefaults.
+func (d *Defaults) Get() DefaultsConfig {
+	if d == nil {
+		return BuiltinDefaults
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.config
+}
+
+// apply fills in the config fields an agent was created without
+func (d *Defaults) apply(config *AgentConfig) {
+	defaults := d.Get()
+	if config.Model == "" {
+		config.Model = defaults.Model
+	}
+	if config.Temperature == 0 {
+		config.Temperature = defaults.Temperature
+	}
+	if config.MaxTokens == 0 {
+		config.MaxTokens = defaults.MaxTokens
+	}
+}
+
+// checkTools returns ErrToolNotAllowed for the first tool outside the allow-list
+func (d *Defaults) checkTools(tools []string) error {
+	allowed := d.Get().AllowedTools
+	if len(allowed) == 0 {
+		return nil
+	}
+	for _, tool := range tools {
+		if !containsString(allowed, tool) {
+			return fmt.Errorf("%w: %s", ErrToolNotAllowed, tool)
+		}
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestDefaultsApplyToNewAgents(t *testing.T) {
+	defaults := NewDefaults(DefaultsConfig{Model: "gpt-4o", Temperature: 0.2, MaxTokens: 500})
+	service := NewAgentServiceWithClient(&fakeLLMClient{}, WithDefaults(defaults))
+	ctx := context.Background()
+
+	ag, err := service.CreateAgent(ctx, &CreateAgentRequest{Name: "a", Type: AgentTypeGeneral, Config: AgentConfig{MaxTokens: 100}})
+	if err != nil {
+		t.Fatalf("CreateAgent failed: %v", err)
+	}
+	if ag.Config.Model != "gpt-4o" || ag.Config.Temperature != 0.2 || ag.Config.MaxTokens != 100 {
+		t.Errorf("Expected defaults for unset fields only, got %+v", ag.Config)
+	}
+
+	// Changed defaults apply to agents created afterwards
+	defaults.Set(DefaultsConfig{Model: "gpt-4o-mini", Temperature: 0.2, MaxTokens: 500})
+	later, _ := service.CreateAgent(ctx, &CreateAgentRequest{Name: "b", Type: AgentTypeGeneral})
+	if later.Config.Model != "gpt-4o-mini" {
+		t.Errorf("Expected the new default model, got %s", later.Config.Model)
+	}
+	if current, _ := service.GetAgent(ctx, ag.ID); current.Config.Model != "gpt-4o" {
+		t.Errorf("Expected existing agents to keep their model, got %s", current.Config.Model)
+	}
+}
+
+func TestDefaultsRestrictTools(t *testing.T) {
+	defaults := NewDefaults(DefaultsConfig{Model: "gpt-4", Temperature: 0.7, MaxTokens: 100, AllowedTools: []string{"search"}})
+	client := &fakeLLMClient{replies: []string{"ok"}}
+	service := NewAgentServiceWithClient(client, WithDefaults(defaults))
+	ctx := context.Background()
+
+	_, err := service.CreateAgent(ctx, &CreateAgentRequest{Name: "a", Type: AgentTypeGeneral, Config: AgentConfig{Tools: []string{"code"}}})
+	if !errors.Is(err, ErrToolNotAllowed) {
+		t.Fatalf("Expected ErrToolNotAllowed on create, got %v", err)
+	}
+
+	ag, err := service.CreateAgent(ctx, &CreateAgentRequest{Name: "a", Type: AgentTypeGeneral, Config: AgentConfig{Tools: []string{"search"}}})
+	if err != nil {
+		t.Fatalf("CreateAgent failed: %v", err)
+	}
+	_, err = service.UpdateAgent(ctx, ag.ID, &UpdateAgentRequest{Config: json.RawMessage(`{"tools": ["search", "file"]}`)})
+	if !errors.Is(err, ErrToolNotAllowed) {
+		t.Errorf("Expected ErrToolNotAllowed on update, got %v", err)
+	}
+
+	result, err := service.ExecuteTask(ctx, ag, &Task{ID: "t1", Input: "hi", Tools: []string{"code"}})
+	if !errors.Is(err, ErrToolNotAllowed) || result.Status != TaskStatusFailed {
+		t.Errorf("Expected the task to fail on a disallowed tool, got %v", err)
+	}
+	if len(client.requests) != 0 {
+		t.Errorf("Expected no LLM call for a rejected task")
+	}
+
+	// Clearing the allow-list allows any tool
+	defaults.Set(DefaultsConfig{Model: "gpt-4", Temperature: 0.7, MaxTokens: 100})
+	if _, err := service.ExecuteTask(ctx, ag, &Task{ID: "t2", Input: "hi", Tools: []string{"code"}}); err != nil {
+		t.Errorf("Expected the task to run, got %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+// AgentDefinition is the portable form of an agent, without runtime state
+type AgentDefinition struct {
+	ID      string      `json:"id,omitempty"`
+	Name    string      `json:"name"`
+	Type    AgentType   `json:"type"`
+	Version int         `json:"version,omitempty"`
+	Config  AgentConfig `json:"config"`
+}
+
+// AgentBundle is the document written by export and read by import
+type AgentBundle struct {
+	Agents []*AgentDefinition `json:"agents"`
+}
+
+// ImportResult reports what an import changed
+type ImportResult struct {
+	Created   []string `json:"created"`
+	Updated   []string `json:"updated"`
+	Unchanged []string `json:"unchanged"`
+}
+
+// Export formats
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// ExportAgents returns the definitions of the given agents, or of every
+// agent when ids is empty, sorted by name
+func (s *agentService) ExportAgents(ctx context.Context, ids []string) ([]*AgentDefinition, error) {
+	agents := s.registry.List()
+	if len(ids) > 0 {
+		agents = make([]*Agent, 0, len(ids))
+		for _, id := range ids {
+			agent, err := s.registry.Get(id)
+			if err != nil {
+				return nil, err
+			}
+			agents = append(agents, agent)
+		}
+	}
+
+	defs := make([]*AgentDefinition, 0, len(agents))
+	for _, agent := range agents {
+		defs = append(defs, &AgentDefinition{
+			ID:      agent.ID,
+			Name:    agent.Name,
+			Type:    agent.Type,
+			Version: agent.Version,
+			Config:  agent.Config,
+		})
+	}
+	sort.Slice(defs, func(i, j int) bool {
+		if defs[i].Name != defs[j].Name {
+			return defs[i].Name < defs[j].Name
+		}
+		return defs[i].ID < defs[j].ID
+	})
+
+	return defs, nil
+}
+
+// ImportAgents creates or updates agents from definitions. Definitions with
+// an ID that exists update that agent (recording a new version when the
+// definition differs); other definitions create agents, keeping their ID
+// when one is given.
+func (s *agentService) ImportAgents(ctx context.Context, defs []*AgentDefinition) (*ImportResult, error) {
+	for i, def := range defs {
+		if def.Name == "" || def.Type == "" {
+			return nil, fmt.Errorf("agent definition %d: name and type are required", i+1)
+		}
+		if err := s.defaults.checkTools(def.Config.Tools); err != nil {
+			return nil, fmt.Errorf("agent definition %d: %w", i+1, err)
+		}
+	}
+
+	result := &ImportResult{Created: []string{}, Updated: []string{}, Unchanged: []string{}}
+	for _, def := range defs {
+		if def.ID != "" {
+			if current, err := s.registry.Get(def.ID); err == nil {
+				// Fields the definition leaves out get the same defaults as
+				// on create, so re-importing an unchanged file is a no-op
+				config := def.Config
+				s.defaults.apply(&config)
+				if current.Name == def.Name && current.Type == def.Type && reflect.DeepEqual(current.Config, config) {
+					result.Unchanged = append(result.Unchanged, def.ID)
+					continue
+				}
+				_, err := s.registry.UpdateVersioned(def.ID, 0, "imported", func(agent *Agent) error {
+					agent.Name = def.Name
+					agent.Type = def.Type
+					agent.Config = config
+					return nil
+				})
+				if err != nil {
+					return result, err
+				}
+				result.Updated = append(result.Updated, def.ID)
+				continue
+			}
+		}
+
+		id := def.ID
+		if id == "" {
+			id = uuid.New().String()
+		}
+		agent, err := s.createAgent(id, &CreateAgentRequest{Name: def.Name, Type: def.Type, Config: def.Config})
+		if err != nil {
+			return result, err
+		}
+		result.Created = append(result.Created, agent.ID)
+	}
+
+	return result, nil
+}
+
+// EncodeAgentBundle renders definitions as JSON or YAML
+func EncodeAgentBundle(defs []*AgentDefinition, format string) ([]byte, error) {
+	bundle := AgentBundle{Agents: defs}
+
+	switch format {
+	case FormatJSON, "":
+		return json.MarshalIndent(bundle, "", "  ")
+	case FormatYAML:
+		// Round-trip through JSON so YAML keys match the JSON field names
+		data, err := json.Marshal(bundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode agents: %w", err)
+		}
+		var doc interface{}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("failed to encode agents: %w", err)
+		}
+		return yaml.Marshal(doc)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// DecodeAgentBundle parses a JSON or YAML bundle. A bare list of
+// definitions or a single definition is accepted too.
+func DecodeAgentBundle(data []byte) ([]*AgentDefinition, error) {
+	// YAML is a superset of JSON, so one decoder handles both
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse agent definitions: %w", err)
+	}
+
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		if _, ok := v["agents"]; !ok {
+			doc = map[string]interface{}{"agents": []interface{}{v}}
+		}
+	case []interface{}:
+		doc = map[string]interface{}{"agents": v}
+	default:
+		return nil, fmt.Errorf("agent definitions must be an object or a list")
+	}
+
+	normalized, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse agent definitions: %w", err)
+	}
+	var bundle AgentBundle
+	if err := json.Unmarshal(normalized, &bundle); err != nil {
+		return nil, fmt.Errorf("invalid agent definitions: %w", err)
+	}
+	if len(bundle.Agents) == 0 {
+		return nil, fmt.Errorf("no agent definitions found")
+	}
+
+	return bundle.Agents, nil
+}
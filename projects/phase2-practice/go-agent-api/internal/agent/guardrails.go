@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/agent-learning/go-agent-api/internal/guardrails"
+)
+
+// screenOutput runs the output guardrails over a completed result,
+// redacting or failing it as configured
+func (s *agentService) screenOutput(task *Task, result *TaskResult, startTime time.Time) (*TaskResult, error) {
+	check := s.guardrails.CheckOutput(result.Output)
+	recordGuardrails(task, check.Findings)
+	result.Guardrails = task.Guardrails
+
+	if check.Blocked {
+		err := check.Err()
+		return failedResult(task, startTime, err), err
+	}
+
+	if check.Text != result.Output {
+		result.Output = check.Text
+		// Keep the parsed form in step with the redacted text, dropping it
+		// rather than leaking unredacted values if it no longer parses
+		if result.StructuredOutput != nil {
+			var value interface{}
+			if err := json.Unmarshal([]byte(stripCodeFence(check.Text)), &value); err != nil {
+				value = nil
+			}
+			result.StructuredOutput = value
+		}
+	}
+
+	return result, nil
+}
+
+// recordGuardrails appends findings to the task's audit trail
+func recordGuardrails(task *Task, findings []guardrails.Finding) {
+	if len(findings) > 0 {
+		task.Guardrails = append(task.Guardrails, findings...)
+	}
+}
@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/agent-learning/go-agent-api/internal/guardrails"
+)
+
+func newGuardedService(t *testing.T, client LLMClient, config guardrails.Config) (AgentService, *Agent) {
+	t.Helper()
+	pipeline, err := guardrails.New(config)
+	if err != nil {
+		t.Fatalf("guardrails.New failed: %v", err)
+	}
+	service := NewAgentServiceWithClient(client, WithGuardrails(pipeline))
+	ag, _ := service.CreateAgent(context.Background(), &CreateAgentRequest{Name: "guarded", Type: AgentTypeGeneral})
+	return service, ag
+}
+
+func TestExecuteTaskRedactsInputAndOutput(t *testing.T) {
+	client := &fakeLLMClient{replies: []string{"Sure, I emailed ops@corp.example and it is classified."}}
+	service, ag := newGuardedService(t, client, guardrails.Config{
+		PIIAction:       guardrails.ActionRedact,
+		OutputBlocklist: []string{"classified"},
+		OutputAction:    guardrails.ActionRedact,
+	})
+
+	task := &Task{ID: "t1", Input: "Email jane@corp.example the report"}
+	result, err := service.ExecuteTask(context.Background(), ag, task)
+	if err != nil {
+		t.Fatalf("ExecuteTask failed: %v", err)
+	}
+
+	sent := client.requests[0].Messages[1].Content
+	if strings.Contains(sent, "jane@") {
+		t.Errorf("Expected input to be redacted before the LLM call, got %q", sent)
+	}
+	if result.Output != "Sure, I emailed [REDACTED_EMAIL] and it is [FILTERED]." {
+		t.Errorf("Unexpected output: %q", result.Output)
+	}
+
+	if len(task.Guardrails) != 3 || len(result.Guardrails) != 3 {
+		t.Fatalf("Expected 3 findings recorded, got %+v", task.Guardrails)
+	}
+	if task.Guardrails[0].Stage != guardrails.StageInput || task.Guardrails[2].Rule != "content.classified" {
+		t.Errorf("Unexpected findings: %+v", task.Guardrails)
+	}
+}
+
+func TestExecuteTaskBlocksInput(t *testing.T) {
+	client := &fakeLLMClient{replies: []string{"unused"}}
+	service, ag := newGuardedService(t, client, guardrails.Config{PIIAction: guardrails.ActionBlock})
+
+	task := &Task{ID: "t1", Input: "My card is 4111 1111 1111 1111"}
+	result, err := service.ExecuteTask(context.Background(), ag, task)
+	if !errors.Is(err, guardrails.ErrBlocked) {
+		t.Fatalf("Expected ErrBlocked, got %v", err)
+	}
+	if result.Status != TaskStatusFailed || len(result.Guardrails) != 1 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if len(client.requests) != 0 {
+		t.Error("Blocked input must not reach the LLM")
+	}
+}
@@ -0,0 +1,13 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// LLMClient is the subset of the OpenAI client used by the agent service.
+// *openai.Client satisfies it; tests can substitute a fake.
+type LLMClient interface {
+	CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error)
+}
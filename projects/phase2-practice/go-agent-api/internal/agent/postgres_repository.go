@@ -0,0 +1,120 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/agent-learning/go-agent-api/internal/database"
+)
+
+// PostgresRepository stores agents in the agents and agent_versions tables
+type PostgresRepository struct {
+	db *database.PostgresDB
+}
+
+// NewPostgresRepository creates a Postgres-backed repository. The schema
+// must already be initialized with InitSchema.
+func NewPostgresRepository(db *database.PostgresDB) *PostgresRepository {
+	return &PostgresRepository{db: db}
+}
+
+// SaveAgent implements AgentRepository
+func (p *PostgresRepository) SaveAgent(agent *Agent, version *AgentVersion) error {
+	config, err := json.Marshal(agent.Config)
+	if err != nil {
+		return fmt.Errorf("failed to encode agent config: %w", err)
+	}
+
+	record := &database.AgentRecord{
+		ID:        agent.ID,
+		Name:      agent.Name,
+		Type:      string(agent.Type),
+		Status:    string(agent.Status),
+		Config:    string(config),
+		Version:   agent.Version,
+		CreatedAt: agent.CreatedAt,
+		UpdatedAt: agent.UpdatedAt,
+	}
+	if version == nil {
+		return p.db.SaveAgent(record)
+	}
+
+	versionConfig, err := json.Marshal(version.Config)
+	if err != nil {
+		return fmt.Errorf("failed to encode version config: %w", err)
+	}
+	return p.db.SaveAgentWithVersion(record, &database.AgentVersionRecord{
+		AgentID:   agent.ID,
+		Version:   version.Version,
+		Name:      version.Name,
+		Type:      string(version.Type),
+		Config:    string(versionConfig),
+		Comment:   version.Comment,
+		CreatedAt: version.CreatedAt,
+	})
+}
+
+// DeleteAgent implements AgentRepository
+func (p *PostgresRepository) DeleteAgent(id string) error {
+	return p.db.DeleteAgent(id)
+}
+
+// LoadAgents implements AgentRepository
+func (p *PostgresRepository) LoadAgents() ([]*Agent, error) {
+	records, err := p.db.ListAgents()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agents: %w", err)
+	}
+
+	agents := make([]*Agent, 0, len(records))
+	for _, record := range records {
+		agent := &Agent{
+			ID:        record.ID,
+			Name:      record.Name,
+			Type:      AgentType(record.Type),
+			Status:    AgentStatus(record.Status),
+			Version:   record.Version,
+			CreatedAt: record.CreatedAt,
+			UpdatedAt: record.UpdatedAt,
+		}
+		if err := decodeConfig(record.Config, &agent.Config); err != nil {
+			return nil, fmt.Errorf("agent %s: %w", record.ID, err)
+		}
+		agents = append(agents, agent)
+	}
+	return agents, nil
+}
+
+// LoadVersions implements AgentRepository
+func (p *PostgresRepository) LoadVersions(agentID string) ([]*AgentVersion, error) {
+	records, err := p.db.ListAgentVersions(agentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agent versions: %w", err)
+	}
+
+	versions := make([]*AgentVersion, 0, len(records))
+	for _, record := range records {
+		version := &AgentVersion{
+			Version:   record.Version,
+			Name:      record.Name,
+			Type:      AgentType(record.Type),
+			Comment:   record.Comment,
+			CreatedAt: record.CreatedAt,
+		}
+		if err := decodeConfig(record.Config, &version.Config); err != nil {
+			return nil, fmt.Errorf("agent %s version %d: %w", agentID, record.Version, err)
+		}
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
+
+func decodeConfig(data string, config *AgentConfig) error {
+	if data == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(data), config); err != nil {
+		return fmt.Errorf("failed to decode config: %w", err)
+	}
+	return nil
+}
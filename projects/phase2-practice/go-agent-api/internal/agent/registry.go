@@ -1,24 +1,77 @@
 package agent
 
 import (
+	"errors"
 	"fmt"
 	"sync"
+	"time"
 )
 
-// AgentRegistry manages registered agents
+// ErrVersionConflict is returned when an update is based on a stale agent version
+var ErrVersionConflict = errors.New("agent version conflict")
+
+// AgentRegistry manages registered agents. With a repository, changes are
+// written through and the in-memory maps act as a cache.
 type AgentRegistry struct {
-	agents map[string]*Agent
-	mu     sync.RWMutex
+	agents   map[string]*Agent
+	versions map[string][]*AgentVersion
+	repo     AgentRepository
+	mu       sync.RWMutex
 }
 
-// NewAgentRegistry creates a new agent registry
+// NewAgentRegistry creates a new in-memory agent registry
 func NewAgentRegistry() *AgentRegistry {
 	return &AgentRegistry{
-		agents: make(map[string]*Agent),
+		agents:   make(map[string]*Agent),
+		versions: make(map[string][]*AgentVersion),
+	}
+}
+
+// NewAgentRegistryWithRepository creates a registry backed by repo and
+// loads the persisted agents into it
+func NewAgentRegistryWithRepository(repo AgentRepository) (*AgentRegistry, error) {
+	r := NewAgentRegistry()
+	r.repo = repo
+
+	agents, err := repo.LoadAgents()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load agents: %w", err)
+	}
+
+	for _, agent := range agents {
+		versions, err := repo.LoadVersions(agent.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load versions of agent %s: %w", agent.ID, err)
+		}
+		if agent.Version == 0 {
+			agent.Version = 1
+		}
+		// Agents saved before versioning existed start their history here
+		if len(versions) == 0 {
+			versions = []*AgentVersion{snapshot(agent, "loaded")}
+		}
+
+		// Nothing is running after a restart
+		agent.Status = AgentStatusIdle
+		r.agents[agent.ID] = agent
+		r.versions[agent.ID] = versions
+	}
+
+	return r, nil
+}
+
+// persist writes an agent through to the repository, if any
+func (r *AgentRegistry) persist(agent *Agent, version *AgentVersion) error {
+	if r.repo == nil {
+		return nil
 	}
+	if err := r.repo.SaveAgent(agent, version); err != nil {
+		return fmt.Errorf("failed to persist agent: %w", err)
+	}
+	return nil
 }
 
-// Register registers a new agent
+// Register registers a new agent and records its first version
 func (r *AgentRegistry) Register(agent *Agent) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -27,7 +80,16 @@ func (r *AgentRegistry) Register(agent *Agent) error {
 		return fmt.Errorf("agent with ID %s already exists", agent.ID)
 	}
 
+	if agent.Version == 0 {
+		agent.Version = 1
+	}
+	version := snapshot(agent, "created")
+	if err := r.persist(agent, version); err != nil {
+		return err
+	}
+
 	r.agents[agent.ID] = agent
+	r.versions[agent.ID] = []*AgentVersion{version}
 	return nil
 }
 
@@ -40,7 +102,14 @@ func (r *AgentRegistry) Unregister(id string) error {
 		return fmt.Errorf("agent with ID %s not found", id)
 	}
 
+	if r.repo != nil {
+		if err := r.repo.DeleteAgent(id); err != nil {
+			return fmt.Errorf("failed to delete persisted agent: %w", err)
+		}
+	}
+
 	delete(r.agents, id)
+	delete(r.versions, id)
 	return nil
 }
 
@@ -66,10 +135,77 @@ func (r *AgentRegistry) Update(agent *Agent) error {
 		return fmt.Errorf("agent with ID %s not found", agent.ID)
 	}
 
+	if err := r.persist(agent, nil); err != nil {
+		return err
+	}
+
 	r.agents[agent.ID] = agent
 	return nil
 }
 
+// UpdateVersioned replaces an agent with a modified copy and records a new
+// version. expectedVersion must match the current version unless it is 0.
+// Agents handed out earlier are never mutated, so running tasks keep the
+// configuration they started with.
+func (r *AgentRegistry) UpdateVersioned(id string, expectedVersion int, comment string, apply func(agent *Agent) error) (*Agent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, exists := r.agents[id]
+	if !exists {
+		return nil, fmt.Errorf("agent with ID %s not found", id)
+	}
+	if expectedVersion != 0 && expectedVersion != current.Version {
+		return nil, fmt.Errorf("%w: expected version %d, current version is %d", ErrVersionConflict, expectedVersion, current.Version)
+	}
+
+	updated := *current
+	if err := apply(&updated); err != nil {
+		return nil, err
+	}
+	updated.ID = current.ID
+	updated.Version = current.Version + 1
+	updated.UpdatedAt = time.Now()
+
+	version := snapshot(&updated, comment)
+	if err := r.persist(&updated, version); err != nil {
+		return nil, err
+	}
+
+	r.agents[id] = &updated
+	r.versions[id] = append(r.versions[id], version)
+	return &updated, nil
+}
+
+// SetStatus updates the runtime status of an agent without creating a
+// version. Status is runtime state and is not persisted.
+func (r *AgentRegistry) SetStatus(id string, status AgentStatus) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	agent, exists := r.agents[id]
+	if !exists {
+		return fmt.Errorf("agent with ID %s not found", id)
+	}
+
+	agent.Status = status
+	agent.UpdatedAt = time.Now()
+	return nil
+}
+
+// Versions returns the version history of an agent, oldest first
+func (r *AgentRegistry) Versions(id string) ([]*AgentVersion, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	versions, exists := r.versions[id]
+	if !exists {
+		return nil, fmt.Errorf("agent with ID %s not found", id)
+	}
+
+	return append([]*AgentVersion(nil), versions...), nil
+}
+
 // List returns all registered agents
 func (r *AgentRegistry) List() []*Agent {
 	r.mu.RLock()
@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// AgentRepository persists agents and their version history. The registry
+// writes through to it and keeps its in-memory map as a cache.
+type AgentRepository interface {
+	// SaveAgent stores an agent; version is non-nil when the save records a new version
+	SaveAgent(agent *Agent, version *AgentVersion) error
+	DeleteAgent(id string) error
+	LoadAgents() ([]*Agent, error)
+	LoadVersions(agentID string) ([]*AgentVersion, error)
+}
+
+// agentRecord is an agent and its history as stored by FileRepository
+type agentRecord struct {
+	Agent    *Agent          `json:"agent"`
+	Versions []*AgentVersion `json:"versions"`
+}
+
+// FileRepository stores agents as a JSON file in a directory
+type FileRepository struct {
+	path   string
+	agents map[string]*agentRecord
+	mu     sync.Mutex
+}
+
+const agentsFile = "agents.json"
+
+// NewFileRepository creates a file-backed repository in dir, loading existing data.
+// Agent configs may hold secrets in Extra or tool settings, so the directory
+// and file are private to the owner.
+func NewFileRepository(dir string) (*FileRepository, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create agent store directory: %w", err)
+	}
+
+	repo := &FileRepository{
+		path:   filepath.Join(dir, agentsFile),
+		agents: make(map[string]*agentRecord),
+	}
+
+	data, err := os.ReadFile(repo.path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read %s: %w", repo.path, err)
+	default:
+		if err := json.Unmarshal(data, &repo.agents); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", repo.path, err)
+		}
+	}
+
+	return repo, nil
+}
+
+// SaveAgent implements AgentRepository
+func (f *FileRepository) SaveAgent(agent *Agent, version *AgentVersion) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	record, exists := f.agents[agent.ID]
+	if !exists {
+		record = &agentRecord{}
+		f.agents[agent.ID] = record
+	}
+	copied := *agent
+	record.Agent = &copied
+	if version != nil {
+		record.Versions = append(record.Versions, version)
+	}
+
+	return f.flush()
+}
+
+// DeleteAgent implements AgentRepository
+func (f *FileRepository) DeleteAgent(id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.agents, id)
+	return f.flush()
+}
+
+// LoadAgents implements AgentRepository
+func (f *FileRepository) LoadAgents() ([]*Agent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	agents := make([]*Agent, 0, len(f.agents))
+	for _, record := range f.agents {
+		copied := *record.Agent
+		agents = append(agents, &copied)
+	}
+	return agents, nil
+}
+
+// LoadVersions implements AgentRepository
+func (f *FileRepository) LoadVersions(agentID string) ([]*AgentVersion, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	record, exists := f.agents[agentID]
+	if !exists {
+		return nil, nil
+	}
+	return append([]*AgentVersion(nil), record.Versions...), nil
+}
+
+// flush atomically rewrites the agents file
+func (f *FileRepository) flush() error {
+	data, err := json.MarshalIndent(f.agents, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode agents: %w", err)
+	}
+
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, f.path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", f.path, err)
+	}
+	return nil
+}
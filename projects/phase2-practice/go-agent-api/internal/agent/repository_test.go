@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileRepositoryPersistsRegistry(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	repo, err := NewFileRepository(dir)
+	if err != nil {
+		t.Fatalf("NewFileRepository failed: %v", err)
+	}
+	registry, err := NewAgentRegistryWithRepository(repo)
+	if err != nil {
+		t.Fatalf("NewAgentRegistryWithRepository failed: %v", err)
+	}
+	service := NewAgentServiceWithClient(&fakeLLMClient{replies: []string{"ok"}}, WithRegistry(registry))
+
+	kept, _ := service.CreateAgent(ctx, &CreateAgentRequest{Name: "kept", Type: AgentTypeGeneral})
+	dropped, _ := service.CreateAgent(ctx, &CreateAgentRequest{Name: "dropped", Type: AgentTypeGeneral})
+	if _, err := service.UpdateAgent(ctx, kept.ID, &UpdateAgentRequest{Config: json.RawMessage(`{"model": "gpt-4o"}`)}); err != nil {
+		t.Fatalf("UpdateAgent failed: %v", err)
+	}
+	if err := service.DeleteAgent(ctx, dropped.ID); err != nil {
+		t.Fatalf("DeleteAgent failed: %v", err)
+	}
+	if _, err := service.ExecuteTask(ctx, kept, &Task{ID: "t1", Input: "hi"}); err != nil {
+		t.Fatalf("ExecuteTask failed: %v", err)
+	}
+
+	// Configs may hold secrets, so the file is private to the owner
+	info, err := os.Stat(filepath.Join(dir, agentsFile))
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected the agents file to be written 0600, got %v", info.Mode().Perm())
+	}
+
+	// Simulate a restart
+	repo, err = NewFileRepository(dir)
+	if err != nil {
+		t.Fatalf("NewFileRepository failed: %v", err)
+	}
+	registry, err = NewAgentRegistryWithRepository(repo)
+	if err != nil {
+		t.Fatalf("NewAgentRegistryWithRepository failed: %v", err)
+	}
+	service = NewAgentServiceWithClient(&fakeLLMClient{replies: []string{"ok"}}, WithRegistry(registry))
+
+	agents, _ := service.ListAgents(ctx)
+	if len(agents) != 1 || agents[0].ID != kept.ID {
+		t.Fatalf("Expected only the kept agent after reload, got %+v", agents)
+	}
+	if agents[0].Version != 2 || agents[0].Config.Model != "gpt-4o" || agents[0].Status != AgentStatusIdle {
+		t.Errorf("Unexpected reloaded agent: %+v", agents[0])
+	}
+
+	versions, _ := service.ListAgentVersions(ctx, kept.ID)
+	if len(versions) != 2 || versions[0].Config.Model != "gpt-4" {
+		t.Errorf("Expected version history to survive restart, got %+v", versions)
+	}
+}
+
+// failingRepository rejects every write
+type failingRepository struct{ FileRepository }
+
+func (f *failingRepository) SaveAgent(agent *Agent, version *AgentVersion) error {
+	return errors.New("disk full")
+}
+
+func TestRegistryWriteFailureLeavesCacheUnchanged(t *testing.T) {
+	registry, err := NewAgentRegistryWithRepository(&failingRepository{FileRepository{agents: map[string]*agentRecord{}}})
+	if err != nil {
+		t.Fatalf("NewAgentRegistryWithRepository failed: %v", err)
+	}
+
+	if err := registry.Register(&Agent{ID: "a1", Name: "a", Type: AgentTypeGeneral}); err == nil {
+		t.Fatal("Expected write failure")
+	}
+	if registry.Count() != 0 {
+		t.Error("Agent should not be cached when persisting fails")
+	}
+}
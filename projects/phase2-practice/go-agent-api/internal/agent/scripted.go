@@ -0,0 +1,142 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// ScriptRule maps a matching request to a canned reply. Empty match fields
+// match anything; the first matching rule wins.
+type ScriptRule struct {
+	// Model matches the requested model exactly
+	Model string `json:"model,omitempty"`
+	// SystemContains matches a substring of the system prompt
+	SystemContains string `json:"system_contains,omitempty"`
+	// Contains matches a substring of the last user message
+	Contains string `json:"contains,omitempty"`
+	// Pattern matches a regular expression against the last user message
+	Pattern string `json:"pattern,omitempty"`
+	Reply   string `json:"reply"`
+
+	pattern *regexp.Regexp
+}
+
+// Script is the definition of a ScriptedClient, usually loaded from JSON
+type Script struct {
+	Rules   []ScriptRule `json:"rules"`
+	Default string       `json:"default"`
+}
+
+// ScriptedClient is an offline LLMClient that answers from a script. It is
+// used for evaluations and demos without network access.
+type ScriptedClient struct {
+	script Script
+	calls  int
+	mu     sync.Mutex
+}
+
+// NewScriptedClient creates a scripted client, compiling rule patterns
+func NewScriptedClient(script Script) (*ScriptedClient, error) {
+	for i := range script.Rules {
+		if script.Rules[i].Pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile(script.Rules[i].Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern in rule %d: %w", i, err)
+		}
+		script.Rules[i].pattern = re
+	}
+	return &ScriptedClient{script: script}, nil
+}
+
+// LoadScriptedClient creates a scripted client from a JSON script file
+func LoadScriptedClient(path string) (*ScriptedClient, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read script: %w", err)
+	}
+
+	var script Script
+	if err := json.Unmarshal(data, &script); err != nil {
+		return nil, fmt.Errorf("failed to parse script: %w", err)
+	}
+	return NewScriptedClient(script)
+}
+
+// CreateChatCompletion implements LLMClient
+func (c *ScriptedClient) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return openai.ChatCompletionResponse{}, err
+	}
+
+	c.mu.Lock()
+	c.calls++
+	c.mu.Unlock()
+
+	var system, user string
+	for _, msg := range req.Messages {
+		switch msg.Role {
+		case openai.ChatMessageRoleSystem:
+			system = msg.Content
+		case openai.ChatMessageRoleUser:
+			user = msg.Content
+		}
+	}
+
+	reply := c.script.Default
+	for _, rule := range c.script.Rules {
+		if rule.matches(req.Model, system, user) {
+			reply = rule.Reply
+			break
+		}
+	}
+
+	// Approximate token usage so reports have something to compare
+	promptTokens := len(strings.Fields(system)) + len(strings.Fields(user))
+	completionTokens := len(strings.Fields(reply))
+
+	return openai.ChatCompletionResponse{
+		ID:    fmt.Sprintf("scripted-%d", c.Calls()),
+		Model: req.Model,
+		Choices: []openai.ChatCompletionChoice{{
+			Message:      openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: reply},
+			FinishReason: openai.FinishReasonStop,
+		}},
+		Usage: openai.Usage{
+			PromptTokens:     promptTokens,
+			CompletionTokens: completionTokens,
+			TotalTokens:      promptTokens + completionTokens,
+		},
+	}, nil
+}
+
+// Calls returns how many completions the client has served
+func (c *ScriptedClient) Calls() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls
+}
+
+func (r *ScriptRule) matches(model, system, user string) bool {
+	if r.Model != "" && r.Model != model {
+		return false
+	}
+	if r.SystemContains != "" && !strings.Contains(system, r.SystemContains) {
+		return false
+	}
+	if r.Contains != "" && !strings.Contains(user, r.Contains) {
+		return false
+	}
+	if r.pattern != nil && !r.pattern.MatchString(user) {
+		return false
+	}
+	return true
+}
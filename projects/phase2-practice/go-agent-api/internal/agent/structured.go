@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/agent-learning/go-agent-api/internal/jsonschema"
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	// DefaultSchemaRetries is used when a task sets an OutputSchema but no SchemaRetries
+	DefaultSchemaRetries = 2
+	// MaxSchemaRetries caps the retries a single task may request
+	MaxSchemaRetries = 5
+)
+
+// executeStructured requests JSON output, validates it against the task's
+// schema and retries with the validation errors fed back to the model
+func (s *agentService) executeStructured(ctx context.Context, agent *Agent, task *Task, messages []openai.ChatCompletionMessage, startTime time.Time) (*TaskResult, error) {
+	schema, err := jsonschema.Compile(task.OutputSchema)
+	if err != nil {
+		err = fmt.Errorf("invalid output_schema: %w", err)
+		return failedResult(task, startTime, err), err
+	}
+
+	// Tell the model what shape we expect
+	messages[0].Content += "\n\nRespond only with a JSON value that conforms to this JSON Schema:\n" + string(task.OutputSchema)
+
+	format := responseFormatFor(schema)
+	retries := schemaRetries(task.SchemaRetries)
+	tokensUsed := 0
+
+	var lastErrors string
+	for attempt := 1; attempt <= retries+1; attempt++ {
+		resp, hit, err := s.complete(ctx, agent, task, messages, format, nil)
+		if err != nil {
+			return failedResult(task, startTime, err), err
+		}
+		if hit == nil {
+			tokensUsed += resp.Usage.TotalTokens
+		}
+
+		content := firstChoiceContent(resp)
+		value, errs, parseErr := schema.ValidateJSON([]byte(stripCodeFence(content)))
+		switch {
+		case parseErr != nil:
+			lastErrors = parseErr.Error()
+		case len(errs) > 0:
+			lastErrors = jsonschema.FormatErrors(errs)
+		default:
+			metadata := responseMetadata(resp)
+			s.recordCache(metadata, agent, task, hit)
+			metadata["tokens_used"] = tokensUsed
+			metadata["schema_attempts"] = attempt
+
+			return &TaskResult{
+				TaskID:           task.ID,
+				Status:           TaskStatusCompleted,
+				Output:           content,
+				StructuredOutput: value,
+				CreatedAt:        startTime,
+				EndedAt:          time.Now(),
+				Duration:         time.Since(startTime).Milliseconds(),
+				Metadata:         metadata,
+			}, nil
+		}
+
+		// Feed the invalid reply and the errors back for the next attempt
+		messages = append(messages,
+			openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: content},
+			openai.ChatCompletionMessage{
+				Role:    openai.ChatMessageRoleUser,
+				Content: "Your previous reply did not validate against the schema:\n" + lastErrors + "\nReply again with corrected JSON only.",
+			},
+		)
+	}
+
+	err = fmt.Errorf("output failed schema validation after %d attempts: %s", retries+1, lastErrors)
+	return failedResult(task, startTime, err), err
+}
+
+// responseFormatFor picks the response_format for a schema. JSON mode only
+// guarantees an object, so it is requested only when the root must be an
+// object; arrays and scalars rely on the prompt and validation retries.
+func responseFormatFor(schema *jsonschema.Schema) *openai.ChatCompletionResponseFormat {
+	if len(schema.Types) != 1 || schema.Types[0] != "object" {
+		return nil
+	}
+	return &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}
+}
+
+// schemaRetries normalizes the retry count requested by a task
+func schemaRetries(requested int) int {
+	if requested <= 0 {
+		return DefaultSchemaRetries
+	}
+	if requested > MaxSchemaRetries {
+		return MaxSchemaRetries
+	}
+	return requested
+}
+
+// stripCodeFence removes a surrounding ```json fence some models add
+func stripCodeFence(content string) string {
+	trimmed := strings.TrimSpace(content)
+	if !strings.HasPrefix(trimmed, "```") {
+		return trimmed
+	}
+	trimmed = strings.TrimPrefix(trimmed, "```")
+	if newline := strings.Index(trimmed, "\n"); newline >= 0 {
+		trimmed = trimmed[newline+1:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(trimmed), "```"))
+}
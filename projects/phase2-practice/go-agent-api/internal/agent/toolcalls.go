@@ -0,0 +1,137 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// MaxToolRounds caps how many times the model may call tools for one task
+const MaxToolRounds = 5
+
+// toolParameters is the JSON Schema of every tool's arguments: tools take
+// a single string input
+var toolParameters = json.RawMessage(`{"type":"object","properties":{"input":{"type":"string"}},"required":["input"]}`)
+
+// taskTools returns the tools a task may call: the task's own list, or the
+// agent's configured tools. Tools the executor does not know are left out.
+func (s *agentService) taskTools(agent *Agent, task *Task) []openai.Tool {
+	if s.toolExecutor == nil {
+		return nil
+	}
+
+	names := task.Tools
+	if len(names) == 0 {
+		names = agent.Config.Tools
+	}
+
+	defs := make([]openai.Tool, 0, len(names))
+	for _, name := range names {
+		description, ok := s.toolExecutor.Description(name)
+		if !ok {
+			continue
+		}
+		defs = append(defs, openai.Tool{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        name,
+				Description: description,
+				Parameters:  toolParameters,
+			},
+		})
+	}
+	return defs
+}
+
+// executeWithTools runs a chat completion that may call tools, executing
+// the requested calls and feeding their output back until the model
+// answers. Guardrail findings on tool output are added to the task.
+func (s *agentService) executeWithTools(ctx context.Context, agent *Agent, task *Task, messages []openai.ChatCompletionMessage, defs []openai.Tool, startTime time.Time) (*TaskResult, error) {
+	tokensUsed := 0
+	toolCalls := 0
+
+	for round := 0; round <= MaxToolRounds; round++ {
+		// The last round offers no tools so the model has to answer
+		offered := defs
+		if round == MaxToolRounds {
+			offered = nil
+		}
+
+		resp, _, err := s.complete(ctx, agent, task, messages, nil, offered)
+		if err != nil {
+			return failedResult(task, startTime, err), err
+		}
+		tokensUsed += resp.Usage.TotalTokens
+
+		if len(resp.Choices) == 0 || len(resp.Choices[0].Message.ToolCalls) == 0 {
+			metadata := responseMetadata(resp)
+			metadata["tokens_used"] = tokensUsed
+			metadata["tool_calls"] = toolCalls
+
+			return &TaskResult{
+				TaskID:     task.ID,
+				Status:     TaskStatusCompleted,
+				Output:     firstChoiceContent(resp),
+				CreatedAt:  startTime,
+				EndedAt:    time.Now(),
+				Duration:   time.Since(startTime).Milliseconds(),
+				Metadata:   metadata,
+				Guardrails: task.Guardrails,
+			}, nil
+		}
+
+		reply := resp.Choices[0].Message
+		messages = append(messages, reply)
+		for _, call := range reply.ToolCalls {
+			toolCalls++
+			messages = append(messages, openai.ChatCompletionMessage{
+				Role:       openai.ChatMessageRoleTool,
+				Content:    s.callTool(ctx, task, defs, call),
+				ToolCallID: call.ID,
+			})
+		}
+	}
+
+	err := fmt.Errorf("model kept calling tools after %d rounds", MaxToolRounds)
+	return failedResult(task, startTime, err), err
+}
+
+// callTool executes one tool call and returns the message content handed
+// back to the model
+func (s *agentService) callTool(ctx context.Context, task *Task, defs []openai.Tool, call openai.ToolCall) string {
+	name := call.Function.Name
+	if !offersTool(defs, name) {
+		return fmt.Sprintf("error: tool %s is not available", name)
+	}
+
+	var args struct {
+		Input string `json:"input"`
+	}
+	input := call.Function.Arguments
+	if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err == nil {
+		input = args.Input
+	}
+
+	result, err := s.toolExecutor.Execute(ctx, name, input)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	recordGuardrails(task, result.Guardrails)
+	if !result.Success {
+		return "error: " + result.Error
+	}
+	return result.Output
+}
+
+// offersTool reports whether a tool was offered to the model
+func offersTool(defs []openai.Tool, name string) bool {
+	for _, def := range defs {
+		if def.Function.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/agent-learning/go-agent-api/internal/guardrails"
+	"github.com/agent-learning/go-agent-api/internal/tools"
+	"github.com/sashabaranov/go-openai"
+)
+
+// lookupTool returns a canned contact record
+type lookupTool struct{}
+
+func (lookupTool) Name() string        { return "lookup" }
+func (lookupTool) Description() string { return "Look up a customer" }
+func (lookupTool) Execute(ctx context.Context, input string) (string, error) {
+	return "Customer " + input + " can be reached at jane@corp.example", nil
+}
+
+// toolCallingClient asks for one lookup call, then answers
+type toolCallingClient struct {
+	requests []openai.ChatCompletionRequest
+}
+
+func (c *toolCallingClient) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	c.requests = append(c.requests, req)
+	message := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: "Done."}
+	if len(c.requests) == 1 {
+		message = openai.ChatCompletionMessage{
+			Role: openai.ChatMessageRoleAssistant,
+			ToolCalls: []openai.ToolCall{{
+				ID:       "call-1",
+				Type:     openai.ToolTypeFunction,
+				Function: openai.FunctionCall{Name: "lookup", Arguments: `{"input": "42"}`},
+			}},
+		}
+	}
+	return openai.ChatCompletionResponse{
+		Model:   req.Model,
+		Choices: []openai.ChatCompletionChoice{{Message: message}},
+		Usage:   openai.Usage{TotalTokens: 10},
+	}, nil
+}
+
+func TestExecuteTaskScreensToolOutput(t *testing.T) {
+	pipeline, err := guardrails.New(guardrails.Config{PIIAction: guardrails.ActionRedact})
+	if err != nil {
+		t.Fatalf("guardrails.New failed: %v", err)
+	}
+	registry := tools.NewToolRegistry()
+	registry.Register(lookupTool{})
+	executor := tools.NewToolExecutor(registry)
+	executor.SetGuardrails(pipeline)
+
+	client := &toolCallingClient{}
+	service := NewAgentServiceWithClient(client, WithGuardrails(pipeline), WithToolExecutor(executor))
+	ag, _ := service.CreateAgent(context.Background(), &CreateAgentRequest{Name: "support", Type: AgentTypeGeneral})
+
+	task := &Task{ID: "t1", Input: "Find customer 42", Tools: []string{"lookup"}}
+	result, err := service.ExecuteTask(context.Background(), ag, task)
+	if err != nil {
+		t.Fatalf("ExecuteTask failed: %v", err)
+	}
+
+	if len(client.requests) != 2 || len(client.requests[0].Tools) != 1 {
+		t.Fatalf("Expected a tool round trip, got %d requests", len(client.requests))
+	}
+	toolReply := client.requests[1].Messages[3]
+	if toolReply.Role != openai.ChatMessageRoleTool || toolReply.ToolCallID != "call-1" || strings.Contains(toolReply.Content, "jane@") {
+		t.Errorf("Expected the redacted tool output to reach the model, got %+v", toolReply)
+	}
+
+	if len(task.Guardrails) != 1 || task.Guardrails[0].Stage != guardrails.StageToolOutput || task.Guardrails[0].Tool != "lookup" {
+		t.Fatalf("Expected the tool finding on the task, got %+v", task.Guardrails)
+	}
+	if len(result.Guardrails) != 1 || result.Output != "Done." || result.Metadata["tool_calls"] != 1 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+}
@@ -0,0 +1,138 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/agent-learning/go-agent-api/internal/guardrails"
+	"github.com/agent-learning/go-agent-api/internal/transcript"
+	"github.com/google/uuid"
+	"github.com/sashabaranov/go-openai"
+)
+
+// WithTranscripts records a transcript of every task execution into store
+func WithTranscripts(store transcript.Store) Option {
+	return func(s *agentService) {
+		s.transcripts = store
+	}
+}
+
+// ReplayResult is the outcome of re-running a task against its transcript
+type ReplayResult struct {
+	// Result is the result of the replayed execution, under a new task ID
+	Result *TaskResult `json:"result"`
+	// Divergences lists calls that differ from the recording. A replay of
+	// unchanged code and configuration has none.
+	Divergences    []transcript.Divergence `json:"divergences"`
+	RecordedOutput string                  `json:"recorded_output"`
+	OutputMatches  bool                    `json:"output_matches"`
+}
+
+// Replay re-runs a recorded task with the recorded agent configuration,
+// serving LLM and tool calls from the transcript instead of live providers.
+// The recorded agent is a detached copy: the live agent's status is not changed.
+func Replay(ctx context.Context, service AgentService, recorded *transcript.Transcript) (*ReplayResult, error) {
+	var agent Agent
+	if err := json.Unmarshal(recorded.Agent, &agent); err != nil {
+		return nil, fmt.Errorf("failed to decode recorded agent: %w", err)
+	}
+	var task Task
+	if err := json.Unmarshal(recorded.Task, &task); err != nil {
+		return nil, fmt.Errorf("failed to decode recorded task: %w", err)
+	}
+
+	// The replay gets its own ID so it never overwrites the original transcript
+	task.ID = uuid.New().String()
+	task.Output = ""
+	task.Error = ""
+	task.Guardrails = nil
+
+	replayer := transcript.NewReplayer(recorded)
+	result, err := service.ExecuteTask(transcript.WithReplayer(ctx, replayer), &agent, &task)
+	if result == nil {
+		return nil, fmt.Errorf("replay failed: %w", err)
+	}
+
+	return &ReplayResult{
+		Result:         result,
+		Divergences:    replayer.Divergences(),
+		RecordedOutput: recorded.Output,
+		OutputMatches:  result.Status == TaskStatus(recorded.Status) && result.Output == recorded.Output,
+	}, nil
+}
+
+// startTranscript returns a context that records into a new transcript.
+// Tasks still run when the snapshot fails, just without a transcript.
+func (s *agentService) startTranscript(ctx context.Context, agent *Agent, task *Task) (context.Context, *transcript.Recorder) {
+	snapshot := *task
+	if s.guardrails != nil {
+		snapshot.Input = redacted(s.guardrails.CheckInput, task.Input)
+	}
+	recorder, err := transcript.NewRecorder(task.ID, &snapshot, agent)
+	if err != nil {
+		log.Printf("Transcript for task %s disabled: %v", task.ID, err)
+		return ctx, nil
+	}
+	if replayer := transcript.ReplayerFrom(ctx); replayer != nil {
+		recorder.SetReplayOf(replayer.Source().TaskID)
+	}
+	return transcript.WithRecorder(ctx, recorder), recorder
+}
+
+// finishTranscript stores the transcript of a finished task
+func (s *agentService) finishTranscript(recorder *transcript.Recorder, result *TaskResult, err error) {
+	if recorder == nil {
+		return
+	}
+
+	status, output, errMsg := string(TaskStatusFailed), "", ""
+	if result != nil {
+		status, output, errMsg = string(result.Status), result.Output, result.Error
+	}
+	if err != nil && errMsg == "" {
+		errMsg = err.Error()
+	}
+
+	t := recorder.Finish(status, output, errMsg)
+	if err := s.transcripts.Save(t); err != nil {
+		log.Printf("Failed to save transcript for task %s: %v", t.TaskID, err)
+	}
+}
+
+// redacted returns text as the guardrails let it through, so a transcript
+// holds nothing the pipeline redacts. Text a guardrail blocks is left out.
+func redacted(check func(string) *guardrails.Result, text string) string {
+	if text == "" {
+		return text
+	}
+	result := check(text)
+	if result.Blocked {
+		return ""
+	}
+	return result.Text
+}
+
+// recordLLM adds a chat completion to the transcript of ctx. Model output,
+// in the response and in earlier assistant turns, is recorded redacted.
+func (s *agentService) recordLLM(ctx context.Context, startedAt time.Time, req openai.ChatCompletionRequest, resp openai.ChatCompletionResponse, cached bool, err error) {
+	recorder := transcript.RecorderFrom(ctx)
+	if recorder == nil {
+		return
+	}
+	if s.guardrails != nil {
+		req.Messages = append([]openai.ChatCompletionMessage(nil), req.Messages...)
+		for i, message := range req.Messages {
+			if message.Role == openai.ChatMessageRoleAssistant {
+				req.Messages[i].Content = redacted(s.guardrails.CheckOutput, message.Content)
+			}
+		}
+		resp.Choices = append([]openai.ChatCompletionChoice(nil), resp.Choices...)
+		for i, choice := range resp.Choices {
+			resp.Choices[i].Message.Content = redacted(s.guardrails.CheckOutput, choice.Message.Content)
+		}
+	}
+	recorder.RecordLLM(startedAt, req, resp, cached, err)
+}
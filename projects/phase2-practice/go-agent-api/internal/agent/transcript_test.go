@@ -0,0 +1,148 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/agent-learning/go-agent-api/internal/guardrails"
+	"github.com/agent-learning/go-agent-api/internal/transcript"
+)
+
+func TestExecuteTaskRecordsTranscript(t *testing.T) {
+	store := transcript.NewMemoryStore(10)
+	client := &fakeLLMClient{replies: []string{"Go is a language"}}
+	service := NewAgentServiceWithClient(client, WithTranscripts(store))
+	ctx := context.Background()
+
+	ag, _ := service.CreateAgent(ctx, &CreateAgentRequest{Name: "qa", Type: AgentTypeDocQA})
+	if _, err := service.ExecuteTask(ctx, ag, &Task{ID: "t1", AgentID: ag.ID, Input: "What is Go?"}); err != nil {
+		t.Fatalf("ExecuteTask failed: %v", err)
+	}
+
+	recorded, err := store.Get("t1")
+	if err != nil {
+		t.Fatalf("Expected a transcript: %v", err)
+	}
+	if recorded.Status != string(TaskStatusCompleted) || recorded.Output != "Go is a language" || len(recorded.Events) != 1 {
+		t.Fatalf("Unexpected transcript: %+v", recorded)
+	}
+	call := recorded.Events[0].LLM
+	if call == nil || len(call.Request.Messages) != 2 || call.Request.Messages[1].Content != "What is Go?" || call.Response == nil {
+		t.Errorf("Expected the rendered request and raw response, got %+v", call)
+	}
+}
+
+func TestReplayReproducesRecordedRun(t *testing.T) {
+	store := transcript.NewMemoryStore(10)
+	client := &fakeLLMClient{replies: []string{"recorded answer"}}
+	service := NewAgentServiceWithClient(client, WithTranscripts(store))
+	ctx := context.Background()
+
+	ag, _ := service.CreateAgent(ctx, &CreateAgentRequest{Name: "qa", Type: AgentTypeDocQA})
+	service.ExecuteTask(ctx, ag, &Task{ID: "t1", AgentID: ag.ID, Input: "What is Go?"})
+	recorded, _ := store.Get("t1")
+
+	// Export and re-import, as when debugging a production incident
+	data, _ := json.Marshal(recorded)
+	var imported transcript.Transcript
+	if err := json.Unmarshal(data, &imported); err != nil {
+		t.Fatalf("Failed to decode exported transcript: %v", err)
+	}
+
+	replay, err := Replay(ctx, service, &imported)
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if !replay.OutputMatches || len(replay.Divergences) != 0 || replay.Result.TaskID == "t1" {
+		t.Errorf("Expected a faithful replay, got %+v", replay)
+	}
+	if len(client.requests) != 1 {
+		t.Errorf("Expected replay not to call the LLM, got %d calls", len(client.requests))
+	}
+
+	replayed, err := store.Get(replay.Result.TaskID)
+	if err != nil || replayed.ReplayOf != "t1" {
+		t.Errorf("Expected the replay to be recorded as a replay of t1, got %+v", replayed)
+	}
+}
+
+func TestReplayReportsDivergence(t *testing.T) {
+	store := transcript.NewMemoryStore(10)
+	service := NewAgentServiceWithClient(&fakeLLMClient{replies: []string{"answer"}}, WithTranscripts(store))
+	ctx := context.Background()
+
+	ag, _ := service.CreateAgent(ctx, &CreateAgentRequest{Name: "qa", Type: AgentTypeDocQA})
+	service.ExecuteTask(ctx, ag, &Task{ID: "t1", AgentID: ag.ID, Input: "What is Go?"})
+	recorded, _ := store.Get("t1")
+
+	// A changed agent type renders a different system prompt
+	var snapshot Agent
+	json.Unmarshal(recorded.Agent, &snapshot)
+	snapshot.Type = AgentTypeGeneral
+	edited := *recorded
+	edited.Agent, _ = json.Marshal(snapshot)
+
+	replay, err := Replay(ctx, service, &edited)
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if len(replay.Divergences) != 1 || replay.Divergences[0].Seq != 1 {
+		t.Errorf("Expected one divergence on the LLM call, got %+v", replay.Divergences)
+	}
+	// The recorded response is still served
+	if !replay.OutputMatches {
+		t.Errorf("Expected the recorded output, got %q", replay.Result.Output)
+	}
+}
+
+func TestReplayLeavesLiveAgentStatus(t *testing.T) {
+	store := transcript.NewMemoryStore(10)
+	service := NewAgentServiceWithClient(&fakeLLMClient{replies: []string{"answer"}}, WithTranscripts(store))
+	ctx := context.Background()
+
+	ag, _ := service.CreateAgent(ctx, &CreateAgentRequest{Name: "qa", Type: AgentTypeDocQA})
+	service.ExecuteTask(ctx, ag, &Task{ID: "t1", AgentID: ag.ID, Input: "What is Go?"})
+	recorded, _ := store.Get("t1")
+
+	// The live agent is busy with another task while the replay runs
+	service.(*agentService).registry.SetStatus(ag.ID, AgentStatusBusy)
+	if _, err := Replay(ctx, service, recorded); err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+
+	live, _ := service.GetAgent(ctx, ag.ID)
+	if live.Status != AgentStatusBusy {
+		t.Errorf("Expected the replay to leave the live agent busy, got %s", live.Status)
+	}
+}
+
+func TestTranscriptRecordsRedactedContent(t *testing.T) {
+	pipeline, err := guardrails.New(guardrails.Config{PIIAction: guardrails.ActionRedact})
+	if err != nil {
+		t.Fatalf("guardrails.New failed: %v", err)
+	}
+	store := transcript.NewMemoryStore(10)
+	client := &fakeLLMClient{replies: []string{"I emailed ops@corp.example"}}
+	service := NewAgentServiceWithClient(client, WithGuardrails(pipeline), WithTranscripts(store))
+	ctx := context.Background()
+
+	ag, _ := service.CreateAgent(ctx, &CreateAgentRequest{Name: "qa", Type: AgentTypeGeneral})
+	service.ExecuteTask(ctx, ag, &Task{ID: "t1", AgentID: ag.ID, Input: "Email jane@corp.example the report"})
+	recorded, _ := store.Get("t1")
+
+	data, _ := json.Marshal(recorded)
+	if strings.Contains(string(data), "jane@") || strings.Contains(string(data), "ops@") {
+		t.Fatalf("Expected the transcript to hold redacted content only, got %s", data)
+	}
+
+	// The redacted recording still replays faithfully
+	replay, err := Replay(ctx, service, recorded)
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if !replay.OutputMatches || len(replay.Divergences) != 0 {
+		t.Errorf("Expected a faithful replay, got %+v", replay)
+	}
+}
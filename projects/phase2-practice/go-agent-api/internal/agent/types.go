@@ -1,7 +1,10 @@
 package agent
 
 import (
+	"encoding/json"
 	"time"
+
+	"github.com/agent-learning/go-agent-api/internal/guardrails"
 )
 
 // AgentType defines the type of agent
@@ -31,6 +34,13 @@ type AgentConfig struct {
 	MaxTokens   int                    `json:"max_tokens"`
 	Tools       []string               `json:"tools"`
 	Extra       map[string]interface{} `json:"extra"`
+
+	// WebhookURL is the default callback for tasks run by this agent
+	WebhookURL string `json:"webhook_url,omitempty"`
+	// MaxConcurrent limits how many of this agent's tasks run at once (0 = no per-agent limit)
+	MaxConcurrent int `json:"max_concurrent,omitempty"`
+	// CacheTTL overrides the response cache TTL in seconds (negative disables caching)
+	CacheTTL int `json:"cache_ttl,omitempty"`
 }
 
 // Agent represents an agent instance
@@ -40,6 +50,7 @@ type Agent struct {
 	Type      AgentType    `json:"type"`
 	Status    AgentStatus  `json:"status"`
 	Config    AgentConfig  `json:"config"`
+	Version   int          `json:"version"`
 	CreatedAt time.Time    `json:"created_at"`
 	UpdatedAt time.Time    `json:"updated_at"`
 	Error     string       `json:"error,omitempty"`
@@ -67,6 +78,7 @@ const (
 type TaskStatus string
 
 const (
+	TaskStatusScheduled  TaskStatus = "scheduled"
 	TaskStatusPending    TaskStatus = "pending"
 	TaskStatusRunning    TaskStatus = "running"
 	TaskStatusCompleted  TaskStatus = "completed"
@@ -90,6 +102,28 @@ type Task struct {
 	UpdatedAt time.Time              `json:"updated_at"`
 	StartedAt *time.Time             `json:"started_at,omitempty"`
 	EndedAt   *time.Time             `json:"ended_at,omitempty"`
+
+	// OutputSchema is an optional JSON Schema the output must satisfy
+	OutputSchema json.RawMessage `json:"output_schema,omitempty"`
+	// SchemaRetries is how many times a reply failing validation is retried
+	SchemaRetries int `json:"schema_retries,omitempty"`
+
+	// RunAt delays execution until the given time
+	RunAt *time.Time `json:"run_at,omitempty"`
+
+	// CallbackURL receives a webhook when the task finishes
+	CallbackURL string `json:"callback_url,omitempty"`
+	// CallbackSecret overrides the server's webhook signing secret
+	CallbackSecret string `json:"-"`
+	// Submitter identifies the API key that submitted the task
+	Submitter string `json:"submitter,omitempty"`
+	// IdempotencyKey is the client-supplied key the task was submitted with
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	// RequestHash fingerprints the submitted request to detect key reuse
+	RequestHash string `json:"-"`
+
+	// Guardrails lists every guardrail rule triggered while executing the task
+	Guardrails []guardrails.Finding `json:"guardrails,omitempty"`
 }
 
 // CreateTaskRequest represents a request to create a task
@@ -100,6 +134,18 @@ type CreateTaskRequest struct {
 	Priority int                    `json:"priority"`
 	Tools    []string               `json:"tools"`
 	Metadata map[string]interface{} `json:"metadata"`
+
+	OutputSchema  json.RawMessage `json:"output_schema,omitempty"`
+	SchemaRetries int             `json:"schema_retries,omitempty"`
+
+	CallbackURL    string `json:"callback_url,omitempty"`
+	CallbackSecret string `json:"callback_secret,omitempty"`
+
+	// RunAt schedules the task for later instead of queueing it immediately
+	RunAt *time.Time `json:"run_at,omitempty"`
+
+	// Submitter is filled in from the request's API key, never from the body
+	Submitter string `json:"-"`
 }
 
 // TaskResult represents the result of a task execution
@@ -112,4 +158,10 @@ type TaskResult struct {
 	Duration  int64                  `json:"duration_ms"`
 	CreatedAt time.Time              `json:"created_at"`
 	EndedAt   time.Time              `json:"ended_at"`
+
+	// StructuredOutput holds the parsed JSON output when the task
+	// carried an OutputSchema
+	StructuredOutput interface{} `json:"structured_output,omitempty"`
+	// Guardrails lists the guardrail rules triggered for the task
+	Guardrails []guardrails.Finding `json:"guardrails,omitempty"`
 }
@@ -0,0 +1,178 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// AgentVersion is an immutable snapshot of an agent definition
+type AgentVersion struct {
+	Version   int         `json:"version"`
+	Name      string      `json:"name"`
+	Type      AgentType   `json:"type"`
+	Config    AgentConfig `json:"config"`
+	Comment   string      `json:"comment,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// UpdateAgentRequest represents a partial update of an agent. Config is a
+// JSON merge patch (RFC 7386) applied to the current config.
+type UpdateAgentRequest struct {
+	Name   *string         `json:"name,omitempty"`
+	Type   *AgentType      `json:"type,omitempty"`
+	Config json.RawMessage `json:"config,omitempty"`
+	// ExpectedVersion is the version the update is based on (0 skips the check)
+	ExpectedVersion int    `json:"version,omitempty"`
+	Comment         string `json:"comment,omitempty"`
+}
+
+// RollbackAgentRequest restores the definition of a previous version
+type RollbackAgentRequest struct {
+	Version         int `json:"version" binding:"required"`
+	ExpectedVersion int `json:"expected_version,omitempty"`
+}
+
+func snapshot(agent *Agent, comment string) *AgentVersion {
+	return &AgentVersion{
+		Version:   agent.Version,
+		Name:      agent.Name,
+		Type:      agent.Type,
+		Config:    agent.Config,
+		Comment:   comment,
+		CreatedAt: time.Now(),
+	}
+}
+
+// UpdateAgent applies a partial update and records a new version. Updates
+// that change nothing return the agent without a new version.
+func (s *agentService) UpdateAgent(ctx context.Context, id string, req *UpdateAgentRequest) (*Agent, error) {
+	current, err := s.registry.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if req.ExpectedVersion != 0 && req.ExpectedVersion != current.Version {
+		return nil, fmt.Errorf("%w: expected version %d, current version is %d", ErrVersionConflict, req.ExpectedVersion, current.Version)
+	}
+
+	name, agentType, config := current.Name, current.Type, current.Config
+	if req.Name != nil {
+		if *req.Name == "" {
+			return nil, fmt.Errorf("name cannot be empty")
+		}
+		name = *req.Name
+	}
+	if req.Type != nil {
+		if *req.Type == "" {
+			return nil, fmt.Errorf("type cannot be empty")
+		}
+		agentType = *req.Type
+	}
+	if len(req.Config) > 0 {
+		if config, err = patchConfig(current.Config, req.Config); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.defaults.checkTools(config.Tools); err != nil {
+		return nil, err
+	}
+
+	if name == current.Name && agentType == current.Type && reflect.DeepEqual(config, current.Config) {
+		return current, nil
+	}
+
+	comment := req.Comment
+	if comment == "" {
+		comment = "updated"
+	}
+	return s.registry.UpdateVersioned(id, current.Version, comment, func(agent *Agent) error {
+		agent.Name = name
+		agent.Type = agentType
+		agent.Config = config
+		return nil
+	})
+}
+
+// ListAgentVersions returns the version history of an agent, oldest first
+func (s *agentService) ListAgentVersions(ctx context.Context, id string) ([]*AgentVersion, error) {
+	return s.registry.Versions(id)
+}
+
+// RollbackAgent restores a previous version's definition as a new version,
+// so history is never rewritten
+func (s *agentService) RollbackAgent(ctx context.Context, id string, req *RollbackAgentRequest) (*Agent, error) {
+	versions, err := s.registry.Versions(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var target *AgentVersion
+	for _, v := range versions {
+		if v.Version == req.Version {
+			target = v
+			break
+		}
+	}
+	if target == nil {
+		return nil, fmt.Errorf("agent %s has no version %d", id, req.Version)
+	}
+
+	return s.registry.UpdateVersioned(id, req.ExpectedVersion, fmt.Sprintf("rollback to version %d", target.Version), func(agent *Agent) error {
+		agent.Name = target.Name
+		agent.Type = target.Type
+		agent.Config = target.Config
+		return nil
+	})
+}
+
+// patchConfig applies a JSON merge patch to an agent config
+func patchConfig(config AgentConfig, patch json.RawMessage) (AgentConfig, error) {
+	var patchDoc map[string]interface{}
+	if err := json.Unmarshal(patch, &patchDoc); err != nil {
+		return config, fmt.Errorf("config patch must be a JSON object: %w", err)
+	}
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		return config, fmt.Errorf("failed to encode config: %w", err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return config, fmt.Errorf("failed to decode config: %w", err)
+	}
+
+	merged, err := json.Marshal(mergePatch(doc, patchDoc))
+	if err != nil {
+		return config, fmt.Errorf("failed to encode patched config: %w", err)
+	}
+
+	var result AgentConfig
+	if err := json.Unmarshal(merged, &result); err != nil {
+		return config, fmt.Errorf("invalid config patch: %w", err)
+	}
+	return result, nil
+}
+
+// mergePatch implements RFC 7386: objects merge recursively, null deletes
+// and everything else replaces
+func mergePatch(target, patch map[string]interface{}) map[string]interface{} {
+	if target == nil {
+		target = make(map[string]interface{})
+	}
+	for key, value := range patch {
+		if value == nil {
+			delete(target, key)
+			continue
+		}
+		if patchObj, ok := value.(map[string]interface{}); ok {
+			targetObj, _ := target[key].(map[string]interface{})
+			target[key] = mergePatch(targetObj, patchObj)
+			continue
+		}
+		target[key] = value
+	}
+	return target
+}
@@ -0,0 +1,176 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func newVersionedAgent(t *testing.T) (AgentService, *Agent) {
+	t.Helper()
+	service := NewAgentServiceWithClient(&fakeLLMClient{replies: []string{"ok"}})
+	ag, err := service.CreateAgent(context.Background(), &CreateAgentRequest{
+		Name:   "reviewer",
+		Type:   AgentTypeCodeReview,
+		Config: AgentConfig{Model: "gpt-4", Tools: []string{"code"}, Extra: map[string]interface{}{"lang": "go"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateAgent failed: %v", err)
+	}
+	return service, ag
+}
+
+func TestUpdateAgentMergesConfigAndVersions(t *testing.T) {
+	service, ag := newVersionedAgent(t)
+	ctx := context.Background()
+
+	if ag.Version != 1 {
+		t.Fatalf("Expected version 1, got %d", ag.Version)
+	}
+
+	updated, err := service.UpdateAgent(ctx, ag.ID, &UpdateAgentRequest{
+		Config:          json.RawMessage(`{"model": "gpt-4o", "tools": ["code", "search"], "extra": {"lang": null, "style": "strict"}}`),
+		ExpectedVersion: 1,
+	})
+	if err != nil {
+		t.Fatalf("UpdateAgent failed: %v", err)
+	}
+	if updated.ID != ag.ID || updated.Version != 2 {
+		t.Errorf("Expected same ID at version 2, got %s v%d", updated.ID, updated.Version)
+	}
+	if updated.Config.Model != "gpt-4o" || len(updated.Config.Tools) != 2 || updated.Config.MaxTokens != 2000 {
+		t.Errorf("Unexpected merged config: %+v", updated.Config)
+	}
+	if _, ok := updated.Config.Extra["lang"]; ok || updated.Config.Extra["style"] != "strict" {
+		t.Errorf("Expected merge patch on extra, got %v", updated.Config.Extra)
+	}
+
+	// The agent handed out before the update is not mutated
+	if ag.Config.Model != "gpt-4" {
+		t.Error("Earlier agent copy was mutated")
+	}
+
+	// Stale version is rejected
+	_, err = service.UpdateAgent(ctx, ag.ID, &UpdateAgentRequest{Config: json.RawMessage(`{"model": "x"}`), ExpectedVersion: 1})
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("Expected version conflict, got %v", err)
+	}
+
+	// A no-op update does not create a version
+	same, _ := service.UpdateAgent(ctx, ag.ID, &UpdateAgentRequest{Config: json.RawMessage(`{"model": "gpt-4o"}`), ExpectedVersion: 2})
+	if same.Version != 2 {
+		t.Errorf("Expected no new version for a no-op update, got %d", same.Version)
+	}
+}
+
+func TestRollbackAgent(t *testing.T) {
+	service, ag := newVersionedAgent(t)
+	ctx := context.Background()
+
+	name := "reviewer-v2"
+	service.UpdateAgent(ctx, ag.ID, &UpdateAgentRequest{Name: &name, Config: json.RawMessage(`{"temperature": 0.1}`)})
+
+	restored, err := service.RollbackAgent(ctx, ag.ID, &RollbackAgentRequest{Version: 1, ExpectedVersion: 2})
+	if err != nil {
+		t.Fatalf("RollbackAgent failed: %v", err)
+	}
+	if restored.Version != 3 || restored.Name != "reviewer" || restored.Config.Temperature != 0.7 {
+		t.Errorf("Unexpected rollback result: %+v", restored)
+	}
+
+	versions, _ := service.ListAgentVersions(ctx, ag.ID)
+	if len(versions) != 3 || versions[1].Name != "reviewer-v2" || versions[2].Comment != "rollback to version 1" {
+		t.Errorf("Unexpected history: %+v", versions)
+	}
+
+	if _, err := service.RollbackAgent(ctx, ag.ID, &RollbackAgentRequest{Version: 9}); err == nil {
+		t.Error("Expected error for unknown version")
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	service, ag := newVersionedAgent(t)
+	ctx := context.Background()
+
+	defs, _ := service.ExportAgents(ctx, nil)
+	data, err := EncodeAgentBundle(defs, FormatYAML)
+	if err != nil {
+		t.Fatalf("EncodeAgentBundle failed: %v", err)
+	}
+	if !strings.Contains(string(data), "max_tokens: 2000") {
+		t.Errorf("Expected JSON field names in YAML:\n%s", data)
+	}
+
+	decoded, err := DecodeAgentBundle(data)
+	if err != nil {
+		t.Fatalf("DecodeAgentBundle failed: %v", err)
+	}
+
+	// Re-importing unchanged definitions is a no-op
+	result, err := service.ImportAgents(ctx, decoded)
+	if err != nil || len(result.Unchanged) != 1 {
+		t.Fatalf("Expected unchanged import, got %+v, %v", result, err)
+	}
+
+	// Edited definitions update in place; new ones are created
+	decoded[0].Config.Model = "gpt-4o"
+	decoded = append(decoded, &AgentDefinition{ID: "docs-bot", Name: "docs", Type: AgentTypeDocQA})
+	result, err = service.ImportAgents(ctx, decoded)
+	if err != nil {
+		t.Fatalf("ImportAgents failed: %v", err)
+	}
+	if len(result.Updated) != 1 || len(result.Created) != 1 || result.Created[0] != "docs-bot" {
+		t.Errorf("Unexpected import result: %+v", result)
+	}
+
+	updated, _ := service.GetAgent(ctx, ag.ID)
+	if updated.Version != 2 || updated.Config.Model != "gpt-4o" {
+		t.Errorf("Expected import to create version 2, got %+v", updated)
+	}
+
+	// A single JSON definition is accepted too
+	single, err := DecodeAgentBundle([]byte(`{"name": "solo", "type": "general"}`))
+	if err != nil || len(single) != 1 || single[0].Name != "solo" {
+		t.Errorf("Expected single definition, got %v, %v", single, err)
+	}
+}
+
+func TestImportAgentsTwiceIsUnchanged(t *testing.T) {
+	service := NewAgentServiceWithClient(nil)
+	ctx := context.Background()
+
+	// Definitions that leave out model, temperature and max_tokens
+	data := []byte(`agents:
+  - id: triage
+    name: triage
+    type: general
+    config:
+      tools: [search]
+  - id: docs
+    name: docs
+    type: doc_qa
+`)
+	for i := 0; i < 2; i++ {
+		defs, err := DecodeAgentBundle(data)
+		if err != nil {
+			t.Fatalf("DecodeAgentBundle failed: %v", err)
+		}
+		result, err := service.ImportAgents(ctx, defs)
+		if err != nil {
+			t.Fatalf("ImportAgents failed: %v", err)
+		}
+		if i == 1 && (len(result.Unchanged) != 2 || len(result.Updated) != 0 || len(result.Created) != 0) {
+			t.Errorf("Expected the second import to change nothing, got %+v", result)
+		}
+	}
+
+	for _, id := range []string{"triage", "docs"} {
+		ag, _ := service.GetAgent(ctx, id)
+		if ag.Version != 1 || ag.Config.Model != BuiltinDefaults.Model ||
+			ag.Config.Temperature != BuiltinDefaults.Temperature || ag.Config.MaxTokens != BuiltinDefaults.MaxTokens {
+			t.Errorf("Expected %s to keep its defaulted config at version 1, got %+v", id, ag)
+		}
+	}
+}
@@ -1,7 +1,12 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/agent-learning/go-agent-api/internal/agent"
 	"github.com/gin-gonic/gin"
@@ -37,6 +42,10 @@ func (h *AgentHandler) CreateAgent(c *gin.Context) {
 	}
 
 	ag, err := h.service.CreateAgent(c.Request.Context(), &req)
+	if errors.Is(err, agent.ErrToolNotAllowed) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
 		return
@@ -63,6 +72,7 @@ func (h *AgentHandler) GetAgent(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", versionETag(ag.Version))
 	c.JSON(http.StatusOK, ag)
 }
 
@@ -105,6 +115,250 @@ func (h *AgentHandler) DeleteAgent(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// UpdateAgent godoc
+// @Summary Update an agent
+// @Description Partially update an agent. config is merged as a JSON merge patch. The expected version must be sent in the If-Match header or the version field; a stale version returns 409.
+// @Tags agents
+// @Accept json
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Param If-Match header string false "Expected agent version"
+// @Param agent body agent.UpdateAgentRequest true "Agent update request"
+// @Success 200 {object} agent.Agent
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 428 {object} ErrorResponse
+// @Router /api/v1/agents/{id} [patch]
+func (h *AgentHandler) UpdateAgent(c *gin.Context) {
+	var req agent.UpdateAgentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	expected, err := expectedVersion(c, req.ExpectedVersion)
+	if err != nil {
+		c.JSON(http.StatusPreconditionRequired, ErrorResponse{Error: err.Error()})
+		return
+	}
+	req.ExpectedVersion = expected
+
+	ctx := c.Request.Context()
+	if _, err := h.service.GetAgent(ctx, c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	ag, err := h.service.UpdateAgent(ctx, c.Param("id"), &req)
+	if err != nil {
+		c.JSON(versionErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.Header("ETag", versionETag(ag.Version))
+	c.JSON(http.StatusOK, ag)
+}
+
+// ListAgentVersions godoc
+// @Summary List agent versions
+// @Description Get the immutable version history of an agent, oldest first
+// @Tags agents
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Success 200 {object} AgentVersionsResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/agents/{id}/versions [get]
+func (h *AgentHandler) ListAgentVersions(c *gin.Context) {
+	versions, err := h.service.ListAgentVersions(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, AgentVersionsResponse{
+		Versions: versions,
+		Total:    len(versions),
+	})
+}
+
+// GetAgentVersion godoc
+// @Summary Get an agent version
+// @Tags agents
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Param version path int true "Version number"
+// @Success 200 {object} agent.AgentVersion
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/agents/{id}/versions/{version} [get]
+func (h *AgentHandler) GetAgentVersion(c *gin.Context) {
+	number, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "version must be a number"})
+		return
+	}
+
+	versions, err := h.service.ListAgentVersions(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	for _, v := range versions {
+		if v.Version == number {
+			c.JSON(http.StatusOK, v)
+			return
+		}
+	}
+	c.JSON(http.StatusNotFound, ErrorResponse{Error: fmt.Sprintf("version %d not found", number)})
+}
+
+// RollbackAgent godoc
+// @Summary Roll back an agent
+// @Description Restore the definition of a previous version. The rollback is recorded as a new version. The expected version must be sent in the If-Match header or the expected_version field; a stale version returns 409.
+// @Tags agents
+// @Accept json
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Param If-Match header string false "Expected agent version"
+// @Param rollback body agent.RollbackAgentRequest true "Rollback request"
+// @Success 200 {object} agent.Agent
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 428 {object} ErrorResponse
+// @Router /api/v1/agents/{id}/rollback [post]
+func (h *AgentHandler) RollbackAgent(c *gin.Context) {
+	var req agent.RollbackAgentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	expected, err := expectedVersion(c, req.ExpectedVersion)
+	if err != nil {
+		c.JSON(http.StatusPreconditionRequired, ErrorResponse{Error: err.Error()})
+		return
+	}
+	req.ExpectedVersion = expected
+
+	ctx := c.Request.Context()
+	if _, err := h.service.GetAgent(ctx, c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	ag, err := h.service.RollbackAgent(ctx, c.Param("id"), &req)
+	if err != nil {
+		c.JSON(versionErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.Header("ETag", versionETag(ag.Version))
+	c.JSON(http.StatusOK, ag)
+}
+
+// ExportAgents godoc
+// @Summary Export agent definitions
+// @Description Export agent definitions as JSON or YAML so they can be kept in version control
+// @Tags agents
+// @Produce json
+// @Produce application/yaml
+// @Param id query []string false "Agent IDs to export (all when omitted)"
+// @Param format query string false "json (default) or yaml"
+// @Success 200 {object} agent.AgentBundle
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/agents/export [get]
+func (h *AgentHandler) ExportAgents(c *gin.Context) {
+	format := c.DefaultQuery("format", agent.FormatJSON)
+
+	defs, err := h.service.ExportAgents(c.Request.Context(), c.QueryArray("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	data, err := agent.EncodeAgentBundle(defs, format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	contentType := "application/json"
+	if format == agent.FormatYAML {
+		contentType = "application/yaml"
+	}
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// ImportAgents godoc
+// @Summary Import agent definitions
+// @Description Create or update agents from a JSON or YAML bundle. Definitions whose ID exists update that agent as a new version.
+// @Tags agents
+// @Accept json
+// @Accept application/yaml
+// @Produce json
+// @Param bundle body agent.AgentBundle true "Agent definitions"
+// @Success 200 {object} agent.ImportResult
+// @Failure 400 {object} ErrorResponse
+// @Router /api/v1/agents/import [post]
+func (h *AgentHandler) ImportAgents(c *gin.Context) {
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	defs, err := agent.DecodeAgentBundle(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	result, err := h.service.ImportAgents(c.Request.Context(), defs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// expectedVersion reads the version an update is based on from If-Match,
+// falling back to the request body
+func expectedVersion(c *gin.Context, bodyVersion int) (int, error) {
+	if header := c.GetHeader("If-Match"); header != "" {
+		value := strings.Trim(strings.TrimPrefix(strings.TrimSpace(header), "W/"), `"`)
+		version, err := strconv.Atoi(value)
+		if err != nil || version <= 0 {
+			return 0, fmt.Errorf("invalid If-Match version %q", header)
+		}
+		return version, nil
+	}
+	if bodyVersion > 0 {
+		return bodyVersion, nil
+	}
+	return 0, fmt.Errorf("If-Match header or version field is required")
+}
+
+func versionETag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+func versionErrorStatus(err error) int {
+	if errors.Is(err, agent.ErrVersionConflict) {
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+// AgentVersionsResponse represents the response for listing agent versions
+type AgentVersionsResponse struct {
+	Versions []*agent.AgentVersion `json:"versions"`
+	Total    int                   `json:"total"`
+}
+
 // AgentsResponse represents the response for listing agents
 type AgentsResponse struct {
 	Agents []*agent.Agent `json:"agents"`
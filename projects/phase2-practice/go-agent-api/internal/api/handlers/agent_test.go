@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/agent-learning/go-agent-api/internal/agent"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestRollbackAgentRequiresExpectedVersion(t *testing.T) {
+	service := agent.NewAgentServiceWithClient(nil)
+	ctx := context.Background()
+	ag, _ := service.CreateAgent(ctx, &agent.CreateAgentRequest{Name: "qa", Type: agent.AgentTypeGeneral})
+	name := "qa-v2"
+	if _, err := service.UpdateAgent(ctx, ag.ID, &agent.UpdateAgentRequest{Name: &name}); err != nil {
+		t.Fatalf("UpdateAgent failed: %v", err)
+	}
+
+	router := gin.New()
+	router.POST("/agents/:id/rollback", NewAgentHandler(service).RollbackAgent)
+	rollback := func(ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/agents/"+ag.ID+"/rollback", strings.NewReader(`{"version": 1}`))
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := rollback(""); w.Code != http.StatusPreconditionRequired {
+		t.Errorf("Expected 428 without If-Match, got %d", w.Code)
+	}
+	if w := rollback(`"1"`); w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for a stale version, got %d", w.Code)
+	}
+	if w := rollback(`"2"`); w.Code != http.StatusOK || w.Header().Get("ETag") != `"3"` {
+		t.Errorf("Expected the rollback to succeed as version 3, got %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/agent-learning/go-agent-api/internal/agent"
+	"github.com/agent-learning/go-agent-api/internal/api/middleware"
+	"github.com/agent-learning/go-agent-api/internal/scheduler"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// maxBatchUpload bounds the size of a batch request body or uploaded file
+const maxBatchUpload = 16 << 20
+
+// BatchHandler handles batch submission requests
+type BatchHandler struct {
+	manager *scheduler.BatchManager
+}
+
+// NewBatchHandler creates a new batch handler
+func NewBatchHandler(manager *scheduler.BatchManager) *BatchHandler {
+	return &BatchHandler{
+		manager: manager,
+	}
+}
+
+// CreateBatch godoc
+// @Summary Submit a batch of tasks
+// @Description Submit many tasks at once. The body is a JSON array of task requests, a
+// @Description {"tasks": [...], "metadata": {...}} object, JSONL (application/x-ndjson), or a
+// @Description multipart upload with the JSONL in the "file" field.
+// @Tags batches
+// @Accept json,mpfd
+// @Produce json
+// @Param batch body scheduler.CreateBatchRequest true "Batch request"
+// @Success 201 {object} scheduler.Batch
+// @Failure 400 {object} ErrorResponse
+// @Router /api/v1/batches [post]
+func (h *BatchHandler) CreateBatch(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBatchUpload)
+
+	req, err := readBatchRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	req.Submitter = middleware.Submitter(c)
+
+	batch, err := h.manager.CreateBatch(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, batch)
+}
+
+// ListBatches godoc
+// @Summary List batches
+// @Description Get all batches with their progress, newest first
+// @Tags batches
+// @Produce json
+// @Success 200 {object} BatchesResponse
+// @Router /api/v1/batches [get]
+func (h *BatchHandler) ListBatches(c *gin.Context) {
+	batches := h.manager.ListBatches()
+
+	c.JSON(http.StatusOK, BatchesResponse{
+		Batches: batches,
+		Total:   len(batches),
+	})
+}
+
+// GetBatch godoc
+// @Summary Get batch by ID
+// @Description Get a batch with its progress and items
+// @Tags batches
+// @Produce json
+// @Param id path string true "Batch ID"
+// @Success 200 {object} scheduler.Batch
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/batches/{id} [get]
+func (h *BatchHandler) GetBatch(c *gin.Context) {
+	batch, err := h.manager.GetBatch(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, batch)
+}
+
+// GetBatchResults godoc
+// @Summary Download batch results
+// @Description Download one JSON line per item, in submission order
+// @Tags batches
+// @Produce json
+// @Param id path string true "Batch ID"
+// @Success 200 {object} scheduler.BatchItemResult
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/batches/{id}/results [get]
+func (h *BatchHandler) GetBatchResults(c *gin.Context) {
+	id := c.Param("id")
+
+	results, err := h.manager.Results(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"batch-%s.jsonl\"", id))
+	c.Status(http.StatusOK)
+
+	encoder := json.NewEncoder(c.Writer)
+	for _, result := range results {
+		if err := encoder.Encode(result); err != nil {
+			return
+		}
+	}
+}
+
+// CancelBatch godoc
+// @Summary Cancel a batch
+// @Description Cancel every pending or running task of a batch
+// @Tags batches
+// @Produce json
+// @Param id path string true "Batch ID"
+// @Success 200 {object} scheduler.Batch
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/batches/{id}/cancel [post]
+func (h *BatchHandler) CancelBatch(c *gin.Context) {
+	batch, err := h.manager.CancelBatch(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, batch)
+}
+
+// readBatchRequest decodes a batch from any of the accepted body formats
+func readBatchRequest(c *gin.Context) (*scheduler.CreateBatchRequest, error) {
+	mediaType, _, _ := mime.ParseMediaType(c.ContentType())
+
+	var data []byte
+	var err error
+	if mediaType == "multipart/form-data" {
+		file, _, ferr := c.Request.FormFile("file")
+		if ferr != nil {
+			return nil, fmt.Errorf("multipart upload requires a file field: %w", ferr)
+		}
+		defer file.Close()
+		data, err = io.ReadAll(file)
+	} else {
+		data, err = io.ReadAll(c.Request.Body)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch: %w", err)
+	}
+
+	data = bytes.TrimSpace(data)
+	switch {
+	case len(data) == 0:
+		return nil, fmt.Errorf("batch body is empty")
+	case data[0] == '[':
+		req := &scheduler.CreateBatchRequest{}
+		if err := json.Unmarshal(data, &req.Tasks); err != nil {
+			return nil, fmt.Errorf("invalid task array: %w", err)
+		}
+		return req, nil
+	case mediaType == "application/json":
+		req := &scheduler.CreateBatchRequest{}
+		if err := json.Unmarshal(data, req); err != nil {
+			return nil, fmt.Errorf("invalid batch: %w", err)
+		}
+		return req, nil
+	default:
+		return parseBatchJSONL(data)
+	}
+}
+
+// parseBatchJSONL reads one task request per line, skipping blank lines
+func parseBatchJSONL(data []byte) (*scheduler.CreateBatchRequest, error) {
+	req := &scheduler.CreateBatchRequest{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxBatchUpload)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var task agent.CreateTaskRequest
+		if err := json.Unmarshal(text, &task); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		req.Tasks = append(req.Tasks, task)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read batch: %w", err)
+	}
+	return req, nil
+}
+
+// BatchesResponse represents the response for listing batches
+type BatchesResponse struct {
+	Batches []*scheduler.Batch `json:"batches"`
+	Total   int                `json:"total"`
+}
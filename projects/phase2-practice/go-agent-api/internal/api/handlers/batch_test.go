@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agent-learning/go-agent-api/internal/agent"
+	"github.com/agent-learning/go-agent-api/internal/scheduler"
+	"github.com/gin-gonic/gin"
+)
+
+// newBatchRouter serves CreateBatch over a scheduler that is never started,
+// so submitted tasks stay queued
+func newBatchRouter(t *testing.T) (*gin.Engine, string) {
+	t.Helper()
+	service := agent.NewAgentServiceWithClient(nil)
+	ag, err := service.CreateAgent(context.Background(), &agent.CreateAgentRequest{Name: "batch", Type: agent.AgentTypeGeneral})
+	if err != nil {
+		t.Fatalf("CreateAgent failed: %v", err)
+	}
+
+	router := gin.New()
+	router.POST("/batches", NewBatchHandler(scheduler.NewBatchManager(scheduler.NewScheduler(service, 1, time.Minute))).CreateBatch)
+	return router, ag.ID
+}
+
+func postBatch(router *gin.Engine, contentType string, body []byte) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/batches", bytes.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func multipartBatch(t *testing.T, field string, content []byte) (string, []byte) {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile(field, "tasks.jsonl")
+	if err != nil {
+		t.Fatalf("CreateFormFile failed: %v", err)
+	}
+	part.Write(content)
+	writer.Close()
+	return writer.FormDataContentType(), body.Bytes()
+}
+
+func TestCreateBatchContentTypes(t *testing.T) {
+	router, agentID := newBatchRouter(t)
+	line := func(input string) string {
+		return fmt.Sprintf(`{"agent_id": %q, "type": "query", "input": %q}`, agentID, input)
+	}
+	jsonl := []byte(line("a") + "\n\n" + line("b") + "\n")
+	multipartType, multipartBody := multipartBatch(t, "file", jsonl)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		tasks       int
+	}{
+		{"json array", "application/json", []byte("[" + line("a") + "," + line("b") + "]"), 2},
+		{"json object", "application/json", []byte(`{"tasks": [` + line("a") + `], "metadata": {"run": "nightly"}}`), 1},
+		{"jsonl", "application/x-ndjson", jsonl, 2},
+		{"multipart", multipartType, multipartBody, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := postBatch(router, tt.contentType, tt.body)
+			if w.Code != http.StatusCreated {
+				t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body.String())
+			}
+			var batch scheduler.Batch
+			if err := json.Unmarshal(w.Body.Bytes(), &batch); err != nil {
+				t.Fatalf("Invalid response: %v", err)
+			}
+			if batch.Counts.Total != tt.tasks || batch.Counts.Pending != tt.tasks {
+				t.Errorf("Expected %d pending tasks, got %+v", tt.tasks, batch.Counts)
+			}
+		})
+	}
+}
+
+func TestCreateBatchRejectsBadBodies(t *testing.T) {
+	router, agentID := newBatchRouter(t)
+	valid := fmt.Sprintf(`{"agent_id": %q, "type": "query", "input": "a"}`, agentID)
+	noFileType, noFileBody := multipartBatch(t, "upload", []byte(valid))
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		errContains string
+	}{
+		{"empty body", "application/json", nil, "empty"},
+		{"malformed array", "application/json", []byte("[" + valid + ","), "invalid task array"},
+		{"malformed jsonl line", "application/x-ndjson", []byte(valid + "\n{not json}\n"), "line 2"},
+		{"missing required field", "application/x-ndjson", []byte(`{"type": "query", "input": "a"}`), "AgentID"},
+		{"multipart without file", noFileType, noFileBody, "file field"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := postBatch(router, tt.contentType, tt.body)
+			if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), tt.errContains) {
+				t.Errorf("Expected 400 mentioning %q, got %d: %s", tt.errContains, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCreateBatchRejectsOversizedUploads(t *testing.T) {
+	router, _ := newBatchRouter(t)
+	oversized := bytes.Repeat([]byte(" "), maxBatchUpload+1)
+
+	if w := postBatch(router, "application/x-ndjson", oversized); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "too large") {
+		t.Errorf("Expected 400 for an oversized body, got %d: %s", w.Code, w.Body.String())
+	}
+
+	contentType, body := multipartBatch(t, "file", oversized)
+	if w := postBatch(router, contentType, body); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "too large") {
+		t.Errorf("Expected 400 for an oversized upload, got %d: %s", w.Code, w.Body.String())
+	}
+}
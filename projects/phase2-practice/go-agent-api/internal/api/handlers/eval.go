@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/agent-learning/go-agent-api/internal/agent"
+	"github.com/agent-learning/go-agent-api/internal/eval"
+	"github.com/gin-gonic/gin"
+)
+
+// EvalHandler handles offline evaluation requests
+type EvalHandler struct {
+	service agent.AgentService
+	runner  *eval.Runner
+}
+
+// NewEvalHandler creates a new evaluation handler
+func NewEvalHandler(service agent.AgentService, runner *eval.Runner) *EvalHandler {
+	return &EvalHandler{
+		service: service,
+		runner:  runner,
+	}
+}
+
+// RunEval godoc
+// @Summary Evaluate agents against a dataset
+// @Description Run a golden dataset against a baseline agent and an optional candidate agent and compare the results. Use format=text for a plain-text report.
+// @Tags evals
+// @Accept json
+// @Produce json
+// @Param eval body eval.RunRequest true "Evaluation request"
+// @Param format query string false "Report format (json or text)"
+// @Success 200 {object} eval.Report
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/evals [post]
+func (h *EvalHandler) RunEval(c *gin.Context) {
+	var req eval.RunRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	cases, err := req.Cases()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	baseline, err := h.service.GetAgent(ctx, req.BaselineAgentID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	var candidate *agent.Agent
+	if req.CandidateAgentID != "" {
+		if candidate, err = h.service.GetAgent(ctx, req.CandidateAgentID); err != nil {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+			return
+		}
+	}
+
+	report, err := h.runner.Compare(ctx, baseline, candidate, cases)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if c.Query("format") == "text" {
+		c.Status(http.StatusOK)
+		c.Header("Content-Type", "text/plain; charset=utf-8")
+		_ = report.WriteText(c.Writer)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
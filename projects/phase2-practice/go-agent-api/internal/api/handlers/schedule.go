@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/agent-learning/go-agent-api/internal/api/middleware"
+	"github.com/agent-learning/go-agent-api/internal/scheduler"
+	"github.com/gin-gonic/gin"
+)
+
+// ScheduleHandler handles recurring schedule requests
+type ScheduleHandler struct {
+	manager *scheduler.ScheduleManager
+}
+
+// NewScheduleHandler creates a new schedule handler
+func NewScheduleHandler(manager *scheduler.ScheduleManager) *ScheduleHandler {
+	return &ScheduleHandler{
+		manager: manager,
+	}
+}
+
+// CreateSchedule godoc
+// @Summary Create a recurring schedule
+// @Description Create a cron schedule that submits a task template on every occurrence
+// @Tags schedules
+// @Accept json
+// @Produce json
+// @Param schedule body scheduler.CreateScheduleRequest true "Schedule creation request"
+// @Success 201 {object} scheduler.Schedule
+// @Failure 400 {object} ErrorResponse
+// @Router /api/v1/schedules [post]
+func (h *ScheduleHandler) CreateSchedule(c *gin.Context) {
+	var req scheduler.CreateScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	req.Submitter = middleware.Submitter(c)
+
+	schedule, err := h.manager.CreateSchedule(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, schedule)
+}
+
+// ListSchedules godoc
+// @Summary List schedules
+// @Description Get all recurring schedules
+// @Tags schedules
+// @Produce json
+// @Success 200 {object} SchedulesResponse
+// @Router /api/v1/schedules [get]
+func (h *ScheduleHandler) ListSchedules(c *gin.Context) {
+	schedules := h.manager.ListSchedules()
+
+	c.JSON(http.StatusOK, SchedulesResponse{
+		Schedules: schedules,
+		Total:     len(schedules),
+	})
+}
+
+// GetSchedule godoc
+// @Summary Get schedule by ID
+// @Tags schedules
+// @Produce json
+// @Param id path string true "Schedule ID"
+// @Success 200 {object} scheduler.Schedule
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/schedules/{id} [get]
+func (h *ScheduleHandler) GetSchedule(c *gin.Context) {
+	schedule, err := h.manager.GetSchedule(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+// DeleteSchedule godoc
+// @Summary Delete a schedule
+// @Tags schedules
+// @Param id path string true "Schedule ID"
+// @Success 204
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/schedules/{id} [delete]
+func (h *ScheduleHandler) DeleteSchedule(c *gin.Context) {
+	if err := h.manager.DeleteSchedule(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// PauseSchedule godoc
+// @Summary Pause a schedule
+// @Tags schedules
+// @Produce json
+// @Param id path string true "Schedule ID"
+// @Success 200 {object} scheduler.Schedule
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/schedules/{id}/pause [post]
+func (h *ScheduleHandler) PauseSchedule(c *gin.Context) {
+	h.setEnabled(c, false)
+}
+
+// ResumeSchedule godoc
+// @Summary Resume a paused schedule
+// @Tags schedules
+// @Produce json
+// @Param id path string true "Schedule ID"
+// @Success 200 {object} scheduler.Schedule
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/schedules/{id}/resume [post]
+func (h *ScheduleHandler) ResumeSchedule(c *gin.Context) {
+	h.setEnabled(c, true)
+}
+
+func (h *ScheduleHandler) setEnabled(c *gin.Context, enabled bool) {
+	schedule, err := h.manager.SetEnabled(c.Param("id"), enabled)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+// SchedulesResponse represents the response for listing schedules
+type SchedulesResponse struct {
+	Schedules []*scheduler.Schedule `json:"schedules"`
+	Total     int                   `json:"total"`
+}
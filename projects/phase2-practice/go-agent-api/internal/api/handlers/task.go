@@ -1,13 +1,23 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/agent-learning/go-agent-api/internal/agent"
+	"github.com/agent-learning/go-agent-api/internal/api/middleware"
 	"github.com/agent-learning/go-agent-api/internal/scheduler"
 	"github.com/gin-gonic/gin"
 )
 
+// watchInterval is how often a watched task is checked for changes
+const watchInterval = 500 * time.Millisecond
+
 // TaskHandler handles task-related requests
 type TaskHandler struct {
 	scheduler *scheduler.Scheduler
@@ -22,13 +32,17 @@ func NewTaskHandler(scheduler *scheduler.Scheduler) *TaskHandler {
 
 // SubmitTask godoc
 // @Summary Submit a new task
-// @Description Submit a new task for execution by an agent
+// @Description Submit a new task for execution by an agent. Retries carrying the same
+// @Description Idempotency-Key return the original task instead of running it again.
 // @Tags tasks
 // @Accept json
 // @Produce json
 // @Param task body agent.CreateTaskRequest true "Task creation request"
+// @Param Idempotency-Key header string false "Client-chosen key that makes retries safe"
+// @Success 200 {object} agent.Task "Replay of an earlier submission with the same key"
 // @Success 201 {object} agent.Task
 // @Failure 400 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse "Key reused with a different request"
 // @Router /api/v1/tasks [post]
 func (h *TaskHandler) SubmitTask(c *gin.Context) {
 	var req agent.CreateTaskRequest
@@ -36,13 +50,23 @@ func (h *TaskHandler) SubmitTask(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
 	}
+	req.Submitter = middleware.Submitter(c)
 
-	task, err := h.scheduler.SubmitTask(&req)
+	task, replayed, err := h.scheduler.SubmitTaskWithKey(&req, c.GetHeader("Idempotency-Key"))
+	if errors.Is(err, scheduler.ErrIdempotencyKeyReused) {
+		c.JSON(http.StatusUnprocessableEntity, ErrorResponse{Error: err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
 	}
 
+	if replayed {
+		c.Header("Idempotent-Replayed", "true")
+		c.JSON(http.StatusOK, task)
+		return
+	}
 	c.JSON(http.StatusCreated, task)
 }
 
@@ -107,22 +131,159 @@ func (h *TaskHandler) CancelTask(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// WatchTask godoc
+// @Summary Watch a task
+// @Description Stream a task as server-sent events: a "status" event with the task whenever its
+// @Description status changes, then a "result" event with the result once it finishes
+// @Tags tasks
+// @Produce text/event-stream
+// @Param id path string true "Task ID"
+// @Success 200 {object} agent.Task
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/tasks/{id}/events [get]
+func (h *TaskHandler) WatchTask(c *gin.Context) {
+	id := c.Param("id")
+
+	if _, err := h.scheduler.TaskSnapshot(id); err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	var last agent.TaskStatus
+	c.Stream(func(w io.Writer) bool {
+		task, err := h.scheduler.TaskSnapshot(id)
+		if err != nil {
+			c.SSEvent("error", ErrorResponse{Error: err.Error()})
+			return false
+		}
+		if task.Status != last {
+			last = task.Status
+			c.SSEvent("status", task)
+		}
+		if scheduler.IsFinished(task.Status) {
+			if result, err := h.scheduler.GetTaskResult(id); err == nil {
+				c.SSEvent("result", result)
+			}
+			return false
+		}
+
+		select {
+		case <-ticker.C:
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
 // ListTasks godoc
-// @Summary List all tasks
-// @Description Get a list of all tasks
+// @Summary List tasks
+// @Description List tasks of any status, newest first, with filters and cursor pagination
 // @Tags tasks
 // @Produce json
+// @Param agent_id query string false "Agent ID"
+// @Param status query string false "Comma-separated statuses"
+// @Param type query string false "Comma-separated task types"
+// @Param created_after query string false "RFC3339 lower bound (inclusive)"
+// @Param created_before query string false "RFC3339 upper bound (exclusive)"
+// @Param metadata query []string false "Metadata filter as key:value, repeatable"
+// @Param q query string false "Words that must all appear in the input or output"
+// @Param order query string false "asc or desc (default)"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param cursor query string false "next_cursor of the previous page"
 // @Success 200 {object} TasksResponse
+// @Failure 400 {object} ErrorResponse
 // @Router /api/v1/tasks [get]
 func (h *TaskHandler) ListTasks(c *gin.Context) {
-	tasks := h.scheduler.ListTasks()
+	query, err := parseTaskQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	page, err := h.scheduler.QueryTasks(query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
 
 	c.JSON(http.StatusOK, TasksResponse{
-		Tasks: tasks,
-		Total: len(tasks),
+		Tasks:      page.Tasks,
+		Total:      page.Total,
+		NextCursor: page.NextCursor,
 	})
 }
 
+// parseTaskQuery reads task listing filters from the query string
+func parseTaskQuery(c *gin.Context) (scheduler.TaskQuery, error) {
+	query := scheduler.TaskQuery{
+		AgentID: c.Query("agent_id"),
+		Text:    strings.TrimSpace(c.Query("q")),
+		Order:   scheduler.SortOrder(c.Query("order")),
+		Cursor:  c.Query("cursor"),
+	}
+
+	for _, status := range splitQuery(c, "status") {
+		query.Statuses = append(query.Statuses, agent.TaskStatus(status))
+	}
+	for _, taskType := range splitQuery(c, "type") {
+		query.Types = append(query.Types, agent.TaskType(taskType))
+	}
+
+	for _, name := range []string{"created_after", "created_before"} {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return query, fmt.Errorf("invalid %s: must be RFC3339", name)
+		}
+		if name == "created_after" {
+			query.CreatedAfter = &t
+		} else {
+			query.CreatedBefore = &t
+		}
+	}
+
+	for _, pair := range c.QueryArray("metadata") {
+		key, value, found := strings.Cut(pair, ":")
+		if !found || key == "" {
+			return query, fmt.Errorf("invalid metadata filter %q: must be key:value", pair)
+		}
+		if query.Metadata == nil {
+			query.Metadata = make(map[string]string)
+		}
+		query.Metadata[key] = value
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return query, fmt.Errorf("invalid limit: must be a positive integer")
+		}
+		query.Limit = limit
+	}
+
+	return query, nil
+}
+
+// splitQuery returns the values of a repeatable, comma-separated parameter
+func splitQuery(c *gin.Context, name string) []string {
+	values := make([]string, 0)
+	for _, param := range c.QueryArray(name) {
+		for _, value := range strings.Split(param, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
 // GetStats godoc
 // @Summary Get scheduler statistics
 // @Description Get statistics about pending, running, and completed tasks
@@ -138,5 +299,8 @@ func (h *TaskHandler) GetStats(c *gin.Context) {
 // TasksResponse represents the response for listing tasks
 type TasksResponse struct {
 	Tasks []*agent.Task `json:"tasks"`
-	Total int           `json:"total"`
+	// Total is the number of tasks matching the filters across all pages
+	Total int `json:"total"`
+	// NextCursor fetches the following page; omitted on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
 }
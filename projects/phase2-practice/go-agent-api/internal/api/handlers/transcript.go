@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/agent-learning/go-agent-api/internal/agent"
+	"github.com/agent-learning/go-agent-api/internal/transcript"
+	"github.com/gin-gonic/gin"
+)
+
+// TranscriptHandler handles task transcript and replay requests
+type TranscriptHandler struct {
+	service agent.AgentService
+	store   transcript.Store
+}
+
+// NewTranscriptHandler creates a new transcript handler
+func NewTranscriptHandler(service agent.AgentService, store transcript.Store) *TranscriptHandler {
+	return &TranscriptHandler{
+		service: service,
+		store:   store,
+	}
+}
+
+// GetTranscript godoc
+// @Summary Get a task transcript
+// @Description Get the full transcript of a task execution: rendered prompts, raw LLM responses, tool calls and timings. Use download=true to export it as a file.
+// @Tags tasks
+// @Produce json
+// @Param id path string true "Task ID"
+// @Param download query bool false "Serve as a file attachment"
+// @Success 200 {object} transcript.Transcript
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/tasks/{id}/transcript [get]
+func (h *TranscriptHandler) GetTranscript(c *gin.Context) {
+	id := c.Param("id")
+
+	t, err := h.store.Get(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if c.Query("download") == "true" {
+		c.Header("Content-Disposition", "attachment; filename=transcript-"+id+".json")
+	}
+	c.JSON(http.StatusOK, t)
+}
+
+// ReplayTask godoc
+// @Summary Replay a task
+// @Description Re-run a task against its recorded LLM and tool responses and report where the execution diverges from the recording
+// @Tags tasks
+// @Produce json
+// @Param id path string true "Task ID"
+// @Success 200 {object} agent.ReplayResult
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/tasks/{id}/replay [post]
+func (h *TranscriptHandler) ReplayTask(c *gin.Context) {
+	t, err := h.store.Get(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	h.replay(c, t)
+}
+
+// ReplayTranscript godoc
+// @Summary Replay an exported transcript
+// @Description Re-run an exported transcript, e.g. one taken from a production incident, against its recorded LLM and tool responses
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param transcript body transcript.Transcript true "Exported transcript"
+// @Success 200 {object} agent.ReplayResult
+// @Failure 400 {object} ErrorResponse
+// @Router /api/v1/transcripts/replay [post]
+func (h *TranscriptHandler) ReplayTranscript(c *gin.Context) {
+	var t transcript.Transcript
+	if err := c.ShouldBindJSON(&t); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	h.replay(c, &t)
+}
+
+func (h *TranscriptHandler) replay(c *gin.Context, t *transcript.Transcript) {
+	result, err := agent.Replay(c.Request.Context(), h.service, t)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
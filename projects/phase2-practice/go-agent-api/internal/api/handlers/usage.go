@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/agent-learning/go-agent-api/internal/scheduler"
+	"github.com/gin-gonic/gin"
+)
+
+// UsageHandler handles usage reporting requests
+type UsageHandler struct {
+	meter *scheduler.UsageMeter
+}
+
+// NewUsageHandler creates a new usage handler
+func NewUsageHandler(meter *scheduler.UsageMeter) *UsageHandler {
+	return &UsageHandler{
+		meter: meter,
+	}
+}
+
+// GetUsage godoc
+// @Summary Get usage
+// @Description Get finished task counts, tokens and execution time since the server started, in total, per agent and per API key
+// @Tags usage
+// @Produce json
+// @Success 200 {object} scheduler.Usage
+// @Router /api/v1/usage [get]
+func (h *UsageHandler) GetUsage(c *gin.Context) {
+	c.JSON(http.StatusOK, h.meter.Usage())
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/agent-learning/go-agent-api/internal/webhook"
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookHandler handles webhook-related requests
+type WebhookHandler struct {
+	dispatcher *webhook.Dispatcher
+}
+
+// NewWebhookHandler creates a new webhook handler
+func NewWebhookHandler(dispatcher *webhook.Dispatcher) *WebhookHandler {
+	return &WebhookHandler{
+		dispatcher: dispatcher,
+	}
+}
+
+// ListDeliveries godoc
+// @Summary List webhook deliveries for a task
+// @Description Get every webhook delivery attempt made for a task
+// @Tags tasks
+// @Produce json
+// @Param id path string true "Task ID"
+// @Success 200 {object} DeliveriesResponse
+// @Router /api/v1/tasks/{id}/webhooks [get]
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	deliveries := h.dispatcher.Deliveries(c.Param("id"))
+
+	c.JSON(http.StatusOK, DeliveriesResponse{
+		Deliveries: deliveries,
+		Total:      len(deliveries),
+	})
+}
+
+// DeliveriesResponse represents the response for listing webhook deliveries
+type DeliveriesResponse struct {
+	Deliveries []*webhook.Delivery `json:"deliveries"`
+	Total      int                 `json:"total"`
+}
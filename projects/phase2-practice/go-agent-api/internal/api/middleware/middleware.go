@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"log"
 	"time"
 
@@ -28,7 +30,8 @@ func CORS() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-API-Key, If-Match, Idempotency-Key")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "ETag, Idempotent-Replayed")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
 
 		if c.Request.Method == "OPTIONS" {
@@ -44,3 +47,32 @@ func CORS() gin.HandlerFunc {
 func Recovery() gin.HandlerFunc {
 	return gin.Recovery()
 }
+
+const (
+	// APIKeyHeader carries the caller's API key
+	APIKeyHeader = "X-API-Key"
+	// submitterContextKey stores the submitter identity in the gin context
+	submitterContextKey = "submitter"
+)
+
+// APIKey derives a submitter identity from the request's API key so that
+// tasks can be attributed to a caller without storing the key itself
+func APIKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key := c.GetHeader(APIKeyHeader); key != "" {
+			c.Set(submitterContextKey, SubmitterID(key))
+		}
+		c.Next()
+	}
+}
+
+// Submitter returns the submitter identity set by APIKey, if any
+func Submitter(c *gin.Context) string {
+	return c.GetString(submitterContextKey)
+}
+
+// SubmitterID returns the stable, non-secret identity for an API key
+func SubmitterID(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return "key_" + hex.EncodeToString(sum[:8])
+}
@@ -4,16 +4,32 @@ import (
 	"github.com/agent-learning/go-agent-api/internal/agent"
 	"github.com/agent-learning/go-agent-api/internal/api/handlers"
 	"github.com/agent-learning/go-agent-api/internal/api/middleware"
+	"github.com/agent-learning/go-agent-api/internal/eval"
 	"github.com/agent-learning/go-agent-api/internal/scheduler"
+	"github.com/agent-learning/go-agent-api/internal/transcript"
+	"github.com/agent-learning/go-agent-api/internal/webhook"
 	"github.com/gin-gonic/gin"
 )
 
+// Dependencies holds the services the API routes are built on
+type Dependencies struct {
+	AgentService agent.AgentService
+	Scheduler    *scheduler.Scheduler
+	Webhooks     *webhook.Dispatcher
+	Schedules    *scheduler.ScheduleManager
+	Batches      *scheduler.BatchManager
+	Evals        *eval.Runner
+	Transcripts  transcript.Store
+	Usage        *scheduler.UsageMeter
+}
+
 // SetupRoutes configures all API routes
-func SetupRoutes(router *gin.Engine, agentService agent.AgentService, scheduler *scheduler.Scheduler) {
+func SetupRoutes(router *gin.Engine, deps Dependencies) {
 	// Apply middleware
 	router.Use(middleware.Logger())
 	router.Use(middleware.CORS())
 	router.Use(middleware.Recovery())
+	router.Use(middleware.APIKey())
 
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
@@ -27,17 +43,23 @@ func SetupRoutes(router *gin.Engine, agentService agent.AgentService, scheduler
 	v1 := router.Group("/api/v1")
 	{
 		// Agent routes
-		agentHandler := handlers.NewAgentHandler(agentService)
+		agentHandler := handlers.NewAgentHandler(deps.AgentService)
 		agents := v1.Group("/agents")
 		{
 			agents.POST("", agentHandler.CreateAgent)
 			agents.GET("", agentHandler.ListAgents)
+			agents.GET("/export", agentHandler.ExportAgents)
+			agents.POST("/import", agentHandler.ImportAgents)
 			agents.GET("/:id", agentHandler.GetAgent)
+			agents.PATCH("/:id", agentHandler.UpdateAgent)
 			agents.DELETE("/:id", agentHandler.DeleteAgent)
+			agents.GET("/:id/versions", agentHandler.ListAgentVersions)
+			agents.GET("/:id/versions/:version", agentHandler.GetAgentVersion)
+			agents.POST("/:id/rollback", agentHandler.RollbackAgent)
 		}
 
 		// Task routes
-		taskHandler := handlers.NewTaskHandler(scheduler)
+		taskHandler := handlers.NewTaskHandler(deps.Scheduler)
 		tasks := v1.Group("/tasks")
 		{
 			tasks.POST("", taskHandler.SubmitTask)
@@ -45,7 +67,60 @@ func SetupRoutes(router *gin.Engine, agentService agent.AgentService, scheduler
 			tasks.GET("/stats", taskHandler.GetStats)
 			tasks.GET("/:id", taskHandler.GetTask)
 			tasks.GET("/:id/result", taskHandler.GetTaskResult)
+			tasks.GET("/:id/events", taskHandler.WatchTask)
 			tasks.DELETE("/:id", taskHandler.CancelTask)
+
+			if deps.Webhooks != nil {
+				webhookHandler := handlers.NewWebhookHandler(deps.Webhooks)
+				tasks.GET("/:id/webhooks", webhookHandler.ListDeliveries)
+			}
+		}
+
+		// Transcript routes
+		if deps.Transcripts != nil {
+			transcriptHandler := handlers.NewTranscriptHandler(deps.AgentService, deps.Transcripts)
+			tasks.GET("/:id/transcript", transcriptHandler.GetTranscript)
+			tasks.POST("/:id/replay", transcriptHandler.ReplayTask)
+			v1.POST("/transcripts/replay", transcriptHandler.ReplayTranscript)
+		}
+
+		// Schedule routes
+		if deps.Schedules != nil {
+			scheduleHandler := handlers.NewScheduleHandler(deps.Schedules)
+			schedules := v1.Group("/schedules")
+			{
+				schedules.POST("", scheduleHandler.CreateSchedule)
+				schedules.GET("", scheduleHandler.ListSchedules)
+				schedules.GET("/:id", scheduleHandler.GetSchedule)
+				schedules.DELETE("/:id", scheduleHandler.DeleteSchedule)
+				schedules.POST("/:id/pause", scheduleHandler.PauseSchedule)
+				schedules.POST("/:id/resume", scheduleHandler.ResumeSchedule)
+			}
+		}
+
+		// Batch routes
+		if deps.Batches != nil {
+			batchHandler := handlers.NewBatchHandler(deps.Batches)
+			batches := v1.Group("/batches")
+			{
+				batches.POST("", batchHandler.CreateBatch)
+				batches.GET("", batchHandler.ListBatches)
+				batches.GET("/:id", batchHandler.GetBatch)
+				batches.GET("/:id/results", batchHandler.GetBatchResults)
+				batches.POST("/:id/cancel", batchHandler.CancelBatch)
+			}
+		}
+
+		// Usage routes
+		if deps.Usage != nil {
+			usageHandler := handlers.NewUsageHandler(deps.Usage)
+			v1.GET("/usage", usageHandler.GetUsage)
+		}
+
+		// Evaluation routes
+		if deps.Evals != nil {
+			evalHandler := handlers.NewEvalHandler(deps.AgentService, deps.Evals)
+			v1.POST("/evals", evalHandler.RunEval)
 		}
 	}
 
@@ -0,0 +1,251 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// MatchType describes how a cached response was found
+type MatchType string
+
+const (
+	MatchExact    MatchType = "exact"
+	MatchSemantic MatchType = "semantic"
+)
+
+// Entry is a cached LLM response
+type Entry struct {
+	Key       string                        `json:"key"`
+	Scope     string                        `json:"scope"`
+	Prompt    string                        `json:"prompt"`
+	Embedding []float32                     `json:"embedding,omitempty"`
+	Response  openai.ChatCompletionResponse `json:"response"`
+	CreatedAt time.Time                     `json:"created_at"`
+	ExpiresAt time.Time                     `json:"expires_at"`
+}
+
+// Backend stores cache entries
+type Backend interface {
+	// Get returns the entry stored under key, if present and not expired
+	Get(ctx context.Context, key string) (*Entry, bool, error)
+	// Set stores an entry for ttl
+	Set(ctx context.Context, entry *Entry, ttl time.Duration) error
+	// Scope returns all live entries sharing a scope, for semantic lookup
+	Scope(ctx context.Context, scope string) ([]*Entry, error)
+}
+
+// Embedder turns text into a vector for semantic matching
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+}
+
+// Config holds response cache settings
+type Config struct {
+	// DefaultTTL applies when an agent does not set its own TTL
+	DefaultTTL time.Duration
+	// SimilarityThreshold enables semantic lookup when > 0 and an
+	// embedder is configured (cosine similarity, 0..1)
+	SimilarityThreshold float64
+}
+
+// Hit describes a cache hit
+type Hit struct {
+	Match      MatchType `json:"match"`
+	Key        string    `json:"key"`
+	Similarity float64   `json:"similarity,omitempty"`
+	AgeMs      int64     `json:"age_ms"`
+}
+
+// Lookup is the outcome of a cache probe. On a miss, pass it to Store
+// so the key and embedding are not recomputed.
+type Lookup struct {
+	Response *openai.ChatCompletionResponse
+	Hit      *Hit
+
+	key       string
+	scope     string
+	prompt    string
+	embedding []float32
+}
+
+// ResponseCache caches chat completions by exact request hash with an
+// optional embedding-similarity fallback
+type ResponseCache struct {
+	backend  Backend
+	embedder Embedder
+	config   Config
+}
+
+// NewResponseCache creates a response cache; embedder may be nil to
+// disable semantic matching
+func NewResponseCache(backend Backend, config Config, embedder Embedder) *ResponseCache {
+	if config.DefaultTTL <= 0 {
+		config.DefaultTTL = time.Hour
+	}
+	return &ResponseCache{
+		backend:  backend,
+		embedder: embedder,
+		config:   config,
+	}
+}
+
+// DefaultTTL returns the TTL used when an agent does not override it
+func (c *ResponseCache) DefaultTTL() time.Duration {
+	return c.config.DefaultTTL
+}
+
+// Lookup probes the cache for a request. A nil Response means a miss.
+func (c *ResponseCache) Lookup(ctx context.Context, req openai.ChatCompletionRequest) (*Lookup, error) {
+	key, scope, prompt, err := Keys(req)
+	if err != nil {
+		return nil, err
+	}
+	lookup := &Lookup{key: key, scope: scope, prompt: prompt}
+
+	entry, ok, err := c.backend.Get(ctx, key)
+	if err != nil {
+		return lookup, fmt.Errorf("cache get failed: %w", err)
+	}
+	if ok {
+		lookup.Response = &entry.Response
+		lookup.Hit = &Hit{Match: MatchExact, Key: key, AgeMs: time.Since(entry.CreatedAt).Milliseconds()}
+		return lookup, nil
+	}
+
+	if !c.semanticEnabled() || prompt == "" {
+		return lookup, nil
+	}
+
+	embedding, err := c.embedder.Embed(ctx, prompt)
+	if err != nil {
+		return lookup, fmt.Errorf("embedding failed: %w", err)
+	}
+	lookup.embedding = embedding
+
+	candidates, err := c.backend.Scope(ctx, scope)
+	if err != nil {
+		return lookup, fmt.Errorf("cache scan failed: %w", err)
+	}
+
+	var best *Entry
+	bestScore := 0.0
+	for _, candidate := range candidates {
+		score := CosineSimilarity(embedding, candidate.Embedding)
+		if score >= c.config.SimilarityThreshold && score > bestScore {
+			best, bestScore = candidate, score
+		}
+	}
+	if best != nil {
+		lookup.Response = &best.Response
+		lookup.Hit = &Hit{
+			Match:      MatchSemantic,
+			Key:        best.Key,
+			Similarity: bestScore,
+			AgeMs:      time.Since(best.CreatedAt).Milliseconds(),
+		}
+	}
+
+	return lookup, nil
+}
+
+// Store caches a response for the request probed by lookup
+func (c *ResponseCache) Store(ctx context.Context, lookup *Lookup, resp openai.ChatCompletionResponse, ttl time.Duration) error {
+	if ttl <= 0 {
+		ttl = c.config.DefaultTTL
+	}
+
+	embedding := lookup.embedding
+	if embedding == nil && c.semanticEnabled() && lookup.prompt != "" {
+		var err error
+		embedding, err = c.embedder.Embed(ctx, lookup.prompt)
+		if err != nil {
+			return fmt.Errorf("embedding failed: %w", err)
+		}
+	}
+
+	now := time.Now()
+	return c.backend.Set(ctx, &Entry{
+		Key:       lookup.key,
+		Scope:     lookup.scope,
+		Prompt:    lookup.prompt,
+		Embedding: embedding,
+		Response:  resp,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}, ttl)
+}
+
+func (c *ResponseCache) semanticEnabled() bool {
+	return c.embedder != nil && c.config.SimilarityThreshold > 0
+}
+
+// keyMaterial is hashed to build cache keys
+type keyMaterial struct {
+	Model          string                               `json:"model"`
+	Temperature    float32                              `json:"temperature"`
+	MaxTokens      int                                  `json:"max_tokens"`
+	ResponseFormat *openai.ChatCompletionResponseFormat `json:"response_format,omitempty"`
+	Messages       []openai.ChatCompletionMessage       `json:"messages"`
+}
+
+// Keys derives the exact key, the semantic scope and the prompt text for a
+// request. The exact key covers the model, temperature, system prompt and
+// every message. The scope covers everything except the final user
+// message, which is the text compared semantically.
+func Keys(req openai.ChatCompletionRequest) (key, scope, prompt string, err error) {
+	material := keyMaterial{
+		Model:          req.Model,
+		Temperature:    req.Temperature,
+		MaxTokens:      req.MaxTokens,
+		ResponseFormat: req.ResponseFormat,
+		Messages:       req.Messages,
+	}
+	key, err = hashJSON(material)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	if n := len(req.Messages); n > 0 && req.Messages[n-1].Role == openai.ChatMessageRoleUser {
+		prompt = req.Messages[n-1].Content
+		material.Messages = req.Messages[:n-1]
+	}
+	scope, err = hashJSON(material)
+	if err != nil {
+		return "", "", "", err
+	}
+	return key, scope, prompt, nil
+}
+
+func hashJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cache key: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// CosineSimilarity returns the cosine similarity of two vectors, or 0 if
+// they differ in length or either is zero
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,130 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// fakeEmbedder maps text to a fixed vector by keyword
+type fakeEmbedder struct {
+	vectors map[string][]float32
+}
+
+func (f *fakeEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	for keyword, vector := range f.vectors {
+		if strings.Contains(text, keyword) {
+			return vector, nil
+		}
+	}
+	return []float32{0, 0, 1}, nil
+}
+
+func chatRequest(question string) openai.ChatCompletionRequest {
+	return openai.ChatCompletionRequest{
+		Model:       "gpt-4",
+		Temperature: 0.2,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: "You answer questions about the docs."},
+			{Role: openai.ChatMessageRoleUser, Content: question},
+		},
+	}
+}
+
+func chatResponse(content string) openai.ChatCompletionResponse {
+	return openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: content}}},
+	}
+}
+
+func TestResponseCacheExactHit(t *testing.T) {
+	ctx := context.Background()
+	c := NewResponseCache(NewMemoryBackend(10), Config{}, nil)
+
+	lookup, err := c.Lookup(ctx, chatRequest("How do I install?"))
+	if err != nil || lookup.Response != nil {
+		t.Fatalf("Expected miss, got %v, %v", lookup, err)
+	}
+	if err := c.Store(ctx, lookup, chatResponse("Run go get"), 0); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	lookup, _ = c.Lookup(ctx, chatRequest("How do I install?"))
+	if lookup.Hit == nil || lookup.Hit.Match != MatchExact {
+		t.Fatalf("Expected exact hit, got %+v", lookup.Hit)
+	}
+	if lookup.Response.Choices[0].Message.Content != "Run go get" {
+		t.Errorf("Unexpected cached content: %q", lookup.Response.Choices[0].Message.Content)
+	}
+
+	// Temperature is part of the key
+	req := chatRequest("How do I install?")
+	req.Temperature = 0.9
+	if lookup, _ := c.Lookup(ctx, req); lookup.Response != nil {
+		t.Error("Expected miss for different temperature")
+	}
+}
+
+func TestResponseCacheSemanticHit(t *testing.T) {
+	ctx := context.Background()
+	embedder := &fakeEmbedder{vectors: map[string][]float32{
+		"install": {1, 0.1, 0},
+		"setup":   {1, 0.2, 0},
+	}}
+	c := NewResponseCache(NewMemoryBackend(10), Config{SimilarityThreshold: 0.95}, embedder)
+
+	lookup, _ := c.Lookup(ctx, chatRequest("How do I install?"))
+	if err := c.Store(ctx, lookup, chatResponse("Run go get"), 0); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	lookup, err := c.Lookup(ctx, chatRequest("What is the setup process?"))
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if lookup.Hit == nil || lookup.Hit.Match != MatchSemantic || lookup.Hit.Similarity < 0.95 {
+		t.Fatalf("Expected semantic hit, got %+v", lookup.Hit)
+	}
+
+	if lookup, _ := c.Lookup(ctx, chatRequest("Unrelated question")); lookup.Response != nil {
+		t.Error("Expected miss below threshold")
+	}
+
+	// A different system prompt is a different scope
+	req := chatRequest("What is the setup process?")
+	req.Messages[0].Content = "You are a pirate."
+	if lookup, _ := c.Lookup(ctx, req); lookup.Response != nil {
+		t.Error("Expected miss across scopes")
+	}
+}
+
+func TestMemoryBackendEvictsAndExpires(t *testing.T) {
+	ctx := context.Background()
+	backend := NewMemoryBackend(2)
+	now := time.Now()
+
+	for _, key := range []string{"a", "b"} {
+		backend.Set(ctx, &Entry{Key: key, Scope: "s", ExpiresAt: now.Add(time.Hour)}, time.Hour)
+	}
+	// Touch "a" so "b" is least recently used
+	backend.Get(ctx, "a")
+	backend.Set(ctx, &Entry{Key: "c", Scope: "s", ExpiresAt: now.Add(time.Hour)}, time.Hour)
+
+	if _, ok, _ := backend.Get(ctx, "b"); ok {
+		t.Error("Expected b to be evicted")
+	}
+	if backend.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", backend.Len())
+	}
+
+	backend.Set(ctx, &Entry{Key: "d", Scope: "s", ExpiresAt: now.Add(-time.Second)}, time.Second)
+	if _, ok, _ := backend.Get(ctx, "d"); ok {
+		t.Error("Expected expired entry to miss")
+	}
+	if entries, _ := backend.Scope(ctx, "s"); len(entries) != 1 {
+		t.Errorf("Expected 1 live entry in scope, got %d", len(entries))
+	}
+}
@@ -0,0 +1,37 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// OpenAIEmbedder computes embeddings with the OpenAI embeddings API
+type OpenAIEmbedder struct {
+	client *openai.Client
+	model  openai.EmbeddingModel
+}
+
+// NewOpenAIEmbedder creates an embedder using the given model
+func NewOpenAIEmbedder(client *openai.Client, model string) *OpenAIEmbedder {
+	return &OpenAIEmbedder{
+		client: client,
+		model:  openai.EmbeddingModel(model),
+	}
+}
+
+// Embed implements Embedder
+func (e *OpenAIEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	resp, err := e.client.CreateEmbeddings(ctx, openai.EmbeddingRequest{
+		Input: []string{text},
+		Model: e.model,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Data) == 0 {
+		return nil, fmt.Errorf("no embedding returned")
+	}
+	return resp.Data[0].Embedding, nil
+}
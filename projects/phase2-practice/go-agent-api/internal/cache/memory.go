@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryBackend is an in-memory LRU cache backend
+type MemoryBackend struct {
+	capacity int
+	entries  map[string]*list.Element
+	scopes   map[string]map[string]struct{}
+	order    *list.List
+	mu       sync.Mutex
+}
+
+// NewMemoryBackend creates an LRU backend holding at most capacity entries
+func NewMemoryBackend(capacity int) *MemoryBackend {
+	if capacity <= 0 {
+		capacity = 1000
+	}
+	return &MemoryBackend{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		scopes:   make(map[string]map[string]struct{}),
+		order:    list.New(),
+	}
+}
+
+// Get implements Backend
+func (m *MemoryBackend) Get(ctx context.Context, key string) (*Entry, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	elem, ok := m.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := elem.Value.(*Entry)
+	if time.Now().After(entry.ExpiresAt) {
+		m.removeLocked(elem)
+		return nil, false, nil
+	}
+
+	m.order.MoveToFront(elem)
+	return entry, true, nil
+}
+
+// Set implements Backend
+func (m *MemoryBackend) Set(ctx context.Context, entry *Entry, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if elem, ok := m.entries[entry.Key]; ok {
+		m.removeLocked(elem)
+	}
+
+	m.entries[entry.Key] = m.order.PushFront(entry)
+	if m.scopes[entry.Scope] == nil {
+		m.scopes[entry.Scope] = make(map[string]struct{})
+	}
+	m.scopes[entry.Scope][entry.Key] = struct{}{}
+
+	for m.order.Len() > m.capacity {
+		m.removeLocked(m.order.Back())
+	}
+	return nil
+}
+
+// Scope implements Backend
+func (m *MemoryBackend) Scope(ctx context.Context, scope string) ([]*Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	entries := make([]*Entry, 0, len(m.scopes[scope]))
+	for key := range m.scopes[scope] {
+		elem := m.entries[key]
+		entry := elem.Value.(*Entry)
+		if now.After(entry.ExpiresAt) {
+			m.removeLocked(elem)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Len returns the number of cached entries
+func (m *MemoryBackend) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.order.Len()
+}
+
+// removeLocked drops an element from the list, index and scope set
+func (m *MemoryBackend) removeLocked(elem *list.Element) {
+	entry := elem.Value.(*Entry)
+	m.order.Remove(elem)
+	delete(m.entries, entry.Key)
+	if keys, ok := m.scopes[entry.Scope]; ok {
+		delete(keys, entry.Key)
+		if len(keys) == 0 {
+			delete(m.scopes, entry.Scope)
+		}
+	}
+}
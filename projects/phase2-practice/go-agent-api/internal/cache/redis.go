@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/agent-learning/go-agent-api/internal/state"
+)
+
+// redisKeyPrefix namespaces cache keys in Redis
+const redisKeyPrefix = "llmcache:"
+
+// RedisBackend stores cache entries in Redis so they are shared between
+// server instances. Expiration is handled by Redis key TTLs.
+type RedisBackend struct {
+	redis *state.RedisStateManager
+}
+
+// NewRedisBackend creates a Redis-backed cache backend
+func NewRedisBackend(redis *state.RedisStateManager) *RedisBackend {
+	return &RedisBackend{redis: redis}
+}
+
+// Get implements Backend
+func (r *RedisBackend) Get(ctx context.Context, key string) (*Entry, bool, error) {
+	var entry Entry
+	ok, err := r.redis.GetJSON(redisKeyPrefix+"entry:"+key, &entry)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	return &entry, true, nil
+}
+
+// Set implements Backend
+func (r *RedisBackend) Set(ctx context.Context, entry *Entry, ttl time.Duration) error {
+	if err := r.redis.SetJSON(redisKeyPrefix+"entry:"+entry.Key, entry, ttl); err != nil {
+		return err
+	}
+	return r.redis.AddSetMember(redisKeyPrefix+"scope:"+entry.Scope, entry.Key, ttl)
+}
+
+// Scope implements Backend. Members whose entries have expired are pruned.
+func (r *RedisBackend) Scope(ctx context.Context, scope string) ([]*Entry, error) {
+	scopeKey := redisKeyPrefix + "scope:" + scope
+	keys, err := r.redis.SetMembers(scopeKey)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*Entry, 0, len(keys))
+	for _, key := range keys {
+		entry, ok, err := r.Get(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			_ = r.redis.RemoveSetMember(scopeKey, key)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
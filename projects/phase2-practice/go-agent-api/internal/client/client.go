@@ -0,0 +1,317 @@
+// Package client is a Go client for the go-agent-api HTTP API
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/agent-learning/go-agent-api/internal/agent"
+	"github.com/agent-learning/go-agent-api/internal/scheduler"
+)
+
+// APIError is a non-2xx response from the server
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("server returned %d: %s", e.StatusCode, e.Message)
+}
+
+// Client calls the go-agent-api HTTP API
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// New creates a client for the server at baseURL. apiKey may be empty.
+func New(baseURL, apiKey string) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// TaskPage is one page of a task listing
+type TaskPage struct {
+	Tasks      []*agent.Task `json:"tasks"`
+	Total      int           `json:"total"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// TaskEvent is one event of a watched task. Exactly one of Task and Result is set.
+type TaskEvent struct {
+	Task   *agent.Task
+	Result *agent.TaskResult
+}
+
+// CreateAgent creates an agent
+func (c *Client) CreateAgent(ctx context.Context, req *agent.CreateAgentRequest) (*agent.Agent, error) {
+	var ag agent.Agent
+	if err := c.do(ctx, http.MethodPost, "/api/v1/agents", req, &ag); err != nil {
+		return nil, err
+	}
+	return &ag, nil
+}
+
+// ListAgents lists all agents
+func (c *Client) ListAgents(ctx context.Context) ([]*agent.Agent, error) {
+	var resp struct {
+		Agents []*agent.Agent `json:"agents"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/agents", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Agents, nil
+}
+
+// GetAgent gets an agent by ID
+func (c *Client) GetAgent(ctx context.Context, id string) (*agent.Agent, error) {
+	var ag agent.Agent
+	if err := c.do(ctx, http.MethodGet, "/api/v1/agents/"+url.PathEscape(id), nil, &ag); err != nil {
+		return nil, err
+	}
+	return &ag, nil
+}
+
+// DeleteAgent deletes an agent
+func (c *Client) DeleteAgent(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/api/v1/agents/"+url.PathEscape(id), nil, nil)
+}
+
+// ImportAgents creates or updates agents from definitions
+func (c *Client) ImportAgents(ctx context.Context, defs []*agent.AgentDefinition) (*agent.ImportResult, error) {
+	var result agent.ImportResult
+	if err := c.do(ctx, http.MethodPost, "/api/v1/agents/import", agent.AgentBundle{Agents: defs}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SubmitTask submits a task. A non-empty idempotencyKey makes retries safe.
+func (c *Client) SubmitTask(ctx context.Context, req *agent.CreateTaskRequest, idempotencyKey string) (*agent.Task, error) {
+	var task agent.Task
+	header := http.Header{}
+	if idempotencyKey != "" {
+		header.Set("Idempotency-Key", idempotencyKey)
+	}
+	if err := c.doWithHeader(ctx, http.MethodPost, "/api/v1/tasks", header, req, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// ListTasks lists tasks. query holds the filters of GET /api/v1/tasks.
+func (c *Client) ListTasks(ctx context.Context, query url.Values) (*TaskPage, error) {
+	path := "/api/v1/tasks"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	var page TaskPage
+	if err := c.do(ctx, http.MethodGet, path, nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// GetTask gets a task by ID
+func (c *Client) GetTask(ctx context.Context, id string) (*agent.Task, error) {
+	var task agent.Task
+	if err := c.do(ctx, http.MethodGet, "/api/v1/tasks/"+url.PathEscape(id), nil, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// GetTaskResult gets the result of a finished task
+func (c *Client) GetTaskResult(ctx context.Context, id string) (*agent.TaskResult, error) {
+	var result agent.TaskResult
+	if err := c.do(ctx, http.MethodGet, "/api/v1/tasks/"+url.PathEscape(id)+"/result", nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CancelTask cancels a pending or running task
+func (c *Client) CancelTask(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/api/v1/tasks/"+url.PathEscape(id), nil, nil)
+}
+
+// Stats returns scheduler statistics
+func (c *Client) Stats(ctx context.Context) (map[string]interface{}, error) {
+	var stats map[string]interface{}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/tasks/stats", nil, &stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// Usage returns task and token usage since the server started
+func (c *Client) Usage(ctx context.Context) (*scheduler.Usage, error) {
+	var usage scheduler.Usage
+	if err := c.do(ctx, http.MethodGet, "/api/v1/usage", nil, &usage); err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
+
+// WatchTask streams the status changes of a task to fn until the task
+// finishes, fn returns an error or ctx is done
+func (c *Client) WatchTask(ctx context.Context, id string, fn func(TaskEvent) error) error {
+	req, err := c.newRequest(ctx, http.MethodGet, "/api/v1/tasks/"+url.PathEscape(id)+"/events", nil, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	// The stream lasts as long as the task, so no client timeout applies
+	streamClient := *c.httpClient
+	streamClient.Timeout = 0
+	resp, err := streamClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to watch task: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return readError(resp)
+	}
+
+	return readEvents(resp.Body, func(name string, data []byte) error {
+		switch name {
+		case "status":
+			var task agent.Task
+			if err := json.Unmarshal(data, &task); err != nil {
+				return fmt.Errorf("invalid status event: %w", err)
+			}
+			return fn(TaskEvent{Task: &task})
+		case "result":
+			var result agent.TaskResult
+			if err := json.Unmarshal(data, &result); err != nil {
+				return fmt.Errorf("invalid result event: %w", err)
+			}
+			return fn(TaskEvent{Result: &result})
+		case "error":
+			var apiErr struct {
+				Error string `json:"error"`
+			}
+			json.Unmarshal(data, &apiErr)
+			return fmt.Errorf("watch failed: %s", apiErr.Error)
+		}
+		return nil
+	})
+}
+
+// readEvents parses a server-sent event stream, calling fn per event
+func readEvents(r io.Reader, fn func(name string, data []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+
+	var name string
+	var data bytes.Buffer
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if data.Len() > 0 || name != "" {
+				if err := fn(name, bytes.TrimSuffix(data.Bytes(), []byte("\n"))); err != nil {
+					return err
+				}
+			}
+			name = ""
+			data.Reset()
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			name = value
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+		}
+	}
+	return scanner.Err()
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	return c.doWithHeader(ctx, method, path, nil, body, out)
+}
+
+func (c *Client) doWithHeader(ctx context.Context, method, path string, header http.Header, body, out interface{}) error {
+	req, err := c.newRequest(ctx, method, path, header, body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return readError(resp)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, header http.Header, body interface{}) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+	return req, nil
+}
+
+// readError turns an error response into an APIError
+func readError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	var body struct {
+		Error string `json:"error"`
+	}
+	message := strings.TrimSpace(string(data))
+	if json.Unmarshal(data, &body) == nil && body.Error != "" {
+		message = body.Error
+	}
+	if message == "" {
+		message = http.StatusText(resp.StatusCode)
+	}
+	return &APIError{StatusCode: resp.StatusCode, Message: message}
+}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agent-learning/go-agent-api/internal/agent"
+	"github.com/agent-learning/go-agent-api/internal/api"
+	"github.com/agent-learning/go-agent-api/internal/scheduler"
+	"github.com/gin-gonic/gin"
+	"github.com/sashabaranov/go-openai"
+)
+
+// echoLLM answers every request with the last user message
+type echoLLM struct{}
+
+func (echoLLM) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	return openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{
+			{Message: openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: "echo: " + req.Messages[len(req.Messages)-1].Content}},
+		},
+		Usage: openai.Usage{TotalTokens: 7},
+	}, nil
+}
+
+// newTestServer serves the API over HTTP. The scheduler is returned unstarted
+// so tests can submit tasks before they run.
+func newTestServer(t *testing.T) (*Client, *scheduler.Scheduler) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	service := agent.NewAgentServiceWithClient(echoLLM{})
+	sched := scheduler.NewScheduler(service, 2, time.Minute)
+	usage := scheduler.NewUsageMeter()
+	sched.AddNotifier(usage)
+
+	router := gin.New()
+	api.SetupRoutes(router, api.Dependencies{AgentService: service, Scheduler: sched, Usage: usage})
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	return New(server.URL, "test-key"), sched
+}
+
+func TestClientAgentsAndTasks(t *testing.T) {
+	c, sched := newTestServer(t)
+	ctx := context.Background()
+
+	ag, err := c.CreateAgent(ctx, &agent.CreateAgentRequest{Name: "echo", Type: agent.AgentTypeGeneral})
+	if err != nil {
+		t.Fatalf("CreateAgent failed: %v", err)
+	}
+	agents, err := c.ListAgents(ctx)
+	if err != nil || len(agents) != 1 || agents[0].ID != ag.ID {
+		t.Fatalf("Expected the created agent to be listed, got %v %v", agents, err)
+	}
+
+	task, err := c.SubmitTask(ctx, &agent.CreateTaskRequest{AgentID: ag.ID, Type: agent.TaskTypeQuery, Input: "hello"}, "")
+	if err != nil {
+		t.Fatalf("SubmitTask failed: %v", err)
+	}
+	sched.Start()
+	defer sched.Stop()
+
+	var statuses []agent.TaskStatus
+	var result *agent.TaskResult
+	err = c.WatchTask(ctx, task.ID, func(event TaskEvent) error {
+		if event.Task != nil {
+			statuses = append(statuses, event.Task.Status)
+		} else {
+			result = event.Result
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WatchTask failed: %v", err)
+	}
+	if len(statuses) == 0 || statuses[len(statuses)-1] != agent.TaskStatusCompleted {
+		t.Errorf("Expected the watch to end on completed, got %v", statuses)
+	}
+	if result == nil || result.Output != "echo: hello" {
+		t.Fatalf("Expected the result event, got %+v", result)
+	}
+
+	page, err := c.ListTasks(ctx, url.Values{"status": {"completed"}})
+	if err != nil || len(page.Tasks) != 1 {
+		t.Errorf("Expected one completed task, got %+v %v", page, err)
+	}
+
+	usage, err := c.Usage(ctx)
+	if err != nil {
+		t.Fatalf("Usage failed: %v", err)
+	}
+	if usage.Total.Completed != 1 || usage.Total.TokensUsed != 7 || usage.ByAgent[ag.ID] == nil {
+		t.Errorf("Unexpected usage: %+v", usage.Total)
+	}
+}
+
+func TestClientReturnsAPIError(t *testing.T) {
+	c, _ := newTestServer(t)
+
+	_, err := c.GetTask(context.Background(), "missing")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Message == "" {
+		t.Errorf("Expected a 404 APIError, got %v", err)
+	}
+}
+
+func TestReadEvents(t *testing.T) {
+	stream := "event:status\ndata:{\"a\":1}\n\n: comment\nevent: result\ndata: line1\ndata: line2\n\n"
+
+	var got []string
+	err := readEvents(strings.NewReader(stream), func(name string, data []byte) error {
+		got = append(got, name+"="+string(data))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("readEvents failed: %v", err)
+	}
+	if len(got) != 2 || got[0] != `status={"a":1}` || got[1] != "result=line1\nline2" {
+		t.Errorf("Unexpected events: %q", got)
+	}
+}
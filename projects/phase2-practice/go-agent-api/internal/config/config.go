@@ -1,118 +1,463 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	Server   ServerConfig
-	OpenAI   OpenAIConfig
-	Redis    RedisConfig
-	Postgres PostgresConfig
-	Agent    AgentConfig
+	Server      ServerConfig     `yaml:"server"`
+	OpenAI      OpenAIConfig     `yaml:"openai"`
+	Redis       RedisConfig      `yaml:"redis"`
+	Postgres    PostgresConfig   `yaml:"postgres"`
+	Agent       AgentConfig      `yaml:"agent"`
+	Webhook     WebhookConfig    `yaml:"webhook"`
+	Storage     StorageConfig    `yaml:"storage"`
+	Cache       CacheConfig      `yaml:"cache"`
+	Guardrails  GuardrailsConfig `yaml:"guardrails"`
+	Transcripts TranscriptConfig `yaml:"transcripts"`
+
+	// File is the YAML file the config was loaded from, if any
+	File string `yaml:"-"`
 }
 
 // ServerConfig holds server configuration
 type ServerConfig struct {
-	Port    string
-	GinMode string
+	Port    string `yaml:"port"`
+	GinMode string `yaml:"gin_mode"`
 }
 
 // OpenAIConfig holds OpenAI API configuration
 type OpenAIConfig struct {
-	APIKey string
-	Model  string
+	APIKey string `yaml:"api_key"`
+	Model  string `yaml:"model"`
 }
 
 // RedisConfig holds Redis configuration
 type RedisConfig struct {
-	Host     string
-	Port     string
-	Password string
-	DB       int
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	Password string `yaml:"password"`
+	DB       int    `yaml:"db"`
 }
 
 // PostgresConfig holds PostgreSQL configuration
 type PostgresConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	DBName   string
-	SSLMode  string
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	DBName   string `yaml:"db_name"`
+	SSLMode  string `yaml:"ssl_mode"`
 }
 
 // AgentConfig holds agent-specific configuration
 type AgentConfig struct {
-	MaxConcurrent int
-	TaskTimeout   int
-	MaxRetries    int
+	MaxConcurrent int `yaml:"max_concurrent"`
+	TaskTimeout   int `yaml:"task_timeout"`
+	MaxRetries    int `yaml:"max_retries"`
+	// PriorityAging is the seconds a queued task waits to gain one priority level
+	PriorityAging int `yaml:"priority_aging"`
+	// IdempotencyTTL is the seconds an Idempotency-Key keeps mapping to its task
+	IdempotencyTTL int `yaml:"idempotency_ttl"`
+	// APIKeyWeights maps an API key to its fair-share weight
+	APIKeyWeights map[string]string `yaml:"api_key_weights"`
+	// Defaults fill in the config of agents created without one
+	Defaults AgentDefaultsConfig `yaml:"defaults"`
+	// AllowedTools restricts the tools agents and tasks may use; empty allows any
+	AllowedTools []string `yaml:"allowed_tools"`
 }
 
-// Load loads configuration from environment variables
-func Load() (*Config, error) {
-	// Load .env file if exists
-	_ = godotenv.Load()
+// AgentDefaultsConfig holds the config new agents start from
+type AgentDefaultsConfig struct {
+	Model       string  `yaml:"model"`
+	Temperature float64 `yaml:"temperature"`
+	MaxTokens   int     `yaml:"max_tokens"`
+}
+
+// StorageConfig holds local persistence configuration
+type StorageConfig struct {
+	// DataDir holds file-based stores such as schedules and delayed tasks
+	DataDir string `yaml:"data_dir"`
+	// AgentStore selects where agents are persisted: memory, file or postgres
+	AgentStore string `yaml:"agent_store"`
+	// TaskStore selects where task history is kept: memory or postgres
+	TaskStore string `yaml:"task_store"`
+}
 
-	config := &Config{
+// CacheConfig holds LLM response cache configuration
+type CacheConfig struct {
+	Enabled    bool   `yaml:"enabled"`
+	Backend    string `yaml:"backend"`
+	TTL        int    `yaml:"ttl"`
+	MaxEntries int    `yaml:"max_entries"`
+	// SemanticThreshold enables embedding-similarity lookup when > 0
+	SemanticThreshold float64 `yaml:"semantic_threshold"`
+	EmbeddingModel    string  `yaml:"embedding_model"`
+}
+
+// GuardrailsConfig holds input/output guardrail configuration
+type GuardrailsConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Actions are off, flag, redact or block
+	PIIAction       string   `yaml:"pii_action"`
+	PIITypes        []string `yaml:"pii_types"`
+	InjectionAction string   `yaml:"injection_action"`
+	OutputBlocklist []string `yaml:"output_blocklist"`
+	OutputAction    string   `yaml:"output_action"`
+}
+
+// TranscriptConfig holds task transcript recording configuration
+type TranscriptConfig struct {
+	// Enabled is off by default as transcripts hold task input and model
+	// output; only what the guardrails let through is recorded
+	Enabled bool `yaml:"enabled"`
+	// Store is memory or file (under DataDir/transcripts)
+	Store string `yaml:"store"`
+	// MaxEntries bounds the memory store
+	MaxEntries int `yaml:"max_entries"`
+}
+
+// WebhookConfig holds task webhook configuration
+type WebhookConfig struct {
+	Secret      string `yaml:"secret"`
+	MaxAttempts int    `yaml:"max_attempts"`
+	Timeout     int    `yaml:"timeout"`
+	// APIKeyDefaults maps an API key to its default callback URL
+	APIKeyDefaults map[string]string `yaml:"api_key_defaults"`
+}
+
+// Default returns the built-in configuration
+func Default() *Config {
+	return &Config{
 		Server: ServerConfig{
-			Port:    getEnv("SERVER_PORT", "8080"),
-			GinMode: getEnv("GIN_MODE", "debug"),
+			Port:    "8080",
+			GinMode: "debug",
 		},
 		OpenAI: OpenAIConfig{
-			APIKey: getEnv("OPENAI_API_KEY", ""),
-			Model:  getEnv("OPENAI_MODEL", "gpt-4"),
+			Model: "gpt-4",
 		},
 		Redis: RedisConfig{
-			Host:     getEnv("REDIS_HOST", "localhost"),
-			Port:     getEnv("REDIS_PORT", "6379"),
-			Password: getEnv("REDIS_PASSWORD", ""),
-			DB:       getEnvAsInt("REDIS_DB", 0),
+			Host: "localhost",
+			Port: "6379",
 		},
 		Postgres: PostgresConfig{
-			Host:     getEnv("POSTGRES_HOST", "localhost"),
-			Port:     getEnv("POSTGRES_PORT", "5432"),
-			User:     getEnv("POSTGRES_USER", "postgres"),
-			Password: getEnv("POSTGRES_PASSWORD", ""),
-			DBName:   getEnv("POSTGRES_DB", "agent_api"),
-			SSLMode:  getEnv("POSTGRES_SSLMODE", "disable"),
+			Host:    "localhost",
+			Port:    "5432",
+			User:    "postgres",
+			DBName:  "agent_api",
+			SSLMode: "disable",
 		},
 		Agent: AgentConfig{
-			MaxConcurrent: getEnvAsInt("MAX_CONCURRENT_AGENTS", 10),
-			TaskTimeout:   getEnvAsInt("TASK_TIMEOUT", 300),
-			MaxRetries:    getEnvAsInt("MAX_RETRIES", 3),
+			MaxConcurrent:  10,
+			TaskTimeout:    300,
+			MaxRetries:     3,
+			PriorityAging:  30,
+			IdempotencyTTL: 86400,
+			APIKeyWeights:  map[string]string{},
+			Defaults: AgentDefaultsConfig{
+				Model:       "gpt-4",
+				Temperature: 0.7,
+				MaxTokens:   2000,
+			},
+			AllowedTools: []string{},
+		},
+		Webhook: WebhookConfig{
+			MaxAttempts:    5,
+			Timeout:        10,
+			APIKeyDefaults: map[string]string{},
+		},
+		Storage: StorageConfig{
+			DataDir:    "./data",
+			AgentStore: "file",
+			TaskStore:  "memory",
 		},
+		Cache: CacheConfig{
+			Backend:        "memory",
+			TTL:            3600,
+			MaxEntries:     1000,
+			EmbeddingModel: "text-embedding-3-small",
+		},
+		Guardrails: GuardrailsConfig{
+			PIIAction:       "redact",
+			PIITypes:        []string{},
+			InjectionAction: "flag",
+			OutputBlocklist: []string{},
+			OutputAction:    "redact",
+		},
+		Transcripts: TranscriptConfig{
+			Enabled:    false,
+			Store:      "memory",
+			MaxEntries: 1000,
+		},
+	}
+}
+
+// Load builds the configuration in layers: built-in defaults, the YAML file
+// (-config flag or CONFIG_FILE), environment variables, then flags. flags
+// may be nil. All invalid values are reported together.
+func Load(flags *Flags) (*Config, error) {
+	// Load .env file if exists
+	_ = godotenv.Load()
+
+	path := os.Getenv("CONFIG_FILE")
+	if flags != nil && flags.File != "" {
+		path = flags.File
 	}
+	return load(path, flags)
+}
 
-	// Validate required fields
-	if config.OpenAI.APIKey == "" {
-		return nil, fmt.Errorf("OPENAI_API_KEY is required")
+// load builds the configuration from the file at path, if any, the
+// environment and flags
+func load(path string, flags *Flags) (*Config, error) {
+	config := Default()
+	config.File = path
+	if path != "" {
+		if err := config.loadFile(path); err != nil {
+			return nil, err
+		}
 	}
 
+	envErr := config.applyEnv()
+	flags.apply(config)
+
+	if err := errors.Join(envErr, config.Validate()); err != nil {
+		return nil, err
+	}
 	return config, nil
 }
 
-// getEnv gets environment variable or returns default value
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// loadFile overlays the YAML file at path. Unknown keys are rejected so
+// typos do not go unnoticed.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv overlays the environment variables that are set
+func (c *Config) applyEnv() error {
+	e := &envReader{}
+
+	e.str("SERVER_PORT", &c.Server.Port)
+	e.str("GIN_MODE", &c.Server.GinMode)
+
+	e.str("OPENAI_API_KEY", &c.OpenAI.APIKey)
+	e.str("OPENAI_MODEL", &c.OpenAI.Model)
+
+	e.str("REDIS_HOST", &c.Redis.Host)
+	e.str("REDIS_PORT", &c.Redis.Port)
+	e.str("REDIS_PASSWORD", &c.Redis.Password)
+	e.int("REDIS_DB", &c.Redis.DB)
+
+	e.str("POSTGRES_HOST", &c.Postgres.Host)
+	e.str("POSTGRES_PORT", &c.Postgres.Port)
+	e.str("POSTGRES_USER", &c.Postgres.User)
+	e.str("POSTGRES_PASSWORD", &c.Postgres.Password)
+	e.str("POSTGRES_DB", &c.Postgres.DBName)
+	e.str("POSTGRES_SSLMODE", &c.Postgres.SSLMode)
+
+	e.int("MAX_CONCURRENT_AGENTS", &c.Agent.MaxConcurrent)
+	e.int("TASK_TIMEOUT", &c.Agent.TaskTimeout)
+	e.int("MAX_RETRIES", &c.Agent.MaxRetries)
+	e.int("PRIORITY_AGING_INTERVAL", &c.Agent.PriorityAging)
+	e.int("IDEMPOTENCY_TTL", &c.Agent.IdempotencyTTL)
+	e.stringMap("SCHEDULER_API_KEY_WEIGHTS", &c.Agent.APIKeyWeights)
+	e.str("AGENT_DEFAULT_MODEL", &c.Agent.Defaults.Model)
+	e.float("AGENT_DEFAULT_TEMPERATURE", &c.Agent.Defaults.Temperature)
+	e.int("AGENT_DEFAULT_MAX_TOKENS", &c.Agent.Defaults.MaxTokens)
+	e.list("AGENT_ALLOWED_TOOLS", &c.Agent.AllowedTools)
+
+	e.str("WEBHOOK_SECRET", &c.Webhook.Secret)
+	e.int("WEBHOOK_MAX_ATTEMPTS", &c.Webhook.MaxAttempts)
+	e.int("WEBHOOK_TIMEOUT", &c.Webhook.Timeout)
+	e.stringMap("WEBHOOK_API_KEY_DEFAULTS", &c.Webhook.APIKeyDefaults)
+
+	e.str("DATA_DIR", &c.Storage.DataDir)
+	e.str("AGENT_STORE", &c.Storage.AgentStore)
+	e.str("TASK_STORE", &c.Storage.TaskStore)
+
+	e.bool("CACHE_ENABLED", &c.Cache.Enabled)
+	e.str("CACHE_BACKEND", &c.Cache.Backend)
+	e.int("CACHE_TTL", &c.Cache.TTL)
+	e.int("CACHE_MAX_ENTRIES", &c.Cache.MaxEntries)
+	e.float("CACHE_SEMANTIC_THRESHOLD", &c.Cache.SemanticThreshold)
+	e.str("CACHE_EMBEDDING_MODEL", &c.Cache.EmbeddingModel)
+
+	e.bool("GUARDRAILS_ENABLED", &c.Guardrails.Enabled)
+	e.str("GUARDRAILS_PII_ACTION", &c.Guardrails.PIIAction)
+	e.list("GUARDRAILS_PII_TYPES", &c.Guardrails.PIITypes)
+	e.str("GUARDRAILS_INJECTION_ACTION", &c.Guardrails.InjectionAction)
+	e.list("GUARDRAILS_OUTPUT_BLOCKLIST", &c.Guardrails.OutputBlocklist)
+	e.str("GUARDRAILS_OUTPUT_ACTION", &c.Guardrails.OutputAction)
+
+	e.bool("TRANSCRIPTS_ENABLED", &c.Transcripts.Enabled)
+	e.str("TRANSCRIPT_STORE", &c.Transcripts.Store)
+	e.int("TRANSCRIPT_MAX_ENTRIES", &c.Transcripts.MaxEntries)
+
+	return errors.Join(e.errs...)
+}
+
+// Validate reports every invalid setting
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	oneOf := func(name, value string, allowed ...string) {
+		for _, a := range allowed {
+			if value == a {
+				return
+			}
+		}
+		errs = append(errs, fmt.Errorf("%s must be one of %s, got %q", name, strings.Join(allowed, ", "), value))
+	}
+
+	check(c.OpenAI.APIKey != "", "OPENAI_API_KEY is required")
+	check(c.Server.Port != "", "server.port is required")
+	oneOf("server.gin_mode", c.Server.GinMode, "debug", "release", "test")
+
+	check(c.Agent.MaxConcurrent > 0, "agent.max_concurrent must be positive, got %d", c.Agent.MaxConcurrent)
+	check(c.Agent.TaskTimeout > 0, "agent.task_timeout must be positive, got %d", c.Agent.TaskTimeout)
+	check(c.Agent.MaxRetries >= 0, "agent.max_retries cannot be negative, got %d", c.Agent.MaxRetries)
+	check(c.Agent.PriorityAging > 0, "agent.priority_aging must be positive, got %d", c.Agent.PriorityAging)
+	check(c.Agent.IdempotencyTTL > 0, "agent.idempotency_ttl must be positive, got %d", c.Agent.IdempotencyTTL)
+	for apiKey, weight := range c.Agent.APIKeyWeights {
+		w, err := strconv.ParseFloat(weight, 64)
+		check(err == nil && w > 0, "agent.api_key_weights: invalid weight %q for API key %s", weight, maskKey(apiKey))
+	}
+	check(c.Agent.Defaults.Model != "", "agent.defaults.model is required")
+	check(c.Agent.Defaults.Temperature > 0 && c.Agent.Defaults.Temperature <= 2,
+		"agent.defaults.temperature must be in (0, 2], got %g", c.Agent.Defaults.Temperature)
+	check(c.Agent.Defaults.MaxTokens > 0, "agent.defaults.max_tokens must be positive, got %d", c.Agent.Defaults.MaxTokens)
+
+	check(c.Webhook.MaxAttempts > 0, "webhook.max_attempts must be positive, got %d", c.Webhook.MaxAttempts)
+	check(c.Webhook.Timeout > 0, "webhook.timeout must be positive, got %d", c.Webhook.Timeout)
+
+	oneOf("storage.agent_store", c.Storage.AgentStore, "memory", "file", "postgres")
+	oneOf("storage.task_store", c.Storage.TaskStore, "memory", "postgres")
+	check(c.Storage.TaskStore != "postgres" || c.Storage.AgentStore == "postgres",
+		"storage.task_store postgres requires storage.agent_store postgres")
+
+	oneOf("cache.backend", c.Cache.Backend, "memory", "redis")
+	check(c.Cache.MaxEntries > 0, "cache.max_entries must be positive, got %d", c.Cache.MaxEntries)
+	check(c.Cache.SemanticThreshold >= 0 && c.Cache.SemanticThreshold <= 1,
+		"cache.semantic_threshold must be in [0, 1], got %g", c.Cache.SemanticThreshold)
+
+	oneOf("transcripts.store", c.Transcripts.Store, "memory", "file")
+	check(c.Transcripts.MaxEntries > 0, "transcripts.max_entries must be positive, got %d", c.Transcripts.MaxEntries)
+
+	return errors.Join(errs...)
+}
+
+// maskKey hides all but the last four characters of an API key
+func maskKey(key string) string {
+	if len(key) <= 4 {
+		return "****"
+	}
+	return "****" + key[len(key)-4:]
+}
+
+// envReader overlays environment variables onto config fields, collecting
+// malformed values instead of silently ignoring them
+type envReader struct {
+	errs []error
+}
+
+// lookup returns a variable that is set and not empty
+func (e *envReader) lookup(key string) (string, bool) {
+	value := os.Getenv(key)
+	return value, value != ""
+}
+
+func (e *envReader) str(key string, field *string) {
+	if value, ok := e.lookup(key); ok {
+		*field = value
+	}
+}
+
+func (e *envReader) int(key string, field *int) {
+	if value, ok := e.lookup(key); ok {
+		intValue, err := strconv.Atoi(value)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s must be an integer, got %q", key, value))
+			return
+		}
+		*field = intValue
+	}
+}
+
+func (e *envReader) bool(key string, field *bool) {
+	if value, ok := e.lookup(key); ok {
+		boolValue, err := strconv.ParseBool(value)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s must be true or false, got %q", key, value))
+			return
+		}
+		*field = boolValue
+	}
+}
+
+func (e *envReader) float(key string, field *float64) {
+	if value, ok := e.lookup(key); ok {
+		floatValue, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s must be a number, got %q", key, value))
+			return
+		}
+		*field = floatValue
+	}
+}
+
+// list parses a comma-separated variable
+func (e *envReader) list(key string, field *[]string) {
+	if value, ok := e.lookup(key); ok {
+		result := make([]string, 0)
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				result = append(result, item)
+			}
+		}
+		*field = result
 	}
-	return defaultValue
 }
 
-// getEnvAsInt gets environment variable as int or returns default value
-func getEnvAsInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
+// stringMap parses a "key=value,key=value" variable
+func (e *envReader) stringMap(key string, field *map[string]string) {
+	if value, ok := e.lookup(key); ok {
+		result := make(map[string]string)
+		for _, pair := range strings.Split(value, ",") {
+			if pair = strings.TrimSpace(pair); pair == "" {
+				continue
+			}
+			k, v, ok := strings.Cut(pair, "=")
+			if !ok || k == "" {
+				e.errs = append(e.errs, fmt.Errorf("%s must be key=value pairs, got %q", key, pair))
+				continue
+			}
+			result[k] = v
 		}
+		*field = result
 	}
-	return defaultValue
 }
 
 // GetDSN returns PostgreSQL connection string
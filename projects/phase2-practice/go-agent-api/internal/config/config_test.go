@@ -0,0 +1,152 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+}
+
+func parseFlags(t *testing.T, args ...string) *Flags {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+	return flags
+}
+
+func TestLoadLayers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, `
+server:
+  port: "9000"
+agent:
+  max_concurrent: 4
+  task_timeout: 60
+  defaults:
+    model: file-model
+  allowed_tools: [search]
+`)
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+	t.Setenv("TASK_TIMEOUT", "120")
+	t.Setenv("MAX_CONCURRENT_AGENTS", "6")
+
+	cfg, err := Load(parseFlags(t, "-config", path, "-max-concurrent", "8"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.Server.Port != "9000" || cfg.Agent.Defaults.Model != "file-model" {
+		t.Errorf("Expected file values, got port %s model %s", cfg.Server.Port, cfg.Agent.Defaults.Model)
+	}
+	if cfg.Agent.TaskTimeout != 120 {
+		t.Errorf("Expected env to override the file, got timeout %d", cfg.Agent.TaskTimeout)
+	}
+	if cfg.Agent.MaxConcurrent != 8 {
+		t.Errorf("Expected flag to override env, got max concurrent %d", cfg.Agent.MaxConcurrent)
+	}
+	if cfg.Agent.MaxRetries != 3 || cfg.Agent.Defaults.MaxTokens != 2000 {
+		t.Errorf("Expected defaults for unset values, got %+v", cfg.Agent)
+	}
+	if len(cfg.Agent.AllowedTools) != 1 || cfg.File != path {
+		t.Errorf("Unexpected config: tools %v file %s", cfg.Agent.AllowedTools, cfg.File)
+	}
+}
+
+func TestLoadReportsAllErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, `
+agent:
+  max_concurrent: 0
+storage:
+  agent_store: disk
+`)
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("TASK_TIMEOUT", "soon")
+
+	_, err := Load(parseFlags(t, "-config", path))
+	if err == nil {
+		t.Fatal("Expected validation errors")
+	}
+	for _, want := range []string{"OPENAI_API_KEY is required", "agent.max_concurrent", "storage.agent_store", "TASK_TIMEOUT must be an integer"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %q, got: %v", want, err)
+		}
+	}
+}
+
+func TestLoadRejectsUnknownKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, "agent:\n  max_concurent: 3\n")
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+
+	_, err := Load(parseFlags(t, "-config", path))
+	if err == nil || !strings.Contains(err.Error(), "max_concurent") {
+		t.Errorf("Expected unknown key error, got %v", err)
+	}
+}
+
+func TestWatcherAppliesSafeSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, "server:\n  port: \"9000\"\nagent:\n  max_concurrent: 4\n")
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+
+	flags := parseFlags(t, "-config", path)
+	cfg, err := Load(flags)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	changes := make(chan *Config, 4)
+	watcher, err := NewWatcher(cfg, flags, func(next *Config) { changes <- next })
+	if err != nil {
+		t.Fatalf("NewWatcher failed: %v", err)
+	}
+	watcher.Start()
+	defer watcher.Stop()
+
+	// Invalid files keep the running config
+	writeConfig(t, path, "agent:\n  max_concurrent: -1\n")
+	time.Sleep(3 * reloadDelay)
+	writeConfig(t, path, "server:\n  port: \"9100\"\nagent:\n  max_concurrent: 20\n  task_timeout: 30\n")
+
+	select {
+	case next := <-changes:
+		if next.Agent.MaxConcurrent != 20 || next.Agent.TaskTimeout != 30 {
+			t.Errorf("Expected reloaded agent settings, got %+v", next.Agent)
+		}
+		if next.Server.Port != "9000" {
+			t.Errorf("Expected the port to wait for a restart, got %s", next.Server.Port)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Config was not reloaded")
+	}
+	select {
+	case next := <-changes:
+		t.Errorf("Unexpected extra reload: %+v", next.Agent)
+	default:
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a, b := Default(), Default()
+	b.Server.Port = "9000"
+	b.Agent.APIKeyWeights = map[string]string{"key": "2"}
+	b.File = "other.yaml"
+
+	changed := a.diff(b)
+	if strings.Join(changed, ",") != "server.port,agent.api_key_weights" {
+		t.Errorf("Unexpected diff: %v", changed)
+	}
+}
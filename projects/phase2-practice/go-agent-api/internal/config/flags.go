@@ -0,0 +1,62 @@
+package config
+
+import "flag"
+
+// Flags are command-line overrides, the highest-precedence config layer.
+// Only flags that were passed override lower layers.
+type Flags struct {
+	// File is the YAML config file; it overrides CONFIG_FILE
+	File string
+
+	fs            *flag.FlagSet
+	port          string
+	ginMode       string
+	model         string
+	maxConcurrent int
+	taskTimeout   int
+	dataDir       string
+	agentStore    string
+	taskStore     string
+}
+
+// RegisterFlags defines the config flags on fs
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{fs: fs}
+	fs.StringVar(&f.File, "config", "", "YAML config file (overrides CONFIG_FILE)")
+	fs.StringVar(&f.port, "port", "", "Server port (SERVER_PORT)")
+	fs.StringVar(&f.ginMode, "gin-mode", "", "Gin mode: debug, release or test (GIN_MODE)")
+	fs.StringVar(&f.model, "model", "", "Default model for new agents (AGENT_DEFAULT_MODEL)")
+	fs.IntVar(&f.maxConcurrent, "max-concurrent", 0, "Maximum concurrently running tasks (MAX_CONCURRENT_AGENTS)")
+	fs.IntVar(&f.taskTimeout, "task-timeout", 0, "Task timeout in seconds (TASK_TIMEOUT)")
+	fs.StringVar(&f.dataDir, "data-dir", "", "Directory of file-based stores (DATA_DIR)")
+	fs.StringVar(&f.agentStore, "agent-store", "", "Agent store: memory, file or postgres (AGENT_STORE)")
+	fs.StringVar(&f.taskStore, "task-store", "", "Task store: memory or postgres (TASK_STORE)")
+	return f
+}
+
+// apply overlays the flags that were set on the command line
+func (f *Flags) apply(c *Config) {
+	if f == nil {
+		return
+	}
+	f.fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "port":
+			c.Server.Port = f.port
+		case "gin-mode":
+			c.Server.GinMode = f.ginMode
+		case "model":
+			c.Agent.Defaults.Model = f.model
+		case "max-concurrent":
+			c.Agent.MaxConcurrent = f.maxConcurrent
+		case "task-timeout":
+			c.Agent.TaskTimeout = f.taskTimeout
+		case "data-dir":
+			c.Storage.DataDir = f.dataDir
+		case "agent-store":
+			c.Storage.AgentStore = f.agentStore
+		case "task-store":
+			c.Storage.TaskStore = f.taskStore
+		}
+	})
+}
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDelay coalesces the burst of events a single save produces
+const reloadDelay = 100 * time.Millisecond
+
+// Watcher reloads the config file when it changes. Only settings that are
+// safe to change at runtime are applied: the scheduler limits, task timeout,
+// priority aging, agent defaults and tool allow-list. Changes to other
+// settings are logged and wait for a restart.
+type Watcher struct {
+	path     string
+	flags    *Flags
+	current  *Config
+	lastData []byte
+	onChange func(*Config)
+	watcher  *fsnotify.Watcher
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewWatcher watches the file cfg was loaded from. onChange is called with
+// the running config after each valid change; invalid files are logged and
+// ignored.
+func NewWatcher(cfg *Config, flags *Flags, onChange func(*Config)) (*Watcher, error) {
+	if cfg.File == "" {
+		return nil, fmt.Errorf("config was not loaded from a file")
+	}
+	data, err := os.ReadFile(cfg.File)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch config file: %w", err)
+	}
+	// The directory is watched because editors and ConfigMap mounts replace
+	// the file rather than writing it in place
+	if err := fsw.Add(filepath.Dir(cfg.File)); err != nil {
+		fsw.Close()
+		return nil, fmt.Errorf("failed to watch config file: %w", err)
+	}
+
+	return &Watcher{
+		path:     cfg.File,
+		flags:    flags,
+		current:  cfg,
+		lastData: data,
+		onChange: onChange,
+		watcher:  fsw,
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// Start starts watching for changes
+func (w *Watcher) Start() {
+	w.wg.Add(1)
+	go w.run()
+	log.Printf("Watching %s for config changes", w.path)
+}
+
+// Stop stops watching
+func (w *Watcher) Stop() {
+	close(w.done)
+	w.watcher.Close()
+	w.wg.Wait()
+}
+
+func (w *Watcher) run() {
+	defer w.wg.Done()
+
+	timer := time.NewTimer(reloadDelay)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case _, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			timer.Reset(reloadDelay)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("Config watch error: %v", err)
+		case <-timer.C:
+			w.reload()
+		}
+	}
+}
+
+// reload applies the file if its content changed
+func (w *Watcher) reload() {
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		// The file may be between a remove and a create; the create retriggers
+		log.Printf("Config reload skipped: %v", err)
+		return
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		// Editors truncate before writing; the write retriggers
+		return
+	}
+	if bytes.Equal(data, w.lastData) {
+		return
+	}
+	w.lastData = data
+
+	next, err := load(w.path, w.flags)
+	if err != nil {
+		log.Printf("Config reload rejected, keeping the running config: %v", err)
+		return
+	}
+
+	running := *w.current
+	running.applyReloadable(next)
+	if changed := running.diff(next); len(changed) > 0 {
+		log.Printf("Config changes to %s require a restart", strings.Join(changed, ", "))
+	}
+	w.current = &running
+
+	log.Printf("Config reloaded from %s", w.path)
+	w.onChange(&running)
+}
+
+// applyReloadable copies the settings that can change at runtime from src
+func (c *Config) applyReloadable(src *Config) {
+	c.Agent.MaxConcurrent = src.Agent.MaxConcurrent
+	c.Agent.TaskTimeout = src.Agent.TaskTimeout
+	c.Agent.PriorityAging = src.Agent.PriorityAging
+	c.Agent.Defaults = src.Agent.Defaults
+	c.Agent.AllowedTools = src.Agent.AllowedTools
+}
+
+// diff returns the YAML names of the settings that differ between c and other
+func (c *Config) diff(other *Config) []string {
+	var changed []string
+	a, b := reflect.ValueOf(c).Elem(), reflect.ValueOf(other).Elem()
+	for i := 0; i < a.NumField(); i++ {
+		section := a.Type().Field(i)
+		name := yamlName(section)
+		if name == "" || reflect.DeepEqual(a.Field(i).Interface(), b.Field(i).Interface()) {
+			continue
+		}
+		if section.Type.Kind() != reflect.Struct {
+			changed = append(changed, name)
+			continue
+		}
+		for j := 0; j < section.Type.NumField(); j++ {
+			if !reflect.DeepEqual(a.Field(i).Field(j).Interface(), b.Field(i).Field(j).Interface()) {
+				changed = append(changed, name+"."+yamlName(section.Type.Field(j)))
+			}
+		}
+	}
+	return changed
+}
+
+// yamlName returns a field's YAML key, or "" for fields not read from YAML
+func yamlName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
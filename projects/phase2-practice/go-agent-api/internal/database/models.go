@@ -12,10 +12,22 @@ type AgentRecord struct {
 	Type      string
 	Status    string
 	Config    string // JSON string
+	Version   int
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
 
+// AgentVersionRecord represents an immutable agent version in the database
+type AgentVersionRecord struct {
+	AgentID   string
+	Version   int
+	Name      string
+	Type      string
+	Config    string // JSON string
+	Comment   string
+	CreatedAt time.Time
+}
+
 // TaskRecord represents a task record in the database
 type TaskRecord struct {
 	ID        string
@@ -32,6 +44,15 @@ type TaskRecord struct {
 	UpdatedAt time.Time
 	StartedAt sql.NullTime
 	EndedAt   sql.NullTime
+
+	Submitter      string
+	IdempotencyKey sql.NullString
+	RequestHash    sql.NullString
+
+	CallbackURL   string
+	OutputSchema  sql.NullString // JSON Schema
+	SchemaRetries int
+	Guardrails    sql.NullString // JSON array of findings
 }
 
 // TaskResultRecord represents a task result record in the database
@@ -2,10 +2,12 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 // PostgresDB wraps PostgreSQL database connection
@@ -54,6 +56,20 @@ func (p *PostgresDB) InitSchema() error {
 	CREATE INDEX IF NOT EXISTS idx_agents_status ON agents(status);
 	CREATE INDEX IF NOT EXISTS idx_agents_type ON agents(type);
 
+	ALTER TABLE agents ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+
+	CREATE TABLE IF NOT EXISTS agent_versions (
+		agent_id VARCHAR(255) NOT NULL,
+		version INTEGER NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		type VARCHAR(50) NOT NULL,
+		config JSONB,
+		comment TEXT,
+		created_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (agent_id, version),
+		FOREIGN KEY (agent_id) REFERENCES agents(id) ON DELETE CASCADE
+	);
+
 	CREATE TABLE IF NOT EXISTS tasks (
 		id VARCHAR(255) PRIMARY KEY,
 		agent_id VARCHAR(255) NOT NULL,
@@ -76,6 +92,28 @@ func (p *PostgresDB) InitSchema() error {
 	CREATE INDEX IF NOT EXISTS idx_tasks_status ON tasks(status);
 	CREATE INDEX IF NOT EXISTS idx_tasks_created_at ON tasks(created_at);
 
+	ALTER TABLE tasks ADD COLUMN IF NOT EXISTS submitter VARCHAR(255) NOT NULL DEFAULT '';
+	ALTER TABLE tasks ADD COLUMN IF NOT EXISTS idempotency_key VARCHAR(255);
+	ALTER TABLE tasks ADD COLUMN IF NOT EXISTS request_hash VARCHAR(64);
+	ALTER TABLE tasks ADD COLUMN IF NOT EXISTS callback_url TEXT NOT NULL DEFAULT '';
+	ALTER TABLE tasks ADD COLUMN IF NOT EXISTS output_schema JSONB;
+	ALTER TABLE tasks ADD COLUMN IF NOT EXISTS schema_retries INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE tasks ADD COLUMN IF NOT EXISTS guardrails JSONB;
+
+	-- A submitter holds each idempotency key at most once
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_tasks_submitter_idempotency_key ON tasks(submitter, idempotency_key)
+		WHERE idempotency_key IS NOT NULL;
+
+	-- Task listing pages by (created_at, id) within the common filters
+	CREATE INDEX IF NOT EXISTS idx_tasks_created_at_id ON tasks(created_at, id);
+	CREATE INDEX IF NOT EXISTS idx_tasks_agent_created_at ON tasks(agent_id, created_at, id);
+	CREATE INDEX IF NOT EXISTS idx_tasks_status_created_at ON tasks(status, created_at, id);
+	CREATE INDEX IF NOT EXISTS idx_tasks_metadata ON tasks USING GIN (metadata jsonb_path_ops);
+	-- Text search is a substring match; a trigram index on the searched
+	-- expression serves ILIKE for words of three or more characters
+	CREATE EXTENSION IF NOT EXISTS pg_trgm;
+	CREATE INDEX IF NOT EXISTS idx_tasks_search ON tasks USING GIN (` + taskSearchText + ` gin_trgm_ops);
+
 	CREATE TABLE IF NOT EXISTS task_results (
 		id SERIAL PRIMARY KEY,
 		task_id VARCHAR(255) NOT NULL,
@@ -102,37 +140,152 @@ func (p *PostgresDB) InitSchema() error {
 
 // SaveAgent saves an agent to the database
 func (p *PostgresDB) SaveAgent(agent *AgentRecord) error {
+	return saveAgent(p.db, agent)
+}
+
+// SaveAgentWithVersion saves an agent and a new version of it in one transaction
+func (p *PostgresDB) SaveAgentWithVersion(agent *AgentRecord, version *AgentVersionRecord) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := saveAgent(tx, agent); err != nil {
+		return err
+	}
+
 	query := `
-		INSERT INTO agents (id, name, type, status, config, created_at, updated_at)
+		INSERT INTO agent_versions (agent_id, version, name, type, config, comment, created_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	if _, err := tx.Exec(query,
+		version.AgentID, version.Version, version.Name, version.Type,
+		version.Config, version.Comment, version.CreatedAt,
+	); err != nil {
+		return fmt.Errorf("failed to save agent version: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// execer is satisfied by *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func saveAgent(db execer, agent *AgentRecord) error {
+	query := `
+		INSERT INTO agents (id, name, type, status, config, version, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		ON CONFLICT (id) DO UPDATE SET
 			name = EXCLUDED.name,
 			type = EXCLUDED.type,
 			status = EXCLUDED.status,
 			config = EXCLUDED.config,
+			version = EXCLUDED.version,
 			updated_at = EXCLUDED.updated_at
 	`
 
-	_, err := p.db.Exec(query,
+	_, err := db.Exec(query,
 		agent.ID, agent.Name, agent.Type, agent.Status,
-		agent.Config, agent.CreatedAt, agent.UpdatedAt,
+		agent.Config, agent.Version, agent.CreatedAt, agent.UpdatedAt,
 	)
+	if err != nil {
+		return fmt.Errorf("failed to save agent: %w", err)
+	}
 
-	return err
+	return nil
+}
+
+// ListAgents retrieves all agents
+func (p *PostgresDB) ListAgents() ([]*AgentRecord, error) {
+	query := `
+		SELECT id, name, type, status, config, version, created_at, updated_at
+		FROM agents
+		ORDER BY created_at
+	`
+
+	rows, err := p.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	agents := make([]*AgentRecord, 0)
+	for rows.Next() {
+		var agent AgentRecord
+		var config sql.NullString
+		err := rows.Scan(
+			&agent.ID, &agent.Name, &agent.Type, &agent.Status,
+			&config, &agent.Version, &agent.CreatedAt, &agent.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		agent.Config = config.String
+		agents = append(agents, &agent)
+	}
+
+	return agents, rows.Err()
+}
+
+// ListAgentVersions retrieves the version history of an agent, oldest first
+func (p *PostgresDB) ListAgentVersions(agentID string) ([]*AgentVersionRecord, error) {
+	query := `
+		SELECT agent_id, version, name, type, config, comment, created_at
+		FROM agent_versions
+		WHERE agent_id = $1
+		ORDER BY version
+	`
+
+	rows, err := p.db.Query(query, agentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := make([]*AgentVersionRecord, 0)
+	for rows.Next() {
+		var version AgentVersionRecord
+		var config, comment sql.NullString
+		err := rows.Scan(
+			&version.AgentID, &version.Version, &version.Name, &version.Type,
+			&config, &comment, &version.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		version.Config = config.String
+		version.Comment = comment.String
+		versions = append(versions, &version)
+	}
+
+	return versions, rows.Err()
+}
+
+// DeleteAgent deletes an agent and its version history
+func (p *PostgresDB) DeleteAgent(id string) error {
+	if _, err := p.db.Exec(`DELETE FROM agents WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete agent: %w", err)
+	}
+	return nil
 }
 
 // SaveTask saves a task to the database
 func (p *PostgresDB) SaveTask(task *TaskRecord) error {
 	query := `
-		INSERT INTO tasks (id, agent_id, type, input, output, status, priority, tools, metadata, error, created_at, updated_at, started_at, ended_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		INSERT INTO tasks (id, agent_id, type, input, output, status, priority, tools, metadata, error, created_at, updated_at, started_at, ended_at,
+			submitter, idempotency_key, request_hash, callback_url, output_schema, schema_retries, guardrails)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
 		ON CONFLICT (id) DO UPDATE SET
 			output = EXCLUDED.output,
 			status = EXCLUDED.status,
 			error = EXCLUDED.error,
 			updated_at = EXCLUDED.updated_at,
 			started_at = EXCLUDED.started_at,
-			ended_at = EXCLUDED.ended_at
+			ended_at = EXCLUDED.ended_at,
+			guardrails = EXCLUDED.guardrails
 	`
 
 	_, err := p.db.Exec(query,
@@ -140,11 +293,54 @@ func (p *PostgresDB) SaveTask(task *TaskRecord) error {
 		task.Status, task.Priority, task.Tools, task.Metadata,
 		task.Error, task.CreatedAt, task.UpdatedAt,
 		task.StartedAt, task.EndedAt,
+		task.Submitter, task.IdempotencyKey, task.RequestHash,
+		task.CallbackURL, task.OutputSchema, task.SchemaRetries, task.Guardrails,
 	)
 
 	return err
 }
 
+// InsertKeyedTask inserts a new task that carries an idempotency key. A task
+// created before since releases the key; if a newer task holds it, nothing is
+// inserted and false is returned.
+func (p *PostgresDB) InsertKeyedTask(task *TaskRecord, since time.Time) (bool, error) {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		UPDATE tasks SET idempotency_key = NULL
+		WHERE submitter = $1 AND idempotency_key = $2 AND created_at < $3`,
+		task.Submitter, task.IdempotencyKey, since,
+	); err != nil {
+		return false, err
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO tasks (id, agent_id, type, input, output, status, priority, tools, metadata, error, created_at, updated_at, started_at, ended_at,
+			submitter, idempotency_key, request_hash, callback_url, output_schema, schema_retries, guardrails)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
+		ON CONFLICT (submitter, idempotency_key) WHERE idempotency_key IS NOT NULL DO NOTHING`,
+		task.ID, task.AgentID, task.Type, task.Input, task.Output,
+		task.Status, task.Priority, task.Tools, task.Metadata,
+		task.Error, task.CreatedAt, task.UpdatedAt,
+		task.StartedAt, task.EndedAt,
+		task.Submitter, task.IdempotencyKey, task.RequestHash,
+		task.CallbackURL, task.OutputSchema, task.SchemaRetries, task.Guardrails,
+	)
+	if err != nil {
+		return false, err
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return inserted == 1, tx.Commit()
+}
+
 // SaveTaskResult saves a task result to the database
 func (p *PostgresDB) SaveTaskResult(result *TaskResultRecord) error {
 	query := `
@@ -160,6 +356,182 @@ func (p *PostgresDB) SaveTaskResult(result *TaskResultRecord) error {
 	return err
 }
 
+// taskColumns are the columns scanned by scanTask, in order
+const taskColumns = `id, agent_id, type, input, output, status, priority, tools, metadata, error, created_at, updated_at, started_at, ended_at,
+	submitter, idempotency_key, request_hash, callback_url, output_schema, schema_retries, guardrails`
+
+// taskSearchText is the text searched by TaskFilter.Text. Queries must use
+// the same expression for idx_tasks_search to apply.
+const taskSearchText = `(input || ' ' || coalesce(output, ''))`
+
+// TaskFilter selects and pages tasks for QueryTasks
+type TaskFilter struct {
+	AgentID       string
+	Statuses      []string
+	Types         []string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	// Metadata matches tasks whose metadata contains every key with the given string value
+	Metadata map[string]string
+	// Text matches tasks whose input or output contains every word,
+	// ignoring case
+	Text      string
+	Ascending bool
+	// AfterCreatedAt and AfterID continue a listing after the last task of a page
+	AfterCreatedAt *time.Time
+	AfterID        string
+	Limit          int
+}
+
+// GetTask retrieves a task by ID
+func (p *PostgresDB) GetTask(id string) (*TaskRecord, error) {
+	row := p.db.QueryRow(`SELECT `+taskColumns+` FROM tasks WHERE id = $1`, id)
+	return scanTask(row)
+}
+
+// GetTaskByIdempotencyKey retrieves the newest task a submitter created with
+// key at or after since
+func (p *PostgresDB) GetTaskByIdempotencyKey(submitter, key string, since time.Time) (*TaskRecord, error) {
+	query := `SELECT ` + taskColumns + `
+		FROM tasks
+		WHERE submitter = $1 AND idempotency_key = $2 AND created_at >= $3
+		ORDER BY created_at DESC
+		LIMIT 1`
+	return scanTask(p.db.QueryRow(query, submitter, key, since))
+}
+
+// QueryTasks retrieves the tasks matching filter ordered by (created_at, id)
+func (p *PostgresDB) QueryTasks(filter TaskFilter) ([]*TaskRecord, error) {
+	conditions, args, err := taskConditions(filter)
+	if err != nil {
+		return nil, err
+	}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	order, cmp := "DESC", "<"
+	if filter.Ascending {
+		order, cmp = "ASC", ">"
+	}
+	if filter.AfterCreatedAt != nil {
+		conditions = append(conditions, fmt.Sprintf("(created_at, id) %s (%s, %s)", cmp, arg(*filter.AfterCreatedAt), arg(filter.AfterID)))
+	}
+
+	query := `SELECT ` + taskColumns + ` FROM tasks`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY created_at %s, id %s", order, order)
+	if filter.Limit > 0 {
+		query += " LIMIT " + arg(filter.Limit)
+	}
+
+	rows, err := p.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tasks: %w", err)
+	}
+	defer rows.Close()
+
+	tasks := make([]*TaskRecord, 0)
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+
+	return tasks, rows.Err()
+}
+
+// CountTasks counts every task matching filter, ignoring paging
+func (p *PostgresDB) CountTasks(filter TaskFilter) (int, error) {
+	conditions, args, err := taskConditions(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `SELECT COUNT(*) FROM tasks`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var count int
+	if err := p.db.QueryRow(query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count tasks: %w", err)
+	}
+	return count, nil
+}
+
+// taskConditions builds the WHERE conditions and arguments of filter,
+// leaving out paging
+func taskConditions(filter TaskFilter) ([]string, []interface{}, error) {
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.AgentID != "" {
+		conditions = append(conditions, "agent_id = "+arg(filter.AgentID))
+	}
+	if len(filter.Statuses) > 0 {
+		conditions = append(conditions, "status = ANY("+arg(pq.Array(filter.Statuses))+")")
+	}
+	if len(filter.Types) > 0 {
+		conditions = append(conditions, "type = ANY("+arg(pq.Array(filter.Types))+")")
+	}
+	if filter.CreatedAfter != nil {
+		conditions = append(conditions, "created_at >= "+arg(*filter.CreatedAfter))
+	}
+	if filter.CreatedBefore != nil {
+		conditions = append(conditions, "created_at < "+arg(*filter.CreatedBefore))
+	}
+	if len(filter.Metadata) > 0 {
+		metadata, err := json.Marshal(filter.Metadata)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to encode metadata filter: %w", err)
+		}
+		conditions = append(conditions, "metadata @> "+arg(string(metadata))+"::jsonb")
+	}
+	// Each word is a case-insensitive substring match, as in the memory store
+	for _, term := range strings.Fields(filter.Text) {
+		conditions = append(conditions, taskSearchText+" ILIKE "+arg("%"+likeEscaper.Replace(term)+"%"))
+	}
+
+	return conditions, args, nil
+}
+
+// likeEscaper escapes the LIKE wildcards in a search word
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// scanner is satisfied by *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTask(row scanner) (*TaskRecord, error) {
+	var task TaskRecord
+	var tools, metadata sql.NullString
+	err := row.Scan(
+		&task.ID, &task.AgentID, &task.Type, &task.Input, &task.Output,
+		&task.Status, &task.Priority, &tools, &metadata,
+		&task.Error, &task.CreatedAt, &task.UpdatedAt,
+		&task.StartedAt, &task.EndedAt,
+		&task.Submitter, &task.IdempotencyKey, &task.RequestHash,
+		&task.CallbackURL, &task.OutputSchema, &task.SchemaRetries, &task.Guardrails,
+	)
+	if err != nil {
+		return nil, err
+	}
+	task.Tools = tools.String
+	task.Metadata = metadata.String
+	return &task, nil
+}
+
 // GetTaskHistory retrieves task history
 func (p *PostgresDB) GetTaskHistory(limit int) ([]*TaskRecord, error) {
 	query := `
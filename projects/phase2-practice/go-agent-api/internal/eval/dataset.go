@@ -0,0 +1,137 @@
+package eval
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// GraderType selects how a case output is scored
+type GraderType string
+
+const (
+	// GraderExact compares trimmed output to the expected text
+	GraderExact GraderType = "exact"
+	// GraderRegex treats the expected text as a regular expression
+	GraderRegex GraderType = "regex"
+	// GraderJSONField compares one field of a JSON output to the expected value
+	GraderJSONField GraderType = "json_field"
+	// GraderLLMJudge asks a judge model whether the output meets the expectation
+	GraderLLMJudge GraderType = "llm_judge"
+)
+
+// Case is one dataset entry
+type Case struct {
+	ID       string     `json:"id"`
+	Input    string     `json:"input"`
+	Expected string     `json:"expected"`
+	Grader   GraderType `json:"grader"`
+	// Field is the dot-separated path used by the json_field grader
+	Field string `json:"field,omitempty"`
+	// Rubric gives the llm_judge grader extra grading instructions
+	Rubric string `json:"rubric,omitempty"`
+}
+
+// LoadDataset reads a JSONL dataset, one case per line. Blank lines and
+// lines starting with # are skipped.
+func LoadDataset(r io.Reader) ([]Case, error) {
+	cases := make([]Case, 0)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		var c Case
+		if err := json.Unmarshal([]byte(text), &c); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if c.ID == "" {
+			c.ID = fmt.Sprintf("case-%d", len(cases)+1)
+		}
+		if err := c.Validate(); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		cases = append(cases, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dataset: %w", err)
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("dataset is empty")
+	}
+
+	return cases, nil
+}
+
+// LoadDatasetFile reads a JSONL dataset from disk
+func LoadDatasetFile(path string) ([]Case, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dataset: %w", err)
+	}
+	defer f.Close()
+
+	return LoadDataset(f)
+}
+
+// Validate checks that a case can be graded
+func (c *Case) Validate() error {
+	if c.Input == "" {
+		return fmt.Errorf("case %s: input is required", c.ID)
+	}
+	if c.Grader == "" {
+		c.Grader = GraderExact
+	}
+
+	switch c.Grader {
+	case GraderExact, GraderRegex, GraderLLMJudge:
+	case GraderJSONField:
+		if c.Field == "" {
+			return fmt.Errorf("case %s: json_field grader requires field", c.ID)
+		}
+	default:
+		return fmt.Errorf("case %s: unknown grader %q", c.ID, c.Grader)
+	}
+	return nil
+}
+
+// RunRequest is an API request to evaluate one or two existing agents
+type RunRequest struct {
+	// Dataset holds the cases inline; DatasetJSONL is accepted instead for
+	// pasting a dataset file as-is
+	Dataset          []Case `json:"dataset"`
+	DatasetJSONL     string `json:"dataset_jsonl"`
+	BaselineAgentID  string `json:"baseline_agent_id" binding:"required"`
+	CandidateAgentID string `json:"candidate_agent_id"`
+}
+
+// Cases returns the validated cases of the request
+func (r *RunRequest) Cases() ([]Case, error) {
+	if r.DatasetJSONL != "" {
+		return LoadDataset(strings.NewReader(r.DatasetJSONL))
+	}
+	if len(r.Dataset) == 0 {
+		return nil, fmt.Errorf("dataset or dataset_jsonl is required")
+	}
+
+	cases := make([]Case, len(r.Dataset))
+	for i, c := range r.Dataset {
+		if c.ID == "" {
+			c.ID = fmt.Sprintf("case-%d", i+1)
+		}
+		if err := c.Validate(); err != nil {
+			return nil, err
+		}
+		cases[i] = c
+	}
+	return cases, nil
+}
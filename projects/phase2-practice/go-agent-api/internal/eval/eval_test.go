@@ -0,0 +1,132 @@
+package eval
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/agent-learning/go-agent-api/internal/agent"
+)
+
+const testDataset = `
+# capital cities
+{"id": "fr", "input": "Capital of France?", "expected": "Paris", "grader": "exact"}
+{"id": "de", "input": "Capital of Germany?", "expected": "^Berlin", "grader": "regex"}
+{"id": "json", "input": "Return the answer as JSON", "expected": "42", "grader": "json_field", "field": "result.value"}
+{"id": "judge", "input": "Say hello", "expected": "A greeting", "grader": "llm_judge"}
+`
+
+func TestLoadDatasetValidates(t *testing.T) {
+	cases, err := LoadDataset(strings.NewReader(testDataset))
+	if err != nil {
+		t.Fatalf("LoadDataset failed: %v", err)
+	}
+	if len(cases) != 4 || cases[2].Field != "result.value" {
+		t.Errorf("Unexpected cases: %+v", cases)
+	}
+
+	if _, err := LoadDataset(strings.NewReader(`{"input": "x", "grader": "json_field"}`)); err == nil {
+		t.Error("Expected error for json_field without field")
+	}
+	if _, err := LoadDataset(strings.NewReader(`{"input": "x", "grader": "fuzzy"}`)); err == nil {
+		t.Error("Expected error for unknown grader")
+	}
+}
+
+func TestScorers(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name   string
+		scorer Scorer
+		c      Case
+		output string
+		passed bool
+	}{
+		{"exact trims", ExactScorer{}, Case{Expected: "Paris"}, " Paris\n", true},
+		{"exact mismatch", ExactScorer{}, Case{Expected: "Paris"}, "paris", false},
+		{"regex", RegexScorer{}, Case{Expected: `1\.\d+`}, "Go 1.18", true},
+		{"json number", JSONFieldScorer{}, Case{Expected: "42", Field: "a.b"}, `{"a": {"b": 42}}`, true},
+		{"json type mismatch", JSONFieldScorer{}, Case{Expected: "42", Field: "a.b"}, `{"a": {"b": "42"}}`, false},
+		{"json plain string", JSONFieldScorer{}, Case{Expected: "positive", Field: "s"}, `{"s": "positive"}`, true},
+		{"json invalid", JSONFieldScorer{}, Case{Expected: "1", Field: "s"}, `not json`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, err := tt.scorer.Score(ctx, tt.c, tt.output)
+			if err != nil {
+				t.Fatalf("Score failed: %v", err)
+			}
+			if score.Passed != tt.passed {
+				t.Errorf("Expected passed=%v, got %+v", tt.passed, score)
+			}
+		})
+	}
+}
+
+func TestCompareWithScriptedProvider(t *testing.T) {
+	client, err := agent.NewScriptedClient(agent.Script{
+		Rules: []agent.ScriptRule{
+			{SystemContains: "You grade answers", Pattern: `Answer to grade:\n.*(?i)hello`, Reply: `{"pass": true, "score": 1, "reason": "greets"}`},
+			{SystemContains: "You grade answers", Reply: `{"pass": false, "reason": "no greeting"}`},
+			{Contains: "France", Reply: "Paris"},
+			{Model: "gpt-4", Contains: "Germany", Reply: "Berlin"},
+			{Contains: "Germany", Reply: "Munich"},
+			{Contains: "JSON", Reply: `{"result": {"value": 42}}`},
+			{Model: "gpt-4", Contains: "hello", Reply: "Bye"},
+			{Contains: "hello", Reply: "Hello there"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewScriptedClient failed: %v", err)
+	}
+
+	ctx := context.Background()
+	service := agent.NewAgentServiceWithClient(client)
+	baseline, _ := service.CreateAgent(ctx, &agent.CreateAgentRequest{Name: "base", Type: agent.AgentTypeGeneral, Config: agent.AgentConfig{Model: "gpt-3.5-turbo"}})
+	candidate, _ := service.CreateAgent(ctx, &agent.CreateAgentRequest{Name: "cand", Type: agent.AgentTypeGeneral, Config: agent.AgentConfig{Model: "gpt-4"}})
+
+	cases, _ := LoadDataset(strings.NewReader(testDataset))
+	report, err := NewRunner(service, NewJudgeScorer(client, "judge")).Compare(ctx, baseline, candidate, cases)
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+
+	if report.Baseline.Passed != 3 || report.Candidate.Passed != 3 {
+		t.Errorf("Unexpected pass counts: baseline %d, candidate %d", report.Baseline.Passed, report.Candidate.Passed)
+	}
+	if report.Improvements != 1 || report.Regressions != 1 {
+		t.Errorf("Expected 1 improvement and 1 regression, got %d and %d", report.Improvements, report.Regressions)
+	}
+
+	changes := make(map[string]Change)
+	for _, c := range report.Cases {
+		changes[c.CaseID] = c.Change
+	}
+	if changes["de"] != ChangeImprovement || changes["judge"] != ChangeRegression || changes["fr"] != ChangeUnchanged {
+		t.Errorf("Unexpected changes: %v", changes)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteText(&buf); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "1 regressions, 1 improvements") {
+		t.Errorf("Unexpected text report:\n%s", buf.String())
+	}
+}
+
+func TestRunWithoutJudgeReportsError(t *testing.T) {
+	client, _ := agent.NewScriptedClient(agent.Script{Default: "anything"})
+	service := agent.NewAgentServiceWithClient(client)
+	ag, _ := service.CreateAgent(context.Background(), &agent.CreateAgentRequest{Name: "a", Type: agent.AgentTypeGeneral})
+
+	run, err := NewRunner(service, nil).Run(context.Background(), ag, []Case{{ID: "j", Input: "x", Grader: GraderLLMJudge}})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if run.Summary.Errors != 1 || run.Results[0].Error == "" {
+		t.Errorf("Expected a grading error, got %+v", run.Results[0])
+	}
+}
@@ -0,0 +1,159 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/agent-learning/go-agent-api/internal/agent"
+)
+
+// Change classifies how a case moved between baseline and candidate
+type Change string
+
+const (
+	ChangeUnchanged   Change = "unchanged"
+	ChangeImprovement Change = "improvement"
+	ChangeRegression  Change = "regression"
+)
+
+// CaseComparison shows one case for both agents side by side
+type CaseComparison struct {
+	CaseID    string      `json:"case_id"`
+	Input     string      `json:"input"`
+	Expected  string      `json:"expected"`
+	Grader    GraderType  `json:"grader"`
+	Baseline  *CaseResult `json:"baseline"`
+	Candidate *CaseResult `json:"candidate,omitempty"`
+	Change    Change      `json:"change,omitempty"`
+}
+
+// Report is the result of an evaluation, optionally comparing two agents
+type Report struct {
+	Cases        []*CaseComparison `json:"cases"`
+	Baseline     Summary           `json:"baseline"`
+	Candidate    *Summary          `json:"candidate,omitempty"`
+	Regressions  int               `json:"regressions"`
+	Improvements int               `json:"improvements"`
+	CreatedAt    time.Time         `json:"created_at"`
+}
+
+// Compare runs the dataset against baseline and, if candidate is non-nil,
+// against candidate, and reports the differences per case
+func (r *Runner) Compare(ctx context.Context, baseline, candidate *agent.Agent, cases []Case) (*Report, error) {
+	baseRun, err := r.Run(ctx, baseline, cases)
+	if err != nil {
+		return nil, fmt.Errorf("baseline run failed: %w", err)
+	}
+
+	var candRun *Run
+	if candidate != nil {
+		if candRun, err = r.Run(ctx, candidate, cases); err != nil {
+			return nil, fmt.Errorf("candidate run failed: %w", err)
+		}
+	}
+
+	report := &Report{
+		Cases:     make([]*CaseComparison, 0, len(cases)),
+		Baseline:  baseRun.Summary,
+		CreatedAt: time.Now(),
+	}
+	if candRun != nil {
+		report.Candidate = &candRun.Summary
+	}
+
+	for i, c := range cases {
+		comparison := &CaseComparison{
+			CaseID:   c.ID,
+			Input:    c.Input,
+			Expected: c.Expected,
+			Grader:   c.Grader,
+			Baseline: baseRun.Results[i],
+		}
+		if candRun != nil {
+			comparison.Candidate = candRun.Results[i]
+			comparison.Change = compareScores(comparison.Baseline.Score, comparison.Candidate.Score)
+			switch comparison.Change {
+			case ChangeRegression:
+				report.Regressions++
+			case ChangeImprovement:
+				report.Improvements++
+			}
+		}
+		report.Cases = append(report.Cases, comparison)
+	}
+
+	return report, nil
+}
+
+// compareScores classifies a pass/fail flip first, then a score change
+func compareScores(base, cand Score) Change {
+	switch {
+	case base.Passed && !cand.Passed:
+		return ChangeRegression
+	case !base.Passed && cand.Passed:
+		return ChangeImprovement
+	case cand.Value < base.Value:
+		return ChangeRegression
+	case cand.Value > base.Value:
+		return ChangeImprovement
+	}
+	return ChangeUnchanged
+}
+
+// WriteText renders the report as plain-text tables
+func (rep *Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "AGENT\tMODEL\tTEMP\tPASSED\tPASS RATE\tAVG SCORE\tAVG MS\tTOKENS")
+	writeSummary(tw, "baseline", rep.Baseline)
+	if rep.Candidate != nil {
+		writeSummary(tw, "candidate", *rep.Candidate)
+	}
+	fmt.Fprintln(tw)
+
+	if rep.Candidate != nil {
+		fmt.Fprintln(tw, "CASE\tGRADER\tBASELINE\tCANDIDATE\tCHANGE")
+	} else {
+		fmt.Fprintln(tw, "CASE\tGRADER\tBASELINE")
+	}
+	for _, c := range rep.Cases {
+		if rep.Candidate != nil {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", c.CaseID, c.Grader, verdict(c.Baseline), verdict(c.Candidate), c.Change)
+		} else {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", c.CaseID, c.Grader, verdict(c.Baseline))
+		}
+	}
+
+	if rep.Candidate != nil {
+		fmt.Fprintf(tw, "\n%d regressions, %d improvements\n", rep.Regressions, rep.Improvements)
+	}
+	return tw.Flush()
+}
+
+func writeSummary(w io.Writer, label string, s Summary) {
+	fmt.Fprintf(w, "%s (%s)\t%s\t%.2f\t%d/%d\t%.0f%%\t%.2f\t%.0f\t%d\n",
+		label, s.AgentName, s.Model, s.Temperature, s.Passed, s.Total, s.PassRate*100, s.AvgScore, s.AvgDurationMs, s.TokensUsed)
+}
+
+func verdict(result *CaseResult) string {
+	switch {
+	case result.Error != "":
+		return "ERROR: " + truncate(result.Error, 40)
+	case result.Score.Passed:
+		return fmt.Sprintf("PASS %.2f", result.Score.Value)
+	default:
+		return fmt.Sprintf("FAIL %.2f", result.Score.Value)
+	}
+}
+
+func truncate(s string, n int) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,157 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/agent-learning/go-agent-api/internal/agent"
+)
+
+// CaseResult is the outcome of running one case against one agent
+type CaseResult struct {
+	CaseID     string `json:"case_id"`
+	Output     string `json:"output"`
+	Error      string `json:"error,omitempty"`
+	Score      Score  `json:"score"`
+	DurationMs int64  `json:"duration_ms"`
+	TokensUsed int    `json:"tokens_used"`
+}
+
+// Summary aggregates the results of one agent over a dataset
+type Summary struct {
+	AgentID       string  `json:"agent_id"`
+	AgentName     string  `json:"agent_name"`
+	Model         string  `json:"model"`
+	Temperature   float32 `json:"temperature"`
+	Total         int     `json:"total"`
+	Passed        int     `json:"passed"`
+	Errors        int     `json:"errors"`
+	PassRate      float64 `json:"pass_rate"`
+	AvgScore      float64 `json:"avg_score"`
+	AvgDurationMs float64 `json:"avg_duration_ms"`
+	TokensUsed    int     `json:"tokens_used"`
+}
+
+// Run is the full result of one agent over a dataset
+type Run struct {
+	Summary Summary       `json:"summary"`
+	Results []*CaseResult `json:"results"`
+}
+
+// Runner executes datasets through the agent service and grades the outputs
+type Runner struct {
+	service agent.AgentService
+	scorers map[GraderType]Scorer
+}
+
+// NewRunner creates a runner. judge may be nil, in which case llm_judge
+// cases are reported as errors.
+func NewRunner(service agent.AgentService, judge Scorer) *Runner {
+	scorers := map[GraderType]Scorer{
+		GraderExact:     ExactScorer{},
+		GraderRegex:     RegexScorer{},
+		GraderJSONField: JSONFieldScorer{},
+	}
+	if judge != nil {
+		scorers[GraderLLMJudge] = judge
+	}
+
+	return &Runner{
+		service: service,
+		scorers: scorers,
+	}
+}
+
+// Run executes every case against an agent, one at a time
+func (r *Runner) Run(ctx context.Context, ag *agent.Agent, cases []Case) (*Run, error) {
+	run := &Run{
+		Summary: Summary{
+			AgentID:     ag.ID,
+			AgentName:   ag.Name,
+			Model:       ag.Config.Model,
+			Temperature: ag.Config.Temperature,
+		},
+		Results: make([]*CaseResult, 0, len(cases)),
+	}
+
+	for _, c := range cases {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		run.Results = append(run.Results, r.runCase(ctx, ag, c))
+	}
+
+	run.summarize()
+	return run, nil
+}
+
+// runCase executes and grades a single case
+func (r *Runner) runCase(ctx context.Context, ag *agent.Agent, c Case) *CaseResult {
+	result := &CaseResult{CaseID: c.ID}
+
+	task := &agent.Task{
+		ID:        fmt.Sprintf("eval-%s-%s", ag.ID, c.ID),
+		AgentID:   ag.ID,
+		Type:      agent.TaskTypeQuery,
+		Input:     c.Input,
+		Status:    agent.TaskStatusRunning,
+		CreatedAt: time.Now(),
+		// Evaluations must measure the model, not the response cache
+		Metadata: map[string]interface{}{"cache_bypass": true},
+	}
+
+	taskResult, err := r.service.ExecuteTask(ctx, ag, task)
+	if taskResult != nil {
+		result.DurationMs = taskResult.Duration
+		if tokens, ok := taskResult.Metadata["tokens_used"].(int); ok {
+			result.TokensUsed = tokens
+		}
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Output = taskResult.Output
+
+	scorer, ok := r.scorers[c.Grader]
+	if !ok {
+		result.Error = fmt.Sprintf("no scorer configured for grader %q", c.Grader)
+		return result
+	}
+
+	score, err := scorer.Score(ctx, c, taskResult.Output)
+	if err != nil {
+		result.Error = fmt.Sprintf("grading failed: %v", err)
+		return result
+	}
+	result.Score = score
+
+	return result
+}
+
+// summarize fills in the run summary from its results
+func (run *Run) summarize() {
+	s := &run.Summary
+	s.Total = len(run.Results)
+
+	var totalScore float64
+	var totalDuration int64
+	for _, result := range run.Results {
+		if result.Error != "" {
+			s.Errors++
+		}
+		if result.Score.Passed {
+			s.Passed++
+		}
+		totalScore += result.Score.Value
+		totalDuration += result.DurationMs
+		s.TokensUsed += result.TokensUsed
+	}
+
+	if s.Total > 0 {
+		s.PassRate = float64(s.Passed) / float64(s.Total)
+		s.AvgScore = totalScore / float64(s.Total)
+		s.AvgDurationMs = float64(totalDuration) / float64(s.Total)
+	}
+}
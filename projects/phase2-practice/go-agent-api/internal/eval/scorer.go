@@ -0,0 +1,164 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/agent-learning/go-agent-api/internal/agent"
+	"github.com/sashabaranov/go-openai"
+)
+
+// Score is the grade of one output
+type Score struct {
+	Passed bool    `json:"passed"`
+	Value  float64 `json:"value"`
+	Reason string  `json:"reason,omitempty"`
+}
+
+// Scorer grades an agent output against a case
+type Scorer interface {
+	Score(ctx context.Context, c Case, output string) (Score, error)
+}
+
+// ExactScorer passes when the trimmed output equals the expected text
+type ExactScorer struct{}
+
+// Score implements Scorer
+func (ExactScorer) Score(ctx context.Context, c Case, output string) (Score, error) {
+	if strings.TrimSpace(output) == strings.TrimSpace(c.Expected) {
+		return pass(), nil
+	}
+	return fail("output does not match expected text"), nil
+}
+
+// RegexScorer passes when the output matches the expected pattern
+type RegexScorer struct{}
+
+// Score implements Scorer
+func (RegexScorer) Score(ctx context.Context, c Case, output string) (Score, error) {
+	re, err := regexp.Compile(c.Expected)
+	if err != nil {
+		return Score{}, fmt.Errorf("invalid pattern: %w", err)
+	}
+	if re.MatchString(output) {
+		return pass(), nil
+	}
+	return fail(fmt.Sprintf("output does not match /%s/", c.Expected)), nil
+}
+
+// JSONFieldScorer parses the output as JSON and compares the value at
+// Case.Field with the expected value. The expected text is parsed as JSON
+// when possible, so "42" matches a number and "\"42\"" only a string.
+type JSONFieldScorer struct{}
+
+// Score implements Scorer
+func (JSONFieldScorer) Score(ctx context.Context, c Case, output string) (Score, error) {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(output), &doc); err != nil {
+		return fail("output is not valid JSON"), nil
+	}
+
+	actual, ok := lookupField(doc, c.Field)
+	if !ok {
+		return fail(fmt.Sprintf("field %s not found", c.Field)), nil
+	}
+
+	var expected interface{}
+	if err := json.Unmarshal([]byte(c.Expected), &expected); err != nil {
+		expected = c.Expected
+	}
+	if reflect.DeepEqual(actual, expected) {
+		return pass(), nil
+	}
+	return fail(fmt.Sprintf("field %s is %v, expected %v", c.Field, actual, expected)), nil
+}
+
+// lookupField walks a dot-separated path through decoded JSON
+func lookupField(doc interface{}, path string) (interface{}, bool) {
+	current := doc
+	for _, part := range strings.Split(path, ".") {
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = obj[part]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// JudgeScorer asks an LLM whether the output satisfies the expectation
+type JudgeScorer struct {
+	client agent.LLMClient
+	model  string
+}
+
+// NewJudgeScorer creates an LLM judge using the given client and model
+func NewJudgeScorer(client agent.LLMClient, model string) *JudgeScorer {
+	if model == "" {
+		model = "gpt-4"
+	}
+	return &JudgeScorer{client: client, model: model}
+}
+
+const judgePrompt = `You grade answers produced by an AI assistant.
+Reply only with a JSON object: {"pass": true|false, "score": number between 0 and 1, "reason": "short explanation"}.`
+
+// judgeVerdict is the JSON reply expected from the judge
+type judgeVerdict struct {
+	Pass   bool     `json:"pass"`
+	Score  *float64 `json:"score"`
+	Reason string   `json:"reason"`
+}
+
+// Score implements Scorer
+func (j *JudgeScorer) Score(ctx context.Context, c Case, output string) (Score, error) {
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "Question:\n%s\n\nExpected answer:\n%s\n\n", c.Input, c.Expected)
+	if c.Rubric != "" {
+		fmt.Fprintf(&prompt, "Grading rubric:\n%s\n\n", c.Rubric)
+	}
+	fmt.Fprintf(&prompt, "Answer to grade:\n%s", output)
+
+	resp, err := j.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: j.model,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: judgePrompt},
+			{Role: openai.ChatMessageRoleUser, Content: prompt.String()},
+		},
+		ResponseFormat: &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject},
+	})
+	if err != nil {
+		return Score{}, fmt.Errorf("judge request failed: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return Score{}, fmt.Errorf("judge returned no choices")
+	}
+
+	var verdict judgeVerdict
+	if err := json.Unmarshal([]byte(resp.Choices[0].Message.Content), &verdict); err != nil {
+		return Score{}, fmt.Errorf("failed to parse judge verdict: %w", err)
+	}
+
+	score := Score{Passed: verdict.Pass, Reason: verdict.Reason}
+	switch {
+	case verdict.Score != nil:
+		score.Value = *verdict.Score
+	case verdict.Pass:
+		score.Value = 1
+	}
+	return score, nil
+}
+
+func pass() Score {
+	return Score{Passed: true, Value: 1}
+}
+
+func fail(reason string) Score {
+	return Score{Passed: false, Value: 0, Reason: reason}
+}
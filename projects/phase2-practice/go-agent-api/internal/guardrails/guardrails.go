@@ -0,0 +1,207 @@
+package guardrails
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Action is what happens when a rule triggers
+type Action string
+
+const (
+	// ActionOff disables a rule group
+	ActionOff Action = "off"
+	// ActionFlag records the finding and lets the text through unchanged
+	ActionFlag Action = "flag"
+	// ActionRedact replaces each match with a placeholder
+	ActionRedact Action = "redact"
+	// ActionBlock rejects the text
+	ActionBlock Action = "block"
+)
+
+// Stage is the point in agent execution where a check runs
+type Stage string
+
+const (
+	StageInput      Stage = "input"
+	StageOutput     Stage = "output"
+	StageToolOutput Stage = "tool_output"
+)
+
+// ErrBlocked is wrapped by errors for text rejected by a guardrail
+var ErrBlocked = errors.New("blocked by guardrails")
+
+// Finding records a triggered rule. Matched text is never stored so
+// findings are safe to keep for auditing.
+type Finding struct {
+	Rule      string    `json:"rule"`
+	Stage     Stage     `json:"stage"`
+	Action    Action    `json:"action"`
+	Matches   int       `json:"matches"`
+	Tool      string    `json:"tool,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Result is the outcome of running a stage over some text
+type Result struct {
+	// Text is the input with redactions applied
+	Text     string
+	Findings []Finding
+	Blocked  bool
+}
+
+// Err returns an ErrBlocked error naming the blocking rules, or nil
+func (r *Result) Err() error {
+	if !r.Blocked {
+		return nil
+	}
+	rules := make([]string, 0)
+	for _, f := range r.Findings {
+		if f.Action == ActionBlock {
+			rules = append(rules, f.Rule)
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrBlocked, strings.Join(rules, ", "))
+}
+
+// Config selects the rules and actions of each stage
+type Config struct {
+	// PIIAction applies PII detectors to task input, tool output and model output
+	PIIAction Action
+	// PIITypes limits detection to email, phone, credit_card and api_key (all when empty)
+	PIITypes []string
+	// InjectionAction applies prompt-injection heuristics to tool output
+	InjectionAction Action
+	// OutputBlocklist lists terms (or /regex/) filtered from model output
+	OutputBlocklist []string
+	// OutputAction applies to OutputBlocklist matches
+	OutputAction Action
+}
+
+// stageRule pairs a rule with the action it takes in a stage
+type stageRule struct {
+	rule   Rule
+	action Action
+}
+
+// Pipeline runs the configured rules for each execution stage
+type Pipeline struct {
+	stages map[Stage][]stageRule
+}
+
+// New builds a pipeline from config
+func New(config Config) (*Pipeline, error) {
+	for _, action := range []Action{config.PIIAction, config.InjectionAction, config.OutputAction} {
+		if err := validateAction(action); err != nil {
+			return nil, err
+		}
+	}
+
+	p := &Pipeline{stages: make(map[Stage][]stageRule)}
+
+	if enabled(config.PIIAction) {
+		for _, rule := range piiRules(config.PIITypes) {
+			for _, stage := range []Stage{StageInput, StageToolOutput, StageOutput} {
+				p.stages[stage] = append(p.stages[stage], stageRule{rule: rule, action: config.PIIAction})
+			}
+		}
+	}
+
+	if enabled(config.InjectionAction) {
+		for _, rule := range injectionRules() {
+			p.stages[StageToolOutput] = append(p.stages[StageToolOutput], stageRule{rule: rule, action: config.InjectionAction})
+		}
+	}
+
+	if enabled(config.OutputAction) {
+		rules, err := blocklistRules(config.OutputBlocklist)
+		if err != nil {
+			return nil, fmt.Errorf("invalid output blocklist: %w", err)
+		}
+		for _, rule := range rules {
+			p.stages[StageOutput] = append(p.stages[StageOutput], stageRule{rule: rule, action: config.OutputAction})
+		}
+	}
+
+	return p, nil
+}
+
+// CheckInput runs the input stage over task input
+func (p *Pipeline) CheckInput(text string) *Result {
+	return p.run(StageInput, "", text)
+}
+
+// CheckOutput runs the output stage over model output
+func (p *Pipeline) CheckOutput(text string) *Result {
+	return p.run(StageOutput, "", text)
+}
+
+// CheckToolOutput runs the tool output stage over a tool's result
+func (p *Pipeline) CheckToolOutput(tool, text string) *Result {
+	return p.run(StageToolOutput, tool, text)
+}
+
+// run applies each rule of a stage in order. Redactions from earlier rules
+// are visible to later ones.
+func (p *Pipeline) run(stage Stage, tool, text string) *Result {
+	result := &Result{Text: text}
+	now := time.Now()
+
+	for _, sr := range p.stages[stage] {
+		matches := sr.rule.Find(result.Text)
+		if len(matches) == 0 {
+			continue
+		}
+
+		result.Findings = append(result.Findings, Finding{
+			Rule:      sr.rule.Name(),
+			Stage:     stage,
+			Action:    sr.action,
+			Matches:   len(matches),
+			Tool:      tool,
+			Timestamp: now,
+		})
+
+		switch sr.action {
+		case ActionBlock:
+			result.Blocked = true
+		case ActionRedact:
+			result.Text = redact(result.Text, matches, sr.rule.Replacement())
+		}
+	}
+
+	return result
+}
+
+// redact replaces non-overlapping match ranges with replacement
+func redact(text string, matches [][]int, replacement string) string {
+	sort.Slice(matches, func(i, j int) bool { return matches[i][0] < matches[j][0] })
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		if m[0] < last {
+			continue
+		}
+		b.WriteString(text[last:m[0]])
+		b.WriteString(replacement)
+		last = m[1]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+func enabled(action Action) bool {
+	return action != "" && action != ActionOff
+}
+
+func validateAction(action Action) error {
+	switch action {
+	case "", ActionOff, ActionFlag, ActionRedact, ActionBlock:
+		return nil
+	}
+	return fmt.Errorf("unknown guardrail action %q", action)
+}
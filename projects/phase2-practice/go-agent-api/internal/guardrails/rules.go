@@ -0,0 +1,193 @@
+package guardrails
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Rule detects one kind of sensitive or unsafe content
+type Rule interface {
+	// Name identifies the rule in findings, e.g. "pii.email"
+	Name() string
+	// Find returns the [start, end) byte ranges of every match
+	Find(text string) [][]int
+	// Replacement is the text that replaces a match when redacting
+	Replacement() string
+}
+
+// patternRule is a regex-based rule with an optional match validator
+type patternRule struct {
+	name        string
+	pattern     *regexp.Regexp
+	replacement string
+	// valid filters out regex matches that are not real hits (e.g. Luhn)
+	valid func(match string) bool
+}
+
+func (r *patternRule) Name() string        { return r.name }
+func (r *patternRule) Replacement() string { return r.replacement }
+
+func (r *patternRule) Find(text string) [][]int {
+	matches := r.pattern.FindAllStringIndex(text, -1)
+	if r.valid == nil {
+		return matches
+	}
+
+	valid := matches[:0]
+	for _, m := range matches {
+		if r.valid(text[m[0]:m[1]]) {
+			valid = append(valid, m)
+		}
+	}
+	return valid
+}
+
+// PII types accepted in Config.PIITypes
+const (
+	PIIEmail      = "email"
+	PIIPhone      = "phone"
+	PIICreditCard = "credit_card"
+	PIIAPIKey     = "api_key"
+)
+
+// piiRules builds the detectors for the requested PII types (all when empty)
+func piiRules(types []string) []Rule {
+	all := map[string]Rule{
+		PIIEmail: &patternRule{
+			name:        "pii.email",
+			pattern:     regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+			replacement: "[REDACTED_EMAIL]",
+		},
+		PIICreditCard: &patternRule{
+			name:        "pii.credit_card",
+			pattern:     regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`),
+			replacement: "[REDACTED_CARD]",
+			valid:       luhnValid,
+		},
+		PIIAPIKey: &patternRule{
+			name: "pii.api_key",
+			pattern: regexp.MustCompile(`\b(?:sk-(?:proj-)?[A-Za-z0-9_\-]{20,}|AKIA[0-9A-Z]{16}|gh[pousr]_[A-Za-z0-9]{36,}|xox[abpr]-[A-Za-z0-9\-]{10,}|AIza[0-9A-Za-z_\-]{35})\b` +
+				`|(?i:(?:api[_-]?key|secret|token|password)\s*[:=]\s*["']?[A-Za-z0-9_\-./+]{16,})`),
+			replacement: "[REDACTED_API_KEY]",
+		},
+		PIIPhone: &patternRule{
+			name: "pii.phone",
+			// International (+CC ...), (NNN) NNN-NNNN, NNN-NNN-NNNN and CN mobile numbers.
+			// Space-separated groups without a + are left alone to avoid order numbers.
+			pattern: regexp.MustCompile(`\+\d{1,3}[ \-.]?(?:\(?\d{1,4}\)?[ \-.]?){1,3}\d{3,4}\b` +
+				`|\(\d{3}\) ?\d{3}[\-.]\d{4}\b|\b\d{3}[\-.]\d{3}[\-.]\d{4}\b|\b1[3-9]\d{9}\b`),
+			replacement: "[REDACTED_PHONE]",
+			valid:       phoneValid,
+		},
+	}
+
+	// Order matters: cards and keys are redacted before the looser phone pattern
+	order := []string{PIIEmail, PIICreditCard, PIIAPIKey, PIIPhone}
+	if len(types) > 0 {
+		wanted := make(map[string]bool, len(types))
+		for _, t := range types {
+			wanted[strings.TrimSpace(t)] = true
+		}
+		filtered := order[:0]
+		for _, t := range order {
+			if wanted[t] {
+				filtered = append(filtered, t)
+			}
+		}
+		order = filtered
+	}
+
+	rules := make([]Rule, 0, len(order))
+	for _, t := range order {
+		rules = append(rules, all[t])
+	}
+	return rules
+}
+
+// luhnValid reports whether the digits in s pass the Luhn checksum
+func luhnValid(s string) bool {
+	sum, digits := 0, 0
+	double := false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+		double = !double
+	}
+	return digits >= 13 && digits <= 19 && sum%10 == 0
+}
+
+// phoneValid checks that a match has a plausible number of digits
+func phoneValid(s string) bool {
+	digits := 0
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			digits++
+		}
+	}
+	return digits >= 7 && digits <= 15
+}
+
+// injectionPatterns are heuristics for instructions smuggled into content
+// the model should treat as data
+var injectionPatterns = []struct {
+	name    string
+	pattern string
+}{
+	{"ignore_instructions", `(?i)\b(?:ignore|disregard|forget|override)\b.{0,30}\b(?:previous|prior|above|earlier|all|your|system)\b.{0,20}\b(?:instructions?|prompts?|rules|directions)\b`},
+	{"role_override", `(?i)\b(?:you are now|from now on,? you (?:are|will)|act as (?:an? )?(?:unrestricted|jailbroken|dan\b)|pretend (?:to be|you are))`},
+	{"prompt_exfiltration", `(?i)\b(?:reveal|print|show|repeat|output|leak)\b.{0,30}\b(?:system prompt|hidden instructions|initial instructions|your instructions)\b`},
+	{"fake_delimiter", `(?im)(?:<\|im_start\|>|<\|system\|>|\[/?INST\]|###\s*(?:system|instruction)s?\s*:?|^\s*system\s*:)`},
+	{"tool_hijack", `(?i)\b(?:call|invoke|execute|run)\b.{0,20}\b(?:tool|function|command)\b.{0,40}\b(?:without|don't|do not)\b.{0,20}\b(?:tell|inform|ask|confirm)`},
+}
+
+// injectionRules builds the prompt-injection heuristics
+func injectionRules() []Rule {
+	rules := make([]Rule, 0, len(injectionPatterns))
+	for _, p := range injectionPatterns {
+		rules = append(rules, &patternRule{
+			name:        "injection." + p.name,
+			pattern:     regexp.MustCompile(p.pattern),
+			replacement: "[REMOVED_SUSPECTED_INJECTION]",
+		})
+	}
+	return rules
+}
+
+// blocklistRules builds output content filters. Terms are matched as
+// case-insensitive whole words unless they are written as /regex/.
+func blocklistRules(terms []string) ([]Rule, error) {
+	rules := make([]Rule, 0, len(terms))
+	for _, term := range terms {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+
+		expr := `(?i)\b` + regexp.QuoteMeta(term) + `\b`
+		if len(term) > 2 && strings.HasPrefix(term, "/") && strings.HasSuffix(term, "/") {
+			expr = term[1 : len(term)-1]
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, &patternRule{
+			name:        "content." + term,
+			pattern:     re,
+			replacement: "[FILTERED]",
+		})
+	}
+	return rules, nil
+}
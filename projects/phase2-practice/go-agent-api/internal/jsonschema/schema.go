@@ -0,0 +1,336 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Schema is a compiled subset of JSON Schema (draft 7) used to validate
+// structured agent output. Supported keywords: type, properties, required,
+// additionalProperties, items, enum, const, minimum, maximum, minLength,
+// maxLength, pattern, minItems and maxItems.
+type Schema struct {
+	Types                []string
+	Properties           map[string]*Schema
+	Required             []string
+	AdditionalProperties *Schema
+	NoAdditional         bool
+	Items                *Schema
+	Enum                 []interface{}
+	Const                interface{}
+	HasConst             bool
+	Minimum              *float64
+	Maximum              *float64
+	MinLength            *int
+	MaxLength            *int
+	Pattern              *regexp.Regexp
+	MinItems             *int
+	MaxItems             *int
+
+	raw json.RawMessage
+}
+
+// rawSchema mirrors the JSON layout of a schema document
+type rawSchema struct {
+	Type                 json.RawMessage            `json:"type"`
+	Properties           map[string]json.RawMessage `json:"properties"`
+	Required             []string                   `json:"required"`
+	AdditionalProperties json.RawMessage            `json:"additionalProperties"`
+	Items                json.RawMessage            `json:"items"`
+	Enum                 []interface{}              `json:"enum"`
+	Const                json.RawMessage            `json:"const"`
+	Minimum              *float64                   `json:"minimum"`
+	Maximum              *float64                   `json:"maximum"`
+	MinLength            *int                       `json:"minLength"`
+	MaxLength            *int                       `json:"maxLength"`
+	Pattern              string                     `json:"pattern"`
+	MinItems             *int                       `json:"minItems"`
+	MaxItems             *int                       `json:"maxItems"`
+}
+
+// ValidationError describes a single schema violation
+type ValidationError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// Error implements the error interface
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// Compile parses a JSON Schema document
+func Compile(data []byte) (*Schema, error) {
+	schema, err := compile(data, "$")
+	if err != nil {
+		return nil, err
+	}
+	schema.raw = append(json.RawMessage(nil), data...)
+	return schema, nil
+}
+
+func compile(data []byte, path string) (*Schema, error) {
+	var raw rawSchema
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid schema at %s: %w", path, err)
+	}
+
+	schema := &Schema{
+		Required:  raw.Required,
+		Enum:      raw.Enum,
+		Minimum:   raw.Minimum,
+		Maximum:   raw.Maximum,
+		MinLength: raw.MinLength,
+		MaxLength: raw.MaxLength,
+		MinItems:  raw.MinItems,
+		MaxItems:  raw.MaxItems,
+	}
+
+	if len(raw.Type) > 0 {
+		var single string
+		if err := json.Unmarshal(raw.Type, &single); err == nil {
+			schema.Types = []string{single}
+		} else if err := json.Unmarshal(raw.Type, &schema.Types); err != nil {
+			return nil, fmt.Errorf("invalid type at %s: must be a string or array of strings", path)
+		}
+		for _, t := range schema.Types {
+			if !isKnownType(t) {
+				return nil, fmt.Errorf("unknown type %q at %s", t, path)
+			}
+		}
+	}
+
+	if len(raw.Properties) > 0 {
+		schema.Properties = make(map[string]*Schema, len(raw.Properties))
+		for name, prop := range raw.Properties {
+			sub, err := compile(prop, path+"."+name)
+			if err != nil {
+				return nil, err
+			}
+			schema.Properties[name] = sub
+		}
+	}
+
+	if len(raw.AdditionalProperties) > 0 {
+		var allowed bool
+		if err := json.Unmarshal(raw.AdditionalProperties, &allowed); err == nil {
+			schema.NoAdditional = !allowed
+		} else {
+			sub, err := compile(raw.AdditionalProperties, path+".additionalProperties")
+			if err != nil {
+				return nil, err
+			}
+			schema.AdditionalProperties = sub
+		}
+	}
+
+	if len(raw.Items) > 0 {
+		sub, err := compile(raw.Items, path+"[]")
+		if err != nil {
+			return nil, err
+		}
+		schema.Items = sub
+	}
+
+	if len(raw.Const) > 0 {
+		if err := json.Unmarshal(raw.Const, &schema.Const); err != nil {
+			return nil, fmt.Errorf("invalid const at %s: %w", path, err)
+		}
+		schema.HasConst = true
+	}
+
+	if raw.Pattern != "" {
+		re, err := regexp.Compile(raw.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern at %s: %w", path, err)
+		}
+		schema.Pattern = re
+	}
+
+	return schema, nil
+}
+
+// Raw returns the original schema document
+func (s *Schema) Raw() json.RawMessage {
+	return s.raw
+}
+
+// Validate checks a decoded JSON value against the schema and returns
+// every violation found. An empty result means the value is valid.
+func (s *Schema) Validate(value interface{}) []ValidationError {
+	errs := make([]ValidationError, 0)
+	s.validate(value, "$", &errs)
+	return errs
+}
+
+// ValidateJSON decodes data and validates it against the schema
+func (s *Schema) ValidateJSON(data []byte) (interface{}, []ValidationError, error) {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	return value, s.Validate(value), nil
+}
+
+func (s *Schema) validate(value interface{}, path string, errs *[]ValidationError) {
+	if len(s.Types) > 0 && !s.matchesType(value) {
+		*errs = append(*errs, ValidationError{
+			Path:    path,
+			Message: fmt.Sprintf("expected %s, got %s", strings.Join(s.Types, " or "), typeOf(value)),
+		})
+		return
+	}
+
+	if s.HasConst && !reflect.DeepEqual(value, s.Const) {
+		*errs = append(*errs, ValidationError{Path: path, Message: fmt.Sprintf("must equal %v", s.Const)})
+	}
+
+	if len(s.Enum) > 0 {
+		found := false
+		for _, allowed := range s.Enum {
+			if reflect.DeepEqual(value, allowed) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			*errs = append(*errs, ValidationError{Path: path, Message: fmt.Sprintf("must be one of %v", s.Enum)})
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		s.validateObject(v, path, errs)
+	case []interface{}:
+		s.validateArray(v, path, errs)
+	case string:
+		s.validateString(v, path, errs)
+	case float64:
+		s.validateNumber(v, path, errs)
+	}
+}
+
+func (s *Schema) validateObject(obj map[string]interface{}, path string, errs *[]ValidationError) {
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			*errs = append(*errs, ValidationError{Path: path, Message: fmt.Sprintf("missing required property %q", name)})
+		}
+	}
+
+	// Iterate in sorted order so error output is deterministic
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		childPath := path + "." + key
+		if prop, ok := s.Properties[key]; ok {
+			prop.validate(obj[key], childPath, errs)
+			continue
+		}
+		if s.NoAdditional {
+			*errs = append(*errs, ValidationError{Path: childPath, Message: "additional property not allowed"})
+		} else if s.AdditionalProperties != nil {
+			s.AdditionalProperties.validate(obj[key], childPath, errs)
+		}
+	}
+}
+
+func (s *Schema) validateArray(arr []interface{}, path string, errs *[]ValidationError) {
+	if s.MinItems != nil && len(arr) < *s.MinItems {
+		*errs = append(*errs, ValidationError{Path: path, Message: fmt.Sprintf("must have at least %d items", *s.MinItems)})
+	}
+	if s.MaxItems != nil && len(arr) > *s.MaxItems {
+		*errs = append(*errs, ValidationError{Path: path, Message: fmt.Sprintf("must have at most %d items", *s.MaxItems)})
+	}
+	if s.Items != nil {
+		for i, item := range arr {
+			s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	}
+}
+
+func (s *Schema) validateString(str string, path string, errs *[]ValidationError) {
+	length := len([]rune(str))
+	if s.MinLength != nil && length < *s.MinLength {
+		*errs = append(*errs, ValidationError{Path: path, Message: fmt.Sprintf("must be at least %d characters", *s.MinLength)})
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		*errs = append(*errs, ValidationError{Path: path, Message: fmt.Sprintf("must be at most %d characters", *s.MaxLength)})
+	}
+	if s.Pattern != nil && !s.Pattern.MatchString(str) {
+		*errs = append(*errs, ValidationError{Path: path, Message: fmt.Sprintf("must match pattern %q", s.Pattern.String())})
+	}
+}
+
+func (s *Schema) validateNumber(num float64, path string, errs *[]ValidationError) {
+	if s.Minimum != nil && num < *s.Minimum {
+		*errs = append(*errs, ValidationError{Path: path, Message: fmt.Sprintf("must be >= %v", *s.Minimum)})
+	}
+	if s.Maximum != nil && num > *s.Maximum {
+		*errs = append(*errs, ValidationError{Path: path, Message: fmt.Sprintf("must be <= %v", *s.Maximum)})
+	}
+}
+
+// matchesType reports whether value matches any of the declared types
+func (s *Schema) matchesType(value interface{}) bool {
+	actual := typeOf(value)
+	for _, t := range s.Types {
+		if t == actual {
+			return true
+		}
+		// Every integer is also a number
+		if t == "number" && actual == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf returns the JSON Schema type name of a decoded JSON value
+func typeOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func isKnownType(t string) bool {
+	switch t {
+	case "null", "boolean", "string", "number", "integer", "array", "object":
+		return true
+	}
+	return false
+}
+
+// FormatErrors renders validation errors as a bulleted list suitable for
+// feeding back to an LLM
+func FormatErrors(errs []ValidationError) string {
+	lines := make([]string, 0, len(errs))
+	for _, err := range errs {
+		lines = append(lines, "- "+err.Error())
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,98 @@
+package jsonschema
+
+import (
+	"strings"
+	"testing"
+)
+
+const reviewSchema = `{
+	"type": "object",
+	"properties": {
+		"verdict": {"type": "string", "enum": ["approve", "reject"]},
+		"score": {"type": "integer", "minimum": 0, "maximum": 10},
+		"issues": {
+			"type": "array",
+			"items": {"type": "string", "minLength": 1},
+			"maxItems": 3
+		}
+	},
+	"required": ["verdict", "score"],
+	"additionalProperties": false
+}`
+
+func TestValidateValidDocument(t *testing.T) {
+	schema, err := Compile([]byte(reviewSchema))
+	if err != nil {
+		t.Fatalf("Failed to compile schema: %v", err)
+	}
+
+	value, errs, err := schema.ValidateJSON([]byte(`{"verdict": "approve", "score": 8, "issues": ["naming"]}`))
+	if err != nil {
+		t.Fatalf("Failed to parse document: %v", err)
+	}
+	if len(errs) != 0 {
+		t.Fatalf("Expected no errors, got %v", errs)
+	}
+
+	obj, ok := value.(map[string]interface{})
+	if !ok || obj["verdict"] != "approve" {
+		t.Errorf("Expected decoded object, got %#v", value)
+	}
+}
+
+func TestValidateReportsAllViolations(t *testing.T) {
+	schema, err := Compile([]byte(reviewSchema))
+	if err != nil {
+		t.Fatalf("Failed to compile schema: %v", err)
+	}
+
+	_, errs, err := schema.ValidateJSON([]byte(`{"verdict": "maybe", "score": 11.5, "issues": ["", "a", "b", "c"], "extra": true}`))
+	if err != nil {
+		t.Fatalf("Failed to parse document: %v", err)
+	}
+
+	expected := []string{
+		"$.extra: additional property not allowed",
+		"$.issues: must have at most 3 items",
+		"$.issues[0]: must be at least 1 characters",
+		"$.score: expected integer, got number",
+		"$.verdict: must be one of [approve reject]",
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d: %v", len(expected), len(errs), errs)
+	}
+	for i, want := range expected {
+		if errs[i].Error() != want {
+			t.Errorf("Error %d: expected %q, got %q", i, want, errs[i].Error())
+		}
+	}
+}
+
+func TestValidateMissingRequired(t *testing.T) {
+	schema, err := Compile([]byte(reviewSchema))
+	if err != nil {
+		t.Fatalf("Failed to compile schema: %v", err)
+	}
+
+	errs := schema.Validate(map[string]interface{}{"verdict": "reject"})
+	if len(errs) != 1 || !strings.Contains(errs[0].Message, `"score"`) {
+		t.Errorf("Expected missing score error, got %v", errs)
+	}
+}
+
+func TestCompileRejectsInvalidSchemas(t *testing.T) {
+	cases := map[string]string{
+		"not json":       `{"type":`,
+		"unknown type":   `{"type": "decimal"}`,
+		"bad pattern":    `{"type": "string", "pattern": "("}`,
+		"nested unknown": `{"type": "object", "properties": {"a": {"type": "float"}}}`,
+	}
+
+	for name, doc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Compile([]byte(doc)); err == nil {
+				t.Errorf("Expected compile error for %s", doc)
+			}
+		})
+	}
+}
@@ -0,0 +1,337 @@
+package scheduler
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/agent-learning/go-agent-api/internal/agent"
+	"github.com/google/uuid"
+)
+
+// MaxBatchSize bounds the number of tasks in one batch
+const MaxBatchSize = 1000
+
+// BatchStatus is the aggregate status of a batch
+type BatchStatus string
+
+const (
+	BatchStatusInProgress BatchStatus = "in_progress"
+	BatchStatusCancelling BatchStatus = "cancelling"
+	BatchStatusCompleted  BatchStatus = "completed"
+	BatchStatusCancelled  BatchStatus = "cancelled"
+)
+
+// BatchCounts counts the items of a batch by task status. Items whose
+// submission was rejected count as failed.
+type BatchCounts struct {
+	Total     int `json:"total"`
+	Pending   int `json:"pending"`
+	Running   int `json:"running"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+	Cancelled int `json:"cancelled"`
+}
+
+// BatchItem is one task request of a batch
+type BatchItem struct {
+	Index  int              `json:"index"`
+	TaskID string           `json:"task_id,omitempty"`
+	Status agent.TaskStatus `json:"status"`
+	Error  string           `json:"error,omitempty"`
+}
+
+// Batch groups tasks submitted together
+type Batch struct {
+	ID        string                 `json:"id"`
+	Status    BatchStatus            `json:"status"`
+	Submitter string                 `json:"submitter,omitempty"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	Counts    BatchCounts            `json:"counts"`
+	// Progress is the fraction of items that reached a terminal status
+	Progress    float64      `json:"progress"`
+	Items       []*BatchItem `json:"items,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+	CompletedAt *time.Time   `json:"completed_at,omitempty"`
+}
+
+// BatchItemResult is one line of a batch's results download
+type BatchItemResult struct {
+	Index            int              `json:"index"`
+	TaskID           string           `json:"task_id,omitempty"`
+	Status           agent.TaskStatus `json:"status"`
+	Output           string           `json:"output,omitempty"`
+	StructuredOutput interface{}      `json:"structured_output,omitempty"`
+	Error            string           `json:"error,omitempty"`
+	DurationMs       int64            `json:"duration_ms,omitempty"`
+}
+
+// CreateBatchRequest represents a request to submit many tasks at once
+type CreateBatchRequest struct {
+	Tasks    []agent.CreateTaskRequest `json:"tasks" binding:"required,min=1,dive"`
+	Metadata map[string]interface{}    `json:"metadata"`
+
+	// Submitter is filled in from the request's API key
+	Submitter string `json:"-"`
+}
+
+// batchRef locates the item of a task within its batch
+type batchRef struct {
+	batch *Batch
+	item  *BatchItem
+}
+
+// BatchManager submits batches through the scheduler and tracks their
+// progress. It must be registered as a notifier of the scheduler.
+type BatchManager struct {
+	scheduler *Scheduler
+	batches   map[string]*Batch
+	// pending maps the ID of each unfinished task to its batch item
+	pending         map[string]batchRef
+	cancelRequested map[string]bool
+	mu              sync.Mutex
+}
+
+// NewBatchManager creates a batch manager on top of a scheduler
+func NewBatchManager(scheduler *Scheduler) *BatchManager {
+	return &BatchManager{
+		scheduler:       scheduler,
+		batches:         make(map[string]*Batch),
+		pending:         make(map[string]batchRef),
+		cancelRequested: make(map[string]bool),
+	}
+}
+
+// CreateBatch submits every task of a batch. A task that fails submission
+// is recorded as a failed item rather than failing the whole batch.
+func (m *BatchManager) CreateBatch(req *CreateBatchRequest) (*Batch, error) {
+	if len(req.Tasks) == 0 {
+		return nil, fmt.Errorf("batch has no tasks")
+	}
+	if len(req.Tasks) > MaxBatchSize {
+		return nil, fmt.Errorf("batch has %d tasks, the maximum is %d", len(req.Tasks), MaxBatchSize)
+	}
+
+	now := time.Now()
+	batch := &Batch{
+		ID:        uuid.New().String(),
+		Status:    BatchStatusInProgress,
+		Submitter: req.Submitter,
+		Metadata:  req.Metadata,
+		Items:     make([]*BatchItem, 0, len(req.Tasks)),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	// Hold the lock while submitting so TaskFinished cannot run for a task
+	// before its item is tracked
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range req.Tasks {
+		taskReq := req.Tasks[i]
+		taskReq.Submitter = req.Submitter
+		taskReq.Metadata = make(map[string]interface{}, len(req.Tasks[i].Metadata)+2)
+		for k, v := range req.Tasks[i].Metadata {
+			taskReq.Metadata[k] = v
+		}
+		taskReq.Metadata["batch_id"] = batch.ID
+		taskReq.Metadata["batch_index"] = i
+
+		item := &BatchItem{Index: i}
+		task, err := m.scheduler.SubmitTask(&taskReq)
+		if err != nil {
+			item.Status = agent.TaskStatusFailed
+			item.Error = err.Error()
+		} else {
+			item.TaskID = task.ID
+			item.Status = task.Status
+			m.pending[task.ID] = batchRef{batch: batch, item: item}
+		}
+		batch.Items = append(batch.Items, item)
+	}
+
+	m.batches[batch.ID] = batch
+	m.refresh(batch, now)
+	log.Printf("Batch %s submitted with %d tasks", batch.ID, len(batch.Items))
+	return m.snapshot(batch, true), nil
+}
+
+// TaskFinished implements TaskNotifier
+func (m *BatchManager) TaskFinished(task *agent.Task, result *agent.TaskResult) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ref, exists := m.pending[task.ID]
+	if !exists {
+		return
+	}
+	delete(m.pending, task.ID)
+
+	ref.item.Status = result.Status
+	ref.item.Error = result.Error
+	m.refresh(ref.batch, time.Now())
+}
+
+// GetBatch returns a batch with its items and current progress
+func (m *BatchManager) GetBatch(id string) (*Batch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	batch, exists := m.batches[id]
+	if !exists {
+		return nil, fmt.Errorf("batch not found: %s", id)
+	}
+
+	// Pending items move to running without a notification
+	for _, item := range batch.Items {
+		if item.TaskID != "" && !IsFinished(item.Status) && m.scheduler.isRunning(item.TaskID) {
+			item.Status = agent.TaskStatusRunning
+		}
+	}
+	m.refresh(batch, batch.UpdatedAt)
+
+	return m.snapshot(batch, true), nil
+}
+
+// ListBatches returns all batches, newest first, without their items
+func (m *BatchManager) ListBatches() []*Batch {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	batches := make([]*Batch, 0, len(m.batches))
+	for _, batch := range m.batches {
+		batches = append(batches, m.snapshot(batch, false))
+	}
+	sort.Slice(batches, func(i, j int) bool {
+		return batches[i].CreatedAt.After(batches[j].CreatedAt)
+	})
+	return batches
+}
+
+// CancelBatch cancels every unfinished task of a batch
+func (m *BatchManager) CancelBatch(id string) (*Batch, error) {
+	m.mu.Lock()
+	batch, exists := m.batches[id]
+	if !exists {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("batch not found: %s", id)
+	}
+	m.cancelRequested[id] = true
+	taskIDs := make([]string, 0)
+	for _, item := range batch.Items {
+		if item.TaskID != "" && !IsFinished(item.Status) {
+			taskIDs = append(taskIDs, item.TaskID)
+		}
+	}
+	m.refresh(batch, time.Now())
+	m.mu.Unlock()
+
+	// CancelTask notifies TaskFinished, which takes the lock
+	for _, taskID := range taskIDs {
+		if err := m.scheduler.CancelTask(taskID); err != nil {
+			// The task finished in the meantime
+			log.Printf("Batch %s: %v", id, err)
+		}
+	}
+
+	log.Printf("Batch %s cancelled (%d tasks)", id, len(taskIDs))
+	return m.GetBatch(id)
+}
+
+// Results returns the outcome of every item of a batch, in submission order
+func (m *BatchManager) Results(id string) ([]*BatchItemResult, error) {
+	batch, err := m.GetBatch(id)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*BatchItemResult, 0, len(batch.Items))
+	for _, item := range batch.Items {
+		line := &BatchItemResult{
+			Index:  item.Index,
+			TaskID: item.TaskID,
+			Status: item.Status,
+			Error:  item.Error,
+		}
+		if item.TaskID != "" {
+			if result, err := m.scheduler.GetTaskResult(item.TaskID); err == nil {
+				line.Output = result.Output
+				line.StructuredOutput = result.StructuredOutput
+				line.DurationMs = result.Duration
+			}
+		}
+		results = append(results, line)
+	}
+	return results, nil
+}
+
+// refresh recomputes the counts and status of a batch.
+// The caller must hold m.mu.
+func (m *BatchManager) refresh(batch *Batch, now time.Time) {
+	counts := BatchCounts{Total: len(batch.Items)}
+	for _, item := range batch.Items {
+		switch item.Status {
+		case agent.TaskStatusScheduled, agent.TaskStatusPending:
+			counts.Pending++
+		case agent.TaskStatusRunning:
+			counts.Running++
+		case agent.TaskStatusCompleted:
+			counts.Completed++
+		case agent.TaskStatusFailed:
+			counts.Failed++
+		case agent.TaskStatusCancelled:
+			counts.Cancelled++
+		}
+	}
+	batch.Counts = counts
+
+	finished := counts.Completed + counts.Failed + counts.Cancelled
+	if counts.Total > 0 {
+		batch.Progress = float64(finished) / float64(counts.Total)
+	}
+	batch.UpdatedAt = now
+
+	cancelled := m.cancelRequested[batch.ID]
+	switch {
+	case finished < counts.Total && cancelled:
+		batch.Status = BatchStatusCancelling
+	case finished < counts.Total:
+		batch.Status = BatchStatusInProgress
+	case batch.CompletedAt == nil:
+		batch.Status = BatchStatusCompleted
+		if cancelled {
+			batch.Status = BatchStatusCancelled
+		}
+		batch.CompletedAt = &now
+		log.Printf("Batch %s finished: %d completed, %d failed, %d cancelled",
+			batch.ID, counts.Completed, counts.Failed, counts.Cancelled)
+	}
+}
+
+// snapshot copies a batch so callers never see later updates.
+// The caller must hold m.mu.
+func (m *BatchManager) snapshot(batch *Batch, withItems bool) *Batch {
+	copied := *batch
+	copied.Items = nil
+	if withItems {
+		copied.Items = make([]*BatchItem, len(batch.Items))
+		for i, item := range batch.Items {
+			itemCopy := *item
+			copied.Items[i] = &itemCopy
+		}
+	}
+	return &copied
+}
+
+// IsFinished reports whether a task status is terminal
+func IsFinished(status agent.TaskStatus) bool {
+	switch status {
+	case agent.TaskStatusCompleted, agent.TaskStatusFailed, agent.TaskStatusCancelled:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/agent-learning/go-agent-api/internal/agent"
+)
+
+func newBatchFixture(t *testing.T, client *blockingClient, maxConcurrent int) (*BatchManager, string) {
+	t.Helper()
+	service := agent.NewAgentServiceWithClient(client)
+	ag, err := service.CreateAgent(context.Background(), &agent.CreateAgentRequest{Name: "reviewer", Type: agent.AgentTypeGeneral})
+	if err != nil {
+		t.Fatalf("CreateAgent failed: %v", err)
+	}
+
+	s := NewScheduler(service, maxConcurrent, time.Minute)
+	batches := NewBatchManager(s)
+	s.AddNotifier(batches)
+	s.Start()
+	t.Cleanup(s.Stop)
+	return batches, ag.ID
+}
+
+func TestBatchCompletesWithResults(t *testing.T) {
+	client := &blockingClient{release: make(chan struct{})}
+	close(client.release)
+	batches, agentID := newBatchFixture(t, client, 2)
+
+	batch, err := batches.CreateBatch(&CreateBatchRequest{
+		Tasks: []agent.CreateTaskRequest{
+			{AgentID: agentID, Type: agent.TaskTypeCodeReview, Input: "a.go"},
+			{AgentID: "missing", Type: agent.TaskTypeCodeReview, Input: "b.go"},
+			{AgentID: agentID, Type: agent.TaskTypeCodeReview, Input: "c.go"},
+		},
+		Submitter: "ci",
+	})
+	if err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+	if batch.Counts.Total != 3 || batch.Items[1].Status != agent.TaskStatusFailed || batch.Items[1].Error == "" {
+		t.Fatalf("Expected rejected item to be recorded as failed, got %+v", batch.Items[1])
+	}
+
+	waitFor(t, func() bool {
+		b, _ := batches.GetBatch(batch.ID)
+		return b.Status == BatchStatusCompleted
+	})
+
+	done, _ := batches.GetBatch(batch.ID)
+	if done.Counts.Completed != 2 || done.Counts.Failed != 1 || done.Progress != 1 || done.CompletedAt == nil {
+		t.Errorf("Unexpected final counts: %+v progress=%v", done.Counts, done.Progress)
+	}
+
+	results, err := batches.Results(batch.ID)
+	if err != nil {
+		t.Fatalf("Results failed: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+	for i, result := range results {
+		if result.Index != i {
+			t.Errorf("Expected results in submission order, got index %d at %d", result.Index, i)
+		}
+	}
+	if results[0].Output != "ok" || results[2].Output != "ok" || results[1].Error == "" {
+		t.Errorf("Unexpected results: %+v %+v %+v", results[0], results[1], results[2])
+	}
+}
+
+func TestCancelBatch(t *testing.T) {
+	// Not started: every task stays queued until cancelled
+	s, agentID := newIdempotencyScheduler(t)
+	batches := NewBatchManager(s)
+	s.AddNotifier(batches)
+
+	req := &CreateBatchRequest{}
+	for i := 0; i < 3; i++ {
+		req.Tasks = append(req.Tasks, agent.CreateTaskRequest{AgentID: agentID, Type: agent.TaskTypeQuery, Input: "x"})
+	}
+	batch, err := batches.CreateBatch(req)
+	if err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+	if batch.Status != BatchStatusInProgress || batch.Counts.Pending != 3 {
+		t.Fatalf("Expected 3 pending items, got %s %+v", batch.Status, batch.Counts)
+	}
+
+	cancelled, err := batches.CancelBatch(batch.ID)
+	if err != nil {
+		t.Fatalf("CancelBatch failed: %v", err)
+	}
+	if cancelled.Status != BatchStatusCancelled || cancelled.Counts.Cancelled != 3 || s.taskQueue.Size() != 0 {
+		t.Errorf("Expected all items cancelled, got %s %+v", cancelled.Status, cancelled.Counts)
+	}
+
+	// Each task was tagged with its batch
+	task, _ := s.GetTask(cancelled.Items[0].TaskID)
+	if task.Metadata["batch_id"] != batch.ID {
+		t.Errorf("Expected batch_id metadata, got %v", task.Metadata)
+	}
+}
+
+func TestCreateBatchRejectsOversizedBatch(t *testing.T) {
+	batches := NewBatchManager(nil)
+	req := &CreateBatchRequest{Tasks: make([]agent.CreateTaskRequest, MaxBatchSize+1)}
+	if _, err := batches.CreateBatch(req); err == nil {
+		t.Error("Expected oversized batch to be rejected")
+	}
+}
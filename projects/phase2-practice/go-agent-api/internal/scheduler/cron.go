@@ -0,0 +1,418 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/agent-learning/go-agent-api/internal/agent"
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+)
+
+// MissedRunPolicy decides what happens to runs missed while the server was down
+type MissedRunPolicy string
+
+const (
+	// MissedRunSkip drops missed runs and waits for the next occurrence
+	MissedRunSkip MissedRunPolicy = "skip"
+	// MissedRunCatchUp runs once to cover all missed occurrences
+	MissedRunCatchUp MissedRunPolicy = "catch_up"
+)
+
+// missedRunGrace is how late a run may fire before it counts as missed
+const missedRunGrace = time.Minute
+
+// cronParser accepts standard 5-field expressions and descriptors like @hourly
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// Schedule is a recurring task definition
+type Schedule struct {
+	ID              string                  `json:"id"`
+	Name            string                  `json:"name"`
+	Cron            string                  `json:"cron"`
+	Timezone        string                  `json:"timezone"`
+	Task            agent.CreateTaskRequest `json:"task"`
+	MissedRunPolicy MissedRunPolicy         `json:"missed_run_policy"`
+	AllowOverlap    bool                    `json:"allow_overlap"`
+	Enabled         bool                    `json:"enabled"`
+	Submitter       string                  `json:"submitter,omitempty"`
+	NextRunAt       time.Time               `json:"next_run_at"`
+	LastRunAt       *time.Time              `json:"last_run_at,omitempty"`
+	LastTaskID      string                  `json:"last_task_id,omitempty"`
+	SkippedRuns     int                     `json:"skipped_runs"`
+	CreatedAt       time.Time               `json:"created_at"`
+	UpdatedAt       time.Time               `json:"updated_at"`
+}
+
+// CreateScheduleRequest represents a request to create a schedule
+type CreateScheduleRequest struct {
+	Name            string                  `json:"name" binding:"required"`
+	Cron            string                  `json:"cron" binding:"required"`
+	Timezone        string                  `json:"timezone"`
+	Task            agent.CreateTaskRequest `json:"task" binding:"required"`
+	MissedRunPolicy MissedRunPolicy         `json:"missed_run_policy"`
+	AllowOverlap    bool                    `json:"allow_overlap"`
+	Enabled         *bool                   `json:"enabled"`
+
+	// Submitter is filled in from the request's API key
+	Submitter string `json:"-"`
+}
+
+// ScheduleManager materializes tasks from recurring schedules
+type ScheduleManager struct {
+	scheduler *Scheduler
+	store     ScheduleStore
+	schedules map[string]*Schedule
+	mu        sync.RWMutex
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+// NewScheduleManager creates a schedule manager; store may be nil to keep
+// schedules in memory only
+func NewScheduleManager(scheduler *Scheduler, store ScheduleStore) *ScheduleManager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ScheduleManager{
+		scheduler: scheduler,
+		store:     store,
+		schedules: make(map[string]*Schedule),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Start loads persisted schedules and starts the trigger loop
+func (m *ScheduleManager) Start() error {
+	if m.store != nil {
+		schedules, err := m.store.LoadSchedules()
+		if err != nil {
+			return fmt.Errorf("failed to load schedules: %w", err)
+		}
+		m.mu.Lock()
+		for _, schedule := range schedules {
+			m.schedules[schedule.ID] = schedule
+		}
+		m.mu.Unlock()
+		log.Printf("Loaded %d schedules", len(schedules))
+	}
+
+	m.wg.Add(1)
+	go m.run()
+	return nil
+}
+
+// Stop stops the trigger loop
+func (m *ScheduleManager) Stop() {
+	m.cancel()
+	m.wg.Wait()
+}
+
+// run checks for due schedules every second
+func (m *ScheduleManager) run() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.tick(now)
+		}
+	}
+}
+
+// CreateSchedule validates and registers a new schedule
+func (m *ScheduleManager) CreateSchedule(req *CreateScheduleRequest) (*Schedule, error) {
+	loc, err := loadLocation(req.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := cronParser.Parse(req.Cron)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression: %w", err)
+	}
+
+	policy := req.MissedRunPolicy
+	switch policy {
+	case "":
+		policy = MissedRunSkip
+	case MissedRunSkip, MissedRunCatchUp:
+	default:
+		return nil, fmt.Errorf("invalid missed_run_policy: %s", policy)
+	}
+
+	if _, err := m.scheduler.agentService.GetAgent(m.ctx, req.Task.AgentID); err != nil {
+		return nil, fmt.Errorf("invalid agent_id: %w", err)
+	}
+	if req.Task.CallbackURL != "" {
+		if err := validateCallbackURL(req.Task.CallbackURL); err != nil {
+			return nil, fmt.Errorf("invalid callback_url: %w", err)
+		}
+	}
+
+	now := time.Now()
+	schedule := &Schedule{
+		ID:              uuid.New().String(),
+		Name:            req.Name,
+		Cron:            req.Cron,
+		Timezone:        loc.String(),
+		Task:            req.Task,
+		MissedRunPolicy: policy,
+		AllowOverlap:    req.AllowOverlap,
+		Enabled:         req.Enabled == nil || *req.Enabled,
+		Submitter:       req.Submitter,
+		NextRunAt:       parsed.Next(now.In(loc)),
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	// One-off run_at makes no sense for a recurring template
+	schedule.Task.RunAt = nil
+
+	if err := m.save(schedule); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.schedules[schedule.ID] = schedule
+	m.mu.Unlock()
+
+	log.Printf("Schedule %s created (%s, next run %s)", schedule.ID, schedule.Cron, schedule.NextRunAt.Format(time.RFC3339))
+	return schedule, nil
+}
+
+// GetSchedule returns a schedule by ID
+func (m *ScheduleManager) GetSchedule(id string) (*Schedule, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	schedule, exists := m.schedules[id]
+	if !exists {
+		return nil, fmt.Errorf("schedule not found: %s", id)
+	}
+	copied := *schedule
+	return &copied, nil
+}
+
+// ListSchedules returns all schedules ordered by creation time
+func (m *ScheduleManager) ListSchedules() []*Schedule {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	schedules := make([]*Schedule, 0, len(m.schedules))
+	for _, schedule := range m.schedules {
+		copied := *schedule
+		schedules = append(schedules, &copied)
+	}
+	sort.Slice(schedules, func(i, j int) bool {
+		return schedules[i].CreatedAt.Before(schedules[j].CreatedAt)
+	})
+	return schedules
+}
+
+// DeleteSchedule removes a schedule
+func (m *ScheduleManager) DeleteSchedule(id string) error {
+	m.mu.Lock()
+	if _, exists := m.schedules[id]; !exists {
+		m.mu.Unlock()
+		return fmt.Errorf("schedule not found: %s", id)
+	}
+	delete(m.schedules, id)
+	m.mu.Unlock()
+
+	if m.store != nil {
+		if err := m.store.DeleteSchedule(id); err != nil {
+			return fmt.Errorf("failed to delete schedule: %w", err)
+		}
+	}
+	return nil
+}
+
+// SetEnabled pauses or resumes a schedule. Resuming recomputes the next run
+// so paused periods are not treated as missed runs.
+func (m *ScheduleManager) SetEnabled(id string, enabled bool) (*Schedule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	schedule, exists := m.schedules[id]
+	if !exists {
+		return nil, fmt.Errorf("schedule not found: %s", id)
+	}
+
+	if enabled && !schedule.Enabled {
+		next, err := nextRun(schedule, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		schedule.NextRunAt = next
+	}
+	schedule.Enabled = enabled
+	schedule.UpdatedAt = time.Now()
+
+	if err := m.save(schedule); err != nil {
+		return nil, err
+	}
+	copied := *schedule
+	return &copied, nil
+}
+
+// tick fires every enabled schedule whose next run time has passed. Due runs
+// are claimed under the lock; submitting tasks and persisting schedules happen
+// after it is released so API calls are not blocked behind them.
+func (m *ScheduleManager) tick(now time.Time) {
+	for _, run := range m.claimDueRuns(now) {
+		task, skipped := m.fire(run)
+
+		m.mu.Lock()
+		schedule, exists := m.schedules[run.schedule.ID]
+		if !exists {
+			// Deleted while the run was being submitted; do not resurrect it
+			m.mu.Unlock()
+			continue
+		}
+		if task != nil {
+			runAt := now
+			schedule.LastRunAt = &runAt
+			schedule.LastTaskID = task.ID
+		}
+		if skipped {
+			schedule.SkippedRuns++
+		}
+		copied := *schedule
+		m.mu.Unlock()
+
+		if err := m.save(&copied); err != nil {
+			log.Printf("Failed to persist schedule %s: %v", copied.ID, err)
+		}
+	}
+}
+
+// dueRun is one schedule occurrence claimed by tick
+type dueRun struct {
+	schedule     Schedule
+	scheduledFor time.Time
+	missed       bool
+}
+
+// claimDueRuns advances every due schedule to its next run and returns a
+// snapshot of each claimed occurrence
+func (m *ScheduleManager) claimDueRuns(now time.Time) []dueRun {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	runs := make([]dueRun, 0)
+	for _, schedule := range m.schedules {
+		if !schedule.Enabled || schedule.NextRunAt.After(now) {
+			continue
+		}
+		run := dueRun{schedule: *schedule, scheduledFor: schedule.NextRunAt}
+		if now.Sub(run.scheduledFor) > missedRunGrace && schedule.MissedRunPolicy != MissedRunCatchUp {
+			run.missed = true
+		}
+
+		next, err := nextRun(schedule, now)
+		if err != nil {
+			log.Printf("Schedule %s disabled: %v", schedule.ID, err)
+			schedule.Enabled = false
+		} else {
+			schedule.NextRunAt = next
+		}
+		schedule.UpdatedAt = now
+
+		runs = append(runs, run)
+	}
+	return runs
+}
+
+// fire materializes one task for a claimed run, applying the missed-run and
+// overlap policies. It returns the submitted task, or whether the run was
+// skipped by a policy; a failed submission returns neither.
+func (m *ScheduleManager) fire(run dueRun) (*agent.Task, bool) {
+	schedule := &run.schedule
+
+	if run.missed {
+		log.Printf("Schedule %s: skipping missed run for %s", schedule.ID, run.scheduledFor.Format(time.RFC3339))
+		return nil, true
+	}
+
+	if !schedule.AllowOverlap && schedule.LastTaskID != "" && m.isActive(schedule.LastTaskID) {
+		log.Printf("Schedule %s: previous run %s still active, skipping", schedule.ID, schedule.LastTaskID)
+		return nil, true
+	}
+
+	req := schedule.Task
+	req.Submitter = schedule.Submitter
+	req.Metadata = make(map[string]interface{}, len(schedule.Task.Metadata)+2)
+	for k, v := range schedule.Task.Metadata {
+		req.Metadata[k] = v
+	}
+	req.Metadata["schedule_id"] = schedule.ID
+	req.Metadata["scheduled_for"] = run.scheduledFor.Format(time.RFC3339)
+
+	task, err := m.scheduler.SubmitTask(&req)
+	if err != nil {
+		log.Printf("Schedule %s: failed to submit task: %v", schedule.ID, err)
+		return nil, false
+	}
+	return task, false
+}
+
+// isActive reports whether a task is still queued or running
+func (m *ScheduleManager) isActive(taskID string) bool {
+	task, err := m.scheduler.GetTask(taskID)
+	if err != nil {
+		return false
+	}
+	switch task.Status {
+	case agent.TaskStatusScheduled, agent.TaskStatusPending, agent.TaskStatusRunning:
+		return true
+	}
+	return false
+}
+
+// save persists a schedule if a store is configured
+func (m *ScheduleManager) save(schedule *Schedule) error {
+	if m.store == nil {
+		return nil
+	}
+	if err := m.store.SaveSchedule(schedule); err != nil {
+		return fmt.Errorf("failed to persist schedule: %w", err)
+	}
+	return nil
+}
+
+// nextRun computes the first occurrence of a schedule strictly after t
+func nextRun(schedule *Schedule, t time.Time) (time.Time, error) {
+	loc, err := loadLocation(schedule.Timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	parsed, err := cronParser.Parse(schedule.Cron)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid cron expression: %w", err)
+	}
+	next := parsed.Next(t.In(loc))
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("cron expression %q never fires", schedule.Cron)
+	}
+	return next, nil
+}
+
+// loadLocation resolves a time zone name, defaulting to UTC
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone: %w", err)
+	}
+	return loc, nil
+}
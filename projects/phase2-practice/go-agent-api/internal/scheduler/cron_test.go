@@ -0,0 +1,170 @@
+package scheduler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/agent-learning/go-agent-api/internal/agent"
+)
+
+func newTestScheduler(t *testing.T) (*Scheduler, *agent.Agent) {
+	t.Helper()
+	service := agent.NewAgentServiceWithClient(nil)
+	ag, err := service.CreateAgent(context.Background(), &agent.CreateAgentRequest{Name: "cron", Type: agent.AgentTypeGeneral})
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	return NewScheduler(service, 1, time.Minute), ag
+}
+
+func TestDelayedTaskReleasedAtRunAt(t *testing.T) {
+	s, ag := newTestScheduler(t)
+
+	runAt := time.Now().Add(time.Hour)
+	task, err := s.SubmitTask(&agent.CreateTaskRequest{AgentID: ag.ID, Type: agent.TaskTypeQuery, Input: "later", RunAt: &runAt})
+	if err != nil {
+		t.Fatalf("SubmitTask failed: %v", err)
+	}
+	if task.Status != agent.TaskStatusScheduled || s.taskQueue.Size() != 0 {
+		t.Fatalf("Expected task to be held as scheduled, got status %s and queue size %d", task.Status, s.taskQueue.Size())
+	}
+
+	s.releaseDueTasks(runAt.Add(-time.Second))
+	if s.taskQueue.Size() != 0 {
+		t.Fatal("Task released before run_at")
+	}
+
+	s.releaseDueTasks(runAt)
+	if s.taskQueue.Size() != 1 || task.Status != agent.TaskStatusPending {
+		t.Fatalf("Expected task to be queued at run_at, got status %s", task.Status)
+	}
+}
+
+func TestDelayedTasksSurviveRestart(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileScheduleStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+
+	s, ag := newTestScheduler(t)
+	s.SetDelayedStore(store)
+	runAt := time.Now().Add(time.Hour)
+	task, err := s.SubmitTask(&agent.CreateTaskRequest{AgentID: ag.ID, Type: agent.TaskTypeQuery, Input: "later", RunAt: &runAt, CallbackSecret: "s3cret"})
+	if err != nil {
+		t.Fatalf("SubmitTask failed: %v", err)
+	}
+
+	// The file holds the callback secret, so only the owner may read it
+	info, err := os.Stat(filepath.Join(dir, delayedTasksFile))
+	if err != nil {
+		t.Fatalf("Delayed tasks file not written: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("Expected delayed tasks file mode 0600, got %o", perm)
+	}
+
+	reopened, err := NewFileScheduleStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	restarted, _ := newTestScheduler(t)
+	restarted.SetDelayedStore(reopened)
+	if err := restarted.RestoreDelayed(); err != nil {
+		t.Fatalf("RestoreDelayed failed: %v", err)
+	}
+
+	restored, err := restarted.GetTask(task.ID)
+	if err != nil {
+		t.Fatalf("Delayed task not restored: %v", err)
+	}
+	if restored.CallbackSecret != "s3cret" || restored.Status != agent.TaskStatusScheduled {
+		t.Errorf("Unexpected restored task: %+v", restored)
+	}
+}
+
+func TestScheduleSkipsMissedRuns(t *testing.T) {
+	s, ag := newTestScheduler(t)
+	m := NewScheduleManager(s, nil)
+
+	schedule, err := m.CreateSchedule(&CreateScheduleRequest{
+		Name: "hourly",
+		Cron: "0 * * * *",
+		Task: agent.CreateTaskRequest{AgentID: ag.ID, Type: agent.TaskTypeQuery, Input: "report"},
+	})
+	if err != nil {
+		t.Fatalf("CreateSchedule failed: %v", err)
+	}
+
+	// Fire well past the grace period: the run is skipped
+	missedBy := schedule.NextRunAt.Add(10 * time.Minute)
+	m.tick(missedBy)
+
+	got, _ := m.GetSchedule(schedule.ID)
+	if got.SkippedRuns != 1 || got.LastTaskID != "" || s.taskQueue.Size() != 0 {
+		t.Errorf("Expected missed run to be skipped, got %+v", got)
+	}
+	if !got.NextRunAt.After(missedBy) {
+		t.Errorf("Expected next run after %s, got %s", missedBy, got.NextRunAt)
+	}
+}
+
+func TestScheduleCatchUpAndOverlap(t *testing.T) {
+	s, ag := newTestScheduler(t)
+	m := NewScheduleManager(s, nil)
+
+	schedule, err := m.CreateSchedule(&CreateScheduleRequest{
+		Name:            "every minute",
+		Cron:            "* * * * *",
+		Timezone:        "Asia/Shanghai",
+		MissedRunPolicy: MissedRunCatchUp,
+		Task:            agent.CreateTaskRequest{AgentID: ag.ID, Type: agent.TaskTypeQuery, Input: "ping", Metadata: map[string]interface{}{"team": "ops"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateSchedule failed: %v", err)
+	}
+
+	// Catch-up runs once even though several occurrences were missed
+	m.tick(schedule.NextRunAt.Add(5 * time.Minute))
+	if s.taskQueue.Size() != 1 {
+		t.Fatalf("Expected one catch-up task, got %d", s.taskQueue.Size())
+	}
+	task := s.taskQueue.Peek()
+	if task.Metadata["schedule_id"] != schedule.ID || task.Metadata["team"] != "ops" {
+		t.Errorf("Unexpected task metadata: %v", task.Metadata)
+	}
+
+	// The first task is still pending, so the next occurrence is skipped
+	got, _ := m.GetSchedule(schedule.ID)
+	m.tick(got.NextRunAt)
+	if s.taskQueue.Size() != 1 {
+		t.Errorf("Expected overlapping run to be skipped, queue size %d", s.taskQueue.Size())
+	}
+	got, _ = m.GetSchedule(schedule.ID)
+	if got.SkippedRuns != 1 {
+		t.Errorf("Expected 1 skipped run, got %d", got.SkippedRuns)
+	}
+}
+
+func TestCreateScheduleValidation(t *testing.T) {
+	s, ag := newTestScheduler(t)
+	m := NewScheduleManager(s, nil)
+
+	cases := map[string]*CreateScheduleRequest{
+		"bad cron":     {Name: "x", Cron: "not cron", Task: agent.CreateTaskRequest{AgentID: ag.ID}},
+		"bad timezone": {Name: "x", Cron: "@daily", Timezone: "Mars/Olympus", Task: agent.CreateTaskRequest{AgentID: ag.ID}},
+		"bad policy":   {Name: "x", Cron: "@daily", MissedRunPolicy: "sometimes", Task: agent.CreateTaskRequest{AgentID: ag.ID}},
+		"bad agent":    {Name: "x", Cron: "@daily", Task: agent.CreateTaskRequest{AgentID: "missing"}},
+		"bad callback": {Name: "x", Cron: "@daily", Task: agent.CreateTaskRequest{AgentID: ag.ID, CallbackURL: "ftp://example.com"}},
+	}
+	for name, req := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := m.CreateSchedule(req); err == nil {
+				t.Error("Expected validation error")
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package scheduler
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/agent-learning/go-agent-api/internal/agent"
+)
+
+// SetDelayedStore configures persistence for delayed tasks.
+// It must be called before Start.
+func (s *Scheduler) SetDelayedStore(store DelayedTaskStore) {
+	s.delayedStore = store
+}
+
+// RestoreDelayed reloads persisted delayed tasks. Tasks whose run time
+// passed while the server was down are released on the next tick.
+func (s *Scheduler) RestoreDelayed() error {
+	if s.delayedStore == nil {
+		return nil
+	}
+
+	tasks, err := s.delayedStore.LoadDelayedTasks()
+	if err != nil {
+		return fmt.Errorf("failed to load delayed tasks: %w", err)
+	}
+
+	s.mu.Lock()
+	for _, task := range tasks {
+		s.delayedTasks[task.ID] = task
+	}
+	s.mu.Unlock()
+
+	log.Printf("Restored %d delayed tasks", len(tasks))
+	return nil
+}
+
+// addDelayed stores a task until its run time
+func (s *Scheduler) addDelayed(task *agent.Task) error {
+	if s.delayedStore != nil {
+		if err := s.delayedStore.SaveDelayedTask(task); err != nil {
+			return fmt.Errorf("failed to persist delayed task: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	s.delayedTasks[task.ID] = task
+	s.mu.Unlock()
+
+	// The loop may need to shorten its sleep for this task
+	s.signal()
+	return nil
+}
+
+// removeDelayed removes and returns a delayed task
+func (s *Scheduler) removeDelayed(taskID string) *agent.Task {
+	s.mu.Lock()
+	task, exists := s.delayedTasks[taskID]
+	delete(s.delayedTasks, taskID)
+	s.mu.Unlock()
+
+	if !exists {
+		return nil
+	}
+
+	if s.delayedStore != nil {
+		if err := s.delayedStore.DeleteDelayedTask(taskID); err != nil {
+			log.Printf("Failed to delete delayed task %s: %v", taskID, err)
+		}
+	}
+	return task
+}
+
+// releaseDueTasks moves delayed tasks whose run time has come into the queue
+func (s *Scheduler) releaseDueTasks(now time.Time) {
+	s.mu.RLock()
+	due := make([]string, 0)
+	for id, task := range s.delayedTasks {
+		if task.RunAt == nil || !task.RunAt.After(now) {
+			due = append(due, id)
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, id := range due {
+		task := s.removeDelayed(id)
+		if task == nil {
+			continue
+		}
+		task.Status = agent.TaskStatusPending
+		task.UpdatedAt = now
+		s.recordTask(task)
+		s.taskQueue.Enqueue(task)
+		log.Printf("Task %s released for execution", task.ID)
+	}
+}
+
+// maxIdleWait bounds how long the scheduler loop sleeps with nothing to do
+const maxIdleWait = time.Minute
+
+// untilNextDelayed returns how long to sleep until the earliest delayed task is due
+func (s *Scheduler) untilNextDelayed(now time.Time) time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	wait := maxIdleWait
+	for _, task := range s.delayedTasks {
+		if task.RunAt == nil {
+			return 0
+		}
+		if d := task.RunAt.Sub(now); d < wait {
+			wait = d
+		}
+	}
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
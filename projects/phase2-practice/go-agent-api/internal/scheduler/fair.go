@@ -0,0 +1,247 @@
+package scheduler
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/agent-learning/go-agent-api/internal/agent"
+)
+
+// DefaultAgingInterval is how long a task waits to gain one priority level
+const DefaultAgingInterval = 30 * time.Second
+
+// anonymousSubmitter groups tasks submitted without an API key
+const anonymousSubmitter = "anonymous"
+
+// FairQueue orders pending tasks with weighted fair queuing across
+// submitters and priority aging within each submitter.
+//
+// Each submitter has its own flow with a virtual time. The flow with the
+// lowest virtual time is served next and its virtual time advances by
+// 1/weight, so a submitter with weight 2 gets twice the share of one with
+// weight 1 regardless of how many tasks either enqueues. Within a flow the
+// task with the highest effective priority (priority plus one level per
+// aging interval waited) runs first, so old low-priority tasks are never
+// starved by a stream of new high-priority ones.
+type FairQueue struct {
+	flows         map[string]*flow
+	weights       map[string]float64
+	agingInterval time.Duration
+	virtualTime   float64
+	mu            sync.RWMutex
+}
+
+// flow holds the pending tasks of a single submitter
+type flow struct {
+	submitter string
+	tasks     []*agent.Task
+	vtime     float64
+}
+
+// NewFairQueue creates a new fair queue
+func NewFairQueue(agingInterval time.Duration) *FairQueue {
+	if agingInterval <= 0 {
+		agingInterval = DefaultAgingInterval
+	}
+	return &FairQueue{
+		flows:         make(map[string]*flow),
+		weights:       make(map[string]float64),
+		agingInterval: agingInterval,
+	}
+}
+
+// SetWeight sets the share of a submitter relative to others (default 1)
+func (q *FairQueue) SetWeight(submitter string, weight float64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if weight <= 0 {
+		delete(q.weights, submitter)
+		return
+	}
+	q.weights[submitter] = weight
+}
+
+// SetAgingInterval changes how fast waiting tasks gain priority
+func (q *FairQueue) SetAgingInterval(interval time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if interval > 0 {
+		q.agingInterval = interval
+	}
+}
+
+// Enqueue adds a task to its submitter's flow
+func (q *FairQueue) Enqueue(task *agent.Task) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	key := submitterKey(task)
+	f, exists := q.flows[key]
+	if !exists {
+		// A newly active flow starts at the current virtual time so it
+		// cannot claim credit for the period it was idle
+		f = &flow{submitter: key, vtime: q.virtualTime}
+		q.flows[key] = f
+	}
+	f.tasks = append(f.tasks, task)
+}
+
+// Dequeue removes and returns the next task regardless of eligibility
+func (q *FairQueue) Dequeue() *agent.Task {
+	return q.DequeueEligible(time.Now(), nil)
+}
+
+// DequeueEligible removes and returns the next task for which eligible
+// returns true. Flows are visited in virtual-time order, so a flow whose
+// tasks are all ineligible does not block the others.
+func (q *FairQueue) DequeueEligible(now time.Time, eligible func(*agent.Task) bool) *agent.Task {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	f, idx := q.selectLocked(now, eligible)
+	if f == nil {
+		return nil
+	}
+
+	task := f.tasks[idx]
+	f.tasks = append(f.tasks[:idx], f.tasks[idx+1:]...)
+
+	q.virtualTime = f.vtime
+	f.vtime += 1 / q.weightLocked(f.submitter)
+	if len(f.tasks) == 0 {
+		delete(q.flows, f.submitter)
+	}
+	return task
+}
+
+// Peek returns the task Dequeue would return without removing it
+func (q *FairQueue) Peek() *agent.Task {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	f, idx := q.selectLocked(time.Now(), nil)
+	if f == nil {
+		return nil
+	}
+	return f.tasks[idx]
+}
+
+// selectLocked finds the flow and task index to serve next
+func (q *FairQueue) selectLocked(now time.Time, eligible func(*agent.Task) bool) (*flow, int) {
+	flows := make([]*flow, 0, len(q.flows))
+	for _, f := range q.flows {
+		flows = append(flows, f)
+	}
+	sort.Slice(flows, func(i, j int) bool {
+		if flows[i].vtime == flows[j].vtime {
+			return flows[i].submitter < flows[j].submitter
+		}
+		return flows[i].vtime < flows[j].vtime
+	})
+
+	for _, f := range flows {
+		best := -1
+		var bestPriority float64
+		for i, task := range f.tasks {
+			if eligible != nil && !eligible(task) {
+				continue
+			}
+			priority := q.effectivePriorityLocked(task, now)
+			if best < 0 || priority > bestPriority ||
+				(priority == bestPriority && task.CreatedAt.Before(f.tasks[best].CreatedAt)) {
+				best = i
+				bestPriority = priority
+			}
+		}
+		if best >= 0 {
+			return f, best
+		}
+	}
+	return nil, -1
+}
+
+// EffectivePriority returns a task's priority including aging
+func (q *FairQueue) EffectivePriority(task *agent.Task, now time.Time) float64 {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.effectivePriorityLocked(task, now)
+}
+
+func (q *FairQueue) effectivePriorityLocked(task *agent.Task, now time.Time) float64 {
+	waited := now.Sub(task.CreatedAt)
+	if waited < 0 {
+		waited = 0
+	}
+	return float64(task.Priority) + float64(waited)/float64(q.agingInterval)
+}
+
+func (q *FairQueue) weightLocked(submitter string) float64 {
+	if weight, ok := q.weights[submitter]; ok {
+		return weight
+	}
+	return 1
+}
+
+// Remove removes a specific task from the queue
+func (q *FairQueue) Remove(taskID string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for key, f := range q.flows {
+		for i, task := range f.tasks {
+			if task.ID == taskID {
+				f.tasks = append(f.tasks[:i], f.tasks[i+1:]...)
+				if len(f.tasks) == 0 {
+					delete(q.flows, key)
+				}
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// List returns all queued tasks
+func (q *FairQueue) List() []*agent.Task {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	tasks := make([]*agent.Task, 0)
+	for _, f := range q.flows {
+		tasks = append(tasks, f.tasks...)
+	}
+	return tasks
+}
+
+// Size returns the number of queued tasks
+func (q *FairQueue) Size() int {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	size := 0
+	for _, f := range q.flows {
+		size += len(f.tasks)
+	}
+	return size
+}
+
+// SizeBySubmitter returns the number of queued tasks per submitter
+func (q *FairQueue) SizeBySubmitter() map[string]int {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	sizes := make(map[string]int, len(q.flows))
+	for key, f := range q.flows {
+		sizes[key] = len(f.tasks)
+	}
+	return sizes
+}
+
+// submitterKey returns the flow key for a task
+func submitterKey(task *agent.Task) string {
+	if task.Submitter == "" {
+		return anonymousSubmitter
+	}
+	return task.Submitter
+}
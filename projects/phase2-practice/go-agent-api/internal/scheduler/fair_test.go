@@ -0,0 +1,139 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agent-learning/go-agent-api/internal/agent"
+	"github.com/sashabaranov/go-openai"
+)
+
+func fairTask(id, submitter string, priority int, createdAt time.Time) *agent.Task {
+	return &agent.Task{
+		ID:        id,
+		Submitter: submitter,
+		Priority:  priority,
+		Status:    agent.TaskStatusPending,
+		CreatedAt: createdAt,
+	}
+}
+
+func TestFairQueueWeightedShares(t *testing.T) {
+	q := NewFairQueue(time.Hour)
+	q.SetWeight("big", 2)
+
+	now := time.Now()
+	for i := 0; i < 6; i++ {
+		q.Enqueue(fairTask(fmt.Sprintf("big-%d", i), "big", 10, now))
+		q.Enqueue(fairTask(fmt.Sprintf("small-%d", i), "small", 1, now))
+	}
+
+	order := make([]string, 0, 6)
+	for i := 0; i < 6; i++ {
+		order = append(order, strings.Split(q.Dequeue().ID, "-")[0])
+	}
+
+	// Weight 2 gets two slots for every one, despite "small" having lower priority
+	got := strings.Join(order, ",")
+	if got != "big,small,big,big,small,big" {
+		t.Errorf("Unexpected service order: %s", got)
+	}
+}
+
+func TestFairQueuePriorityAging(t *testing.T) {
+	q := NewFairQueue(time.Second)
+	now := time.Now()
+
+	q.Enqueue(fairTask("old-low", "", 1, now.Add(-10*time.Second)))
+	q.Enqueue(fairTask("new-high", "", 5, now))
+
+	// 1 + 10s/1s of aging beats 5
+	if task := q.DequeueEligible(now, nil); task.ID != "old-low" {
+		t.Errorf("Expected aged task first, got %s", task.ID)
+	}
+}
+
+func TestFairQueueSkipsIneligible(t *testing.T) {
+	q := NewFairQueue(time.Hour)
+	now := time.Now()
+
+	blocked := fairTask("blocked", "a", 10, now)
+	blocked.AgentID = "busy"
+	q.Enqueue(blocked)
+	q.Enqueue(fairTask("free", "a", 1, now))
+
+	task := q.DequeueEligible(now, func(task *agent.Task) bool { return task.AgentID != "busy" })
+	if task == nil || task.ID != "free" {
+		t.Fatalf("Expected eligible task, got %v", task)
+	}
+	if q.Size() != 1 || q.Peek().ID != "blocked" {
+		t.Error("Expected blocked task to remain queued")
+	}
+}
+
+// blockingClient holds every completion until release is closed
+type blockingClient struct {
+	release chan struct{}
+}
+
+func (b *blockingClient) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	select {
+	case <-b.release:
+	case <-ctx.Done():
+		return openai.ChatCompletionResponse{}, ctx.Err()
+	}
+	return openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: "ok"}}},
+	}, nil
+}
+
+func TestSchedulerPerAgentConcurrency(t *testing.T) {
+	client := &blockingClient{release: make(chan struct{})}
+	service := agent.NewAgentServiceWithClient(client)
+	ctx := context.Background()
+
+	limited, _ := service.CreateAgent(ctx, &agent.CreateAgentRequest{Name: "limited", Type: agent.AgentTypeGeneral, Config: agent.AgentConfig{MaxConcurrent: 1}})
+	other, _ := service.CreateAgent(ctx, &agent.CreateAgentRequest{Name: "other", Type: agent.AgentTypeGeneral})
+
+	s := NewScheduler(service, 3, time.Minute)
+	s.Start()
+	defer s.Stop()
+
+	for i := 0; i < 3; i++ {
+		if _, err := s.SubmitTask(&agent.CreateTaskRequest{AgentID: limited.ID, Type: agent.TaskTypeQuery, Input: "x"}); err != nil {
+			t.Fatalf("SubmitTask failed: %v", err)
+		}
+	}
+	if _, err := s.SubmitTask(&agent.CreateTaskRequest{AgentID: other.ID, Type: agent.TaskTypeQuery, Input: "y"}); err != nil {
+		t.Fatalf("SubmitTask failed: %v", err)
+	}
+
+	waitFor(t, func() bool {
+		stats := s.GetStats()
+		return stats["running_tasks"] == 2 && stats["pending_tasks"] == 2
+	})
+
+	running := s.GetStats()["running_by_agent"].(map[string]int)
+	if running[limited.ID] != 1 || running[other.ID] != 1 {
+		t.Errorf("Unexpected per-agent running counts: %v", running)
+	}
+
+	// Releasing the LLM lets every task finish without polling
+	close(client.release)
+	waitFor(t, func() bool { return s.GetStats()["completed_tasks"] == 4 })
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("Timed out waiting for condition")
+}
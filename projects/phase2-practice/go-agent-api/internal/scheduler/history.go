@@ -0,0 +1,337 @@
+package scheduler
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agent-learning/go-agent-api/internal/agent"
+)
+
+// Task listing page sizes
+const (
+	DefaultTaskPageSize = 50
+	MaxTaskPageSize     = 200
+)
+
+// SortOrder orders task listings by creation time
+type SortOrder string
+
+const (
+	SortNewestFirst SortOrder = "desc"
+	SortOldestFirst SortOrder = "asc"
+)
+
+// TaskQuery filters and pages a task listing
+type TaskQuery struct {
+	AgentID       string
+	Statuses      []agent.TaskStatus
+	Types         []agent.TaskType
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	// Metadata matches tasks whose metadata has every key set to the given string
+	Metadata map[string]string
+	// Text matches tasks whose input or output contains every word,
+	// ignoring case
+	Text  string
+	Order SortOrder
+	Limit int
+	// Cursor is the NextCursor of the previous page
+	Cursor string
+}
+
+// TaskPage is one page of a task listing
+type TaskPage struct {
+	Tasks []*agent.Task
+	// Total counts every task matching the query across all pages
+	Total int
+	// NextCursor fetches the following page; empty on the last page
+	NextCursor string
+}
+
+// TaskStore records every task the scheduler has seen, including finished ones
+type TaskStore interface {
+	SaveTask(task *agent.Task) error
+	GetTask(taskID string) (*agent.Task, error)
+	QueryTasks(query TaskQuery) (*TaskPage, error)
+	// FindByIdempotencyKey returns the newest task a submitter created with
+	// key since the given time, or nil if there is none
+	FindByIdempotencyKey(submitter, key string, since time.Time) (*agent.Task, error)
+	// ClaimIdempotencyKey saves a new task carrying an idempotency key. If the
+	// submitter already holds the key with a task created since the given
+	// time, nothing is saved and that task is returned instead.
+	ClaimIdempotencyKey(task *agent.Task, since time.Time) (*agent.Task, error)
+}
+
+// SetTaskStore replaces the in-memory task store.
+// It must be called before Start.
+func (s *Scheduler) SetTaskStore(store TaskStore) {
+	s.taskStore = store
+}
+
+// QueryTasks lists tasks of any status matching query
+func (s *Scheduler) QueryTasks(query TaskQuery) (*TaskPage, error) {
+	return s.taskStore.QueryTasks(query)
+}
+
+// TaskSnapshot returns the last recorded state of a task. Unlike GetTask it
+// never returns the live task, so it is safe to poll while the task runs.
+func (s *Scheduler) TaskSnapshot(taskID string) (*agent.Task, error) {
+	return s.taskStore.GetTask(taskID)
+}
+
+// recordTask saves the current state of a task. Failures are logged so a
+// store outage never stops execution.
+func (s *Scheduler) recordTask(task *agent.Task) {
+	if err := s.taskStore.SaveTask(task); err != nil {
+		log.Printf("Failed to record task %s: %v", task.ID, err)
+	}
+}
+
+// normalize validates a query and applies the default order and page size
+func (q *TaskQuery) normalize() error {
+	switch q.Order {
+	case "":
+		q.Order = SortNewestFirst
+	case SortNewestFirst, SortOldestFirst:
+	default:
+		return fmt.Errorf("invalid order %q: must be asc or desc", q.Order)
+	}
+
+	if q.Limit <= 0 {
+		q.Limit = DefaultTaskPageSize
+	}
+	if q.Limit > MaxTaskPageSize {
+		q.Limit = MaxTaskPageSize
+	}
+
+	if q.CreatedAfter != nil && q.CreatedBefore != nil && !q.CreatedAfter.Before(*q.CreatedBefore) {
+		return fmt.Errorf("created_after must be before created_before")
+	}
+	return nil
+}
+
+// taskCursor is the (created_at, id) position of the last task of a page
+type taskCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// encodeCursor returns the opaque cursor continuing after task
+func encodeCursor(task *agent.Task) string {
+	raw := strconv.FormatInt(task.CreatedAt.UnixNano(), 10) + ":" + task.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor parses a cursor produced by encodeCursor
+func decodeCursor(cursor string) (*taskCursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	nanos, id, found := strings.Cut(string(raw), ":")
+	if !found || id == "" {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	return &taskCursor{CreatedAt: time.Unix(0, n), ID: id}, nil
+}
+
+// before reports whether a sorts before b in the given order
+func before(a, b taskCursor, order SortOrder) bool {
+	if order == SortOldestFirst {
+		a, b = b, a
+	}
+	if a.CreatedAt.Equal(b.CreatedAt) {
+		return a.ID > b.ID
+	}
+	return a.CreatedAt.After(b.CreatedAt)
+}
+
+// MemoryTaskStore keeps task snapshots in memory
+type MemoryTaskStore struct {
+	tasks map[string]*agent.Task
+	// keys maps submitter and idempotency key to a task ID
+	keys map[string]string
+	mu   sync.RWMutex
+}
+
+// NewMemoryTaskStore creates an empty in-memory task store
+func NewMemoryTaskStore() *MemoryTaskStore {
+	return &MemoryTaskStore{
+		tasks: make(map[string]*agent.Task),
+		keys:  make(map[string]string),
+	}
+}
+
+// SaveTask implements TaskStore
+func (m *MemoryTaskStore) SaveTask(task *agent.Task) error {
+	copied := *task
+	m.mu.Lock()
+	m.tasks[task.ID] = &copied
+	if task.IdempotencyKey != "" {
+		m.keys[idempotencyIndex(task.Submitter, task.IdempotencyKey)] = task.ID
+	}
+	m.mu.Unlock()
+	return nil
+}
+
+// FindByIdempotencyKey implements TaskStore
+func (m *MemoryTaskStore) FindByIdempotencyKey(submitter, key string, since time.Time) (*agent.Task, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	task, exists := m.tasks[m.keys[idempotencyIndex(submitter, key)]]
+	if !exists || task.CreatedAt.Before(since) {
+		return nil, nil
+	}
+	copied := *task
+	return &copied, nil
+}
+
+// ClaimIdempotencyKey implements TaskStore
+func (m *MemoryTaskStore) ClaimIdempotencyKey(task *agent.Task, since time.Time) (*agent.Task, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	index := idempotencyIndex(task.Submitter, task.IdempotencyKey)
+	if holder, exists := m.tasks[m.keys[index]]; exists && !holder.CreatedAt.Before(since) {
+		copied := *holder
+		return &copied, nil
+	}
+
+	copied := *task
+	m.tasks[task.ID] = &copied
+	m.keys[index] = task.ID
+	return nil, nil
+}
+
+func idempotencyIndex(submitter, key string) string {
+	return submitter + "\x00" + key
+}
+
+// GetTask implements TaskStore
+func (m *MemoryTaskStore) GetTask(taskID string) (*agent.Task, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	task, exists := m.tasks[taskID]
+	if !exists {
+		return nil, fmt.Errorf("task not found: %s", taskID)
+	}
+	copied := *task
+	return &copied, nil
+}
+
+// QueryTasks implements TaskStore
+func (m *MemoryTaskStore) QueryTasks(query TaskQuery) (*TaskPage, error) {
+	if err := query.normalize(); err != nil {
+		return nil, err
+	}
+	cursor, err := decodeCursor(query.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	terms := strings.Fields(strings.ToLower(query.Text))
+
+	m.mu.RLock()
+	matched := make([]*agent.Task, 0)
+	total := 0
+	for _, task := range m.tasks {
+		if !matchesTask(task, &query, terms) {
+			continue
+		}
+		total++
+		if cursor != nil && !before(*cursor, taskCursor{CreatedAt: task.CreatedAt, ID: task.ID}, query.Order) {
+			continue
+		}
+		copied := *task
+		matched = append(matched, &copied)
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(matched, func(i, j int) bool {
+		a := taskCursor{CreatedAt: matched[i].CreatedAt, ID: matched[i].ID}
+		b := taskCursor{CreatedAt: matched[j].CreatedAt, ID: matched[j].ID}
+		return before(a, b, query.Order)
+	})
+
+	page := newTaskPage(matched, query.Limit)
+	page.Total = total
+	return page, nil
+}
+
+// newTaskPage cuts tasks to limit; tasks holds at least limit+1 entries
+// when another page follows
+func newTaskPage(tasks []*agent.Task, limit int) *TaskPage {
+	page := &TaskPage{Tasks: tasks}
+	if len(tasks) > limit {
+		page.Tasks = tasks[:limit]
+		page.NextCursor = encodeCursor(page.Tasks[limit-1])
+	}
+	return page
+}
+
+// matchesTask applies the filters of query to task. terms are the
+// lower-cased words of query.Text.
+func matchesTask(task *agent.Task, query *TaskQuery, terms []string) bool {
+	if query.AgentID != "" && task.AgentID != query.AgentID {
+		return false
+	}
+	if len(query.Statuses) > 0 && !containsStatus(query.Statuses, task.Status) {
+		return false
+	}
+	if len(query.Types) > 0 && !containsType(query.Types, task.Type) {
+		return false
+	}
+	if query.CreatedAfter != nil && task.CreatedAt.Before(*query.CreatedAfter) {
+		return false
+	}
+	if query.CreatedBefore != nil && !task.CreatedAt.Before(*query.CreatedBefore) {
+		return false
+	}
+	for key, want := range query.Metadata {
+		if value, ok := task.Metadata[key].(string); !ok || value != want {
+			return false
+		}
+	}
+	if len(terms) > 0 {
+		text := strings.ToLower(task.Input + " " + task.Output)
+		for _, term := range terms {
+			if !strings.Contains(text, term) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func containsStatus(statuses []agent.TaskStatus, status agent.TaskStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+func containsType(types []agent.TaskType, taskType agent.TaskType) bool {
+	for _, t := range types {
+		if t == taskType {
+			return true
+		}
+	}
+	return false
+}
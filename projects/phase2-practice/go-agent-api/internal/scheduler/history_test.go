@@ -0,0 +1,160 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/agent-learning/go-agent-api/internal/agent"
+)
+
+func seedTaskStore(t *testing.T) (*MemoryTaskStore, time.Time) {
+	t.Helper()
+	store := NewMemoryTaskStore()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 5; i++ {
+		task := &agent.Task{
+			ID:        fmt.Sprintf("task-%d", i),
+			AgentID:   "agent-a",
+			Type:      agent.TaskTypeQuery,
+			Input:     fmt.Sprintf("Summarize report %d", i),
+			Status:    agent.TaskStatusCompleted,
+			Metadata:  map[string]interface{}{"team": "search"},
+			CreatedAt: base.Add(time.Duration(i) * time.Minute),
+		}
+		if i%2 == 1 {
+			task.AgentID = "agent-b"
+			task.Status = agent.TaskStatusFailed
+			task.Output = "Quarterly Revenue grew"
+			task.Metadata = map[string]interface{}{"team": "billing"}
+		}
+		if err := store.SaveTask(task); err != nil {
+			t.Fatalf("SaveTask failed: %v", err)
+		}
+	}
+	return store, base
+}
+
+func taskIDs(page *TaskPage) []string {
+	ids := make([]string, 0, len(page.Tasks))
+	for _, task := range page.Tasks {
+		ids = append(ids, task.ID)
+	}
+	return ids
+}
+
+func TestMemoryTaskStoreFilters(t *testing.T) {
+	store, base := seedTaskStore(t)
+	after := base.Add(time.Minute)
+	beforeTime := base.Add(4 * time.Minute)
+
+	tests := []struct {
+		name  string
+		query TaskQuery
+		want  string
+	}{
+		{"newest first", TaskQuery{}, "[task-4 task-3 task-2 task-1 task-0]"},
+		{"agent", TaskQuery{AgentID: "agent-b"}, "[task-3 task-1]"},
+		{"status", TaskQuery{Statuses: []agent.TaskStatus{agent.TaskStatusCompleted}, Order: SortOldestFirst}, "[task-0 task-2 task-4]"},
+		{"type", TaskQuery{Types: []agent.TaskType{agent.TaskTypeCodeReview}}, "[]"},
+		{"time range", TaskQuery{CreatedAfter: &after, CreatedBefore: &beforeTime}, "[task-3 task-2 task-1]"},
+		{"metadata", TaskQuery{Metadata: map[string]string{"team": "billing"}}, "[task-3 task-1]"},
+		{"text over output", TaskQuery{Text: "revenue QUARTERLY"}, "[task-3 task-1]"},
+		{"text over input", TaskQuery{Text: "report 2"}, "[task-2]"},
+		{"text substring", TaskQuery{Text: "EVENU"}, "[task-3 task-1]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := store.QueryTasks(tt.query)
+			if err != nil {
+				t.Fatalf("QueryTasks failed: %v", err)
+			}
+			if got := fmt.Sprint(taskIDs(page)); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMemoryTaskStoreCursorPagination(t *testing.T) {
+	store, _ := seedTaskStore(t)
+
+	for _, order := range []SortOrder{SortNewestFirst, SortOldestFirst} {
+		query := TaskQuery{Order: order, Limit: 2}
+		seen := make([]string, 0)
+		pages := 0
+		for {
+			page, err := store.QueryTasks(query)
+			if err != nil {
+				t.Fatalf("QueryTasks failed: %v", err)
+			}
+			pages++
+			if page.Total != 5 {
+				t.Errorf("Expected total 5 on every page, got %d", page.Total)
+			}
+			seen = append(seen, taskIDs(page)...)
+			if page.NextCursor == "" {
+				break
+			}
+			query.Cursor = page.NextCursor
+		}
+
+		want := "[task-4 task-3 task-2 task-1 task-0]"
+		if order == SortOldestFirst {
+			want = "[task-0 task-1 task-2 task-3 task-4]"
+		}
+		if got := fmt.Sprint(seen); got != want || pages != 3 {
+			t.Errorf("%s: expected %s over 3 pages, got %s over %d", order, want, got, pages)
+		}
+	}
+}
+
+func TestMemoryTaskStoreRejectsBadQueries(t *testing.T) {
+	store, base := seedTaskStore(t)
+
+	if _, err := store.QueryTasks(TaskQuery{Cursor: "not-a-cursor"}); err == nil {
+		t.Error("Expected invalid cursor error")
+	}
+	if _, err := store.QueryTasks(TaskQuery{Order: "sideways"}); err == nil {
+		t.Error("Expected invalid order error")
+	}
+	if _, err := store.QueryTasks(TaskQuery{CreatedAfter: &base, CreatedBefore: &base}); err == nil {
+		t.Error("Expected invalid time range error")
+	}
+}
+
+func TestSchedulerKeepsFinishedTasks(t *testing.T) {
+	client := &blockingClient{release: make(chan struct{})}
+	close(client.release)
+	service := agent.NewAgentServiceWithClient(client)
+	ag, _ := service.CreateAgent(context.Background(), &agent.CreateAgentRequest{Name: "a", Type: agent.AgentTypeGeneral})
+
+	s := NewScheduler(service, 2, time.Minute)
+	s.Start()
+	defer s.Stop()
+
+	task, err := s.SubmitTask(&agent.CreateTaskRequest{AgentID: ag.ID, Type: agent.TaskTypeQuery, Input: "hello world"})
+	if err != nil {
+		t.Fatalf("SubmitTask failed: %v", err)
+	}
+	waitFor(t, func() bool { return s.GetStats()["completed_tasks"] == 1 })
+
+	got, err := s.GetTask(task.ID)
+	if err != nil {
+		t.Fatalf("Expected finished task to be found: %v", err)
+	}
+	if got.Status != agent.TaskStatusCompleted || got.Output != "ok" {
+		t.Errorf("Unexpected finished task: %+v", got)
+	}
+
+	page, err := s.QueryTasks(TaskQuery{Statuses: []agent.TaskStatus{agent.TaskStatusCompleted}, Text: "hello"})
+	if err != nil {
+		t.Fatalf("QueryTasks failed: %v", err)
+	}
+	if len(page.Tasks) != 1 || page.Tasks[0].ID != task.ID {
+		t.Errorf("Expected completed task in listing, got %v", taskIDs(page))
+	}
+}
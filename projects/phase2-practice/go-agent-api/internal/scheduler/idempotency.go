@@ -0,0 +1,104 @@
+package scheduler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/agent-learning/go-agent-api/internal/agent"
+)
+
+// DefaultIdempotencyTTL is how long an idempotency key maps to its task
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// MaxIdempotencyKeyLength bounds client-supplied keys
+const MaxIdempotencyKeyLength = 255
+
+// ErrIdempotencyKeyReused is returned when a key is sent again with a
+// different request
+var ErrIdempotencyKeyReused = errors.New("idempotency key was already used with a different request")
+
+// SetIdempotencyTTL sets how long a key keeps returning its original task
+func (s *Scheduler) SetIdempotencyTTL(ttl time.Duration) {
+	if ttl > 0 {
+		s.idemTTL = ttl
+	}
+}
+
+// SubmitTaskWithKey submits a task at most once per submitter and key within
+// the retention window. A repeated request returns the original task with
+// replayed set; a different request under the same key is rejected with
+// ErrIdempotencyKeyReused.
+func (s *Scheduler) SubmitTaskWithKey(req *agent.CreateTaskRequest, key string) (task *agent.Task, replayed bool, err error) {
+	if key == "" {
+		task, err = s.SubmitTask(req)
+		return task, false, err
+	}
+	if len(key) > MaxIdempotencyKeyLength {
+		return nil, false, fmt.Errorf("idempotency key exceeds %d characters", MaxIdempotencyKeyLength)
+	}
+
+	hash, err := requestHash(req)
+	if err != nil {
+		return nil, false, err
+	}
+
+	since := time.Now().Add(-s.idemTTL)
+	existing, err := s.taskStore.FindByIdempotencyKey(req.Submitter, key, since)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to look up idempotency key: %w", err)
+	}
+	if existing != nil {
+		return s.replayTask(existing, key, hash)
+	}
+
+	task, err = s.newTask(req, key, hash)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// The store claims the key atomically, so of concurrent submissions,
+	// even from other instances sharing the store, only one is admitted
+	existing, err = s.taskStore.ClaimIdempotencyKey(task, since)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+	if existing != nil {
+		return s.replayTask(existing, key, hash)
+	}
+
+	if err := s.admitTask(task, false); err != nil {
+		// The claimed task will never run; record why
+		task.Status = agent.TaskStatusFailed
+		task.Error = err.Error()
+		s.recordTask(task)
+		return nil, false, err
+	}
+	return task, false, nil
+}
+
+// replayTask returns the task that holds an idempotency key, provided it
+// was submitted with the same request
+func (s *Scheduler) replayTask(existing *agent.Task, key, hash string) (*agent.Task, bool, error) {
+	if existing.RequestHash != hash {
+		return nil, false, fmt.Errorf("%w: %s", ErrIdempotencyKeyReused, key)
+	}
+	// The live task has a more current status than the stored snapshot
+	if live, err := s.GetTask(existing.ID); err == nil {
+		existing = live
+	}
+	return existing, true, nil
+}
+
+// requestHash fingerprints the parts of a request a client controls
+func requestHash(req *agent.CreateTaskRequest) (string, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode request: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
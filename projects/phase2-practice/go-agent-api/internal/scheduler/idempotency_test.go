@@ -0,0 +1,140 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agent-learning/go-agent-api/internal/agent"
+)
+
+func newIdempotencyScheduler(t *testing.T) (*Scheduler, string) {
+	t.Helper()
+	service := agent.NewAgentServiceWithClient(&blockingClient{release: make(chan struct{})})
+	ag, err := service.CreateAgent(context.Background(), &agent.CreateAgentRequest{Name: "a", Type: agent.AgentTypeGeneral})
+	if err != nil {
+		t.Fatalf("CreateAgent failed: %v", err)
+	}
+	// Not started: submitted tasks stay queued
+	return NewScheduler(service, 1, time.Minute), ag.ID
+}
+
+func TestSubmitTaskWithKeyReplaysOriginal(t *testing.T) {
+	s, agentID := newIdempotencyScheduler(t)
+	req := agent.CreateTaskRequest{AgentID: agentID, Type: agent.TaskTypeQuery, Input: "review", Submitter: "alice"}
+
+	first, replayed, err := s.SubmitTaskWithKey(&req, "key-1")
+	if err != nil || replayed {
+		t.Fatalf("Expected new task, got replayed=%v err=%v", replayed, err)
+	}
+	if first.IdempotencyKey != "key-1" {
+		t.Errorf("Expected key on task, got %q", first.IdempotencyKey)
+	}
+
+	retry := req
+	second, replayed, err := s.SubmitTaskWithKey(&retry, "key-1")
+	if err != nil || !replayed {
+		t.Fatalf("Expected replay, got replayed=%v err=%v", replayed, err)
+	}
+	if second.ID != first.ID {
+		t.Errorf("Expected original task %s, got %s", first.ID, second.ID)
+	}
+	if size := s.taskQueue.Size(); size != 1 {
+		t.Errorf("Expected a single queued task, got %d", size)
+	}
+
+	// Keys are scoped to the submitter
+	other := req
+	other.Submitter = "bob"
+	third, replayed, err := s.SubmitTaskWithKey(&other, "key-1")
+	if err != nil || replayed || third.ID == first.ID {
+		t.Errorf("Expected a new task for another submitter, got replayed=%v err=%v", replayed, err)
+	}
+}
+
+func TestSubmitTaskWithKeyRejectsDifferentRequest(t *testing.T) {
+	s, agentID := newIdempotencyScheduler(t)
+	req := agent.CreateTaskRequest{AgentID: agentID, Type: agent.TaskTypeQuery, Input: "review"}
+	if _, _, err := s.SubmitTaskWithKey(&req, "key-1"); err != nil {
+		t.Fatalf("SubmitTaskWithKey failed: %v", err)
+	}
+
+	changed := req
+	changed.Input = "something else"
+	if _, _, err := s.SubmitTaskWithKey(&changed, "key-1"); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Errorf("Expected ErrIdempotencyKeyReused, got %v", err)
+	}
+}
+
+func TestSubmitTaskWithKeyExpires(t *testing.T) {
+	s, agentID := newIdempotencyScheduler(t)
+	s.SetIdempotencyTTL(10 * time.Millisecond)
+	req := agent.CreateTaskRequest{AgentID: agentID, Type: agent.TaskTypeQuery, Input: "review"}
+
+	first, _, err := s.SubmitTaskWithKey(&req, "key-1")
+	if err != nil {
+		t.Fatalf("SubmitTaskWithKey failed: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	second, replayed, err := s.SubmitTaskWithKey(&req, "key-1")
+	if err != nil || replayed || second.ID == first.ID {
+		t.Errorf("Expected a new task after the key expired, got replayed=%v err=%v", replayed, err)
+	}
+}
+
+func TestSubmitTaskWithKeyConcurrentRetries(t *testing.T) {
+	s, agentID := newIdempotencyScheduler(t)
+	req := agent.CreateTaskRequest{AgentID: agentID, Type: agent.TaskTypeQuery, Input: "review"}
+
+	const retries = 20
+	ids := make(chan string, retries)
+	var wg sync.WaitGroup
+	for i := 0; i < retries; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			retry := req
+			task, _, err := s.SubmitTaskWithKey(&retry, "key-1")
+			if err != nil {
+				t.Errorf("SubmitTaskWithKey failed: %v", err)
+				return
+			}
+			ids <- task.ID
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[string]bool)
+	for id := range ids {
+		seen[id] = true
+	}
+	if len(seen) != 1 || s.taskQueue.Size() != 1 {
+		t.Errorf("Expected every retry to get the same single task, got %d tasks and queue size %d", len(seen), s.taskQueue.Size())
+	}
+}
+
+func TestMemoryTaskStoreClaimIdempotencyKey(t *testing.T) {
+	store := NewMemoryTaskStore()
+	now := time.Now()
+	first := &agent.Task{ID: "t1", Submitter: "alice", IdempotencyKey: "key-1", CreatedAt: now}
+	if holder, _ := store.ClaimIdempotencyKey(first, now.Add(-time.Hour)); holder != nil {
+		t.Fatalf("Expected the first claim to win, got holder %s", holder.ID)
+	}
+
+	second := &agent.Task{ID: "t2", Submitter: "alice", IdempotencyKey: "key-1", CreatedAt: now}
+	if holder, _ := store.ClaimIdempotencyKey(second, now.Add(-time.Hour)); holder == nil || holder.ID != "t1" {
+		t.Errorf("Expected t1 to keep the key, got %v", holder)
+	}
+	if _, err := store.GetTask("t2"); err == nil {
+		t.Error("A losing claim must not be saved")
+	}
+
+	// Once the holder is older than the window, the key can be claimed again
+	if holder, _ := store.ClaimIdempotencyKey(second, now.Add(time.Second)); holder != nil {
+		t.Errorf("Expected the expired key to be claimed, got holder %s", holder.ID)
+	}
+}
@@ -0,0 +1,235 @@
+package scheduler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/agent-learning/go-agent-api/internal/agent"
+	"github.com/agent-learning/go-agent-api/internal/database"
+)
+
+// PostgresTaskStore stores tasks in the tasks table. Tasks reference their
+// agent, so agents must be persisted in the same database.
+type PostgresTaskStore struct {
+	db *database.PostgresDB
+}
+
+// NewPostgresTaskStore creates a Postgres-backed task store. The schema
+// must already be initialized with InitSchema.
+func NewPostgresTaskStore(db *database.PostgresDB) *PostgresTaskStore {
+	return &PostgresTaskStore{db: db}
+}
+
+// SaveTask implements TaskStore
+func (p *PostgresTaskStore) SaveTask(task *agent.Task) error {
+	record, err := taskRecord(task)
+	if err != nil {
+		return err
+	}
+
+	if err := p.db.SaveTask(record); err != nil {
+		return fmt.Errorf("failed to save task: %w", err)
+	}
+	return nil
+}
+
+// ClaimIdempotencyKey implements TaskStore. The unique index on
+// (submitter, idempotency_key) decides between concurrent claims.
+func (p *PostgresTaskStore) ClaimIdempotencyKey(task *agent.Task, since time.Time) (*agent.Task, error) {
+	record, err := taskRecord(task)
+	if err != nil {
+		return nil, err
+	}
+
+	inserted, err := p.db.InsertKeyedTask(record, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+	if inserted {
+		return nil, nil
+	}
+
+	holder, err := p.db.GetTaskByIdempotencyKey(task.Submitter, task.IdempotencyKey, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task by idempotency key: %w", err)
+	}
+	return taskFromRecord(holder)
+}
+
+// taskRecord converts a task to its database row
+func taskRecord(task *agent.Task) (*database.TaskRecord, error) {
+	tools, err := json.Marshal(task.Tools)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode tools: %w", err)
+	}
+	metadata, err := json.Marshal(task.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode metadata: %w", err)
+	}
+	var findings sql.NullString
+	if len(task.Guardrails) > 0 {
+		data, err := json.Marshal(task.Guardrails)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode guardrail findings: %w", err)
+		}
+		findings = sql.NullString{String: string(data), Valid: true}
+	}
+
+	record := &database.TaskRecord{
+		ID:        task.ID,
+		AgentID:   task.AgentID,
+		Type:      string(task.Type),
+		Input:     task.Input,
+		Output:    sql.NullString{String: task.Output, Valid: task.Output != ""},
+		Status:    string(task.Status),
+		Priority:  task.Priority,
+		Tools:     string(tools),
+		Metadata:  string(metadata),
+		Error:     sql.NullString{String: task.Error, Valid: task.Error != ""},
+		CreatedAt: task.CreatedAt,
+		UpdatedAt: task.UpdatedAt,
+
+		Submitter:      task.Submitter,
+		IdempotencyKey: sql.NullString{String: task.IdempotencyKey, Valid: task.IdempotencyKey != ""},
+		RequestHash:    sql.NullString{String: task.RequestHash, Valid: task.RequestHash != ""},
+
+		CallbackURL:   task.CallbackURL,
+		OutputSchema:  sql.NullString{String: string(task.OutputSchema), Valid: len(task.OutputSchema) > 0},
+		SchemaRetries: task.SchemaRetries,
+		Guardrails:    findings,
+	}
+	if task.StartedAt != nil {
+		record.StartedAt = sql.NullTime{Time: *task.StartedAt, Valid: true}
+	}
+	if task.EndedAt != nil {
+		record.EndedAt = sql.NullTime{Time: *task.EndedAt, Valid: true}
+	}
+	return record, nil
+}
+
+// GetTask implements TaskStore
+func (p *PostgresTaskStore) GetTask(taskID string) (*agent.Task, error) {
+	record, err := p.db.GetTask(taskID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("task not found: %s", taskID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+	return taskFromRecord(record)
+}
+
+// FindByIdempotencyKey implements TaskStore
+func (p *PostgresTaskStore) FindByIdempotencyKey(submitter, key string, since time.Time) (*agent.Task, error) {
+	record, err := p.db.GetTaskByIdempotencyKey(submitter, key, since)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task by idempotency key: %w", err)
+	}
+	return taskFromRecord(record)
+}
+
+// QueryTasks implements TaskStore
+func (p *PostgresTaskStore) QueryTasks(query TaskQuery) (*TaskPage, error) {
+	if err := query.normalize(); err != nil {
+		return nil, err
+	}
+	cursor, err := decodeCursor(query.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := database.TaskFilter{
+		AgentID:       query.AgentID,
+		CreatedAfter:  query.CreatedAfter,
+		CreatedBefore: query.CreatedBefore,
+		Metadata:      query.Metadata,
+		Text:          query.Text,
+		Ascending:     query.Order == SortOldestFirst,
+		// One extra row tells whether another page follows
+		Limit: query.Limit + 1,
+	}
+	for _, status := range query.Statuses {
+		filter.Statuses = append(filter.Statuses, string(status))
+	}
+	for _, taskType := range query.Types {
+		filter.Types = append(filter.Types, string(taskType))
+	}
+	if cursor != nil {
+		filter.AfterCreatedAt = &cursor.CreatedAt
+		filter.AfterID = cursor.ID
+	}
+
+	records, err := p.db.QueryTasks(filter)
+	if err != nil {
+		return nil, err
+	}
+	total, err := p.db.CountTasks(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := make([]*agent.Task, 0, len(records))
+	for _, record := range records {
+		task, err := taskFromRecord(record)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	page := newTaskPage(tasks, query.Limit)
+	page.Total = total
+	return page, nil
+}
+
+func taskFromRecord(record *database.TaskRecord) (*agent.Task, error) {
+	task := &agent.Task{
+		ID:        record.ID,
+		AgentID:   record.AgentID,
+		Type:      agent.TaskType(record.Type),
+		Input:     record.Input,
+		Output:    record.Output.String,
+		Status:    agent.TaskStatus(record.Status),
+		Priority:  record.Priority,
+		Error:     record.Error.String,
+		CreatedAt: record.CreatedAt,
+		UpdatedAt: record.UpdatedAt,
+
+		Submitter:      record.Submitter,
+		IdempotencyKey: record.IdempotencyKey.String,
+		RequestHash:    record.RequestHash.String,
+
+		CallbackURL:   record.CallbackURL,
+		SchemaRetries: record.SchemaRetries,
+	}
+	if record.OutputSchema.Valid {
+		task.OutputSchema = json.RawMessage(record.OutputSchema.String)
+	}
+	if record.StartedAt.Valid {
+		task.StartedAt = &record.StartedAt.Time
+	}
+	if record.EndedAt.Valid {
+		task.EndedAt = &record.EndedAt.Time
+	}
+	if record.Tools != "" {
+		if err := json.Unmarshal([]byte(record.Tools), &task.Tools); err != nil {
+			return nil, fmt.Errorf("task %s: failed to decode tools: %w", record.ID, err)
+		}
+	}
+	if record.Metadata != "" {
+		if err := json.Unmarshal([]byte(record.Metadata), &task.Metadata); err != nil {
+			return nil, fmt.Errorf("task %s: failed to decode metadata: %w", record.ID, err)
+		}
+	}
+	if record.Guardrails.Valid {
+		if err := json.Unmarshal([]byte(record.Guardrails.String), &task.Guardrails); err != nil {
+			return nil, fmt.Errorf("task %s: failed to decode guardrail findings: %w", record.ID, err)
+		}
+	}
+	return task, nil
+}
@@ -0,0 +1,75 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/agent-learning/go-agent-api/internal/agent"
+	"github.com/agent-learning/go-agent-api/internal/guardrails"
+)
+
+func TestTaskRecordRoundTrip(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	started := created.Add(time.Second)
+	ended := created.Add(2 * time.Second)
+	task := &agent.Task{
+		ID:             "task-1",
+		AgentID:        "agent-a",
+		Type:           agent.TaskTypeQuery,
+		Input:          "Summarize report",
+		Output:         `{"summary":"ok"}`,
+		Status:         agent.TaskStatusCompleted,
+		Priority:       3,
+		Tools:          []string{"search"},
+		Metadata:       map[string]interface{}{"team": "search"},
+		CreatedAt:      created,
+		UpdatedAt:      ended,
+		StartedAt:      &started,
+		EndedAt:        &ended,
+		Submitter:      "alice",
+		IdempotencyKey: "key-1",
+		RequestHash:    "hash",
+		CallbackURL:    "https://example.com/hook",
+		OutputSchema:   json.RawMessage(`{"type":"object","required":["summary"]}`),
+		SchemaRetries:  2,
+		Guardrails: []guardrails.Finding{{
+			Rule:      "email",
+			Stage:     guardrails.StageInput,
+			Action:    guardrails.ActionRedact,
+			Matches:   1,
+			Timestamp: created,
+		}},
+	}
+
+	record, err := taskRecord(task)
+	if err != nil {
+		t.Fatalf("taskRecord failed: %v", err)
+	}
+	got, err := taskFromRecord(record)
+	if err != nil {
+		t.Fatalf("taskFromRecord failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, task) {
+		t.Errorf("round trip changed the task:\n got  %+v\n want %+v", got, task)
+	}
+}
+
+func TestTaskRecordOmitsEmptyOptionalFields(t *testing.T) {
+	record, err := taskRecord(&agent.Task{ID: "task-1", Status: agent.TaskStatusPending})
+	if err != nil {
+		t.Fatalf("taskRecord failed: %v", err)
+	}
+	if record.OutputSchema.Valid || record.Guardrails.Valid {
+		t.Errorf("expected NULL schema and findings, got %+v / %+v", record.OutputSchema, record.Guardrails)
+	}
+
+	got, err := taskFromRecord(record)
+	if err != nil {
+		t.Fatalf("taskFromRecord failed: %v", err)
+	}
+	if got.OutputSchema != nil || got.Guardrails != nil {
+		t.Errorf("expected no schema and no findings, got %s / %v", got.OutputSchema, got.Guardrails)
+	}
+}
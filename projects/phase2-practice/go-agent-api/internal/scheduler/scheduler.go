@@ -4,21 +4,36 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net/url"
 	"sync"
 	"time"
 
 	"github.com/agent-learning/go-agent-api/internal/agent"
+	"github.com/agent-learning/go-agent-api/internal/jsonschema"
 	"github.com/google/uuid"
 )
 
+// TaskNotifier is notified when a task reaches a terminal status
+type TaskNotifier interface {
+	TaskFinished(task *agent.Task, result *agent.TaskResult)
+}
+
 // Scheduler manages task scheduling and execution
 type Scheduler struct {
 	agentService  agent.AgentService
-	taskQueue     *TaskQueue
+	taskQueue     *FairQueue
 	runningTasks  map[string]*agent.Task
+	agentRunning  map[string]int
 	taskResults   map[string]*agent.TaskResult
+	taskCancels   map[string]context.CancelFunc
+	delayedTasks  map[string]*agent.Task
+	delayedStore  DelayedTaskStore
+	taskStore     TaskStore
+	idemTTL       time.Duration
+	notifiers     []TaskNotifier
 	maxConcurrent int
 	taskTimeout   time.Duration
+	wake          chan struct{}
 	mu            sync.RWMutex
 	ctx           context.Context
 	cancel        context.CancelFunc
@@ -30,16 +45,35 @@ func NewScheduler(agentService agent.AgentService, maxConcurrent int, taskTimeou
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Scheduler{
 		agentService:  agentService,
-		taskQueue:     NewTaskQueue(),
+		taskQueue:     NewFairQueue(DefaultAgingInterval),
 		runningTasks:  make(map[string]*agent.Task),
+		agentRunning:  make(map[string]int),
 		taskResults:   make(map[string]*agent.TaskResult),
+		taskCancels:   make(map[string]context.CancelFunc),
+		delayedTasks:  make(map[string]*agent.Task),
+		taskStore:     NewMemoryTaskStore(),
+		idemTTL:       DefaultIdempotencyTTL,
 		maxConcurrent: maxConcurrent,
 		taskTimeout:   taskTimeout,
+		wake:          make(chan struct{}, 1),
 		ctx:           ctx,
 		cancel:        cancel,
 	}
 }
 
+// AddNotifier registers a notifier for finished tasks.
+// Notifiers must be added before Start.
+func (s *Scheduler) AddNotifier(notifier TaskNotifier) {
+	s.notifiers = append(s.notifiers, notifier)
+}
+
+// notify informs all notifiers that a task has finished
+func (s *Scheduler) notify(task *agent.Task, result *agent.TaskResult) {
+	for _, notifier := range s.notifiers {
+		notifier.TaskFinished(task, result)
+	}
+}
+
 // Start starts the scheduler
 func (s *Scheduler) Start() {
 	s.wg.Add(1)
@@ -54,70 +88,145 @@ func (s *Scheduler) Stop() {
 	log.Println("Scheduler stopped")
 }
 
-// run is the main scheduler loop
+// SetPriorityAging sets how long a queued task waits to gain one priority level
+func (s *Scheduler) SetPriorityAging(interval time.Duration) {
+	s.taskQueue.SetAgingInterval(interval)
+}
+
+// SetSubmitterWeight sets the fair-share weight of a submitter (default 1)
+func (s *Scheduler) SetSubmitterWeight(submitter string, weight float64) {
+	s.taskQueue.SetWeight(submitter, weight)
+}
+
+// SetMaxConcurrent changes the global concurrency limit. Raising it starts
+// queued tasks right away; lowering it lets running tasks finish.
+func (s *Scheduler) SetMaxConcurrent(maxConcurrent int) {
+	s.mu.Lock()
+	s.maxConcurrent = maxConcurrent
+	s.mu.Unlock()
+	s.signal()
+}
+
+// SetTaskTimeout changes the timeout of tasks started from now on
+func (s *Scheduler) SetTaskTimeout(timeout time.Duration) {
+	s.mu.Lock()
+	s.taskTimeout = timeout
+	s.mu.Unlock()
+}
+
+// signal wakes the scheduler loop; it never blocks
+func (s *Scheduler) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run is the main scheduler loop. It sleeps until a task is submitted, a
+// running task finishes, or the next delayed task becomes due.
 func (s *Scheduler) run() {
 	defer s.wg.Done()
 
-	ticker := time.NewTicker(100 * time.Millisecond)
-	defer ticker.Stop()
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
 
 	for {
+		s.releaseDueTasks(time.Now())
+		s.processQueue()
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(s.untilNextDelayed(time.Now()))
+
 		select {
 		case <-s.ctx.Done():
 			return
-		case <-ticker.C:
-			s.processQueue()
+		case <-s.wake:
+		case <-timer.C:
 		}
 	}
 }
 
-// processQueue processes pending tasks in the queue
+// processQueue starts queued tasks until the global limit is reached or no
+// queued task is eligible under its agent's concurrency limit
 func (s *Scheduler) processQueue() {
-	s.mu.Lock()
-	runningCount := len(s.runningTasks)
-	s.mu.Unlock()
+	for {
+		s.mu.Lock()
+		if len(s.runningTasks) >= s.maxConcurrent {
+			s.mu.Unlock()
+			return
+		}
 
-	// Check if we can start more tasks
-	if runningCount >= s.maxConcurrent {
-		return
-	}
+		task := s.taskQueue.DequeueEligible(time.Now(), s.canRunLocked)
+		if task == nil {
+			s.mu.Unlock()
+			return
+		}
 
-	// Get next task
-	task := s.taskQueue.Dequeue()
-	if task == nil {
-		return
-	}
+		// Skip tasks that changed state while queued
+		if task.Status != agent.TaskStatusPending {
+			s.mu.Unlock()
+			continue
+		}
 
-	// Check if task is still pending
-	if task.Status != agent.TaskStatusPending {
-		return
+		// Reserve the slot before starting so the next iteration sees it
+		task.Status = agent.TaskStatusRunning
+		now := time.Now()
+		task.StartedAt = &now
+		task.UpdatedAt = now
+		s.runningTasks[task.ID] = task
+		s.agentRunning[task.AgentID]++
+		s.mu.Unlock()
+		s.recordTask(task)
+
+		go s.executeTask(task)
 	}
+}
 
-	// Start task execution
-	go s.executeTask(task)
+// canRunLocked reports whether a task's agent has a free slot.
+// The caller must hold s.mu.
+func (s *Scheduler) canRunLocked(task *agent.Task) bool {
+	ag, err := s.agentService.GetAgent(s.ctx, task.AgentID)
+	if err != nil {
+		// Let execution report the missing agent
+		return true
+	}
+	limit := ag.Config.MaxConcurrent
+	return limit <= 0 || s.agentRunning[task.AgentID] < limit
 }
 
-// executeTask executes a single task
+// executeTask executes a single task that processQueue marked as running
 func (s *Scheduler) executeTask(task *agent.Task) {
-	// Mark task as running
-	task.Status = agent.TaskStatusRunning
-	now := time.Now()
-	task.StartedAt = &now
-	task.UpdatedAt = now
-
+	// Create context with timeout; CancelTask uses the cancel func
 	s.mu.Lock()
-	s.runningTasks[task.ID] = task
+	ctx, cancel := context.WithTimeout(s.ctx, s.taskTimeout)
+	s.taskCancels[task.ID] = cancel
 	s.mu.Unlock()
+	defer cancel()
 
 	defer func() {
 		s.mu.Lock()
 		delete(s.runningTasks, task.ID)
+		delete(s.taskCancels, task.ID)
+		s.agentRunning[task.AgentID]--
+		if s.agentRunning[task.AgentID] <= 0 {
+			delete(s.agentRunning, task.AgentID)
+		}
 		s.mu.Unlock()
+		s.signal()
 	}()
 
-	// Create context with timeout
-	ctx, cancel := context.WithTimeout(s.ctx, s.taskTimeout)
-	defer cancel()
+	// The task may have been cancelled before execution began
+	s.mu.RLock()
+	cancelled := task.Status == agent.TaskStatusCancelled
+	s.mu.RUnlock()
+	if cancelled {
+		return
+	}
 
 	// Get agent
 	ag, err := s.agentService.GetAgent(ctx, task.AgentID)
@@ -133,52 +242,116 @@ func (s *Scheduler) executeTask(task *agent.Task) {
 		return
 	}
 
-	// Update task
-	task.Status = agent.TaskStatusCompleted
-	task.Output = result.Output
-	endTime := time.Now()
-	task.EndedAt = &endTime
-	task.UpdatedAt = endTime
-
-	// Store result
-	s.mu.Lock()
-	s.taskResults[task.ID] = result
-	s.mu.Unlock()
+	// Discard results of tasks cancelled while executing
+	won := s.finish(task, agent.TaskStatusCompleted, result, func() {
+		task.Output = result.Output
+	})
+	if !won {
+		return
+	}
+	s.recordTask(task)
 
 	log.Printf("Task %s completed in %dms", task.ID, result.Duration)
+	s.notify(task, result)
 }
 
 // handleTaskError handles task execution errors
 func (s *Scheduler) handleTaskError(task *agent.Task, err error) {
-	task.Status = agent.TaskStatusFailed
-	task.Error = err.Error()
+	// A task cancelled while running fails with a context error; keep it cancelled
+	result := terminalResult(task, agent.TaskStatusFailed, err.Error())
+	if !s.finish(task, agent.TaskStatusFailed, result, func() { task.Error = err.Error() }) {
+		return
+	}
+	s.recordTask(task)
+
+	log.Printf("Task %s failed: %v", task.ID, err)
+	s.notify(task, result)
+}
+
+// markCancelled records a cancelled result for a task and notifies listeners.
+// It returns false if the task had already finished.
+func (s *Scheduler) markCancelled(task *agent.Task) bool {
+	result := terminalResult(task, agent.TaskStatusCancelled, "task cancelled")
+	if !s.finish(task, agent.TaskStatusCancelled, result, nil) {
+		return false
+	}
+	s.recordTask(task)
+
+	s.notify(task, result)
+	return true
+}
+
+// finish moves a task into a terminal status and stores its result, unless
+// the task already reached one. The check and the update happen under s.mu
+// so that of an execution finishing and a concurrent CancelTask only one
+// wins; only the winner may record and notify. apply runs under the lock.
+func (s *Scheduler) finish(task *agent.Task, status agent.TaskStatus, result *agent.TaskResult, apply func()) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch task.Status {
+	case agent.TaskStatusCompleted, agent.TaskStatusFailed, agent.TaskStatusCancelled:
+		return false
+	}
+
+	task.Status = status
 	endTime := time.Now()
 	task.EndedAt = &endTime
 	task.UpdatedAt = endTime
+	if apply != nil {
+		apply()
+	}
+	s.taskResults[task.ID] = result
+	return true
+}
 
-	// Store error result
-	s.mu.Lock()
-	s.taskResults[task.ID] = &agent.TaskResult{
+// terminalResult builds the result of a task that failed or was cancelled
+func terminalResult(task *agent.Task, status agent.TaskStatus, errMsg string) *agent.TaskResult {
+	endTime := time.Now()
+	return &agent.TaskResult{
 		TaskID:    task.ID,
-		Status:    agent.TaskStatusFailed,
-		Error:     err.Error(),
+		Status:    status,
+		Error:     errMsg,
 		CreatedAt: task.CreatedAt,
 		EndedAt:   endTime,
 		Duration:  endTime.Sub(task.CreatedAt).Milliseconds(),
 	}
-	s.mu.Unlock()
-
-	log.Printf("Task %s failed: %v", task.ID, err)
 }
 
 // SubmitTask submits a new task for execution
 func (s *Scheduler) SubmitTask(req *agent.CreateTaskRequest) (*agent.Task, error) {
+	task, err := s.newTask(req, "", "")
+	if err != nil {
+		return nil, err
+	}
+	if err := s.admitTask(task, true); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// newTask validates a request and builds its task, recording the idempotency
+// key and request hash it was submitted with
+func (s *Scheduler) newTask(req *agent.CreateTaskRequest, idempotencyKey, requestHash string) (*agent.Task, error) {
 	// Validate agent exists
 	_, err := s.agentService.GetAgent(s.ctx, req.AgentID)
 	if err != nil {
 		return nil, fmt.Errorf("invalid agent_id: %w", err)
 	}
 
+	// Reject malformed schemas up front rather than at execution time
+	if len(req.OutputSchema) > 0 {
+		if _, err := jsonschema.Compile(req.OutputSchema); err != nil {
+			return nil, fmt.Errorf("invalid output_schema: %w", err)
+		}
+	}
+
+	if req.CallbackURL != "" {
+		if err := validateCallbackURL(req.CallbackURL); err != nil {
+			return nil, fmt.Errorf("invalid callback_url: %w", err)
+		}
+	}
+
 	// Create task
 	task := &agent.Task{
 		ID:        uuid.New().String(),
@@ -191,23 +364,64 @@ func (s *Scheduler) SubmitTask(req *agent.CreateTaskRequest) (*agent.Task, error
 		Metadata:  req.Metadata,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
+
+		OutputSchema:  req.OutputSchema,
+		SchemaRetries: req.SchemaRetries,
+
+		CallbackURL:    req.CallbackURL,
+		CallbackSecret: req.CallbackSecret,
+		Submitter:      req.Submitter,
+		IdempotencyKey: idempotencyKey,
+		RequestHash:    requestHash,
+	}
+
+	// Delayed tasks wait until their run time
+	if req.RunAt != nil && req.RunAt.After(time.Now()) {
+		runAt := *req.RunAt
+		task.RunAt = &runAt
+		task.Status = agent.TaskStatusScheduled
+	}
+
+	return task, nil
+}
+
+// admitTask queues a new task, or holds it until its run time. record is
+// false when the task store already holds the task.
+func (s *Scheduler) admitTask(task *agent.Task, record bool) error {
+	if task.Status == agent.TaskStatusScheduled {
+		if err := s.addDelayed(task); err != nil {
+			return err
+		}
+		if record {
+			s.recordTask(task)
+		}
+		log.Printf("Task %s scheduled for %s", task.ID, task.RunAt.Format(time.RFC3339))
+		return nil
 	}
 
 	// Add to queue
+	if record {
+		s.recordTask(task)
+	}
 	s.taskQueue.Enqueue(task)
+	s.signal()
 
 	log.Printf("Task %s submitted (priority: %d)", task.ID, task.Priority)
-	return task, nil
+	return nil
 }
 
 // GetTask retrieves a task by ID
 func (s *Scheduler) GetTask(taskID string) (*agent.Task, error) {
-	// Check running tasks
+	// Check running and delayed tasks
 	s.mu.RLock()
 	if task, exists := s.runningTasks[taskID]; exists {
 		s.mu.RUnlock()
 		return task, nil
 	}
+	if task, exists := s.delayedTasks[taskID]; exists {
+		s.mu.RUnlock()
+		return task, nil
+	}
 	s.mu.RUnlock()
 
 	// Check queue
@@ -218,7 +432,17 @@ func (s *Scheduler) GetTask(taskID string) (*agent.Task, error) {
 		}
 	}
 
-	return nil, fmt.Errorf("task not found: %s", taskID)
+	// Finished tasks are only kept in the task store
+	return s.taskStore.GetTask(taskID)
+}
+
+// isRunning reports whether a task is currently executing
+func (s *Scheduler) isRunning(taskID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, running := s.runningTasks[taskID]
+	return running
 }
 
 // GetTaskResult retrieves the result of a completed task
@@ -237,19 +461,35 @@ func (s *Scheduler) GetTaskResult(taskID string) (*agent.TaskResult, error) {
 // CancelTask cancels a pending or running task
 func (s *Scheduler) CancelTask(taskID string) error {
 	// Try to remove from queue
-	if s.taskQueue.Remove(taskID) {
+	if task := s.findQueued(taskID); task != nil && s.taskQueue.Remove(taskID) {
+		s.markCancelled(task)
 		log.Printf("Task %s cancelled (was pending)", taskID)
 		return nil
 	}
 
+	// Try to remove from delayed tasks
+	if task := s.removeDelayed(taskID); task != nil {
+		s.markCancelled(task)
+		log.Printf("Task %s cancelled (was scheduled)", taskID)
+		return nil
+	}
+
 	// Check if running
 	s.mu.RLock()
-	_, isRunning := s.runningTasks[taskID]
+	task, isRunning := s.runningTasks[taskID]
+	cancel := s.taskCancels[taskID]
 	s.mu.RUnlock()
 
 	if isRunning {
-		// Task is running, mark as cancelled
-		// The actual cancellation will happen via context
+		// Record the cancellation first so the failing execution does not
+		// overwrite it, then abort the execution context. An execution that
+		// finished in the meantime wins and the task is left as it is.
+		if !s.markCancelled(task) {
+			return fmt.Errorf("task not found or already completed: %s", taskID)
+		}
+		if cancel != nil {
+			cancel()
+		}
 		log.Printf("Task %s cancelled (was running)", taskID)
 		return nil
 	}
@@ -257,6 +497,16 @@ func (s *Scheduler) CancelTask(taskID string) error {
 	return fmt.Errorf("task not found or already completed: %s", taskID)
 }
 
+// findQueued returns a pending task from the queue
+func (s *Scheduler) findQueued(taskID string) *agent.Task {
+	for _, task := range s.taskQueue.List() {
+		if task.ID == taskID {
+			return task
+		}
+	}
+	return nil
+}
+
 // ListTasks returns all tasks (pending, running, and completed)
 func (s *Scheduler) ListTasks() []*agent.Task {
 	tasks := make([]*agent.Task, 0)
@@ -264,11 +514,14 @@ func (s *Scheduler) ListTasks() []*agent.Task {
 	// Add pending tasks
 	tasks = append(tasks, s.taskQueue.List()...)
 
-	// Add running tasks
+	// Add running and delayed tasks
 	s.mu.RLock()
 	for _, task := range s.runningTasks {
 		tasks = append(tasks, task)
 	}
+	for _, task := range s.delayedTasks {
+		tasks = append(tasks, task)
+	}
 	s.mu.RUnlock()
 
 	return tasks
@@ -279,10 +532,34 @@ func (s *Scheduler) GetStats() map[string]interface{} {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	runningByAgent := make(map[string]int, len(s.agentRunning))
+	for agentID, count := range s.agentRunning {
+		runningByAgent[agentID] = count
+	}
+
 	return map[string]interface{}{
-		"pending_tasks":   s.taskQueue.Len(),
-		"running_tasks":   len(s.runningTasks),
-		"completed_tasks": len(s.taskResults),
-		"max_concurrent":  s.maxConcurrent,
+		"pending_tasks":        s.taskQueue.Size(),
+		"running_tasks":        len(s.runningTasks),
+		"scheduled_tasks":      len(s.delayedTasks),
+		"completed_tasks":      len(s.taskResults),
+		"max_concurrent":       s.maxConcurrent,
+		"running_by_agent":     runningByAgent,
+		"pending_by_submitter": s.taskQueue.SizeBySubmitter(),
+	}
+}
+
+// validateCallbackURL accepts only absolute http(s) URLs, so a bad callback is
+// reported at submit time rather than failing every webhook delivery
+func validateCallbackURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("scheme must be http or https")
+	}
+	if u.Host == "" {
+		return fmt.Errorf("host is required")
 	}
+	return nil
 }
@@ -0,0 +1,87 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agent-learning/go-agent-api/internal/agent"
+)
+
+type countingNotifier struct {
+	mu       sync.Mutex
+	finished map[string][]agent.TaskStatus
+}
+
+func (n *countingNotifier) TaskFinished(task *agent.Task, result *agent.TaskResult) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.finished[task.ID] = append(n.finished[task.ID], result.Status)
+}
+
+func (n *countingNotifier) count() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.finished)
+}
+
+func TestCancelRacingCompletionNotifiesOnce(t *testing.T) {
+	const tasks = 20
+
+	client := &blockingClient{release: make(chan struct{})}
+	service := agent.NewAgentServiceWithClient(client)
+	ag, _ := service.CreateAgent(context.Background(), &agent.CreateAgentRequest{Name: "a", Type: agent.AgentTypeGeneral})
+
+	notifier := &countingNotifier{finished: make(map[string][]agent.TaskStatus)}
+	s := NewScheduler(service, tasks, time.Minute)
+	s.AddNotifier(notifier)
+	s.Start()
+	defer s.Stop()
+
+	ids := make([]string, 0, tasks)
+	for i := 0; i < tasks; i++ {
+		task, err := s.SubmitTask(&agent.CreateTaskRequest{AgentID: ag.ID, Type: agent.TaskTypeQuery, Input: "x"})
+		if err != nil {
+			t.Fatalf("SubmitTask failed: %v", err)
+		}
+		ids = append(ids, task.ID)
+	}
+	waitFor(t, func() bool { return s.GetStats()["running_tasks"] == tasks })
+
+	// Let every execution finish while cancelling all of them
+	close(client.release)
+	for _, id := range ids {
+		go s.CancelTask(id)
+	}
+	waitFor(t, func() bool { return notifier.count() == tasks })
+	time.Sleep(20 * time.Millisecond)
+
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+	for _, id := range ids {
+		statuses := notifier.finished[id]
+		if len(statuses) != 1 {
+			t.Errorf("Task %s notified %d times: %v", id, len(statuses), statuses)
+			continue
+		}
+		result, err := s.GetTaskResult(id)
+		if err != nil || result.Status != statuses[0] {
+			t.Errorf("Task %s stored result %v does not match notification %s", id, result, statuses[0])
+		}
+	}
+}
+
+func TestSubmitTaskRejectsInvalidCallbackURL(t *testing.T) {
+	s, ag := newTestScheduler(t)
+
+	for _, callback := range []string{"ftp://example.com/hook", "/relative/hook", "http://", "://bad"} {
+		if _, err := s.SubmitTask(&agent.CreateTaskRequest{AgentID: ag.ID, Type: agent.TaskTypeQuery, Input: "x", CallbackURL: callback}); err == nil {
+			t.Errorf("Expected callback_url %q to be rejected", callback)
+		}
+	}
+
+	if _, err := s.SubmitTask(&agent.CreateTaskRequest{AgentID: ag.ID, Type: agent.TaskTypeQuery, Input: "x", CallbackURL: "https://example.com/hook"}); err != nil {
+		t.Errorf("Expected https callback_url to be accepted: %v", err)
+	}
+}
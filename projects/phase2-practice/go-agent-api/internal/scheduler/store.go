@@ -0,0 +1,164 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/agent-learning/go-agent-api/internal/agent"
+)
+
+// DelayedTaskStore persists tasks waiting for their run_at time
+type DelayedTaskStore interface {
+	SaveDelayedTask(task *agent.Task) error
+	DeleteDelayedTask(taskID string) error
+	LoadDelayedTasks() ([]*agent.Task, error)
+}
+
+// ScheduleStore persists recurring schedules
+type ScheduleStore interface {
+	SaveSchedule(schedule *Schedule) error
+	DeleteSchedule(id string) error
+	LoadSchedules() ([]*Schedule, error)
+}
+
+// delayedRecord keeps fields that agent.Task does not serialize
+type delayedRecord struct {
+	Task           *agent.Task `json:"task"`
+	CallbackSecret string      `json:"callback_secret,omitempty"`
+}
+
+// FileScheduleStore stores schedules and delayed tasks as JSON files in a directory
+type FileScheduleStore struct {
+	dir          string
+	schedules    map[string]*Schedule
+	delayedTasks map[string]*delayedRecord
+	mu           sync.Mutex
+}
+
+const (
+	schedulesFile    = "schedules.json"
+	delayedTasksFile = "delayed_tasks.json"
+)
+
+// NewFileScheduleStore creates a file-backed store in dir, loading existing data
+func NewFileScheduleStore(dir string) (*FileScheduleStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create store directory: %w", err)
+	}
+
+	store := &FileScheduleStore{
+		dir:          dir,
+		schedules:    make(map[string]*Schedule),
+		delayedTasks: make(map[string]*delayedRecord),
+	}
+
+	if err := readJSONFile(filepath.Join(dir, schedulesFile), &store.schedules); err != nil {
+		return nil, err
+	}
+	if err := readJSONFile(filepath.Join(dir, delayedTasksFile), &store.delayedTasks); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// SaveSchedule implements ScheduleStore
+func (f *FileScheduleStore) SaveSchedule(schedule *Schedule) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	copied := *schedule
+	f.schedules[schedule.ID] = &copied
+	return writeJSONFile(filepath.Join(f.dir, schedulesFile), f.schedules)
+}
+
+// DeleteSchedule implements ScheduleStore
+func (f *FileScheduleStore) DeleteSchedule(id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.schedules, id)
+	return writeJSONFile(filepath.Join(f.dir, schedulesFile), f.schedules)
+}
+
+// LoadSchedules implements ScheduleStore
+func (f *FileScheduleStore) LoadSchedules() ([]*Schedule, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	schedules := make([]*Schedule, 0, len(f.schedules))
+	for _, schedule := range f.schedules {
+		copied := *schedule
+		schedules = append(schedules, &copied)
+	}
+	return schedules, nil
+}
+
+// SaveDelayedTask implements DelayedTaskStore
+func (f *FileScheduleStore) SaveDelayedTask(task *agent.Task) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	copied := *task
+	f.delayedTasks[task.ID] = &delayedRecord{Task: &copied, CallbackSecret: task.CallbackSecret}
+	return writeJSONFile(filepath.Join(f.dir, delayedTasksFile), f.delayedTasks)
+}
+
+// DeleteDelayedTask implements DelayedTaskStore
+func (f *FileScheduleStore) DeleteDelayedTask(taskID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.delayedTasks, taskID)
+	return writeJSONFile(filepath.Join(f.dir, delayedTasksFile), f.delayedTasks)
+}
+
+// LoadDelayedTasks implements DelayedTaskStore
+func (f *FileScheduleStore) LoadDelayedTasks() ([]*agent.Task, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	tasks := make([]*agent.Task, 0, len(f.delayedTasks))
+	for _, record := range f.delayedTasks {
+		task := *record.Task
+		task.CallbackSecret = record.CallbackSecret
+		tasks = append(tasks, &task)
+	}
+	return tasks, nil
+}
+
+// readJSONFile decodes path into v; a missing file leaves v untouched
+func readJSONFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return nil
+}
+
+// writeJSONFile atomically replaces path with the JSON encoding of v
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+
+	// Tasks and schedules may carry callback secrets, so keep them private
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
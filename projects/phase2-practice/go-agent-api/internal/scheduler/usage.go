@@ -0,0 +1,102 @@
+package scheduler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/agent-learning/go-agent-api/internal/agent"
+)
+
+// UsageTotals aggregates finished tasks
+type UsageTotals struct {
+	Tasks      int   `json:"tasks"`
+	Completed  int   `json:"completed"`
+	Failed     int   `json:"failed"`
+	Cancelled  int   `json:"cancelled"`
+	TokensUsed int   `json:"tokens_used"`
+	DurationMs int64 `json:"duration_ms"`
+}
+
+// Usage reports task and token usage since the server started
+type Usage struct {
+	Since       time.Time               `json:"since"`
+	Total       UsageTotals             `json:"total"`
+	ByAgent     map[string]*UsageTotals `json:"by_agent"`
+	BySubmitter map[string]*UsageTotals `json:"by_submitter"`
+}
+
+// UsageMeter accumulates usage from finished tasks. It must be registered
+// as a notifier of the scheduler.
+type UsageMeter struct {
+	usage Usage
+	mu    sync.Mutex
+}
+
+// NewUsageMeter creates an empty usage meter
+func NewUsageMeter() *UsageMeter {
+	return &UsageMeter{
+		usage: Usage{
+			Since:       time.Now(),
+			ByAgent:     make(map[string]*UsageTotals),
+			BySubmitter: make(map[string]*UsageTotals),
+		},
+	}
+}
+
+// TaskFinished implements TaskNotifier
+func (m *UsageMeter) TaskFinished(task *agent.Task, result *agent.TaskResult) {
+	// Cached responses report 0 tokens
+	tokens, _ := result.Metadata["tokens_used"].(int)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	add := func(totals *UsageTotals) {
+		totals.Tasks++
+		switch result.Status {
+		case agent.TaskStatusCompleted:
+			totals.Completed++
+		case agent.TaskStatusFailed:
+			totals.Failed++
+		case agent.TaskStatusCancelled:
+			totals.Cancelled++
+		}
+		totals.TokensUsed += tokens
+		totals.DurationMs += result.Duration
+	}
+
+	add(&m.usage.Total)
+	add(usageEntry(m.usage.ByAgent, task.AgentID))
+	if task.Submitter != "" {
+		add(usageEntry(m.usage.BySubmitter, task.Submitter))
+	}
+}
+
+// Usage returns a copy of the usage so far
+func (m *UsageMeter) Usage() *Usage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	usage := m.usage
+	usage.ByAgent = copyUsage(m.usage.ByAgent)
+	usage.BySubmitter = copyUsage(m.usage.BySubmitter)
+	return &usage
+}
+
+func usageEntry(entries map[string]*UsageTotals, key string) *UsageTotals {
+	totals, exists := entries[key]
+	if !exists {
+		totals = &UsageTotals{}
+		entries[key] = totals
+	}
+	return totals
+}
+
+func copyUsage(entries map[string]*UsageTotals) map[string]*UsageTotals {
+	copied := make(map[string]*UsageTotals, len(entries))
+	for key, totals := range entries {
+		t := *totals
+		copied[key] = &t
+	}
+	return copied
+}
@@ -176,3 +176,64 @@ func (r *RedisStateManager) ListTasks() ([]string, error) {
 
 	return tasks, nil
 }
+
+// SetJSON stores a JSON-encoded value under key with an optional expiration
+func (r *RedisStateManager) SetJSON(key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	if err := r.client.Set(r.ctx, key, data, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to set value: %w", err)
+	}
+
+	return nil
+}
+
+// GetJSON decodes the value stored under key. It reports false if the key does not exist.
+func (r *RedisStateManager) GetJSON(key string, value interface{}) (bool, error) {
+	data, err := r.client.Get(r.ctx, key).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get value: %w", err)
+	}
+
+	if err := json.Unmarshal(data, value); err != nil {
+		return false, fmt.Errorf("failed to unmarshal value: %w", err)
+	}
+
+	return true, nil
+}
+
+// AddSetMember adds a member to a set and extends the set's expiration
+func (r *RedisStateManager) AddSetMember(key, member string, ttl time.Duration) error {
+	pipe := r.client.TxPipeline()
+	pipe.SAdd(r.ctx, key, member)
+	if ttl > 0 {
+		pipe.Expire(r.ctx, key, ttl)
+	}
+	if _, err := pipe.Exec(r.ctx); err != nil {
+		return fmt.Errorf("failed to add set member: %w", err)
+	}
+	return nil
+}
+
+// SetMembers returns all members of a set
+func (r *RedisStateManager) SetMembers(key string) ([]string, error) {
+	members, err := r.client.SMembers(r.ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get set members: %w", err)
+	}
+	return members, nil
+}
+
+// RemoveSetMember removes a member from a set
+func (r *RedisStateManager) RemoveSetMember(key, member string) error {
+	if err := r.client.SRem(r.ctx, key, member).Err(); err != nil {
+		return fmt.Errorf("failed to remove set member: %w", err)
+	}
+	return nil
+}
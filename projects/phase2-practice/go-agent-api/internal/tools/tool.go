@@ -3,6 +3,10 @@ package tools
 import (
 	"context"
 	"fmt"
+	"time"
+
+	"github.com/agent-learning/go-agent-api/internal/guardrails"
+	"github.com/agent-learning/go-agent-api/internal/transcript"
 )
 
 // Tool represents a tool that can be used by agents
@@ -17,6 +21,9 @@ type ToolResult struct {
 	Success bool   `json:"success"`
 	Output  string `json:"output"`
 	Error   string `json:"error,omitempty"`
+
+	// Guardrails lists the guardrail rules triggered by the output
+	Guardrails []guardrails.Finding `json:"guardrails,omitempty"`
 }
 
 // BaseTool provides common functionality for tools
@@ -55,7 +62,8 @@ func ExecuteWithResult(ctx context.Context, tool Tool, input string) *ToolResult
 
 // ToolExecutor executes tools safely
 type ToolExecutor struct {
-	registry *ToolRegistry
+	registry   *ToolRegistry
+	guardrails *guardrails.Pipeline
 }
 
 // NewToolExecutor creates a new tool executor
@@ -65,14 +73,77 @@ func NewToolExecutor(registry *ToolRegistry) *ToolExecutor {
 	}
 }
 
-// Execute executes a tool by name
+// SetGuardrails screens tool output for PII and prompt injection before
+// it is handed back to an agent
+func (te *ToolExecutor) SetGuardrails(p *guardrails.Pipeline) {
+	te.guardrails = p
+}
+
+// Description returns the description of a registered tool
+func (te *ToolExecutor) Description(toolName string) (string, bool) {
+	tool, err := te.registry.Get(toolName)
+	if err != nil {
+		return "", false
+	}
+	return tool.Description(), true
+}
+
+// Execute executes a tool by name. The call is added to the task transcript
+// of ctx, and a replay returns the recorded result without running the tool.
+// A replay never runs tools: a call missing from the recording fails with
+// transcript.ErrReplayExhausted.
 func (te *ToolExecutor) Execute(ctx context.Context, toolName, input string) (*ToolResult, error) {
+	if replayer := transcript.ReplayerFrom(ctx); replayer != nil {
+		call, errMsg, ok := replayer.NextTool(toolName, input)
+		if !ok {
+			return nil, fmt.Errorf("%w: tool %s", transcript.ErrReplayExhausted, toolName)
+		}
+		if call.NotRun {
+			return nil, fmt.Errorf("replayed error: %s", errMsg)
+		}
+		return &ToolResult{Success: call.Success, Output: call.Output, Error: errMsg}, nil
+	}
+
+	startedAt := time.Now()
+	result, err := te.execute(ctx, toolName, input)
+
+	call := transcript.ToolCall{Tool: toolName, Input: input}
+	errMsg := ""
+	if err != nil {
+		call.NotRun = true
+		errMsg = err.Error()
+	} else {
+		call.Output = result.Output
+		call.Success = result.Success
+		errMsg = result.Error
+	}
+	transcript.RecorderFrom(ctx).RecordTool(startedAt, call, errMsg)
+	return result, err
+}
+
+// execute runs a tool and screens its output
+func (te *ToolExecutor) execute(ctx context.Context, toolName, input string) (*ToolResult, error) {
 	tool, err := te.registry.Get(toolName)
 	if err != nil {
 		return nil, fmt.Errorf("tool not found: %s", toolName)
 	}
 
-	return ExecuteWithResult(ctx, tool, input), nil
+	result := ExecuteWithResult(ctx, tool, input)
+	if te.guardrails == nil || !result.Success {
+		return result, nil
+	}
+
+	check := te.guardrails.CheckToolOutput(toolName, result.Output)
+	result.Guardrails = check.Findings
+	if check.Blocked {
+		result.Success = false
+		result.Output = ""
+		result.Error = check.Err().Error()
+		return result, nil
+	}
+	result.Output = check.Text
+
+	return result, nil
 }
 
 // ExecuteMultiple executes multiple tools sequentially
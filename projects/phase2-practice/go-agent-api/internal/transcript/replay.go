@@ -0,0 +1,158 @@
+package transcript
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// ErrReplayExhausted is returned when a replay makes more calls than were recorded
+var ErrReplayExhausted = errors.New("no recorded response left to replay")
+
+// Divergence describes a replayed call that differs from the recording
+type Divergence struct {
+	// Seq is the sequence number of the recorded event
+	Seq    int    `json:"seq"`
+	Type   string `json:"type"`
+	Detail string `json:"detail"`
+}
+
+// Replayer serves recorded LLM and tool responses in order. It satisfies
+// the agent LLM client interface so it can stand in for the provider.
+type Replayer struct {
+	source      *Transcript
+	llmCalls    []Event
+	toolCalls   []Event
+	divergences []Divergence
+	mu          sync.Mutex
+}
+
+// NewReplayer creates a replayer for a recorded transcript
+func NewReplayer(t *Transcript) *Replayer {
+	r := &Replayer{source: t}
+	for _, event := range t.Events {
+		switch event.Type {
+		case EventLLMCall:
+			r.llmCalls = append(r.llmCalls, event)
+		case EventToolCall:
+			r.toolCalls = append(r.toolCalls, event)
+		}
+	}
+	return r
+}
+
+// Source returns the transcript being replayed
+func (r *Replayer) Source() *Transcript {
+	return r.source
+}
+
+// CreateChatCompletion returns the next recorded response, or the recorded error
+func (r *Replayer) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.llmCalls) == 0 {
+		r.diverge(0, EventLLMCall, "unexpected extra LLM call")
+		return openai.ChatCompletionResponse{}, ErrReplayExhausted
+	}
+	event := r.llmCalls[0]
+	r.llmCalls = r.llmCalls[1:]
+
+	if detail := compareRequests(event.LLM.Request, req); detail != "" {
+		r.diverge(event.Seq, EventLLMCall, detail)
+	}
+	if event.Error != "" {
+		return openai.ChatCompletionResponse{}, fmt.Errorf("replayed error: %s", event.Error)
+	}
+	if event.LLM.Response == nil {
+		return openai.ChatCompletionResponse{}, fmt.Errorf("recorded LLM call %d has no response", event.Seq)
+	}
+	return *event.LLM.Response, nil
+}
+
+// NextTool returns the next recorded call of a tool. ok is false when the
+// recording holds no further call of that tool.
+func (r *Replayer) NextTool(tool, input string) (call ToolCall, errMsg string, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, event := range r.toolCalls {
+		if event.Tool.Tool != tool {
+			continue
+		}
+		r.toolCalls = append(r.toolCalls[:i:i], r.toolCalls[i+1:]...)
+		if event.Tool.Input != input {
+			r.diverge(event.Seq, EventToolCall, fmt.Sprintf("tool %s called with different input", tool))
+		}
+		return *event.Tool, event.Error, true
+	}
+
+	r.diverge(0, EventToolCall, fmt.Sprintf("unexpected call of tool %s", tool))
+	return ToolCall{}, "", false
+}
+
+// Divergences lists the differences found so far, including recorded calls
+// the replay never made
+func (r *Replayer) Divergences() []Divergence {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	divergences := append([]Divergence(nil), r.divergences...)
+	for _, event := range append(append([]Event(nil), r.llmCalls...), r.toolCalls...) {
+		divergences = append(divergences, Divergence{Seq: event.Seq, Type: string(event.Type), Detail: "recorded call was not replayed"})
+	}
+	return divergences
+}
+
+// diverge records a divergence. The caller must hold r.mu.
+func (r *Replayer) diverge(seq int, eventType EventType, detail string) {
+	r.divergences = append(r.divergences, Divergence{Seq: seq, Type: string(eventType), Detail: detail})
+}
+
+// compareRequests describes how a replayed request differs from the
+// recorded one, or returns "" when they match
+func compareRequests(recorded, replayed openai.ChatCompletionRequest) string {
+	switch {
+	case recorded.Model != replayed.Model:
+		return fmt.Sprintf("model changed from %s to %s", recorded.Model, replayed.Model)
+	case recorded.Temperature != replayed.Temperature:
+		return fmt.Sprintf("temperature changed from %v to %v", recorded.Temperature, replayed.Temperature)
+	case len(recorded.Messages) != len(replayed.Messages):
+		return fmt.Sprintf("message count changed from %d to %d", len(recorded.Messages), len(replayed.Messages))
+	}
+	for i := range recorded.Messages {
+		if !sameMessage(recorded.Messages[i], replayed.Messages[i]) {
+			return fmt.Sprintf("message %d (%s) changed", i, recorded.Messages[i].Role)
+		}
+	}
+	return ""
+}
+
+// sameMessage compares messages through their JSON form, which is what a
+// recording preserves
+func sameMessage(a, b openai.ChatCompletionMessage) bool {
+	aj, errA := json.Marshal(a)
+	bj, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	return string(aj) == string(bj)
+}
+
+type replayerKey struct{}
+
+// WithReplayer returns a context whose LLM and tool calls are served by r
+func WithReplayer(ctx context.Context, r *Replayer) context.Context {
+	return context.WithValue(ctx, replayerKey{}, r)
+}
+
+// ReplayerFrom returns the replayer of ctx, or nil
+func ReplayerFrom(ctx context.Context) *Replayer {
+	r, _ := ctx.Value(replayerKey{}).(*Replayer)
+	return r
+}
@@ -0,0 +1,133 @@
+package transcript
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+)
+
+// Store keeps transcripts by task ID
+type Store interface {
+	Save(t *Transcript) error
+	Get(taskID string) (*Transcript, error)
+}
+
+// MemoryStore keeps the most recent transcripts in memory
+type MemoryStore struct {
+	maxEntries  int
+	transcripts map[string]*Transcript
+	// order holds task IDs oldest first for eviction
+	order []string
+	mu    sync.RWMutex
+}
+
+// NewMemoryStore creates a store holding up to maxEntries transcripts
+// (unbounded when maxEntries <= 0)
+func NewMemoryStore(maxEntries int) *MemoryStore {
+	return &MemoryStore{
+		maxEntries:  maxEntries,
+		transcripts: make(map[string]*Transcript),
+	}
+}
+
+// Save implements Store
+func (m *MemoryStore) Save(t *Transcript) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.transcripts[t.TaskID]; !exists {
+		m.order = append(m.order, t.TaskID)
+	}
+	m.transcripts[t.TaskID] = t
+
+	for m.maxEntries > 0 && len(m.order) > m.maxEntries {
+		delete(m.transcripts, m.order[0])
+		m.order = m.order[1:]
+	}
+	return nil
+}
+
+// Get implements Store
+func (m *MemoryStore) Get(taskID string) (*Transcript, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	t, exists := m.transcripts[taskID]
+	if !exists {
+		return nil, fmt.Errorf("transcript not found: %s", taskID)
+	}
+	return t, nil
+}
+
+// safeID restricts task IDs used as file names
+var safeID = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// FileStore writes one JSON file per transcript into a directory
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a file-backed store in dir
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create transcript directory: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Save implements Store
+func (f *FileStore) Save(t *Transcript) error {
+	path, err := f.path(t.TaskID)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode transcript: %w", err)
+	}
+
+	// Transcripts hold task input and model output, so only the server may read them
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write transcript: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to replace transcript: %w", err)
+	}
+	return nil
+}
+
+// Get implements Store
+func (f *FileStore) Get(taskID string) (*Transcript, error) {
+	path, err := f.path(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, fmt.Errorf("transcript not found: %s", taskID)
+	}
+	return LoadFile(path)
+}
+
+func (f *FileStore) path(taskID string) (string, error) {
+	if !safeID.MatchString(taskID) {
+		return "", fmt.Errorf("invalid task id %q", taskID)
+	}
+	return filepath.Join(f.dir, taskID+".json"), nil
+}
+
+// LoadFile reads an exported transcript, e.g. a regression test fixture
+func LoadFile(path string) (*Transcript, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+	var t Transcript
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("failed to decode transcript: %w", err)
+	}
+	return &t, nil
+}
@@ -0,0 +1,173 @@
+package transcript
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// EventType identifies what a transcript event recorded
+type EventType string
+
+const (
+	EventLLMCall  EventType = "llm_call"
+	EventToolCall EventType = "tool_call"
+)
+
+// LLMCall is a rendered chat completion request and its response
+type LLMCall struct {
+	Request  openai.ChatCompletionRequest   `json:"request"`
+	Response *openai.ChatCompletionResponse `json:"response,omitempty"`
+	// Cached is set when the response came from the response cache
+	Cached bool `json:"cached,omitempty"`
+}
+
+// ToolCall is one tool invocation and its output after guardrails
+type ToolCall struct {
+	Tool    string `json:"tool"`
+	Input   string `json:"input"`
+	Output  string `json:"output,omitempty"`
+	Success bool   `json:"success"`
+	// NotRun is set when the tool could not be run at all, e.g. it is not
+	// registered. The event error holds the reason.
+	NotRun bool `json:"not_run,omitempty"`
+}
+
+// Event is one step of a task execution
+type Event struct {
+	Seq        int       `json:"seq"`
+	Type       EventType `json:"type"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+	LLM        *LLMCall  `json:"llm,omitempty"`
+	Tool       *ToolCall `json:"tool,omitempty"`
+}
+
+// Transcript is the full record of one task execution
+type Transcript struct {
+	TaskID string `json:"task_id"`
+	// Task and Agent snapshot exactly what was executed so it can be re-run
+	Task   json.RawMessage `json:"task"`
+	Agent  json.RawMessage `json:"agent"`
+	Events []Event         `json:"events"`
+
+	Status     string    `json:"status"`
+	Output     string    `json:"output,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	EndedAt    time.Time `json:"ended_at"`
+	DurationMs int64     `json:"duration_ms"`
+
+	// ReplayOf is the task ID of the transcript a replay ran against
+	ReplayOf string `json:"replay_of,omitempty"`
+}
+
+// Recorder collects the events of a running task. It is safe for
+// concurrent use, and a nil Recorder records nothing.
+type Recorder struct {
+	transcript *Transcript
+	mu         sync.Mutex
+}
+
+// NewRecorder starts a transcript for a task, snapshotting the task and agent
+func NewRecorder(taskID string, task, agent interface{}) (*Recorder, error) {
+	taskJSON, err := json.Marshal(task)
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot task: %w", err)
+	}
+	agentJSON, err := json.Marshal(agent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot agent: %w", err)
+	}
+
+	return &Recorder{
+		transcript: &Transcript{
+			TaskID:    taskID,
+			Task:      taskJSON,
+			Agent:     agentJSON,
+			Events:    make([]Event, 0),
+			StartedAt: time.Now(),
+		},
+	}, nil
+}
+
+// RecordLLM records a chat completion. resp is ignored when err is set.
+func (r *Recorder) RecordLLM(startedAt time.Time, req openai.ChatCompletionRequest, resp openai.ChatCompletionResponse, cached bool, err error) {
+	if r == nil {
+		return
+	}
+	call := &LLMCall{Request: req, Cached: cached}
+	if err == nil {
+		call.Response = &resp
+	}
+	r.add(Event{Type: EventLLMCall, StartedAt: startedAt, LLM: call}, err)
+}
+
+// RecordTool records a tool invocation
+func (r *Recorder) RecordTool(startedAt time.Time, call ToolCall, errMsg string) {
+	if r == nil {
+		return
+	}
+	var err error
+	if errMsg != "" {
+		err = fmt.Errorf("%s", errMsg)
+	}
+	r.add(Event{Type: EventToolCall, StartedAt: startedAt, Tool: &call}, err)
+}
+
+func (r *Recorder) add(event Event, err error) {
+	event.DurationMs = time.Since(event.StartedAt).Milliseconds()
+	if err != nil {
+		event.Error = err.Error()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	event.Seq = len(r.transcript.Events) + 1
+	r.transcript.Events = append(r.transcript.Events, event)
+}
+
+// SetReplayOf marks the transcript as a replay of another task
+func (r *Recorder) SetReplayOf(taskID string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.transcript.ReplayOf = taskID
+}
+
+// Finish completes the transcript with the task outcome and returns it
+func (r *Recorder) Finish(status, output, errMsg string) *Transcript {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t := r.transcript
+	t.Status = status
+	t.Output = output
+	t.Error = errMsg
+	t.EndedAt = time.Now()
+	t.DurationMs = t.EndedAt.Sub(t.StartedAt).Milliseconds()
+
+	copied := *t
+	copied.Events = append([]Event(nil), t.Events...)
+	return &copied
+}
+
+type recorderKey struct{}
+
+// WithRecorder returns a context that records into r
+func WithRecorder(ctx context.Context, r *Recorder) context.Context {
+	return context.WithValue(ctx, recorderKey{}, r)
+}
+
+// RecorderFrom returns the recorder of ctx, or nil
+func RecorderFrom(ctx context.Context) *Recorder {
+	r, _ := ctx.Value(recorderKey{}).(*Recorder)
+	return r
+}
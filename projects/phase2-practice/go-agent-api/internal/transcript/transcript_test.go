@@ -0,0 +1,109 @@
+package transcript
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func newTestTranscript(t *testing.T, taskID string) *Transcript {
+	t.Helper()
+	r, err := NewRecorder(taskID, map[string]string{"input": "hi"}, map[string]string{"name": "qa"})
+	if err != nil {
+		t.Fatalf("NewRecorder failed: %v", err)
+	}
+
+	req := openai.ChatCompletionRequest{
+		Model:    "gpt-4",
+		Messages: []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "hi"}},
+	}
+	resp := openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: "hello"}}},
+	}
+	r.RecordLLM(time.Now(), req, resp, false, nil)
+	r.RecordTool(time.Now(), ToolCall{Tool: "search", Input: "go", Output: "results", Success: true}, "")
+	return r.Finish("completed", "hello", "")
+}
+
+func TestReplayerServesRecordedCalls(t *testing.T) {
+	recorded := newTestTranscript(t, "t1")
+	replayer := NewReplayer(recorded)
+
+	resp, err := replayer.CreateChatCompletion(context.Background(), recorded.Events[0].LLM.Request)
+	if err != nil || resp.Choices[0].Message.Content != "hello" {
+		t.Fatalf("Expected the recorded response, got %+v %v", resp, err)
+	}
+	call, _, ok := replayer.NextTool("search", "go")
+	if !ok || call.Output != "results" {
+		t.Fatalf("Expected the recorded tool output, got %+v", call)
+	}
+	if divergences := replayer.Divergences(); len(divergences) != 0 {
+		t.Errorf("Expected no divergences, got %+v", divergences)
+	}
+
+	if _, err := replayer.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{}); !errors.Is(err, ErrReplayExhausted) {
+		t.Errorf("Expected ErrReplayExhausted, got %v", err)
+	}
+}
+
+func TestReplayerReportsMissedCalls(t *testing.T) {
+	replayer := NewReplayer(newTestTranscript(t, "t1"))
+
+	// The replay never calls the LLM and passes different tool input
+	replayer.NextTool("search", "rust")
+
+	divergences := replayer.Divergences()
+	if len(divergences) != 2 {
+		t.Fatalf("Expected 2 divergences, got %+v", divergences)
+	}
+	if divergences[0].Type != string(EventToolCall) || divergences[1].Detail != "recorded call was not replayed" {
+		t.Errorf("Unexpected divergences: %+v", divergences)
+	}
+}
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	if err := store.Save(newTestTranscript(t, "t1")); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := store.Get("t1")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if len(loaded.Events) != 2 || loaded.Events[1].Tool.Output != "results" || loaded.Output != "hello" {
+		t.Errorf("Unexpected transcript after round trip: %+v", loaded)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "t1.json"))
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected the transcript file to be private: %v %v", info, err)
+	}
+
+	if _, err := store.Get("../t1"); err == nil {
+		t.Error("Expected path traversal to be rejected")
+	}
+}
+
+func TestMemoryStoreEvictsOldest(t *testing.T) {
+	store := NewMemoryStore(2)
+	for _, id := range []string{"t1", "t2", "t3"} {
+		store.Save(newTestTranscript(t, id))
+	}
+
+	if _, err := store.Get("t1"); err == nil {
+		t.Error("Expected the oldest transcript to be evicted")
+	}
+	if _, err := store.Get("t3"); err != nil {
+		t.Errorf("Expected the newest transcript to be kept: %v", err)
+	}
+}
@@ -0,0 +1,320 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/agent-learning/go-agent-api/internal/agent"
+	"github.com/google/uuid"
+)
+
+// Event identifies why a webhook was sent
+type Event string
+
+const (
+	EventTaskCompleted Event = "task.completed"
+	EventTaskFailed    Event = "task.failed"
+	EventTaskCancelled Event = "task.cancelled"
+)
+
+// Headers set on every webhook request
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Config holds webhook delivery settings
+type Config struct {
+	Secret         string
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Timeout        time.Duration
+}
+
+// Payload is the JSON body POSTed to a callback URL
+type Payload struct {
+	Event     Event             `json:"event"`
+	TaskID    string            `json:"task_id"`
+	AgentID   string            `json:"agent_id"`
+	Status    agent.TaskStatus  `json:"status"`
+	Result    *agent.TaskResult `json:"result,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// Delivery records a single attempt to deliver a webhook
+type Delivery struct {
+	ID         string    `json:"id"`
+	TaskID     string    `json:"task_id"`
+	Event      Event     `json:"event"`
+	URL        string    `json:"url"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// target is a resolved callback destination
+type target struct {
+	url    string
+	secret string
+}
+
+// Dispatcher delivers signed task webhooks with retries and keeps a
+// per-task delivery log
+type Dispatcher struct {
+	config            Config
+	agentService      agent.AgentService
+	client            *http.Client
+	submitterDefaults map[string]string
+	deliveries        map[string][]*Delivery
+	mu                sync.RWMutex
+	ctx               context.Context
+	cancel            context.CancelFunc
+	wg                sync.WaitGroup
+}
+
+// NewDispatcher creates a new webhook dispatcher
+func NewDispatcher(config Config, agentService agent.AgentService) *Dispatcher {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 5
+	}
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = time.Second
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = time.Minute
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Dispatcher{
+		config:            config,
+		agentService:      agentService,
+		client:            &http.Client{Timeout: config.Timeout},
+		submitterDefaults: make(map[string]string),
+		deliveries:        make(map[string][]*Delivery),
+		ctx:               ctx,
+		cancel:            cancel,
+	}
+}
+
+// SetSubmitterDefault registers the default callback URL for tasks
+// submitted with the given submitter (API key) identity
+func (d *Dispatcher) SetSubmitterDefault(submitter, url string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.submitterDefaults[submitter] = url
+}
+
+// Stop cancels pending retries and waits for in-flight deliveries
+func (d *Dispatcher) Stop() {
+	d.cancel()
+	d.wg.Wait()
+}
+
+// TaskFinished is called by the scheduler when a task reaches a terminal
+// status. The callback URL is resolved from the task, then the agent's
+// default webhook, then the submitter's default webhook.
+func (d *Dispatcher) TaskFinished(task *agent.Task, result *agent.TaskResult) {
+	event, ok := eventFor(task.Status)
+	if !ok {
+		return
+	}
+
+	tgt, ok := d.resolveTarget(task)
+	if !ok {
+		return
+	}
+
+	payload := &Payload{
+		Event:     event,
+		TaskID:    task.ID,
+		AgentID:   task.AgentID,
+		Status:    task.Status,
+		Result:    result,
+		Timestamp: time.Now(),
+	}
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.deliver(tgt, payload)
+	}()
+}
+
+// Deliveries returns the delivery log for a task
+func (d *Dispatcher) Deliveries(taskID string) []*Delivery {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	deliveries := make([]*Delivery, len(d.deliveries[taskID]))
+	copy(deliveries, d.deliveries[taskID])
+	return deliveries
+}
+
+// resolveTarget picks the callback URL for a task
+func (d *Dispatcher) resolveTarget(task *agent.Task) (target, bool) {
+	secret := d.config.Secret
+	if task.CallbackSecret != "" {
+		secret = task.CallbackSecret
+	}
+
+	if task.CallbackURL != "" {
+		return target{url: task.CallbackURL, secret: secret}, true
+	}
+
+	if ag, err := d.agentService.GetAgent(d.ctx, task.AgentID); err == nil && ag.Config.WebhookURL != "" {
+		return target{url: ag.Config.WebhookURL, secret: secret}, true
+	}
+
+	if task.Submitter != "" {
+		d.mu.RLock()
+		url, ok := d.submitterDefaults[task.Submitter]
+		d.mu.RUnlock()
+		if ok {
+			return target{url: url, secret: secret}, true
+		}
+	}
+
+	return target{}, false
+}
+
+// deliver POSTs the payload, retrying with exponential backoff
+func (d *Dispatcher) deliver(tgt target, payload *Payload) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Webhook for task %s: failed to marshal payload: %v", payload.TaskID, err)
+		return
+	}
+
+	deliveryID := uuid.New().String()
+	backoff := d.config.InitialBackoff
+
+	for attempt := 1; attempt <= d.config.MaxAttempts; attempt++ {
+		delivery, retryable := d.attempt(tgt, payload, body, deliveryID, attempt)
+		d.record(delivery)
+
+		if delivery.Success || !retryable {
+			return
+		}
+
+		if attempt == d.config.MaxAttempts {
+			break
+		}
+
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > d.config.MaxBackoff {
+			backoff = d.config.MaxBackoff
+		}
+	}
+
+	log.Printf("Webhook for task %s to %s failed after %d attempts", payload.TaskID, tgt.url, d.config.MaxAttempts)
+}
+
+// attempt performs one delivery and reports whether a failure is retryable
+func (d *Dispatcher) attempt(tgt target, payload *Payload, body []byte, deliveryID string, attempt int) (*Delivery, bool) {
+	start := time.Now()
+	delivery := &Delivery{
+		ID:        deliveryID,
+		TaskID:    payload.TaskID,
+		Event:     payload.Event,
+		URL:       tgt.url,
+		Attempt:   attempt,
+		CreatedAt: start,
+	}
+
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, tgt.url, bytes.NewReader(body))
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery, false
+	}
+
+	timestamp := strconv.FormatInt(start.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, string(payload.Event))
+	req.Header.Set(HeaderDelivery, deliveryID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	if tgt.secret != "" {
+		req.Header.Set(HeaderSignature, Sign(tgt.secret, timestamp, body))
+	}
+
+	resp, err := d.client.Do(req)
+	delivery.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery, true
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	delivery.StatusCode = resp.StatusCode
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		delivery.Success = true
+		return delivery, false
+	}
+
+	delivery.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+	// Client errors other than rate limiting will not succeed on retry
+	retryable := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	return delivery, retryable
+}
+
+// record appends a delivery to the task's log
+func (d *Dispatcher) record(delivery *Delivery) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.deliveries[delivery.TaskID] = append(d.deliveries[delivery.TaskID], delivery)
+}
+
+// Sign computes the signature header value for a webhook body.
+// The signed message is "<timestamp>.<body>".
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature header produced by Sign
+func Verify(secret, timestamp string, body []byte, signature string) bool {
+	expected := Sign(secret, timestamp, body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// eventFor maps a terminal task status to a webhook event
+func eventFor(status agent.TaskStatus) (Event, bool) {
+	switch status {
+	case agent.TaskStatusCompleted:
+		return EventTaskCompleted, true
+	case agent.TaskStatusFailed:
+		return EventTaskFailed, true
+	case agent.TaskStatusCancelled:
+		return EventTaskCancelled, true
+	}
+	return "", false
+}
@@ -0,0 +1,126 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/agent-learning/go-agent-api/internal/agent"
+)
+
+func newTestDispatcher(t *testing.T) (*Dispatcher, agent.AgentService) {
+	t.Helper()
+	service := agent.NewAgentServiceWithClient(nil)
+	dispatcher := NewDispatcher(Config{
+		Secret:         "top-secret",
+		MaxAttempts:    3,
+		InitialBackoff: 5 * time.Millisecond,
+		MaxBackoff:     10 * time.Millisecond,
+	}, service)
+	t.Cleanup(dispatcher.Stop)
+	return dispatcher, service
+}
+
+func waitForDeliveries(t *testing.T, d *Dispatcher, taskID string, n int) []*Delivery {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if deliveries := d.Deliveries(taskID); len(deliveries) >= n {
+			return deliveries
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for %d deliveries", n)
+	return nil
+}
+
+func TestDispatcherRetriesAndSigns(t *testing.T) {
+	var calls int32
+	var payload Payload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !Verify("top-secret", r.Header.Get(HeaderTimestamp), body, r.Header.Get(HeaderSignature)) {
+			t.Errorf("Invalid signature %q", r.Header.Get(HeaderSignature))
+		}
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_ = json.Unmarshal(body, &payload)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	dispatcher, _ := newTestDispatcher(t)
+	task := &agent.Task{ID: "task-1", AgentID: "agent-1", Status: agent.TaskStatusCompleted, CallbackURL: server.URL}
+	dispatcher.TaskFinished(task, &agent.TaskResult{TaskID: "task-1", Status: agent.TaskStatusCompleted, Output: "done"})
+
+	deliveries := waitForDeliveries(t, dispatcher, "task-1", 2)
+	if deliveries[0].Success || deliveries[0].StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected first attempt to fail with 503, got %+v", deliveries[0])
+	}
+	if !deliveries[1].Success || deliveries[1].Attempt != 2 {
+		t.Errorf("Expected second attempt to succeed, got %+v", deliveries[1])
+	}
+	if deliveries[0].ID != deliveries[1].ID {
+		t.Error("Expected retries to share a delivery ID")
+	}
+	if payload.Event != EventTaskCompleted || payload.Result == nil || payload.Result.Output != "done" {
+		t.Errorf("Unexpected payload: %+v", payload)
+	}
+}
+
+func TestDispatcherDoesNotRetryClientErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	dispatcher, _ := newTestDispatcher(t)
+	task := &agent.Task{ID: "task-2", Status: agent.TaskStatusFailed, CallbackURL: server.URL}
+	dispatcher.TaskFinished(task, nil)
+
+	waitForDeliveries(t, dispatcher, "task-2", 1)
+	time.Sleep(30 * time.Millisecond)
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("Expected 1 call, got %d", n)
+	}
+}
+
+func TestDispatcherResolvesDefaults(t *testing.T) {
+	received := make(chan string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.URL.Path
+	}))
+	defer server.Close()
+
+	dispatcher, service := newTestDispatcher(t)
+	ag, err := service.CreateAgent(context.Background(), &agent.CreateAgentRequest{
+		Name:   "hooked",
+		Type:   agent.AgentTypeGeneral,
+		Config: agent.AgentConfig{WebhookURL: server.URL + "/agent"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	dispatcher.SetSubmitterDefault("key_abc", server.URL+"/submitter")
+
+	dispatcher.TaskFinished(&agent.Task{ID: "t1", AgentID: ag.ID, Status: agent.TaskStatusCancelled}, nil)
+	dispatcher.TaskFinished(&agent.Task{ID: "t2", AgentID: "unknown", Submitter: "key_abc", Status: agent.TaskStatusCompleted}, nil)
+	dispatcher.TaskFinished(&agent.Task{ID: "t3", AgentID: "unknown", Status: agent.TaskStatusCompleted}, nil)
+
+	paths := map[string]bool{<-received: true, <-received: true}
+	if !paths["/agent"] || !paths["/submitter"] {
+		t.Errorf("Expected agent and submitter defaults to be used, got %v", paths)
+	}
+	if len(dispatcher.Deliveries("t3")) != 0 {
+		t.Error("Expected no delivery for task without any webhook")
+	}
+}
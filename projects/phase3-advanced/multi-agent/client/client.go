@@ -0,0 +1,501 @@
+// Package client 用Go编写连接多Agent Hub的工作Agent：
+// 负责注册、心跳、断线重连，把收到的任务分发给按能力注册的处理函数并回报结果
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/agent-learning/multi-agent/protocol"
+	"github.com/gorilla/websocket"
+)
+
+// ErrNotConnected 当前没有到Hub的连接（例如正在重连），或发送时连接断开
+var ErrNotConnected = errors.New("not connected to hub")
+
+// Config 客户端配置
+type Config struct {
+	URL      string // Hub的WebSocket地址，例如 ws://localhost:8080/ws
+	AgentID  string
+	Name     string
+	MaxTasks int
+	Metadata map[string]interface{}
+
+	HeartbeatInterval time.Duration // 心跳间隔，需小于服务器的心跳超时
+	MinBackoff        time.Duration // 首次重连前的等待时间
+	MaxBackoff        time.Duration // 重连等待时间上限
+	DialTimeout       time.Duration
+	DedupWindow       time.Duration // 记录需确认消息ID的时长，应不短于Hub的重传时长
+
+	// SigningKey 设置后对发出的每条消息签名
+	SigningKey *protocol.Key
+	// Keyring 设置后注册时上报公钥，并加密发给已知对端的消息
+	Keyring    *protocol.Keyring
+	Encryption protocol.EncryptionAlgorithm
+}
+
+// DefaultConfig 默认配置
+func DefaultConfig(hubURL, agentID string) *Config {
+	return &Config{
+		URL:               hubURL,
+		AgentID:           agentID,
+		Name:              agentID,
+		MaxTasks:          5,
+		HeartbeatInterval: 30 * time.Second,
+		MinBackoff:        time.Second,
+		MaxBackoff:        30 * time.Second,
+		DialTimeout:       10 * time.Second,
+		DedupWindow:       10 * time.Minute,
+		Encryption:        protocol.EncryptionX25519ChaCha20Poly1305,
+	}
+}
+
+// TaskHandler 任务处理函数，返回的输出作为TASK_COMPLETE的output，
+// 返回错误时回报TASK_FAILED。ctx在任务超时或客户端关闭时取消
+type TaskHandler func(ctx context.Context, task *Task) (map[string]interface{}, error)
+
+// MessageHandler 其他消息的处理函数
+type MessageHandler func(msg *protocol.Message)
+
+// Client 连接Hub的工作Agent
+type Client struct {
+	config     *Config
+	serializer *protocol.Serializer
+	dedup      *protocol.Deduplicator
+
+	handlers  map[string]TaskHandler
+	listeners map[protocol.MessageType][]MessageHandler
+	topics    map[string]bool               // 订阅的主题模式，重连后重新订阅
+	cancels   map[string]context.CancelFunc // 执行中任务的取消函数，收到TASK_CANCEL时调用
+	unsent    []*protocol.Message           // 断线期间未送达的任务结果，重连注册后补发
+
+	conn    *websocket.Conn
+	writeMu sync.Mutex // gorilla连接只允许一个并发写
+	running int
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu sync.RWMutex
+}
+
+// New 创建客户端，Connect之前用HandleTask注册能力
+func New(config *Config) *Client {
+	if config.MaxTasks <= 0 {
+		config.MaxTasks = 5
+	}
+	if config.Name == "" {
+		config.Name = config.AgentID
+	}
+	if config.DedupWindow <= 0 {
+		config.DedupWindow = 10 * time.Minute
+	}
+	// 零值的心跳间隔会使NewTicker panic，零值的退避会使重连成为忙循环
+	if config.HeartbeatInterval <= 0 {
+		config.HeartbeatInterval = 30 * time.Second
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = time.Second
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = 30 * time.Second
+	}
+	if config.MaxBackoff < config.MinBackoff {
+		config.MaxBackoff = config.MinBackoff
+	}
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = 10 * time.Second
+	}
+
+	serializer := protocol.NewSerializer()
+	if config.Keyring != nil {
+		serializer.SetEncryption(config.Keyring, config.Encryption)
+	}
+	if config.SigningKey != nil {
+		serializer.SetSigningKey(config.SigningKey)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Client{
+		config:     config,
+		serializer: serializer,
+		dedup:      protocol.NewDeduplicator(config.DedupWindow),
+		handlers:   make(map[string]TaskHandler),
+		listeners:  make(map[protocol.MessageType][]MessageHandler),
+		topics:     make(map[string]bool),
+		cancels:    make(map[string]context.CancelFunc),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+// HandleTask 注册capability能力的任务处理函数，能力在注册时上报给Hub
+func (c *Client) HandleTask(capability string, handler TaskHandler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.handlers[capability] = handler
+}
+
+// OnMessage 注册其他类型消息（例如ERROR、BROADCAST）的处理函数
+func (c *Client) OnMessage(msgType protocol.MessageType, handler MessageHandler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.listeners[msgType] = append(c.listeners[msgType], handler)
+}
+
+// Connect 连接Hub并注册，之后在后台发送心跳，连接断开时自动重连并重新注册。
+// 首次连接失败时返回错误，不重试
+func (c *Client) Connect(ctx context.Context) error {
+	if len(c.Capabilities()) == 0 {
+		return fmt.Errorf("no task handlers registered")
+	}
+
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.wg.Add(2)
+	go c.run(conn)
+	go c.heartbeatLoop()
+
+	return nil
+}
+
+// Close 断开连接并停止后台协程，正在执行的任务的ctx被取消
+func (c *Client) Close() error {
+	c.cancel()
+
+	c.mu.Lock()
+	conn := c.conn
+	c.conn = nil
+	c.mu.Unlock()
+
+	var err error
+	if conn != nil {
+		c.writeMu.Lock()
+		conn.WriteMessage(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+		c.writeMu.Unlock()
+		err = conn.Close()
+	}
+
+	c.wg.Wait()
+	return err
+}
+
+// Connected 检查当前是否连接着Hub
+func (c *Client) Connected() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.conn != nil
+}
+
+// Capabilities 返回已注册处理函数的能力
+func (c *Client) Capabilities() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	capabilities := make([]string, 0, len(c.handlers))
+	for capability := range c.handlers {
+		capabilities = append(capabilities, capability)
+	}
+	sort.Strings(capabilities)
+	return capabilities
+}
+
+// Load 返回当前负载：执行中的任务数 / MaxTasks
+func (c *Client) Load() float64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	load := float64(c.running) / float64(c.config.MaxTasks)
+	if load > 1 {
+		load = 1
+	}
+	return load
+}
+
+// Send 发送消息，未连接时返回ErrNotConnected
+func (c *Client) Send(msg *protocol.Message) error {
+	data, err := c.serializer.Serialize(msg)
+	if err != nil {
+		return err
+	}
+
+	c.mu.RLock()
+	conn := c.conn
+	c.mu.RUnlock()
+	if conn == nil {
+		return ErrNotConnected
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		// 关闭连接使读协程退出并重连
+		conn.Close()
+		return fmt.Errorf("failed to send %s: %w: %w", msg.Type, ErrNotConnected, err)
+	}
+	return nil
+}
+
+// sendToHub 发送发给Hub的消息
+func (c *Client) sendToHub(msgType protocol.MessageType, payload interface{}) error {
+	msg := protocol.NewMessage(msgType, c.config.AgentID, protocol.ServerID)
+	if err := msg.SetPayload(payload); err != nil {
+		return err
+	}
+	return c.Send(msg)
+}
+
+// dial 建立连接并注册
+func (c *Client) dial(ctx context.Context) (*websocket.Conn, error) {
+	u, err := url.Parse(c.config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hub URL: %w", err)
+	}
+	query := u.Query()
+	query.Set("agent_id", c.config.AgentID)
+	u.RawQuery = query.Encode()
+
+	dialer := websocket.Dialer{HandshakeTimeout: c.config.DialTimeout}
+	conn, _, err := dialer.DialContext(ctx, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", c.config.URL, err)
+	}
+
+	c.mu.Lock()
+	if c.ctx.Err() != nil {
+		c.mu.Unlock()
+		conn.Close()
+		return nil, c.ctx.Err()
+	}
+	c.conn = conn
+	c.mu.Unlock()
+
+	if err := c.register(); err != nil {
+		c.dropConnection(conn)
+		return nil, err
+	}
+
+	// Hub按连接记录订阅，新连接需重新订阅
+	if topics := c.Subscriptions(); len(topics) > 0 {
+		if err := c.Send(protocol.NewSubscribeMessage(c.config.AgentID, topics...)); err != nil {
+			c.dropConnection(conn)
+			return nil, fmt.Errorf("failed to subscribe: %w", err)
+		}
+	}
+
+	// 重新注册后Hub才接受任务结果
+	if err := c.resendResults(); err != nil {
+		c.dropConnection(conn)
+		return nil, fmt.Errorf("failed to resend task results: %w", err)
+	}
+
+	return conn, nil
+}
+
+// register 发送AGENT_REGISTER，每次（重新）连接后调用
+func (c *Client) register() error {
+	payload := &protocol.AgentRegisterPayload{
+		Name:         c.config.Name,
+		Capabilities: c.Capabilities(),
+		MaxTasks:     c.config.MaxTasks,
+		Metadata:     c.config.Metadata,
+	}
+	if c.config.Keyring != nil {
+		payload.EncryptionKey = c.config.Keyring.PublicKey()
+	}
+
+	if err := c.sendToHub(protocol.MessageTypeAgentRegister, payload); err != nil {
+		return fmt.Errorf("failed to register: %w", err)
+	}
+	return nil
+}
+
+// dropConnection 关闭连接，仍是当前连接时清空
+func (c *Client) dropConnection(conn *websocket.Conn) {
+	c.mu.Lock()
+	if c.conn == conn {
+		c.conn = nil
+	}
+	c.mu.Unlock()
+
+	conn.Close()
+}
+
+// run 读取消息，连接断开后按退避时间重连，直到Close
+func (c *Client) run(conn *websocket.Conn) {
+	defer c.wg.Done()
+
+	for {
+		c.readLoop(conn)
+		c.dropConnection(conn)
+
+		var err error
+		if conn, err = c.reconnect(); err != nil {
+			return
+		}
+	}
+}
+
+// reconnect 以指数退避重连，只在客户端关闭时返回错误
+func (c *Client) reconnect() (*websocket.Conn, error) {
+	backoff := c.config.MinBackoff
+	for {
+		select {
+		case <-c.ctx.Done():
+			return nil, c.ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		conn, err := c.dial(c.ctx)
+		if err == nil {
+			log.Printf("Agent %s reconnected to %s", c.config.AgentID, c.config.URL)
+			return conn, nil
+		}
+		if c.ctx.Err() != nil {
+			return nil, c.ctx.Err()
+		}
+		log.Printf("Agent %s reconnect failed: %v", c.config.AgentID, err)
+
+		backoff *= 2
+		if backoff > c.config.MaxBackoff {
+			backoff = c.config.MaxBackoff
+		}
+	}
+}
+
+// readLoop 读取并处理消息直到连接断开
+func (c *Client) readLoop(conn *websocket.Conn) {
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if c.ctx.Err() == nil {
+				log.Printf("Agent %s lost connection: %v", c.config.AgentID, err)
+			}
+			return
+		}
+
+		msg, err := c.serializer.Deserialize(data)
+		if err != nil {
+			log.Printf("Agent %s dropped message: %v", c.config.AgentID, err)
+			continue
+		}
+
+		c.handleMessage(msg)
+	}
+}
+
+// handleMessage 分发收到的消息
+func (c *Client) handleMessage(msg *protocol.Message) {
+	// 需确认的消息收到即确认，重传的消息只重新确认，不再处理
+	if msg.RequireAck {
+		ack, duplicate := c.dedup.Check(msg.From, msg.MessageID)
+		if !duplicate {
+			ack = &protocol.AckPayload{MessageID: msg.MessageID, Success: true}
+			c.dedup.Done(msg.From, msg.MessageID, ack)
+		}
+		if err := c.Send(protocol.NewAckMessage(c.config.AgentID, msg, ack)); err != nil {
+			log.Printf("Agent %s failed to ack message %s: %v", c.config.AgentID, msg.MessageID, err)
+		}
+		if duplicate {
+			return
+		}
+	}
+
+	switch msg.Type {
+	case protocol.MessageTypeTaskRequest:
+		var payload protocol.TaskRequestPayload
+		if err := msg.GetPayload(&payload); err != nil {
+			log.Printf("Agent %s received invalid task request: %v", c.config.AgentID, err)
+			return
+		}
+		c.wg.Add(1)
+		go c.runTask(&payload)
+
+	case protocol.MessageTypeTaskCancel:
+		var payload protocol.TaskCancelPayload
+		if msg.GetPayload(&payload) == nil {
+			c.cancelTask(payload.TaskID)
+		}
+
+	case protocol.MessageTypeKeyExchange:
+		// 登记其他Agent的公钥，之后发给它们的消息自动加密
+		var payload protocol.KeyExchangePayload
+		if c.config.Keyring != nil && msg.GetPayload(&payload) == nil &&
+			payload.PublicKey != "" && payload.AgentID != c.config.AgentID {
+			if err := c.config.Keyring.AddPeer(payload.AgentID, payload.PublicKey); err != nil {
+				log.Printf("Agent %s ignored key of %s: %v", c.config.AgentID, payload.AgentID, err)
+			}
+		}
+
+	case protocol.MessageTypeError:
+		var payload protocol.ErrorPayload
+		if msg.GetPayload(&payload) == nil {
+			log.Printf("Agent %s received error: %v", c.config.AgentID, &payload)
+		}
+	}
+
+	c.mu.RLock()
+	listeners := c.listeners[msg.Type]
+	c.mu.RUnlock()
+
+	for _, listener := range listeners {
+		listener(msg)
+	}
+}
+
+// heartbeatLoop 定期发送心跳，上报状态和负载
+func (c *Client) heartbeatLoop() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.config.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.sendHeartbeat(); err != nil && !errors.Is(err, ErrNotConnected) {
+				log.Printf("Agent %s heartbeat failed: %v", c.config.AgentID, err)
+			}
+		}
+	}
+}
+
+// sendHeartbeat 发送一次心跳
+func (c *Client) sendHeartbeat() error {
+	c.mu.RLock()
+	running := c.running
+	c.mu.RUnlock()
+
+	status := protocol.AgentStatusActive
+	switch {
+	case running == 0:
+		status = protocol.AgentStatusIdle
+	case running >= c.config.MaxTasks:
+		status = protocol.AgentStatusBusy
+	}
+
+	return c.sendToHub(protocol.MessageTypeHeartbeat, &protocol.HeartbeatPayload{
+		Status:       status,
+		Load:         c.Load(),
+		TasksRunning: running,
+		Capabilities: c.Capabilities(),
+	})
+}
@@ -0,0 +1,458 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/agent-learning/multi-agent/internal/communication"
+	"github.com/agent-learning/multi-agent/protocol"
+)
+
+// startTestHub 在空闲端口上启动WebSocket服务器，收到的消息写入返回的通道
+func startTestHub(t *testing.T) (*communication.WebSocketServer, string, chan *protocol.Message) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	config := communication.DefaultWebSocketConfig()
+	config.Host = "127.0.0.1"
+	config.Port = port
+	config.WorkerPoolSize = 2
+
+	server := communication.NewWebSocketServer(config)
+	received := make(chan *protocol.Message, 100)
+	for _, msgType := range []protocol.MessageType{
+		protocol.MessageTypeAgentRegister,
+		protocol.MessageTypeHeartbeat,
+		protocol.MessageTypeTaskProgress,
+		protocol.MessageTypeTaskComplete,
+		protocol.MessageTypeTaskFailed,
+	} {
+		server.RegisterMessageHandler(msgType, func(msg *protocol.Message) error {
+			received <- msg
+			return nil
+		})
+	}
+
+	if err := server.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	t.Cleanup(func() { server.Stop() })
+
+	return server, fmt.Sprintf("ws://127.0.0.1:%d/ws", port), received
+}
+
+// expectMessage 等待指定类型的消息，跳过其他消息
+func expectMessage(t *testing.T, received chan *protocol.Message, msgType protocol.MessageType) *protocol.Message {
+	t.Helper()
+
+	timeout := time.After(3 * time.Second)
+	for {
+		select {
+		case msg := <-received:
+			if msg.Type == msgType {
+				return msg
+			}
+		case <-timeout:
+			t.Fatalf("Timed out waiting for %s", msgType)
+			return nil
+		}
+	}
+}
+
+// connectClient 创建客户端并连接，Hub启动前的连接失败会重试
+func connectClient(t *testing.T, c *Client) {
+	t.Helper()
+
+	var err error
+	for i := 0; i < 50; i++ {
+		if err = c.Connect(context.Background()); err == nil {
+			t.Cleanup(func() { c.Close() })
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("Connect failed: %v", err)
+}
+
+func sendTask(t *testing.T, server *communication.WebSocketServer, agentID string, payload *protocol.TaskRequestPayload) {
+	t.Helper()
+
+	msg := protocol.NewMessage(protocol.MessageTypeTaskRequest, protocol.ServerID, agentID)
+	msg.SetPayload(payload)
+	if err := server.SendMessage(msg); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+}
+
+func TestClientRunsTasks(t *testing.T) {
+	server, hubURL, received := startTestHub(t)
+
+	config := DefaultConfig(hubURL, "worker-1")
+	config.MaxTasks = 2
+	config.HeartbeatInterval = 20 * time.Millisecond
+	c := New(config)
+
+	release := make(chan struct{})
+	c.HandleTask("echo", func(ctx context.Context, task *Task) (map[string]interface{}, error) {
+		if err := task.Progress(50, "halfway"); err != nil {
+			return nil, err
+		}
+		<-release
+		return map[string]interface{}{"echo": task.Input}, nil
+	})
+	c.HandleTask("fail", func(ctx context.Context, task *Task) (map[string]interface{}, error) {
+		return nil, fmt.Errorf("boom")
+	})
+	connectClient(t, c)
+
+	var register protocol.AgentRegisterPayload
+	expectMessage(t, received, protocol.MessageTypeAgentRegister).GetPayload(&register)
+	if register.Name != "worker-1" || len(register.Capabilities) != 2 || register.MaxTasks != 2 {
+		t.Errorf("Unexpected registration: %+v", register)
+	}
+
+	sendTask(t, server, "worker-1", &protocol.TaskRequestPayload{TaskID: "task-1", TaskType: "echo", Input: "hello"})
+
+	var progress protocol.TaskProgressPayload
+	expectMessage(t, received, protocol.MessageTypeTaskProgress).GetPayload(&progress)
+	if progress.TaskID != "task-1" || progress.Progress != 50 {
+		t.Errorf("Unexpected progress: %+v", progress)
+	}
+
+	// 执行任务期间心跳上报负载
+	for {
+		var heartbeat protocol.HeartbeatPayload
+		expectMessage(t, received, protocol.MessageTypeHeartbeat).GetPayload(&heartbeat)
+		if heartbeat.TasksRunning == 1 {
+			if heartbeat.Load != 0.5 || heartbeat.Status != protocol.AgentStatusActive {
+				t.Errorf("Unexpected heartbeat: %+v", heartbeat)
+			}
+			break
+		}
+	}
+	close(release)
+
+	var complete protocol.TaskCompletePayload
+	expectMessage(t, received, protocol.MessageTypeTaskComplete).GetPayload(&complete)
+	if complete.TaskID != "task-1" || complete.Status != protocol.TaskStatusSuccess || complete.Output["echo"] != "hello" {
+		t.Errorf("Unexpected result: %+v", complete)
+	}
+
+	// 按要求的能力匹配处理函数
+	sendTask(t, server, "worker-1", &protocol.TaskRequestPayload{
+		TaskID:       "task-2",
+		TaskType:     "analysis",
+		Requirements: map[string]interface{}{"capabilities": []string{"fail"}},
+	})
+	var failed protocol.TaskFailedPayload
+	expectMessage(t, received, protocol.MessageTypeTaskFailed).GetPayload(&failed)
+	if failed.TaskID != "task-2" || failed.ErrorCode != "EXECUTION_FAILED" || failed.ErrorMessage != "boom" {
+		t.Errorf("Unexpected failure: %+v", failed)
+	}
+
+	sendTask(t, server, "worker-1", &protocol.TaskRequestPayload{TaskID: "task-3", TaskType: "translate"})
+	expectMessage(t, received, protocol.MessageTypeTaskFailed).GetPayload(&failed)
+	if failed.TaskID != "task-3" || failed.ErrorCode != string(protocol.RejectReasonCapabilityMismatch) {
+		t.Errorf("Unexpected failure: %+v", failed)
+	}
+}
+
+func TestNewFillsZeroConfig(t *testing.T) {
+	_, hubURL, received := startTestHub(t)
+
+	// 未经DefaultConfig创建的配置也能连接，心跳和重连不会因零值panic或忙循环
+	c := New(&Config{URL: hubURL, AgentID: "worker-1", MaxBackoff: time.Millisecond, MinBackoff: -time.Second})
+	if c.config.HeartbeatInterval <= 0 || c.config.MinBackoff <= 0 || c.config.MaxBackoff < c.config.MinBackoff || c.config.DialTimeout <= 0 {
+		t.Fatalf("Expected zero durations to be defaulted, got %+v", c.config)
+	}
+	c.HandleTask("echo", func(ctx context.Context, task *Task) (map[string]interface{}, error) {
+		return nil, nil
+	})
+	connectClient(t, c)
+	expectMessage(t, received, protocol.MessageTypeAgentRegister)
+	c.Close()
+}
+
+func TestClientReconnects(t *testing.T) {
+	server, hubURL, received := startTestHub(t)
+
+	config := DefaultConfig(hubURL, "worker-1")
+	config.MinBackoff = 10 * time.Millisecond
+	c := New(config)
+	c.HandleTask("echo", func(ctx context.Context, task *Task) (map[string]interface{}, error) {
+		return map[string]interface{}{"echo": task.Input}, nil
+	})
+	connectClient(t, c)
+	expectMessage(t, received, protocol.MessageTypeAgentRegister)
+
+	// 服务器端断开连接，客户端重连并重新注册
+	conn, err := server.GetConnectionManager().GetConnectionByAgent("worker-1")
+	if err != nil {
+		t.Fatalf("GetConnectionByAgent failed: %v", err)
+	}
+	conn.Close()
+
+	msg := expectMessage(t, received, protocol.MessageTypeAgentRegister)
+	if msg.From != "worker-1" {
+		t.Errorf("Expected re-registration from worker-1, got %s", msg.From)
+	}
+
+	// 新连接可以继续接收任务
+	sendTask(t, server, "worker-1", &protocol.TaskRequestPayload{TaskID: "task-1", TaskType: "echo", Input: "again"})
+	var complete protocol.TaskCompletePayload
+	expectMessage(t, received, protocol.MessageTypeTaskComplete).GetPayload(&complete)
+	if complete.Output["echo"] != "again" {
+		t.Errorf("Unexpected result after reconnect: %+v", complete)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if c.Connected() {
+		t.Error("Expected the client to be disconnected after Close")
+	}
+}
+
+func TestClientResendsResultsAfterReconnect(t *testing.T) {
+	server, hubURL, received := startTestHub(t)
+
+	config := DefaultConfig(hubURL, "worker-1")
+	config.MinBackoff = 200 * time.Millisecond
+	c := New(config)
+
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	c.HandleTask("echo", func(ctx context.Context, task *Task) (map[string]interface{}, error) {
+		started <- struct{}{}
+		<-release
+		return map[string]interface{}{"echo": task.Input}, nil
+	})
+	c.HandleTask("fail", func(ctx context.Context, task *Task) (map[string]interface{}, error) {
+		started <- struct{}{}
+		<-release
+		return nil, fmt.Errorf("boom")
+	})
+	connectClient(t, c)
+	expectMessage(t, received, protocol.MessageTypeAgentRegister)
+
+	sendTask(t, server, "worker-1", &protocol.TaskRequestPayload{TaskID: "task-1", TaskType: "echo", Input: "hello"})
+	sendTask(t, server, "worker-1", &protocol.TaskRequestPayload{TaskID: "task-2", TaskType: "fail"})
+	<-started
+	<-started
+
+	// 处理函数执行期间连接断开，结果在重连前产生
+	conn, err := server.GetConnectionManager().GetConnectionByAgent("worker-1")
+	if err != nil {
+		t.Fatalf("GetConnectionByAgent failed: %v", err)
+	}
+	conn.Close()
+	for c.Connected() {
+		time.Sleep(5 * time.Millisecond)
+	}
+	close(release)
+
+	// 重连注册后补发两个结果；Hub并发处理收到的消息，到达测试的顺序不定
+	registered := false
+	var complete protocol.TaskCompletePayload
+	var failed protocol.TaskFailedPayload
+	timeout := time.After(3 * time.Second)
+	for !registered || complete.TaskID == "" || failed.TaskID == "" {
+		select {
+		case msg := <-received:
+			switch msg.Type {
+			case protocol.MessageTypeAgentRegister:
+				registered = true
+			case protocol.MessageTypeTaskComplete:
+				msg.GetPayload(&complete)
+			case protocol.MessageTypeTaskFailed:
+				msg.GetPayload(&failed)
+			}
+		case <-timeout:
+			t.Fatalf("Timed out waiting for resent results, got %+v and %+v", complete, failed)
+		}
+	}
+	if complete.TaskID != "task-1" || complete.Output["echo"] != "hello" {
+		t.Errorf("Unexpected resent result: %+v", complete)
+	}
+	if failed.TaskID != "task-2" || failed.ErrorCode != "EXECUTION_FAILED" {
+		t.Errorf("Unexpected resent failure: %+v", failed)
+	}
+}
+
+func TestClientStopsCancelledTasks(t *testing.T) {
+	server, hubURL, received := startTestHub(t)
+
+	c := New(DefaultConfig(hubURL, "worker-1"))
+	stopped := make(chan struct{})
+	c.HandleTask("slow", func(ctx context.Context, task *Task) (map[string]interface{}, error) {
+		task.Progress(10, "started")
+		<-ctx.Done()
+		close(stopped)
+		return nil, ctx.Err()
+	})
+	connectClient(t, c)
+	expectMessage(t, received, protocol.MessageTypeAgentRegister)
+
+	sendTask(t, server, "worker-1", &protocol.TaskRequestPayload{TaskID: "task-1", TaskType: "slow"})
+	expectMessage(t, received, protocol.MessageTypeTaskProgress)
+
+	cancel := protocol.NewMessage(protocol.MessageTypeTaskCancel, protocol.ServerID, "worker-1")
+	cancel.SetPayload(&protocol.TaskCancelPayload{TaskID: "task-1", Reason: "composite task failed"})
+	if err := server.SendMessage(cancel); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+
+	select {
+	case <-stopped:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Expected the handler context to be cancelled")
+	}
+
+	// 被取消的任务不回报结果
+	select {
+	case msg := <-received:
+		if msg.Type == protocol.MessageTypeTaskFailed || msg.Type == protocol.MessageTypeTaskComplete {
+			t.Errorf("Expected no result for a cancelled task, got %s", msg.Type)
+		}
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestClientAcksAndDeduplicates(t *testing.T) {
+	server, hubURL, received := startTestHub(t)
+	sender := server.GetReliableSender()
+
+	c := New(DefaultConfig(hubURL, "worker-1"))
+	c.HandleTask("echo", func(ctx context.Context, task *Task) (map[string]interface{}, error) {
+		return map[string]interface{}{"echo": task.Input}, nil
+	})
+	connectClient(t, c)
+	expectMessage(t, received, protocol.MessageTypeAgentRegister)
+
+	msg := protocol.NewMessage(protocol.MessageTypeTaskRequest, protocol.ServerID, "worker-1")
+	msg.SetPayload(&protocol.TaskRequestPayload{TaskID: "task-1", TaskType: "echo", Input: "once"})
+	msg.RequireAck = true
+	if err := server.SendMessage(msg); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	expectMessage(t, received, protocol.MessageTypeTaskComplete)
+
+	for i := 0; i < 50 && sender.PendingCount() > 0; i++ {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if sender.PendingCount() != 0 {
+		t.Fatal("Expected the task request to be acknowledged")
+	}
+
+	// 重传的任务请求不再执行
+	if err := server.GetDispatcher().SendToAgent("worker-1", msg); err != nil {
+		t.Fatalf("SendToAgent failed: %v", err)
+	}
+	timeout := time.After(300 * time.Millisecond)
+	for {
+		select {
+		case dup := <-received:
+			if dup.Type == protocol.MessageTypeTaskComplete {
+				t.Fatal("Retransmitted task request was executed again")
+			}
+		case <-timeout:
+			return
+		}
+	}
+}
+
+func TestClientSubscribesToTopics(t *testing.T) {
+	server, hubURL, received := startTestHub(t)
+
+	newClient := func(agentID string) *Client {
+		config := DefaultConfig(hubURL, agentID)
+		config.MinBackoff = 10 * time.Millisecond
+		c := New(config)
+		c.HandleTask("echo", func(ctx context.Context, task *Task) (map[string]interface{}, error) {
+			return nil, nil
+		})
+		return c
+	}
+
+	publisher := newClient("worker-1")
+	subscriber := newClient("worker-2")
+
+	published := make(chan *protocol.Message, 10)
+	subscriber.OnMessage(protocol.MessageTypeBroadcast, func(msg *protocol.Message) {
+		published <- msg
+	})
+
+	// 连接前的订阅在连接后发送
+	if err := subscriber.Subscribe("reports.code.*"); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if err := subscriber.Subscribe("reports.#.code"); err == nil {
+		t.Error("Expected an invalid pattern to be rejected")
+	}
+	connectClient(t, publisher)
+	connectClient(t, subscriber)
+	expectMessage(t, received, protocol.MessageTypeAgentRegister)
+	expectMessage(t, received, protocol.MessageTypeAgentRegister)
+
+	publish := func(topic string) {
+		t.Helper()
+		payload := &protocol.BroadcastPayload{Event: "progress", Message: topic}
+		if err := publisher.Publish(topic, protocol.MessageTypeBroadcast, payload, false); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+	}
+	expectPublished := func(topic string) {
+		t.Helper()
+		select {
+		case msg := <-published:
+			if msg.Topic != topic || msg.From != "worker-1" {
+				t.Errorf("Expected a publication to %s, got %+v", topic, msg)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("Timed out waiting for a publication to %s", topic)
+		}
+	}
+	// 当前连接已订阅，且旧连接的订阅已移除
+	subscribed := func() bool {
+		conn, err := server.GetConnectionManager().GetConnectionByAgent("worker-2")
+		subscribers := server.GetRouter().Subscribers("reports.code.progress")
+		return err == nil && len(subscribers) == 1 && subscribers[0] == conn.ID
+	}
+
+	waitSubscribed := func() {
+		t.Helper()
+		for i := 0; i < 100 && !subscribed(); i++ {
+			time.Sleep(20 * time.Millisecond)
+		}
+		if !subscribed() {
+			t.Fatal("Timed out waiting for the subscription")
+		}
+	}
+
+	waitSubscribed()
+	publish("reports.review.progress")
+	publish("reports.code.progress")
+	expectPublished("reports.code.progress")
+
+	// 重连后自动重新订阅
+	conn, _ := server.GetConnectionManager().GetConnectionByAgent("worker-2")
+	conn.Close()
+	expectMessage(t, received, protocol.MessageTypeAgentRegister)
+	waitSubscribed()
+	publish("reports.code.progress")
+	expectPublished("reports.code.progress")
+
+	subscriber.Unsubscribe("reports.code.*")
+	if topics := subscriber.Subscriptions(); len(topics) != 0 {
+		t.Errorf("Expected no subscriptions, got %v", topics)
+	}
+}
@@ -0,0 +1,210 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/agent-learning/multi-agent/protocol"
+)
+
+// Task Hub分配给本Agent的任务
+type Task struct {
+	ID           string
+	Type         string
+	Input        interface{}
+	Requirements map[string]interface{}
+	Capability   string // 处理该任务的能力
+
+	client *Client
+}
+
+// Progress 上报任务进度（0-100）
+func (t *Task) Progress(progress int, message string) error {
+	if progress < 0 || progress > 100 {
+		return fmt.Errorf("progress must be between 0 and 100, got %d", progress)
+	}
+
+	return t.client.sendToHub(protocol.MessageTypeTaskProgress, &protocol.TaskProgressPayload{
+		TaskID:   t.ID,
+		Progress: progress,
+		Message:  message,
+	})
+}
+
+// findHandler 按任务类型查找处理函数，找不到时按任务要求的能力查找
+func (c *Client) findHandler(payload *protocol.TaskRequestPayload) (string, TaskHandler) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if handler, exists := c.handlers[payload.TaskType]; exists {
+		return payload.TaskType, handler
+	}
+
+	if capabilities, ok := payload.Requirements["capabilities"].([]interface{}); ok {
+		for _, capability := range capabilities {
+			name, _ := capability.(string)
+			if handler, exists := c.handlers[name]; exists {
+				return name, handler
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// runTask 执行任务并回报结果
+func (c *Client) runTask(payload *protocol.TaskRequestPayload) {
+	defer c.wg.Done()
+
+	capability, handler := c.findHandler(payload)
+	if handler == nil {
+		c.reportFailure(payload.TaskID, string(protocol.RejectReasonCapabilityMismatch),
+			fmt.Errorf("no handler for task type %q", payload.TaskType))
+		return
+	}
+
+	c.mu.Lock()
+	c.running++
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.running--
+		c.mu.Unlock()
+	}()
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if payload.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(c.ctx, time.Duration(payload.Timeout)*time.Second)
+	} else {
+		ctx, cancel = context.WithCancel(c.ctx)
+	}
+	defer cancel()
+
+	c.mu.Lock()
+	c.cancels[payload.TaskID] = cancel
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.cancels, payload.TaskID)
+		c.mu.Unlock()
+	}()
+
+	task := &Task{
+		ID:           payload.TaskID,
+		Type:         payload.TaskType,
+		Input:        payload.Input,
+		Requirements: payload.Requirements,
+		Capability:   capability,
+		client:       c,
+	}
+
+	start := time.Now()
+	output, err := c.execute(ctx, handler, task)
+
+	// 客户端已关闭，Hub会把任务重新分配
+	if c.ctx.Err() != nil {
+		return
+	}
+
+	// Hub已取消任务，不再回报结果
+	if errors.Is(ctx.Err(), context.Canceled) {
+		log.Printf("Agent %s stopped cancelled task %s", c.config.AgentID, task.ID)
+		return
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		c.reportFailure(task.ID, "TASK_TIMEOUT", err)
+		return
+	}
+	if err != nil {
+		c.reportFailure(task.ID, "EXECUTION_FAILED", err)
+		return
+	}
+
+	if output == nil {
+		output = make(map[string]interface{})
+	}
+	c.sendResult(protocol.MessageTypeTaskComplete, task.ID, &protocol.TaskCompletePayload{
+		TaskID:      task.ID,
+		Status:      protocol.TaskStatusSuccess,
+		Output:      output,
+		Duration:    time.Since(start).Milliseconds(),
+		CompletedAt: time.Now().Format(time.RFC3339),
+	})
+}
+
+// cancelTask 取消执行中的任务，任务不存在（已结束或未开始）时忽略
+func (c *Client) cancelTask(taskID string) {
+	c.mu.RLock()
+	cancel, exists := c.cancels[taskID]
+	c.mu.RUnlock()
+
+	if exists {
+		cancel()
+	}
+}
+
+// execute 调用处理函数，panic转为错误
+func (c *Client) execute(ctx context.Context, handler TaskHandler, task *Task) (output map[string]interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("task handler panicked: %v", r)
+		}
+	}()
+
+	return handler(ctx, task)
+}
+
+// reportFailure 回报TASK_FAILED
+func (c *Client) reportFailure(taskID, code string, cause error) {
+	c.sendResult(protocol.MessageTypeTaskFailed, taskID, &protocol.TaskFailedPayload{
+		TaskID:       taskID,
+		ErrorCode:    code,
+		ErrorMessage: cause.Error(),
+	})
+}
+
+// sendResult 回报任务结果，连接断开时暂存，重连注册后补发
+func (c *Client) sendResult(msgType protocol.MessageType, taskID string, payload interface{}) {
+	msg := protocol.NewMessage(msgType, c.config.AgentID, protocol.ServerID)
+	if err := msg.SetPayload(payload); err != nil {
+		log.Printf("Agent %s failed to report task %s: %v", c.config.AgentID, taskID, err)
+		return
+	}
+
+	err := c.Send(msg)
+	if err == nil {
+		return
+	}
+	if !errors.Is(err, ErrNotConnected) {
+		log.Printf("Agent %s failed to report task %s: %v", c.config.AgentID, taskID, err)
+		return
+	}
+
+	c.mu.Lock()
+	c.unsent = append(c.unsent, msg)
+	c.mu.Unlock()
+	log.Printf("Agent %s will report task %s after reconnecting", c.config.AgentID, taskID)
+}
+
+// resendResults 按顺序补发暂存的任务结果，发送失败时剩余的留到下次重连
+func (c *Client) resendResults() error {
+	c.mu.Lock()
+	unsent := c.unsent
+	c.unsent = nil
+	c.mu.Unlock()
+
+	for i, msg := range unsent {
+		if err := c.Send(msg); err != nil {
+			c.mu.Lock()
+			c.unsent = append(unsent[i:], c.unsent...)
+			c.mu.Unlock()
+			return err
+		}
+	}
+	return nil
+}
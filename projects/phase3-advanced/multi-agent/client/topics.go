@@ -0,0 +1,74 @@
+package client
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/agent-learning/multi-agent/protocol"
+)
+
+// Subscribe 订阅主题，匹配的消息按类型交给OnMessage注册的处理函数。
+// 未连接时只记录订阅，连接和重连后自动订阅
+func (c *Client) Subscribe(topics ...string) error {
+	for _, topic := range topics {
+		if err := protocol.ValidateTopicPattern(topic); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	for _, topic := range topics {
+		c.topics[topic] = true
+	}
+	c.mu.Unlock()
+
+	return c.sendIfConnected(protocol.NewSubscribeMessage(c.config.AgentID, topics...))
+}
+
+// Unsubscribe 取消订阅主题
+func (c *Client) Unsubscribe(topics ...string) error {
+	c.mu.Lock()
+	for _, topic := range topics {
+		delete(c.topics, topic)
+	}
+	c.mu.Unlock()
+
+	return c.sendIfConnected(protocol.NewUnsubscribeMessage(c.config.AgentID, topics...))
+}
+
+// Subscriptions 获取订阅的主题模式
+func (c *Client) Subscriptions() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	topics := make([]string, 0, len(c.topics))
+	for topic := range c.topics {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// Publish 发布消息到主题，Hub只转发给订阅了匹配主题的连接。retain为true时Hub保留为主题的最后一条消息
+func (c *Client) Publish(topic string, msgType protocol.MessageType, payload interface{}, retain bool) error {
+	if err := protocol.ValidateTopic(topic); err != nil {
+		return err
+	}
+
+	msg := protocol.NewMessage(msgType, c.config.AgentID, "broadcast")
+	if err := msg.SetPayload(payload); err != nil {
+		return err
+	}
+	msg.Topic = topic
+	msg.Retain = retain
+
+	return c.Send(msg)
+}
+
+// sendIfConnected 发送消息，未连接时不报错
+func (c *Client) sendIfConnected(msg *protocol.Message) error {
+	if err := c.Send(msg); err != nil && !errors.Is(err, ErrNotConnected) {
+		return err
+	}
+	return nil
+}
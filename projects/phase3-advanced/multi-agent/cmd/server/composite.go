@@ -0,0 +1,598 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/agent-learning/multi-agent/internal/aggregator"
+	"github.com/agent-learning/multi-agent/internal/scheduler"
+	decomposer "github.com/agent-learning/multi-agent/internal/task-decomposer"
+	"github.com/agent-learning/multi-agent/protocol"
+	"github.com/google/uuid"
+)
+
+// CompositeStatus 复合任务状态
+type CompositeStatus string
+
+const (
+	CompositeStatusRunning   CompositeStatus = "RUNNING"
+	CompositeStatusCompleted CompositeStatus = "COMPLETED"
+	CompositeStatusFailed    CompositeStatus = "FAILED"
+)
+
+// 子任务在复合任务中的状态
+const (
+	SubTaskStatusWaiting   = "WAITING"   // 等待依赖完成
+	SubTaskStatusSubmitted = "SUBMITTED" // 已提交给调度器
+	SubTaskStatusCompleted = "COMPLETED"
+	SubTaskStatusFailed    = "FAILED"
+	SubTaskStatusCancelled = "CANCELLED"
+)
+
+// CompositeTaskRequest 创建复合任务的请求
+type CompositeTaskRequest struct {
+	ID           string                 `json:"id,omitempty"`
+	Type         string                 `json:"type"`
+	Description  string                 `json:"description"`
+	Input        interface{}            `json:"input,omitempty"`
+	Priority     int                    `json:"priority,omitempty"`
+	Capabilities []string               `json:"capabilities,omitempty"`
+	Requirements map[string]interface{} `json:"requirements,omitempty"`
+	Strategy     string                 `json:"strategy,omitempty"` // 分解策略，默认HYBRID
+}
+
+// CompositeTask 分解后按依赖图执行的复合任务
+type CompositeTask struct {
+	ID          string                       `json:"id"`
+	Type        string                       `json:"type"`
+	Description string                       `json:"description"`
+	Input       interface{}                  `json:"input,omitempty"`
+	Strategy    string                       `json:"strategy"`
+	Status      CompositeStatus              `json:"status"`
+	SubTasks    []*CompositeSubTask          `json:"sub_tasks"`
+	Levels      [][]string                   `json:"levels"` // 按拓扑层级分组的子任务ID
+	Result      *aggregator.AggregatedResult `json:"result,omitempty"`
+	Error       string                       `json:"error,omitempty"`
+	CreatedAt   time.Time                    `json:"created_at"`
+	CompletedAt *time.Time                   `json:"completed_at,omitempty"`
+
+	priority int
+}
+
+// CompositeSubTask 复合任务中的子任务
+type CompositeSubTask struct {
+	ID           string                 `json:"id"`
+	Type         string                 `json:"type"`
+	Description  string                 `json:"description"`
+	Level        int                    `json:"level"`
+	Dependencies []string               `json:"dependencies"`
+	Capabilities []string               `json:"capabilities"`
+	Status       string                 `json:"status"`
+	Output       map[string]interface{} `json:"output,omitempty"`
+
+	input    interface{}
+	priority int
+}
+
+// createCompositeTask 分解任务并提交第一层子任务
+func (s *Server) createCompositeTask(req *CompositeTaskRequest) (*CompositeTask, error) {
+	if req.ID == "" {
+		req.ID = "composite-" + uuid.New().String()[:8]
+	}
+	if req.Priority <= 0 {
+		req.Priority = 5
+	}
+
+	task := decomposer.NewTask(req.ID, req.Type, req.Description)
+	task.Input = req.Input
+	task.Priority = req.Priority
+	task.Capabilities = append(task.Capabilities, req.Capabilities...)
+	for key, value := range req.Requirements {
+		task.SetRequirement(key, value)
+	}
+
+	config := decomposer.DefaultConfig()
+	if req.Strategy != "" {
+		config.Strategy = decomposer.DecompositionStrategy(req.Strategy)
+	}
+	d := decomposer.NewDecomposer(config)
+	if s.planner != nil {
+		// 规划结果只能使用已注册Agent的能力
+		s.planner.SetCapabilities(s.agentCapabilities())
+		d.SetPlanner(s.planner)
+	}
+	result, err := d.Decompose(task)
+	if err != nil {
+		return nil, err
+	}
+
+	composite, err := newCompositeTask(req, result)
+	if err != nil {
+		return nil, err
+	}
+
+	s.compositeMu.Lock()
+	if _, exists := s.composites[composite.ID]; exists {
+		s.compositeMu.Unlock()
+		return nil, fmt.Errorf("composite task %s already exists", composite.ID)
+	}
+	s.composites[composite.ID] = composite
+	for _, sub := range composite.SubTasks {
+		s.subTaskParents[sub.ID] = composite.ID
+	}
+	s.compositeMu.Unlock()
+
+	log.Printf("Composite task %s decomposed into %d sub-tasks (%d levels)",
+		composite.ID, len(composite.SubTasks), len(composite.Levels))
+
+	s.advanceComposite(composite.ID)
+	return composite, nil
+}
+
+// agentCapabilities 返回已注册Agent的全部能力
+func (s *Server) agentCapabilities() []string {
+	seen := make(map[string]bool)
+	capabilities := make([]string, 0)
+	for _, agent := range s.registry.ListAgents() {
+		for _, capability := range agent.Capabilities {
+			if !seen[capability] {
+				seen[capability] = true
+				capabilities = append(capabilities, capability)
+			}
+		}
+	}
+	return capabilities
+}
+
+// newCompositeTask 根据分解结果构建复合任务，计算子任务的拓扑层级
+func newCompositeTask(req *CompositeTaskRequest, result *decomposer.DecompositionResult) (*CompositeTask, error) {
+	composite := &CompositeTask{
+		ID:          req.ID,
+		Type:        req.Type,
+		Description: req.Description,
+		Input:       req.Input,
+		Strategy:    result.Strategy,
+		Status:      CompositeStatusRunning,
+		SubTasks:    make([]*CompositeSubTask, 0, len(result.SubTasks)),
+		CreatedAt:   time.Now(),
+		priority:    req.Priority,
+	}
+
+	// 简单任务不分解，唯一的子任务沿用父任务ID，改名以区分父子结果
+	ids := make(map[string]bool)
+	for _, st := range result.SubTasks {
+		if st.ID == req.ID {
+			st.ID = req.ID + "-sub-0"
+		}
+		ids[st.ID] = true
+	}
+
+	graph := decomposer.NewDependencyGraph()
+	for _, st := range result.SubTasks {
+		sub := &CompositeSubTask{
+			ID:           st.ID,
+			Type:         st.Type,
+			Description:  st.Description,
+			Dependencies: make([]string, 0),
+			Capabilities: st.Capabilities,
+			Status:       SubTaskStatusWaiting,
+			input:        st.Input,
+			priority:     st.Priority,
+		}
+		// 没有指定能力的子任务（例如按优先级分解的阶段）沿用父任务的能力
+		if len(sub.Capabilities) == 0 {
+			sub.Capabilities = req.Capabilities
+		}
+		if sub.input == nil {
+			sub.input = req.Input
+		}
+		if sub.priority <= 0 {
+			sub.priority = req.Priority
+		}
+
+		// 只保留图内的依赖，指向外部任务的依赖由分解出的子任务自己处理
+		graph.AddNode(st.ID)
+		for _, dep := range st.Dependencies {
+			if ids[dep] {
+				sub.Dependencies = append(sub.Dependencies, dep)
+				graph.AddEdge(dep, st.ID, 1)
+			}
+		}
+
+		composite.SubTasks = append(composite.SubTasks, sub)
+	}
+
+	if err := graph.CalculateLevels(); err != nil {
+		return nil, fmt.Errorf("invalid dependency graph: %w", err)
+	}
+	for _, sub := range composite.SubTasks {
+		sub.Level = graph.GetLevel(sub.ID)
+	}
+	composite.Levels = graph.GetParallelTasks()
+	for _, level := range composite.Levels {
+		sort.Strings(level)
+	}
+
+	return composite, nil
+}
+
+// advanceComposite 提交依赖已全部完成的子任务，所有子任务完成后聚合父任务结果。
+// 状态转换在compositeMu内决定，调度器、聚合器和事件发布在释放锁之后调用
+func (s *Server) advanceComposite(compositeID string) {
+	s.compositeMu.Lock()
+	composite, exists := s.composites[compositeID]
+	if !exists || composite.Status != CompositeStatusRunning {
+		s.compositeMu.Unlock()
+		return
+	}
+
+	ready := make([]*scheduler.Task, 0)
+	completed := 0
+	for _, sub := range composite.SubTasks {
+		switch {
+		case sub.Status == SubTaskStatusCompleted:
+			completed++
+		case sub.Status == SubTaskStatusWaiting && composite.dependenciesCompleted(sub):
+			// 先标记为已提交，并发的advanceComposite不会重复提交
+			sub.Status = SubTaskStatusSubmitted
+			ready = append(ready, composite.schedulerTask(sub))
+		}
+	}
+	finished := completed == len(composite.SubTasks)
+	s.compositeMu.Unlock()
+
+	if finished {
+		s.finishComposite(compositeID)
+		return
+	}
+
+	for i, task := range ready {
+		if err := s.taskManager.SubmitTask(task); err != nil {
+			// 未提交的子任务退回等待状态，失败处理时直接取消
+			s.compositeMu.Lock()
+			for _, unsubmitted := range ready[i:] {
+				if sub := composite.subTask(unsubmitted.ID); sub.Status == SubTaskStatusSubmitted {
+					sub.Status = SubTaskStatusWaiting
+				}
+			}
+			s.compositeMu.Unlock()
+
+			s.failComposite(compositeID, fmt.Sprintf("failed to submit sub-task %s: %v", task.ID, err))
+			return
+		}
+
+		// 提交期间复合任务已失败，取消刚提交的子任务
+		s.compositeMu.Lock()
+		cancelled := composite.subTask(task.ID).Status == SubTaskStatusCancelled
+		s.compositeMu.Unlock()
+		if cancelled {
+			s.cancelSubTask(task.ID, "composite task failed")
+			continue
+		}
+
+		s.broadcastTaskStatus(task.ID)
+		go s.tryAllocateTask(task.ID)
+	}
+	s.publishCompositeUpdate(compositeID)
+}
+
+// dependenciesCompleted 检查子任务的依赖是否都已完成
+func (c *CompositeTask) dependenciesCompleted(sub *CompositeSubTask) bool {
+	for _, dep := range sub.Dependencies {
+		if upstream := c.subTask(dep); upstream == nil || upstream.Status != SubTaskStatusCompleted {
+			return false
+		}
+	}
+	return true
+}
+
+// schedulerTask 把子任务转换为调度器任务，上游子任务的输出作为输入的一部分
+func (c *CompositeTask) schedulerTask(sub *CompositeSubTask) *scheduler.Task {
+	upstream := make(map[string]interface{}, len(sub.Dependencies))
+	for _, dep := range sub.Dependencies {
+		upstream[dep] = c.subTask(dep).Output
+	}
+
+	return &scheduler.Task{
+		ID:                   sub.ID,
+		Type:                 sub.Type,
+		Priority:             sub.priority,
+		RequiredCapabilities: sub.Capabilities,
+		Metadata: map[string]interface{}{
+			"description": sub.Description,
+			"created_at":  time.Now().Format(time.RFC3339),
+			"parent_id":   c.ID,
+			"input": map[string]interface{}{
+				"description": sub.Description,
+				"input":       sub.input,
+				"upstream":    upstream,
+			},
+		},
+	}
+}
+
+// subTask 按ID查找子任务
+func (c *CompositeTask) subTask(id string) *CompositeSubTask {
+	for _, sub := range c.SubTasks {
+		if sub.ID == id {
+			return sub
+		}
+	}
+	return nil
+}
+
+// event 复合任务进度事件的负载
+func (c *CompositeTask) event() map[string]interface{} {
+	completed := 0
+	for _, sub := range c.SubTasks {
+		if sub.Status == SubTaskStatusCompleted {
+			completed++
+		}
+	}
+	return map[string]interface{}{
+		"composite_id": c.ID,
+		"status":       c.Status,
+		"completed":    completed,
+		"total":        len(c.SubTasks),
+		"error":        c.Error,
+	}
+}
+
+// completeSubTask 记录子任务结果并作为父任务结果的一部分，然后提交下游子任务
+func (s *Server) completeSubTask(taskID string, result *aggregator.TaskResult) {
+	s.compositeMu.Lock()
+	composite, sub := s.compositeOf(taskID)
+	if sub == nil || sub.Status != SubTaskStatusSubmitted {
+		s.compositeMu.Unlock()
+		return
+	}
+	compositeID := composite.ID
+	s.compositeMu.Unlock()
+
+	// 先加入父任务结果再标记完成，聚合时不会遗漏该子任务的结果
+	parentResult := &aggregator.TaskResult{
+		ID:        compositeID + "/" + taskID,
+		TaskID:    compositeID,
+		AgentID:   result.AgentID,
+		Data:      result.Data,
+		Score:     result.Score,
+		CreatedAt: time.Now(),
+	}
+	if err := s.aggregator.AddResult(parentResult); err != nil {
+		log.Printf("Result of sub-task %s rejected for composite %s: %v", taskID, compositeID, err)
+	}
+
+	s.compositeMu.Lock()
+	if sub.Status != SubTaskStatusSubmitted {
+		s.compositeMu.Unlock()
+		return
+	}
+	sub.Status = SubTaskStatusCompleted
+	sub.Output = result.Data
+	s.compositeMu.Unlock()
+
+	s.advanceComposite(compositeID)
+}
+
+// failSubTask 子任务失败时复合任务失败，取消其他未完成的子任务
+func (s *Server) failSubTask(taskID, reason string) {
+	s.compositeMu.Lock()
+	composite, sub := s.compositeOf(taskID)
+	if sub == nil || composite.Status != CompositeStatusRunning {
+		s.compositeMu.Unlock()
+		return
+	}
+	sub.Status = SubTaskStatusFailed
+	compositeID := composite.ID
+	s.compositeMu.Unlock()
+
+	s.failComposite(compositeID, fmt.Sprintf("sub-task %s failed: %s", taskID, reason))
+}
+
+// compositeOf 查找子任务所属的复合任务，调用方需持有compositeMu
+func (s *Server) compositeOf(taskID string) (*CompositeTask, *CompositeSubTask) {
+	compositeID, exists := s.subTaskParents[taskID]
+	if !exists {
+		return nil, nil
+	}
+	composite := s.composites[compositeID]
+	return composite, composite.subTask(taskID)
+}
+
+// failComposite 标记复合任务失败，取消未完成的子任务并通知执行它们的Agent
+func (s *Server) failComposite(compositeID, reason string) {
+	s.compositeMu.Lock()
+	composite, exists := s.composites[compositeID]
+	if !exists || composite.Status != CompositeStatusRunning {
+		s.compositeMu.Unlock()
+		return
+	}
+
+	submitted := make([]string, 0)
+	for _, sub := range composite.SubTasks {
+		switch sub.Status {
+		case SubTaskStatusSubmitted:
+			submitted = append(submitted, sub.ID)
+			sub.Status = SubTaskStatusCancelled
+		case SubTaskStatusWaiting:
+			sub.Status = SubTaskStatusCancelled
+		}
+	}
+
+	now := time.Now()
+	composite.Status = CompositeStatusFailed
+	composite.Error = reason
+	composite.CompletedAt = &now
+	s.compositeMu.Unlock()
+
+	log.Printf("Composite task %s failed: %s", compositeID, reason)
+	for _, taskID := range submitted {
+		s.cancelSubTask(taskID, reason)
+	}
+	s.publishCompositeUpdate(compositeID)
+}
+
+// cancelSubTask 取消调度器中的子任务，已分配给Agent的子任务发送TASK_CANCEL通知Agent停止执行
+func (s *Server) cancelSubTask(taskID, reason string) {
+	agentID, assignErr := s.taskManager.GetAssignment(taskID)
+	if err := s.taskManager.CancelTask(taskID); err != nil {
+		log.Printf("Failed to cancel sub-task %s: %v", taskID, err)
+		return
+	}
+	s.broadcastTaskStatus(taskID)
+	if assignErr != nil {
+		return
+	}
+
+	msg := protocol.NewMessage(protocol.MessageTypeTaskCancel, protocol.ServerID, agentID)
+	msg.SetPayload(&protocol.TaskCancelPayload{TaskID: taskID, Reason: reason})
+	if err := s.wsServer.SendMessage(msg); err != nil {
+		log.Printf("Failed to send cancellation of task %s to agent %s: %v", taskID, agentID, err)
+	}
+}
+
+// finishComposite 聚合子任务结果作为父任务结果
+func (s *Server) finishComposite(compositeID string) {
+	aggregated, err := s.aggregator.AggregateTask(compositeID)
+	if err != nil {
+		s.failComposite(compositeID, fmt.Sprintf("failed to aggregate results: %v", err))
+		return
+	}
+
+	s.compositeMu.Lock()
+	composite, exists := s.composites[compositeID]
+	if !exists || composite.Status != CompositeStatusRunning {
+		s.compositeMu.Unlock()
+		return
+	}
+	now := time.Now()
+	composite.Status = CompositeStatusCompleted
+	composite.Result = aggregated
+	composite.CompletedAt = &now
+	s.compositeMu.Unlock()
+
+	log.Printf("Composite task %s completed, confidence: %.2f", compositeID, aggregated.Confidence)
+	s.broadcastAggregatedResult(aggregated)
+	s.publishCompositeUpdate(compositeID)
+}
+
+// publishCompositeUpdate 发布复合任务进度事件
+func (s *Server) publishCompositeUpdate(compositeID string) {
+	s.compositeMu.Lock()
+	composite, exists := s.composites[compositeID]
+	if !exists {
+		s.compositeMu.Unlock()
+		return
+	}
+	event := composite.event()
+	topic := taskTopic(composite.Type, protocol.EventCompositeTaskUpdate)
+	s.compositeMu.Unlock()
+
+	s.publishEvent(topic, protocol.EventCompositeTaskUpdate, event, false)
+}
+
+// evictComposites 移除结束超过compositeRetention的复合任务及其子任务索引
+func (s *Server) evictComposites(now time.Time) {
+	s.compositeMu.Lock()
+	defer s.compositeMu.Unlock()
+
+	for id, composite := range s.composites {
+		if composite.CompletedAt == nil || now.Sub(*composite.CompletedAt) < s.compositeRetention {
+			continue
+		}
+		for _, sub := range composite.SubTasks {
+			delete(s.subTaskParents, sub.ID)
+		}
+		delete(s.composites, id)
+	}
+}
+
+// handleCompositeTasksAPI 复合任务列表API
+func (s *Server) handleCompositeTasksAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		s.compositeMu.Lock()
+		composites := make([]*CompositeTask, 0, len(s.composites))
+		for _, composite := range s.composites {
+			composites = append(composites, composite)
+		}
+		sort.Slice(composites, func(i, j int) bool {
+			return composites[i].CreatedAt.Before(composites[j].CreatedAt)
+		})
+		data, err := json.Marshal(composites)
+		s.compositeMu.Unlock()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Write(data)
+
+	case "POST":
+		var req CompositeTaskRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		composite, err := s.createCompositeTask(&req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		s.compositeMu.Lock()
+		data, err := json.Marshal(composite)
+		s.compositeMu.Unlock()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write(data)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleCompositeTaskAPI 单个复合任务API
+func (s *Server) handleCompositeTaskAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	// 提取复合任务ID
+	compositeID := r.URL.Path[len("/api/composite-tasks/"):]
+
+	s.compositeMu.Lock()
+	composite, exists := s.composites[compositeID]
+	var data []byte
+	var err error
+	if exists {
+		data, err = json.Marshal(composite)
+	}
+	s.compositeMu.Unlock()
+
+	if !exists {
+		http.Error(w, "Composite task not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write(data)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/agent-learning/multi-agent/internal/communication"
+	"github.com/agent-learning/multi-agent/protocol"
+)
+
+// handleDeadLetter 处理无法投递的消息：通知控制台，未送达的任务请求按Agent故障处理
+func (s *Server) handleDeadLetter(letter *communication.DeadLetter) {
+	msg := letter.Message
+	log.Printf("Message %s (%s) to %s dead-lettered after %d attempts: %s",
+		msg.MessageID, msg.Type, msg.To, letter.Attempts, letter.Reason)
+
+	s.publishEvent(messageTopic(protocol.EventMessageDeadLettered), protocol.EventMessageDeadLettered, map[string]interface{}{
+		"message_id": msg.MessageID,
+		"type":       msg.Type,
+		"to":         msg.To,
+		"reason":     letter.Reason,
+		"attempts":   letter.Attempts,
+	}, false)
+
+	if msg.Type != protocol.MessageTypeTaskRequest {
+		return
+	}
+
+	// Agent收不到仍分配给它的任务，收回其任务重新分配
+	var payload protocol.TaskRequestPayload
+	if msg.GetPayload(&payload) == nil && s.isAssignedTo(payload.TaskID, msg.To) {
+		go s.handleAgentFailure(msg.To, "task request undeliverable")
+	}
+}
+
+// handleDeadLettersAPI 死信列表API
+func (s *Server) handleDeadLettersAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	switch r.Method {
+	case "GET":
+		letters := s.wsServer.GetReliableSender().GetDeadLetters().List()
+		json.NewEncoder(w).Encode(letters)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleDeadLetterAPI 单个死信API，POST /api/dead-letters/{id}/retry 重新投递
+func (s *Server) handleDeadLetterAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// 提取消息ID
+	messageID := r.URL.Path[len("/api/dead-letters/"):]
+	sender := s.wsServer.GetReliableSender()
+
+	if strings.HasSuffix(messageID, "/retry") {
+		if r.Method != "POST" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := sender.Redeliver(strings.TrimSuffix(messageID, "/retry")); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		letter, err := sender.GetDeadLetters().Get(messageID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(letter)
+
+	case "DELETE":
+		if _, err := sender.GetDeadLetters().Remove(messageID); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
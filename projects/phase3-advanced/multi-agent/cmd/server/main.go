@@ -2,33 +2,53 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/agent-learning/multi-agent/internal/aggregator"
 	"github.com/agent-learning/multi-agent/internal/communication"
 	"github.com/agent-learning/multi-agent/internal/scheduler"
+	decomposer "github.com/agent-learning/multi-agent/internal/task-decomposer"
+	"github.com/agent-learning/multi-agent/protocol"
 )
 
 // Server 主服务器
 type Server struct {
+	config      *communication.WebSocketConfig
 	wsServer    *communication.WebSocketServer
 	registry    *scheduler.AgentRegistry
 	taskManager *scheduler.TaskManager
 	aggregator  *aggregator.ResultAggregator
-	allocator   *scheduler.TaskAllocator
+
+	publicKeys map[string]string // agentID -> base64 X25519公钥
+	keysMu     sync.RWMutex
+
+	heartbeatTimeout time.Duration // 超过该时间没有心跳的Agent视为故障
+	reliableDelivery bool          // 任务请求要求Agent确认，未确认时重传
+	stop             chan struct{}
+
+	reconnectGrace time.Duration          // 断开的Agent在该时间内重连时保留其任务
+	disconnects    map[string]*time.Timer // agentID -> 等待重连的计时器
+	disconnectMu   sync.Mutex
+
+	composites         map[string]*CompositeTask // compositeID -> 复合任务
+	subTaskParents     map[string]string         // 子任务ID -> compositeID
+	compositeRetention time.Duration             // 结束的复合任务保留多久后移除
+	compositeMu        sync.Mutex
+
+	planner *decomposer.Planner // PLANNER分解策略使用的规划器，为nil时回退到规则策略
 }
 
 // NewServer 创建服务器
-func NewServer() *Server {
+func NewServer(wsConfig *communication.WebSocketConfig) *Server {
 	// 创建WebSocket服务器
-	wsConfig := communication.DefaultWebSocketConfig()
-	wsConfig.Port = 8080
 	wsServer := communication.NewWebSocketServer(wsConfig)
 
 	// 创建Agent注册表
@@ -38,7 +58,7 @@ func NewServer() *Server {
 	taskQueue := scheduler.NewTaskQueue(100)
 
 	// 创建任务分配器
-	allocator := scheduler.NewTaskAllocator(registry, scheduler.AllocationPolicyLoadBalance)
+	allocator := scheduler.NewTaskAllocator(registry, scheduler.StrategyLoadBalance)
 
 	// 创建任务管理器
 	taskManager := scheduler.NewTaskManager(taskQueue, allocator)
@@ -59,32 +79,54 @@ func NewServer() *Server {
 		MaxScore: 100,
 	})
 
-	return &Server{
+	s := &Server{
+		config:      wsConfig,
 		wsServer:    wsServer,
 		registry:    registry,
 		taskManager: taskManager,
 		aggregator:  agg,
-		allocator:   allocator,
+		publicKeys:  make(map[string]string),
+
+		heartbeatTimeout: 90 * time.Second,
+		stop:             make(chan struct{}),
+
+		reconnectGrace: 30 * time.Second,
+		disconnects:    make(map[string]*time.Timer),
+
+		composites:         make(map[string]*CompositeTask),
+		subTaskParents:     make(map[string]string),
+		compositeRetention: time.Hour,
 	}
-}
 
-// Start 启动服务器
-func (s *Server) Start() error {
+	// Agent断开连接且未及时重连时收回其任务
+	wsServer.OnDisconnect(s.handleAgentDisconnect)
+
+	// 无法投递的消息进入死信
+	wsServer.GetReliableSender().OnDeadLetter(s.handleDeadLetter)
+
 	// 注册WebSocket消息处理器
 	s.registerMessageHandlers()
 
 	// 注册HTTP API路由
 	s.registerHTTPHandlers()
 
+	return s
+}
+
+// Start 启动服务器
+func (s *Server) Start() error {
 	// 启动WebSocket服务器
 	if err := s.wsServer.Start(); err != nil {
 		return fmt.Errorf("failed to start WebSocket server: %w", err)
 	}
 
+	// 启动Agent心跳监控
+	go s.monitorAgents()
+
 	log.Println("🚀 Multi-Agent Server started")
-	log.Printf("   WebSocket: ws://localhost:%d/ws", wsConfig.Port)
-	log.Printf("   Web UI: http://localhost:%d", wsConfig.Port)
-	log.Printf("   API: http://localhost:%d/api", wsConfig.Port)
+	log.Printf("   WebSocket: ws://localhost:%d/ws", s.config.Port)
+	log.Printf("   Web UI: http://localhost:%d", s.config.Port)
+	log.Printf("   API: http://localhost:%d/api", s.config.Port)
 
 	return nil
 }
@@ -92,39 +134,180 @@ func (s *Server) Start() error {
 // Stop 停止服务器
 func (s *Server) Stop() error {
 	log.Println("Stopping server...")
+	close(s.stop)
 	return s.wsServer.Stop()
 }
 
+// monitorAgents 定期检查Agent心跳，超时的Agent按故障处理，并移除过期的复合任务
+func (s *Server) monitorAgents() {
+	ticker := time.NewTicker(s.heartbeatTimeout / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			for _, agentID := range s.registry.CheckHeartbeat(s.heartbeatTimeout) {
+				s.handleAgentFailure(agentID, "heartbeat timeout")
+			}
+			s.evictComposites(time.Now())
+		}
+	}
+}
+
+// handleAgentDisconnect Agent断开后标记为离线，不再分配新任务。reconnectGrace内重连时
+// 保留其任务和信箱中的任务请求；仍未重连时按故障处理。未注册的连接（例如Web控制台）删除信箱
+func (s *Server) handleAgentDisconnect(agentID string) {
+	agent, err := s.registry.GetAgent(agentID)
+	if err != nil {
+		if mailboxes := s.wsServer.GetMailboxes(); mailboxes != nil {
+			mailboxes.Remove(agentID)
+		}
+		return
+	}
+
+	if s.reconnectGrace <= 0 {
+		s.handleAgentFailure(agentID, "disconnected")
+		return
+	}
+
+	log.Printf("Agent %s disconnected, waiting %s for it to reconnect", agentID, s.reconnectGrace)
+	s.registry.UpdateAgentStatus(agentID, scheduler.AgentStatusOffline)
+	s.broadcastAgentUpdate(protocol.EventAgentStatusUpdate, agent)
+
+	s.disconnectMu.Lock()
+	defer s.disconnectMu.Unlock()
+
+	if timer, exists := s.disconnects[agentID]; exists {
+		timer.Stop()
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(s.reconnectGrace, func() {
+		s.disconnectMu.Lock()
+		current := s.disconnects[agentID] == timer
+		if current {
+			delete(s.disconnects, agentID)
+		}
+		s.disconnectMu.Unlock()
+
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+		if !current {
+			return
+		}
+		if _, err := s.wsServer.GetConnectionManager().GetConnectionByAgent(agentID); err == nil {
+			return
+		}
+		s.handleAgentFailure(agentID, "disconnected")
+	})
+	s.disconnects[agentID] = timer
+}
+
+// cancelDisconnect 停止等待Agent重连的计时器
+func (s *Server) cancelDisconnect(agentID string) {
+	s.disconnectMu.Lock()
+	defer s.disconnectMu.Unlock()
+
+	if timer, exists := s.disconnects[agentID]; exists {
+		timer.Stop()
+		delete(s.disconnects, agentID)
+	}
+}
+
+// handleAgentFailure 将Agent标记为故障，收回其未完成的任务并重新分配
+func (s *Server) handleAgentFailure(agentID, reason string) {
+	agent, err := s.registry.GetAgent(agentID)
+	if err != nil {
+		// 未注册的连接（例如Web控制台）
+		return
+	}
+
+	log.Printf("Agent %s failed: %s", agentID, reason)
+	s.registry.UpdateAgentStatus(agentID, scheduler.AgentStatusError)
+	s.broadcastAgentUpdate(protocol.EventAgentStatusUpdate, agent)
+
+	// 任务将重新分配，不再重传或从信箱投递发给该Agent的任务请求
+	s.wsServer.GetReliableSender().CancelPending(agentID)
+	if mailboxes := s.wsServer.GetMailboxes(); mailboxes != nil {
+		mailboxes.Discard(agentID, func(msg *protocol.Message) bool {
+			return msg.Type == protocol.MessageTypeTaskRequest
+		})
+	}
+
+	for _, r := range s.taskManager.ReassignAgentTasks(agentID) {
+		log.Printf("Task %s reclaimed from agent %s (reassignments: %d, requeued: %v)",
+			r.TaskID, agentID, r.Count, r.Requeued)
+
+		topic := taskTopic(s.taskTypeOf(r.TaskID), protocol.EventTaskReassigned)
+		s.publishEvent(topic, protocol.EventTaskReassigned, map[string]interface{}{
+			"task_id":       r.TaskID,
+			"from_agent":    r.FromAgent,
+			"reassignments": r.Count,
+			"requeued":      r.Requeued,
+			"reason":        reason,
+		}, false)
+
+		if r.Requeued {
+			go s.tryAllocateTask(r.TaskID)
+		} else {
+			s.broadcastTaskStatus(r.TaskID)
+			s.failSubTask(r.TaskID, "exceeded maximum reassignments")
+		}
+	}
+}
+
+// allocatePendingTasks 尝试分配所有等待中的任务，例如因没有可用Agent而被搁置的重新分配任务
+func (s *Server) allocatePendingTasks() {
+	for _, task := range s.taskManager.ListTasksByStatus(scheduler.TaskStatusPending) {
+		s.tryAllocateTask(task.ID)
+	}
+}
+
 // registerMessageHandlers 注册WebSocket消息处理器
 func (s *Server) registerMessageHandlers() {
+	// Web控制台连接
+	s.wsServer.RegisterMessageHandler(protocol.MessageTypeClientConnect, s.handleClientConnect)
+
 	// Agent注册
-	s.wsServer.RegisterMessageHandler("AGENT_REGISTER", s.handleAgentRegister)
+	s.wsServer.RegisterMessageHandler(protocol.MessageTypeAgentRegister, s.handleAgentRegister)
 
 	// Agent心跳
-	s.wsServer.RegisterMessageHandler("HEARTBEAT", s.handleHeartbeat)
+	s.wsServer.RegisterMessageHandler(protocol.MessageTypeHeartbeat, s.handleHeartbeat)
 
 	// 任务结果提交
-	s.wsServer.RegisterMessageHandler("TASK_RESULT", s.handleTaskResult)
+	s.wsServer.RegisterMessageHandler(protocol.MessageTypeTaskComplete, s.handleTaskComplete)
+	s.wsServer.RegisterMessageHandler(protocol.MessageTypeTaskFailed, s.handleTaskFailed)
 
-	// 任务状态更新
-	s.wsServer.RegisterMessageHandler("TASK_STATUS", s.handleTaskStatus)
+	// 任务进度更新
+	s.wsServer.RegisterMessageHandler(protocol.MessageTypeTaskProgress, s.handleTaskProgress)
+
+	// 公钥查询
+	s.wsServer.RegisterMessageHandler(protocol.MessageTypeKeyExchange, s.handleKeyExchange)
 }
 
 // registerHTTPHandlers 注册HTTP API处理器
 func (s *Server) registerHTTPHandlers() {
 	// 静态文件
-	http.HandleFunc("/", s.handleIndex)
-	http.Handle("/css/", http.StripPrefix("/css/", http.FileServer(http.Dir("./web/css"))))
-	http.Handle("/js/", http.StripPrefix("/js/", http.FileServer(http.Dir("./web/js"))))
+	s.wsServer.HandleFunc("/", s.handleIndex)
+	s.wsServer.Handle("/css/", http.StripPrefix("/css/", http.FileServer(http.Dir("./web/css"))))
+	s.wsServer.Handle("/js/", http.StripPrefix("/js/", http.FileServer(http.Dir("./web/js"))))
 
 	// API路由
-	http.HandleFunc("/api/agents", s.handleAgentsAPI)
-	http.HandleFunc("/api/agents/", s.handleAgentAPI)
-	http.HandleFunc("/api/tasks", s.handleTasksAPI)
-	http.HandleFunc("/api/tasks/", s.handleTaskAPI)
-	http.HandleFunc("/api/results", s.handleResultsAPI)
-	http.HandleFunc("/api/results/", s.handleResultAPI)
-	http.HandleFunc("/api/results/aggregate/", s.handleAggregateResultAPI)
+	s.wsServer.HandleFunc("/api/agents", s.handleAgentsAPI)
+	s.wsServer.HandleFunc("/api/agents/", s.handleAgentAPI)
+	s.wsServer.HandleFunc("/api/tasks", s.handleTasksAPI)
+	s.wsServer.HandleFunc("/api/tasks/", s.handleTaskAPI)
+	s.wsServer.HandleFunc("/api/composite-tasks", s.handleCompositeTasksAPI)
+	s.wsServer.HandleFunc("/api/composite-tasks/", s.handleCompositeTaskAPI)
+	s.wsServer.HandleFunc("/api/results", s.handleResultsAPI)
+	s.wsServer.HandleFunc("/api/results/", s.handleResultAPI)
+	s.wsServer.HandleFunc("/api/results/aggregate/", s.handleAggregateResultAPI)
+	s.wsServer.HandleFunc("/api/dead-letters", s.handleDeadLettersAPI)
+	s.wsServer.HandleFunc("/api/dead-letters/", s.handleDeadLetterAPI)
 }
 
 // handleIndex 首页处理
@@ -170,7 +353,7 @@ func (s *Server) handleAgentsAPI(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// 广播Agent注册消息
-		s.broadcastAgentUpdate("AGENT_REGISTERED", &agent)
+		s.broadcastAgentUpdate(protocol.EventAgentRegistered, &agent)
 
 		w.WriteHeader(http.StatusCreated)
 		json.NewEncoder(w).Encode(agent)
@@ -208,6 +391,11 @@ func (s *Server) handleAgentAPI(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
 		}
+		if mailboxes := s.wsServer.GetMailboxes(); mailboxes != nil {
+			mailboxes.Remove(agentID)
+		}
+		s.wsServer.GetRouter().ClearRetained(agentTopics(agentID))
+		s.wsServer.GetRouter().ClearRetainedBy(agentID)
 		w.WriteHeader(http.StatusNoContent)
 
 	default:
@@ -230,31 +418,36 @@ func (s *Server) handleTasksAPI(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case "GET":
 		tasks := s.taskManager.ListTasks()
-		json.NewEncoder(w).Encode(tasks)
+		webTasks := make([]*WebTask, 0, len(tasks))
+		for _, task := range tasks {
+			webTasks = append(webTasks, FromSchedulerTask(task))
+		}
+		json.NewEncoder(w).Encode(webTasks)
 
 	case "POST":
-		var task scheduler.Task
-		if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
+		var webTask WebTask
+		if err := json.NewDecoder(r.Body).Decode(&webTask); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		task.Status = string(scheduler.TaskStatusPending)
-		task.CreatedAt = time.Now()
+		webTask.CreatedAt = time.Now()
+		task := webTask.ToSchedulerTask()
 
-		if err := s.taskManager.AddTask(&task); err != nil {
+		if err := s.taskManager.SubmitTask(task); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		// 尝试分配任务
-		go s.tryAllocateTask(&task)
-
 		// 广播任务创建消息
-		s.broadcastTaskUpdate("TASK_CREATED", &task)
+		s.broadcastTaskUpdate(protocol.EventTaskCreated, task)
+		created := FromSchedulerTask(task)
+
+		// 尝试分配任务
+		go s.tryAllocateTask(task.ID)
 
 		w.WriteHeader(http.StatusCreated)
-		json.NewEncoder(w).Encode(task)
+		json.NewEncoder(w).Encode(created)
 
 	default:
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -271,12 +464,12 @@ func (s *Server) handleTaskAPI(w http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case "GET":
-		task := s.taskManager.GetTask(taskID)
-		if task == nil {
-			http.Error(w, "Task not found", http.StatusNotFound)
+		task, err := s.taskManager.GetTask(taskID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
 			return
 		}
-		json.NewEncoder(w).Encode(task)
+		json.NewEncoder(w).Encode(FromSchedulerTask(task))
 
 	default:
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -342,96 +535,215 @@ func (s *Server) handleAggregateResultAPI(w http.ResponseWriter, r *http.Request
 	}
 }
 
+// handleClientConnect 处理Web控制台连接消息
+func (s *Server) handleClientConnect(msg *protocol.Message) error {
+	var payload protocol.ClientConnectPayload
+	if err := msg.GetPayload(&payload); err != nil {
+		return err
+	}
+
+	log.Printf("Client %s connected (type: %s)", msg.From, payload.ClientType)
+	return nil
+}
+
 // handleAgentRegister 处理Agent注册消息
-func (s *Server) handleAgentRegister(msg *communication.Message) error {
+func (s *Server) handleAgentRegister(msg *protocol.Message) error {
 	log.Printf("Agent registration from: %s", msg.From)
 
+	var payload protocol.AgentRegisterPayload
+	if err := msg.GetPayload(&payload); err != nil {
+		return err
+	}
+
 	agent := &scheduler.Agent{
 		ID:            msg.From,
-		Name:          fmt.Sprintf("Agent-%s", msg.From),
-		Capabilities:  []string{},
+		Name:          payload.Name,
+		Capabilities:  payload.Capabilities,
 		Status:        scheduler.AgentStatusIdle,
-		MaxTasks:      5,
-		RegisteredAt:   time.Now(),
+		MaxTasks:      payload.MaxTasks,
+		Metadata:      payload.Metadata,
+		RegisteredAt:  time.Now(),
 		LastHeartbeat: time.Now(),
 	}
-
-	// 从payload中提取信息
-	if name, ok := msg.Payload["name"].(string); ok {
-		agent.Name = name
+	if agent.MaxTasks <= 0 {
+		agent.MaxTasks = 5
 	}
-	if caps, ok := msg.Payload["capabilities"].([]interface{}); ok {
-		for _, cap := range caps {
-			if capStr, ok := cap.(string); ok {
-				agent.Capabilities = append(agent.Capabilities, capStr)
-			}
+
+	if payload.EncryptionKey != "" {
+		if _, err := protocol.ParsePublicKey(payload.EncryptionKey); err != nil {
+			return protocol.NewError(protocol.ErrorTypeValidation, "INVALID_ENCRYPTION_KEY", err.Error())
+		}
+		if err := s.checkKeyRotation(msg.From, payload.EncryptionKey); err != nil {
+			return err
 		}
 	}
-	if maxTasks, ok := msg.Payload["max_tasks"].(float64); ok {
-		agent.MaxTasks = int(maxTasks)
+
+	// 客户端每次重连都重新注册。已注册的Agent保留执行中的任务和计数，只更新元数据，
+	// 并停止等待其重连的计时器
+	if _, err := s.registry.GetAgent(msg.From); err == nil {
+		existing, err := s.registry.Reregister(agent)
+		if err != nil {
+			return protocol.NewError(protocol.ErrorTypeValidation, "REGISTRATION_FAILED", err.Error())
+		}
+		s.cancelDisconnect(msg.From)
+		log.Printf("Agent %s re-registered with %d running tasks", msg.From, existing.CurrentTasks)
+		s.broadcastAgentUpdate(protocol.EventAgentStatusUpdate, existing)
+	} else {
+		if err := s.registry.Register(agent); err != nil {
+			return protocol.NewError(protocol.ErrorTypeValidation, "REGISTRATION_FAILED", err.Error())
+		}
+
+		// 广播Agent注册事件
+		s.broadcastAgentUpdate(protocol.EventAgentRegistered, agent)
 	}
 
-	if err := s.registry.Register(agent); err != nil {
-		log.Printf("Failed to register agent: %v", err)
-		return err
+	// 新Agent可以接手等待中的任务
+	go s.allocatePendingTasks()
+
+	// 登记公钥并通知其他Agent，使它们可以加密发给该Agent的消息
+	if payload.EncryptionKey != "" {
+		s.keysMu.Lock()
+		s.publicKeys[msg.From] = payload.EncryptionKey
+		s.keysMu.Unlock()
+
+		announce := protocol.NewMessage(protocol.MessageTypeKeyExchange, protocol.ServerID, "broadcast")
+		announce.SetPayload(&protocol.KeyExchangePayload{AgentID: msg.From, PublicKey: payload.EncryptionKey})
+		if err := s.wsServer.BroadcastMessage(announce); err != nil {
+			log.Printf("Failed to announce key of agent %s: %v", msg.From, err)
+		}
 	}
 
-	// 广播Agent注册事件
-	s.broadcastAgentUpdate("AGENT_REGISTERED", agent)
+	return nil
+}
+
+// checkKeyRotation 已登记的公钥只能由签名验证通过的消息更换，
+// 否则任何以该ID连接的客户端都能替换公钥，截获发给该Agent的密文
+func (s *Server) checkKeyRotation(agentID, publicKey string) error {
+	s.keysMu.RLock()
+	current, exists := s.publicKeys[agentID]
+	s.keysMu.RUnlock()
+	if !exists || current == publicKey {
+		return nil
+	}
 
+	// 登记了签名密钥的Agent的消息在Hub入口已验证签名
+	if _, signed := s.wsServer.GetKeyStore().Get(agentID); !signed {
+		return protocol.NewError(protocol.ErrorTypeAuthentication, "KEY_ROTATION_UNAUTHENTICATED",
+			fmt.Sprintf("agent %s already registered an encryption key; rotating it requires a signed message", agentID))
+	}
 	return nil
 }
 
-// handleHeartbeat 处理心跳消息
-func (s *Server) handleHeartbeat(msg *communication.Message) error {
-	agentID := msg.From
+// handleKeyExchange 回复Agent查询的公钥
+func (s *Server) handleKeyExchange(msg *protocol.Message) error {
+	var payload protocol.KeyExchangePayload
+	if err := msg.GetPayload(&payload); err != nil {
+		return err
+	}
 
-	agent, err := s.registry.GetAgent(agentID)
-	if err != nil {
-		log.Printf("Heartbeat from unknown agent: %s", agentID)
-		return nil
+	s.keysMu.RLock()
+	publicKey, exists := s.publicKeys[payload.AgentID]
+	s.keysMu.RUnlock()
+	if !exists {
+		return protocol.NewError(protocol.ErrorTypeValidation, "UNKNOWN_KEY",
+			fmt.Sprintf("agent %s has not registered an encryption key", payload.AgentID))
 	}
 
-	// 更新心跳时间
-	agent.LastHeartbeat = time.Now()
+	reply := protocol.NewMessage(protocol.MessageTypeKeyExchange, protocol.ServerID, msg.From)
+	reply.SetPayload(&protocol.KeyExchangePayload{AgentID: payload.AgentID, PublicKey: publicKey})
+	return s.wsServer.SendMessage(reply)
+}
 
-	// 更新状态
-	if status, ok := msg.Payload["status"].(string); ok {
-		agent.Status = scheduler.AgentStatus(status)
+// handleHeartbeat 处理心跳消息
+func (s *Server) handleHeartbeat(msg *protocol.Message) error {
+	var payload protocol.HeartbeatPayload
+	if err := msg.GetPayload(&payload); err != nil {
+		return err
+	}
+
+	if err := s.registry.UpdateHeartbeat(msg.From); err != nil {
+		return protocol.NewError(protocol.ErrorTypeValidation, "UNKNOWN_AGENT", err.Error())
 	}
 
 	// 更新负载
-	if load, ok := msg.Payload["load"].(float64); ok {
-		agent.Load = load
+	if err := s.registry.UpdateAgentLoad(msg.From, payload.Load); err != nil {
+		return protocol.NewError(protocol.ErrorTypeValidation, "INVALID_LOAD", err.Error())
 	}
 
-	s.registry.UpdateAgent(agent)
+	// 更新状态，变化时通知控制台
+	agent, err := s.registry.GetAgent(msg.From)
+	if err != nil {
+		return err
+	}
+	status := agentStatus(payload.Status)
+	if agent.Status != status {
+		s.registry.UpdateAgentStatus(msg.From, status)
+		s.broadcastAgentUpdate(protocol.EventAgentStatusUpdate, agent)
+	}
 
 	return nil
 }
 
-// handleTaskResult 处理任务结果
-func (s *Server) handleTaskResult(msg *communication.Message) error {
+// handleTaskComplete 处理任务结果
+func (s *Server) handleTaskComplete(msg *protocol.Message) error {
 	log.Printf("Task result from %s", msg.From)
 
+	var payload protocol.TaskCompletePayload
+	if err := msg.GetPayload(&payload); err != nil {
+		return err
+	}
+
+	// 丢弃任务被收回后原Agent迟到的结果
+	if !s.isAssignedTo(payload.TaskID, msg.From) && s.taskManager.WasReassignedFrom(payload.TaskID, msg.From) {
+		log.Printf("Discarding late result for task %s from %s", payload.TaskID, msg.From)
+		stale := protocol.NewError(protocol.ErrorTypeValidation, "STALE_RESULT",
+			fmt.Sprintf("task %s was reassigned after %s failed", payload.TaskID, msg.From))
+		stale.Severity = protocol.ErrorSeverityWarning
+		return stale
+	}
+
 	// 构建TaskResult
 	result := &aggregator.TaskResult{
 		ID:        msg.MessageID,
-		TaskID:    msg.Payload["task_id"].(string),
+		TaskID:    payload.TaskID,
 		AgentID:   msg.From,
-		Data:      msg.Payload["data"].(map[string]interface{}),
-		Score:     msg.Payload["score"].(float64),
+		Data:      payload.Output,
+		Score:     payload.Score,
 		CreatedAt: time.Now(),
 	}
+	if result.Data == nil {
+		result.Data = make(map[string]interface{})
+	}
 
 	// 添加到聚合器
 	if err := s.aggregator.AddResult(result); err != nil {
-		log.Printf("Failed to add result: %v", err)
-		return err
+		return protocol.NewError(protocol.ErrorTypeValidation, "RESULT_REJECTED", err.Error())
 	}
 
 	// 广播结果提交事件
-	s.broadcastResultUpdate("RESULT_SUBMITTED", result)
+	s.broadcastResultUpdate(protocol.EventResultSubmitted, result)
+
+	// 更新发送方负责的任务
+	if s.isAssignedTo(payload.TaskID, msg.From) {
+		failed := payload.Status == protocol.TaskStatusFailed || payload.Status == protocol.TaskStatusTimeout
+		var err error
+		if failed {
+			err = s.taskManager.FailTask(payload.TaskID)
+		} else {
+			err = s.taskManager.CompleteTask(payload.TaskID)
+		}
+		if err != nil {
+			return protocol.NewError(protocol.ErrorTypeExecution, "TASK_UPDATE_FAILED", err.Error())
+		}
+		s.broadcastTaskStatus(payload.TaskID)
+
+		// 复合任务的子任务：推进依赖图
+		if failed {
+			s.failSubTask(payload.TaskID, string(payload.Status))
+		} else {
+			s.completeSubTask(payload.TaskID, result)
+		}
+	}
 
 	// 尝试聚合结果
 	go s.tryAggregateResults(result.TaskID)
@@ -439,61 +751,102 @@ func (s *Server) handleTaskResult(msg *communication.Message) error {
 	return nil
 }
 
-// handleTaskStatus 处理任务状态更新
-func (s *Server) handleTaskStatus(msg *communication.Message) error {
-	taskID := msg.Payload["task_id"].(string)
-	task := s.taskManager.GetTask(taskID)
-	if task == nil {
-		return fmt.Errorf("task not found: %s", taskID)
+// handleTaskFailed 处理任务失败
+func (s *Server) handleTaskFailed(msg *protocol.Message) error {
+	var payload protocol.TaskFailedPayload
+	if err := msg.GetPayload(&payload); err != nil {
+		return err
+	}
+
+	log.Printf("Task %s failed on %s: %s", payload.TaskID, msg.From, payload.ErrorMessage)
+
+	if !s.isAssignedTo(payload.TaskID, msg.From) {
+		return protocol.NewError(protocol.ErrorTypeValidation, "TASK_NOT_ASSIGNED",
+			fmt.Sprintf("task %s is not assigned to %s", payload.TaskID, msg.From))
 	}
 
-	// 更新状态
-	if status, ok := msg.Payload["status"].(string); ok {
-		task.Status = scheduler.TaskStatus(status)
+	if err := s.taskManager.FailTask(payload.TaskID); err != nil {
+		return protocol.NewError(protocol.ErrorTypeExecution, "TASK_UPDATE_FAILED", err.Error())
+	}
+
+	// 广播任务状态更新
+	s.broadcastTaskStatus(payload.TaskID)
+
+	s.failSubTask(payload.TaskID, payload.ErrorMessage)
+
+	return nil
+}
+
+// handleTaskProgress 处理任务进度更新
+func (s *Server) handleTaskProgress(msg *protocol.Message) error {
+	var payload protocol.TaskProgressPayload
+	if err := msg.GetPayload(&payload); err != nil {
+		return err
 	}
 
-	// 更新进度
-	if progress, ok := msg.Payload["progress"].(float64); ok {
-		task.Progress = int(progress)
+	if !s.isAssignedTo(payload.TaskID, msg.From) {
+		return protocol.NewError(protocol.ErrorTypeValidation, "TASK_NOT_ASSIGNED",
+			fmt.Sprintf("task %s is not assigned to %s", payload.TaskID, msg.From))
 	}
 
-	s.taskManager.UpdateTask(task)
+	if err := s.taskManager.UpdateProgress(payload.TaskID, payload.Progress); err != nil {
+		return protocol.NewError(protocol.ErrorTypeExecution, "TASK_UPDATE_FAILED", err.Error())
+	}
 
 	// 广播任务状态更新
-	s.broadcastTaskUpdate("TASK_STATUS_UPDATE", task)
+	s.broadcastTaskStatus(payload.TaskID)
 
 	return nil
 }
 
+// isAssignedTo 检查任务是否分配给了指定Agent
+func (s *Server) isAssignedTo(taskID, agentID string) bool {
+	assigned, err := s.taskManager.GetAssignment(taskID)
+	return err == nil && assigned == agentID
+}
+
 // tryAllocateTask 尝试分配任务
-func (s *Server) tryAllocateTask(task *scheduler.Task) {
-	agentID, err := s.allocator.AllocateTask(task)
+func (s *Server) tryAllocateTask(taskID string) {
+	agentID, err := s.taskManager.AssignTask(taskID)
+	if err != nil {
+		log.Printf("Failed to allocate task %s: %v", taskID, err)
+		return
+	}
+
+	task, err := s.taskManager.GetTask(taskID)
 	if err != nil {
-		log.Printf("Failed to allocate task %s: %v", task.ID, err)
+		log.Printf("Failed to load task %s: %v", taskID, err)
 		return
 	}
+	webTask := FromSchedulerTask(task)
 
-	task.AssignedTo = agentID
-	task.Status = scheduler.TaskStatusRunning
-	s.taskManager.UpdateTask(task)
+	// 复合任务的子任务带有包含上游结果的输入
+	input := interface{}(webTask.Description)
+	if taskInput, exists := task.Metadata["input"]; exists {
+		input = taskInput
+	}
 
 	// 发送任务给Agent
-	msg := communication.NewMessageBuilder().
-		SetType("TASK_REQUEST").
-		SetFrom("server").
-		SetTo(agentID).
-		AddPayloadField("task_id", task.ID).
-		AddPayloadField("task_type", task.Type).
-		AddPayloadField("priority", task.Priority).
-		AddPayloadField("description", task.Description).
-		Build()
-
-	s.wsServer.SendMessage(msg)
+	msg := protocol.NewMessage(protocol.MessageTypeTaskRequest, protocol.ServerID, agentID)
+	msg.RequireAck = s.reliableDelivery
+	msg.SetPayload(&protocol.TaskRequestPayload{
+		TaskID:   task.ID,
+		TaskType: task.Type,
+		Input:    input,
+		Requirements: map[string]interface{}{
+			"priority":     task.Priority,
+			"capabilities": task.RequiredCapabilities,
+		},
+	})
+
+	if err := s.wsServer.SendMessage(msg); err != nil {
+		log.Printf("Failed to send task %s to agent %s: %v", taskID, agentID, err)
+	}
 
 	// 广播任务分配事件
-	s.broadcastTaskUpdate("TASK_ASSIGNED", task)
+	s.broadcastTaskUpdate(protocol.EventTaskAssigned, task)
 
-	log.Printf("Task %s allocated to agent %s", task.ID, agentID)
+	log.Printf("Task %s allocated to agent %s", taskID, agentID)
 }
 
 // tryAggregateResults 尝试聚合结果
@@ -517,65 +870,119 @@ func (s *Server) tryAggregateResults(taskID string) {
 }
 
 // broadcastAgentUpdate 广播Agent更新
-func (s *Server) broadcastAgentUpdate(eventType string, agent *scheduler.Agent) {
-	msg := communication.NewMessageBuilder().
-		SetType(eventType).
-		SetFrom("server").
-		SetTo("broadcast").
-		AddPayloadField("agent_id", agent.ID).
-		AddPayloadField("name", agent.Name).
-		AddPayloadField("status", agent.Status).
-		Build()
-
-	s.wsServer.BroadcastMessage(msg)
+func (s *Server) broadcastAgentUpdate(eventType protocol.MessageType, agent *scheduler.Agent) {
+	s.publishEvent(agentTopic(agent.ID, eventType), eventType, map[string]interface{}{
+		"agent_id": agent.ID,
+		"name":     agent.Name,
+		"status":   agent.Status,
+	}, true)
 }
 
 // broadcastTaskUpdate 广播任务更新
-func (s *Server) broadcastTaskUpdate(eventType string, task *scheduler.Task) {
-	msg := communication.NewMessageBuilder().
-		SetType(eventType).
-		SetFrom("server").
-		SetTo("broadcast").
-		AddPayloadField("task_id", task.ID).
-		AddPayloadField("status", task.Status).
-		AddPayloadField("assigned_to", task.AssignedTo).
-		Build()
+func (s *Server) broadcastTaskUpdate(eventType protocol.MessageType, task *scheduler.Task) {
+	webTask := FromSchedulerTask(task)
+	s.publishEvent(taskTopic(task.Type, eventType), eventType, map[string]interface{}{
+		"task_id":     webTask.ID,
+		"status":      webTask.Status,
+		"assigned_to": webTask.AssignedTo,
+		"progress":    webTask.Progress,
+	}, false)
+}
 
-	s.wsServer.BroadcastMessage(msg)
+// broadcastTaskStatus 广播任务状态更新
+func (s *Server) broadcastTaskStatus(taskID string) {
+	task, err := s.taskManager.GetTask(taskID)
+	if err != nil {
+		return
+	}
+	s.broadcastTaskUpdate(protocol.EventTaskStatusUpdate, task)
 }
 
 // broadcastResultUpdate 广播结果更新
-func (s *Server) broadcastResultUpdate(eventType string, result *aggregator.TaskResult) {
-	msg := communication.NewMessageBuilder().
-		SetType(eventType).
-		SetFrom("server").
-		SetTo("broadcast").
-		AddPayloadField("result_id", result.ID).
-		AddPayloadField("task_id", result.TaskID).
-		AddPayloadField("agent_id", result.AgentID).
-		AddPayloadField("status", result.Status).
-		Build()
-
-	s.wsServer.BroadcastMessage(msg)
+func (s *Server) broadcastResultUpdate(eventType protocol.MessageType, result *aggregator.TaskResult) {
+	s.publishEvent(resultTopic(s.taskTypeOf(result.TaskID), eventType), eventType, map[string]interface{}{
+		"result_id": result.ID,
+		"task_id":   result.TaskID,
+		"agent_id":  result.AgentID,
+		"status":    result.Status,
+	}, false)
 }
 
 // broadcastAggregatedResult 广播聚合结果
 func (s *Server) broadcastAggregatedResult(aggregated *aggregator.AggregatedResult) {
-	msg := communication.NewMessageBuilder().
-		SetType("RESULT_AGGREGATED").
-		SetFrom("server").
-		SetTo("broadcast").
-		AddPayloadField("task_id", aggregated.TaskID).
-		AddPayloadField("confidence", aggregated.Confidence).
-		AddPayloadField("conflicts", len(aggregated.Conflicts)).
-		Build()
+	topic := resultTopic(s.taskTypeOf(aggregated.TaskID), protocol.EventResultAggregated)
+	s.publishEvent(topic, protocol.EventResultAggregated, map[string]interface{}{
+		"task_id":    aggregated.TaskID,
+		"confidence": aggregated.Confidence,
+		"conflicts":  len(aggregated.Conflicts),
+	}, false)
+}
 
-	s.wsServer.BroadcastMessage(msg)
+// agentStatus 将协议中的Agent状态映射为调度器状态
+func agentStatus(status protocol.AgentStatus) scheduler.AgentStatus {
+	switch status {
+	case protocol.AgentStatusBusy:
+		return scheduler.AgentStatusBusy
+	case protocol.AgentStatusMaintenance:
+		return scheduler.AgentStatusMaintenance
+	case protocol.AgentStatusError:
+		return scheduler.AgentStatusError
+	default:
+		return scheduler.AgentStatusIdle
+	}
 }
 
 func main() {
+	keysFile := flag.String("keys", "", "JSON file of agent signing keys")
+	requireSignatures := flag.Bool("require-signatures", false, "Reject messages from agents without a signing key")
+	heartbeatTimeout := flag.Duration("heartbeat-timeout", 90*time.Second, "Treat agents without a heartbeat for this long as failed")
+	reconnectGrace := flag.Duration("reconnect-grace", 30*time.Second, "Keep the tasks of a disconnected agent this long for it to reconnect (0 reassigns them immediately)")
+	maxReassignments := flag.Int("max-reassignments", scheduler.DefaultMaxReassignments, "Fail a task after it has been reclaimed from this many failed agents")
+	mailboxDir := flag.String("mailbox-dir", "", "Persist offline mailboxes in this directory so they survive a restart")
+	mailboxSize := flag.Int("mailbox-size", 100, "Maximum number of messages buffered per disconnected agent")
+	mailboxTTL := flag.Duration("mailbox-ttl", time.Hour, "Drop buffered messages older than this")
+	maxRetainedTopics := flag.Int("max-retained-topics", 32, "Maximum number of topics each agent may retain a message on (0 for no limit, -1 to let only the server retain)")
+	maxRetainedSize := flag.Int("max-retained-size", 16*1024, "Maximum payload size in bytes of a message an agent retains (0 for no limit)")
+	reliableDelivery := flag.Bool("reliable-delivery", false, "Retransmit task requests until the agent acknowledges them")
+	plannerURL := flag.String("planner-url", "", "OpenAI-compatible API used by the PLANNER decomposition strategy (default "+decomposer.DefaultChatURL+" when OPENAI_API_KEY is set)")
+	plannerModel := flag.String("planner-model", "gpt-4o-mini", "Model used by the PLANNER decomposition strategy")
+	flag.Parse()
+
+	wsConfig := communication.DefaultWebSocketConfig()
+	wsConfig.RequireSignatures = *requireSignatures
+	wsConfig.Mailbox.Dir = *mailboxDir
+	wsConfig.Mailbox.MaxMessages = *mailboxSize
+	wsConfig.Mailbox.TTL = *mailboxTTL
+	wsConfig.MaxRetainedTopics = *maxRetainedTopics
+	wsConfig.MaxRetainedSize = *maxRetainedSize
+
 	// 创建服务器
-	server := NewServer()
+	server := NewServer(wsConfig)
+	server.heartbeatTimeout = *heartbeatTimeout
+	server.reconnectGrace = *reconnectGrace
+	server.reliableDelivery = *reliableDelivery
+	server.taskManager.SetMaxReassignments(*maxReassignments)
+
+	// PLANNER策略的语言模型：设置了OPENAI_API_KEY或-planner-url（例如不需要密钥的本地服务）时启用
+	apiKey := os.Getenv("OPENAI_API_KEY")
+	if apiKey != "" || *plannerURL != "" {
+		baseURL := *plannerURL
+		if baseURL == "" {
+			baseURL = decomposer.DefaultChatURL
+		}
+		server.planner = decomposer.NewPlanner(decomposer.NewChatClient(baseURL, apiKey, *plannerModel))
+		log.Printf("PLANNER strategy uses %s at %s", *plannerModel, baseURL)
+	}
+
+	// 加载Agent签名密钥
+	if *keysFile != "" {
+		keys, err := protocol.LoadKeyStore(*keysFile)
+		if err != nil {
+			log.Fatalf("Failed to load signing keys: %v", err)
+		}
+		server.wsServer.SetKeyStore(keys)
+		log.Printf("Loaded signing keys from %s", *keysFile)
+	}
 
 	// 启动服务器
 	if err := server.Start(); err != nil {
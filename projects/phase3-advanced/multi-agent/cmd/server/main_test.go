@@ -0,0 +1,878 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agent-learning/multi-agent/internal/communication"
+	"github.com/agent-learning/multi-agent/internal/scheduler"
+	decomposer "github.com/agent-learning/multi-agent/internal/task-decomposer"
+	"github.com/agent-learning/multi-agent/protocol"
+	"github.com/gorilla/websocket"
+)
+
+// startTestServer 在空闲端口上启动服务器，返回其地址。configure可在创建服务器前修改配置
+func startTestServer(t *testing.T, configure ...func(*communication.WebSocketConfig)) (*Server, string) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	config := communication.DefaultWebSocketConfig()
+	config.Host = "127.0.0.1"
+	config.Port = l.Addr().(*net.TCPAddr).Port
+	for _, fn := range configure {
+		fn(config)
+	}
+
+	server := NewServer(config)
+	// 断开的Agent很快按故障处理，需要等待重连的测试自行延长
+	server.reconnectGrace = 50 * time.Millisecond
+	if err := server.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	t.Cleanup(func() { server.Stop() })
+
+	return server, addr
+}
+
+// testAgent 测试用的Agent连接
+type testAgent struct {
+	t    *testing.T
+	id   string
+	conn *websocket.Conn
+}
+
+func connectAgent(t *testing.T, addr, id string) *testAgent {
+	t.Helper()
+
+	url := fmt.Sprintf("ws://%s/ws?agent_id=%s", addr, id)
+	var conn *websocket.Conn
+	var err error
+	for i := 0; i < 50; i++ {
+		if conn, _, err = websocket.DefaultDialer.Dial(url, nil); err == nil {
+			t.Cleanup(func() { conn.Close() })
+			return &testAgent{t: t, id: id, conn: conn}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("Dial failed: %v", err)
+	return nil
+}
+
+// connectDashboard 以控制台身份连接并订阅topics，Hub记录订阅后返回
+func connectDashboard(t *testing.T, server *Server, addr string, topics ...string) *testAgent {
+	t.Helper()
+
+	dashboard := connectAgent(t, addr, "web-client")
+	if err := dashboard.conn.WriteJSON(protocol.NewSubscribeMessage("web-client", topics...)); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	waitFor(t, "dashboard subscription", func() bool {
+		conn, err := server.wsServer.GetConnectionManager().GetConnectionByAgent("web-client")
+		return err == nil && len(server.wsServer.GetRouter().Subscriptions(conn.ID)) == len(topics)
+	})
+	return dashboard
+}
+
+func (a *testAgent) send(msgType protocol.MessageType, payload interface{}) *protocol.Message {
+	a.t.Helper()
+
+	msg := protocol.NewMessage(msgType, a.id, protocol.ServerID)
+	if err := msg.SetPayload(payload); err != nil {
+		a.t.Fatalf("SetPayload failed: %v", err)
+	}
+	if err := a.conn.WriteJSON(msg); err != nil {
+		a.t.Fatalf("WriteJSON failed: %v", err)
+	}
+	return msg
+}
+
+// expect 读取消息直到出现指定类型，跳过广播事件
+func (a *testAgent) expect(msgType protocol.MessageType) *protocol.Message {
+	a.t.Helper()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		a.conn.SetReadDeadline(deadline)
+		var msg protocol.Message
+		if err := a.conn.ReadJSON(&msg); err != nil {
+			a.t.Fatalf("Waiting for %s: %v", msgType, err)
+		}
+		if msg.Type == msgType {
+			return &msg
+		}
+	}
+}
+
+func getJSON(t *testing.T, url string, target interface{}) {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(target); err != nil {
+		t.Fatalf("Failed to decode %s: %v", url, err)
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for %s", what)
+}
+
+func TestServerTaskLifecycle(t *testing.T) {
+	server, addr := startTestServer(t)
+	dashboard := connectDashboard(t, server, addr, "tasks.*.status")
+
+	agent := connectAgent(t, addr, "worker-1")
+	agent.send(protocol.MessageTypeAgentRegister, &protocol.AgentRegisterPayload{
+		Name:         "Worker",
+		Capabilities: []string{"code"},
+	})
+	waitFor(t, "registration", func() bool {
+		_, err := server.registry.GetAgent("worker-1")
+		return err == nil
+	})
+
+	body := bytes.NewBufferString(`{"id":"task-001","type":"code","priority":5,"description":"write code","capabilities":["code"]}`)
+	resp, err := http.Post("http://"+addr+"/api/tasks", "application/json", body)
+	if err != nil {
+		t.Fatalf("POST /api/tasks failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", resp.StatusCode)
+	}
+
+	request := agent.expect(protocol.MessageTypeTaskRequest)
+	var task protocol.TaskRequestPayload
+	if err := request.GetPayload(&task); err != nil {
+		t.Fatalf("GetPayload failed: %v", err)
+	}
+	if task.TaskID != "task-001" || task.Input != "write code" {
+		t.Errorf("Unexpected task request: %+v", task)
+	}
+
+	agent.send(protocol.MessageTypeTaskProgress, &protocol.TaskProgressPayload{TaskID: "task-001", Progress: 50})
+	// 进度处理完成后再继续，避免与之后的结果并发处理
+	dashboard.expect(protocol.EventTaskStatusUpdate)
+
+	// 类型错误的结果返回ERROR而不是使处理器panic
+	bad := protocol.NewMessage(protocol.MessageTypeTaskComplete, "worker-1", protocol.ServerID)
+	bad.Payload = map[string]interface{}{
+		"task_id":      "task-001",
+		"status":       "SUCCESS",
+		"completed_at": time.Now().Format(time.RFC3339),
+		"score":        "high",
+	}
+	agent.conn.WriteJSON(bad)
+	errMsg := agent.expect(protocol.MessageTypeError)
+	var errPayload protocol.ErrorPayload
+	errMsg.GetPayload(&errPayload)
+	if errPayload.ErrorCode != "INVALID_PAYLOAD" || errPayload.OriginalMessageID != bad.MessageID {
+		t.Errorf("Unexpected error: %+v", errPayload)
+	}
+
+	agent.send(protocol.MessageTypeTaskComplete, &protocol.TaskCompletePayload{
+		TaskID:      "task-001",
+		Status:      protocol.TaskStatusSuccess,
+		Output:      map[string]interface{}{"code": "ok"},
+		CompletedAt: time.Now().Format(time.RFC3339),
+		Score:       90,
+	})
+
+	waitFor(t, "task completion", func() bool {
+		var webTask WebTask
+		getJSON(t, "http://"+addr+"/api/tasks/task-001", &webTask)
+		return webTask.Status == "COMPLETED" && webTask.Progress == 50
+	})
+
+	var results []map[string]interface{}
+	getJSON(t, "http://"+addr+"/api/results", &results)
+	if len(results) != 1 || results[0]["score"] != 90.0 {
+		t.Errorf("Expected one result with score 90, got %v", results)
+	}
+}
+
+func TestServerKeyExchange(t *testing.T) {
+	_, addr := startTestServer(t)
+
+	keyring, _ := protocol.NewKeyring()
+	peer := connectAgent(t, addr, "worker-2")
+	agent := connectAgent(t, addr, "worker-1")
+
+	agent.send(protocol.MessageTypeAgentRegister, &protocol.AgentRegisterPayload{
+		Name:          "Worker",
+		Capabilities:  []string{"code"},
+		EncryptionKey: keyring.PublicKey(),
+	})
+
+	// 已连接的Agent收到新Agent的公钥
+	var announced protocol.KeyExchangePayload
+	peer.expect(protocol.MessageTypeKeyExchange).GetPayload(&announced)
+	if announced.AgentID != "worker-1" || announced.PublicKey != keyring.PublicKey() {
+		t.Errorf("Unexpected key announcement: %+v", announced)
+	}
+
+	// 之后连接的Agent可以查询
+	peer.send(protocol.MessageTypeKeyExchange, &protocol.KeyExchangePayload{AgentID: "worker-1"})
+	var reply protocol.KeyExchangePayload
+	peer.expect(protocol.MessageTypeKeyExchange).GetPayload(&reply)
+	if reply.PublicKey != keyring.PublicKey() {
+		t.Errorf("Unexpected key reply: %+v", reply)
+	}
+
+	peer.send(protocol.MessageTypeKeyExchange, &protocol.KeyExchangePayload{AgentID: "worker-9"})
+	var errPayload protocol.ErrorPayload
+	peer.expect(protocol.MessageTypeError).GetPayload(&errPayload)
+	if errPayload.ErrorCode != "UNKNOWN_KEY" {
+		t.Errorf("Expected UNKNOWN_KEY, got %+v", errPayload)
+	}
+
+	peer.send(protocol.MessageTypeAgentRegister, &protocol.AgentRegisterPayload{
+		Name:          "Worker 2",
+		Capabilities:  []string{"code"},
+		EncryptionKey: "not-a-key",
+	})
+	peer.expect(protocol.MessageTypeError).GetPayload(&errPayload)
+	if errPayload.ErrorCode != "INVALID_ENCRYPTION_KEY" {
+		t.Errorf("Expected INVALID_ENCRYPTION_KEY, got %+v", errPayload)
+	}
+}
+
+func TestServerRejectsUnsignedKeyRotation(t *testing.T) {
+	server, addr := startTestServer(t)
+	signingKey := protocol.NewHMACKey([]byte("worker-2-secret"))
+	server.wsServer.GetKeyStore().Register("worker-2", signingKey)
+
+	original, _ := protocol.NewKeyring()
+	rotated, _ := protocol.NewKeyring()
+	register := func(agent *testAgent, publicKey string, key *protocol.Key) {
+		msg := protocol.NewMessage(protocol.MessageTypeAgentRegister, agent.id, protocol.ServerID)
+		msg.SetPayload(&protocol.AgentRegisterPayload{Name: "Worker", Capabilities: []string{"code"}, EncryptionKey: publicKey})
+		if key != nil {
+			key.Sign(msg)
+		}
+		if err := agent.conn.WriteJSON(msg); err != nil {
+			t.Fatalf("WriteJSON failed: %v", err)
+		}
+	}
+	publicKey := func(agentID string) string {
+		server.keysMu.RLock()
+		defer server.keysMu.RUnlock()
+		return server.publicKeys[agentID]
+	}
+
+	// 未登记签名密钥的Agent不能替换已登记的公钥
+	agent := connectAgent(t, addr, "worker-1")
+	register(agent, original.PublicKey(), nil)
+	waitFor(t, "key registration", func() bool { return publicKey("worker-1") == original.PublicKey() })
+
+	register(agent, rotated.PublicKey(), nil)
+	var errPayload protocol.ErrorPayload
+	agent.expect(protocol.MessageTypeError).GetPayload(&errPayload)
+	if errPayload.ErrorCode != "KEY_ROTATION_UNAUTHENTICATED" || publicKey("worker-1") != original.PublicKey() {
+		t.Errorf("Expected the unsigned rotation to be rejected, got %+v", errPayload)
+	}
+
+	// 签名验证通过的注册可以更换公钥
+	signer := connectAgent(t, addr, "worker-2")
+	register(signer, original.PublicKey(), signingKey)
+	waitFor(t, "key registration", func() bool { return publicKey("worker-2") == original.PublicKey() })
+	register(signer, rotated.PublicKey(), signingKey)
+	waitFor(t, "signed key rotation", func() bool { return publicKey("worker-2") == rotated.PublicKey() })
+}
+
+func TestServerReassignsTasksOfFailedAgent(t *testing.T) {
+	server, addr := startTestServer(t)
+	server.taskManager.SetMaxReassignments(1)
+
+	register := func(agent *testAgent) {
+		agent.send(protocol.MessageTypeAgentRegister, &protocol.AgentRegisterPayload{
+			Name:         agent.id,
+			Capabilities: []string{"code"},
+		})
+		waitFor(t, "registration of "+agent.id, func() bool {
+			_, err := server.registry.GetAgent(agent.id)
+			return err == nil
+		})
+	}
+
+	dashboard := connectDashboard(t, server, addr, "tasks.#", "messages.#")
+	first := connectAgent(t, addr, "worker-1")
+	register(first)
+
+	body := bytes.NewBufferString(`{"id":"task-001","type":"code","priority":5,"description":"write code","capabilities":["code"]}`)
+	resp, err := http.Post("http://"+addr+"/api/tasks", "application/json", body)
+	if err != nil {
+		t.Fatalf("POST /api/tasks failed: %v", err)
+	}
+	resp.Body.Close()
+	first.expect(protocol.MessageTypeTaskRequest)
+
+	second := connectAgent(t, addr, "worker-2")
+	register(second)
+
+	// worker-1断开：任务被收回并分配给worker-2
+	first.conn.Close()
+
+	var event map[string]interface{}
+	dashboard.expect(protocol.EventTaskReassigned).GetPayload(&event)
+	if event["task_id"] != "task-001" || event["from_agent"] != "worker-1" || event["requeued"] != true {
+		t.Errorf("Unexpected reassignment event: %v", event)
+	}
+
+	var request protocol.TaskRequestPayload
+	second.expect(protocol.MessageTypeTaskRequest).GetPayload(&request)
+	if request.TaskID != "task-001" {
+		t.Errorf("Expected task-001 to be reassigned to worker-2, got %+v", request)
+	}
+
+	agent, _ := server.registry.GetAgent("worker-1")
+	if agent.Status != scheduler.AgentStatusError {
+		t.Errorf("Expected worker-1 to be in ERROR, got %s", agent.Status)
+	}
+
+	// worker-1重连后迟到的结果被丢弃
+	late := connectAgent(t, addr, "worker-1")
+	late.send(protocol.MessageTypeTaskComplete, &protocol.TaskCompletePayload{
+		TaskID:      "task-001",
+		Status:      protocol.TaskStatusSuccess,
+		CompletedAt: time.Now().Format(time.RFC3339),
+	})
+	var errPayload protocol.ErrorPayload
+	late.expect(protocol.MessageTypeError).GetPayload(&errPayload)
+	if errPayload.ErrorCode != "STALE_RESULT" {
+		t.Errorf("Expected STALE_RESULT, got %+v", errPayload)
+	}
+	if results := server.aggregator.GetResultsByTask("task-001"); len(results) != 0 {
+		t.Errorf("Expected the late result to be discarded, got %d results", len(results))
+	}
+
+	// 超过最大重新分配次数后任务失败
+	second.conn.Close()
+	dashboard.expect(protocol.EventTaskReassigned).GetPayload(&event)
+	if event["requeued"] != false {
+		t.Errorf("Expected the task not to be requeued, got %v", event)
+	}
+
+	var webTask WebTask
+	getJSON(t, "http://"+addr+"/api/tasks/task-001", &webTask)
+	if webTask.Status != "FAILED" || webTask.Reassignments != 2 {
+		t.Errorf("Expected a failed task with 2 reassignments, got %+v", webTask)
+	}
+}
+
+func TestServerKeepsTasksOfReconnectingAgent(t *testing.T) {
+	server, addr := startTestServer(t)
+	server.reconnectGrace = time.Minute
+	mailboxes := server.wsServer.GetMailboxes()
+
+	register := func(agent *testAgent) {
+		agent.send(protocol.MessageTypeAgentRegister, &protocol.AgentRegisterPayload{
+			Name:         agent.id,
+			Capabilities: []string{"code"},
+		})
+		waitFor(t, "registration of "+agent.id, func() bool {
+			return server.registry.GetAgentCountByStatus()[scheduler.AgentStatusIdle] == 1
+		})
+	}
+
+	first := connectAgent(t, addr, "worker-1")
+	register(first)
+
+	body := bytes.NewBufferString(`{"id":"task-001","type":"code","priority":5,"description":"write code","capabilities":["code"]}`)
+	resp, err := http.Post("http://"+addr+"/api/tasks", "application/json", body)
+	if err != nil {
+		t.Fatalf("POST /api/tasks failed: %v", err)
+	}
+	resp.Body.Close()
+	first.expect(protocol.MessageTypeTaskRequest)
+
+	// 断开期间标记为离线，任务仍归worker-1
+	first.conn.Close()
+	waitFor(t, "the disconnect", func() bool {
+		return server.registry.GetAgentCountByStatus()[scheduler.AgentStatusOffline] == 1
+	})
+
+	// 宽限期内重连后结果照常接受
+	second := connectAgent(t, addr, "worker-1")
+	register(second)
+	second.send(protocol.MessageTypeTaskComplete, &protocol.TaskCompletePayload{
+		TaskID:      "task-001",
+		Status:      protocol.TaskStatusSuccess,
+		CompletedAt: time.Now().Format(time.RFC3339),
+	})
+	waitFor(t, "task completion", func() bool {
+		var webTask WebTask
+		getJSON(t, "http://"+addr+"/api/tasks/task-001", &webTask)
+		return webTask.Status == "COMPLETED" && webTask.Reassignments == 0
+	})
+
+	// 未注册的连接断开后、Agent注销后都删除信箱
+	viewer := connectAgent(t, addr, "viewer")
+	waitFor(t, "the viewer connection", func() bool {
+		_, err := server.wsServer.GetConnectionManager().GetConnectionByAgent("viewer")
+		return err == nil
+	})
+	viewer.conn.Close()
+	waitFor(t, "the viewer mailbox removal", func() bool {
+		return errors.Is(mailboxes.Store("viewer", protocol.NewMessage(protocol.MessageTypeHeartbeat, protocol.ServerID, "viewer")), communication.ErrNoMailbox)
+	})
+
+	// 注销时同时清除Agent保留的消息
+	report := protocol.NewMessage(protocol.MessageTypeBroadcast, "worker-1", "broadcast")
+	report.SetPayload(&protocol.BroadcastPayload{Event: "summary"})
+	report.Topic = "reports.worker-1"
+	report.Retain = true
+	if err := second.conn.WriteJSON(report); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	waitFor(t, "the retained report", func() bool {
+		return len(server.wsServer.GetRouter().Retained("reports.#")) == 1
+	})
+
+	req, _ := http.NewRequest(http.MethodDelete, "http://"+addr+"/api/agents/worker-1", nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("DELETE /api/agents/worker-1 failed: %v", err)
+	}
+	resp.Body.Close()
+	if retained := server.wsServer.GetRouter().Retained("reports.#"); len(retained) != 0 {
+		t.Errorf("Expected the retained report of the unregistered agent to be cleared, got %+v", retained)
+	}
+	if err := mailboxes.Store("worker-1", protocol.NewMessage(protocol.MessageTypeHeartbeat, protocol.ServerID, "worker-1")); !errors.Is(err, communication.ErrNoMailbox) {
+		t.Errorf("Expected the mailbox of the unregistered agent to be removed, got %v", err)
+	}
+}
+
+func TestServerKeepsCountersOfReregisteringAgent(t *testing.T) {
+	server, addr := startTestServer(t)
+	server.reconnectGrace = 300 * time.Millisecond
+
+	register := func(agent *testAgent, version string, status scheduler.AgentStatus) {
+		agent.send(protocol.MessageTypeAgentRegister, &protocol.AgentRegisterPayload{
+			Name:         agent.id,
+			Capabilities: []string{"code"},
+			MaxTasks:     2,
+			Metadata:     map[string]interface{}{"version": version},
+		})
+		waitFor(t, fmt.Sprintf("%s to be %s", agent.id, status), func() bool {
+			return server.registry.GetAgentCountByStatus()[status] == 1
+		})
+	}
+
+	first := connectAgent(t, addr, "worker-1")
+	register(first, "1", scheduler.AgentStatusIdle)
+
+	taskIDs := []string{"task-001", "task-002"}
+	for _, id := range taskIDs {
+		body := bytes.NewBufferString(`{"id":"` + id + `","type":"code","priority":5,"description":"write code","capabilities":["code"]}`)
+		resp, err := http.Post("http://"+addr+"/api/tasks", "application/json", body)
+		if err != nil {
+			t.Fatalf("POST /api/tasks failed: %v", err)
+		}
+		resp.Body.Close()
+		first.expect(protocol.MessageTypeTaskRequest)
+	}
+	waitFor(t, "worker-1 to be busy", func() bool {
+		return server.registry.GetAgentCountByStatus()[scheduler.AgentStatusBusy] == 1
+	})
+
+	first.conn.Close()
+	waitFor(t, "the disconnect", func() bool {
+		return server.registry.GetAgentCountByStatus()[scheduler.AgentStatusOffline] == 1
+	})
+
+	// 宽限期内重连并重新注册：保留执行中的任务，只更新元数据，停止计时器
+	second := connectAgent(t, addr, "worker-1")
+	register(second, "2", scheduler.AgentStatusBusy)
+
+	server.disconnectMu.Lock()
+	pending := len(server.disconnects)
+	server.disconnectMu.Unlock()
+	if pending != 0 {
+		t.Errorf("Expected the reconnect timer to be stopped, %d pending", pending)
+	}
+
+	time.Sleep(2 * server.reconnectGrace)
+
+	var agent scheduler.Agent
+	getJSON(t, "http://"+addr+"/api/agents/worker-1", &agent)
+	if agent.CurrentTasks != 2 || agent.Status != scheduler.AgentStatusBusy || agent.Metadata["version"] != "2" {
+		t.Errorf("Expected 2 running tasks and refreshed metadata, got %+v", agent)
+	}
+	for _, id := range taskIDs {
+		var webTask WebTask
+		getJSON(t, "http://"+addr+"/api/tasks/"+id, &webTask)
+		if webTask.AssignedTo != "worker-1" || webTask.Reassignments != 0 {
+			t.Errorf("Expected %s to stay with worker-1, got %+v", id, webTask)
+		}
+	}
+}
+
+func TestServerExecutesCompositeTask(t *testing.T) {
+	server, addr := startTestServer(t)
+
+	worker := connectAgent(t, addr, "worker-1")
+	worker.send(protocol.MessageTypeAgentRegister, &protocol.AgentRegisterPayload{
+		Name:         "worker-1",
+		Capabilities: []string{"document_parsing", "content_analysis", "summarization"},
+	})
+	waitFor(t, "registration", func() bool {
+		_, err := server.registry.GetAgent("worker-1")
+		return err == nil
+	})
+
+	createComposite := func(id string) *CompositeTask {
+		body := bytes.NewBufferString(`{"id":"` + id + `","type":"document_processing","description":"summarize","input":"doc text"}`)
+		resp, err := http.Post("http://"+addr+"/api/composite-tasks", "application/json", body)
+		if err != nil {
+			t.Fatalf("POST /api/composite-tasks failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		var composite CompositeTask
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("Expected 201, got %d", resp.StatusCode)
+		}
+		json.NewDecoder(resp.Body).Decode(&composite)
+		return &composite
+	}
+
+	composite := createComposite("doc-001")
+	if len(composite.SubTasks) != 3 || len(composite.Levels) != 3 {
+		t.Fatalf("Expected a chain of 3 sub-tasks, got %+v", composite)
+	}
+
+	// 按层级执行：每个子任务收到上游子任务的输出
+	outputs := []map[string]interface{}{{"parsed": "yes"}, {"topics": "go"}, {"summary": "short"}}
+	for level, output := range outputs {
+		var request protocol.TaskRequestPayload
+		worker.expect(protocol.MessageTypeTaskRequest).GetPayload(&request)
+		if request.TaskID != composite.Levels[level][0] {
+			t.Fatalf("Level %d: expected %s, got %s", level, composite.Levels[level][0], request.TaskID)
+		}
+
+		input, _ := request.Input.(map[string]interface{})
+		upstream, _ := input["upstream"].(map[string]interface{})
+		if input["input"] != "doc text" || (level == 0) != (len(upstream) == 0) {
+			t.Errorf("Level %d: unexpected input %v", level, request.Input)
+		}
+		if level > 0 {
+			previous, _ := upstream[composite.Levels[level-1][0]].(map[string]interface{})
+			for key, value := range outputs[level-1] {
+				if previous[key] != value {
+					t.Errorf("Level %d: expected upstream output %v, got %v", level, outputs[level-1], upstream)
+				}
+			}
+		}
+
+		worker.send(protocol.MessageTypeTaskComplete, &protocol.TaskCompletePayload{
+			TaskID:      request.TaskID,
+			Status:      protocol.TaskStatusSuccess,
+			Output:      output,
+			CompletedAt: time.Now().Format(time.RFC3339),
+		})
+	}
+
+	var result CompositeTask
+	waitFor(t, "composite completion", func() bool {
+		getJSON(t, "http://"+addr+"/api/composite-tasks/doc-001", &result)
+		return result.Status == CompositeStatusCompleted
+	})
+	if result.Result == nil || result.Result.MergedData["parsed"] != "yes" || result.Result.MergedData["summary"] != "short" {
+		t.Errorf("Expected sub-task outputs merged into the parent result, got %+v", result.Result)
+	}
+
+	// 子任务失败时复合任务失败，下游子任务不再执行
+	failing := createComposite("doc-002")
+	var request protocol.TaskRequestPayload
+	worker.expect(protocol.MessageTypeTaskRequest).GetPayload(&request)
+	worker.send(protocol.MessageTypeTaskFailed, &protocol.TaskFailedPayload{
+		TaskID:       request.TaskID,
+		ErrorCode:    "EXECUTION_FAILED",
+		ErrorMessage: "unreadable",
+	})
+
+	waitFor(t, "composite failure", func() bool {
+		getJSON(t, "http://"+addr+"/api/composite-tasks/"+failing.ID, &result)
+		return result.Status == CompositeStatusFailed
+	})
+	for _, sub := range result.SubTasks {
+		expected := SubTaskStatusCancelled
+		if sub.ID == request.TaskID {
+			expected = SubTaskStatusFailed
+		}
+		if sub.Status != expected {
+			t.Errorf("Expected sub-task %s to be %s, got %s", sub.ID, expected, sub.Status)
+		}
+	}
+}
+
+// plannerClient 返回固定分解方案的LLM客户端，记录收到的提示词
+type plannerClient struct {
+	mu      sync.Mutex
+	prompts []string
+}
+
+func (c *plannerClient) Complete(ctx context.Context, prompt string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.prompts = append(c.prompts, prompt)
+	return `{"sub_tasks": [
+		{"id": "parse", "type": "parse", "description": "Parse the document", "capabilities": ["document_parsing"]},
+		{"id": "summary", "type": "summarize", "description": "Summarize", "capabilities": ["summarization"], "dependencies": ["parse"]}
+	]}`, nil
+}
+
+func TestServerPlansCompositeTasksWithRegisteredCapabilities(t *testing.T) {
+	server, addr := startTestServer(t)
+	client := &plannerClient{}
+	server.planner = decomposer.NewPlanner(client)
+
+	worker := connectAgent(t, addr, "worker-1")
+	worker.send(protocol.MessageTypeAgentRegister, &protocol.AgentRegisterPayload{
+		Name:         "worker-1",
+		Capabilities: []string{"document_parsing", "summarization"},
+	})
+	waitFor(t, "registration", func() bool {
+		_, err := server.registry.GetAgent("worker-1")
+		return err == nil
+	})
+
+	body := bytes.NewBufferString(`{"id":"doc-004","type":"document_processing","description":"summarize","strategy":"PLANNER"}`)
+	resp, err := http.Post("http://"+addr+"/api/composite-tasks", "application/json", body)
+	if err != nil {
+		t.Fatalf("POST /api/composite-tasks failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var composite CompositeTask
+	json.NewDecoder(resp.Body).Decode(&composite)
+	if composite.Strategy != "PLANNER" || len(composite.SubTasks) != 2 || len(composite.Levels) != 2 {
+		t.Fatalf("Expected the planned sub-tasks, got %+v", composite)
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if len(client.prompts) != 1 || !strings.Contains(client.prompts[0], "document_parsing, summarization") {
+		t.Errorf("Expected the prompt to list the registered capabilities, got %v", client.prompts)
+	}
+}
+
+func TestServerCancelsAndEvictsCompositeTasks(t *testing.T) {
+	server, addr := startTestServer(t)
+
+	worker := connectAgent(t, addr, "worker-1")
+	worker.send(protocol.MessageTypeAgentRegister, &protocol.AgentRegisterPayload{
+		Name:         "worker-1",
+		Capabilities: []string{"document_parsing", "content_analysis", "summarization"},
+	})
+	waitFor(t, "registration", func() bool {
+		_, err := server.registry.GetAgent("worker-1")
+		return err == nil
+	})
+
+	body := bytes.NewBufferString(`{"id":"doc-003","type":"document_processing","description":"summarize","input":"doc text"}`)
+	resp, err := http.Post("http://"+addr+"/api/composite-tasks", "application/json", body)
+	if err != nil {
+		t.Fatalf("POST /api/composite-tasks failed: %v", err)
+	}
+	resp.Body.Close()
+
+	// 复合任务失败时，执行中的子任务通知Agent取消
+	var request protocol.TaskRequestPayload
+	worker.expect(protocol.MessageTypeTaskRequest).GetPayload(&request)
+	server.failComposite("doc-003", "operator abort")
+
+	var cancel protocol.TaskCancelPayload
+	worker.expect(protocol.MessageTypeTaskCancel).GetPayload(&cancel)
+	if cancel.TaskID != request.TaskID || cancel.Reason != "operator abort" {
+		t.Errorf("Unexpected cancellation: %+v", cancel)
+	}
+	task, _ := server.taskManager.GetTask(request.TaskID)
+	if task.Status != string(scheduler.TaskStatusCancelled) {
+		t.Errorf("Expected the sub-task to be cancelled, got %s", task.Status)
+	}
+
+	// 结束超过保留时长的复合任务被移除
+	status := func() int {
+		resp, err := http.Get("http://" + addr + "/api/composite-tasks/doc-003")
+		if err != nil {
+			t.Fatalf("GET composite failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	server.evictComposites(time.Now())
+	if code := status(); code != http.StatusOK {
+		t.Errorf("Expected the failed composite to be kept, got %d", code)
+	}
+	server.evictComposites(time.Now().Add(server.compositeRetention))
+	if code := status(); code != http.StatusNotFound {
+		t.Errorf("Expected the composite to be evicted, got %d", code)
+	}
+	server.compositeMu.Lock()
+	defer server.compositeMu.Unlock()
+	if len(server.subTaskParents) != 0 {
+		t.Errorf("Expected the sub-task index to be cleared, got %v", server.subTaskParents)
+	}
+}
+
+func TestServerDeadLettersUnacknowledgedTaskRequests(t *testing.T) {
+	server, addr := startTestServer(t, func(config *communication.WebSocketConfig) {
+		config.Delivery.MaxAttempts = 2
+		config.Delivery.InitialBackoff = 50 * time.Millisecond
+		config.Delivery.CheckInterval = 10 * time.Millisecond
+	})
+	server.reliableDelivery = true
+
+	register := func(agent *testAgent) {
+		agent.send(protocol.MessageTypeAgentRegister, &protocol.AgentRegisterPayload{
+			Name:         agent.id,
+			Capabilities: []string{"code"},
+		})
+		waitFor(t, "registration of "+agent.id, func() bool {
+			_, err := server.registry.GetAgent(agent.id)
+			return err == nil
+		})
+	}
+
+	// 死信和重新分配事件由不同的发送协程投递，先后顺序不确定，分别订阅
+	deadLetters := connectDashboard(t, server, addr, "messages.#")
+	reassignments := connectDashboard(t, server, addr, "tasks.*.reassigned")
+	silent := connectAgent(t, addr, "worker-1")
+	register(silent)
+
+	body := bytes.NewBufferString(`{"id":"task-001","type":"code","priority":5,"description":"write code","capabilities":["code"]}`)
+	resp, err := http.Post("http://"+addr+"/api/tasks", "application/json", body)
+	if err != nil {
+		t.Fatalf("POST /api/tasks failed: %v", err)
+	}
+	resp.Body.Close()
+
+	// 未确认的任务请求被重传，之后进入死信
+	first := silent.expect(protocol.MessageTypeTaskRequest)
+	retry := silent.expect(protocol.MessageTypeTaskRequest)
+	if !first.RequireAck || retry.MessageID != first.MessageID {
+		t.Fatalf("Expected a retransmission of %s, got %s", first.MessageID, retry.MessageID)
+	}
+
+	var event map[string]interface{}
+	deadLetters.expect(protocol.EventMessageDeadLettered).GetPayload(&event)
+	if event["message_id"] != first.MessageID || event["to"] != "worker-1" {
+		t.Errorf("Unexpected dead letter event: %v", event)
+	}
+
+	var letters []*communication.DeadLetter
+	getJSON(t, "http://"+addr+"/api/dead-letters", &letters)
+	if len(letters) != 1 || letters[0].Message.Type != protocol.MessageTypeTaskRequest || letters[0].Attempts != 2 {
+		t.Fatalf("Unexpected dead letters: %+v", letters)
+	}
+
+	// 任务从收不到请求的Agent收回，分配给确认请求的Agent
+	reassignments.expect(protocol.EventTaskReassigned)
+	worker := connectAgent(t, addr, "worker-2")
+	register(worker)
+
+	request := worker.expect(protocol.MessageTypeTaskRequest)
+	worker.conn.WriteJSON(protocol.NewAckMessage("worker-2", request,
+		&protocol.AckPayload{MessageID: request.MessageID, Success: true}))
+	waitFor(t, "the ack", func() bool { return server.wsServer.GetReliableSender().PendingCount() == 0 })
+
+	if assigned, _ := server.taskManager.GetAssignment("task-001"); assigned != "worker-2" {
+		t.Errorf("Expected task-001 to be assigned to worker-2, got %s", assigned)
+	}
+
+	req, _ := http.NewRequest("DELETE", "http://"+addr+"/api/dead-letters/"+first.MessageID, nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil || resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("DELETE dead letter failed: %v %v", err, resp)
+	}
+	resp.Body.Close()
+	if n := server.wsServer.GetReliableSender().GetDeadLetters().Count(); n != 0 {
+		t.Errorf("Expected no dead letters after DELETE, got %d", n)
+	}
+}
+
+func TestServerPublishesDashboardEvents(t *testing.T) {
+	server, addr := startTestServer(t)
+
+	agent := connectAgent(t, addr, "worker-1")
+	agent.send(protocol.MessageTypeAgentRegister, &protocol.AgentRegisterPayload{
+		Name:         "Worker",
+		Capabilities: []string{"code"},
+	})
+	waitFor(t, "registration", func() bool {
+		_, err := server.registry.GetAgent("worker-1")
+		return err == nil
+	})
+
+	// 之后订阅的控制台立即收到保留的Agent事件
+	dashboard := connectDashboard(t, server, addr, "agents.*.registered", "tasks.code.*")
+	registered := dashboard.expect(protocol.EventAgentRegistered)
+	if registered.Topic != "agents.worker-1.registered" || !registered.Retain {
+		t.Errorf("Expected the retained registration, got %+v", registered)
+	}
+
+	// 只收到订阅的任务类型的事件
+	for _, task := range []string{
+		`{"id":"task-001","type":"review","priority":5,"description":"review code","capabilities":["review"]}`,
+		`{"id":"task-002","type":"code","priority":5,"description":"write code","capabilities":["review"]}`,
+	} {
+		resp, err := http.Post("http://"+addr+"/api/tasks", "application/json", bytes.NewBufferString(task))
+		if err != nil {
+			t.Fatalf("POST /api/tasks failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	created := dashboard.expect(protocol.EventTaskCreated)
+	if created.Topic != "tasks.code.created" || created.Payload["task_id"] != "task-002" {
+		t.Errorf("Expected only the code task, got %+v", created)
+	}
+
+	// 未订阅的Agent不再收到控制台事件
+	agent.send(protocol.MessageTypeHeartbeat, &protocol.HeartbeatPayload{Status: protocol.AgentStatusIdle})
+	agent.conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	var msg protocol.Message
+	if err := agent.conn.ReadJSON(&msg); err == nil {
+		t.Errorf("Expected no events on an unsubscribed connection, got %s", msg.Type)
+	}
+
+	// 注销后不再保留该Agent的事件
+	req, _ := http.NewRequest(http.MethodDelete, "http://"+addr+"/api/agents/worker-1", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("DELETE /api/agents/worker-1 failed: %v", err)
+	}
+	resp.Body.Close()
+	if retained := server.wsServer.GetRouter().Retained("agents.#"); len(retained) != 0 {
+		t.Errorf("Expected the retained events of worker-1 to be cleared, got %d", len(retained))
+	}
+}
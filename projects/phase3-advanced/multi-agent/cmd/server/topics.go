@@ -0,0 +1,72 @@
+package main
+
+import (
+	"log"
+
+	"github.com/agent-learning/multi-agent/protocol"
+)
+
+// 控制台事件发布到以下主题，控制台只订阅它显示的内容：
+//
+//	agents.<agentID>.registered|status          Agent事件，保留最后一条，Agent注销时清除
+//	tasks.<任务类型>.created|assigned|status|reassigned|composite
+//	results.<任务类型>.submitted|aggregated
+//	messages.dead_lettered
+//
+// Agent ID和任务类型中的"."和通配符替换为"_"。这些主题只有服务器能发布（见WebSocketConfig.ReservedTopics）
+var eventTopics = map[protocol.MessageType]string{
+	protocol.EventAgentRegistered:     "registered",
+	protocol.EventAgentStatusUpdate:   "status",
+	protocol.EventTaskCreated:         "created",
+	protocol.EventTaskAssigned:        "assigned",
+	protocol.EventTaskStatusUpdate:    "status",
+	protocol.EventTaskReassigned:      "reassigned",
+	protocol.EventCompositeTaskUpdate: "composite",
+	protocol.EventResultSubmitted:     "submitted",
+	protocol.EventResultAggregated:    "aggregated",
+	protocol.EventMessageDeadLettered: "dead_lettered",
+}
+
+// agentTopic Agent事件的主题
+func agentTopic(agentID string, eventType protocol.MessageType) string {
+	return "agents." + protocol.TopicLevel(agentID) + "." + eventTopics[eventType]
+}
+
+// agentTopics 匹配Agent所有事件主题的模式
+func agentTopics(agentID string) string {
+	return "agents." + protocol.TopicLevel(agentID) + ".#"
+}
+
+// taskTopic 任务事件的主题
+func taskTopic(taskType string, eventType protocol.MessageType) string {
+	return "tasks." + protocol.TopicLevel(taskType) + "." + eventTopics[eventType]
+}
+
+// resultTopic 结果事件的主题
+func resultTopic(taskType string, eventType protocol.MessageType) string {
+	return "results." + protocol.TopicLevel(taskType) + "." + eventTopics[eventType]
+}
+
+// messageTopic 消息投递事件的主题
+func messageTopic(eventType protocol.MessageType) string {
+	return "messages." + eventTopics[eventType]
+}
+
+// taskTypeOf 获取任务类型，任务不存在时返回空字符串
+func (s *Server) taskTypeOf(taskID string) string {
+	task, err := s.taskManager.GetTask(taskID)
+	if err != nil {
+		return ""
+	}
+	return task.Type
+}
+
+// publishEvent 把控制台事件发布到主题，retain为true时保留为主题的最后一条消息
+func (s *Server) publishEvent(topic string, eventType protocol.MessageType, payload map[string]interface{}, retain bool) {
+	msg := protocol.NewMessage(eventType, protocol.ServerID, "broadcast")
+	msg.Payload = payload
+
+	if err := s.wsServer.Publish(topic, msg, retain); err != nil {
+		log.Printf("Failed to publish %s to %s: %v", eventType, topic, err)
+	}
+}
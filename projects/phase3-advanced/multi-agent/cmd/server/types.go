@@ -16,6 +16,8 @@ type WebTask struct {
 	Status      string    `json:"status"`
 	AssignedTo  string    `json:"assigned_to"`
 	Progress    int       `json:"progress"`
+	Reassignments int     `json:"reassignments,omitempty"`
+	ParentID    string    `json:"parent_id,omitempty"` // 所属复合任务
 	CreatedAt   time.Time `json:"created_at"`
 }
 
@@ -45,11 +47,15 @@ func FromSchedulerTask(st *scheduler.Task) *WebTask {
 		Capabilities: st.RequiredCapabilities,
 		Status:       st.Status,
 		AssignedTo:   st.AssignedAgentID,
+		Reassignments: st.Reassignments,
 	}
 
 	if desc, ok := st.Metadata["description"].(string); ok {
 		wt.Description = desc
 	}
+	if parentID, ok := st.Metadata["parent_id"].(string); ok {
+		wt.ParentID = parentID
+	}
 	if prog, ok := st.Metadata["progress"].(int); ok {
 		wt.Progress = prog
 	}
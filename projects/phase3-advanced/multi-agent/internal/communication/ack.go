@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"sync"
 	"time"
+
+	"github.com/agent-learning/multi-agent/protocol"
 )
 
 // AckStatus 确认状态
@@ -225,7 +227,7 @@ func (b *MessageBuilder) SetMessageID(id string) *MessageBuilder {
 }
 
 // SetType 设置消息类型
-func (b *MessageBuilder) SetType(msgType string) *MessageBuilder {
+func (b *MessageBuilder) SetType(msgType protocol.MessageType) *MessageBuilder {
 	b.msg.Type = msgType
 	return b
 }
@@ -1,6 +1,7 @@
 package communication
 
 import (
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -8,6 +9,13 @@ import (
 	"github.com/gorilla/websocket"
 )
 
+var (
+	// ErrConnectionInactive 连接已关闭
+	ErrConnectionInactive = errors.New("connection is not active")
+	// ErrSendBufferFull 连接的发送缓冲区已满
+	ErrSendBufferFull = errors.New("send buffer is full")
+)
+
 // ConnectionStatus 连接状态
 type ConnectionStatus string
 
@@ -26,6 +34,7 @@ type Connection struct {
 	ConnectedAt   time.Time
 	LastHeartbeat time.Time
 	SendChan      chan []byte
+	unverified    bool // Agent登记了密钥，连接尚未收到验证通过的签名消息
 	mu            sync.RWMutex
 }
 
@@ -42,20 +51,36 @@ func NewConnection(id, agentID string, conn *websocket.Conn) *Connection {
 	}
 }
 
+// NewUnverifiedConnection 创建尚未确认Agent身份的连接，
+// 由ConnectionManager.VerifyConnection确认前不接收发给该Agent的消息
+func NewUnverifiedConnection(id, agentID string, conn *websocket.Conn) *Connection {
+	c := NewConnection(id, agentID, conn)
+	c.unverified = true
+	return c
+}
+
+// Verified 检查连接是否已确认Agent身份
+func (c *Connection) Verified() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return !c.unverified
+}
+
 // Send 发送消息
 func (c *Connection) Send(data []byte) error {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
 	if c.Status != ConnectionStatusConnected {
-		return fmt.Errorf("connection is not active")
+		return ErrConnectionInactive
 	}
 
 	select {
 	case c.SendChan <- data:
 		return nil
 	default:
-		return fmt.Errorf("send buffer is full")
+		return ErrSendBufferFull
 	}
 }
 
@@ -72,6 +97,11 @@ func (c *Connection) Close() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	// 服务器停止和读协程退出都会关闭连接
+	if c.Status == ConnectionStatusDisconnected {
+		return nil
+	}
+
 	c.Status = ConnectionStatusDisconnected
 	close(c.SendChan)
 
@@ -115,11 +145,32 @@ func (m *ConnectionManager) AddConnection(conn *Connection) error {
 	}
 
 	m.connections[conn.ID] = conn
-	m.agentConns[conn.AgentID] = conn
+	// 未确认身份的连接不替换Agent现有的连接
+	if conn.Verified() {
+		m.agentConns[conn.AgentID] = conn
+	}
 
 	return nil
 }
 
+// VerifyConnection 确认连接的Agent身份，之后发给该Agent的消息经此连接投递。
+// 连接已确认或已移除时返回false
+func (m *ConnectionManager) VerifyConnection(conn *Connection) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.connections[conn.ID] != conn || conn.Verified() {
+		return false
+	}
+
+	conn.mu.Lock()
+	conn.unverified = false
+	conn.mu.Unlock()
+	m.agentConns[conn.AgentID] = conn
+
+	return true
+}
+
 // RemoveConnection 移除连接
 func (m *ConnectionManager) RemoveConnection(connID string) error {
 	m.mu.Lock()
@@ -131,7 +182,10 @@ func (m *ConnectionManager) RemoveConnection(connID string) error {
 	}
 
 	delete(m.connections, connID)
-	delete(m.agentConns, conn.AgentID)
+	// Agent重连后agentConns已指向新连接，只删除仍指向本连接的映射
+	if m.agentConns[conn.AgentID] == conn {
+		delete(m.agentConns, conn.AgentID)
+	}
 
 	return conn.Close()
 }
@@ -240,6 +294,31 @@ func (m *ConnectionManager) BroadcastToAgents(agentIDs []string, data []byte) er
 	return nil
 }
 
+// BroadcastToConnections 广播给指定连接列表
+func (m *ConnectionManager) BroadcastToConnections(connIDs []string, data []byte) error {
+	m.mu.RLock()
+	conns := make([]*Connection, 0, len(connIDs))
+	for _, connID := range connIDs {
+		if conn, exists := m.connections[connID]; exists && conn.Status == ConnectionStatusConnected {
+			conns = append(conns, conn)
+		}
+	}
+	m.mu.RUnlock()
+
+	errors := make([]error, 0)
+	for _, conn := range conns {
+		if err := conn.Send(data); err != nil {
+			errors = append(errors, fmt.Errorf("failed to send to %s: %w", conn.AgentID, err))
+		}
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("broadcast failed for %d connections", len(errors))
+	}
+
+	return nil
+}
+
 // CheckHeartbeat 检查心跳超时
 func (m *ConnectionManager) CheckHeartbeat(timeout time.Duration) []string {
 	m.mu.RLock()
@@ -0,0 +1,339 @@
+package communication
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoMailbox Agent从未连接过，没有信箱
+var ErrNoMailbox = errors.New("agent has no mailbox")
+
+// MailboxConfig 离线信箱配置
+type MailboxConfig struct {
+	MaxMessages int           // 每个Agent最多缓存的消息数，超出时丢弃最早的
+	TTL         time.Duration // 缓存超过该时间的消息被丢弃
+	Dir         string        // 持久化目录，为空时只保存在内存中
+}
+
+// DefaultMailboxConfig 默认信箱配置
+func DefaultMailboxConfig() *MailboxConfig {
+	return &MailboxConfig{
+		MaxMessages: 100,
+		TTL:         time.Hour,
+	}
+}
+
+// mailboxEntry 信箱中的消息
+type mailboxEntry struct {
+	Message  *Message  `json:"message"`
+	StoredAt time.Time `json:"stored_at"`
+}
+
+// mailboxFile 信箱的持久化格式
+type mailboxFile struct {
+	AgentID  string          `json:"agent_id"`
+	Messages []*mailboxEntry `json:"messages"`
+}
+
+// mailbox 单个Agent的信箱
+type mailbox struct {
+	entries  []*mailboxEntry
+	flushing bool // 是否有协程正在向Agent投递
+}
+
+// MailboxStore 离线信箱：缓存发给暂时断开的Agent的消息，重连后按顺序投递。
+// Agent首次连接时创建信箱，从未连接过的Agent的消息不缓存
+type MailboxStore struct {
+	config    *MailboxConfig
+	mailboxes map[string]*mailbox // agentID -> 信箱
+	mu        sync.Mutex
+}
+
+// NewMailboxStore 创建信箱存储，持久化的消息由Load恢复
+func NewMailboxStore(config *MailboxConfig) *MailboxStore {
+	if config == nil {
+		config = DefaultMailboxConfig()
+	}
+
+	return &MailboxStore{
+		config:    config,
+		mailboxes: make(map[string]*mailbox),
+	}
+}
+
+// Load 从持久化目录恢复信箱，丢弃已过期的消息
+func (m *MailboxStore) Load() error {
+	if m.config.Dir == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(m.config.Dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create mailbox directory: %w", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(m.config.Dir, "*.json"))
+	if err != nil {
+		return fmt.Errorf("failed to list mailboxes: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read mailbox %s: %w", path, err)
+		}
+
+		var file mailboxFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return fmt.Errorf("failed to parse mailbox %s: %w", path, err)
+		}
+
+		box := m.openLocked(file.AgentID)
+		box.entries = append(box.entries, file.Messages...)
+		m.expireLocked(file.AgentID, box, time.Now())
+	}
+
+	return nil
+}
+
+// Open 为Agent创建信箱，已存在时不做处理
+func (m *MailboxStore) Open(agentID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.openLocked(agentID)
+}
+
+func (m *MailboxStore) openLocked(agentID string) *mailbox {
+	box, exists := m.mailboxes[agentID]
+	if !exists {
+		box = &mailbox{entries: make([]*mailboxEntry, 0)}
+		m.mailboxes[agentID] = box
+	}
+	return box
+}
+
+// Store 把消息放入Agent的信箱。信箱已满时丢弃最早的消息；同一消息（例如重传）只缓存一次
+func (m *MailboxStore) Store(agentID string, msg *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	box, exists := m.mailboxes[agentID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrNoMailbox, agentID)
+	}
+
+	for _, entry := range box.entries {
+		if entry.Message.MessageID == msg.MessageID {
+			return nil
+		}
+	}
+
+	box.entries = append(box.entries, &mailboxEntry{Message: msg, StoredAt: time.Now()})
+	if m.config.MaxMessages > 0 && len(box.entries) > m.config.MaxMessages {
+		dropped := len(box.entries) - m.config.MaxMessages
+		box.entries = box.entries[dropped:]
+		log.Printf("Mailbox of agent %s is full, dropped %d oldest messages", agentID, dropped)
+	}
+
+	m.persistLocked(agentID, box)
+	return nil
+}
+
+// Discard 移除Agent信箱中匹配的消息，返回移除的消息数
+func (m *MailboxStore) Discard(agentID string, match func(msg *Message) bool) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	box, exists := m.mailboxes[agentID]
+	if !exists {
+		return 0
+	}
+
+	kept := box.entries[:0]
+	for _, entry := range box.entries {
+		if !match(entry.Message) {
+			kept = append(kept, entry)
+		}
+	}
+
+	discarded := len(box.entries) - len(kept)
+	if discarded > 0 {
+		box.entries = kept
+		m.persistLocked(agentID, box)
+	}
+	return discarded
+}
+
+// Remove 删除Agent的信箱及其持久化文件，返回丢弃的消息数。Agent注销后调用，
+// 之后发给它的消息不再缓存
+func (m *MailboxStore) Remove(agentID string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	box, exists := m.mailboxes[agentID]
+	if !exists {
+		return 0
+	}
+
+	dropped := len(box.entries)
+	box.entries = nil
+	m.persistLocked(agentID, box)
+	delete(m.mailboxes, agentID)
+	return dropped
+}
+
+// Pending 获取Agent信箱中待投递的消息数
+func (m *MailboxStore) Pending(agentID string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if box, exists := m.mailboxes[agentID]; exists {
+		return len(box.entries)
+	}
+	return 0
+}
+
+// Counts 获取每个非空信箱的消息数
+func (m *MailboxStore) Counts() map[string]int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	counts := make(map[string]int)
+	for agentID, box := range m.mailboxes {
+		if len(box.entries) > 0 {
+			counts[agentID] = len(box.entries)
+		}
+	}
+	return counts
+}
+
+// PurgeExpired 丢弃所有信箱中过期的消息，返回丢弃的消息数
+func (m *MailboxStore) PurgeExpired() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	count := 0
+	for agentID, box := range m.mailboxes {
+		count += m.expireLocked(agentID, box, now)
+	}
+	return count
+}
+
+// expireLocked 丢弃信箱中过期的消息，调用方持有m.mu
+func (m *MailboxStore) expireLocked(agentID string, box *mailbox, now time.Time) int {
+	if m.config.TTL <= 0 {
+		return 0
+	}
+
+	// 消息按放入顺序排列，过期的都在前面
+	expired := 0
+	for expired < len(box.entries) && now.Sub(box.entries[expired].StoredAt) > m.config.TTL {
+		expired++
+	}
+
+	if expired > 0 {
+		box.entries = box.entries[expired:]
+		log.Printf("Dropped %d expired messages from the mailbox of agent %s", expired, agentID)
+		m.persistLocked(agentID, box)
+	}
+	return expired
+}
+
+// beginFlush 标记开始投递，已有协程在投递时返回false
+func (m *MailboxStore) beginFlush(agentID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	box, exists := m.mailboxes[agentID]
+	if !exists || box.flushing {
+		return false
+	}
+	box.flushing = true
+	return true
+}
+
+// endFlush 投递中断（Agent断开），清除投递标记
+func (m *MailboxStore) endFlush(agentID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if box, exists := m.mailboxes[agentID]; exists {
+		box.flushing = false
+	}
+}
+
+// next 返回下一条待投递的消息。信箱为空时清除投递标记并返回nil，
+// 之后放入的消息由新的投递协程处理
+func (m *MailboxStore) next(agentID string) *Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	box, exists := m.mailboxes[agentID]
+	if !exists {
+		return nil
+	}
+
+	m.expireLocked(agentID, box, time.Now())
+	if len(box.entries) == 0 {
+		box.flushing = false
+		return nil
+	}
+	return box.entries[0].Message
+}
+
+// remove 移除已投递的队首消息
+func (m *MailboxStore) remove(agentID, messageID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	box, exists := m.mailboxes[agentID]
+	if !exists || len(box.entries) == 0 || box.entries[0].Message.MessageID != messageID {
+		return
+	}
+
+	box.entries = box.entries[1:]
+	m.persistLocked(agentID, box)
+}
+
+// persistLocked 把信箱写入持久化目录，信箱为空时删除文件。写入失败只记录日志，消息仍保留在内存中
+func (m *MailboxStore) persistLocked(agentID string, box *mailbox) {
+	if m.config.Dir == "" {
+		return
+	}
+
+	path := filepath.Join(m.config.Dir, url.PathEscape(agentID)+".json")
+	if len(box.entries) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			log.Printf("Failed to remove mailbox of agent %s: %v", agentID, err)
+		}
+		return
+	}
+
+	data, err := json.Marshal(&mailboxFile{AgentID: agentID, Messages: box.entries})
+	if err != nil {
+		log.Printf("Failed to serialize mailbox of agent %s: %v", agentID, err)
+		return
+	}
+
+	// 先写临时文件再重命名，避免进程中断时留下不完整的文件
+	tmp := strings.TrimSuffix(path, ".json") + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		log.Printf("Failed to persist mailbox of agent %s: %v", agentID, err)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		log.Printf("Failed to persist mailbox of agent %s: %v", agentID, err)
+	}
+}
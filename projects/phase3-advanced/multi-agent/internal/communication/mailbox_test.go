@@ -0,0 +1,98 @@
+package communication
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/agent-learning/multi-agent/protocol"
+)
+
+func numberedMessage(to string, n int) *Message {
+	msg := heartbeatMessage(protocol.ServerID)
+	msg.To = to
+	msg.MessageID = fmt.Sprintf("msg-%d", n)
+	return msg
+}
+
+func TestMailboxStore_Limits(t *testing.T) {
+	store := NewMailboxStore(&MailboxConfig{MaxMessages: 2, TTL: 50 * time.Millisecond})
+
+	if err := store.Store("agent-001", numberedMessage("agent-001", 0)); err == nil {
+		t.Fatal("Agents that never connected should have no mailbox")
+	}
+
+	store.Open("agent-001")
+	for i := 0; i < 3; i++ {
+		store.Store("agent-001", numberedMessage("agent-001", i))
+	}
+	// 重传的同一消息只缓存一次
+	store.Store("agent-001", numberedMessage("agent-001", 2))
+
+	if n := store.Pending("agent-001"); n != 2 {
+		t.Fatalf("Expected the mailbox to keep 2 messages, got %d", n)
+	}
+	if msg := store.next("agent-001"); msg.MessageID != "msg-1" {
+		t.Errorf("Expected the oldest message to be dropped, next is %s", msg.MessageID)
+	}
+
+	if n := store.Discard("agent-001", func(msg *Message) bool { return msg.MessageID == "msg-2" }); n != 1 {
+		t.Errorf("Expected 1 discarded message, got %d", n)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if n := store.PurgeExpired(); n != 1 || store.Pending("agent-001") != 0 {
+		t.Errorf("Expected the expired message to be purged, got %d", n)
+	}
+}
+
+func TestMailboxStore_Persistence(t *testing.T) {
+	dir := t.TempDir()
+	config := &MailboxConfig{MaxMessages: 10, TTL: time.Hour, Dir: dir}
+
+	store := NewMailboxStore(config)
+	store.Open("agent/001")
+	store.Open("agent-002")
+	for i := 0; i < 3; i++ {
+		store.Store("agent/001", numberedMessage("agent/001", i))
+	}
+	store.remove("agent/001", "msg-0")
+
+	// 空信箱不写文件
+	files, _ := os.ReadDir(dir)
+	if len(files) != 1 {
+		t.Fatalf("Expected one mailbox file, got %d", len(files))
+	}
+
+	restored := NewMailboxStore(config)
+	if err := restored.Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if counts := restored.Counts(); len(counts) != 1 || counts["agent/001"] != 2 {
+		t.Fatalf("Unexpected restored mailboxes: %v", counts)
+	}
+	if msg := restored.next("agent/001"); msg.MessageID != "msg-1" || msg.To != "agent/001" {
+		t.Errorf("Expected msg-1 first, got %+v", msg)
+	}
+
+	// 信箱文件只有所有者可读写
+	if info, err := os.Stat(filepath.Join(dir, files[0].Name())); err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	} else if info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected mailbox files to be written 0600, got %v", info.Mode().Perm())
+	}
+
+	// 删除信箱时一并删除文件，之后的消息不再缓存
+	if n := restored.Remove("agent/001"); n != 2 {
+		t.Errorf("Expected 2 dropped messages, got %d", n)
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Errorf("Expected the file of a removed mailbox to be deleted, got %d files", len(files))
+	}
+	if err := restored.Store("agent/001", numberedMessage("agent/001", 3)); !errors.Is(err, ErrNoMailbox) {
+		t.Errorf("Expected ErrNoMailbox after Remove, got %v", err)
+	}
+}
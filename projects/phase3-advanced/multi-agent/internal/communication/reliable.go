@@ -0,0 +1,338 @@
+package communication
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/agent-learning/multi-agent/protocol"
+)
+
+// ReliableConfig 需确认消息的重传配置
+type ReliableConfig struct {
+	MaxAttempts        int           // 包括首次发送在内的最大发送次数
+	InitialBackoff     time.Duration // 首次重传前等待确认的时间，之后每次翻倍
+	MaxBackoff         time.Duration
+	TTL                time.Duration // 超过该时间仍未确认的消息进入死信
+	CheckInterval      time.Duration // 检查到期重传的间隔
+	DeadLetterCapacity int           // 死信保留的最大条数，超出时丢弃最早的
+}
+
+// DefaultReliableConfig 默认重传配置
+func DefaultReliableConfig() *ReliableConfig {
+	return &ReliableConfig{
+		MaxAttempts:        5,
+		InitialBackoff:     time.Second,
+		MaxBackoff:         30 * time.Second,
+		TTL:                2 * time.Minute,
+		CheckInterval:      200 * time.Millisecond,
+		DeadLetterCapacity: 1000,
+	}
+}
+
+// DeadLetter 无法投递的消息
+type DeadLetter struct {
+	Message     *Message  `json:"message"`
+	Reason      string    `json:"reason"`
+	Attempts    int       `json:"attempts"`
+	FirstSentAt time.Time `json:"first_sent_at"`
+	DeadAt      time.Time `json:"dead_at"`
+}
+
+// DeadLetterStore 死信存储，按进入顺序保存
+type DeadLetterStore struct {
+	letters  []*DeadLetter
+	capacity int
+	mu       sync.RWMutex
+}
+
+// NewDeadLetterStore 创建死信存储，capacity<=0时不限制条数
+func NewDeadLetterStore(capacity int) *DeadLetterStore {
+	return &DeadLetterStore{
+		letters:  make([]*DeadLetter, 0),
+		capacity: capacity,
+	}
+}
+
+// Add 添加死信
+func (s *DeadLetterStore) Add(letter *DeadLetter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.letters = append(s.letters, letter)
+	if s.capacity > 0 && len(s.letters) > s.capacity {
+		s.letters = s.letters[len(s.letters)-s.capacity:]
+	}
+}
+
+// List 列出所有死信
+func (s *DeadLetterStore) List() []*DeadLetter {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	letters := make([]*DeadLetter, len(s.letters))
+	copy(letters, s.letters)
+	return letters
+}
+
+// Get 获取消息的死信
+func (s *DeadLetterStore) Get(messageID string) (*DeadLetter, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, letter := range s.letters {
+		if letter.Message.MessageID == messageID {
+			return letter, nil
+		}
+	}
+
+	return nil, fmt.Errorf("dead letter %s not found", messageID)
+}
+
+// Remove 移除消息的死信
+func (s *DeadLetterStore) Remove(messageID string) (*DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, letter := range s.letters {
+		if letter.Message.MessageID == messageID {
+			s.letters = append(s.letters[:i], s.letters[i+1:]...)
+			return letter, nil
+		}
+	}
+
+	return nil, fmt.Errorf("dead letter %s not found", messageID)
+}
+
+// Count 获取死信数
+func (s *DeadLetterStore) Count() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.letters)
+}
+
+// pendingDelivery 等待确认的消息
+type pendingDelivery struct {
+	msg       *Message
+	attempts  int
+	firstSent time.Time
+	nextRetry time.Time
+	backoff   time.Duration
+	lastError string // 最近一次发送失败的原因
+}
+
+// ReliableSender 至少一次投递：发送需确认的消息，按指数退避重传直到收到ACK，
+// 超过最大次数或TTL仍未确认的消息进入死信
+type ReliableSender struct {
+	config       *ReliableConfig
+	send         func(msg *Message) error
+	acks         *AckManager
+	deadLetters  *DeadLetterStore
+	pending      map[string]*pendingDelivery // messageID -> 投递状态
+	onDeadLetter func(letter *DeadLetter)
+	withdraw     func(msg *Message) // 放弃投递时撤回已缓存的消息，例如离线信箱中的副本
+	mu           sync.Mutex
+}
+
+// NewReliableSender 创建可靠发送器，send执行单次发送
+func NewReliableSender(config *ReliableConfig, send func(msg *Message) error) *ReliableSender {
+	if config == nil {
+		config = DefaultReliableConfig()
+	}
+
+	return &ReliableSender{
+		config:      config,
+		send:        send,
+		acks:        NewAckManager(config.TTL),
+		deadLetters: NewDeadLetterStore(config.DeadLetterCapacity),
+		pending:     make(map[string]*pendingDelivery),
+	}
+}
+
+// Send 发送消息并在确认前重传。首次发送失败（例如接收方未连接）时按退避重试，不返回错误
+func (r *ReliableSender) Send(msg *Message) error {
+	if msg.IsBroadcast() {
+		return fmt.Errorf("broadcast message %s cannot require an ack", msg.MessageID)
+	}
+	msg.RequireAck = true
+
+	r.mu.Lock()
+	if _, exists := r.pending[msg.MessageID]; exists {
+		r.mu.Unlock()
+		return fmt.Errorf("message %s is already pending", msg.MessageID)
+	}
+
+	now := time.Now()
+	delivery := &pendingDelivery{
+		msg:       msg,
+		firstSent: now,
+		backoff:   r.config.InitialBackoff,
+	}
+	r.pending[msg.MessageID] = delivery
+	r.acks.RegisterMessage(msg.MessageID)
+	r.attemptLocked(delivery, now)
+	r.mu.Unlock()
+
+	return nil
+}
+
+// HandleAck 处理接收方回复的ACK。迟到或重复的ACK被忽略
+func (r *ReliableSender) HandleAck(msg *Message) error {
+	var payload protocol.AckPayload
+	if err := msg.GetPayload(&payload); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	delivery, exists := r.pending[payload.MessageID]
+	if !exists || delivery.msg.To != msg.From {
+		r.mu.Unlock()
+		return nil
+	}
+	delete(r.pending, payload.MessageID)
+	r.mu.Unlock()
+
+	if payload.Success {
+		r.acks.Confirm(payload.MessageID, true, "")
+		return nil
+	}
+
+	// 接收方拒绝处理，重传不会成功
+	r.deadLetter(delivery, "rejected by recipient: "+payload.Error)
+	return nil
+}
+
+// ProcessRetries 重传到期的消息，并把超过最大次数或TTL的消息转入死信
+func (r *ReliableSender) ProcessRetries(now time.Time) {
+	var expired []*pendingDelivery
+	var reasons []string
+
+	r.mu.Lock()
+	for id, delivery := range r.pending {
+		reason := ""
+		switch {
+		case r.config.TTL > 0 && now.Sub(delivery.firstSent) >= r.config.TTL:
+			reason = fmt.Sprintf("not acknowledged within %s", r.config.TTL)
+		case now.Before(delivery.nextRetry):
+			continue
+		case delivery.attempts >= r.config.MaxAttempts:
+			reason = fmt.Sprintf("not acknowledged after %d attempts", delivery.attempts)
+		default:
+			r.attemptLocked(delivery, now)
+			continue
+		}
+
+		if delivery.lastError != "" {
+			reason += ": " + delivery.lastError
+		}
+		delete(r.pending, id)
+		expired = append(expired, delivery)
+		reasons = append(reasons, reason)
+	}
+	r.mu.Unlock()
+
+	for i, delivery := range expired {
+		r.deadLetter(delivery, reasons[i])
+	}
+
+	// 确认结果保留TTL，供GetAck查询
+	r.acks.CleanupExpired(r.config.TTL)
+}
+
+// attemptLocked 发送一次并安排下次重传，调用方持有r.mu
+func (r *ReliableSender) attemptLocked(delivery *pendingDelivery, now time.Time) {
+	delivery.attempts++
+	delivery.nextRetry = now.Add(delivery.backoff)
+
+	delivery.backoff *= 2
+	if r.config.MaxBackoff > 0 && delivery.backoff > r.config.MaxBackoff {
+		delivery.backoff = r.config.MaxBackoff
+	}
+
+	if err := r.send(delivery.msg); err != nil {
+		delivery.lastError = err.Error()
+	} else {
+		delivery.lastError = ""
+	}
+}
+
+// deadLetter 把消息转入死信并通知回调
+func (r *ReliableSender) deadLetter(delivery *pendingDelivery, reason string) {
+	letter := &DeadLetter{
+		Message:     delivery.msg,
+		Reason:      reason,
+		Attempts:    delivery.attempts,
+		FirstSentAt: delivery.firstSent,
+		DeadAt:      time.Now(),
+	}
+	r.deadLetters.Add(letter)
+	r.acks.Confirm(delivery.msg.MessageID, false, reason)
+	if r.withdraw != nil {
+		r.withdraw(delivery.msg)
+	}
+
+	r.mu.Lock()
+	onDeadLetter := r.onDeadLetter
+	r.mu.Unlock()
+
+	if onDeadLetter != nil {
+		onDeadLetter(letter)
+	}
+}
+
+// CancelPending 停止重传发给agentID的消息，返回取消的消息数。取消的消息不进入死信
+func (r *ReliableSender) CancelPending(agentID string) int {
+	r.mu.Lock()
+	var cancelled []*Message
+	for id, delivery := range r.pending {
+		if delivery.msg.To == agentID {
+			delete(r.pending, id)
+			cancelled = append(cancelled, delivery.msg)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, msg := range cancelled {
+		r.acks.Confirm(msg.MessageID, false, "cancelled")
+		if r.withdraw != nil {
+			r.withdraw(msg)
+		}
+	}
+	return len(cancelled)
+}
+
+// Redeliver 从死信中取出消息重新投递，重传次数和TTL重新计算
+func (r *ReliableSender) Redeliver(messageID string) error {
+	letter, err := r.deadLetters.Remove(messageID)
+	if err != nil {
+		return err
+	}
+	return r.Send(letter.Message)
+}
+
+// OnDeadLetter 设置消息进入死信时的回调
+func (r *ReliableSender) OnDeadLetter(handler func(letter *DeadLetter)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.onDeadLetter = handler
+}
+
+// PendingCount 获取等待确认的消息数
+func (r *ReliableSender) PendingCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.pending)
+}
+
+// GetAckManager 获取确认管理器，可用WaitForAck等待消息的投递结果
+func (r *ReliableSender) GetAckManager() *AckManager {
+	return r.acks
+}
+
+// GetDeadLetters 获取死信存储
+func (r *ReliableSender) GetDeadLetters() *DeadLetterStore {
+	return r.deadLetters
+}
@@ -0,0 +1,188 @@
+package communication
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agent-learning/multi-agent/protocol"
+)
+
+// newTestSender 创建记录发送次数的可靠发送器，fail为true时发送失败
+func newTestSender(fail *bool) (*ReliableSender, map[string]int) {
+	sent := make(map[string]int)
+	config := DefaultReliableConfig()
+	config.MaxAttempts = 3
+	config.InitialBackoff = time.Second
+	config.MaxBackoff = 3 * time.Second
+	config.TTL = time.Minute
+
+	sender := NewReliableSender(config, func(msg *Message) error {
+		sent[msg.MessageID]++
+		if fail != nil && *fail {
+			return fmt.Errorf("agent %s is not connected", msg.To)
+		}
+		return nil
+	})
+	return sender, sent
+}
+
+func ackMessage(from string, original *Message, success bool, errMsg string) *Message {
+	return protocol.NewAckMessage(from, original, &protocol.AckPayload{
+		MessageID: original.MessageID,
+		Success:   success,
+		Error:     errMsg,
+	})
+}
+
+func requestTo(agentID string) *Message {
+	msg := protocol.NewMessage(protocol.MessageTypeTaskRequest, protocol.ServerID, agentID)
+	msg.SetPayload(&protocol.TaskRequestPayload{TaskID: "task-1", TaskType: "echo"})
+	return msg
+}
+
+func TestReliableSender_Backoff(t *testing.T) {
+	fail := true
+	sender, sent := newTestSender(&fail)
+
+	var letters []*DeadLetter
+	sender.OnDeadLetter(func(letter *DeadLetter) {
+		letters = append(letters, letter)
+	})
+
+	msg := requestTo("agent-001")
+	if err := sender.Send(msg); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	start := time.Now()
+
+	// 重传间隔1s、2s，之后等待3s的确认
+	schedule := []struct {
+		after time.Duration
+		sends int
+	}{
+		{500 * time.Millisecond, 1},
+		{time.Second, 2},
+		{2500 * time.Millisecond, 2},
+		{3 * time.Second, 3},
+		{5900 * time.Millisecond, 3},
+	}
+	for _, step := range schedule {
+		sender.ProcessRetries(start.Add(step.after))
+		if sent[msg.MessageID] != step.sends {
+			t.Fatalf("After %s expected %d sends, got %d", step.after, step.sends, sent[msg.MessageID])
+		}
+	}
+	if len(letters) != 0 {
+		t.Fatalf("Message dead-lettered too early: %+v", letters[0])
+	}
+
+	sender.ProcessRetries(start.Add(6 * time.Second))
+	if len(letters) != 1 || sender.PendingCount() != 0 {
+		t.Fatalf("Expected a dead letter after the last attempt, got %d", len(letters))
+	}
+	if !strings.Contains(letters[0].Reason, "after 3 attempts: agent agent-001 is not connected") {
+		t.Errorf("Unexpected reason: %s", letters[0].Reason)
+	}
+
+	ack, _ := sender.GetAckManager().GetAck(msg.MessageID)
+	if ack == nil || ack.Status != AckStatusFailed {
+		t.Errorf("Expected a failed ack, got %+v", ack)
+	}
+}
+
+func TestReliableSender_HandleAck(t *testing.T) {
+	sender, _ := newTestSender(nil)
+
+	// 只接受接收方的ACK
+	msg := requestTo("agent-001")
+	sender.Send(msg)
+	sender.HandleAck(ackMessage("agent-002", msg, true, ""))
+	if sender.PendingCount() != 1 {
+		t.Fatal("ACK from another agent should be ignored")
+	}
+
+	sender.HandleAck(ackMessage("agent-001", msg, true, ""))
+	if sender.PendingCount() != 0 {
+		t.Fatal("Expected the message to be confirmed")
+	}
+
+	// 迟到的重复ACK被忽略
+	if err := sender.HandleAck(ackMessage("agent-001", msg, true, "")); err != nil {
+		t.Errorf("Duplicate ACK should be ignored: %v", err)
+	}
+
+	// 拒绝处理的消息直接进入死信
+	rejected := requestTo("agent-001")
+	sender.Send(rejected)
+	sender.HandleAck(ackMessage("agent-001", rejected, false, "unknown task"))
+
+	letter, err := sender.GetDeadLetters().Get(rejected.MessageID)
+	if err != nil || letter.Reason != "rejected by recipient: unknown task" || letter.Attempts != 1 {
+		t.Errorf("Unexpected dead letter: %+v (%v)", letter, err)
+	}
+
+	if err := sender.Send(protocol.NewMessage(protocol.MessageTypeBroadcast, protocol.ServerID, "broadcast")); err == nil {
+		t.Error("Broadcast messages should not require an ack")
+	}
+}
+
+func TestReliableSender_TTL(t *testing.T) {
+	sender, _ := newTestSender(nil)
+	sender.config.MaxAttempts = 100
+
+	msg := requestTo("agent-001")
+	sender.Send(msg)
+
+	sender.ProcessRetries(time.Now().Add(time.Minute))
+	letter, err := sender.GetDeadLetters().Get(msg.MessageID)
+	if err != nil || letter.Reason != "not acknowledged within 1m0s" {
+		t.Errorf("Unexpected dead letter: %+v (%v)", letter, err)
+	}
+}
+
+func TestReliableSender_CancelAndRedeliver(t *testing.T) {
+	sender, sent := newTestSender(nil)
+
+	first := requestTo("agent-001")
+	second := requestTo("agent-002")
+	sender.Send(first)
+	sender.Send(second)
+
+	if n := sender.CancelPending("agent-001"); n != 1 || sender.PendingCount() != 1 {
+		t.Fatalf("Expected 1 cancelled message, got %d", n)
+	}
+	if sender.GetDeadLetters().Count() != 0 {
+		t.Error("Cancelled messages should not be dead-lettered")
+	}
+
+	sender.HandleAck(ackMessage("agent-002", second, false, "busy"))
+	if err := sender.Redeliver(second.MessageID); err != nil {
+		t.Fatalf("Redeliver failed: %v", err)
+	}
+	if sent[second.MessageID] != 2 || sender.PendingCount() != 1 || sender.GetDeadLetters().Count() != 0 {
+		t.Errorf("Expected the message to be pending again, sent %d times", sent[second.MessageID])
+	}
+
+	if err := sender.Redeliver("missing"); err == nil {
+		t.Error("Expected an error for an unknown dead letter")
+	}
+}
+
+func TestDeadLetterStore_Capacity(t *testing.T) {
+	store := NewDeadLetterStore(2)
+	for i := 0; i < 3; i++ {
+		msg := requestTo("agent-001")
+		msg.MessageID = fmt.Sprintf("msg-%d", i)
+		store.Add(&DeadLetter{Message: msg})
+	}
+
+	letters := store.List()
+	if len(letters) != 2 || letters[0].Message.MessageID != "msg-1" {
+		t.Errorf("Expected the oldest letter to be dropped, got %d letters", len(letters))
+	}
+	if _, err := store.Remove("msg-2"); err != nil || store.Count() != 1 {
+		t.Errorf("Remove failed: %v", err)
+	}
+}
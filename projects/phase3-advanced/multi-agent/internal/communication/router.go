@@ -1,28 +1,40 @@
 package communication
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"sync"
+	"time"
+
+	"github.com/agent-learning/multi-agent/protocol"
 )
 
 // MessageHandler 消息处理器
 type MessageHandler func(msg *Message) error
 
-// MessageRouter 消息路由器
+// MessageRouter 消息路由器：按消息类型路由到处理器，按主题路由到订阅者
 type MessageRouter struct {
-	handlers map[string]MessageHandler // messageType -> handler
-	mu       sync.RWMutex
+	handlers      map[protocol.MessageType]MessageHandler // messageType -> handler
+	subscriptions map[string]map[string]bool              // subscriberID -> 订阅的主题模式
+	retained      map[string]*Message                     // topic -> 保留的最后一条消息
+	// Agent保留消息的限制，服务器发布的消息不受限制（见SetRetainLimits）
+	maxRetainedTopics int
+	maxRetainedSize   int
+	mu                sync.RWMutex
 }
 
 // NewMessageRouter 创建消息路由器
 func NewMessageRouter() *MessageRouter {
 	return &MessageRouter{
-		handlers: make(map[string]MessageHandler),
+		handlers:      make(map[protocol.MessageType]MessageHandler),
+		subscriptions: make(map[string]map[string]bool),
+		retained:      make(map[string]*Message),
 	}
 }
 
 // RegisterHandler 注册消息处理器
-func (r *MessageRouter) RegisterHandler(messageType string, handler MessageHandler) {
+func (r *MessageRouter) RegisterHandler(messageType protocol.MessageType, handler MessageHandler) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -30,7 +42,7 @@ func (r *MessageRouter) RegisterHandler(messageType string, handler MessageHandl
 }
 
 // UnregisterHandler 注销消息处理器
-func (r *MessageRouter) UnregisterHandler(messageType string) {
+func (r *MessageRouter) UnregisterHandler(messageType protocol.MessageType) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -44,14 +56,15 @@ func (r *MessageRouter) Route(msg *Message) error {
 	r.mu.RUnlock()
 
 	if !exists {
-		return fmt.Errorf("no handler for message type: %s", msg.Type)
+		return protocol.NewError(protocol.ErrorTypeProtocol, "UNSUPPORTED_TYPE",
+			fmt.Sprintf("no handler for message type: %s", msg.Type))
 	}
 
 	return handler(msg)
 }
 
 // HasHandler 检查是否有处理器
-func (r *MessageRouter) HasHandler(messageType string) bool {
+func (r *MessageRouter) HasHandler(messageType protocol.MessageType) bool {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
@@ -67,17 +80,8 @@ func (r *MessageRouter) GetHandlerCount() int {
 	return len(r.handlers)
 }
 
-// Message 消息定义（简化版，与protocol包兼容）
-type Message struct {
-	MessageID string                 `json:"message_id"`
-	Type      string                 `json:"type"`
-	From      string                 `json:"from"`
-	To        string                 `json:"to"`
-	Timestamp string                 `json:"timestamp"`
-	Priority  int                    `json:"priority,omitempty"`
-	Payload   map[string]interface{} `json:"payload"`
-	Metadata  map[string]interface{} `json:"metadata,omitempty"`
-}
+// Message 消息定义，即protocol.Message
+type Message = protocol.Message
 
 // MessageQueue 消息队列（用于异步处理）
 type MessageQueue struct {
@@ -140,6 +144,7 @@ type MessageDispatcher struct {
 	connMgr    *ConnectionManager
 	inQueue    *MessageQueue // 接收队列
 	outQueue   *MessageQueue // 发送队列
+	mailboxes  *MailboxStore // 离线信箱，为nil时不缓存
 	workerPool int
 	mu         sync.RWMutex
 }
@@ -173,6 +178,11 @@ func (d *MessageDispatcher) DispatchIncoming(msg *Message) error {
 
 // DispatchOutgoing 分发发送的消息
 func (d *MessageDispatcher) DispatchOutgoing(msg *Message) error {
+	// 带主题的消息只发给订阅者
+	if msg.Topic != "" {
+		return d.Publish(msg)
+	}
+
 	// 根据目标发送消息
 	if msg.To == "broadcast" {
 		// 广播消息
@@ -183,31 +193,158 @@ func (d *MessageDispatcher) DispatchOutgoing(msg *Message) error {
 	return d.SendToAgent(msg.To, msg)
 }
 
-// SendToAgent 发送消息给指定Agent
+// SendToAgent 发送消息给指定Agent。设置了离线信箱时，发给暂时断开的Agent的消息放入信箱，
+// 信箱中还有待投递的消息时新消息也放入信箱排在后面，保持投递顺序
 func (d *MessageDispatcher) SendToAgent(agentID string, msg *Message) error {
+	mailboxes := d.getMailboxes()
+	if mailboxes != nil && mailboxes.Pending(agentID) > 0 {
+		if err := mailboxes.Store(agentID, msg); err == nil {
+			d.FlushMailbox(agentID)
+			return nil
+		}
+	}
+
 	conn, err := d.connMgr.GetConnectionByAgent(agentID)
-	if err != nil {
-		return fmt.Errorf("failed to get connection for agent %s: %w", agentID, err)
+	if err == nil {
+		// 序列化消息
+		data, serr := SerializeMessage(msg)
+		if serr != nil {
+			return fmt.Errorf("failed to serialize message: %w", serr)
+		}
+
+		err = conn.Send(data)
+		if !errors.Is(err, ErrConnectionInactive) {
+			return err
+		}
+	}
+
+	// Agent离线或连接正在关闭
+	if mailboxes != nil && mailboxes.Store(agentID, msg) == nil {
+		return nil
+	}
+	return fmt.Errorf("failed to get connection for agent %s: %w", agentID, err)
+}
+
+// SetMailboxes 设置离线信箱
+func (d *MessageDispatcher) SetMailboxes(mailboxes *MailboxStore) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.mailboxes = mailboxes
+}
+
+func (d *MessageDispatcher) getMailboxes() *MailboxStore {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.mailboxes
+}
+
+// FlushMailbox 在后台按顺序投递Agent信箱中的消息，已在投递时不重复启动
+func (d *MessageDispatcher) FlushMailbox(agentID string) {
+	mailboxes := d.getMailboxes()
+	if mailboxes == nil || !mailboxes.beginFlush(agentID) {
+		return
+	}
+
+	go d.flushMailbox(mailboxes, agentID)
+}
+
+// 信箱投递遇到发送缓冲区已满时的重试间隔（逐次加倍）和次数上限，
+// 超过上限后暂停投递，消息留在信箱中，下次有消息发给该Agent或Agent重连时继续
+const (
+	flushRetryInterval    = 10 * time.Millisecond
+	flushMaxRetryInterval = time.Second
+	flushMaxRetries       = 10
+)
+
+// flushMailbox 投递信箱中的消息直到信箱为空、Agent断开或重试次数用尽
+func (d *MessageDispatcher) flushMailbox(mailboxes *MailboxStore, agentID string) {
+	retries := 0
+	backoff := flushRetryInterval
+
+	for {
+		msg := mailboxes.next(agentID)
+		if msg == nil {
+			return
+		}
+
+		conn, err := d.connMgr.GetConnectionByAgent(agentID)
+		if err != nil {
+			d.pauseFlush(mailboxes, agentID, "")
+			return
+		}
+
+		data, err := SerializeMessage(msg)
+		if err != nil {
+			log.Printf("Dropped message %s from the mailbox of agent %s: %v", msg.MessageID, agentID, err)
+			mailboxes.remove(agentID, msg.MessageID)
+			continue
+		}
+
+		if err := conn.Send(data); err != nil {
+			if errors.Is(err, ErrConnectionInactive) || retries >= flushMaxRetries {
+				log.Printf("Paused mailbox delivery to agent %s: %v", agentID, err)
+				d.pauseFlush(mailboxes, agentID, conn.ID)
+				return
+			}
+
+			// 发送缓冲区已满，稍后重试
+			retries++
+			time.Sleep(backoff)
+			if backoff *= 2; backoff > flushMaxRetryInterval {
+				backoff = flushMaxRetryInterval
+			}
+			continue
+		}
+		mailboxes.remove(agentID, msg.MessageID)
+		retries = 0
+		backoff = flushRetryInterval
 	}
+}
 
+// pauseFlush 清除投递标记。结束投递前Agent可能已用新连接重连，
+// 其FlushMailbox因投递标记未清除而没有启动，在这里重新启动
+func (d *MessageDispatcher) pauseFlush(mailboxes *MailboxStore, agentID, connID string) {
+	mailboxes.endFlush(agentID)
+
+	if conn, err := d.connMgr.GetConnectionByAgent(agentID); err == nil && conn.ID != connID {
+		d.FlushMailbox(agentID)
+	}
+}
+
+// BroadcastMessage 广播消息
+func (d *MessageDispatcher) BroadcastMessage(msg *Message) error {
 	// 序列化消息
 	data, err := SerializeMessage(msg)
 	if err != nil {
 		return fmt.Errorf("failed to serialize message: %w", err)
 	}
 
-	return conn.Send(data)
+	return d.connMgr.BroadcastToAll(data)
 }
 
-// BroadcastMessage 广播消息
-func (d *MessageDispatcher) BroadcastMessage(msg *Message) error {
+// Publish 把带主题的消息发给订阅了匹配主题的连接，Retain的消息同时保留为主题的最后一条消息。
+// 超过保留限制的消息不发布，返回*protocol.ErrorPayload
+func (d *MessageDispatcher) Publish(msg *Message) error {
+	if msg.Retain {
+		if err := d.router.Retain(msg); err != nil {
+			return err
+		}
+	}
+
+	connIDs := d.router.Subscribers(msg.Topic)
+	if len(connIDs) == 0 {
+		return nil
+	}
+
 	// 序列化消息
 	data, err := SerializeMessage(msg)
 	if err != nil {
 		return fmt.Errorf("failed to serialize message: %w", err)
 	}
 
-	return d.connMgr.BroadcastToAll(data)
+	return d.connMgr.BroadcastToConnections(connIDs, data)
 }
 
 // SendToAgents 发送消息给多个Agent
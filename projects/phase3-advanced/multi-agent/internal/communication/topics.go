@@ -0,0 +1,209 @@
+package communication
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/agent-learning/multi-agent/protocol"
+)
+
+// Subscribe 为订阅者添加主题模式，模式无效时不添加任何模式。
+// WebSocketServer以连接ID作为订阅者，同一Agent的多个连接各自订阅
+func (r *MessageRouter) Subscribe(subscriberID string, patterns ...string) error {
+	for _, pattern := range patterns {
+		if err := protocol.ValidateTopicPattern(pattern); err != nil {
+			return protocol.NewError(protocol.ErrorTypeValidation, "INVALID_TOPIC", err.Error())
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	subscribed, exists := r.subscriptions[subscriberID]
+	if !exists {
+		subscribed = make(map[string]bool)
+		r.subscriptions[subscriberID] = subscribed
+	}
+	for _, pattern := range patterns {
+		subscribed[pattern] = true
+	}
+
+	return nil
+}
+
+// Unsubscribe 移除订阅者的主题模式，只移除与订阅时相同的模式
+func (r *MessageRouter) Unsubscribe(subscriberID string, patterns ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	subscribed, exists := r.subscriptions[subscriberID]
+	if !exists {
+		return
+	}
+	for _, pattern := range patterns {
+		delete(subscribed, pattern)
+	}
+	if len(subscribed) == 0 {
+		delete(r.subscriptions, subscriberID)
+	}
+}
+
+// UnsubscribeAll 移除订阅者的所有订阅
+func (r *MessageRouter) UnsubscribeAll(subscriberID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.subscriptions, subscriberID)
+}
+
+// Subscriptions 获取订阅者的主题模式
+func (r *MessageRouter) Subscriptions(subscriberID string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	patterns := make([]string, 0, len(r.subscriptions[subscriberID]))
+	for pattern := range r.subscriptions[subscriberID] {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	return patterns
+}
+
+// Subscribers 获取订阅了匹配topic的模式的订阅者
+func (r *MessageRouter) Subscribers(topic string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	subscribers := make([]string, 0)
+	for subscriberID, subscribed := range r.subscriptions {
+		for pattern := range subscribed {
+			if protocol.MatchTopic(pattern, topic) {
+				subscribers = append(subscribers, subscriberID)
+				break
+			}
+		}
+	}
+	sort.Strings(subscribers)
+	return subscribers
+}
+
+// SetRetainLimits 限制每个Agent保留消息的主题数和负载（JSON）字节数，0表示不限制；
+// maxTopics为负数时只有服务器能保留消息
+func (r *MessageRouter) SetRetainLimits(maxTopics, maxSize int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.maxRetainedTopics = maxTopics
+	r.maxRetainedSize = maxSize
+}
+
+// Retain 保留消息为其主题的最后一条消息，负载为空的消息清除该主题保留的消息。
+// Agent的消息超过保留限制时返回*protocol.ErrorPayload，不保留
+func (r *MessageRouter) Retain(msg *Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(msg.Payload) == 0 {
+		delete(r.retained, msg.Topic)
+		return nil
+	}
+	if msg.From != protocol.ServerID {
+		if err := r.checkRetainLimits(msg); err != nil {
+			return err
+		}
+	}
+	r.retained[msg.Topic] = msg
+	return nil
+}
+
+// checkRetainLimits 检查Agent保留的消息是否超过限制，调用方持有写锁
+func (r *MessageRouter) checkRetainLimits(msg *Message) error {
+	if r.maxRetainedTopics < 0 {
+		return protocol.NewError(protocol.ErrorTypeAuthentication, "RETAIN_NOT_ALLOWED",
+			"only the server may retain messages")
+	}
+
+	if r.maxRetainedSize > 0 {
+		data, err := json.Marshal(msg.Payload)
+		if err != nil {
+			return protocol.NewError(protocol.ErrorTypeValidation, "INVALID_MESSAGE", err.Error())
+		}
+		if len(data) > r.maxRetainedSize {
+			return protocol.NewError(protocol.ErrorTypeResource, "RETAINED_TOO_LARGE",
+				fmt.Sprintf("retained payload of %d bytes exceeds the limit of %d bytes", len(data), r.maxRetainedSize))
+		}
+	}
+
+	// 替换自己保留的消息不增加主题数
+	if current, exists := r.retained[msg.Topic]; r.maxRetainedTopics == 0 || (exists && current.From == msg.From) {
+		return nil
+	}
+	count := 0
+	for _, retained := range r.retained {
+		if retained.From == msg.From {
+			count++
+		}
+	}
+	if count >= r.maxRetainedTopics {
+		return protocol.NewError(protocol.ErrorTypeResource, "RETAIN_LIMIT",
+			fmt.Sprintf("%s already retains messages on %d topics, the limit", msg.From, count))
+	}
+	return nil
+}
+
+// ClearRetainedBy 清除publisher保留的消息，返回清除的消息数
+func (r *MessageRouter) ClearRetainedBy(publisher string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cleared := 0
+	for topic, retained := range r.retained {
+		if retained.From == publisher {
+			delete(r.retained, topic)
+			cleared++
+		}
+	}
+	return cleared
+}
+
+// ClearRetained 清除主题匹配任一模式的保留消息，返回清除的消息数
+func (r *MessageRouter) ClearRetained(patterns ...string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cleared := 0
+	for topic := range r.retained {
+		for _, pattern := range patterns {
+			if protocol.MatchTopic(pattern, topic) {
+				delete(r.retained, topic)
+				cleared++
+				break
+			}
+		}
+	}
+	return cleared
+}
+
+// Retained 获取主题匹配任一模式的保留消息，按主题排序
+func (r *MessageRouter) Retained(patterns ...string) []*Message {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	topics := make([]string, 0)
+	for topic := range r.retained {
+		for _, pattern := range patterns {
+			if protocol.MatchTopic(pattern, topic) {
+				topics = append(topics, topic)
+				break
+			}
+		}
+	}
+	sort.Strings(topics)
+
+	messages := make([]*Message, len(topics))
+	for i, topic := range topics {
+		messages[i] = r.retained[topic]
+	}
+	return messages
+}
@@ -0,0 +1,125 @@
+package communication
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/agent-learning/multi-agent/protocol"
+)
+
+func topicMessage(topic string, payload *protocol.BroadcastPayload) *Message {
+	msg := protocol.NewMessage(protocol.MessageTypeBroadcast, protocol.ServerID, "broadcast")
+	msg.Topic = topic
+	if payload != nil {
+		msg.SetPayload(payload)
+	} else {
+		msg.Payload = map[string]interface{}{}
+	}
+	return msg
+}
+
+func TestMessageRouter_Subscriptions(t *testing.T) {
+	router := NewMessageRouter()
+
+	if err := router.Subscribe("conn-1", "tasks.code.*", "agents.#"); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	router.Subscribe("conn-2", "tasks.*.created")
+
+	// 无效模式不添加任何订阅
+	if err := router.Subscribe("conn-3", "tasks.*", "tasks.#.code"); err == nil {
+		t.Error("Expected an invalid pattern to be rejected")
+	}
+	if patterns := router.Subscriptions("conn-3"); len(patterns) != 0 {
+		t.Errorf("Expected no subscriptions after a failed subscribe, got %v", patterns)
+	}
+
+	if subscribers := router.Subscribers("tasks.code.created"); len(subscribers) != 2 {
+		t.Errorf("Expected both connections to match, got %v", subscribers)
+	}
+	if subscribers := router.Subscribers("tasks.code.status"); len(subscribers) != 1 || subscribers[0] != "conn-1" {
+		t.Errorf("Expected only conn-1 to match, got %v", subscribers)
+	}
+
+	router.Unsubscribe("conn-1", "tasks.code.*")
+	if subscribers := router.Subscribers("tasks.code.status"); len(subscribers) != 0 {
+		t.Errorf("Expected no subscribers after unsubscribe, got %v", subscribers)
+	}
+
+	router.UnsubscribeAll("conn-2")
+	if patterns := router.Subscriptions("conn-2"); len(patterns) != 0 {
+		t.Errorf("Expected all subscriptions to be removed, got %v", patterns)
+	}
+}
+
+func TestMessageRouter_Retained(t *testing.T) {
+	router := NewMessageRouter()
+
+	router.Retain(topicMessage("agents.agent-001.status", &protocol.BroadcastPayload{Event: "busy"}))
+	router.Retain(topicMessage("agents.agent-001.status", &protocol.BroadcastPayload{Event: "idle"}))
+	router.Retain(topicMessage("agents.agent-002.status", &protocol.BroadcastPayload{Event: "busy"}))
+
+	// 同一主题只保留最后一条，匹配多个模式的消息只返回一次
+	retained := router.Retained("agents.*.status", "agents.agent-001.#")
+	if len(retained) != 2 || retained[0].Topic != "agents.agent-001.status" || retained[0].Payload["event"] != "idle" {
+		t.Fatalf("Unexpected retained messages: %+v", retained)
+	}
+
+	router.Retain(topicMessage("agents.agent-001.status", nil))
+	if retained := router.Retained("#"); len(retained) != 1 || retained[0].Topic != "agents.agent-002.status" {
+		t.Errorf("Expected an empty payload to clear the retained message, got %+v", retained)
+	}
+
+	router.Retain(topicMessage("agents.agent-002.registered", &protocol.BroadcastPayload{Event: "registered"}))
+	if n := router.ClearRetained("agents.agent-002.#"); n != 2 || len(router.Retained("#")) != 0 {
+		t.Errorf("Expected both messages of agent-002 to be cleared, got %d", n)
+	}
+}
+
+func TestMessageRouter_RetainLimits(t *testing.T) {
+	router := NewMessageRouter()
+	router.SetRetainLimits(2, 64)
+
+	agentMessage := func(topic, event string) *Message {
+		msg := topicMessage(topic, &protocol.BroadcastPayload{Event: event})
+		msg.From = "agent-001"
+		return msg
+	}
+	expectCode := func(err error, code string) {
+		t.Helper()
+		var perr *protocol.ErrorPayload
+		if !errors.As(err, &perr) || perr.ErrorCode != code {
+			t.Errorf("Expected %s, got %v", code, err)
+		}
+	}
+
+	for _, topic := range []string{"reports.a", "reports.b"} {
+		if err := router.Retain(agentMessage(topic, "ok")); err != nil {
+			t.Fatalf("Retain %s failed: %v", topic, err)
+		}
+	}
+
+	// 替换自己保留的主题不计入主题数，新主题超过上限
+	if err := router.Retain(agentMessage("reports.a", "again")); err != nil {
+		t.Errorf("Expected replacing an own topic to succeed, got %v", err)
+	}
+	expectCode(router.Retain(agentMessage("reports.c", "ok")), "RETAIN_LIMIT")
+	expectCode(router.Retain(agentMessage("reports.a", strings.Repeat("x", 100))), "RETAINED_TOO_LARGE")
+
+	// 服务器发布的消息不受限制
+	if err := router.Retain(topicMessage("agents.agent-001.status", &protocol.BroadcastPayload{Event: strings.Repeat("x", 100)})); err != nil {
+		t.Errorf("Expected the server to retain without limits, got %v", err)
+	}
+
+	// 注销时清除Agent保留的消息
+	if n := router.ClearRetainedBy("agent-001"); n != 2 {
+		t.Errorf("Expected 2 messages of agent-001 to be cleared, got %d", n)
+	}
+	if retained := router.Retained("#"); len(retained) != 1 || retained[0].From != protocol.ServerID {
+		t.Errorf("Expected only the server's message to remain, got %+v", retained)
+	}
+
+	router.SetRetainLimits(-1, 0)
+	expectCode(router.Retain(agentMessage("reports.a", "ok")), "RETAIN_NOT_ALLOWED")
+}
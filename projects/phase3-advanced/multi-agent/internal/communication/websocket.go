@@ -3,12 +3,14 @@ package communication
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"sync"
 	"time"
 
+	"github.com/agent-learning/multi-agent/protocol"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
@@ -26,22 +28,50 @@ type WebSocketConfig struct {
 	PongTimeout       time.Duration
 	MessageQueueSize  int
 	WorkerPoolSize    int
+
+	// RequireSignatures 为true时拒绝未登记签名密钥的Agent的消息；
+	// 为false时只验证已登记密钥的Agent
+	RequireSignatures bool
+	// SignatureWindow 签名消息时间戳允许的偏差，也是消息ID去重的时长
+	SignatureWindow time.Duration
+
+	// Delivery 发送需确认消息（RequireAck）的重传配置
+	Delivery *ReliableConfig
+	// DedupWindow 接收需确认消息时记录MessageID的时长，应不短于发送方的重传时长
+	DedupWindow time.Duration
+
+	// Mailbox 离线信箱配置，为nil时发给离线Agent的消息直接失败
+	Mailbox *MailboxConfig
+
+	// ReservedTopics 只有服务器能发布的主题模式，Agent发布到匹配的主题时被拒绝
+	ReservedTopics []string
+	// MaxRetainedTopics 每个Agent最多保留消息的主题数，0表示不限制，负数表示只有服务器能保留消息
+	MaxRetainedTopics int
+	// MaxRetainedSize Agent保留消息的负载（JSON）最大字节数，0表示不限制
+	MaxRetainedSize int
 }
 
 // DefaultWebSocketConfig 默认配置
 func DefaultWebSocketConfig() *WebSocketConfig {
 	return &WebSocketConfig{
-		Host:             "0.0.0.0",
-		Port:             8080,
-		ReadBufferSize:   1024,
-		WriteBufferSize:  1024,
-		HandshakeTimeout: 10 * time.Second,
-		ReadTimeout:      60 * time.Second,
-		WriteTimeout:     10 * time.Second,
-		PingInterval:     30 * time.Second,
-		PongTimeout:      60 * time.Second,
-		MessageQueueSize: 1000,
-		WorkerPoolSize:   10,
+		Host:              "0.0.0.0",
+		Port:              8080,
+		ReadBufferSize:    1024,
+		WriteBufferSize:   1024,
+		HandshakeTimeout:  10 * time.Second,
+		ReadTimeout:       60 * time.Second,
+		WriteTimeout:      10 * time.Second,
+		PingInterval:      30 * time.Second,
+		PongTimeout:       60 * time.Second,
+		MessageQueueSize:  1000,
+		WorkerPoolSize:    10,
+		SignatureWindow:   5 * time.Minute,
+		Delivery:          DefaultReliableConfig(),
+		DedupWindow:       10 * time.Minute,
+		Mailbox:           DefaultMailboxConfig(),
+		ReservedTopics:    []string{"agents.#", "tasks.#", "results.#", "messages.#"},
+		MaxRetainedTopics: 32,
+		MaxRetainedSize:   16 * 1024,
 	}
 }
 
@@ -51,9 +81,19 @@ type WebSocketServer struct {
 	connMgr    *ConnectionManager
 	router     *MessageRouter
 	dispatcher *MessageDispatcher
+	validator  *protocol.Validator
+	keys       *protocol.KeyStore
+	replay     *protocol.ReplayGuard
+	reliable   *ReliableSender
+	dedup      *protocol.Deduplicator
+	mailboxes  *MailboxStore
 	upgrader   websocket.Upgrader
+	mux        *http.ServeMux
 	server     *http.Server
 
+	// onDisconnect Agent的最后一个连接断开时的回调
+	onDisconnect func(agentID string)
+
 	ctx    context.Context
 	cancel context.CancelFunc
 	wg     sync.WaitGroup
@@ -69,15 +109,21 @@ func NewWebSocketServer(config *WebSocketConfig) *WebSocketServer {
 
 	connMgr := NewConnectionManager()
 	router := NewMessageRouter()
+	router.SetRetainLimits(config.MaxRetainedTopics, config.MaxRetainedSize)
 	dispatcher := NewMessageDispatcher(router, connMgr, config.MessageQueueSize, config.WorkerPoolSize)
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	return &WebSocketServer{
+	s := &WebSocketServer{
 		config:     config,
 		connMgr:    connMgr,
 		router:     router,
 		dispatcher: dispatcher,
+		validator:  protocol.NewValidator(),
+		keys:       protocol.NewKeyStore(),
+		replay:     protocol.NewReplayGuard(config.SignatureWindow),
+		dedup:      protocol.NewDeduplicator(config.DedupWindow),
+		mux:        http.NewServeMux(),
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:   config.ReadBufferSize,
 			WriteBufferSize:  config.WriteBufferSize,
@@ -89,10 +135,49 @@ func NewWebSocketServer(config *WebSocketConfig) *WebSocketServer {
 		ctx:    ctx,
 		cancel: cancel,
 	}
+
+	// 需确认的消息直接发送，不经过发送队列，重传由ReliableSender安排
+	s.reliable = NewReliableSender(config.Delivery, func(msg *Message) error {
+		return dispatcher.SendToAgent(msg.To, msg)
+	})
+
+	if config.Mailbox != nil {
+		s.mailboxes = NewMailboxStore(config.Mailbox)
+		dispatcher.SetMailboxes(s.mailboxes)
+
+		// 进入死信或取消的消息不再从信箱投递
+		s.reliable.withdraw = func(msg *Message) {
+			s.mailboxes.Discard(msg.To, func(buffered *Message) bool {
+				return buffered.MessageID == msg.MessageID
+			})
+		}
+	}
+
+	s.mux.HandleFunc("/ws", s.handleWebSocket)
+	s.mux.HandleFunc("/health", s.handleHealth)
+
+	return s
+}
+
+// HandleFunc 在WebSocket服务器的HTTP端口上注册额外的处理函数
+func (s *WebSocketServer) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	s.mux.HandleFunc(pattern, handler)
+}
+
+// Handle 在WebSocket服务器的HTTP端口上注册额外的处理器
+func (s *WebSocketServer) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
 }
 
 // Start 启动服务器
 func (s *WebSocketServer) Start() error {
+	// 恢复持久化的离线信箱
+	if s.mailboxes != nil {
+		if err := s.mailboxes.Load(); err != nil {
+			return fmt.Errorf("failed to load mailboxes: %w", err)
+		}
+	}
+
 	// 启动消息处理worker
 	for i := 0; i < s.config.WorkerPoolSize; i++ {
 		s.wg.Add(1)
@@ -106,16 +191,15 @@ func (s *WebSocketServer) Start() error {
 	s.wg.Add(1)
 	go s.heartbeatChecker()
 
-	// 设置HTTP路由
-	mux := http.NewServeMux()
-	mux.HandleFunc("/ws", s.handleWebSocket)
-	mux.HandleFunc("/health", s.handleHealth)
+	// 启动需确认消息的重传
+	s.wg.Add(1)
+	go s.deliveryRetrier()
 
 	// 创建HTTP服务器
 	addr := fmt.Sprintf("%s:%d", s.config.Host, s.config.Port)
 	s.server = &http.Server{
 		Addr:    addr,
-		Handler: mux,
+		Handler: s.mux,
 	}
 
 	log.Printf("WebSocket server starting on %s", addr)
@@ -168,9 +252,18 @@ func (s *WebSocketServer) handleWebSocket(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// 创建连接
+	// 创建连接。agent_id未经验证，登记了密钥的Agent要等第一条验证通过的签名消息
+	// 确认身份后才接收消息、投递信箱，之前不算重连
 	connID := uuid.New().String()
 	conn := NewConnection(connID, agentID, wsConn)
+	if _, signed := s.GetKeyStore().Get(agentID); signed {
+		conn = NewUnverifiedConnection(connID, agentID, wsConn)
+	}
+
+	// 连接过的Agent断开后，发给它的消息缓存到信箱
+	if s.mailboxes != nil {
+		s.mailboxes.Open(agentID)
+	}
 
 	// 添加到连接管理器
 	if err := s.connMgr.AddConnection(conn); err != nil {
@@ -185,6 +278,11 @@ func (s *WebSocketServer) handleWebSocket(w http.ResponseWriter, r *http.Request
 	s.wg.Add(2)
 	go s.readPump(conn)
 	go s.writePump(conn)
+
+	// 投递断开期间缓存的消息
+	if conn.Verified() {
+		s.dispatcher.FlushMailbox(agentID)
+	}
 }
 
 // handleHealth 健康检查
@@ -205,8 +303,13 @@ func (s *WebSocketServer) handleHealth(w http.ResponseWriter, r *http.Request) {
 func (s *WebSocketServer) readPump(conn *Connection) {
 	defer func() {
 		s.wg.Done()
+		s.router.UnsubscribeAll(conn.ID)
 		s.connMgr.RemoveConnection(conn.ID)
 		log.Printf("Agent %s disconnected (connection: %s)", conn.AgentID, conn.ID)
+		// 未确认身份的连接不代表Agent，断开时Agent的状态不变
+		if conn.Verified() {
+			s.notifyDisconnect(conn.AgentID)
+		}
 	}()
 
 	// 设置读超时
@@ -233,15 +336,29 @@ func (s *WebSocketServer) readPump(conn *Connection) {
 			return
 		}
 
-		// 反序列化消息
-		msg, err := DeserializeMessage(data)
-		if err != nil {
-			log.Printf("Failed to deserialize message from agent %s: %v", conn.AgentID, err)
+		// 更新心跳
+		conn.UpdateHeartbeat()
+
+		msg, perr := s.parseFrame(conn, data)
+		if perr != nil {
+			log.Printf("Rejected message from agent %s: %v", conn.AgentID, perr)
+			s.replyConnectionError(conn, perr, msg)
 			continue
 		}
 
-		// 更新心跳
-		conn.UpdateHeartbeat()
+		// 第一条验证通过的签名消息确认连接身份，再投递断开期间缓存的消息
+		if s.connMgr.VerifyConnection(conn) {
+			log.Printf("Agent %s verified (connection: %s)", conn.AgentID, conn.ID)
+			s.dispatcher.FlushMailbox(conn.AgentID)
+		}
+
+		// 订阅属于连接而非Agent，在读协程中处理
+		if msg.Type == protocol.MessageTypeSubscribe || msg.Type == protocol.MessageTypeUnsubscribe {
+			if perr := s.handleSubscription(conn, msg); perr != nil {
+				s.replyError(conn.AgentID, perr, msg)
+			}
+			continue
+		}
 
 		// 入队处理
 		if err := s.dispatcher.EnqueueIncoming(msg); err != nil {
@@ -250,6 +367,247 @@ func (s *WebSocketServer) readPump(conn *Connection) {
 	}
 }
 
+// handleSubscription 更新连接的主题订阅，新订阅的连接立即收到匹配主题保留的消息
+func (s *WebSocketServer) handleSubscription(conn *Connection, msg *Message) *protocol.ErrorPayload {
+	var payload protocol.SubscribePayload
+	if err := msg.GetPayload(&payload); err != nil {
+		return protocol.NewError(protocol.ErrorTypeValidation, "INVALID_MESSAGE", err.Error())
+	}
+
+	if msg.Type == protocol.MessageTypeUnsubscribe {
+		s.router.Unsubscribe(conn.ID, payload.Topics...)
+		return nil
+	}
+
+	if err := s.router.Subscribe(conn.ID, payload.Topics...); err != nil {
+		var perr *protocol.ErrorPayload
+		if errors.As(err, &perr) {
+			return perr
+		}
+		return protocol.NewError(protocol.ErrorTypeValidation, "INVALID_TOPIC", err.Error())
+	}
+
+	for _, retained := range s.router.Retained(payload.Topics...) {
+		data, err := SerializeMessage(retained)
+		if err != nil {
+			log.Printf("Failed to serialize retained message of topic %s: %v", retained.Topic, err)
+			continue
+		}
+		if err := conn.Send(data); err != nil {
+			log.Printf("Failed to send retained message of topic %s to agent %s: %v", retained.Topic, conn.AgentID, err)
+		}
+	}
+
+	return nil
+}
+
+// notifyDisconnect Agent没有其他连接时调用断开回调，服务器停止时不调用
+func (s *WebSocketServer) notifyDisconnect(agentID string) {
+	if s.ctx.Err() != nil {
+		return
+	}
+	if _, err := s.connMgr.GetConnectionByAgent(agentID); err == nil {
+		return
+	}
+
+	s.mu.RLock()
+	onDisconnect := s.onDisconnect
+	s.mu.RUnlock()
+
+	if onDisconnect != nil {
+		onDisconnect(agentID)
+	}
+}
+
+// parseFrame 反序列化并验证入站帧，失败时返回的错误可直接回复给发送方
+func (s *WebSocketServer) parseFrame(conn *Connection, data []byte) (*Message, *protocol.ErrorPayload) {
+	if err := s.validator.ValidateSize(data); err != nil {
+		return nil, protocol.NewError(protocol.ErrorTypeProtocol, "MESSAGE_TOO_LARGE", err.Error())
+	}
+
+	msg, err := DeserializeMessage(data)
+	if err != nil {
+		return nil, protocol.NewError(protocol.ErrorTypeProtocol, "MALFORMED_MESSAGE", err.Error())
+	}
+
+	if err := s.validator.Validate(msg); err != nil {
+		return msg, protocol.NewError(protocol.ErrorTypeValidation, "INVALID_MESSAGE", err.Error())
+	}
+
+	// 连接只能以自己的身份发送消息
+	if msg.From != conn.AgentID {
+		return msg, protocol.NewError(protocol.ErrorTypeValidation, "SENDER_MISMATCH",
+			fmt.Sprintf("message from %s received on the connection of %s", msg.From, conn.AgentID))
+	}
+
+	if perr := s.verifySignature(msg); perr != nil {
+		return msg, perr
+	}
+
+	return msg, nil
+}
+
+// verifySignature 验证已登记密钥的Agent的签名，并拒绝重放的消息
+func (s *WebSocketServer) verifySignature(msg *Message) *protocol.ErrorPayload {
+	key, exists := s.GetKeyStore().Get(msg.From)
+	if !exists {
+		if s.config.RequireSignatures {
+			return protocol.NewError(protocol.ErrorTypeAuthentication, "UNKNOWN_SIGNER",
+				fmt.Sprintf("no signing key registered for %s", msg.From))
+		}
+		return nil
+	}
+
+	if err := key.Verify(msg); err != nil {
+		return protocol.NewError(protocol.ErrorTypeAuthentication, "INVALID_SIGNATURE", err.Error())
+	}
+
+	// 需确认消息的重传与原消息相同，仍要在时间窗口内，由dispatchIncoming去重并重新确认
+	check := s.replay.Check
+	if msg.RequireAck && s.dedup.Contains(msg.From, msg.MessageID) {
+		check = s.replay.CheckRetransmit
+	}
+
+	if err := check(msg); err != nil {
+		return protocol.NewError(protocol.ErrorTypeAuthentication, "REPLAYED_MESSAGE", err.Error())
+	}
+
+	return nil
+}
+
+// replyError 向agentID回复ERROR消息，original为引发错误的消息（可为nil）
+func (s *WebSocketServer) replyError(agentID string, payload *protocol.ErrorPayload, original *Message) {
+	// 不回复ERROR消息，避免双方互相回复
+	if original != nil && original.Type == protocol.MessageTypeError {
+		return
+	}
+
+	reply := protocol.NewErrorMessage(protocol.ServerID, agentID, payload, original)
+	if err := s.dispatcher.SendToAgent(agentID, reply); err != nil {
+		log.Printf("Failed to send error to agent %s: %v", agentID, err)
+	}
+}
+
+// replyConnectionError 回复连接上被拒绝的消息。未确认身份的连接直接回复，
+// 不经SendToAgent发给该Agent已确认的连接或信箱
+func (s *WebSocketServer) replyConnectionError(conn *Connection, payload *protocol.ErrorPayload, original *Message) {
+	if conn.Verified() {
+		s.replyError(conn.AgentID, payload, original)
+		return
+	}
+	if original != nil && original.Type == protocol.MessageTypeError {
+		return
+	}
+
+	data, err := SerializeMessage(protocol.NewErrorMessage(protocol.ServerID, conn.AgentID, payload, original))
+	if err == nil {
+		err = conn.Send(data)
+	}
+	if err != nil {
+		log.Printf("Failed to send error to connection %s: %v", conn.ID, err)
+	}
+}
+
+// dispatchIncoming 分发接收的消息
+func (s *WebSocketServer) dispatchIncoming(msg *Message) error {
+	// Agent之间的消息直接转发，ACK和去重由接收方处理
+	if msg.To != protocol.ServerID && !msg.IsBroadcast() {
+		return s.forward(msg)
+	}
+
+	if msg.Encrypted {
+		return protocol.NewError(protocol.ErrorTypeProtocol, "ENCRYPTED_PAYLOAD",
+			"payloads addressed to the hub must not be encrypted")
+	}
+
+	if msg.Type == protocol.MessageTypeAck {
+		return s.reliable.HandleAck(msg)
+	}
+
+	// 带主题的消息发给订阅者，不路由到处理器。个别订阅者发送失败不回复发布方
+	if msg.Topic != "" {
+		if s.isReservedTopic(msg.Topic) {
+			return protocol.NewError(protocol.ErrorTypeAuthentication, "RESERVED_TOPIC",
+				fmt.Sprintf("topic %s is reserved for the server", msg.Topic))
+		}
+		if err := s.dispatcher.Publish(msg); err != nil {
+			// 超过保留限制的消息未发布，回复发布方
+			var perr *protocol.ErrorPayload
+			if errors.As(err, &perr) {
+				return perr
+			}
+			log.Printf("Failed to publish message %s to topic %s: %v", msg.MessageID, msg.Topic, err)
+		}
+		return nil
+	}
+
+	if msg.RequireAck {
+		return s.routeOnce(msg)
+	}
+
+	return s.route(msg)
+}
+
+// isReservedTopic 检查主题是否只能由服务器发布
+func (s *WebSocketServer) isReservedTopic(topic string) bool {
+	for _, pattern := range s.config.ReservedTopics {
+		if protocol.MatchTopic(pattern, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// route 路由到消息处理器，处理器panic时转为错误
+func (s *WebSocketServer) route(msg *Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = protocol.NewError(protocol.ErrorTypeExecution, "HANDLER_PANIC",
+				fmt.Sprintf("handler for %s panicked: %v", msg.Type, r))
+		}
+	}()
+
+	return s.dispatcher.DispatchIncoming(msg)
+}
+
+// routeOnce 处理需确认的消息：重传的消息不再路由，只重新确认；首次收到时路由后回复ACK
+func (s *WebSocketServer) routeOnce(msg *Message) error {
+	if ack, duplicate := s.dedup.Check(msg.From, msg.MessageID); duplicate {
+		// 仍在处理中的消息等处理完成后再确认
+		if ack != nil {
+			s.sendAck(msg, ack)
+		}
+		return nil
+	}
+
+	err := s.route(msg)
+
+	ack := &protocol.AckPayload{MessageID: msg.MessageID, Success: err == nil}
+	if err != nil {
+		ack.Error = err.Error()
+	}
+	s.dedup.Done(msg.From, msg.MessageID, ack)
+	s.sendAck(msg, ack)
+
+	return err
+}
+
+// sendAck 向消息的发送方回复ACK
+func (s *WebSocketServer) sendAck(original *Message, ack *protocol.AckPayload) {
+	reply := protocol.NewAckMessage(protocol.ServerID, original, ack)
+	if err := s.dispatcher.SendToAgent(original.From, reply); err != nil {
+		log.Printf("Failed to ack message %s from agent %s: %v", original.MessageID, original.From, err)
+	}
+}
+
+// forward 把消息原样转发给接收方，不解读负载（可能是密文）
+func (s *WebSocketServer) forward(msg *Message) error {
+	if err := s.dispatcher.SendToAgent(msg.To, msg); err != nil {
+		return protocol.NewError(protocol.ErrorTypeResource, "RECIPIENT_UNAVAILABLE", err.Error())
+	}
+	return nil
+}
+
 // writePump 发送消息
 func (s *WebSocketServer) writePump(conn *Connection) {
 	defer s.wg.Done()
@@ -297,15 +655,20 @@ func (s *WebSocketServer) incomingMessageWorker(id int) {
 		select {
 		case <-s.ctx.Done():
 			return
-		default:
-			msg := s.dispatcher.inQueue.DequeueWait()
+		case msg := <-s.dispatcher.inQueue.messages:
 			if msg == nil {
 				continue
 			}
 
-			// 分发消息
-			if err := s.dispatcher.DispatchIncoming(msg); err != nil {
+			// 分发消息，处理失败时向发送方回复ERROR
+			if err := s.dispatchIncoming(msg); err != nil {
 				log.Printf("Worker %d: failed to dispatch incoming message: %v", id, err)
+
+				var payload *protocol.ErrorPayload
+				if !errors.As(err, &payload) {
+					payload = protocol.NewError(protocol.ErrorTypeExecution, "HANDLER_FAILED", err.Error())
+				}
+				s.replyError(msg.From, payload, msg)
 			}
 		}
 	}
@@ -319,8 +682,7 @@ func (s *WebSocketServer) outgoingMessageWorker(id int) {
 		select {
 		case <-s.ctx.Done():
 			return
-		default:
-			msg := s.dispatcher.outQueue.DequeueWait()
+		case msg := <-s.dispatcher.outQueue.messages:
 			if msg == nil {
 				continue
 			}
@@ -350,17 +712,42 @@ func (s *WebSocketServer) heartbeatChecker() {
 				log.Printf("Connection %s heartbeat timeout", connID)
 				s.connMgr.RemoveConnection(connID)
 			}
+
+			// 顺带清理信箱中过期的消息
+			if s.mailboxes != nil {
+				s.mailboxes.PurgeExpired()
+			}
+		}
+	}
+}
+
+// deliveryRetrier 定期重传未确认的消息
+func (s *WebSocketServer) deliveryRetrier() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.reliable.config.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.reliable.ProcessRetries(now)
 		}
 	}
 }
 
 // RegisterMessageHandler 注册消息处理器
-func (s *WebSocketServer) RegisterMessageHandler(messageType string, handler MessageHandler) {
+func (s *WebSocketServer) RegisterMessageHandler(messageType protocol.MessageType, handler MessageHandler) {
 	s.router.RegisterHandler(messageType, handler)
 }
 
-// SendMessage 发送消息
+// SendMessage 发送消息。RequireAck的消息在收到ACK前按退避重传，无法投递时进入死信
 func (s *WebSocketServer) SendMessage(msg *Message) error {
+	if msg.RequireAck {
+		return s.reliable.Send(msg)
+	}
 	return s.dispatcher.EnqueueOutgoing(msg)
 }
 
@@ -370,16 +757,62 @@ func (s *WebSocketServer) BroadcastMessage(msg *Message) error {
 	return s.dispatcher.EnqueueOutgoing(msg)
 }
 
+// Publish 把消息发布到topic，只投递给订阅了匹配主题的连接。retain为true时保留为主题的最后一条消息
+func (s *WebSocketServer) Publish(topic string, msg *Message, retain bool) error {
+	if err := protocol.ValidateTopic(topic); err != nil {
+		return err
+	}
+
+	msg.To = "broadcast"
+	msg.Topic = topic
+	msg.Retain = retain
+	return s.dispatcher.EnqueueOutgoing(msg)
+}
+
 // GetConnectionManager 获取连接管理器
 func (s *WebSocketServer) GetConnectionManager() *ConnectionManager {
 	return s.connMgr
 }
 
+// OnDisconnect 设置Agent断开连接（包括心跳超时）时的回调
+func (s *WebSocketServer) OnDisconnect(handler func(agentID string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.onDisconnect = handler
+}
+
+// SetKeyStore 设置Agent签名密钥库
+func (s *WebSocketServer) SetKeyStore(keys *protocol.KeyStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys = keys
+}
+
+// GetKeyStore 获取Agent签名密钥库
+func (s *WebSocketServer) GetKeyStore() *protocol.KeyStore {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.keys
+}
+
 // GetRouter 获取路由器
 func (s *WebSocketServer) GetRouter() *MessageRouter {
 	return s.router
 }
 
+// GetReliableSender 获取需确认消息的发送器，用于查询死信和投递结果
+func (s *WebSocketServer) GetReliableSender() *ReliableSender {
+	return s.reliable
+}
+
+// GetMailboxes 获取离线信箱，未启用时为nil
+func (s *WebSocketServer) GetMailboxes() *MailboxStore {
+	return s.mailboxes
+}
+
 // GetDispatcher 获取分发器
 func (s *WebSocketServer) GetDispatcher() *MessageDispatcher {
 	return s.dispatcher
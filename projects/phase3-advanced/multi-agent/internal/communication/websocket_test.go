@@ -0,0 +1,701 @@
+package communication
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/agent-learning/multi-agent/protocol"
+	"github.com/gorilla/websocket"
+)
+
+// startTestServer 在空闲端口上启动WebSocket服务器，configure可在启动前修改配置
+func startTestServer(t *testing.T, configure ...func(*WebSocketConfig)) *WebSocketServer {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	config := DefaultWebSocketConfig()
+	config.Host = "127.0.0.1"
+	config.Port = port
+	config.WorkerPoolSize = 2
+	for _, fn := range configure {
+		fn(config)
+	}
+
+	server := NewWebSocketServer(config)
+	if err := server.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	t.Cleanup(func() { server.Stop() })
+
+	return server
+}
+
+// dialTestServer 以agentID身份连接服务器
+func dialTestServer(t *testing.T, server *WebSocketServer, agentID string) *websocket.Conn {
+	t.Helper()
+
+	url := fmt.Sprintf("ws://%s:%d/ws?agent_id=%s", server.config.Host, server.config.Port, agentID)
+	var conn *websocket.Conn
+	var err error
+	for i := 0; i < 50; i++ {
+		if conn, _, err = websocket.DefaultDialer.Dial(url, nil); err == nil {
+			t.Cleanup(func() { conn.Close() })
+			return conn
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("Dial failed: %v", err)
+	return nil
+}
+
+// readTestMessage 读取下一条消息
+func readTestMessage(t *testing.T, conn *websocket.Conn) *Message {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("ReadMessage failed: %v", err)
+	}
+
+	msg, err := DeserializeMessage(data)
+	if err != nil {
+		t.Fatalf("DeserializeMessage failed: %v", err)
+	}
+	return msg
+}
+
+// expectError 读取下一条消息并检查是否为指定错误码的ERROR消息
+func expectError(t *testing.T, conn *websocket.Conn, code string) *protocol.ErrorPayload {
+	t.Helper()
+
+	msg := readTestMessage(t, conn)
+	if msg.Type != protocol.MessageTypeError {
+		t.Fatalf("Expected ERROR message, got %s", msg.Type)
+	}
+
+	var payload protocol.ErrorPayload
+	if err := msg.GetPayload(&payload); err != nil {
+		t.Fatalf("GetPayload failed: %v", err)
+	}
+	if payload.ErrorCode != code {
+		t.Errorf("Expected error code %s, got %s (%s)", code, payload.ErrorCode, payload.ErrorMessage)
+	}
+	return &payload
+}
+
+func heartbeatMessage(from string) *Message {
+	msg := protocol.NewMessage(protocol.MessageTypeHeartbeat, from, protocol.ServerID)
+	msg.SetPayload(&protocol.HeartbeatPayload{Status: protocol.AgentStatusIdle})
+	return msg
+}
+
+func TestWebSocketServer_RejectsInvalidFrames(t *testing.T) {
+	server := startTestServer(t)
+
+	handled := make(chan *Message, 1)
+	server.RegisterMessageHandler(protocol.MessageTypeHeartbeat, func(msg *Message) error {
+		handled <- msg
+		return nil
+	})
+
+	conn := dialTestServer(t, server, "agent-001")
+
+	// 非JSON帧
+	conn.WriteMessage(websocket.TextMessage, []byte("not json"))
+	expectError(t, conn, "MALFORMED_MESSAGE")
+
+	// 缺少必需负载字段
+	invalid := protocol.NewMessage(protocol.MessageTypeHeartbeat, "agent-001", protocol.ServerID)
+	conn.WriteJSON(invalid)
+	payload := expectError(t, conn, "INVALID_MESSAGE")
+	if payload.ErrorType != protocol.ErrorTypeValidation || payload.OriginalMessageID != invalid.MessageID {
+		t.Errorf("Unexpected error payload: %+v", payload)
+	}
+
+	// 冒充其他Agent
+	conn.WriteJSON(heartbeatMessage("agent-002"))
+	expectError(t, conn, "SENDER_MISMATCH")
+
+	// 有效消息仍被路由，连接未被断开
+	conn.WriteJSON(heartbeatMessage("agent-001"))
+	select {
+	case msg := <-handled:
+		if msg.From != "agent-001" {
+			t.Errorf("Expected message from agent-001, got %s", msg.From)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Valid message was not routed")
+	}
+}
+
+func TestWebSocketServer_HandlerErrors(t *testing.T) {
+	server := startTestServer(t)
+
+	server.RegisterMessageHandler(protocol.MessageTypeHeartbeat, func(msg *Message) error {
+		var payload protocol.HeartbeatPayload
+		if err := msg.GetPayload(&payload); err != nil {
+			return err
+		}
+		if payload.Load > 0.5 {
+			panic("overloaded")
+		}
+		return fmt.Errorf("plain failure")
+	})
+
+	conn := dialTestServer(t, server, "agent-001")
+
+	// 负载类型错误时返回类型化的验证错误
+	msg := heartbeatMessage("agent-001")
+	msg.Payload["load"] = "high"
+	conn.WriteJSON(msg)
+	payload := expectError(t, conn, "INVALID_PAYLOAD")
+	if payload.ErrorType != protocol.ErrorTypeValidation {
+		t.Errorf("Expected validation error, got %s", payload.ErrorType)
+	}
+
+	// 处理器panic不会终止worker
+	msg = heartbeatMessage("agent-001")
+	msg.Payload["load"] = 0.9
+	conn.WriteJSON(msg)
+	expectError(t, conn, "HANDLER_PANIC")
+
+	// 普通错误
+	conn.WriteJSON(heartbeatMessage("agent-001"))
+	payload = expectError(t, conn, "HANDLER_FAILED")
+	if payload.ErrorType != protocol.ErrorTypeExecution {
+		t.Errorf("Expected execution error, got %s", payload.ErrorType)
+	}
+
+	// 没有处理器的消息类型
+	query := protocol.NewMessage(protocol.MessageTypeStatusQuery, "agent-001", protocol.ServerID)
+	query.Payload["query_type"] = "agents"
+	conn.WriteJSON(query)
+	expectError(t, conn, "UNSUPPORTED_TYPE")
+}
+
+func TestWebSocketServer_VerifiesSignatures(t *testing.T) {
+	server := startTestServer(t)
+
+	handled := make(chan *Message, 4)
+	server.RegisterMessageHandler(protocol.MessageTypeHeartbeat, func(msg *Message) error {
+		handled <- msg
+		return nil
+	})
+
+	key := protocol.NewHMACKey([]byte("secret"))
+	server.GetKeyStore().Register("agent-001", key)
+
+	conn := dialTestServer(t, server, "agent-001")
+
+	// 已登记密钥的Agent必须签名
+	conn.WriteJSON(heartbeatMessage("agent-001"))
+	payload := expectError(t, conn, "INVALID_SIGNATURE")
+	if payload.ErrorType != protocol.ErrorTypeAuthentication {
+		t.Errorf("Expected authentication error, got %s", payload.ErrorType)
+	}
+
+	// 签名后被篡改
+	tampered := heartbeatMessage("agent-001")
+	key.Sign(tampered)
+	tampered.Payload["load"] = 0.9
+	conn.WriteJSON(tampered)
+	expectError(t, conn, "INVALID_SIGNATURE")
+
+	signed := heartbeatMessage("agent-001")
+	key.Sign(signed)
+	conn.WriteJSON(signed)
+	select {
+	case <-handled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Signed message was not routed")
+	}
+
+	// 重放同一条消息
+	conn.WriteJSON(signed)
+	expectError(t, conn, "REPLAYED_MESSAGE")
+
+	// 需确认消息的重传只重新确认；换了时间戳的同ID消息仍是重放
+	reliable := heartbeatMessage("agent-001")
+	reliable.RequireAck = true
+	key.Sign(reliable)
+	for i := 0; i < 2; i++ {
+		conn.WriteJSON(reliable)
+		if reply := readTestMessage(t, conn); reply.Type != protocol.MessageTypeAck {
+			t.Fatalf("Expected an ack of the retransmission, got %+v", reply)
+		}
+	}
+	<-handled
+	reused := *reliable
+	reused.Timestamp = time.Now().Add(time.Second).Format(time.RFC3339)
+	key.Sign(&reused)
+	conn.WriteJSON(&reused)
+	expectError(t, conn, "REPLAYED_MESSAGE")
+
+	// 过期的时间戳
+	stale := heartbeatMessage("agent-001")
+	stale.Timestamp = time.Now().Add(-time.Hour).Format(time.RFC3339)
+	key.Sign(stale)
+	conn.WriteJSON(stale)
+	expectError(t, conn, "REPLAYED_MESSAGE")
+
+	// 未登记密钥的Agent只在要求签名时被拒绝
+	other := dialTestServer(t, server, "agent-002")
+	other.WriteJSON(heartbeatMessage("agent-002"))
+	select {
+	case <-handled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Unsigned message from an agent without a key was not routed")
+	}
+
+	server.config.RequireSignatures = true
+	other.WriteJSON(heartbeatMessage("agent-002"))
+	expectError(t, other, "UNKNOWN_SIGNER")
+}
+
+func TestWebSocketServer_ForwardsEncryptedMessages(t *testing.T) {
+	server := startTestServer(t)
+
+	sender, _ := protocol.NewKeyring()
+	receiver, _ := protocol.NewKeyring()
+	sender.AddPeer("agent-002", receiver.PublicKey())
+	receiver.AddPeer("agent-001", sender.PublicKey())
+
+	conn1 := dialTestServer(t, server, "agent-001")
+	conn2 := dialTestServer(t, server, "agent-002")
+
+	// 等待两个连接都登记完成
+	for i := 0; i < 50 && server.GetConnectionManager().GetConnectionCount() < 2; i++ {
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	msg := protocol.NewMessage(protocol.MessageTypeTaskRequest, "agent-001", "agent-002")
+	msg.SetPayload(&protocol.TaskRequestPayload{TaskID: "task-001", Input: "secret"})
+	sender.Encrypt(msg, protocol.EncryptionX25519ChaCha20Poly1305)
+	conn1.WriteJSON(msg)
+
+	forwarded := readTestMessage(t, conn2)
+	if !forwarded.Encrypted || forwarded.MessageID != msg.MessageID || forwarded.Payload["task_id"] != nil {
+		t.Fatalf("Expected the ciphertext to be forwarded unchanged, got %+v", forwarded)
+	}
+	if err := receiver.Decrypt(forwarded); err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+	if forwarded.Payload["input"] != "secret" {
+		t.Errorf("Unexpected decrypted payload: %+v", forwarded.Payload)
+	}
+
+	// Hub不解密发给自己的消息
+	toServer := heartbeatMessage("agent-001")
+	toServer.To = "agent-002"
+	sender.Encrypt(toServer, protocol.EncryptionAES256GCM)
+	toServer.To = protocol.ServerID
+	conn1.WriteJSON(toServer)
+	expectError(t, conn1, "ENCRYPTED_PAYLOAD")
+
+	// 接收方不在线
+	offline := heartbeatMessage("agent-001")
+	offline.To = "agent-003"
+	conn1.WriteJSON(offline)
+	expectError(t, conn1, "RECIPIENT_UNAVAILABLE")
+}
+
+func TestWebSocketServer_OnDisconnect(t *testing.T) {
+	server := startTestServer(t)
+
+	disconnected := make(chan string, 2)
+	server.OnDisconnect(func(agentID string) {
+		disconnected <- agentID
+	})
+
+	first := dialTestServer(t, server, "agent-001")
+	for i := 0; i < 50 && server.GetConnectionManager().GetConnectionCount() < 1; i++ {
+		time.Sleep(20 * time.Millisecond)
+	}
+	second := dialTestServer(t, server, "agent-001")
+	for i := 0; i < 50 && server.GetConnectionManager().GetConnectionCount() < 2; i++ {
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// 重连后旧连接断开不算Agent断开
+	first.Close()
+	select {
+	case agentID := <-disconnected:
+		t.Fatalf("Unexpected disconnect of %s while a newer connection is open", agentID)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// 消息仍能送达新连接
+	msg := heartbeatMessage(protocol.ServerID)
+	msg.To = "agent-001"
+	if err := server.SendMessage(msg); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	if received := readTestMessage(t, second); received.MessageID != msg.MessageID {
+		t.Errorf("Expected the message on the newer connection, got %+v", received)
+	}
+
+	second.Close()
+	select {
+	case agentID := <-disconnected:
+		if agentID != "agent-001" {
+			t.Errorf("Expected agent-001, got %s", agentID)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Disconnect callback was not called")
+	}
+}
+
+// waitFor 轮询直到条件成立
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for %s", what)
+}
+
+func TestWebSocketServer_RetransmitsUntilAcked(t *testing.T) {
+	server := startTestServer(t, func(config *WebSocketConfig) {
+		config.Delivery.MaxAttempts = 3
+		config.Delivery.InitialBackoff = 50 * time.Millisecond
+		config.Delivery.MaxBackoff = 100 * time.Millisecond
+		config.Delivery.CheckInterval = 10 * time.Millisecond
+	})
+	sender := server.GetReliableSender()
+
+	conn := dialTestServer(t, server, "agent-001")
+	waitFor(t, "the connection", func() bool { return server.GetConnectionManager().GetConnectionCount() == 1 })
+
+	msg := heartbeatMessage(protocol.ServerID)
+	msg.To = "agent-001"
+	msg.RequireAck = true
+	if err := server.SendMessage(msg); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+
+	// 未确认时以相同的消息ID重传
+	first := readTestMessage(t, conn)
+	second := readTestMessage(t, conn)
+	if !first.RequireAck || first.MessageID != msg.MessageID || second.MessageID != msg.MessageID {
+		t.Fatalf("Expected retransmissions of %s, got %s and %s", msg.MessageID, first.MessageID, second.MessageID)
+	}
+
+	conn.WriteJSON(protocol.NewAckMessage("agent-001", second,
+		&protocol.AckPayload{MessageID: second.MessageID, Success: true}))
+	waitFor(t, "the ack", func() bool { return sender.PendingCount() == 0 })
+
+	ack, err := sender.GetAckManager().GetAck(msg.MessageID)
+	if err != nil || ack.Status != AckStatusConfirmed {
+		t.Errorf("Expected a confirmed ack, got %+v (%v)", ack, err)
+	}
+
+	// 接收方不在线，重传次数用尽后进入死信
+	offline := heartbeatMessage(protocol.ServerID)
+	offline.To = "agent-002"
+	offline.RequireAck = true
+	server.SendMessage(offline)
+	waitFor(t, "the dead letter", func() bool { return sender.GetDeadLetters().Count() == 1 })
+
+	letter, err := sender.GetDeadLetters().Get(offline.MessageID)
+	if err != nil || letter.Attempts != 3 || !strings.Contains(letter.Reason, "after 3 attempts") {
+		t.Errorf("Unexpected dead letter: %+v (%v)", letter, err)
+	}
+}
+
+func TestWebSocketServer_DeduplicatesRequireAck(t *testing.T) {
+	server := startTestServer(t)
+
+	var handled int32
+	server.RegisterMessageHandler(protocol.MessageTypeHeartbeat, func(msg *Message) error {
+		atomic.AddInt32(&handled, 1)
+		return nil
+	})
+	server.RegisterMessageHandler(protocol.MessageTypeTaskProgress, func(msg *Message) error {
+		return fmt.Errorf("unknown task")
+	})
+
+	conn := dialTestServer(t, server, "agent-001")
+
+	// 重传的消息只处理一次，每次都回复ACK
+	msg := heartbeatMessage("agent-001")
+	msg.RequireAck = true
+	for i := 0; i < 2; i++ {
+		conn.WriteJSON(msg)
+
+		var ack protocol.AckPayload
+		reply := readTestMessage(t, conn)
+		if reply.Type != protocol.MessageTypeAck || reply.GetPayload(&ack) != nil ||
+			ack.MessageID != msg.MessageID || !ack.Success {
+			t.Fatalf("Expected a successful ack of %s, got %+v", msg.MessageID, reply)
+		}
+	}
+	if n := atomic.LoadInt32(&handled); n != 1 {
+		t.Errorf("Expected the message to be handled once, got %d", n)
+	}
+
+	// 其他Agent使用相同的消息ID不算重传
+	other := dialTestServer(t, server, "agent-002")
+	reused := heartbeatMessage("agent-002")
+	reused.MessageID = msg.MessageID
+	reused.RequireAck = true
+	other.WriteJSON(reused)
+	readTestMessage(t, other)
+	if n := atomic.LoadInt32(&handled); n != 2 {
+		t.Errorf("Expected the other agent's message to be handled, got %d", n)
+	}
+
+	// 处理失败时回复失败的ACK和ERROR
+	progress := protocol.NewMessage(protocol.MessageTypeTaskProgress, "agent-001", protocol.ServerID)
+	progress.SetPayload(&protocol.TaskProgressPayload{TaskID: "task-1", Progress: 10})
+	progress.RequireAck = true
+	conn.WriteJSON(progress)
+
+	var ack protocol.AckPayload
+	readTestMessage(t, conn).GetPayload(&ack)
+	if ack.Success || ack.Error == "" {
+		t.Errorf("Expected a failed ack, got %+v", ack)
+	}
+	expectError(t, conn, "HANDLER_FAILED")
+}
+
+func TestWebSocketServer_BuffersForDisconnectedAgents(t *testing.T) {
+	dir := t.TempDir()
+	configure := func(config *WebSocketConfig) {
+		config.Mailbox.Dir = dir
+	}
+	server := startTestServer(t, configure)
+	connMgr := server.GetConnectionManager()
+
+	sender := dialTestServer(t, server, "agent-001")
+	receiver := dialTestServer(t, server, "agent-002")
+	waitFor(t, "both connections", func() bool { return connMgr.GetConnectionCount() == 2 })
+
+	receiver.Close()
+	waitFor(t, "the disconnect", func() bool { return connMgr.GetConnectionCount() == 1 })
+
+	// 发给断开的Agent的消息放入信箱，Agent之间的消息也不再回复RECIPIENT_UNAVAILABLE
+	var sent []string
+	for i := 0; i < 2; i++ {
+		msg := heartbeatMessage(protocol.ServerID)
+		msg.To = "agent-002"
+		server.SendMessage(msg)
+		sent = append(sent, msg.MessageID)
+		waitFor(t, "the buffered message", func() bool { return server.GetMailboxes().Pending("agent-002") == i+1 })
+	}
+	forwarded := heartbeatMessage("agent-001")
+	forwarded.To = "agent-002"
+	sender.WriteJSON(forwarded)
+	sent = append(sent, forwarded.MessageID)
+	waitFor(t, "the forwarded message", func() bool { return server.GetMailboxes().Pending("agent-002") == 3 })
+
+	// 从未连接过的Agent没有信箱
+	unknown := heartbeatMessage("agent-001")
+	unknown.To = "agent-003"
+	sender.WriteJSON(unknown)
+	expectError(t, sender, "RECIPIENT_UNAVAILABLE")
+
+	// 重启后信箱从持久化目录恢复
+	server.Stop()
+	restarted := startTestServer(t, func(config *WebSocketConfig) {
+		configure(config)
+		config.Port = server.config.Port
+	})
+	if n := restarted.GetMailboxes().Pending("agent-002"); n != 3 {
+		t.Fatalf("Expected 3 persisted messages, got %d", n)
+	}
+
+	// 重连后按顺序投递
+	reconnected := dialTestServer(t, restarted, "agent-002")
+	for _, id := range sent {
+		if msg := readTestMessage(t, reconnected); msg.MessageID != id {
+			t.Fatalf("Expected %s, got %s", id, msg.MessageID)
+		}
+	}
+	waitFor(t, "the empty mailbox", func() bool { return restarted.GetMailboxes().Pending("agent-002") == 0 })
+}
+
+func TestWebSocketServer_VerifiesBeforeDelivering(t *testing.T) {
+	server := startTestServer(t)
+	connMgr := server.GetConnectionManager()
+	server.RegisterMessageHandler(protocol.MessageTypeHeartbeat, func(msg *Message) error {
+		return nil
+	})
+	disconnected := make(chan string, 2)
+	server.OnDisconnect(func(agentID string) {
+		disconnected <- agentID
+	})
+
+	key := protocol.NewHMACKey([]byte("secret"))
+	server.GetKeyStore().Register("agent-001", key)
+	signedHeartbeat := func() *Message {
+		msg := heartbeatMessage("agent-001")
+		key.Sign(msg)
+		return msg
+	}
+
+	// 连接在第一条验证通过的消息之后才代表Agent
+	agent := dialTestServer(t, server, "agent-001")
+	waitFor(t, "the connection", func() bool { return connMgr.GetConnectionCount() == 1 })
+	if _, err := connMgr.GetConnectionByAgent("agent-001"); err == nil {
+		t.Fatal("Expected no connection for the agent before it is verified")
+	}
+	agent.WriteJSON(signedHeartbeat())
+	waitFor(t, "the verification", func() bool {
+		_, err := connMgr.GetConnectionByAgent("agent-001")
+		return err == nil
+	})
+	agent.Close()
+	<-disconnected
+
+	buffered := heartbeatMessage(protocol.ServerID)
+	buffered.To = "agent-001"
+	server.SendMessage(buffered)
+	waitFor(t, "the buffered message", func() bool { return server.GetMailboxes().Pending("agent-001") == 1 })
+
+	// 只凭agent_id连接的冒充者收不到信箱中的消息，它的错误回复也不进入信箱
+	impostor := dialTestServer(t, server, "agent-001")
+	waitFor(t, "the impostor", func() bool { return connMgr.GetConnectionCount() == 1 })
+	impostor.WriteJSON(heartbeatMessage("agent-001"))
+	expectError(t, impostor, "INVALID_SIGNATURE")
+	if n := server.GetMailboxes().Pending("agent-001"); n != 1 {
+		t.Errorf("Expected the message to stay in the mailbox, got %d pending", n)
+	}
+	if _, err := connMgr.GetConnectionByAgent("agent-001"); err == nil {
+		t.Error("Expected the impostor not to count as the agent's connection")
+	}
+
+	// 冒充者断开不算Agent断开
+	impostor.Close()
+	select {
+	case agentID := <-disconnected:
+		t.Fatalf("Unexpected disconnect of %s for an unverified connection", agentID)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// Agent重连并发送签名消息后收到缓存的消息
+	reconnected := dialTestServer(t, server, "agent-001")
+	reconnected.WriteJSON(signedHeartbeat())
+	if msg := readTestMessage(t, reconnected); msg.MessageID != buffered.MessageID {
+		t.Errorf("Expected the buffered message, got %+v", msg)
+	}
+}
+
+func TestWebSocketServer_Topics(t *testing.T) {
+	server := startTestServer(t)
+
+	handled := make(chan *Message, 1)
+	server.RegisterMessageHandler(protocol.MessageTypeBroadcast, func(msg *Message) error {
+		handled <- msg
+		return nil
+	})
+
+	// 两个控制台使用相同的Agent ID，各自订阅
+	code := dialTestServer(t, server, "web-client")
+	all := dialTestServer(t, server, "web-client")
+	publisher := dialTestServer(t, server, "agent-001")
+
+	code.WriteJSON(protocol.NewSubscribeMessage("web-client", "tasks.code.*", "reports.#"))
+	all.WriteJSON(protocol.NewSubscribeMessage("web-client", "tasks.#", "agents.#", "reports.#"))
+	waitFor(t, "subscriptions", func() bool {
+		return len(server.GetRouter().Subscribers("tasks.code.created")) == 2
+	})
+
+	// Agent发布的消息只发给订阅者，不路由到处理器
+	published := topicMessage("reports.code.progress", &protocol.BroadcastPayload{Event: "progress"})
+	published.From = "agent-001"
+	publisher.WriteJSON(published)
+	for _, conn := range []*websocket.Conn{code, all} {
+		if msg := readTestMessage(t, conn); msg.MessageID != published.MessageID || msg.Topic != "reports.code.progress" {
+			t.Fatalf("Expected the publication, got %+v", msg)
+		}
+	}
+
+	// 服务器的主题不接受Agent发布，也不保留Agent伪造的消息
+	for _, topic := range []string{"tasks.code.created", "agents.agent-002.status", "results.code.submitted", "messages.dead_lettered"} {
+		forged := topicMessage(topic, &protocol.BroadcastPayload{Event: "forged"})
+		forged.From = "agent-001"
+		forged.Retain = true
+		publisher.WriteJSON(forged)
+		if payload := expectError(t, publisher, "RESERVED_TOPIC"); payload.ErrorType != protocol.ErrorTypeAuthentication {
+			t.Errorf("Expected an authentication error for %s, got %s", topic, payload.ErrorType)
+		}
+	}
+	if retained := server.GetRouter().Retained("#"); len(retained) != 0 {
+		t.Errorf("Expected no retained forgeries, got %+v", retained)
+	}
+
+	// Agent保留的消息受主题数限制，超过时不发布
+	server.GetRouter().SetRetainLimits(1, 0)
+	for i, topic := range []string{"reports.code.summary", "reports.code.details"} {
+		retained := topicMessage(topic, &protocol.BroadcastPayload{Event: "summary"})
+		retained.From = "agent-001"
+		retained.Retain = true
+		publisher.WriteJSON(retained)
+		if i == 0 {
+			for _, conn := range []*websocket.Conn{code, all} {
+				if msg := readTestMessage(t, conn); msg.MessageID != retained.MessageID {
+					t.Fatalf("Expected the retained publication, got %+v", msg)
+				}
+			}
+			continue
+		}
+		expectError(t, publisher, "RETAIN_LIMIT")
+	}
+	if retained := server.GetRouter().Retained("reports.#"); len(retained) != 1 || retained[0].Topic != "reports.code.summary" {
+		t.Errorf("Expected only the first retained publication, got %+v", retained)
+	}
+
+	if err := server.Publish("tasks.review.created", topicMessage("", &protocol.BroadcastPayload{Event: "created"}), false); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if msg := readTestMessage(t, all); msg.Topic != "tasks.review.created" {
+		t.Fatalf("Expected the review task, got %+v", msg)
+	}
+
+	status := topicMessage("", &protocol.BroadcastPayload{Event: "idle"})
+	server.Publish("agents.agent-001.status", status, true)
+	if msg := readTestMessage(t, all); msg.MessageID != status.MessageID {
+		t.Fatalf("Expected the status, got %+v", msg)
+	}
+
+	// 只订阅了代码任务的连接没有收到其他主题，之后订阅时立即收到保留的状态
+	code.WriteJSON(protocol.NewUnsubscribeMessage("web-client", "tasks.code.*"))
+	code.WriteJSON(protocol.NewSubscribeMessage("web-client", "agents.*.status"))
+	if msg := readTestMessage(t, code); msg.MessageID != status.MessageID || !msg.Retain {
+		t.Fatalf("Expected the retained status, got %+v", msg)
+	}
+	waitFor(t, "unsubscribe", func() bool {
+		return len(server.GetRouter().Subscribers("tasks.code.created")) == 1
+	})
+
+	select {
+	case msg := <-handled:
+		t.Errorf("Publications should not reach handlers, got %+v", msg)
+	default:
+	}
+
+	// 无效模式返回错误
+	publisher.WriteJSON(protocol.NewSubscribeMessage("agent-001", "tasks.co*"))
+	expectError(t, publisher, "INVALID_MESSAGE")
+
+	// 断开的连接的订阅被移除
+	all.Close()
+	waitFor(t, "cleanup", func() bool {
+		return len(server.GetRouter().Subscribers("tasks.code.created")) == 0
+	})
+}
@@ -14,6 +14,7 @@ const (
 	AgentStatusBusy       AgentStatus = "BUSY"       // 忙碌
 	AgentStatusOffline    AgentStatus = "OFFLINE"    // 离线
 	AgentStatusMaintenance AgentStatus = "MAINTENANCE" // 维护中
+	AgentStatusError      AgentStatus = "ERROR"      // 故障（心跳超时或连接断开）
 )
 
 // Agent Agent信息
@@ -81,6 +82,45 @@ func (r *AgentRegistry) Register(agent *Agent) error {
 	return nil
 }
 
+// Reregister Agent重连后重新注册：更新名称、能力、最大任务数和元数据，
+// 保留执行中的任务数和负载，状态按任务数恢复为空闲或忙碌。Agent未注册时返回错误
+func (r *AgentRegistry) Reregister(agent *Agent) (*Agent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, exists := r.agents[agent.ID]
+	if !exists {
+		return nil, fmt.Errorf("agent %s not found", agent.ID)
+	}
+
+	if agent.Name == "" {
+		return nil, fmt.Errorf("agent name cannot be empty")
+	}
+
+	if len(agent.Capabilities) == 0 {
+		return nil, fmt.Errorf("agent must have at least one capability")
+	}
+
+	existing.Name = agent.Name
+	existing.Capabilities = agent.Capabilities
+	if agent.MaxTasks > 0 {
+		existing.MaxTasks = agent.MaxTasks
+	}
+	existing.Metadata = agent.Metadata
+	if existing.Metadata == nil {
+		existing.Metadata = make(map[string]interface{})
+	}
+	existing.LastHeartbeat = time.Now()
+
+	if existing.CurrentTasks >= existing.MaxTasks {
+		existing.Status = AgentStatusBusy
+	} else {
+		existing.Status = AgentStatusIdle
+	}
+
+	return existing, nil
+}
+
 // Unregister 注销Agent
 func (r *AgentRegistry) Unregister(agentID string) error {
 	r.mu.Lock()
@@ -242,7 +282,7 @@ func (r *AgentRegistry) UpdateHeartbeat(agentID string) error {
 	return nil
 }
 
-// CheckHeartbeat 检查心跳超时
+// CheckHeartbeat 检查心跳超时，将新超时的Agent标记为故障并返回其ID
 func (r *AgentRegistry) CheckHeartbeat(timeout time.Duration) []string {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -251,9 +291,12 @@ func (r *AgentRegistry) CheckHeartbeat(timeout time.Duration) []string {
 	timeoutAgents := make([]string, 0)
 
 	for _, agent := range r.agents {
+		if agent.Status == AgentStatusError {
+			continue
+		}
 		if now.Sub(agent.LastHeartbeat) > timeout {
-			// 标记为离线
-			agent.Status = AgentStatusOffline
+			// 标记为故障
+			agent.Status = AgentStatusError
 			timeoutAgents = append(timeoutAgents, agent.ID)
 		}
 	}
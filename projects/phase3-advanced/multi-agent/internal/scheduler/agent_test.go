@@ -133,6 +133,50 @@ func TestAgentRegistry_Unregister(t *testing.T) {
 	}
 }
 
+func TestAgentRegistry_Reregister(t *testing.T) {
+	registry := NewAgentRegistry()
+
+	if _, err := registry.Reregister(&Agent{ID: "agent-001", Name: "Test Agent", Capabilities: []string{"test"}}); err == nil {
+		t.Error("Expected error for an unregistered agent")
+	}
+
+	registry.Register(&Agent{
+		ID:           "agent-001",
+		Name:         "Test Agent",
+		Capabilities: []string{"test"},
+		MaxTasks:     2,
+	})
+	registry.IncrementTaskCount("agent-001")
+	registry.UpdateAgentLoad("agent-001", 0.5)
+	registry.UpdateAgentStatus("agent-001", AgentStatusOffline)
+
+	updated, err := registry.Reregister(&Agent{
+		ID:           "agent-001",
+		Name:         "Renamed Agent",
+		Capabilities: []string{"test", "review"},
+		MaxTasks:     1,
+		Metadata:     map[string]interface{}{"version": "2"},
+	})
+	if err != nil {
+		t.Fatalf("Reregister failed: %v", err)
+	}
+
+	// 保留任务数和负载，只更新元数据；任务数达到新的上限时为忙碌
+	if updated.CurrentTasks != 1 || updated.Load != 0.5 {
+		t.Errorf("Expected 1 task at load 0.5, got %d at %v", updated.CurrentTasks, updated.Load)
+	}
+	if updated.Name != "Renamed Agent" || len(updated.Capabilities) != 2 || updated.MaxTasks != 1 || updated.Metadata["version"] != "2" {
+		t.Errorf("Expected refreshed metadata, got %+v", updated)
+	}
+	if updated.Status != AgentStatusBusy {
+		t.Errorf("Expected status BUSY, got %s", updated.Status)
+	}
+
+	if _, err := registry.Reregister(&Agent{ID: "agent-001", Name: "Test Agent"}); err == nil {
+		t.Error("Expected error without capabilities")
+	}
+}
+
 func TestAgentRegistry_ListAgents(t *testing.T) {
 	registry := NewAgentRegistry()
 
@@ -335,10 +379,16 @@ func TestAgentRegistry_Heartbeat(t *testing.T) {
 		t.Errorf("Expected 1 timeout agent, got %d", len(timeoutAgents))
 	}
 
-	// Check status changed to offline
+	// Check status changed to error
 	updated, _ := registry.GetAgent("agent-001")
-	if updated.Status != AgentStatusOffline {
-		t.Errorf("Expected status OFFLINE, got %s", updated.Status)
+	if updated.Status != AgentStatusError {
+		t.Errorf("Expected status ERROR, got %s", updated.Status)
+	}
+
+	// An agent that already timed out is reported only once
+	timeoutAgents = registry.CheckHeartbeat(1 * time.Second)
+	if len(timeoutAgents) != 0 {
+		t.Errorf("Expected no newly timed out agents, got %v", timeoutAgents)
 	}
 }
 
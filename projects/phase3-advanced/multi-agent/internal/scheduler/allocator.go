@@ -25,6 +25,8 @@ type Task struct {
 	AssignedAgentID string                 `json:"assigned_agent_id,omitempty"`
 	Status          string                 `json:"status"`
 	Metadata        map[string]interface{} `json:"metadata"`
+	Reassignments   int                    `json:"reassignments,omitempty"`   // Agent故障后被重新分配的次数
+	PreviousAgents  []string               `json:"previous_agents,omitempty"` // 故障前分配过的Agent
 }
 
 // TaskAllocator 任务分配器
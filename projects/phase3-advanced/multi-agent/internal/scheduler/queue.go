@@ -253,25 +253,46 @@ func (q *TaskQueue) GetTasksByPriority(minPriority int) []*Task {
 	return tasks
 }
 
+// DefaultMaxReassignments 任务因Agent故障被重新分配的默认最大次数
+const DefaultMaxReassignments = 3
+
 // TaskManager 任务管理器
 type TaskManager struct {
-	queue       *TaskQueue
-	allocator   *TaskAllocator
-	tasks       map[string]*Task // taskID -> Task
-	assignments map[string]string // taskID -> agentID
-	mu          sync.RWMutex
+	queue            *TaskQueue
+	allocator        *TaskAllocator
+	tasks            map[string]*Task  // taskID -> Task
+	assignments      map[string]string // taskID -> agentID
+	maxReassignments int
+	mu               sync.RWMutex
+}
+
+// Reassignment 一次因Agent故障而进行的任务重新分配
+type Reassignment struct {
+	TaskID    string `json:"task_id"`
+	FromAgent string `json:"from_agent"`
+	Count     int    `json:"count"`    // 该任务累计的重新分配次数
+	Requeued  bool   `json:"requeued"` // false表示超过最大次数，任务被标记为失败
 }
 
 // NewTaskManager 创建任务管理器
 func NewTaskManager(queue *TaskQueue, allocator *TaskAllocator) *TaskManager {
 	return &TaskManager{
-		queue:       queue,
-		allocator:   allocator,
-		tasks:       make(map[string]*Task),
-		assignments: make(map[string]string),
+		queue:            queue,
+		allocator:        allocator,
+		tasks:            make(map[string]*Task),
+		assignments:      make(map[string]string),
+		maxReassignments: DefaultMaxReassignments,
 	}
 }
 
+// SetMaxReassignments 设置任务最大重新分配次数
+func (m *TaskManager) SetMaxReassignments(max int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.maxReassignments = max
+}
+
 // SubmitTask 提交任务
 func (m *TaskManager) SubmitTask(task *Task) error {
 	m.mu.Lock()
@@ -354,6 +375,32 @@ func (m *TaskManager) CompleteTask(taskID string) error {
 	return nil
 }
 
+// UpdateProgress 更新任务进度，已分配的任务进入执行中状态
+func (m *TaskManager) UpdateProgress(taskID string, progress int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	task, exists := m.tasks[taskID]
+	if !exists {
+		return fmt.Errorf("task %s not found", taskID)
+	}
+
+	switch task.Status {
+	case string(TaskStatusAssigned):
+		task.Status = string(TaskStatusRunning)
+	case string(TaskStatusRunning):
+	default:
+		return fmt.Errorf("task %s is not in progress (status: %s)", taskID, task.Status)
+	}
+
+	if task.Metadata == nil {
+		task.Metadata = make(map[string]interface{})
+	}
+	task.Metadata["progress"] = progress
+
+	return nil
+}
+
 // FailTask 标记任务失败
 func (m *TaskManager) FailTask(taskID string) error {
 	m.mu.Lock()
@@ -403,6 +450,68 @@ func (m *TaskManager) CancelTask(taskID string) error {
 	return nil
 }
 
+// ReassignAgentTasks 收回故障Agent上未完成的任务：未超过最大重新分配次数的任务回到队列，
+// 否则标记为失败
+func (m *TaskManager) ReassignAgentTasks(agentID string) []Reassignment {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reassignments := make([]Reassignment, 0)
+	for taskID, assignedAgentID := range m.assignments {
+		if assignedAgentID != agentID {
+			continue
+		}
+
+		task, exists := m.tasks[taskID]
+		if !exists {
+			continue
+		}
+		if task.Status != string(TaskStatusAssigned) && task.Status != string(TaskStatusRunning) {
+			continue
+		}
+
+		delete(m.assignments, taskID)
+		m.allocator.registry.DecrementTaskCount(agentID)
+
+		task.Reassignments++
+		task.PreviousAgents = append(task.PreviousAgents, agentID)
+		task.AssignedAgentID = ""
+
+		r := Reassignment{TaskID: taskID, FromAgent: agentID, Count: task.Reassignments}
+		if task.Reassignments <= m.maxReassignments {
+			task.Status = string(TaskStatusPending)
+			if err := m.queue.Enqueue(task); err == nil {
+				r.Requeued = true
+			}
+		}
+		if !r.Requeued {
+			task.Status = string(TaskStatusFailed)
+		}
+
+		reassignments = append(reassignments, r)
+	}
+
+	return reassignments
+}
+
+// WasReassignedFrom 检查任务是否曾因故障从agentID收回，用于识别迟到的结果
+func (m *TaskManager) WasReassignedFrom(taskID, agentID string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	task, exists := m.tasks[taskID]
+	if !exists {
+		return false
+	}
+
+	for _, previous := range task.PreviousAgents {
+		if previous == agentID {
+			return true
+		}
+	}
+	return false
+}
+
 // GetTask 获取任务
 func (m *TaskManager) GetTask(taskID string) (*Task, error) {
 	m.mu.RLock()
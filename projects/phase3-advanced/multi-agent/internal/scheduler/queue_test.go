@@ -409,6 +409,104 @@ func TestTaskManager_CompleteTask(t *testing.T) {
 	}
 }
 
+func TestTaskManager_UpdateProgress(t *testing.T) {
+	registry := NewAgentRegistry()
+	allocator := NewTaskAllocator(registry, StrategyLoadBalance)
+	queue := NewTaskQueue(100)
+	manager := NewTaskManager(queue, allocator)
+
+	registry.Register(&Agent{
+		ID:           "agent-001",
+		Name:         "Test Agent",
+		Capabilities: []string{"test"},
+		Status:       AgentStatusIdle,
+		MaxTasks:     5,
+	})
+	manager.SubmitTask(&Task{ID: "task-001", Type: "test", Priority: 5})
+
+	// Pending tasks have no progress
+	if err := manager.UpdateProgress("task-001", 10); err == nil {
+		t.Error("Expected error for a pending task")
+	}
+
+	manager.AssignTask("task-001")
+	if err := manager.UpdateProgress("task-001", 40); err != nil {
+		t.Fatalf("UpdateProgress failed: %v", err)
+	}
+
+	task, _ := manager.GetTask("task-001")
+	if task.Status != string(TaskStatusRunning) {
+		t.Errorf("Expected status RUNNING, got %s", task.Status)
+	}
+	if task.Metadata["progress"] != 40 {
+		t.Errorf("Expected progress 40, got %v", task.Metadata["progress"])
+	}
+
+	manager.CompleteTask("task-001")
+	if err := manager.UpdateProgress("task-001", 50); err == nil {
+		t.Error("Expected error for a completed task")
+	}
+}
+
+func TestTaskManager_ReassignAgentTasks(t *testing.T) {
+	registry := NewAgentRegistry()
+	allocator := NewTaskAllocator(registry, StrategyLoadBalance)
+	queue := NewTaskQueue(100)
+	manager := NewTaskManager(queue, allocator)
+	manager.SetMaxReassignments(1)
+
+	for _, id := range []string{"agent-001", "agent-002"} {
+		registry.Register(&Agent{
+			ID:           id,
+			Name:         id,
+			Capabilities: []string{"test"},
+			Status:       AgentStatusIdle,
+			MaxTasks:     5,
+		})
+	}
+
+	manager.SubmitTask(&Task{ID: "task-001", Type: "test", Priority: 5})
+	manager.SubmitTask(&Task{ID: "task-002", Type: "test", Priority: 5})
+	registry.UpdateAgentStatus("agent-002", AgentStatusMaintenance)
+	manager.AssignTask("task-001")
+	manager.AssignTask("task-002")
+	manager.CompleteTask("task-002")
+
+	// Agent故障：未完成的任务回到队列，已完成的任务不受影响
+	registry.UpdateAgentStatus("agent-001", AgentStatusError)
+	reassignments := manager.ReassignAgentTasks("agent-001")
+	if len(reassignments) != 1 || reassignments[0].TaskID != "task-001" || !reassignments[0].Requeued {
+		t.Fatalf("Unexpected reassignments: %+v", reassignments)
+	}
+
+	task, _ := manager.GetTask("task-001")
+	if task.Status != string(TaskStatusPending) || task.AssignedAgentID != "" || !queue.Contains("task-001") {
+		t.Errorf("Expected task-001 to be pending in the queue, got %+v", task)
+	}
+	if !manager.WasReassignedFrom("task-001", "agent-001") || manager.WasReassignedFrom("task-002", "agent-001") {
+		t.Error("Unexpected WasReassignedFrom result")
+	}
+	if agent, _ := registry.GetAgent("agent-001"); agent.CurrentTasks != 0 || agent.Status != AgentStatusError {
+		t.Errorf("Expected agent-001 to be in ERROR with no tasks, got %+v", agent)
+	}
+
+	// 故障Agent不再被分配任务
+	registry.UpdateAgentStatus("agent-002", AgentStatusIdle)
+	agentID, err := manager.AssignTask("task-001")
+	if err != nil || agentID != "agent-002" {
+		t.Fatalf("Expected task-001 to move to agent-002, got %s (%v)", agentID, err)
+	}
+
+	// 超过最大重新分配次数后任务失败
+	reassignments = manager.ReassignAgentTasks("agent-002")
+	if len(reassignments) != 1 || reassignments[0].Requeued || reassignments[0].Count != 2 {
+		t.Fatalf("Unexpected reassignments: %+v", reassignments)
+	}
+	if task.Status != string(TaskStatusFailed) || queue.Contains("task-001") {
+		t.Errorf("Expected task-001 to fail, got %s", task.Status)
+	}
+}
+
 func TestTaskManager_FailTask(t *testing.T) {
 	registry := NewAgentRegistry()
 	allocator := NewTaskAllocator(registry, StrategyLoadBalance)
@@ -14,6 +14,7 @@ type SchedulerConfig struct {
 	HeartbeatInterval  time.Duration      // 心跳检查间隔
 	HeartbeatTimeout   time.Duration      // 心跳超时时间
 	WorkerCount        int                // 工作协程数
+	MaxReassignments   int                // 任务因Agent故障被重新分配的最大次数
 }
 
 // DefaultSchedulerConfig 默认配置
@@ -24,6 +25,7 @@ func DefaultSchedulerConfig() *SchedulerConfig {
 		HeartbeatInterval:  30 * time.Second,
 		HeartbeatTimeout:   90 * time.Second,
 		WorkerCount:        5,
+		MaxReassignments:   DefaultMaxReassignments,
 	}
 }
 
@@ -35,6 +37,11 @@ type Scheduler struct {
 	queue     *TaskQueue
 	manager   *TaskManager
 
+	// onReassign Agent故障导致任务被重新分配时的回调。使用独立的锁，
+	// 避免Stop持有mu等待心跳检查协程时死锁
+	onReassign func(agentID string, reassignments []Reassignment)
+	callbackMu sync.RWMutex
+
 	ctx    context.Context
 	cancel context.CancelFunc
 	wg     sync.WaitGroup
@@ -52,6 +59,7 @@ func NewScheduler(config *SchedulerConfig) *Scheduler {
 	allocator := NewTaskAllocator(registry, config.AllocationStrategy)
 	queue := NewTaskQueue(config.MaxQueueSize)
 	manager := NewTaskManager(queue, allocator)
+	manager.SetMaxReassignments(config.MaxReassignments)
 
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -125,6 +133,32 @@ func (s *Scheduler) UpdateAgentHeartbeat(agentID string) error {
 	return s.registry.UpdateHeartbeat(agentID)
 }
 
+// HandleAgentFailure 将Agent标记为故障，并收回其未完成的任务重新排队
+func (s *Scheduler) HandleAgentFailure(agentID string) ([]Reassignment, error) {
+	if err := s.registry.UpdateAgentStatus(agentID, AgentStatusError); err != nil {
+		return nil, err
+	}
+
+	reassignments := s.manager.ReassignAgentTasks(agentID)
+
+	s.callbackMu.RLock()
+	onReassign := s.onReassign
+	s.callbackMu.RUnlock()
+	if onReassign != nil && len(reassignments) > 0 {
+		onReassign(agentID, reassignments)
+	}
+
+	return reassignments, nil
+}
+
+// OnReassign 设置任务被重新分配时的回调，例如通知控制台
+func (s *Scheduler) OnReassign(handler func(agentID string, reassignments []Reassignment)) {
+	s.callbackMu.Lock()
+	defer s.callbackMu.Unlock()
+
+	s.onReassign = handler
+}
+
 // SubmitTask 提交任务
 func (s *Scheduler) SubmitTask(task *Task) error {
 	return s.manager.SubmitTask(task)
@@ -245,6 +279,16 @@ func (s *Scheduler) heartbeatChecker() {
 			if len(timeoutAgents) > 0 {
 				fmt.Printf("Heartbeat checker: %d agents timed out: %v\n", len(timeoutAgents), timeoutAgents)
 			}
+			for _, agentID := range timeoutAgents {
+				reassignments, err := s.HandleAgentFailure(agentID)
+				if err != nil {
+					fmt.Printf("Heartbeat checker: failed to handle agent %s: %v\n", agentID, err)
+					continue
+				}
+				for _, r := range reassignments {
+					fmt.Printf("Heartbeat checker: task %s reclaimed from agent %s (requeued: %v)\n", r.TaskID, agentID, r.Requeued)
+				}
+			}
 		}
 	}
 }
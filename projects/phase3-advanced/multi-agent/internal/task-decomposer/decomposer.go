@@ -13,6 +13,7 @@ type Decomposer struct {
 	rules     []*DecompositionRule
 	analyzer  *ComplexityAnalyzer
 	generator *SubTaskGenerator
+	planner   *Planner
 }
 
 // DecompositionRule 分解规则
@@ -52,9 +53,15 @@ func DefaultConfig() *DecomposerConfig {
 		MaxSubTasks:        10,
 		ParallelThreshold:  3,
 		ComplexityAnalysis: true,
+		FallbackStrategy:   StrategyHybrid,
 	}
 }
 
+// SetPlanner 设置PLANNER策略使用的规划器
+func (d *Decomposer) SetPlanner(planner *Planner) {
+	d.planner = planner
+}
+
 // Decompose 分解任务
 func (d *Decomposer) Decompose(task *Task) (*DecompositionResult, error) {
 	// 验证任务
@@ -77,19 +84,13 @@ func (d *Decomposer) Decompose(task *Task) (*DecompositionResult, error) {
 
 	// 选择分解策略
 	var subTasks []*SubTask
+	var fallbackReason string
 	var err error
 
-	switch d.config.Strategy {
-	case StrategyDependency:
-		subTasks, err = d.decomposeByDependency(task)
-	case StrategyPriority:
-		subTasks, err = d.decomposeByPriority(task)
-	case StrategyCapability:
-		subTasks, err = d.decomposeByCapability(task)
-	case StrategyHybrid:
-		subTasks, err = d.decomposeHybrid(task)
-	default:
-		return nil, fmt.Errorf("unknown strategy: %s", d.config.Strategy)
+	if d.config.Strategy == StrategyPlanner {
+		subTasks, fallbackReason, err = d.decomposeWithPlanner(task)
+	} else {
+		subTasks, err = d.decomposeWithStrategy(d.config.Strategy, task)
 	}
 
 	if err != nil {
@@ -109,7 +110,7 @@ func (d *Decomposer) Decompose(task *Task) (*DecompositionResult, error) {
 		subTask.Level = graph.GetLevel(subTask.ID)
 	}
 
-	return &DecompositionResult{
+	result := &DecompositionResult{
 		OriginalTask: task,
 		SubTasks:     subTasks,
 		Graph:        graph,
@@ -118,7 +119,88 @@ func (d *Decomposer) Decompose(task *Task) (*DecompositionResult, error) {
 			"sub_task_count": len(subTasks),
 			"max_level":      d.getMaxLevel(subTasks),
 		},
-	}, nil
+	}
+
+	// 记录规划失败的原因和实际使用的策略
+	if fallbackReason != "" {
+		result.Metadata["planner_fallback"] = fallbackReason
+		result.Metadata["fallback_strategy"] = string(d.fallbackStrategy())
+	}
+
+	return result, nil
+}
+
+// decomposeWithStrategy 使用规则策略分解
+func (d *Decomposer) decomposeWithStrategy(strategy DecompositionStrategy, task *Task) ([]*SubTask, error) {
+	switch strategy {
+	case StrategyDependency:
+		return d.decomposeByDependency(task)
+	case StrategyPriority:
+		return d.decomposeByPriority(task)
+	case StrategyCapability:
+		return d.decomposeByCapability(task)
+	case StrategyHybrid:
+		return d.decomposeHybrid(task)
+	default:
+		return nil, fmt.Errorf("unknown strategy: %s", strategy)
+	}
+}
+
+// decomposeWithPlanner 由LLM规划分解。规划失败时使用备用策略，并返回失败原因
+func (d *Decomposer) decomposeWithPlanner(task *Task) ([]*SubTask, string, error) {
+	if d.planner == nil {
+		subTasks, err := d.decomposeWithStrategy(d.fallbackStrategy(), task)
+		return subTasks, "no planner configured", err
+	}
+
+	plan, err := d.planner.Plan(task, d.config.MaxSubTasks)
+	if err != nil {
+		subTasks, fallbackErr := d.decomposeWithStrategy(d.fallbackStrategy(), task)
+		return subTasks, err.Error(), fallbackErr
+	}
+
+	// LLM给出的ID只在本次规划内有效，替换为全局唯一的子任务ID
+	ids := make(map[string]string, len(plan.SubTasks))
+	for i, planned := range plan.SubTasks {
+		ids[planned.ID] = d.generateSubTaskID(task.ID, i)
+	}
+
+	subTasks := make([]*SubTask, 0, len(plan.SubTasks))
+	for _, planned := range plan.SubTasks {
+		dependencies := make([]string, 0, len(planned.Dependencies))
+		for _, dep := range planned.Dependencies {
+			dependencies = append(dependencies, ids[dep])
+		}
+
+		priority := planned.Priority
+		if priority <= 0 {
+			priority = task.Priority
+		}
+
+		subTasks = append(subTasks, &SubTask{
+			ID:           ids[planned.ID],
+			ParentID:     task.ID,
+			Type:         planned.Type,
+			Description:  planned.Description,
+			Input:        planned.Input,
+			Priority:     priority,
+			Dependencies: dependencies,
+			Capabilities: planned.Capabilities,
+			Metadata: map[string]interface{}{
+				"planned_id": planned.ID,
+			},
+		})
+	}
+
+	return subTasks, "", nil
+}
+
+// fallbackStrategy PLANNER策略失败时使用的规则策略
+func (d *Decomposer) fallbackStrategy() DecompositionStrategy {
+	if d.config.FallbackStrategy == "" || d.config.FallbackStrategy == StrategyPlanner {
+		return StrategyHybrid
+	}
+	return d.config.FallbackStrategy
 }
 
 // decomposeByDependency 基于依赖关系分解
@@ -290,9 +372,11 @@ func (d *Decomposer) registerDefaultRules() {
 			return t.Type == "code_review"
 		},
 		Decompose: func(t *Task) ([]*SubTask, error) {
+			// ID带随机后缀，只生成一次，保证依赖引用的ID与子任务ID一致
+			ids := []string{d.generateSubTaskID(t.ID, 0), d.generateSubTaskID(t.ID, 1), d.generateSubTaskID(t.ID, 2)}
 			return []*SubTask{
 				{
-					ID:           d.generateSubTaskID(t.ID, 0),
+					ID:           ids[0],
 					ParentID:     t.ID,
 					Type:         "syntax_check",
 					Description:  "Check code syntax",
@@ -301,23 +385,23 @@ func (d *Decomposer) registerDefaultRules() {
 					Level:        0,
 				},
 				{
-					ID:           d.generateSubTaskID(t.ID, 1),
+					ID:           ids[1],
 					ParentID:     t.ID,
 					Type:         "quality_check",
 					Description:  "Check code quality",
 					Priority:     t.Priority,
 					Capabilities: []string{"quality_analysis"},
-					Dependencies: []string{d.generateSubTaskID(t.ID, 0)},
+					Dependencies: []string{ids[0]},
 					Level:        1,
 				},
 				{
-					ID:           d.generateSubTaskID(t.ID, 2),
+					ID:           ids[2],
 					ParentID:     t.ID,
 					Type:         "security_check",
 					Description:  "Check code security",
 					Priority:     t.Priority,
 					Capabilities: []string{"security_analysis"},
-					Dependencies: []string{d.generateSubTaskID(t.ID, 0)},
+					Dependencies: []string{ids[0]},
 					Level:        1,
 				},
 			}, nil
@@ -333,9 +417,11 @@ func (d *Decomposer) registerDefaultRules() {
 			return t.Type == "document_processing" || strings.Contains(t.Type, "doc")
 		},
 		Decompose: func(t *Task) ([]*SubTask, error) {
+			// ID带随机后缀，只生成一次，保证依赖引用的ID与子任务ID一致
+			ids := []string{d.generateSubTaskID(t.ID, 0), d.generateSubTaskID(t.ID, 1), d.generateSubTaskID(t.ID, 2)}
 			return []*SubTask{
 				{
-					ID:           d.generateSubTaskID(t.ID, 0),
+					ID:           ids[0],
 					ParentID:     t.ID,
 					Type:         "parse",
 					Description:  "Parse document",
@@ -344,23 +430,23 @@ func (d *Decomposer) registerDefaultRules() {
 					Level:        0,
 				},
 				{
-					ID:           d.generateSubTaskID(t.ID, 1),
+					ID:           ids[1],
 					ParentID:     t.ID,
 					Type:         "analyze",
 					Description:  "Analyze document content",
 					Priority:     t.Priority,
 					Capabilities: []string{"content_analysis"},
-					Dependencies: []string{d.generateSubTaskID(t.ID, 0)},
+					Dependencies: []string{ids[0]},
 					Level:        1,
 				},
 				{
-					ID:           d.generateSubTaskID(t.ID, 2),
+					ID:           ids[2],
 					ParentID:     t.ID,
 					Type:         "summarize",
 					Description:  "Generate document summary",
 					Priority:     t.Priority,
 					Capabilities: []string{"summarization"},
-					Dependencies: []string{d.generateSubTaskID(t.ID, 1)},
+					Dependencies: []string{ids[1]},
 					Level:        2,
 				},
 			}, nil
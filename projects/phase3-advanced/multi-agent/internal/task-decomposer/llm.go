@@ -0,0 +1,84 @@
+package decomposer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultChatURL OpenAI Chat Completions接口的默认地址
+const DefaultChatURL = "https://api.openai.com/v1"
+
+// ChatClient 通过OpenAI兼容的Chat Completions接口调用语言模型，实现LLMClient
+type ChatClient struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	httpClient *http.Client
+}
+
+// NewChatClient 创建客户端，baseURL为接口前缀（例如DefaultChatURL），apiKey为空时不发送认证头
+func NewChatClient(baseURL, apiKey, model string) *ChatClient {
+	return &ChatClient{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		model:      model,
+		httpClient: &http.Client{},
+	}
+}
+
+// chatMessage Chat Completions的消息
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Complete 以单条用户消息发送提示词，返回第一个候选回复。超时由ctx控制
+func (c *ChatClient) Complete(ctx context.Context, prompt string) (string, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"model":       c.model,
+		"messages":    []chatMessage{{Role: "user", Content: prompt}},
+		"temperature": 0,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("chat completion returned %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+
+	var reply struct {
+		Choices []struct {
+			Message chatMessage `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return "", fmt.Errorf("invalid chat completion response: %w", err)
+	}
+	if len(reply.Choices) == 0 {
+		return "", fmt.Errorf("chat completion returned no choices")
+	}
+
+	return reply.Choices[0].Message.Content, nil
+}
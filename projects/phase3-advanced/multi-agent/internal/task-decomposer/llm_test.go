@@ -0,0 +1,44 @@
+package decomposer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestChatClientComplete(t *testing.T) {
+	var request struct {
+		Model    string        `json:"model"`
+		Messages []chatMessage `json:"messages"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" || r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unexpected request", http.StatusUnauthorized)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "{\"sub_tasks\": []}"}}]}`))
+	}))
+	defer server.Close()
+
+	client := NewChatClient(server.URL+"/v1/", "secret", "test-model")
+	reply, err := client.Complete(context.Background(), "plan this")
+	if err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if reply != `{"sub_tasks": []}` {
+		t.Errorf("Unexpected reply: %q", reply)
+	}
+	if request.Model != "test-model" || len(request.Messages) != 1 || request.Messages[0].Content != "plan this" {
+		t.Errorf("Unexpected request: %+v", request)
+	}
+
+	// 非200响应带上状态码和响应内容
+	_, err = NewChatClient(server.URL+"/v1", "wrong", "test-model").Complete(context.Background(), "plan this")
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected a 401 error, got %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+package decomposer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LLMClient 规划器使用的语言模型客户端，输入提示词返回模型的文本回复
+type LLMClient interface {
+	Complete(ctx context.Context, prompt string) (string, error)
+}
+
+// PlannedSubTask LLM输出的子任务，ID只在一次规划内有效
+type PlannedSubTask struct {
+	ID           string      `json:"id"`
+	Type         string      `json:"type"`
+	Description  string      `json:"description"`
+	Capabilities []string    `json:"capabilities"`
+	Dependencies []string    `json:"dependencies"`
+	Priority     int         `json:"priority,omitempty"`
+	Input        interface{} `json:"input,omitempty"`
+}
+
+// Plan LLM输出的分解方案
+type Plan struct {
+	SubTasks []*PlannedSubTask `json:"sub_tasks"`
+}
+
+// Planner 让LLM把任务分解为带能力和依赖的子任务，并验证输出。可被多个分解器并发使用
+type Planner struct {
+	client       LLMClient
+	capabilities map[string]bool // 可用的Agent能力，为空时不限制
+	timeout      time.Duration
+	mu           sync.RWMutex
+}
+
+// NewPlanner 创建规划器
+func NewPlanner(client LLMClient) *Planner {
+	return &Planner{
+		client:       client,
+		capabilities: make(map[string]bool),
+		timeout:      30 * time.Second,
+	}
+}
+
+// SetCapabilities 设置可用的Agent能力，规划结果只能使用这些能力
+func (p *Planner) SetCapabilities(capabilities []string) {
+	known := make(map[string]bool, len(capabilities))
+	for _, capability := range capabilities {
+		known[capability] = true
+	}
+
+	p.mu.Lock()
+	p.capabilities = known
+	p.mu.Unlock()
+}
+
+// SetTimeout 设置单次LLM调用的超时时间
+func (p *Planner) SetTimeout(timeout time.Duration) {
+	p.timeout = timeout
+}
+
+// Plan 请求LLM分解任务，返回验证通过的分解方案
+func (p *Planner) Plan(task *Task, maxSubTasks int) (*Plan, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	reply, err := p.client.Complete(ctx, p.buildPrompt(task, maxSubTasks))
+	if err != nil {
+		return nil, fmt.Errorf("llm request failed: %w", err)
+	}
+
+	plan, err := parsePlan(reply)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.validate(plan, maxSubTasks); err != nil {
+		return nil, fmt.Errorf("invalid plan: %w", err)
+	}
+
+	return plan, nil
+}
+
+// buildPrompt 构建分解提示词
+func (p *Planner) buildPrompt(task *Task, maxSubTasks int) string {
+	var b strings.Builder
+
+	b.WriteString("Decompose the following task into sub-tasks that can be executed by different agents.\n\n")
+	fmt.Fprintf(&b, "Task type: %s\n", task.Type)
+	fmt.Fprintf(&b, "Description: %s\n", task.Description)
+	if task.Input != nil {
+		if input, err := json.Marshal(task.Input); err == nil {
+			fmt.Fprintf(&b, "Input: %s\n", input)
+		}
+	}
+	if len(task.Capabilities) > 0 {
+		fmt.Fprintf(&b, "Required capabilities: %s\n", strings.Join(task.Capabilities, ", "))
+	}
+
+	if capabilities := p.knownCapabilities(); len(capabilities) > 0 {
+		fmt.Fprintf(&b, "\nAvailable agent capabilities (use only these): %s\n", strings.Join(capabilities, ", "))
+	}
+	if maxSubTasks > 0 {
+		fmt.Fprintf(&b, "Use at most %d sub-tasks.\n", maxSubTasks)
+	}
+
+	b.WriteString(`
+Reply with JSON only, in this format:
+{"sub_tasks": [{"id": "short-id", "type": "sub_task_type", "description": "...", "capabilities": ["..."], "dependencies": ["id of a sub-task that must finish first"]}]}
+Dependencies must not form a cycle.`)
+
+	return b.String()
+}
+
+// knownCapabilities 返回排序后的可用能力
+func (p *Planner) knownCapabilities() []string {
+	known := p.capabilitySet()
+	capabilities := make([]string, 0, len(known))
+	for capability := range known {
+		capabilities = append(capabilities, capability)
+	}
+	sort.Strings(capabilities)
+	return capabilities
+}
+
+// capabilitySet 返回当前的可用能力集合。SetCapabilities整体替换集合，返回的集合不会再被修改
+func (p *Planner) capabilitySet() map[string]bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.capabilities
+}
+
+// parsePlan 解析LLM回复，容忍JSON前后的说明文字和代码块标记
+func parsePlan(reply string) (*Plan, error) {
+	start := strings.Index(reply, "{")
+	end := strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("llm reply contains no JSON object")
+	}
+
+	var plan Plan
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse llm reply: %w", err)
+	}
+	return &plan, nil
+}
+
+// validate 检查子任务数量、ID、能力和依赖，依赖不能成环
+func (p *Planner) validate(plan *Plan, maxSubTasks int) error {
+	if len(plan.SubTasks) == 0 {
+		return fmt.Errorf("no sub-tasks")
+	}
+	if maxSubTasks > 0 && len(plan.SubTasks) > maxSubTasks {
+		return fmt.Errorf("%d sub-tasks exceed the limit of %d", len(plan.SubTasks), maxSubTasks)
+	}
+
+	known := p.capabilitySet()
+	graph := NewDependencyGraph()
+	for _, st := range plan.SubTasks {
+		if st.ID == "" {
+			return fmt.Errorf("sub-task without id")
+		}
+		if st.Type == "" {
+			return fmt.Errorf("sub-task %s has no type", st.ID)
+		}
+		if _, exists := graph.Nodes[st.ID]; exists {
+			return fmt.Errorf("duplicate sub-task id %s", st.ID)
+		}
+		graph.AddNode(st.ID)
+
+		for _, capability := range st.Capabilities {
+			if len(known) > 0 && !known[capability] {
+				return fmt.Errorf("sub-task %s requires unknown capability %s", st.ID, capability)
+			}
+		}
+	}
+
+	for _, st := range plan.SubTasks {
+		for _, dep := range st.Dependencies {
+			if _, exists := graph.Nodes[dep]; !exists {
+				return fmt.Errorf("sub-task %s depends on unknown sub-task %s", st.ID, dep)
+			}
+			graph.AddEdge(dep, st.ID, 1)
+		}
+	}
+
+	if graph.HasCycle() {
+		return fmt.Errorf("circular dependency detected")
+	}
+
+	return nil
+}
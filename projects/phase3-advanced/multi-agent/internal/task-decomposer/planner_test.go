@@ -0,0 +1,148 @@
+package decomposer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// scriptedClient 按顺序返回预设回复的LLM客户端
+type scriptedClient struct {
+	replies []string
+	err     error
+	prompts []string
+}
+
+func (c *scriptedClient) Complete(ctx context.Context, prompt string) (string, error) {
+	c.prompts = append(c.prompts, prompt)
+	if c.err != nil {
+		return "", c.err
+	}
+	if len(c.replies) == 0 {
+		return "", errors.New("no scripted reply")
+	}
+	reply := c.replies[0]
+	c.replies = c.replies[1:]
+	return reply, nil
+}
+
+// newPlannerDecomposer 创建使用PLANNER策略的分解器
+func newPlannerDecomposer(client LLMClient) *Decomposer {
+	config := DefaultConfig()
+	config.Strategy = StrategyPlanner
+	config.ComplexityAnalysis = false
+	d := NewDecomposer(config)
+
+	planner := NewPlanner(client)
+	planner.SetCapabilities([]string{"research", "writing", "review"})
+	d.SetPlanner(planner)
+	return d
+}
+
+func TestDecompose_Planner(t *testing.T) {
+	client := &scriptedClient{replies: []string{"Here is the plan:\n```json\n" + `{"sub_tasks": [
+		{"id": "research", "type": "research", "description": "Collect sources", "capabilities": ["research"]},
+		{"id": "draft", "type": "write", "description": "Write the draft", "capabilities": ["writing"], "dependencies": ["research"]},
+		{"id": "check", "type": "review", "description": "Fact check", "capabilities": ["review"], "dependencies": ["research"]},
+		{"id": "final", "type": "write", "description": "Final edit", "capabilities": ["writing"], "dependencies": ["draft", "check"], "priority": 9}
+	]}` + "\n```"}}
+	d := newPlannerDecomposer(client)
+
+	task := NewTask("article-001", "article", "Write an article about Go")
+	result, err := d.Decompose(task)
+	if err != nil {
+		t.Fatalf("Decompose failed: %v", err)
+	}
+
+	if !strings.Contains(client.prompts[0], "research, review, writing") {
+		t.Errorf("Prompt should list the available capabilities: %s", client.prompts[0])
+	}
+	if result.Strategy != string(StrategyPlanner) || result.Metadata["planner_fallback"] != nil {
+		t.Errorf("Expected the planned decomposition, got %s %v", result.Strategy, result.Metadata)
+	}
+	if len(result.SubTasks) != 4 {
+		t.Fatalf("Expected 4 sub-tasks, got %d", len(result.SubTasks))
+	}
+
+	byPlannedID := make(map[string]*SubTask)
+	for _, st := range result.SubTasks {
+		byPlannedID[st.Metadata["planned_id"].(string)] = st
+		if st.ParentID != task.ID || !strings.HasPrefix(st.ID, task.ID+"-sub-") {
+			t.Errorf("Sub-task %s should get a generated ID under %s", st.ID, task.ID)
+		}
+	}
+
+	final := byPlannedID["final"]
+	if final.Level != 2 || final.Priority != 9 || len(final.Dependencies) != 2 {
+		t.Errorf("Unexpected final sub-task: %+v", final)
+	}
+	if final.Dependencies[0] != byPlannedID["draft"].ID || final.Dependencies[1] != byPlannedID["check"].ID {
+		t.Errorf("Dependencies should be mapped to generated IDs, got %v", final.Dependencies)
+	}
+	if groups := result.Graph.GetParallelTasks(); len(groups) != 3 || len(groups[1]) != 2 {
+		t.Errorf("Expected levels of 1, 2 and 1 sub-tasks, got %v", groups)
+	}
+}
+
+func TestDecompose_PlannerFallback(t *testing.T) {
+	tests := []struct {
+		name   string
+		client *scriptedClient
+		reason string
+	}{
+		{
+			name:   "LLM error",
+			client: &scriptedClient{err: errors.New("rate limited")},
+			reason: "rate limited",
+		},
+		{
+			name:   "Not JSON",
+			client: &scriptedClient{replies: []string{"I cannot help with that."}},
+			reason: "no JSON object",
+		},
+		{
+			name: "Cycle",
+			client: &scriptedClient{replies: []string{`{"sub_tasks": [
+				{"id": "a", "type": "research", "capabilities": ["research"], "dependencies": ["b"]},
+				{"id": "b", "type": "write", "capabilities": ["writing"], "dependencies": ["a"]}
+			]}`}},
+			reason: "circular dependency",
+		},
+		{
+			name: "Unknown capability",
+			client: &scriptedClient{replies: []string{`{"sub_tasks": [
+				{"id": "a", "type": "translate", "capabilities": ["translation"]}
+			]}`}},
+			reason: "unknown capability translation",
+		},
+		{
+			name: "Unknown dependency",
+			client: &scriptedClient{replies: []string{`{"sub_tasks": [
+				{"id": "a", "type": "research", "capabilities": ["research"], "dependencies": ["z"]}
+			]}`}},
+			reason: "unknown sub-task z",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newPlannerDecomposer(tt.client)
+
+			// code_review匹配混合策略的默认规则
+			result, err := d.Decompose(NewTask("review-001", "code_review", "Review the patch"))
+			if err != nil {
+				t.Fatalf("Decompose should fall back instead of failing: %v", err)
+			}
+
+			reason, _ := result.Metadata["planner_fallback"].(string)
+			if !strings.Contains(reason, tt.reason) {
+				t.Errorf("Expected fallback reason containing %q, got %q", tt.reason, reason)
+			}
+			if result.Metadata["fallback_strategy"] != string(StrategyHybrid) || len(result.SubTasks) != 3 {
+				t.Errorf("Expected the hybrid rule decomposition, got %v with %d sub-tasks",
+					result.Metadata, len(result.SubTasks))
+			}
+		})
+	}
+}
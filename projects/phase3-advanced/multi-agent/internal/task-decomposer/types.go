@@ -72,6 +72,7 @@ const (
 	StrategyPriority    DecompositionStrategy = "PRIORITY"    // 基于优先级
 	StrategyCapability  DecompositionStrategy = "CAPABILITY"  // 基于能力
 	StrategyHybrid      DecompositionStrategy = "HYBRID"      // 混合策略
+	StrategyPlanner     DecompositionStrategy = "PLANNER"     // 由LLM规划，失败时使用备用策略
 )
 
 // TaskComplexity 任务复杂度
@@ -92,6 +93,7 @@ type DecomposerConfig struct {
 	MaxSubTasks        int                   `json:"max_sub_tasks"`        // 最大子任务数
 	ParallelThreshold  int                   `json:"parallel_threshold"`   // 并行阈值
 	ComplexityAnalysis bool                  `json:"complexity_analysis"`  // 是否进行复杂度分析
+	FallbackStrategy   DecompositionStrategy `json:"fallback_strategy"`    // PLANNER策略失败时使用的规则策略
 }
 
 // NewTask 创建新任务
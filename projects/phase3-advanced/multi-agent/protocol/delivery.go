@@ -0,0 +1,85 @@
+package protocol
+
+import (
+	"sync"
+	"time"
+)
+
+// Deduplicator 接收方按发送方和MessageID对需确认的消息去重，并记录处理结果，
+// 重传的消息不再处理，直接用记录的结果重新确认。不同发送方的相同ID互不影响
+type Deduplicator struct {
+	window    time.Duration
+	seen      map[string]*dedupEntry // 发送方+messageID -> 处理记录
+	lastPrune time.Time
+	mu        sync.Mutex
+}
+
+type dedupEntry struct {
+	ack    *AckPayload // 处理完成前为nil
+	seenAt time.Time
+}
+
+// NewDeduplicator 创建去重器，window为记录消息ID的时长，应不短于发送方的重传时长
+func NewDeduplicator(window time.Duration) *Deduplicator {
+	return &Deduplicator{
+		window: window,
+		seen:   make(map[string]*dedupEntry),
+	}
+}
+
+// Check 检查并记录from发来的消息ID。首次收到时duplicate为false，调用方处理后用Done记录结果；
+// 重复收到时duplicate为true，ack为记录的结果，仍在处理中时为nil
+func (d *Deduplicator) Check(from, messageID string) (ack *AckPayload, duplicate bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := senderKey(from, messageID)
+	now := time.Now()
+	if entry, exists := d.seen[key]; exists && now.Sub(entry.seenAt) <= d.window {
+		return entry.ack, true
+	}
+
+	if now.Sub(d.lastPrune) > d.window {
+		for id, entry := range d.seen {
+			if now.Sub(entry.seenAt) > d.window {
+				delete(d.seen, id)
+			}
+		}
+		d.lastPrune = now
+	}
+	d.seen[key] = &dedupEntry{seenAt: now}
+
+	return nil, false
+}
+
+// Contains 检查from发来的消息ID是否已记录，不记录新ID
+func (d *Deduplicator) Contains(from, messageID string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entry, exists := d.seen[senderKey(from, messageID)]
+	return exists && time.Since(entry.seenAt) <= d.window
+}
+
+// Done 记录from发来的消息的处理结果
+func (d *Deduplicator) Done(from, messageID string, ack *AckPayload) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if entry, exists := d.seen[senderKey(from, messageID)]; exists {
+		entry.ack = ack
+	}
+}
+
+// Len 返回记录的消息ID数
+func (d *Deduplicator) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return len(d.seen)
+}
+
+// senderKey 组合发送方和消息ID，消息ID由发送方生成，只在同一发送方内唯一
+func senderKey(from, messageID string) string {
+	return from + "\x00" + messageID
+}
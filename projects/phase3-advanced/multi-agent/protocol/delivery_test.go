@@ -0,0 +1,71 @@
+package protocol
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestNewAckMessage(t *testing.T) {
+	original := NewMessage(MessageTypeTaskRequest, ServerID, "worker-1")
+	original.RequireAck = true
+
+	msg := NewAckMessage("worker-1", original, &AckPayload{MessageID: original.MessageID, Success: true})
+	if msg.To != ServerID || msg.Type != MessageTypeAck {
+		t.Errorf("Expected an ACK to the sender, got %s to %s", msg.Type, msg.To)
+	}
+	if err := NewValidator().Validate(msg); err != nil {
+		t.Fatalf("Expected a valid ack: %v", err)
+	}
+
+	delete(msg.Payload, "success")
+	if err := NewValidator().Validate(msg); err == nil {
+		t.Error("Expected an ack without success to be invalid")
+	}
+
+	// require_ack在序列化后保留
+	data, _ := json.Marshal(original)
+	var decoded Message
+	json.Unmarshal(data, &decoded)
+	if !decoded.RequireAck {
+		t.Error("Expected require_ack to survive serialization")
+	}
+}
+
+func TestDeduplicator(t *testing.T) {
+	dedup := NewDeduplicator(50 * time.Millisecond)
+
+	if _, duplicate := dedup.Check("worker-1", "msg-1"); duplicate {
+		t.Fatal("First delivery should not be a duplicate")
+	}
+
+	// 处理完成前的重传没有可回复的确认
+	if ack, duplicate := dedup.Check("worker-1", "msg-1"); !duplicate || ack != nil {
+		t.Fatalf("Expected an in-flight duplicate, got %v %v", ack, duplicate)
+	}
+
+	dedup.Done("worker-1", "msg-1", &AckPayload{MessageID: "msg-1", Success: true})
+	if ack, duplicate := dedup.Check("worker-1", "msg-1"); !duplicate || ack == nil || !ack.Success {
+		t.Errorf("Expected the recorded ack, got %v %v", ack, duplicate)
+	}
+	if !dedup.Contains("worker-1", "msg-1") || dedup.Contains("worker-1", "msg-2") {
+		t.Error("Contains should only report recorded IDs")
+	}
+
+	// 其他发送方使用相同的ID不算重复
+	if dedup.Contains("worker-2", "msg-1") {
+		t.Error("Expected IDs to be scoped to their sender")
+	}
+	if _, duplicate := dedup.Check("worker-2", "msg-1"); duplicate {
+		t.Error("Expected another sender's message with the same ID to be new")
+	}
+
+	// 超出窗口的ID被遗忘
+	time.Sleep(60 * time.Millisecond)
+	if _, duplicate := dedup.Check("worker-1", "msg-1"); duplicate {
+		t.Error("Expected the ID to be forgotten after the window")
+	}
+	if dedup.Len() != 1 {
+		t.Errorf("Expected expired IDs to be pruned, got %d", dedup.Len())
+	}
+}
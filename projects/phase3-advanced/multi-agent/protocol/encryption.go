@@ -0,0 +1,348 @@
+package protocol
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+// EncryptionAlgorithm 负载加密算法
+type EncryptionAlgorithm string
+
+const (
+	// EncryptionAES256GCM 使用与对端共享的对称密钥
+	EncryptionAES256GCM EncryptionAlgorithm = "AES-256-GCM"
+	// EncryptionX25519ChaCha20Poly1305 每条消息生成临时X25519密钥与接收方公钥协商
+	EncryptionX25519ChaCha20Poly1305 EncryptionAlgorithm = "X25519-ChaCha20-Poly1305"
+)
+
+// hkdfInfo 密钥派生的上下文信息
+const hkdfInfo = "multi-agent payload encryption"
+
+// 加解密错误
+var (
+	ErrUnknownPeer       = errors.New("no encryption key for peer")
+	ErrDecryptionFailed  = errors.New("payload decryption failed")
+	ErrNotEncrypted      = errors.New("message is not encrypted")
+	ErrAlreadyEncrypted  = errors.New("message is already encrypted")
+	ErrUnsupportedCipher = errors.New("unsupported encryption algorithm")
+)
+
+// EncryptedPayload 加密消息的负载，Hub只转发不解读
+type EncryptedPayload struct {
+	Ciphertext   string `json:"ciphertext"`              // base64
+	Nonce        string `json:"nonce"`                   // base64
+	EphemeralKey string `json:"ephemeral_key,omitempty"` // base64，X25519临时公钥
+}
+
+// Keyring Agent的加密密钥：自己的X25519私钥、对端公钥和对称密钥
+type Keyring struct {
+	privateKey *ecdh.PrivateKey
+	peers      map[string]*ecdh.PublicKey // agentID -> X25519公钥
+	shared     map[string][]byte          // agentID -> AES-256密钥
+	mu         sync.RWMutex
+}
+
+// NewKeyring 生成新的X25519密钥对并创建密钥环
+func NewKeyring() (*Keyring, error) {
+	privateKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate X25519 key: %w", err)
+	}
+	return NewKeyringFromPrivateKey(privateKey), nil
+}
+
+// NewKeyringFromPrivateKey 用已有的X25519私钥创建密钥环
+func NewKeyringFromPrivateKey(privateKey *ecdh.PrivateKey) *Keyring {
+	return &Keyring{
+		privateKey: privateKey,
+		peers:      make(map[string]*ecdh.PublicKey),
+		shared:     make(map[string][]byte),
+	}
+}
+
+// PublicKey 返回base64编码的X25519公钥，在注册时发送给Hub
+func (k *Keyring) PublicKey() string {
+	return base64.StdEncoding.EncodeToString(k.privateKey.PublicKey().Bytes())
+}
+
+// ParsePublicKey 解析base64编码的X25519公钥
+func ParsePublicKey(publicKey string) (*ecdh.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+
+	pub, err := ecdh.X25519().NewPublicKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	return pub, nil
+}
+
+// AddPeer 登记对端的base64编码X25519公钥
+func (k *Keyring) AddPeer(agentID, publicKey string) error {
+	pub, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return fmt.Errorf("peer %s: %w", agentID, err)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.peers[agentID] = pub
+	return nil
+}
+
+// HasPeer 检查是否有对端的公钥
+func (k *Keyring) HasPeer(agentID string) bool {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	_, exists := k.peers[agentID]
+	return exists
+}
+
+// SetSharedKey 设置与对端预共享的AES-256密钥。未设置时从双方X25519密钥派生
+func (k *Keyring) SetSharedKey(agentID string, key []byte) error {
+	if len(key) != 32 {
+		return fmt.Errorf("AES-256 key must be 32 bytes, got %d", len(key))
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.shared[agentID] = key
+	return nil
+}
+
+// CanEncryptTo 检查是否能用algorithm加密发给agentID的消息
+func (k *Keyring) CanEncryptTo(agentID string, algorithm EncryptionAlgorithm) bool {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	_, hasPeer := k.peers[agentID]
+	if algorithm == EncryptionAES256GCM {
+		_, hasShared := k.shared[agentID]
+		return hasShared || hasPeer
+	}
+	return hasPeer
+}
+
+// Encrypt 用algorithm加密消息负载，替换为EncryptedPayload
+func (k *Keyring) Encrypt(msg *Message, algorithm EncryptionAlgorithm) error {
+	if msg.Encrypted {
+		return ErrAlreadyEncrypted
+	}
+
+	plaintext, err := json.Marshal(msg.Payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	var aead cipher.AEAD
+	var payload EncryptedPayload
+
+	switch algorithm {
+	case EncryptionAES256GCM:
+		key, err := k.sharedKey(msg.To)
+		if err != nil {
+			return err
+		}
+		if aead, err = newGCM(key); err != nil {
+			return err
+		}
+
+	case EncryptionX25519ChaCha20Poly1305:
+		peer, err := k.peer(msg.To)
+		if err != nil {
+			return err
+		}
+		ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+		if err != nil {
+			return fmt.Errorf("failed to generate ephemeral key: %w", err)
+		}
+		shared, err := ephemeral.ECDH(peer)
+		if err != nil {
+			return fmt.Errorf("key agreement failed: %w", err)
+		}
+		key, err := deriveKey(shared, ephemeral.PublicKey().Bytes(), peer.Bytes())
+		if err != nil {
+			return err
+		}
+		if aead, err = chacha20poly1305.New(key); err != nil {
+			return err
+		}
+		payload.EphemeralKey = base64.StdEncoding.EncodeToString(ephemeral.PublicKey().Bytes())
+
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedCipher, algorithm)
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	payload.Nonce = base64.StdEncoding.EncodeToString(nonce)
+	payload.Ciphertext = base64.StdEncoding.EncodeToString(aead.Seal(nil, nonce, plaintext, additionalData(msg)))
+
+	if err := msg.SetPayload(&payload); err != nil {
+		return err
+	}
+	msg.Encrypted = true
+	msg.EncryptionAlgorithm = string(algorithm)
+	return nil
+}
+
+// Decrypt 解密消息负载，恢复原始负载
+func (k *Keyring) Decrypt(msg *Message) error {
+	if !msg.Encrypted {
+		return ErrNotEncrypted
+	}
+
+	var payload EncryptedPayload
+	if err := msg.GetPayload(&payload); err != nil {
+		return err
+	}
+
+	nonce, err := base64.StdEncoding.DecodeString(payload.Nonce)
+	if err != nil {
+		return ErrDecryptionFailed
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(payload.Ciphertext)
+	if err != nil {
+		return ErrDecryptionFailed
+	}
+
+	var aead cipher.AEAD
+
+	switch EncryptionAlgorithm(msg.EncryptionAlgorithm) {
+	case EncryptionAES256GCM:
+		key, err := k.sharedKey(msg.From)
+		if err != nil {
+			return err
+		}
+		if aead, err = newGCM(key); err != nil {
+			return err
+		}
+
+	case EncryptionX25519ChaCha20Poly1305:
+		raw, err := base64.StdEncoding.DecodeString(payload.EphemeralKey)
+		if err != nil {
+			return ErrDecryptionFailed
+		}
+		ephemeral, err := ecdh.X25519().NewPublicKey(raw)
+		if err != nil {
+			return ErrDecryptionFailed
+		}
+		shared, err := k.privateKey.ECDH(ephemeral)
+		if err != nil {
+			return ErrDecryptionFailed
+		}
+		key, err := deriveKey(shared, raw, k.privateKey.PublicKey().Bytes())
+		if err != nil {
+			return err
+		}
+		if aead, err = chacha20poly1305.New(key); err != nil {
+			return err
+		}
+
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedCipher, msg.EncryptionAlgorithm)
+	}
+
+	if len(nonce) != aead.NonceSize() {
+		return ErrDecryptionFailed
+	}
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData(msg))
+	if err != nil {
+		return ErrDecryptionFailed
+	}
+
+	var original map[string]interface{}
+	if err := json.Unmarshal(plaintext, &original); err != nil {
+		return fmt.Errorf("failed to unmarshal decrypted payload: %w", err)
+	}
+
+	msg.Payload = original
+	msg.Encrypted = false
+	msg.EncryptionAlgorithm = ""
+	return nil
+}
+
+// peer 获取对端公钥
+func (k *Keyring) peer(agentID string) (*ecdh.PublicKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	pub, exists := k.peers[agentID]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPeer, agentID)
+	}
+	return pub, nil
+}
+
+// sharedKey 获取与对端的AES-256密钥：优先使用预共享密钥，否则由双方X25519密钥派生
+func (k *Keyring) sharedKey(agentID string) ([]byte, error) {
+	k.mu.RLock()
+	key, exists := k.shared[agentID]
+	k.mu.RUnlock()
+	if exists {
+		return key, nil
+	}
+
+	peer, err := k.peer(agentID)
+	if err != nil {
+		return nil, err
+	}
+
+	shared, err := k.privateKey.ECDH(peer)
+	if err != nil {
+		return nil, fmt.Errorf("key agreement failed: %w", err)
+	}
+
+	// 双方按相同顺序排列公钥，派生出同一个密钥
+	a, b := k.privateKey.PublicKey().Bytes(), peer.Bytes()
+	if bytes.Compare(a, b) > 0 {
+		a, b = b, a
+	}
+	return deriveKey(shared, a, b)
+}
+
+// deriveKey 用HKDF-SHA256从ECDH共享秘密派生32字节密钥，两个公钥作为盐
+func deriveKey(shared, first, second []byte) ([]byte, error) {
+	salt := append(append([]byte{}, first...), second...)
+
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, shared, salt, []byte(hkdfInfo)), key); err != nil {
+		return nil, fmt.Errorf("key derivation failed: %w", err)
+	}
+	return key, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// additionalData 把消息头绑定到密文上，防止负载被挪到其他消息中
+func additionalData(msg *Message) []byte {
+	return []byte(msg.MessageID + "\x00" + string(msg.Type) + "\x00" + msg.From + "\x00" + msg.To)
+}
@@ -0,0 +1,166 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// newTestKeyrings 创建两个互相登记了公钥的密钥环
+func newTestKeyrings(t *testing.T) (*Keyring, *Keyring) {
+	t.Helper()
+
+	alice, err := NewKeyring()
+	if err != nil {
+		t.Fatalf("NewKeyring failed: %v", err)
+	}
+	bob, err := NewKeyring()
+	if err != nil {
+		t.Fatalf("NewKeyring failed: %v", err)
+	}
+
+	if err := alice.AddPeer("bob", bob.PublicKey()); err != nil {
+		t.Fatalf("AddPeer failed: %v", err)
+	}
+	if err := bob.AddPeer("alice", alice.PublicKey()); err != nil {
+		t.Fatalf("AddPeer failed: %v", err)
+	}
+	return alice, bob
+}
+
+func TestKeyringEncryptDecrypt(t *testing.T) {
+	for _, algorithm := range []EncryptionAlgorithm{EncryptionAES256GCM, EncryptionX25519ChaCha20Poly1305} {
+		t.Run(string(algorithm), func(t *testing.T) {
+			alice, bob := newTestKeyrings(t)
+
+			msg := NewMessage(MessageTypeTaskRequest, "alice", "bob")
+			msg.SetPayload(&TaskRequestPayload{TaskID: "task-001", Input: "secret input"})
+
+			if err := alice.Encrypt(msg, algorithm); err != nil {
+				t.Fatalf("Encrypt failed: %v", err)
+			}
+			if !msg.Encrypted || msg.EncryptionAlgorithm != string(algorithm) {
+				t.Fatalf("Expected an encrypted message, got %+v", msg)
+			}
+			if _, exists := msg.Payload["task_id"]; exists {
+				t.Fatal("Plaintext payload leaked into the encrypted message")
+			}
+			if err := NewValidator().Validate(msg); err != nil {
+				t.Errorf("Encrypted message failed validation: %v", err)
+			}
+
+			// 篡改消息头会使解密失败
+			tampered := *msg
+			tampered.Type = MessageTypeTaskComplete
+			if err := bob.Decrypt(&tampered); !errors.Is(err, ErrDecryptionFailed) {
+				t.Errorf("Expected ErrDecryptionFailed for a tampered header, got %v", err)
+			}
+
+			if err := bob.Decrypt(msg); err != nil {
+				t.Fatalf("Decrypt failed: %v", err)
+			}
+			var payload TaskRequestPayload
+			msg.GetPayload(&payload)
+			if payload.TaskID != "task-001" || payload.Input != "secret input" || msg.Encrypted {
+				t.Errorf("Unexpected decrypted message: %+v %+v", msg, payload)
+			}
+		})
+	}
+}
+
+func TestKeyringRejectsThirdParty(t *testing.T) {
+	alice, _ := newTestKeyrings(t)
+	eve, _ := NewKeyring()
+	eve.AddPeer("alice", alice.PublicKey())
+
+	for _, algorithm := range []EncryptionAlgorithm{EncryptionAES256GCM, EncryptionX25519ChaCha20Poly1305} {
+		msg := NewMessage(MessageTypeBroadcast, "alice", "bob")
+		msg.Payload["message"] = "hello"
+		alice.Encrypt(msg, algorithm)
+
+		if err := eve.Decrypt(msg); !errors.Is(err, ErrDecryptionFailed) {
+			t.Errorf("%s: expected a third party to fail decryption, got %v", algorithm, err)
+		}
+	}
+
+	msg := NewMessage(MessageTypeBroadcast, "alice", "carol")
+	if err := alice.Encrypt(msg, EncryptionX25519ChaCha20Poly1305); !errors.Is(err, ErrUnknownPeer) {
+		t.Errorf("Expected ErrUnknownPeer, got %v", err)
+	}
+}
+
+func TestKeyringSharedKey(t *testing.T) {
+	alice, _ := NewKeyring()
+	bob, _ := NewKeyring()
+
+	key := bytes.Repeat([]byte{7}, 32)
+	alice.SetSharedKey("bob", key)
+	bob.SetSharedKey("alice", key)
+
+	if alice.CanEncryptTo("bob", EncryptionX25519ChaCha20Poly1305) {
+		t.Error("X25519 encryption should require the peer public key")
+	}
+
+	msg := NewMessage(MessageTypeBroadcast, "alice", "bob")
+	msg.Payload["message"] = "hello"
+	if err := alice.Encrypt(msg, EncryptionAES256GCM); err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if err := bob.Decrypt(msg); err != nil || msg.Payload["message"] != "hello" {
+		t.Errorf("Decrypt failed: %v %v", err, msg.Payload)
+	}
+
+	if err := alice.SetSharedKey("bob", []byte("short")); err == nil {
+		t.Error("Expected a short key to be rejected")
+	}
+}
+
+func TestSerializerEncryptsTransparently(t *testing.T) {
+	alice, bob := newTestKeyrings(t)
+	signing := NewHMACKey([]byte("secret"))
+
+	sender := NewSerializer()
+	sender.SetEncryption(alice, EncryptionX25519ChaCha20Poly1305)
+	sender.SetSigningKey(signing)
+
+	msg := NewMessage(MessageTypeBroadcast, "alice", "bob")
+	msg.Payload["message"] = "hello"
+	data, err := sender.Serialize(msg)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	if msg.Encrypted || msg.Signature != "" {
+		t.Error("Serialize should not modify the caller's message")
+	}
+
+	// 没有密钥环的一方（Hub）看到的是密文，但可以验证签名
+	hub, err := NewSerializer().Deserialize(data)
+	if err != nil {
+		t.Fatalf("Deserialize failed: %v", err)
+	}
+	if !hub.Encrypted || hub.Payload["message"] != nil {
+		t.Errorf("Expected ciphertext without a keyring, got %+v", hub.Payload)
+	}
+	if err := signing.Verify(hub); err != nil {
+		t.Errorf("Signature over ciphertext failed to verify: %v", err)
+	}
+
+	receiver := NewSerializer()
+	receiver.SetEncryption(bob, EncryptionX25519ChaCha20Poly1305)
+	received, err := receiver.Deserialize(data)
+	if err != nil {
+		t.Fatalf("Deserialize failed: %v", err)
+	}
+	if received.Encrypted || received.Payload["message"] != "hello" {
+		t.Errorf("Expected the decrypted payload, got %+v", received.Payload)
+	}
+
+	// 发给Hub的消息没有对端公钥，保持明文
+	toServer := NewMessage(MessageTypeHeartbeat, "alice", ServerID)
+	toServer.Payload["status"] = "IDLE"
+	data, _ = sender.Serialize(toServer)
+	plain, _ := NewSerializer().Deserialize(data)
+	if plain.Encrypted || plain.Payload["status"] != "IDLE" {
+		t.Errorf("Expected a plaintext message to the hub, got %+v", plain)
+	}
+}
@@ -1,7 +1,10 @@
 package protocol
 
 import (
+	"fmt"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // MessageType 定义消息类型
@@ -14,17 +17,52 @@ const (
 	MessageTypeTaskReject   MessageType = "TASK_REJECT"
 	MessageTypeTaskComplete MessageType = "TASK_COMPLETE"
 	MessageTypeTaskFailed   MessageType = "TASK_FAILED"
+	MessageTypeTaskCancel   MessageType = "TASK_CANCEL"
 
 	// 状态相关消息
 	MessageTypeHeartbeat      MessageType = "HEARTBEAT"
 	MessageTypeStatusQuery    MessageType = "STATUS_QUERY"
 	MessageTypeStatusResponse MessageType = "STATUS_RESPONSE"
 
+	// 任务进度消息
+	MessageTypeTaskProgress MessageType = "TASK_PROGRESS"
+
+	// 注册相关消息
+	MessageTypeAgentRegister MessageType = "AGENT_REGISTER"
+	MessageTypeClientConnect MessageType = "CLIENT_CONNECT"
+
+	// 密钥交换消息
+	MessageTypeKeyExchange MessageType = "KEY_EXCHANGE"
+
+	// 投递确认消息
+	MessageTypeAck MessageType = "ACK"
+
+	// 主题订阅消息
+	MessageTypeSubscribe   MessageType = "SUBSCRIBE"
+	MessageTypeUnsubscribe MessageType = "UNSUBSCRIBE"
+
 	// 通用消息
 	MessageTypeBroadcast MessageType = "BROADCAST"
 	MessageTypeError     MessageType = "ERROR"
 )
 
+// ServerID 服务器在消息From/To字段中的地址
+const ServerID = "server"
+
+// 服务器推送给Web控制台的事件类型，只出站，不接受入站
+const (
+	EventAgentRegistered     MessageType = "AGENT_REGISTERED"
+	EventAgentStatusUpdate   MessageType = "AGENT_STATUS_UPDATE"
+	EventTaskCreated         MessageType = "TASK_CREATED"
+	EventTaskAssigned        MessageType = "TASK_ASSIGNED"
+	EventTaskStatusUpdate    MessageType = "TASK_STATUS_UPDATE"
+	EventTaskReassigned      MessageType = "TASK_REASSIGNED"
+	EventResultSubmitted     MessageType = "RESULT_SUBMITTED"
+	EventResultAggregated    MessageType = "RESULT_AGGREGATED"
+	EventCompositeTaskUpdate MessageType = "COMPOSITE_TASK_UPDATE"
+	EventMessageDeadLettered MessageType = "MESSAGE_DEAD_LETTERED"
+)
+
 // AgentStatus 定义Agent状态
 type AgentStatus string
 
@@ -61,11 +99,12 @@ const (
 type ErrorType string
 
 const (
-	ErrorTypeProtocol   ErrorType = "PROTOCOL_ERROR"
-	ErrorTypeValidation ErrorType = "VALIDATION_ERROR"
-	ErrorTypeExecution  ErrorType = "EXECUTION_ERROR"
-	ErrorTypeTimeout    ErrorType = "TIMEOUT_ERROR"
-	ErrorTypeResource   ErrorType = "RESOURCE_ERROR"
+	ErrorTypeProtocol       ErrorType = "PROTOCOL_ERROR"
+	ErrorTypeValidation     ErrorType = "VALIDATION_ERROR"
+	ErrorTypeExecution      ErrorType = "EXECUTION_ERROR"
+	ErrorTypeTimeout        ErrorType = "TIMEOUT_ERROR"
+	ErrorTypeResource       ErrorType = "RESOURCE_ERROR"
+	ErrorTypeAuthentication ErrorType = "AUTHENTICATION_ERROR"
 )
 
 // ErrorSeverity 定义错误严重级别
@@ -89,6 +128,14 @@ type Message struct {
 	Payload   map[string]interface{} `json:"payload"`
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
 
+	// RequireAck 为true时接收方需回复ACK，发送方在确认前按退避重传，接收方按MessageID去重
+	RequireAck bool `json:"require_ack,omitempty"`
+
+	// Topic 非空时消息发布到该主题，To须为broadcast，Hub只投递给订阅了匹配主题的连接
+	Topic string `json:"topic,omitempty"`
+	// Retain 为true时Hub保留该主题的最后一条消息，之后订阅的连接立即收到
+	Retain bool `json:"retain,omitempty"`
+
 	// 安全相关字段
 	Signature           string `json:"signature,omitempty"`
 	Encrypted           bool   `json:"encrypted,omitempty"`
@@ -127,6 +174,7 @@ type TaskCompletePayload struct {
 	Output      map[string]interface{} `json:"output"`
 	Duration    int64                  `json:"duration"`
 	CompletedAt string                 `json:"completed_at"`
+	Score       float64                `json:"score,omitempty"`
 }
 
 // TaskFailedPayload 任务失败消息负载
@@ -138,6 +186,12 @@ type TaskFailedPayload struct {
 	RetryPossible bool                   `json:"retry_possible"`
 }
 
+// TaskCancelPayload 任务取消消息负载，Hub通知Agent停止执行已分配的任务
+type TaskCancelPayload struct {
+	TaskID string `json:"task_id"`
+	Reason string `json:"reason,omitempty"`
+}
+
 // HeartbeatPayload 心跳消息负载
 type HeartbeatPayload struct {
 	Status       AgentStatus `json:"status"`
@@ -147,6 +201,48 @@ type HeartbeatPayload struct {
 	Capabilities []string    `json:"capabilities"`
 }
 
+// TaskProgressPayload 任务进度消息负载
+type TaskProgressPayload struct {
+	TaskID   string `json:"task_id"`
+	Progress int    `json:"progress"`
+	Message  string `json:"message,omitempty"`
+}
+
+// AgentRegisterPayload Agent注册消息负载
+type AgentRegisterPayload struct {
+	Name         string                 `json:"name"`
+	Capabilities []string               `json:"capabilities"`
+	MaxTasks     int                    `json:"max_tasks,omitempty"`
+	Metadata     map[string]interface{} `json:"metadata,omitempty"`
+	// EncryptionKey base64编码的X25519公钥，Hub转发给其他Agent用于端到端加密
+	EncryptionKey string `json:"encryption_key,omitempty"`
+}
+
+// ClientConnectPayload Web客户端连接消息负载
+type ClientConnectPayload struct {
+	ClientType string `json:"client_type"`
+	UserAgent  string `json:"user_agent,omitempty"`
+}
+
+// KeyExchangePayload 密钥交换消息负载。Agent发送只含AgentID的消息向Hub查询该Agent的公钥，
+// Hub回复或在Agent注册时广播带PublicKey的消息
+type KeyExchangePayload struct {
+	AgentID   string `json:"agent_id"`
+	PublicKey string `json:"public_key,omitempty"`
+}
+
+// AckPayload 投递确认消息负载，Success为false表示接收方拒绝处理，发送方不再重传
+type AckPayload struct {
+	MessageID string `json:"message_id"`
+	Success   bool   `json:"success"`
+	Error     string `json:"error,omitempty"`
+}
+
+// SubscribePayload 订阅和取消订阅消息负载，Topics可包含通配符
+type SubscribePayload struct {
+	Topics []string `json:"topics"`
+}
+
 // StatusQueryPayload 状态查询消息负载
 type StatusQueryPayload struct {
 	QueryType string `json:"query_type"`
@@ -179,6 +275,21 @@ type ErrorPayload struct {
 	Severity          ErrorSeverity `json:"severity"`
 }
 
+// NewError 创建错误负载，可直接作为error返回给消息处理器的调用方
+func NewError(errorType ErrorType, code, message string) *ErrorPayload {
+	return &ErrorPayload{
+		ErrorType:    errorType,
+		ErrorCode:    code,
+		ErrorMessage: message,
+		Severity:     ErrorSeverityError,
+	}
+}
+
+// Error 实现error接口
+func (e *ErrorPayload) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.ErrorType, e.ErrorCode, e.ErrorMessage)
+}
+
 // NewMessage 创建新消息
 func NewMessage(msgType MessageType, from, to string) *Message {
 	return &Message{
@@ -193,18 +304,40 @@ func NewMessage(msgType MessageType, from, to string) *Message {
 	}
 }
 
+// NewErrorMessage 创建错误消息，original为引发错误的消息（可为nil）
+func NewErrorMessage(from, to string, payload *ErrorPayload, original *Message) *Message {
+	msg := NewMessage(MessageTypeError, from, to)
+	if original != nil {
+		payload.OriginalMessageID = original.MessageID
+	}
+	msg.SetPayload(payload) // ErrorPayload只含基本类型，序列化不会失败
+	return msg
+}
+
+// NewAckMessage 创建对original的确认消息，发回original的发送方
+func NewAckMessage(from string, original *Message, ack *AckPayload) *Message {
+	msg := NewMessage(MessageTypeAck, from, original.From)
+	msg.SetPayload(ack) // AckPayload只含基本类型，序列化不会失败
+	return msg
+}
+
 // SetPayload 设置消息负载
-func (m *Message) SetPayload(payload interface{}) {
-	// 将结构体转换为map
-	// 实际实现中使用json序列化/反序列化
-	m.Payload = interfaceToMap(payload)
+func (m *Message) SetPayload(payload interface{}) error {
+	payloadMap, err := SerializePayload(payload)
+	if err != nil {
+		return err
+	}
+	m.Payload = payloadMap
+	return nil
 }
 
 // GetPayload 获取并解析消息负载
 func (m *Message) GetPayload(target interface{}) error {
-	// 将map转换为目标结构体
-	// 实际实现中使用json序列化/反序列化
-	return mapToInterface(m.Payload, target)
+	if err := DeserializePayload(m.Payload, target); err != nil {
+		return NewError(ErrorTypeValidation, "INVALID_PAYLOAD",
+			fmt.Sprintf("invalid %s payload: %v", m.Type, err))
+	}
+	return nil
 }
 
 // SetMetadata 设置元数据
@@ -236,19 +369,5 @@ func (m *Message) IsHighPriority() bool {
 
 // 辅助函数
 func generateMessageID() string {
-	// 实际实现中使用uuid.New().String()
-	return "msg-" + time.Now().Format("20060102150405")
-}
-
-func interfaceToMap(v interface{}) map[string]interface{} {
-	// 简化实现，实际使用json.Marshal/Unmarshal
-	if m, ok := v.(map[string]interface{}); ok {
-		return m
-	}
-	return make(map[string]interface{})
-}
-
-func mapToInterface(m map[string]interface{}, target interface{}) error {
-	// 简化实现，实际使用json.Marshal/Unmarshal
-	return nil
+	return "msg-" + uuid.New().String()
 }
@@ -0,0 +1,100 @@
+package protocol
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMessagePayloadRoundTrip(t *testing.T) {
+	msg := NewMessage(MessageTypeTaskComplete, "worker-1", ServerID)
+	err := msg.SetPayload(&TaskCompletePayload{
+		TaskID:      "task-001",
+		Status:      TaskStatusSuccess,
+		Output:      map[string]interface{}{"answer": "42"},
+		CompletedAt: "2026-01-29T10:00:00Z",
+		Score:       88,
+	})
+	if err != nil {
+		t.Fatalf("SetPayload failed: %v", err)
+	}
+
+	if err := NewValidator().Validate(msg); err != nil {
+		t.Fatalf("Expected a valid message: %v", err)
+	}
+
+	var payload TaskCompletePayload
+	if err := msg.GetPayload(&payload); err != nil {
+		t.Fatalf("GetPayload failed: %v", err)
+	}
+	if payload.TaskID != "task-001" || payload.Score != 88 || payload.Output["answer"] != "42" {
+		t.Errorf("Unexpected payload: %+v", payload)
+	}
+}
+
+func TestMessageGetPayloadTypeMismatch(t *testing.T) {
+	msg := NewMessage(MessageTypeHeartbeat, "worker-1", ServerID)
+	msg.Payload = map[string]interface{}{"status": "IDLE", "load": "high"}
+
+	var payload HeartbeatPayload
+	err := msg.GetPayload(&payload)
+
+	var protoErr *ErrorPayload
+	if !errors.As(err, &protoErr) {
+		t.Fatalf("Expected *ErrorPayload, got %v", err)
+	}
+	if protoErr.ErrorType != ErrorTypeValidation || protoErr.ErrorCode != "INVALID_PAYLOAD" {
+		t.Errorf("Unexpected error: %+v", protoErr)
+	}
+}
+
+func TestNewMessageIDsAreUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		id := NewMessage(MessageTypeHeartbeat, "a", "b").MessageID
+		if seen[id] {
+			t.Fatalf("Duplicate message ID %s", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestNewErrorMessage(t *testing.T) {
+	original := NewMessage(MessageTypeHeartbeat, "worker-1", ServerID)
+	msg := NewErrorMessage(ServerID, "worker-1", NewError(ErrorTypeProtocol, "BAD", "bad frame"), original)
+
+	if err := NewValidator().Validate(msg); err != nil {
+		t.Fatalf("Expected a valid error message: %v", err)
+	}
+	if msg.Payload["original_message_id"] != original.MessageID {
+		t.Errorf("Expected original message ID, got %v", msg.Payload["original_message_id"])
+	}
+	if msg.Payload["severity"] != string(ErrorSeverityError) {
+		t.Errorf("Expected ERROR severity, got %v", msg.Payload["severity"])
+	}
+}
+
+func TestValidatorServerMessages(t *testing.T) {
+	validator := NewValidator()
+
+	register := NewMessage(MessageTypeAgentRegister, "worker-1", ServerID)
+	register.SetPayload(&AgentRegisterPayload{Name: "Worker"})
+	if err := validator.Validate(register); err == nil || !strings.Contains(err.Error(), "capabilities") {
+		t.Errorf("Expected capabilities error, got %v", err)
+	}
+	register.SetPayload(&AgentRegisterPayload{Name: "Worker", Capabilities: []string{"code"}})
+	if err := validator.Validate(register); err != nil {
+		t.Errorf("Expected a valid registration: %v", err)
+	}
+
+	progress := NewMessage(MessageTypeTaskProgress, "worker-1", ServerID)
+	progress.SetPayload(&TaskProgressPayload{TaskID: "task-001", Progress: 150})
+	if err := validator.Validate(progress); err == nil {
+		t.Error("Expected progress range error")
+	}
+
+	validator.SetMaxMessageSize(8)
+	if err := validator.ValidateSize([]byte(`{"message_id":"x"}`)); err == nil {
+		t.Error("Expected size error")
+	}
+}
@@ -5,9 +5,13 @@ import (
 	"fmt"
 )
 
-// Serializer 消息序列化器
+// Serializer 消息序列化器。设置密钥环后透明地加密发往已知对端的负载、
+// 解密收到的加密负载
 type Serializer struct {
 	prettyPrint bool
+	keyring     *Keyring
+	algorithm   EncryptionAlgorithm
+	signer      *Key
 }
 
 // NewSerializer 创建新的序列化器
@@ -23,8 +27,12 @@ func (s *Serializer) Serialize(msg *Message) ([]byte, error) {
 		return nil, fmt.Errorf("message cannot be nil")
 	}
 
+	msg, err := s.seal(msg)
+	if err != nil {
+		return nil, err
+	}
+
 	var data []byte
-	var err error
 
 	if s.prettyPrint {
 		data, err = json.MarshalIndent(msg, "", "  ")
@@ -50,9 +58,40 @@ func (s *Serializer) Deserialize(data []byte) (*Message, error) {
 		return nil, fmt.Errorf("failed to deserialize message: %w", err)
 	}
 
+	// 没有密钥环时（例如Hub）保持密文不变
+	if msg.Encrypted && s.keyring != nil {
+		if err := s.keyring.Decrypt(&msg); err != nil {
+			return nil, fmt.Errorf("failed to decrypt message: %w", err)
+		}
+	}
+
 	return &msg, nil
 }
 
+// seal 按需加密和签名，返回副本，不修改调用方的消息
+func (s *Serializer) seal(msg *Message) (*Message, error) {
+	encrypt := s.keyring != nil && !msg.Encrypted && s.keyring.CanEncryptTo(msg.To, s.algorithm)
+	if !encrypt && s.signer == nil {
+		return msg, nil
+	}
+
+	sealed := *msg
+	if encrypt {
+		if err := s.keyring.Encrypt(&sealed, s.algorithm); err != nil {
+			return nil, fmt.Errorf("failed to encrypt message: %w", err)
+		}
+	}
+
+	// 签名覆盖密文，Hub无需解密即可验证
+	if s.signer != nil {
+		if err := s.signer.Sign(&sealed); err != nil {
+			return nil, fmt.Errorf("failed to sign message: %w", err)
+		}
+	}
+
+	return &sealed, nil
+}
+
 // SerializeToString 序列化消息为JSON字符串
 func (s *Serializer) SerializeToString(msg *Message) (string, error) {
 	data, err := s.Serialize(msg)
@@ -72,6 +111,18 @@ func (s *Serializer) SetPrettyPrint(pretty bool) {
 	s.prettyPrint = pretty
 }
 
+// SetEncryption 设置密钥环和加密算法。发往有密钥的对端的消息会被加密，
+// 其他消息（例如发给Hub的）保持明文
+func (s *Serializer) SetEncryption(keyring *Keyring, algorithm EncryptionAlgorithm) {
+	s.keyring = keyring
+	s.algorithm = algorithm
+}
+
+// SetSigningKey 设置签名密钥，序列化时在加密之后签名
+func (s *Serializer) SetSigningKey(key *Key) {
+	s.signer = key
+}
+
 // SerializePayload 序列化负载
 func SerializePayload(payload interface{}) (map[string]interface{}, error) {
 	// 将结构体序列化为map
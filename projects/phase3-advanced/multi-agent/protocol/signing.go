@@ -0,0 +1,284 @@
+package protocol
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// SignatureAlgorithm 签名算法
+type SignatureAlgorithm string
+
+const (
+	SignatureHMACSHA256 SignatureAlgorithm = "HMAC-SHA256"
+	SignatureEd25519    SignatureAlgorithm = "Ed25519"
+)
+
+// 签名校验错误
+var (
+	ErrMissingSignature = errors.New("message is not signed")
+	ErrInvalidSignature = errors.New("signature verification failed")
+)
+
+// Key 签名密钥。HMAC密钥同时用于签名和验证；Ed25519密钥在Agent端持有私钥，
+// 在Hub端只登记公钥
+type Key struct {
+	Algorithm  SignatureAlgorithm
+	Secret     []byte             // HMAC-SHA256
+	PrivateKey ed25519.PrivateKey // Ed25519签名
+	PublicKey  ed25519.PublicKey  // Ed25519验证
+}
+
+// NewHMACKey 创建HMAC-SHA256密钥
+func NewHMACKey(secret []byte) *Key {
+	return &Key{Algorithm: SignatureHMACSHA256, Secret: secret}
+}
+
+// NewEd25519Key 用私钥创建Ed25519签名密钥
+func NewEd25519Key(privateKey ed25519.PrivateKey) *Key {
+	return &Key{
+		Algorithm:  SignatureEd25519,
+		PrivateKey: privateKey,
+		PublicKey:  privateKey.Public().(ed25519.PublicKey),
+	}
+}
+
+// NewEd25519PublicKey 用公钥创建Ed25519验证密钥
+func NewEd25519PublicKey(publicKey ed25519.PublicKey) *Key {
+	return &Key{Algorithm: SignatureEd25519, PublicKey: publicKey}
+}
+
+// Sign 对消息签名，写入Signature字段
+func (k *Key) Sign(msg *Message) error {
+	data, err := CanonicalBytes(msg)
+	if err != nil {
+		return err
+	}
+
+	var sig []byte
+	switch k.Algorithm {
+	case SignatureHMACSHA256:
+		sig = hmacSum(k.Secret, data)
+	case SignatureEd25519:
+		if len(k.PrivateKey) != ed25519.PrivateKeySize {
+			return errors.New("ed25519 private key is required to sign")
+		}
+		sig = ed25519.Sign(k.PrivateKey, data)
+	default:
+		return fmt.Errorf("unsupported signature algorithm: %s", k.Algorithm)
+	}
+
+	msg.Signature = base64.StdEncoding.EncodeToString(sig)
+	return nil
+}
+
+// Verify 验证消息签名
+func (k *Key) Verify(msg *Message) error {
+	if msg.Signature == "" {
+		return ErrMissingSignature
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(msg.Signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	data, err := CanonicalBytes(msg)
+	if err != nil {
+		return err
+	}
+
+	switch k.Algorithm {
+	case SignatureHMACSHA256:
+		if !hmac.Equal(sig, hmacSum(k.Secret, data)) {
+			return ErrInvalidSignature
+		}
+	case SignatureEd25519:
+		if len(k.PublicKey) != ed25519.PublicKeySize || !ed25519.Verify(k.PublicKey, data, sig) {
+			return ErrInvalidSignature
+		}
+	default:
+		return fmt.Errorf("unsupported signature algorithm: %s", k.Algorithm)
+	}
+
+	return nil
+}
+
+func hmacSum(secret, data []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// CanonicalBytes 返回消息的规范化字节，即去掉Signature后的JSON。
+// 先解码为通用值再编码，使对象键有序、数字表示与接收方解码后一致
+func CanonicalBytes(msg *Message) ([]byte, error) {
+	unsigned := *msg
+	unsigned.Signature = ""
+
+	data, err := json.Marshal(&unsigned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, fmt.Errorf("failed to normalize message: %w", err)
+	}
+
+	return json.Marshal(generic)
+}
+
+// KeyStore Hub端登记的Agent验证密钥
+type KeyStore struct {
+	keys map[string]*Key // agentID -> Key
+	mu   sync.RWMutex
+}
+
+// NewKeyStore 创建密钥库
+func NewKeyStore() *KeyStore {
+	return &KeyStore{
+		keys: make(map[string]*Key),
+	}
+}
+
+// Register 登记Agent的验证密钥
+func (s *KeyStore) Register(agentID string, key *Key) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[agentID] = key
+}
+
+// Remove 删除Agent的密钥
+func (s *KeyStore) Remove(agentID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, agentID)
+}
+
+// Get 获取Agent的密钥
+func (s *KeyStore) Get(agentID string) (*Key, bool) {
+	if s == nil {
+		return nil, false
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, exists := s.keys[agentID]
+	return key, exists
+}
+
+// keyFileEntry 密钥文件中的一项
+type keyFileEntry struct {
+	Algorithm SignatureAlgorithm `json:"algorithm"`
+	Key       string             `json:"key"` // base64：HMAC密钥或Ed25519公钥
+}
+
+// LoadKeyStore 从JSON文件加载密钥，格式为
+// {"agent-id": {"algorithm": "HMAC-SHA256" | "Ed25519", "key": "<base64>"}}
+func LoadKeyStore(path string) (*KeyStore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	var entries map[string]keyFileEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse key file: %w", err)
+	}
+
+	store := NewKeyStore()
+	for agentID, entry := range entries {
+		raw, err := base64.StdEncoding.DecodeString(entry.Key)
+		if err != nil || len(raw) == 0 {
+			return nil, fmt.Errorf("invalid key for agent %s", agentID)
+		}
+
+		switch entry.Algorithm {
+		case SignatureHMACSHA256:
+			store.Register(agentID, NewHMACKey(raw))
+		case SignatureEd25519:
+			if len(raw) != ed25519.PublicKeySize {
+				return nil, fmt.Errorf("invalid Ed25519 public key for agent %s", agentID)
+			}
+			store.Register(agentID, NewEd25519PublicKey(raw))
+		default:
+			return nil, fmt.Errorf("unsupported signature algorithm for agent %s: %s", agentID, entry.Algorithm)
+		}
+	}
+
+	return store, nil
+}
+
+// ReplayGuard 防重放：拒绝时间戳超出窗口或同一发送方的消息ID已出现过的消息
+type ReplayGuard struct {
+	window    time.Duration
+	seen      map[string]time.Time // 发送方+messageID -> 消息时间戳
+	lastPrune time.Time
+	mu        sync.Mutex
+}
+
+// NewReplayGuard 创建防重放检查器，window为允许的时钟偏差
+func NewReplayGuard(window time.Duration) *ReplayGuard {
+	return &ReplayGuard{
+		window: window,
+		seen:   make(map[string]time.Time),
+	}
+}
+
+// Check 检查消息并记录其ID
+func (g *ReplayGuard) Check(msg *Message) error {
+	return g.check(msg, false)
+}
+
+// CheckRetransmit 检查需确认消息的重传：时间戳仍须在窗口内，
+// 已记录的ID只接受时间戳与原消息相同的重传。调用方负责对重传去重
+func (g *ReplayGuard) CheckRetransmit(msg *Message) error {
+	return g.check(msg, true)
+}
+
+func (g *ReplayGuard) check(msg *Message, retransmit bool) error {
+	ts, err := time.Parse(time.RFC3339, msg.Timestamp)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp: %w", err)
+	}
+
+	now := time.Now()
+	if ts.Before(now.Add(-g.window)) || ts.After(now.Add(g.window)) {
+		return fmt.Errorf("timestamp %s is outside the %s window", msg.Timestamp, g.window)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	key := senderKey(msg.From, msg.MessageID)
+	if seenAt, exists := g.seen[key]; exists {
+		if retransmit && seenAt.Equal(ts) {
+			return nil
+		}
+		return fmt.Errorf("message %s was already received", msg.MessageID)
+	}
+
+	// 超出窗口的ID不会再通过时间戳检查，可以丢弃
+	if now.Sub(g.lastPrune) > g.window {
+		for id, seenAt := range g.seen {
+			if seenAt.Before(now.Add(-g.window)) {
+				delete(g.seen, id)
+			}
+		}
+		g.lastPrune = now
+	}
+	g.seen[key] = ts
+
+	return nil
+}
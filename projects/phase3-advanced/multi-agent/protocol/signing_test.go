@@ -0,0 +1,146 @@
+package protocol
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func signedTestMessage(t *testing.T, key *Key) *Message {
+	t.Helper()
+
+	msg := NewMessage(MessageTypeHeartbeat, "worker-1", ServerID)
+	msg.SetPayload(&HeartbeatPayload{Status: AgentStatusIdle, Load: 0.25, TasksRunning: 3})
+	if err := key.Sign(msg); err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	return msg
+}
+
+func TestKeySignVerify(t *testing.T) {
+	_, privateKey, _ := ed25519.GenerateKey(nil)
+
+	tests := []struct {
+		name   string
+		signer *Key
+		verify *Key
+	}{
+		{"hmac", NewHMACKey([]byte("secret")), NewHMACKey([]byte("secret"))},
+		{"ed25519", NewEd25519Key(privateKey), NewEd25519PublicKey(privateKey.Public().(ed25519.PublicKey))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := signedTestMessage(t, tt.signer)
+
+			// 经过序列化往返后签名仍然有效
+			data, _ := NewSerializer().Serialize(msg)
+			received, _ := NewSerializer().Deserialize(data)
+			if err := tt.verify.Verify(received); err != nil {
+				t.Fatalf("Verify failed: %v", err)
+			}
+
+			received.From = "worker-2"
+			if err := tt.verify.Verify(received); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Expected ErrInvalidSignature for a tampered message, got %v", err)
+			}
+
+			received.From = msg.From
+			received.Signature = ""
+			if err := tt.verify.Verify(received); !errors.Is(err, ErrMissingSignature) {
+				t.Errorf("Expected ErrMissingSignature, got %v", err)
+			}
+		})
+	}
+
+	msg := signedTestMessage(t, NewHMACKey([]byte("secret")))
+	if err := NewHMACKey([]byte("other")).Verify(msg); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature for the wrong key, got %v", err)
+	}
+	if err := NewEd25519PublicKey(privateKey.Public().(ed25519.PublicKey)).Sign(msg); err == nil {
+		t.Error("Expected signing with a public key to fail")
+	}
+}
+
+func TestCanonicalBytesNormalizesPayload(t *testing.T) {
+	typed := NewMessage(MessageTypeTaskProgress, "worker-1", ServerID)
+	typed.Payload = map[string]interface{}{"progress": 50, "task_id": "task-001"}
+
+	decoded := *typed
+	decoded.Payload = map[string]interface{}{"task_id": "task-001", "progress": float64(50)}
+
+	a, _ := CanonicalBytes(typed)
+	b, _ := CanonicalBytes(&decoded)
+	if string(a) != string(b) {
+		t.Errorf("Expected identical canonical bytes:\n%s\n%s", a, b)
+	}
+}
+
+func TestReplayGuard(t *testing.T) {
+	guard := NewReplayGuard(time.Minute)
+
+	msg := NewMessage(MessageTypeHeartbeat, "worker-1", ServerID)
+	if err := guard.Check(msg); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if err := guard.Check(msg); err == nil {
+		t.Error("Expected a replayed message ID to be rejected")
+	}
+
+	// 重传须与原消息的时间戳相同，其他发送方的相同ID不受影响
+	if err := guard.CheckRetransmit(msg); err != nil {
+		t.Errorf("Expected an identical retransmission to pass: %v", err)
+	}
+	altered := *msg
+	altered.Timestamp = time.Now().Add(time.Second).Format(time.RFC3339)
+	if err := guard.CheckRetransmit(&altered); err == nil {
+		t.Error("Expected a retransmission with a different timestamp to be rejected")
+	}
+	other := *msg
+	other.From = "worker-2"
+	if err := guard.Check(&other); err != nil {
+		t.Errorf("Expected IDs to be scoped to their sender: %v", err)
+	}
+
+	old := NewMessage(MessageTypeHeartbeat, "worker-1", ServerID)
+	old.Timestamp = time.Now().Add(-2 * time.Minute).Format(time.RFC3339)
+	if err := guard.Check(old); err == nil {
+		t.Error("Expected an old timestamp to be rejected")
+	}
+
+	future := NewMessage(MessageTypeHeartbeat, "worker-1", ServerID)
+	future.Timestamp = time.Now().Add(2 * time.Minute).Format(time.RFC3339)
+	if err := guard.Check(future); err == nil {
+		t.Error("Expected a future timestamp to be rejected")
+	}
+}
+
+func TestLoadKeyStore(t *testing.T) {
+	publicKey, _, _ := ed25519.GenerateKey(nil)
+	path := filepath.Join(t.TempDir(), "keys.json")
+	content := `{
+		"worker-1": {"algorithm": "HMAC-SHA256", "key": "` + base64.StdEncoding.EncodeToString([]byte("secret")) + `"},
+		"worker-2": {"algorithm": "Ed25519", "key": "` + base64.StdEncoding.EncodeToString(publicKey) + `"}
+	}`
+	os.WriteFile(path, []byte(content), 0o600)
+
+	store, err := LoadKeyStore(path)
+	if err != nil {
+		t.Fatalf("LoadKeyStore failed: %v", err)
+	}
+	if key, ok := store.Get("worker-1"); !ok || key.Algorithm != SignatureHMACSHA256 {
+		t.Errorf("Expected an HMAC key for worker-1, got %+v", key)
+	}
+	if key, ok := store.Get("worker-2"); !ok || key.Algorithm != SignatureEd25519 || key.PrivateKey != nil {
+		t.Errorf("Expected a public Ed25519 key for worker-2, got %+v", key)
+	}
+
+	os.WriteFile(path, []byte(`{"worker-1": {"algorithm": "RSA", "key": "c2VjcmV0"}}`), 0o600)
+	if _, err := LoadKeyStore(path); err == nil {
+		t.Error("Expected an unsupported algorithm error")
+	}
+}
@@ -0,0 +1,91 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 主题由"."分隔的层级组成，例如tasks.code.created。订阅时可使用通配符
+const (
+	TopicSeparator    = "."
+	TopicWildcardOne  = "*" // 匹配恰好一个层级
+	TopicWildcardRest = "#" // 匹配剩余的零个或多个层级，只能是最后一个层级
+)
+
+// ValidateTopic 验证发布用的主题名，主题名不能包含通配符
+func ValidateTopic(topic string) error {
+	return validateTopicLevels(topic, false)
+}
+
+// ValidateTopicPattern 验证订阅用的主题模式
+func ValidateTopicPattern(pattern string) error {
+	return validateTopicLevels(pattern, true)
+}
+
+func validateTopicLevels(topic string, wildcards bool) error {
+	if topic == "" {
+		return errors.New("topic is empty")
+	}
+
+	levels := strings.Split(topic, TopicSeparator)
+	for i, level := range levels {
+		switch {
+		case level == "":
+			return fmt.Errorf("topic %q has an empty level", topic)
+		case level == TopicWildcardOne || level == TopicWildcardRest:
+			if !wildcards {
+				return fmt.Errorf("topic %q must not contain wildcards", topic)
+			}
+			if level == TopicWildcardRest && i != len(levels)-1 {
+				return fmt.Errorf("wildcard %s must be the last level of %q", TopicWildcardRest, topic)
+			}
+		case strings.ContainsAny(level, TopicWildcardOne+TopicWildcardRest):
+			return fmt.Errorf("topic %q has a level mixing wildcards and text", topic)
+		}
+	}
+
+	return nil
+}
+
+// MatchTopic 判断主题是否匹配订阅模式
+func MatchTopic(pattern, topic string) bool {
+	patternLevels := strings.Split(pattern, TopicSeparator)
+	topicLevels := strings.Split(topic, TopicSeparator)
+
+	for i, level := range patternLevels {
+		if level == TopicWildcardRest {
+			return true
+		}
+		if i >= len(topicLevels) {
+			return false
+		}
+		if level != TopicWildcardOne && level != topicLevels[i] {
+			return false
+		}
+	}
+
+	return len(patternLevels) == len(topicLevels)
+}
+
+// TopicLevel 把任意字符串（例如Agent ID或任务类型）转换为可用作主题层级的形式
+func TopicLevel(s string) string {
+	if s == "" {
+		return "_"
+	}
+	return strings.NewReplacer(TopicSeparator, "_", TopicWildcardOne, "_", TopicWildcardRest, "_").Replace(s)
+}
+
+// NewSubscribeMessage 创建向Hub订阅主题的消息
+func NewSubscribeMessage(from string, topics ...string) *Message {
+	msg := NewMessage(MessageTypeSubscribe, from, ServerID)
+	msg.SetPayload(&SubscribePayload{Topics: topics}) // 只含字符串，序列化不会失败
+	return msg
+}
+
+// NewUnsubscribeMessage 创建取消订阅主题的消息
+func NewUnsubscribeMessage(from string, topics ...string) *Message {
+	msg := NewMessage(MessageTypeUnsubscribe, from, ServerID)
+	msg.SetPayload(&SubscribePayload{Topics: topics})
+	return msg
+}
@@ -0,0 +1,76 @@
+package protocol
+
+import "testing"
+
+func TestMatchTopic(t *testing.T) {
+	tests := []struct {
+		pattern string
+		topic   string
+		match   bool
+	}{
+		{"tasks.code.created", "tasks.code.created", true},
+		{"tasks.code.*", "tasks.code.created", true},
+		{"tasks.code.*", "tasks.code", false},
+		{"tasks.code.*", "tasks.code.created.extra", false},
+		{"tasks.*.status", "tasks.review.status", true},
+		{"tasks.#", "tasks", true},
+		{"tasks.#", "tasks.code.created", true},
+		{"#", "agents.agent-001.status", true},
+		{"tasks.#", "agents.agent-001.status", false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchTopic(tt.pattern, tt.topic); got != tt.match {
+			t.Errorf("MatchTopic(%q, %q) = %v, expected %v", tt.pattern, tt.topic, got, tt.match)
+		}
+	}
+}
+
+func TestValidateTopic(t *testing.T) {
+	if err := ValidateTopicPattern("tasks.*.#"); err != nil {
+		t.Errorf("Expected a valid pattern: %v", err)
+	}
+
+	for _, pattern := range []string{"", "tasks..code", "tasks.#.status", "tasks.co*"} {
+		if err := ValidateTopicPattern(pattern); err == nil {
+			t.Errorf("Expected pattern %q to be invalid", pattern)
+		}
+	}
+
+	if err := ValidateTopic("tasks.*"); err == nil {
+		t.Error("Published topics should not contain wildcards")
+	}
+	if level := TopicLevel("agent.1#"); level != "agent_1_" {
+		t.Errorf("Unexpected topic level: %s", level)
+	}
+}
+
+func TestValidator_Topics(t *testing.T) {
+	validator := NewValidator()
+
+	if err := validator.Validate(NewSubscribeMessage("web-client", "tasks.code.*")); err != nil {
+		t.Fatalf("Expected a valid subscription: %v", err)
+	}
+	if err := validator.Validate(NewUnsubscribeMessage("web-client", "tasks.#.code")); err == nil {
+		t.Error("Expected an invalid pattern to be rejected")
+	}
+
+	msg := NewMessage(MessageTypeBroadcast, "agent-001", "broadcast")
+	msg.SetPayload(&BroadcastPayload{Event: "progress", Message: "50%"})
+	msg.Topic = "tasks.code.progress"
+	msg.Retain = true
+	if err := validator.Validate(msg); err != nil {
+		t.Fatalf("Expected a valid publication: %v", err)
+	}
+
+	msg.To = ServerID
+	if err := validator.Validate(msg); err == nil {
+		t.Error("Expected a topic on a direct message to be rejected")
+	}
+
+	msg.To = "broadcast"
+	msg.Topic = ""
+	if err := validator.Validate(msg); err == nil {
+		t.Error("Expected retain without a topic to be rejected")
+	}
+}
@@ -26,6 +26,10 @@ func (v *Validator) Validate(msg *Message) error {
 		return fmt.Errorf("basic validation failed: %w", err)
 	}
 
+	if err := v.validateTopic(msg); err != nil {
+		return fmt.Errorf("topic validation failed: %w", err)
+	}
+
 	if err := v.validatePayload(msg); err != nil {
 		return fmt.Errorf("payload validation failed: %w", err)
 	}
@@ -33,6 +37,14 @@ func (v *Validator) Validate(msg *Message) error {
 	return nil
 }
 
+// ValidateSize 验证原始消息大小
+func (v *Validator) ValidateSize(data []byte) error {
+	if v.maxMessageSize > 0 && len(data) > v.maxMessageSize {
+		return fmt.Errorf("message size %d exceeds limit %d", len(data), v.maxMessageSize)
+	}
+	return nil
+}
+
 // validateBasicFields 验证基本字段
 func (v *Validator) validateBasicFields(msg *Message) error {
 	// 验证必需字段
@@ -80,6 +92,11 @@ func (v *Validator) validatePayload(msg *Message) error {
 		return errors.New("payload is required")
 	}
 
+	// 加密负载只能验证信封，内容由接收方解密后处理
+	if msg.Encrypted {
+		return v.validateEncryptedPayload(msg)
+	}
+
 	switch msg.Type {
 	case MessageTypeTaskRequest:
 		return v.validateTaskRequestPayload(msg.Payload)
@@ -91,8 +108,22 @@ func (v *Validator) validatePayload(msg *Message) error {
 		return v.validateTaskCompletePayload(msg.Payload)
 	case MessageTypeTaskFailed:
 		return v.validateTaskFailedPayload(msg.Payload)
+	case MessageTypeTaskCancel:
+		return v.validateTaskCancelPayload(msg.Payload)
+	case MessageTypeTaskProgress:
+		return v.validateTaskProgressPayload(msg.Payload)
 	case MessageTypeHeartbeat:
 		return v.validateHeartbeatPayload(msg.Payload)
+	case MessageTypeAgentRegister:
+		return v.validateAgentRegisterPayload(msg.Payload)
+	case MessageTypeClientConnect:
+		return v.validateClientConnectPayload(msg.Payload)
+	case MessageTypeKeyExchange:
+		return v.validateKeyExchangePayload(msg.Payload)
+	case MessageTypeAck:
+		return v.validateAckPayload(msg.Payload)
+	case MessageTypeSubscribe, MessageTypeUnsubscribe:
+		return v.validateSubscribePayload(msg.Payload)
 	case MessageTypeStatusQuery:
 		return v.validateStatusQueryPayload(msg.Payload)
 	case MessageTypeStatusResponse:
@@ -197,6 +228,32 @@ func (v *Validator) validateTaskFailedPayload(payload map[string]interface{}) er
 	return nil
 }
 
+// validateTaskCancelPayload 验证任务取消负载
+func (v *Validator) validateTaskCancelPayload(payload map[string]interface{}) error {
+	if _, ok := payload["task_id"]; !ok {
+		return errors.New("task_id is required")
+	}
+
+	return nil
+}
+
+// validateTaskProgressPayload 验证任务进度负载
+func (v *Validator) validateTaskProgressPayload(payload map[string]interface{}) error {
+	if _, ok := payload["task_id"]; !ok {
+		return errors.New("task_id is required")
+	}
+
+	progress, ok := payload["progress"].(float64)
+	if !ok {
+		return errors.New("progress is required")
+	}
+	if progress < 0 || progress > 100 {
+		return fmt.Errorf("progress must be between 0 and 100, got %v", progress)
+	}
+
+	return nil
+}
+
 // validateHeartbeatPayload 验证心跳负载
 func (v *Validator) validateHeartbeatPayload(payload map[string]interface{}) error {
 	if _, ok := payload["status"]; !ok {
@@ -206,6 +263,109 @@ func (v *Validator) validateHeartbeatPayload(payload map[string]interface{}) err
 	return nil
 }
 
+// validateAgentRegisterPayload 验证Agent注册负载
+func (v *Validator) validateAgentRegisterPayload(payload map[string]interface{}) error {
+	if _, ok := payload["name"]; !ok {
+		return errors.New("name is required")
+	}
+
+	if caps, ok := payload["capabilities"].([]interface{}); !ok || len(caps) == 0 {
+		return errors.New("capabilities is required")
+	}
+
+	return nil
+}
+
+// validateClientConnectPayload 验证客户端连接负载
+func (v *Validator) validateClientConnectPayload(payload map[string]interface{}) error {
+	if _, ok := payload["client_type"]; !ok {
+		return errors.New("client_type is required")
+	}
+
+	return nil
+}
+
+// validateKeyExchangePayload 验证密钥交换负载
+func (v *Validator) validateKeyExchangePayload(payload map[string]interface{}) error {
+	if _, ok := payload["agent_id"]; !ok {
+		return errors.New("agent_id is required")
+	}
+
+	return nil
+}
+
+// validateAckPayload 验证投递确认负载
+func (v *Validator) validateAckPayload(payload map[string]interface{}) error {
+	if _, ok := payload["message_id"].(string); !ok {
+		return errors.New("message_id is required")
+	}
+
+	if _, ok := payload["success"].(bool); !ok {
+		return errors.New("success is required")
+	}
+
+	return nil
+}
+
+// validateTopic 验证发布主题，带主题的消息只能广播
+func (v *Validator) validateTopic(msg *Message) error {
+	if msg.Topic == "" {
+		if msg.Retain {
+			return errors.New("retain requires a topic")
+		}
+		return nil
+	}
+
+	if !msg.IsBroadcast() {
+		return errors.New("messages with a topic must be sent to broadcast")
+	}
+
+	return ValidateTopic(msg.Topic)
+}
+
+// validateSubscribePayload 验证订阅负载
+func (v *Validator) validateSubscribePayload(payload map[string]interface{}) error {
+	topics, ok := payload["topics"].([]interface{})
+	if !ok || len(topics) == 0 {
+		return errors.New("topics is required")
+	}
+
+	for _, topic := range topics {
+		pattern, ok := topic.(string)
+		if !ok {
+			return errors.New("topics must be strings")
+		}
+		if err := ValidateTopicPattern(pattern); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateEncryptedPayload 验证加密负载信封
+func (v *Validator) validateEncryptedPayload(msg *Message) error {
+	switch EncryptionAlgorithm(msg.EncryptionAlgorithm) {
+	case EncryptionAES256GCM:
+	case EncryptionX25519ChaCha20Poly1305:
+		if _, ok := msg.Payload["ephemeral_key"].(string); !ok {
+			return errors.New("ephemeral_key is required")
+		}
+	default:
+		return fmt.Errorf("unsupported encryption algorithm: %s", msg.EncryptionAlgorithm)
+	}
+
+	if _, ok := msg.Payload["ciphertext"].(string); !ok {
+		return errors.New("ciphertext is required")
+	}
+
+	if _, ok := msg.Payload["nonce"].(string); !ok {
+		return errors.New("nonce is required")
+	}
+
+	return nil
+}
+
 // validateStatusQueryPayload 验证状态查询负载
 func (v *Validator) validateStatusQueryPayload(payload map[string]interface{}) error {
 	if _, ok := payload["query_type"]; !ok {
@@ -266,7 +426,15 @@ func (v *Validator) isValidMessageType(msgType MessageType) bool {
 		MessageTypeTaskReject,
 		MessageTypeTaskComplete,
 		MessageTypeTaskFailed,
+		MessageTypeTaskCancel,
+		MessageTypeTaskProgress,
 		MessageTypeHeartbeat,
+		MessageTypeAgentRegister,
+		MessageTypeClientConnect,
+		MessageTypeKeyExchange,
+		MessageTypeAck,
+		MessageTypeSubscribe,
+		MessageTypeUnsubscribe,
 		MessageTypeStatusQuery,
 		MessageTypeStatusResponse,
 		MessageTypeBroadcast,
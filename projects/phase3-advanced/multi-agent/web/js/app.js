@@ -33,8 +33,14 @@ class MultiAgentApp {
         wsClient.on('TASK_CREATED', (msg) => this.handleTaskCreated(msg));
         wsClient.on('TASK_ASSIGNED', (msg) => this.handleTaskAssigned(msg));
         wsClient.on('TASK_STATUS_UPDATE', (msg) => this.handleTaskStatusUpdate(msg));
+        wsClient.on('TASK_REASSIGNED', (msg) => this.handleTaskReassigned(msg));
         wsClient.on('RESULT_SUBMITTED', (msg) => this.handleResultSubmitted(msg));
         wsClient.on('RESULT_AGGREGATED', (msg) => this.handleResultAggregated(msg));
+        wsClient.on('COMPOSITE_TASK_UPDATE', (msg) => this.handleCompositeTaskUpdate(msg));
+        wsClient.on('MESSAGE_DEAD_LETTERED', (msg) => this.handleMessageDeadLettered(msg));
+
+        // 只订阅控制台显示的事件，Agent事件保留最后一条，连接后立即收到
+        wsClient.subscribe('agents.#', 'tasks.#', 'results.#', 'messages.#');
 
         // 连接
         wsClient.connect();
@@ -682,6 +688,18 @@ class MultiAgentApp {
         this.refreshTasks();
     }
 
+    handleTaskReassigned(msg) {
+        console.log('Task reassigned:', msg);
+        const { task_id, from_agent, requeued } = msg.payload;
+        if (requeued) {
+            this.showNotification(`任务 ${task_id} 已从故障Agent ${from_agent} 收回并重新分配`, 'warning');
+        } else {
+            this.showNotification(`任务 ${task_id} 超过最大重新分配次数，已标记为失败`, 'error');
+        }
+        this.refreshTasks();
+        this.refreshAgents();
+    }
+
     handleResultSubmitted(msg) {
         console.log('Result submitted:', msg);
         this.refreshResults();
@@ -692,6 +710,23 @@ class MultiAgentApp {
         this.refreshResults();
     }
 
+    handleCompositeTaskUpdate(msg) {
+        console.log('Composite task update:', msg);
+        const { composite_id, status, error } = msg.payload;
+        if (status === 'COMPLETED') {
+            this.showNotification(`复合任务 ${composite_id} 已完成`, 'success');
+        } else if (status === 'FAILED') {
+            this.showNotification(`复合任务 ${composite_id} 失败: ${error}`, 'error');
+        }
+        this.refreshTasks();
+    }
+
+    handleMessageDeadLettered(msg) {
+        console.log('Message dead-lettered:', msg);
+        const { message_id, type, to, reason } = msg.payload;
+        this.showNotification(`发给 ${to} 的 ${type} 消息 ${message_id} 无法投递: ${reason}`, 'warning');
+    }
+
     // 显示通知
     showNotification(message, type = 'info') {
         // 简单的alert，可以替换为更好的通知组件
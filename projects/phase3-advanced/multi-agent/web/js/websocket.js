@@ -6,6 +6,7 @@ class WebSocketClient {
         this.reconnectInterval = 3000;
         this.reconnectTimer = null;
         this.messageHandlers = new Map();
+        this.topics = new Set();
         this.isIntentionalClose = false;
     }
 
@@ -39,6 +40,11 @@ class WebSocketClient {
                     user_agent: navigator.userAgent
                 }
             });
+
+            // 订阅按连接记录，重连后重新订阅
+            if (this.topics.size > 0) {
+                this.sendSubscription('SUBSCRIBE', Array.from(this.topics));
+            }
         };
 
         this.ws.onmessage = (event) => {
@@ -114,6 +120,32 @@ class WebSocketClient {
         }
     }
 
+    // 订阅主题，只接收订阅的主题上的事件
+    subscribe(...topics) {
+        topics.forEach(topic => this.topics.add(topic));
+        if (this.isConnected()) {
+            this.sendSubscription('SUBSCRIBE', topics);
+        }
+    }
+
+    // 取消订阅主题
+    unsubscribe(...topics) {
+        topics.forEach(topic => this.topics.delete(topic));
+        if (this.isConnected()) {
+            this.sendSubscription('UNSUBSCRIBE', topics);
+        }
+    }
+
+    // 发送订阅或取消订阅消息
+    sendSubscription(type, topics) {
+        this.send({
+            type: type,
+            from: 'web-client',
+            to: 'server',
+            payload: { topics: topics }
+        });
+    }
+
     // 发送消息
     send(message) {
         if (this.ws && this.ws.readyState === WebSocket.OPEN) {